@@ -0,0 +1,25 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/spf13/afero"
+)
+
+// InfoAttributes returns the content of $GIT_DIR/info/attributes,
+// which contains the attributes that are specific to the repository.
+// nil is returned if the file doesn't exist
+func (b *Backend) InfoAttributes() ([]byte, error) {
+	p := ginternals.InfoAttributesPath(b.config)
+	data, err := afero.ReadFile(b.fs, p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read %s: %w", p, err)
+	}
+	return data, nil
+}
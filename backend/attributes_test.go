@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoAttributes(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, dir)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+	require.NoError(t, b.Init(ginternals.Master))
+
+	data, err := b.InfoAttributes()
+	require.NoError(t, err)
+	assert.Nil(t, data, "a missing file should not be an error")
+
+	p := ginternals.InfoAttributesPath(cfg)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, []byte("*.bin binary\n"), 0o644))
+	data, err = b.InfoAttributes()
+	require.NoError(t, err)
+	assert.Equal(t, "*.bin binary\n", string(data))
+}
@@ -5,11 +5,16 @@ package backend
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Nivl/git-go/ginternals"
 	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/debuglog"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/Nivl/git-go/ginternals/object"
 	"github.com/Nivl/git-go/ginternals/packfile"
 	"github.com/Nivl/git-go/internal/cache"
+	"github.com/Nivl/git-go/internal/pathutil"
 	"github.com/Nivl/git-go/internal/syncutil"
 	"github.com/spf13/afero"
 )
@@ -18,27 +23,155 @@ import (
 type Backend struct {
 	config *config.Config
 
-	objectMu     *syncutil.NamedMutex
-	cache        *cache.LRU
+	objectMu *syncutil.NamedMutex
+	cache    *cache.LRU
+
+	// looseObjects maps the oid of the loose objects to the path of
+	// the object directory containing them
 	looseObjects *sync.Map
 
-	packfiles map[ginternals.Oid]*packfile.Pack
+	// packfilesMu protects packfiles, since packfiles can be added
+	// after the backend has been loaded
+	packfilesMu sync.RWMutex
+	packfiles   map[ginternals.Oid]*packfile.Pack
 
 	refs *sync.Map
+	// packedRefs is set when the packed-refs file is too large to be
+	// loaded in memory, and is binary searched instead
+	packedRefsMu sync.RWMutex
+	packedRefs   *packedRefs
+	// peeled maps the oid of the annotated tags to the object they
+	// target once peeled, as found in the packed-refs file
+	peeled *sync.Map
 
 	fs afero.Fs
+
+	objectCache ObjectCache
+	// cacheUploader sends the objects written by the backend to
+	// objectCache. nil if there's no objectCache
+	cacheUploader *objectCacheUploader
+	indexer       Indexer
+	layout        ginternals.Layout
+
+	scanIgnore     []string
+	scanWarningsMu sync.Mutex
+	scanWarnings   []ScanWarning
+
+	staleLockAge time.Duration
+	verifyPacks  bool
+	mmapPacks    bool
+	verifyCRC    bool
+	limits       object.Limits
+	logger       debuglog.Logger
+
+	// shared contains the value of core.sharedRepository, which sets
+	// the permissions of the files written in the repository
+	shared config.SharedRepository
+
+	// caseInsensitive is set once we know whether the filesystem
+	// is case-insensitive or not
+	caseMu          sync.Mutex
+	caseInsensitive *bool
+}
+
+// Options represents the optional params that can be used to create
+// a Backend
+type Options struct {
+	// ObjectCache is a shared cache used to retrieve the objects that
+	// are not in the local odb. The objects written by the backend
+	// are also sent to the cache, in the background. Close() waits
+	// for the objects to be sent.
+	// No cache is used by default
+	ObjectCache ObjectCache
+	// Indexer is notified every time an object or a reference is
+	// written, so external indexes can be kept in sync with the
+	// repository.
+	// No indexer is used by default
+	Indexer Indexer
+	// Layout maps the loose objects and the references to the files
+	// storing them.
+	// Defaults to ginternals.FSLayout, which is the layout used by git
+	Layout ginternals.Layout
+	// ScanIgnore contains glob patterns (see filepath.Match) of the
+	// files and directories to skip when scanning the repository.
+	// The patterns are matched against the base name of the files.
+	// Defaults to DefaultScanIgnore when nil. Use an empty slice
+	// to not ignore anything
+	ScanIgnore []string
+	// StaleLockAge is the age after which a lock file (X.lock)
+	// left in the repository is considered stale and removed when
+	// writing X.
+	// Like git, existing locks are never removed by default, and
+	// writing X fails with a lockfile.LockedError containing the
+	// age of the lock
+	StaleLockAge time.Duration
+	// VerifyPacks makes sure the SHA stored in the footer of every
+	// packfile matches its content when the packfiles are loaded.
+	// This requires reading all the packfiles of the repository,
+	// which can be slow on big repositories.
+	// Defaults to false
+	VerifyPacks bool
+	// CacheSize is the maximum number of bytes of decompressed
+	// objects kept in memory, to avoid having to read and inflate
+	// the objects that are used often.
+	// Defaults to DefaultCacheSize. Use a negative value to disable
+	// the cache
+	CacheSize int
+	// MmapPacks maps the packfiles and their indexes in memory
+	// instead of reading them with syscalls, which speeds up the
+	// workloads reading a lot of objects. The files are read normally
+	// on the platforms that don't support mmap.
+	// Defaults to false
+	MmapPacks bool
+	// VerifyObjectCRC checks the CRC of the packed objects every time
+	// they are read from a packfile, so on-disk corruption is reported
+	// as packfile.ErrCRCMismatch instead of producing invalid objects.
+	// Defaults to false
+	VerifyObjectCRC bool
+	// Limits contains the limits enforced when reading objects from
+	// the odb, which should be set when the objects may come from an
+	// untrusted source (see object.UntrustedLimits()).
+	// Limits.MaxDeltaDepth and Limits.MaxObjectSize are enforced when
+	// reading the objects, the other limits are available to the
+	// parsers using Limits().
+	// Defaults to no limits
+	Limits object.Limits
 }
 
 // NewFS returns a new Backend object using the local FileSystem
 func NewFS(cfg *config.Config) (*Backend, error) {
-	return New(cfg, afero.NewOsFs())
+	return New(cfg, pathutil.NewOsFs())
 }
 
 // New returns a new Backend object
 func New(cfg *config.Config, fs afero.Fs) (*Backend, error) {
-	c, err := cache.NewLRU(1000)
-	if err != nil {
-		return nil, fmt.Errorf("could not create LRU cache: %w", err)
+	return NewWithOptions(cfg, fs, Options{})
+}
+
+// NewWithOptions returns a new Backend object using the provided
+// options
+func NewWithOptions(cfg *config.Config, fs afero.Fs, opts Options) (*Backend, error) {
+	start := time.Now()
+	if opts.ScanIgnore == nil {
+		opts.ScanIgnore = DefaultScanIgnore
+	}
+	if err := validateScanIgnore(opts.ScanIgnore); err != nil {
+		return nil, err
+	}
+	if opts.Layout == nil {
+		opts.Layout = ginternals.FSLayout{}
+	}
+
+	var c *cache.LRU
+	if opts.CacheSize >= 0 {
+		if opts.CacheSize == 0 {
+			opts.CacheSize = DefaultCacheSize
+		}
+		var err error
+		c, err = cache.NewLRUWithBudget(maxCachedObjects, opts.CacheSize)
+		if err != nil {
+			return nil, fmt.Errorf("could not create LRU cache: %w", err)
+		}
 	}
 	b := &Backend{
 		config:       cfg,
@@ -47,7 +180,18 @@ func New(cfg *config.Config, fs afero.Fs) (*Backend, error) {
 		objectMu:     syncutil.NewNamedMutex(101),
 		packfiles:    map[ginternals.Oid]*packfile.Pack{},
 		refs:         &sync.Map{},
+		peeled:       &sync.Map{},
 		looseObjects: &sync.Map{},
+		objectCache:  opts.ObjectCache,
+		indexer:      opts.Indexer,
+		layout:       opts.Layout,
+		scanIgnore:   opts.ScanIgnore,
+		staleLockAge: opts.StaleLockAge,
+		verifyPacks:  opts.VerifyPacks,
+		mmapPacks:    opts.MmapPacks,
+		verifyCRC:    opts.VerifyObjectCRC,
+		limits:       opts.Limits,
+		logger:       cfg.Logger,
 	}
 
 	// we load a few things in memory
@@ -93,24 +237,63 @@ func New(cfg *config.Config, fs afero.Fs) (*Backend, error) {
 		return nil, fmt.Errorf("could not load config: %w", loadConfigErr)
 	}
 
+	if b.logger != nil {
+		looseObjects := 0
+		b.looseObjects.Range(func(_, _ interface{}) bool {
+			looseObjects++
+			return true
+		})
+		b.logger.Debug("repository loaded",
+			"path", b.Path(),
+			"loose_objects", looseObjects,
+			"packfiles", len(b.packfiles),
+			"scan_warnings", len(b.scanWarnings),
+			"duration", time.Since(start))
+	}
+	if b.objectCache != nil {
+		b.cacheUploader = newObjectCacheUploader(b.objectCache)
+	}
 	return b, nil
 }
 
 // Close frees the resources used by the Backend
 // This method cannot be called concurrently with other methods
 func (b *Backend) Close() (err error) {
+	if b.cacheUploader != nil {
+		b.cacheUploader.close()
+	}
+
+	b.packfilesMu.Lock()
+	defer b.packfilesMu.Unlock()
+
 	for oid, pack := range b.packfiles {
 		if e := pack.Close(); e != nil {
 			// we don't return directly because we still want to try to
 			// close the other packfiles
-			err = fmt.Errorf("could not close packfile %s: %w", oid.String(), err)
+			err = fmt.Errorf("could not close packfile %s: %w", oid.String(), e)
 		}
 	}
 	b.packfiles = map[ginternals.Oid]*packfile.Pack{}
 	return err
 }
 
+// lockOptions returns the options to use to lock a file of the
+// repository
+func (b *Backend) lockOptions() lockfile.Options {
+	return lockfile.Options{
+		StaleAge:    b.staleLockAge,
+		Perm:        b.filePerm(0o644),
+		IgnoreUmask: b.shared.IsShared(),
+	}
+}
+
 // Path returns the absolute path of the repo
 func (b *Backend) Path() string {
 	return ginternals.DotGitPath(b.config)
 }
+
+// Limits returns the limits enforced when reading the objects of the
+// odb
+func (b *Backend) Limits() object.Limits {
+	return b.limits
+}
@@ -1,11 +1,18 @@
 package backend_test
 
 import (
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
 	"github.com/Nivl/git-go/internal/testutil"
 	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,3 +31,81 @@ func TestPath(t *testing.T) {
 
 	require.Equal(t, cfg.GitDirPath, b.Path())
 }
+
+// flatLayout is a Layout that stores the loose objects at the root of
+// the object directory, and the references in a single directory
+type flatLayout struct{}
+
+func (flatLayout) LooseObjectKey(oid ginternals.Oid) string {
+	return "loose-" + oid.String()
+}
+
+func (flatLayout) LooseObjectID(key string) (ginternals.Oid, bool) {
+	if !strings.HasPrefix(key, "loose-") {
+		return ginternals.NullOid, false
+	}
+	oid, err := ginternals.NewOidFromStr(strings.TrimPrefix(key, "loose-"))
+	return oid, err == nil
+}
+
+func (flatLayout) IsLooseObjectDir(key string) bool {
+	return key == "."
+}
+
+func (flatLayout) RefKey(name string) string {
+	if !strings.HasPrefix(name, "refs/") {
+		return name
+	}
+	return "refs/" + url.PathEscape(strings.TrimPrefix(name, "refs/"))
+}
+
+func (flatLayout) RefName(key string) (string, bool) {
+	if !strings.HasPrefix(key, "refs/") {
+		return key, true
+	}
+	name, err := url.PathUnescape(strings.TrimPrefix(key, "refs/"))
+	return "refs/" + name, err == nil
+}
+
+func TestLayout(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, dir)
+	opts := backend.Options{
+		Layout: flatLayout{},
+	}
+	b, err := backend.NewWithOptions(cfg, afero.NewOsFs(), opts)
+	require.NoError(t, err)
+	require.NoError(t, b.Init(ginternals.Master))
+
+	oid, err := b.WriteObject(object.New(object.TypeBlob, []byte("flat")))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(ginternals.ObjectsPath(cfg), "loose-"+oid.String()))
+
+	refName := "refs/heads/feat/flat"
+	require.NoError(t, b.WriteReference(ginternals.NewReference(refName, oid)))
+	assert.FileExists(t, filepath.Join(b.Path(), "refs", "heads%2Ffeat%2Fflat"))
+	require.NoError(t, b.Close())
+
+	// The data should be found once the repository is reloaded
+	b, err = backend.NewWithOptions(cfg, afero.NewOsFs(), opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	o, err := b.Object(oid)
+	require.NoError(t, err)
+	assert.Equal(t, "flat", string(o.Bytes()))
+
+	ref, err := b.Reference(refName)
+	require.NoError(t, err)
+	assert.Equal(t, oid, ref.Target())
+
+	ref, err = b.UnresolvedReference(ginternals.Head)
+	require.NoError(t, err)
+	assert.Equal(t, ginternals.LocalBranchFullName(ginternals.Master), ref.SymbolicTarget())
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/Nivl/git-go/ginternals"
+)
+
+// IsCaseInsensitive returns whether the repository is stored on a
+// case-insensitive filesystem (like the default filesystems of macOS
+// and Windows).
+// On such filesystems refs/heads/Foo and refs/heads/foo would be
+// stored in the same file, so references that only differ by their
+// case cannot be stored as loose references.
+// false is returned if the case sensitivity couldn't be detected
+func (b *Backend) IsCaseInsensitive() bool {
+	b.caseMu.Lock()
+	defer b.caseMu.Unlock()
+	if b.caseInsensitive != nil {
+		return *b.caseInsensitive
+	}
+
+	// Like git, we check if a file of the repository can be
+	// accessed using a different case. The check is only
+	// conclusive if the file exists
+	for _, name := range []string{"config", ginternals.Head} {
+		if _, err := b.fs.Stat(filepath.Join(b.Path(), name)); err != nil {
+			continue
+		}
+		_, err := b.fs.Stat(filepath.Join(b.Path(), swapCase(name)))
+		insensitive := err == nil
+		b.caseInsensitive = &insensitive
+		return insensitive
+	}
+	return false
+}
+
+// swapCase returns the provided string with the case of all its
+// letters swapped
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// caseConflict returns the name of the reference that would share
+// a file or a directory with the provided reference on a
+// case-insensitive filesystem, if any.
+// An empty string is returned if the filesystem is case sensitive
+func (b *Backend) caseConflict(name string) (string, error) {
+	if !b.IsCaseInsensitive() {
+		return "", nil
+	}
+
+	conflictsOn := ""
+	b.refs.Range(func(key, value interface{}) bool {
+		if refCaseConflict(key.(string), name) {
+			conflictsOn = key.(string)
+			return false
+		}
+		return true
+	})
+	if conflictsOn != "" {
+		return conflictsOn, nil
+	}
+
+	// A loose reference that only differs by its case from a packed
+	// reference would shadow it
+	err := b.walkPackedReferences(func(refName string, target []byte) error {
+		if refName != name && strings.EqualFold(refName, name) {
+			conflictsOn = refName
+			return WalkStop
+		}
+		return nil
+	})
+	if err != nil && err != WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+		return "", err
+	}
+	return conflictsOn, nil
+}
+
+// refCaseConflict returns whether the 2 references would share a
+// file or a directory on a case-insensitive filesystem while having
+// a different name.
+// That's the case if the first segment that differs between the two
+// names only differs by its case.
+// Ex. refs/heads/Foo and refs/heads/foo, or refs/heads/Feat/a and
+// refs/heads/feat/b
+func refCaseConflict(a, b string) bool {
+	segmentsA := strings.Split(a, "/")
+	segmentsB := strings.Split(b, "/")
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if segmentsA[i] == segmentsB[i] {
+			continue
+		}
+		return strings.EqualFold(segmentsA[i], segmentsB[i])
+	}
+	return false
+}
+
+// checkCaseConflict returns an error wrapping ErrRefCaseConflict if
+// the provided reference cannot be stored as a loose reference
+func (b *Backend) checkCaseConflict(name string) error {
+	conflictsOn, err := b.caseConflict(name)
+	if err != nil {
+		return fmt.Errorf("could not check for case conflicts: %w", err)
+	}
+	if conflictsOn != "" {
+		return fmt.Errorf("reference %s conflicts with %s: %w", name, conflictsOn, ginternals.ErrRefCaseConflict)
+	}
+	return nil
+}
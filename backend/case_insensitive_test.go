@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefCaseConflict(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b     string
+		conflict bool
+	}{
+		{a: "refs/heads/foo", b: "refs/heads/foo", conflict: false},
+		{a: "refs/heads/Foo", b: "refs/heads/foo", conflict: true},
+		{a: "refs/heads/Feat/a", b: "refs/heads/feat/b", conflict: true},
+		{a: "refs/heads/feat/a", b: "refs/heads/feat/A", conflict: true},
+		{a: "refs/heads/feat/a", b: "refs/heads/feat/b", conflict: false},
+		{a: "refs/heads/Foo", b: "refs/heads/foo/bar", conflict: true},
+		{a: "refs/heads/foo", b: "refs/heads/foobar", conflict: false},
+		{a: "refs/heads/a/Foo", b: "refs/heads/b/foo", conflict: false},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s-%s", i, tc.a, tc.b), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.conflict, refCaseConflict(tc.a, tc.b))
+			assert.Equal(t, tc.conflict, refCaseConflict(tc.b, tc.a))
+		})
+	}
+}
+
+func TestCaseInsensitiveReferences(t *testing.T) {
+	t.Parallel()
+
+	// newBackend returns a backend of the small repo that behaves as if
+	// it was on a case-insensitive filesystem
+	newBackend := func(t *testing.T) *Backend {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		b, err := NewFS(confutil.NewCommonConfig(t, repoPath))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		// The tests run on case-sensitive filesystems
+		require.False(t, b.IsCaseInsensitive())
+		insensitive := true
+		b.caseInsensitive = &insensitive
+		return b
+	}
+
+	target, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+
+	t.Run("should fail creating a reference conflicting with a loose reference", func(t *testing.T) {
+		t.Parallel()
+
+		b := newBackend(t)
+		err := b.WriteReference(ginternals.NewReference("refs/tags/Lightweight", target))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefCaseConflict), "unexpected error: %v", err)
+
+		_, err = b.Reference("refs/tags/Lightweight")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail creating a reference conflicting with a packed reference", func(t *testing.T) {
+		t.Parallel()
+
+		b := newBackend(t)
+		err := b.WriteReference(ginternals.NewReference("refs/heads/Master", target))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefCaseConflict), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail creating a reference conflicting with a directory", func(t *testing.T) {
+		t.Parallel()
+
+		b := newBackend(t)
+		err := b.WriteReference(ginternals.NewReference("refs/heads/ML/new", target))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefCaseConflict), "unexpected error: %v", err)
+	})
+
+	t.Run("should allow updating existing references", func(t *testing.T) {
+		t.Parallel()
+
+		b := newBackend(t)
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/master", target)))
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/ml/new", target)))
+	})
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// CommitGraph returns the commit-graph of the repository.
+// commitgraph.ErrNoGraph is returned if the repository doesn't have
+// a commit-graph
+func (b *Backend) CommitGraph() (*commitgraph.Graph, error) {
+	return commitgraph.Open(b.fs, ginternals.ObjectsInfoPath(b.config))
+}
+
+// WriteCommitGraph adds all the commits reachable from the references
+// of the repository to its commit-graph.
+// With opts.Split, only the commits that are not already in the
+// graph are written in a new layer of the chain, otherwise the whole
+// graph is rewritten.
+// The new commit-graph is returned
+func (b *Backend) WriteCommitGraph(opts commitgraph.WriteOptions) (*commitgraph.Graph, error) {
+	current, err := b.CommitGraph()
+	if err != nil {
+		if !errors.Is(err, commitgraph.ErrNoGraph) {
+			return nil, fmt.Errorf("could not load the current commit-graph: %w", err)
+		}
+		current = nil
+	}
+
+	// We first get all the commits targeted by a reference
+	queue := []ginternals.Oid{}
+	err = b.WalkReferences(func(ref *ginternals.Reference) error {
+		if ref.Target().IsZero() {
+			return nil
+		}
+		oid, err := b.Peel(ref.Target())
+		if err != nil {
+			return fmt.Errorf("could not peel %s: %w", ref.Name(), err)
+		}
+		queue = append(queue, oid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the references: %w", err)
+	}
+
+	// Then we walk the history until we reach commits that are
+	// already in the graph
+	commits := []*commitgraph.Commit{}
+	seen := map[ginternals.Oid]struct{}{}
+	for len(queue) > 0 {
+		oid := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if _, ok := seen[oid]; ok {
+			continue
+		}
+		seen[oid] = struct{}{}
+		if current != nil && current.HasCommit(oid) {
+			continue
+		}
+
+		o, err := b.Object(oid)
+		if err != nil {
+			return nil, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		// references may target other objects, like blobs or trees
+		if o.Type() != object.TypeCommit {
+			continue
+		}
+		c, err := o.AsCommit()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse commit %s: %w", oid.String(), err)
+		}
+		commits = append(commits, &commitgraph.Commit{
+			ID:         c.ID(),
+			TreeID:     c.TreeID(),
+			ParentIDs:  c.ParentIDs(),
+			CommitTime: c.Committer().Time.Unix(),
+		})
+		queue = append(queue, c.ParentIDs()...)
+	}
+
+	if !opts.Shared.IsShared() {
+		opts.Shared = b.shared
+	}
+	g, err := commitgraph.Write(b.fs, ginternals.ObjectsInfoPath(b.config), current, commits, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not write the commit-graph: %w", err)
+	}
+	return g, nil
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCommitGraph(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	head, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	// checkGraph checks that the graph contains all the commits of
+	// the repo
+	checkGraph := func(t *testing.T, g *commitgraph.Graph) {
+		t.Helper()
+
+		// generated using git rev-list --all | wc -l
+		assert.Equal(t, 34, g.Len())
+		c, err := g.Commit(head)
+		require.NoError(t, err)
+		assert.Equal(t, "e5b9e846e1b468bc9597ff95d71dfacda8bd54e3", c.TreeID.String())
+		require.Len(t, c.ParentIDs, 1)
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", c.ParentIDs[0].String())
+		assert.Equal(t, int64(1592616250), c.CommitTime)
+		assert.Equal(t, uint32(17), c.Generation)
+	}
+
+	// The repo contains a commit-graph generated by git
+	g, err := b.CommitGraph()
+	require.NoError(t, err)
+	require.Len(t, g.Layers(), 1)
+	checkGraph(t, g)
+
+	// The single file should be converted to a chain
+	g, err = b.WriteCommitGraph(commitgraph.WriteOptions{Split: true})
+	require.NoError(t, err)
+	require.Len(t, g.Layers(), 1)
+	checkGraph(t, g)
+	_, err = os.Stat(filepath.Join(ginternals.ObjectsInfoPath(cfg), commitgraph.GraphFileName))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "the single file should have been removed")
+
+	// Nothing changed, so the graph should remain the same
+	g2, err := b.WriteCommitGraph(commitgraph.WriteOptions{Split: true})
+	require.NoError(t, err)
+	require.Len(t, g2.Layers(), 1)
+	assert.Equal(t, g.Layers()[0].ID(), g2.Layers()[0].ID())
+
+	g, err = b.CommitGraph()
+	require.NoError(t, err)
+	checkGraph(t, g)
+}
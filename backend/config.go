@@ -11,7 +11,14 @@ import (
 	"github.com/spf13/afero"
 )
 
+// loadConfig loads the options of the config that are used by the
+// backend
 func (b *Backend) loadConfig() error {
+	shared, _, err := b.config.FromFile().SharedRepository()
+	if err != nil {
+		return err
+	}
+	b.shared = shared
 	return nil
 }
 
@@ -21,6 +28,13 @@ type InitOptions struct {
 	// CreateSymlink will create a .git FILE that will contains a path
 	// to the repo.
 	CreateSymlink bool
+	// TemplateDir contains the path of a directory whose content is
+	// copied into the new repository (hooks, info/exclude,
+	// description, etc.). Nothing is copied if empty
+	TemplateDir string
+	// Shared sets how the repository is shared between several
+	// users. The value is saved in core.sharedRepository
+	Shared config.SharedRepository
 }
 
 // Init initializes a repository.
@@ -37,6 +51,11 @@ func (b *Backend) Init(branchName string) error {
 // Calling this method on an existing repository is safe. It will not
 // overwrite things that are already there, but will add what's missing.
 func (b *Backend) InitWithOptions(branchName string, opts InitOptions) error {
+	// The repository may already be shared through its config
+	if opts.Shared.IsShared() {
+		b.shared = opts.Shared
+	}
+
 	if opts.CreateSymlink {
 		linkSource := filepath.Join(b.config.WorkTreePath, config.DefaultDotGitDirName)
 		linkTarget := fmt.Sprintf("gitdir: %s", ginternals.DotGitPath(b.config))
@@ -60,10 +79,19 @@ func (b *Backend) InitWithOptions(branchName string, opts InitOptions) error {
 		if err := b.fs.MkdirAll(d, 0o750); err != nil {
 			return fmt.Errorf("could not create directory %s: %w", d, err)
 		}
+		if err := chmodShared(b.fs, b.shared, d, b.shared.DirPerm(0o750)); err != nil {
+			return err
+		}
+	}
+
+	if opts.TemplateDir != "" {
+		if err := b.copyTemplate(opts.TemplateDir); err != nil {
+			return err
+		}
 	}
 
-	// Create the files with the default content if they don't already exist
-	// (taken from a repo created on github)
+	// Create the files with the default content (taken from a repo
+	// created on github), unless the template provides them
 	files := []struct {
 		path    string
 		content []byte
@@ -74,15 +102,25 @@ func (b *Backend) InitWithOptions(branchName string, opts InitOptions) error {
 		},
 	}
 	for _, f := range files {
-		err := afero.WriteFile(b.fs, f.path, f.content, 0o644)
+		if b.templateHasFile(opts.TemplateDir, f.path) {
+			continue
+		}
+		err := afero.WriteFile(b.fs, f.path, f.content, b.filePerm(0o644))
 		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("could not create file %s: %w", f.path, err)
 		}
+		if err := b.chmod(f.path, 0o644); err != nil {
+			return err
+		}
 	}
 
-	// We only create a config file if we don't already have one
+	// We only create a config file if we don't already have one, unless
+	// we need to update it
 	_, err := b.fs.Stat(b.config.LocalConfig)
-	if errors.Is(err, os.ErrNotExist) {
+	if errors.Is(err, os.ErrNotExist) || opts.Shared.IsShared() {
+		if opts.Shared.IsShared() {
+			b.config.FromFile().UpdateSharedRepository(opts.Shared)
+		}
 		if err = b.config.FromFile().Save(); err != nil {
 			return fmt.Errorf("could not save the config: %w", err)
 		}
@@ -17,6 +17,27 @@ const (
 	CfgCorePrecomposeUnicode = "precomposeunicode"
 )
 
+// tmpObjectPrefix is the prefix of the temporary files used to write
+// the objects. This is the same prefix as git, which allows
+// git gc to remove the files left behind by a crash
+const tmpObjectPrefix = "tmp_obj_"
+
+// tmpPackPrefix is the prefix of the temporary files used to write
+// the packfiles and their indexes. This is the same prefix as git
+const tmpPackPrefix = "tmp_pack_"
+
+// looseObjectHeaderMaxSize is the maximum size of the header of a
+// loose object: its type, a space, its size, and a NULL char
+const looseObjectHeaderMaxSize = 32
+
+// DefaultCacheSize is the default maximum number of bytes of
+// decompressed objects kept in memory by a Backend
+const DefaultCacheSize = 96 * 1024 * 1024
+
+// maxCachedObjects is the maximum number of objects kept in memory
+// by a Backend, regardless of their size
+const maxCachedObjects = 1000
+
 // RefWalkFunc represents a function that will be applied on all references
 // found by Walk()
 type RefWalkFunc = func(ref *ginternals.Reference) error
@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+)
+
+// ErrIndexFailed is an error thrown when the data have been persisted
+// but the Indexer of the backend failed to process them
+var ErrIndexFailed = errors.New("could not index data")
+
+// Indexer represents an external index that needs to be kept in sync
+// with the repository, like a full-text code search engine.
+// The methods are called synchronously once the data have been
+// persisted. If a method fails, the write that triggered it returns
+// an error wrapping ErrIndexFailed.
+// Implementations must be safe for concurrent use
+type Indexer interface {
+	// IndexObject is called when an object is added to the odb,
+	// either as a loose object, or as part of a packfile added using
+	// AddPackfile() or Quarantine.Migrate().
+	// It is not called for objects that were already in the odb, so
+	// repacking the objects doesn't trigger any calls
+	IndexObject(o *object.Object) error
+	// IndexReference is called when a reference is created or
+	// updated
+	IndexReference(ref *ginternals.Reference) error
+	// RemoveReference is called when a reference is deleted
+	RemoveReference(name string) error
+}
+
+// indexObject sends the given object to the indexer of the backend,
+// if any
+func (b *Backend) indexObject(o *object.Object) error {
+	if b.indexer == nil {
+		return nil
+	}
+	if err := b.indexer.IndexObject(o); err != nil {
+		return fmt.Errorf("object %s: %v: %w", o.ID().String(), err, ErrIndexFailed) //nolint:errorlint // we can only wrap one error
+	}
+	return nil
+}
+
+// newPackedObjects returns the oids of the objects of the given
+// packfile that are not in the odb yet. Nothing is returned if the
+// backend doesn't have an indexer.
+// This needs to be called before the packfile is made available
+func (b *Backend) newPackedObjects(pack *packfile.Pack) ([]ginternals.Oid, error) {
+	if b.indexer == nil {
+		return nil, nil
+	}
+	oids := []ginternals.Oid{}
+	err := pack.WalkOids(func(oid ginternals.Oid) error {
+		found, err := b.HasObject(oid)
+		if err != nil {
+			return fmt.Errorf("could not check if object %s exists: %w", oid.String(), err)
+		}
+		if !found {
+			oids = append(oids, oid)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the objects of %s: %w", pack.Path(), err)
+	}
+	return oids, nil
+}
+
+// indexObjects sends the objects that have the given oids to the
+// indexer of the backend, if any
+func (b *Backend) indexObjects(oids []ginternals.Oid) error {
+	if b.indexer == nil {
+		return nil
+	}
+	for _, oid := range oids {
+		o, err := b.Object(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		if err = b.indexObject(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexReference sends the given reference to the indexer of the
+// backend, if any
+func (b *Backend) indexReference(ref *ginternals.Reference) error {
+	if b.indexer == nil {
+		return nil
+	}
+	if err := b.indexer.IndexReference(ref); err != nil {
+		return fmt.Errorf("reference %s: %v: %w", ref.Name(), err, ErrIndexFailed) //nolint:errorlint // we can only wrap one error
+	}
+	return nil
+}
+
+// removeIndexedReference tells the indexer of the backend, if any,
+// that the given reference has been removed
+func (b *Backend) removeIndexedReference(name string) error {
+	if b.indexer == nil {
+		return nil
+	}
+	if err := b.indexer.RemoveReference(name); err != nil {
+		return fmt.Errorf("reference %s: %v: %w", name, err, ErrIndexFailed) //nolint:errorlint // we can only wrap one error
+	}
+	return nil
+}
+
+// IndexAll sends all the objects and references of the repository
+// to the provided indexer. This is meant to be used to build the
+// initial index of a repository, the indexer of the backend can then
+// be used to keep it up to date.
+// Objects are sent one at a time, in no particular order, before the
+// references.
+// This method can be called concurrently
+func (b *Backend) IndexAll(idx Indexer) error {
+	seen := map[ginternals.Oid]struct{}{}
+	indexObject := func(oid ginternals.Oid) error {
+		// an object may be in multiple packs
+		if _, ok := seen[oid]; ok {
+			return nil
+		}
+		seen[oid] = struct{}{}
+
+		o, err := b.Object(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		if err = idx.IndexObject(o); err != nil {
+			return fmt.Errorf("object %s: %v: %w", oid.String(), err, ErrIndexFailed) //nolint:errorlint // we can only wrap one error
+		}
+		return nil
+	}
+	if err := b.WalkLooseObjectIDs(indexObject); err != nil {
+		return fmt.Errorf("could not index the loose objects: %w", err)
+	}
+	if err := b.WalkPackedObjectIDs(indexObject); err != nil {
+		return fmt.Errorf("could not index the packed objects: %w", err)
+	}
+
+	err := b.WalkReferences(func(ref *ginternals.Reference) error {
+		if err := idx.IndexReference(ref); err != nil {
+			return fmt.Errorf("reference %s: %v: %w", ref.Name(), err, ErrIndexFailed) //nolint:errorlint // we can only wrap one error
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not index the references: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIndexer is an in-memory Indexer that keeps track of the data
+// it received
+type fakeIndexer struct {
+	mu      sync.Mutex
+	objects map[ginternals.Oid]int
+	refs    map[string]*ginternals.Reference
+	removed []string
+	err     error
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{
+		objects: map[ginternals.Oid]int{},
+		refs:    map[string]*ginternals.Reference{},
+	}
+}
+
+func (idx *fakeIndexer) IndexObject(o *object.Object) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.err != nil {
+		return idx.err
+	}
+	idx.objects[o.ID()]++
+	return nil
+}
+
+func (idx *fakeIndexer) IndexReference(ref *ginternals.Reference) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.err != nil {
+		return idx.err
+	}
+	idx.refs[ref.Name()] = ref
+	return nil
+}
+
+func (idx *fakeIndexer) RemoveReference(name string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.err != nil {
+		return idx.err
+	}
+	delete(idx.refs, name)
+	idx.removed = append(idx.removed, name)
+	return nil
+}
+
+func TestIndexer(t *testing.T) {
+	t.Parallel()
+
+	// newBackend returns a backend of the small repo using the
+	// provided indexer
+	newBackend := func(t *testing.T, idx Indexer) *Backend {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{Indexer: idx})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		return b
+	}
+
+	t.Run("new objects should be indexed", func(t *testing.T) {
+		t.Parallel()
+
+		idx := newFakeIndexer()
+		b := newBackend(t, idx)
+
+		o := object.New(object.TypeBlob, []byte("new blob"))
+		_, err := b.WriteObject(o)
+		require.NoError(t, err)
+		assert.Equal(t, 1, idx.objects[o.ID()])
+
+		// writing the same object should be a no-op
+		_, err = b.WriteObject(o)
+		require.NoError(t, err)
+		assert.Equal(t, 1, idx.objects[o.ID()])
+
+		// existing objects should not be indexed
+		oid, err := ginternals.NewOidFromStr("642480605b8b0fd464ab5762e044269cf29a60a3")
+		require.NoError(t, err)
+		blob, err := b.Object(oid)
+		require.NoError(t, err)
+		_, err = b.WriteObject(blob)
+		require.NoError(t, err)
+		assert.Len(t, idx.objects, 1)
+	})
+
+	t.Run("new packed objects should be indexed", func(t *testing.T) {
+		t.Parallel()
+
+		idx := newFakeIndexer()
+		b := newBackend(t, idx)
+
+		existingID, err := ginternals.NewOidFromStr("642480605b8b0fd464ab5762e044269cf29a60a3")
+		require.NoError(t, err)
+		existing, err := b.Object(existingID)
+		require.NoError(t, err)
+		o := object.New(object.TypeBlob, []byte("new packed blob"))
+		built, err := packfile.Build([]*packfile.BuildObject{
+			{Object: o},
+			{Object: existing},
+		}, packfile.BuildOptions{})
+		require.NoError(t, err)
+		_, err = b.AddPackfile(built.Pack, built.Index)
+		require.NoError(t, err)
+		assert.Equal(t, map[ginternals.Oid]int{o.ID(): 1}, idx.objects)
+
+		// Repacking doesn't add any objects
+		loose := object.New(object.TypeBlob, []byte("new loose blob"))
+		_, err = b.WriteObject(loose)
+		require.NoError(t, err)
+		_, err = b.Repack(RepackOptions{All: true, RemoveRedundant: true})
+		require.NoError(t, err)
+		assert.Equal(t, map[ginternals.Oid]int{o.ID(): 1, loose.ID(): 1}, idx.objects)
+	})
+
+	t.Run("migrated objects should be indexed", func(t *testing.T) {
+		t.Parallel()
+
+		idx := newFakeIndexer()
+		b := newBackend(t, idx)
+
+		q, err := b.NewQuarantine()
+		require.NoError(t, err)
+		loose, err := q.Backend().WriteObject(object.New(object.TypeBlob, []byte("loose")))
+		require.NoError(t, err)
+		packed := object.New(object.TypeBlob, []byte("packed"))
+		built, err := packfile.Build([]*packfile.BuildObject{
+			{Object: packed},
+		}, packfile.BuildOptions{})
+		require.NoError(t, err)
+		_, err = q.Backend().AddPackfile(built.Pack, built.Index)
+		require.NoError(t, err)
+		assert.Empty(t, idx.objects, "quarantined objects should not be indexed")
+
+		require.NoError(t, q.Migrate())
+		assert.Equal(t, map[ginternals.Oid]int{loose: 1, packed.ID(): 1}, idx.objects)
+	})
+
+	t.Run("references should be indexed", func(t *testing.T) {
+		t.Parallel()
+
+		idx := newFakeIndexer()
+		b := newBackend(t, idx)
+
+		target, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/indexed", target)))
+		require.NoError(t, b.WriteReferenceSafe(ginternals.NewSymbolicReference("refs/heads/symbolic", "refs/heads/indexed")))
+		require.NoError(t, b.WritePseudoReference(ginternals.OrigHead, target))
+
+		require.Len(t, idx.refs, 3)
+		assert.Equal(t, target, idx.refs["refs/heads/indexed"].Target())
+		assert.Equal(t, "refs/heads/indexed", idx.refs["refs/heads/symbolic"].SymbolicTarget())
+		assert.Equal(t, target, idx.refs[ginternals.OrigHead].Target())
+
+		require.NoError(t, b.DeletePseudoReference(ginternals.OrigHead))
+		assert.Equal(t, []string{ginternals.OrigHead}, idx.removed)
+		assert.Len(t, idx.refs, 2)
+	})
+
+	t.Run("indexer errors should be returned", func(t *testing.T) {
+		t.Parallel()
+
+		idx := newFakeIndexer()
+		idx.err = errors.New("index is down")
+		b := newBackend(t, idx)
+
+		o := object.New(object.TypeBlob, []byte("new blob"))
+		_, err := b.WriteObject(o)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrIndexFailed), "unexpected error: %v", err)
+
+		// the object should have been persisted anyway
+		found, err := b.HasObject(o.ID())
+		require.NoError(t, err)
+		assert.True(t, found)
+
+		target, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		err = b.WriteReference(ginternals.NewReference("refs/heads/indexed", target))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrIndexFailed), "unexpected error: %v", err)
+		_, err = b.Reference("refs/heads/indexed")
+		require.NoError(t, err)
+	})
+
+	t.Run("IndexAll should index the whole repository", func(t *testing.T) {
+		t.Parallel()
+
+		b := newBackend(t, nil)
+		idx := newFakeIndexer()
+		require.NoError(t, b.IndexAll(idx))
+
+		// generated using
+		// git cat-file --batch-all-objects --batch-check | wc -l
+		assert.Len(t, idx.objects, 366)
+		for oid, count := range idx.objects {
+			assert.Equal(t, 1, count, "%s has been indexed multiple times", oid.String())
+		}
+		// HEAD, ORIG_HEAD, FETCH_HEAD, and the 11 references of refs/
+		assert.Len(t, idx.refs, 14)
+		assert.Contains(t, idx.refs, "refs/heads/master")
+	})
+}
@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ObjectCache represents a cache that can be shared between multiple
+// repositories (for example by a fleet of CI runners) to retrieve
+// objects that are not available locally.
+// Implementations must be safe for concurrent use
+type ObjectCache interface {
+	// GetObjects returns the objects matching the provided oids.
+	// Objects that are not in the cache are omitted from the result
+	GetObjects(oids []ginternals.Oid) ([]*object.Object, error)
+	// PutObjects adds the provided objects to the cache
+	PutObjects(objects []*object.Object) error
+}
+
+// objectFromCache looks for an object in the shared object cache.
+// ginternals.ErrObjectNotFound is returned if the object is not in
+// the cache, or if the cache could not be reached
+func (b *Backend) objectFromCache(oid ginternals.Oid) (*object.Object, error) {
+	if b.objectCache == nil {
+		return nil, ginternals.ErrObjectNotFound
+	}
+
+	objects, err := b.objectCache.GetObjects([]ginternals.Oid{oid})
+	// The cache is only an optimization, so we treat any failure as
+	// a cache miss
+	if err != nil {
+		return nil, ginternals.ErrObjectNotFound
+	}
+	for _, o := range objects {
+		// We don't trust the cache and make sure we got what we asked
+		if o.ID() == oid {
+			return o, nil
+		}
+	}
+	return nil, ginternals.ErrObjectNotFound
+}
+
+// addToObjectCache queues the given object to be sent to the shared
+// object cache. The object is sent in the background, so writing an
+// object never waits for the cache
+func (b *Backend) addToObjectCache(o *object.Object) {
+	if b.cacheUploader == nil {
+		return
+	}
+	b.cacheUploader.add(o)
+}
+
+// List of the limits of the objectCacheUploader
+const (
+	// objectCacheQueueSize is the maximum number of objects waiting
+	// to be sent to the shared object cache. The objects written
+	// while the queue is full are not sent
+	objectCacheQueueSize = 4096
+	// objectCacheBatchSize is the maximum number of objects sent to
+	// the shared object cache in a single request
+	objectCacheBatchSize = 256
+)
+
+// objectCacheUploader sends objects to a shared object cache in the
+// background, in batches.
+// Sending objects is best-effort: the errors are ignored, and the
+// objects are dropped if the queue is full, since the cache is only
+// an optimization
+type objectCacheUploader struct {
+	cache ObjectCache
+	queue chan *object.Object
+	done  chan struct{}
+
+	// mu prevents objects from being added to a closed queue
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newObjectCacheUploader returns an objectCacheUploader sending
+// objects to the given cache until close() is called
+func newObjectCacheUploader(cache ObjectCache) *objectCacheUploader {
+	u := &objectCacheUploader{
+		cache: cache,
+		queue: make(chan *object.Object, objectCacheQueueSize),
+		done:  make(chan struct{}),
+	}
+	go u.run()
+	return u
+}
+
+// add queues the given object. Nothing happens if the queue is full
+// or if the uploader has been closed
+func (u *objectCacheUploader) add(o *object.Object) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.closed {
+		return
+	}
+	select {
+	case u.queue <- o:
+	default:
+	}
+}
+
+// run sends the queued objects to the cache until the queue is
+// closed. All the objects queued while a request is in flight are
+// sent together in the next request
+func (u *objectCacheUploader) run() {
+	defer close(u.done)
+	for o := range u.queue {
+		batch := []*object.Object{o}
+	fill:
+		for len(batch) < objectCacheBatchSize {
+			select {
+			case o, ok := <-u.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, o)
+			default:
+				break fill
+			}
+		}
+		u.cache.PutObjects(batch) //nolint:errcheck // the cache is only an optimization
+	}
+}
+
+// close waits for the queued objects to be sent, and stops the
+// uploader. This method can be called multiple times
+func (u *objectCacheUploader) close() {
+	u.mu.Lock()
+	if !u.closed {
+		u.closed = true
+		close(u.queue)
+	}
+	u.mu.Unlock()
+	<-u.done
+}
+
+// PrefetchObjects retrieves, in a single batch, the objects that are
+// missing from the local odb from the shared object cache, and keeps
+// them in memory.
+// Nothing happens if the Backend has no object cache.
+// This method can be called concurrently
+func (b *Backend) PrefetchObjects(oids []ginternals.Oid) error {
+	if b.objectCache == nil || b.cache == nil {
+		return nil
+	}
+
+	missing := make([]ginternals.Oid, 0, len(oids))
+	for _, oid := range oids {
+		found, err := b.hasLocalObject(oid)
+		if err != nil {
+			return err
+		}
+		if !found {
+			missing = append(missing, oid)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	objects, err := b.objectCache.GetObjects(missing)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		b.cache.AddWithSize(o.ID(), o, len(o.Bytes()))
+	}
+	return nil
+}
+
+// hasLocalObject returns whether an object is available without
+// having to query the shared object cache
+func (b *Backend) hasLocalObject(oid ginternals.Oid) (bool, error) {
+	key := oid[:]
+	b.objectMu.Lock(key)
+	defer b.objectMu.Unlock(key)
+
+	if _, found := b.cache.Get(oid); found {
+		return true, nil
+	}
+	if _, found := b.looseObjects.Load(oid); found {
+		return true, nil
+	}
+	_, err := b.objectFromPackfile(oid)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, ginternals.ErrObjectNotFound) {
+		return false, nil
+	}
+	return false, fmt.Errorf("could not look for object %s: %w", oid.String(), err)
+}
@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeObjectCache is an in-memory ObjectCache that keeps track of
+// the requests it received
+type fakeObjectCache struct {
+	mu      sync.Mutex
+	objects map[ginternals.Oid]*object.Object
+	gets    int
+	puts    int
+	err     error
+	// unblock, if set, makes PutObjects wait until it's closed
+	unblock chan struct{}
+}
+
+func newFakeObjectCache(objects ...*object.Object) *fakeObjectCache {
+	c := &fakeObjectCache{
+		objects: map[ginternals.Oid]*object.Object{},
+	}
+	for _, o := range objects {
+		c.objects[o.ID()] = o
+	}
+	return c
+}
+
+func (c *fakeObjectCache) GetObjects(oids []ginternals.Oid) ([]*object.Object, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	if c.err != nil {
+		return nil, c.err
+	}
+	res := []*object.Object{}
+	for _, oid := range oids {
+		if o, ok := c.objects[oid]; ok {
+			res = append(res, o)
+		}
+	}
+	return res, nil
+}
+
+func (c *fakeObjectCache) PutObjects(objects []*object.Object) error {
+	if c.unblock != nil {
+		<-c.unblock
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.puts++
+	if c.err != nil {
+		return c.err
+	}
+	for _, o := range objects {
+		c.objects[o.ID()] = o
+	}
+	return nil
+}
+
+func TestObjectCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing objects should be fetched from the cache", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		remoteObject := object.New(object.TypeBlob, []byte("only in the cache"))
+		c := newFakeObjectCache(remoteObject)
+
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{ObjectCache: c})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		o, err := b.Object(remoteObject.ID())
+		require.NoError(t, err)
+		assert.Equal(t, remoteObject.Bytes(), o.Bytes())
+
+		// local objects should not hit the cache
+		oid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		_, err = b.Object(oid)
+		require.NoError(t, err)
+		assert.Equal(t, 1, c.gets)
+	})
+
+	t.Run("unreachable cache should be treated as a miss", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		c := newFakeObjectCache()
+		c.err = errors.New("connection refused")
+
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{ObjectCache: c})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		_, err = b.Object(ginternals.NewOidFromContent([]byte("nope")))
+		require.ErrorIs(t, err, ginternals.ErrObjectNotFound)
+
+		// writing should still work
+		_, err = b.WriteObject(object.New(object.TypeBlob, []byte("new blob")))
+		require.NoError(t, err)
+	})
+
+	t.Run("written objects should be sent to the cache", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		c := newFakeObjectCache()
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{ObjectCache: c})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		o := object.New(object.TypeBlob, []byte("new blob"))
+		_, err = b.WriteObject(o)
+		require.NoError(t, err)
+		// Close waits for the objects to be sent
+		require.NoError(t, b.Close())
+		assert.Contains(t, c.objects, o.ID())
+	})
+
+	t.Run("writing should not wait for the cache", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		c := newFakeObjectCache()
+		c.unblock = make(chan struct{})
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{ObjectCache: c})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		// The cache blocks until unblock is closed, so this would
+		// hang if the objects were sent synchronously
+		objects := make([]*object.Object, 0, 10)
+		for i := 0; i < 10; i++ {
+			o := object.New(object.TypeBlob, []byte(fmt.Sprintf("blob %d", i)))
+			_, err = b.WriteObject(o)
+			require.NoError(t, err)
+			objects = append(objects, o)
+		}
+
+		close(c.unblock)
+		require.NoError(t, b.Close())
+		for _, o := range objects {
+			assert.Contains(t, c.objects, o.ID())
+		}
+		// The objects queued while a request is in flight are
+		// batched together
+		assert.Less(t, c.puts, len(objects))
+	})
+
+	t.Run("PrefetchObjects should only request missing objects in one batch", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		remote1 := object.New(object.TypeBlob, []byte("remote 1"))
+		remote2 := object.New(object.TypeBlob, []byte("remote 2"))
+		c := newFakeObjectCache(remote1, remote2)
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{ObjectCache: c})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		local, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		err = b.PrefetchObjects([]ginternals.Oid{local, remote1.ID(), remote2.ID()})
+		require.NoError(t, err)
+		assert.Equal(t, 1, c.gets)
+
+		// the objects should now be served from memory
+		_, err = b.Object(remote1.ID())
+		require.NoError(t, err)
+		_, err = b.Object(remote2.ID())
+		require.NoError(t, err)
+		assert.Equal(t, 1, c.gets)
+	})
+}
@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/internal/zlibutil"
+)
+
+// looseHeaderMaxSize is the maximum size of the header of a loose
+// object: the longest type, a space, a 64 bits int, and a NULL char
+const looseHeaderMaxSize = len("commit") + 1 + 20 + 1
+
+// ObjectInfo returns the type and the size of the object that has the
+// given oid, without loading the object.
+// Only the header of a loose object is decompressed, and only the
+// headers of a packed object (and of its delta chain) are read, which
+// is much cheaper than calling Object() on large objects.
+// This method can be called concurrently
+func (b *Backend) ObjectInfo(oid ginternals.Oid) (object.Type, int, error) {
+	if b.cache != nil {
+		if cachedO, found := b.cache.Get(oid); found {
+			if o, valid := cachedO.(*object.Object); valid {
+				return o.Type(), o.Size(), nil
+			}
+		}
+	}
+
+	typ, size, err := b.looseObjectInfo(oid)
+	if err == nil {
+		return typ, size, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return 0, 0, fmt.Errorf("failed looking for loose object: %w", err)
+	}
+
+	b.packfilesMu.RLock()
+	for _, pack := range b.packfiles {
+		typ, size, err := pack.ObjectInfo(oid)
+		if err == nil {
+			b.packfilesMu.RUnlock()
+			return typ, int(size), nil
+		}
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			b.packfilesMu.RUnlock()
+			return 0, 0, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+	}
+	b.packfilesMu.RUnlock()
+
+	// The object may only be available remotely, in which case it
+	// has to be downloaded
+	o, err := b.Object(oid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return o.Type(), o.Size(), nil
+}
+
+// looseObjectInfo returns the type and the size of a loose object by
+// only decompressing its header.
+// os.ErrNotExist is returned if the object is not a loose object
+func (b *Backend) looseObjectInfo(oid ginternals.Oid) (typ object.Type, size int, err error) {
+	dir, exists := b.looseObjects.Load(oid)
+	if !exists {
+		return 0, 0, os.ErrNotExist
+	}
+
+	strOid := oid.String()
+	p := b.looseObjectPath(dir.(string), oid)
+	f, err := b.fs.Open(p)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not get object %s at path %s: %w", strOid, p, err)
+	}
+	defer errutil.Close(f, &err)
+
+	zlibReader, err := zlibutil.NewReader(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not decompress parts of object %s at path %s: %w", strOid, p, err)
+	}
+	defer errutil.Close(zlibReader, &err)
+
+	// The header is "{type} {size}\0". Small objects may be shorter
+	// than the max size of a header
+	header := make([]byte, looseHeaderMaxSize)
+	n, err := io.ReadFull(zlibReader, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return 0, 0, fmt.Errorf("could not read object %s at path %s: %w", strOid, p, err)
+	}
+	header = header[:n]
+
+	end := bytes.IndexByte(header, 0)
+	space := bytes.IndexByte(header, ' ')
+	if end == -1 || space == -1 || space > end {
+		return 0, 0, fmt.Errorf("invalid header for object %s at path %s: %w", strOid, p, object.ErrObjectInvalid)
+	}
+	typ, err = object.NewTypeFromString(string(header[:space]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unsupported type %s for object %s at path %s: %w", string(header[:space]), strOid, p, object.ErrObjectInvalid)
+	}
+	size, err = strconv.Atoi(string(header[space+1 : end]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid size %s for object %s at path %s: %w", string(header[space+1:end]), strOid, p, err)
+	}
+	return typ, size, nil
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectInfo(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	testCases := []struct {
+		desc         string
+		oid          string
+		expectedType object.Type
+		expectedErr  error
+	}{
+		{
+			desc:         "loose object",
+			oid:          "b07e28976ac8972715598f390964d53cf4dbc1bd",
+			expectedType: object.TypeBlob,
+		},
+		{
+			desc:         "packed commit",
+			oid:          "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			expectedType: object.TypeCommit,
+		},
+		{
+			desc:         "packed tree",
+			oid:          "e5b9e846e1b468bc9597ff95d71dfacda8bd54e3",
+			expectedType: object.TypeTree,
+		},
+		{
+			desc:         "packed blob",
+			oid:          "642480605b8b0fd464ab5762e044269cf29a60a3",
+			expectedType: object.TypeBlob,
+		},
+		{
+			desc:        "missing object",
+			oid:         "2dcdadc2a420225783794fbffd51e2e137a69646",
+			expectedErr: ginternals.ErrObjectNotFound,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			oid, err := ginternals.NewOidFromStr(tc.oid)
+			require.NoError(t, err)
+
+			typ, size, err := b.ObjectInfo(oid)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			o, err := b.Object(oid)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedType, typ)
+			assert.Equal(t, o.Size(), size)
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"compress/zlib"
 	"errors"
 	"fmt"
 	"io"
@@ -9,12 +8,15 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/Nivl/git-go/ginternals"
 	"github.com/Nivl/git-go/ginternals/object"
 	"github.com/Nivl/git-go/ginternals/packfile"
 	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/internal/fsutil"
 	"github.com/Nivl/git-go/internal/readutil"
+	"github.com/Nivl/git-go/internal/zlibutil"
 	"github.com/spf13/afero"
 )
 
@@ -37,9 +39,16 @@ func (b *Backend) objectUnsafe(oid ginternals.Oid) (*object.Object, error) {
 		}
 	}
 
+	// We only time the lookups if someone is listening
+	var start time.Time
+	if b.logger != nil {
+		start = time.Now()
+	}
+
 	// First let's look for loose objects
 	o, err := b.looseObject(oid)
 	if err == nil {
+		b.logObjectLookup(oid, "loose", start)
 		return o, nil
 	}
 	if !errors.Is(err, os.ErrNotExist) {
@@ -47,28 +56,54 @@ func (b *Backend) objectUnsafe(oid ginternals.Oid) (*object.Object, error) {
 	}
 
 	// Not found? Let's find it in a packfile
+	source := "packfile"
 	o, err = b.objectFromPackfile(oid)
+	if errors.Is(err, ginternals.ErrObjectNotFound) {
+		// Still not found? Let's ask the shared cache
+		source = "object cache"
+		o, err = b.objectFromCache(oid)
+	}
 	if err != nil {
+		if errors.Is(err, ginternals.ErrObjectNotFound) {
+			b.logObjectLookup(oid, "none", start)
+		}
 		return nil, err
 	}
+	b.logObjectLookup(oid, source, start)
 	if b.cache != nil {
-		b.cache.Add(oid, o)
+		b.cache.AddWithSize(oid, o, len(o.Bytes()))
 	}
 	return o, nil
 }
 
+// logObjectLookup emits a debug event for an object that has been
+// looked for in the odb, with where it has been found (loose,
+// packfile, object cache, or none)
+func (b *Backend) logObjectLookup(oid ginternals.Oid, source string, start time.Time) {
+	// We check the logger ourselves to not allocate the arguments
+	// for nothing, since this is called for every object
+	if b.logger == nil {
+		return
+	}
+	b.logger.Debug("object lookup",
+		"oid", oid.String(),
+		"source", source,
+		"duration", time.Since(start))
+}
+
 // looseObject returns the object matching the given OID
 // The format of an object is an ascii encoded type, an ascii encoded
 // space, then an ascii encoded length of the object, then a null
 // character, then the body of the object
 // TODO(melvin): Move to ginternals (NewFromLoose or something)
 func (b *Backend) looseObject(oid ginternals.Oid) (o *object.Object, err error) {
-	if _, exists := b.looseObjects.Load(oid); !exists {
+	dir, exists := b.looseObjects.Load(oid)
+	if !exists {
 		return nil, os.ErrNotExist
 	}
 
 	strOid := oid.String()
-	p := ginternals.LooseObjectPath(b.config, strOid)
+	p := b.looseObjectPath(dir.(string), oid)
 	f, err := b.fs.Open(p)
 	if err != nil {
 		return nil, fmt.Errorf("could not get object %s at path %s: %w", strOid, p, err)
@@ -76,15 +111,21 @@ func (b *Backend) looseObject(oid ginternals.Oid) (o *object.Object, err error)
 	defer errutil.Close(f, &err)
 
 	// Objects are zlib encoded
-	zlibReader, err := zlib.NewReader(f)
+	zlibReader, err := zlibutil.NewReader(f)
 	if err != nil {
 		return nil, fmt.Errorf("could not decompress parts of object %s at path %s: %w", strOid, p, err)
 	}
 	defer errutil.Close(zlibReader, &err)
 
 	// We directly read the entire file since most of it is the content we
-	// need, this allows us to be able to easily store the object's content
-	buff, err := io.ReadAll(zlibReader)
+	// need, this allows us to be able to easily store the object's content.
+	// If the size of the objects is limited, we don't read more than
+	// needed to know the object is too big
+	var r io.Reader = zlibReader
+	if b.limits.MaxObjectSize > 0 {
+		r = io.LimitReader(zlibReader, int64(b.limits.MaxObjectSize)+looseObjectHeaderMaxSize)
+	}
+	buff, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("could not read object %s at path %s: %w", strOid, p, err)
 	}
@@ -96,7 +137,7 @@ func (b *Backend) looseObject(oid ginternals.Oid) (o *object.Object, err error)
 	// space character that we'll need to trim
 	typ := readutil.ReadTo(buff, ' ')
 	if typ == nil {
-		return nil, fmt.Errorf("could not find object type for %s at path %s: %w", strOid, p, err)
+		return nil, fmt.Errorf("could not find object type for %s at path %s: %w", strOid, p, object.ErrObjectInvalid)
 	}
 
 	oType, err := object.NewTypeFromString(string(typ))
@@ -112,31 +153,53 @@ func (b *Backend) looseObject(oid ginternals.Oid) (o *object.Object, err error)
 	// type "man ascii" in a terminal for more information
 	size := readutil.ReadTo(buff[pointerPos:], 0)
 	if size == nil {
-		return nil, fmt.Errorf("could not find object size for %s at path %s: %w", strOid, p, err)
+		return nil, fmt.Errorf("could not find object size for %s at path %s: %w", strOid, p, object.ErrObjectInvalid)
 	}
 	oSize, err := strconv.Atoi(string(size))
 	if err != nil {
 		return nil, fmt.Errorf("invalid size %s for object %s at path %s: %w", size, strOid, p, err)
 	}
+	if err = b.limits.CheckObjectSize(uint64(oSize)); err != nil {
+		return nil, fmt.Errorf("could not read object %s at path %s: %w", strOid, p, err)
+	}
 	pointerPos += len(size)
 	pointerPos++                  // one more for the NULL char
 	oContent := buff[pointerPos:] // sugar
 
 	if len(oContent) != oSize {
-		return nil, fmt.Errorf("object marked as size %d, but has %d at path %s: %w", oSize, len(oContent), p, err)
+		return nil, fmt.Errorf("object marked as size %d, but has %d at path %s: %w", oSize, len(oContent), p, object.ErrObjectInvalid)
 	}
 
 	return object.New(oType, oContent), nil
 }
 
-// loadPacks loads the packfiles in memory
+// objectDirs returns the object directories of the repository. The
+// first one is the one of the repository, the others are the
+// alternates
+func (b *Backend) objectDirs() []string {
+	return append([]string{ginternals.ObjectsPath(b.config)}, b.config.AlternateObjectDirPaths...)
+}
+
+// loadPacks loads the packfiles of all the object directories
+// in memory
 func (b *Backend) loadPacks() error {
-	p := ginternals.ObjectsPacksPath(b.config)
+	for _, dir := range b.objectDirs() {
+		if err := b.loadPacksFrom(filepath.Join(dir, "pack")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPacksFrom loads the packfiles of the given directory in memory
+func (b *Backend) loadPacksFrom(p string) error {
 	return afero.Walk(b.fs, p, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
-			//nolint:nilerr // in case of error we just skip it and move on.
-			// this will happen if the repo is empty and the ./objects/pack
-			// folder doesn't exists
+			// This will happen if the repo is empty and the
+			// ./objects/pack folder doesn't exists
+			if !errors.Is(err, os.ErrNotExist) {
+				b.addScanWarning(path, err)
+			}
 			return nil
 		}
 
@@ -144,6 +207,13 @@ func (b *Backend) loadPacks() error {
 			return nil
 		}
 
+		if b.isScanIgnored(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// There should be no directories, but just in case,
 		// we make sure we don't go in them
 		if info.IsDir() {
@@ -156,21 +226,54 @@ func (b *Backend) loadPacks() error {
 		}
 
 		packFilePath := filepath.Join(p, info.Name())
-		pack, err := packfile.NewFromFile(b.fs, packFilePath)
+		pack, err := packfile.NewFromFileWithOptions(b.fs, packFilePath, packfile.Options{
+			Mmap:      b.mmapPacks,
+			VerifyCRC: b.verifyCRC,
+			Limits:    b.limits,
+			Logger:    b.logger,
+		})
 		if err != nil {
+			// A packfile we cannot read should not prevent us from
+			// using the rest of the repository
+			if errors.Is(err, os.ErrPermission) {
+				b.addScanWarning(packFilePath, err)
+				return nil
+			}
 			return fmt.Errorf("could not parse packfile at %s: %w", packFilePath, err)
 		}
-		b.packfiles[pack.ID()] = pack
-
+		if b.verifyPacks {
+			if err = pack.Verify(); err != nil {
+				pack.Close() //nolint:errcheck // it already failed
+				return fmt.Errorf("could not verify packfile: %w", err)
+			}
+		}
+		b.addPackfile(pack)
 		return nil
 	})
 }
 
+// addPackfile adds the given packfile to the list of packfiles of the
+// repository. The packfile is closed if it's already in the list,
+// which happens when the same packfile is shared by multiple object
+// directories
+func (b *Backend) addPackfile(pack *packfile.Pack) {
+	b.packfilesMu.Lock()
+	defer b.packfilesMu.Unlock()
+
+	if _, ok := b.packfiles[pack.ID()]; ok {
+		pack.Close() //nolint:errcheck // we already have this pack
+		return
+	}
+	b.packfiles[pack.ID()] = pack
+}
+
 // objectFromPackfile looks for an object in the packfiles
 func (b *Backend) objectFromPackfile(oid ginternals.Oid) (*object.Object, error) {
 	// TODO(melvin): parse MIDX files to speed up the process
 	// MIDX file: https://git-scm.com/docs/multi-pack-index
 	// https://github.com/Nivl/git-go/issues/13
+	b.packfilesMu.RLock()
+	defer b.packfilesMu.RUnlock()
 	for _, pack := range b.packfiles {
 		o, err := pack.GetObject(oid)
 		if err == nil {
@@ -228,23 +331,36 @@ func (b *Backend) WriteObject(o *object.Object) (ginternals.Oid, error) {
 
 	// Persist the data on disk
 	sha := o.ID().String()
-	p := ginternals.LooseObjectPath(b.config, sha)
+	p := b.looseObjectPath(ginternals.ObjectsPath(b.config), o.ID())
 
 	// We need to make sure the dest dir exists
 	dest := filepath.Dir(p)
-	if err = b.fs.MkdirAll(dest, 0o755); err != nil {
+	if err = b.mkdirAll(dest, 0o755); err != nil {
 		return ginternals.NullOid, fmt.Errorf("could not create the destination directory %s: %w", dest, err)
 	}
 
+	// The object is first written to a temporary file that is then
+	// renamed, so a crash or a concurrent reader never sees a
+	// partially written object. Like git, the temporary files are
+	// named tmp_obj_XXXXXX so they can be cleaned up if we crash.
 	// We use 444 because git object are read-only
-	if err = afero.WriteFile(b.fs, p, data, 0o444); err != nil {
+	tmp, err := fsutil.WriteTempFile(b.fs, dest, tmpObjectPrefix, data, b.filePerm(0o444))
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not persist object %s: %w", sha, err)
+	}
+	if err = fsutil.MoveFile(b.fs, tmp, p); err != nil {
+		b.fs.Remove(tmp) //nolint:errcheck // it already failed
 		return ginternals.NullOid, fmt.Errorf("could not persist object %s at path %s: %w", sha, p, err)
 	}
 
 	// add the object to the cache
-	b.looseObjects.Store(o.ID(), struct{}{})
+	b.looseObjects.Store(o.ID(), ginternals.ObjectsPath(b.config))
 	if b.cache != nil {
-		b.cache.Add(o.ID(), o)
+		b.cache.AddWithSize(o.ID(), o, len(o.Bytes()))
+	}
+	b.addToObjectCache(o)
+	if err = b.indexObject(o); err != nil {
+		return o.ID(), err
 	}
 	return o.ID(), nil
 }
@@ -252,6 +368,8 @@ func (b *Backend) WriteObject(o *object.Object) (ginternals.Oid, error) {
 // WalkPackedObjectIDs runs the provided method on all the oids of all the
 // packfiles
 func (b *Backend) WalkPackedObjectIDs(f packfile.OidWalkFunc) error {
+	b.packfilesMu.RLock()
+	defer b.packfilesMu.RUnlock()
 	for _, pack := range b.packfiles {
 		if err := pack.WalkOids(f); err != nil {
 			return err
@@ -260,60 +378,75 @@ func (b *Backend) WalkPackedObjectIDs(f packfile.OidWalkFunc) error {
 	return nil
 }
 
-// loadLooseObject loads the loose object in memory
+// loadLooseObject loads the loose object of all the object
+// directories in memory
 func (b *Backend) loadLooseObject() error {
-	objectsPath := ginternals.ObjectsPath(b.config)
+	for _, dir := range b.objectDirs() {
+		if err := b.loadLooseObjectFrom(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLooseObjectFrom loads the loose object of the given object
+// directory in memory. The objects that have already been found in
+// another directory are ignored
+func (b *Backend) loadLooseObjectFrom(objectsPath string) error {
 	return afero.Walk(b.fs, objectsPath, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
-			//nolint:nilerr // in case of error we just skip it and move on.
-			// this will happen if the repo is empty and the ./objects
+			// This will happen if the repo is empty and the ./objects
 			// folder doesn't exists
+			if !errors.Is(err, os.ErrNotExist) {
+				b.addScanWarning(path, err)
+			}
 			return nil
 		}
 		if path == objectsPath {
 			return nil
 		}
 
-		// We're interested in all the directory that are named "00"
-		// up to "ff"
-		if info.IsDir() {
-			if !b.isLooseObjectDir(info.Name()) {
+		if b.isScanIgnored(info.Name()) {
+			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// We're only interested in the files inside a loose object
-		// directory
-		prefix := filepath.Base(filepath.Dir(path))
-		if !b.isLooseObjectDir(prefix) {
-			return filepath.SkipDir
+		rel, err := filepath.Rel(objectsPath, path)
+		if err != nil {
+			return err //nolint:wrapcheck // the error message is already pretty descriptive
 		}
+		// the keys of the layout are UNIX paths
+		key := filepath.ToSlash(rel)
 
-		if filepath.Ext(info.Name()) != "" {
-			return filepath.SkipDir
+		// We're only interested in the directories that may contain
+		// loose objects (for git, the ones named "00" up to "ff")
+		if info.IsDir() {
+			if !b.layout.IsLooseObjectDir(key) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		sha := prefix + info.Name()
-		oid, err := ginternals.NewOidFromStr(sha)
-		if err != nil {
-			return fmt.Errorf("could not get oid from %s: %w", sha, err)
+		oid, ok := b.layout.LooseObjectID(key)
+		if !ok {
+			// Files that are not objects in a loose object directory
+			// shouldn't prevent us from loading the other ones
+			if dir := filepath.ToSlash(filepath.Dir(rel)); dir != "." && b.layout.IsLooseObjectDir(dir) {
+				b.addScanWarning(path, fmt.Errorf("could not get oid from %s: %w", key, ginternals.ErrInvalidOid))
+			}
+			return nil
 		}
-		b.looseObjects.Store(oid, struct{}{})
+		b.looseObjects.LoadOrStore(oid, objectsPath)
 		return nil
 	})
 }
 
-// isLooseObjectDir checks if a directory name is anything between 00 and ff
-func (b *Backend) isLooseObjectDir(name string) bool {
-	if len(name) != 2 {
-		return false
-	}
-	dirNum, parseErr := strconv.ParseInt(name, 16, 64)
-	if parseErr != nil || dirNum < 0x00 || dirNum > 0xff {
-		return false
-	}
-	return true
+// looseObjectPath returns the path of the loose object with the
+// given oid, stored in the given object directory
+func (b *Backend) looseObjectPath(objectsPath string, oid ginternals.Oid) string {
+	return ginternals.KeyPath(objectsPath, b.layout.LooseObjectKey(oid))
 }
 
 // WalkLooseObjectIDs runs the provided method on all the oids of all the
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/Nivl/git-go/ginternals/packfile"
 	"github.com/Nivl/git-go/internal/testutil"
 	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -89,6 +92,135 @@ func TestObject(t *testing.T) {
 		require.Nil(t, obj)
 		require.True(t, errors.Is(err, ginternals.ErrObjectNotFound), "unexpected error received")
 	})
+
+	t.Run("objects bigger than the limit should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewWithOptions(cfg, afero.NewOsFs(), Options{
+			Limits: object.Limits{MaxObjectSize: 10},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		// loose object
+		oid, err := ginternals.NewOidFromStr("b07e28976ac8972715598f390964d53cf4dbc1bd")
+		require.NoError(t, err)
+		_, err = b.Object(oid)
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+
+		// packed object
+		oid, err = ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		_, err = b.Object(oid)
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+	})
+
+	t.Run("should emit the lookups", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		logger := &testutil.Logger{}
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		cfg.Logger = logger
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		events := logger.Events("repository loaded")
+		require.Len(t, events, 1)
+		assert.Equal(t, 2, events[0].Args["loose_objects"])
+		assert.Equal(t, 1, events[0].Args["packfiles"])
+		require.Len(t, logger.Events("packfile loaded"), 1)
+
+		for i, sha := range []string{
+			"b07e28976ac8972715598f390964d53cf4dbc1bd",
+			"1dcdadc2a420225783794fbffd51e2e137a69646",
+			"1dcdadc2a420225783794fbffd51e2e137a69646", // served by the cache
+			"0000000000000000000000000000000000000001",
+		} {
+			oid, err := ginternals.NewOidFromStr(sha)
+			require.NoError(t, err, "test %d", i)
+			_, err = b.Object(oid)
+			if err != nil {
+				require.ErrorIs(t, err, ginternals.ErrObjectNotFound, "test %d", i)
+			}
+		}
+
+		events = logger.Events("object lookup")
+		sources := make([]string, 0, len(events))
+		for _, e := range events {
+			sources = append(sources, e.Args["oid"].(string)+" "+e.Args["source"].(string))
+		}
+		assert.Equal(t, []string{
+			"b07e28976ac8972715598f390964d53cf4dbc1bd loose",
+			"1dcdadc2a420225783794fbffd51e2e137a69646 packfile",
+			"0000000000000000000000000000000000000001 none",
+		}, sources)
+	})
+}
+
+func TestAlternateObjectDirs(t *testing.T) {
+	t.Parallel()
+
+	altRepoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	repoPath, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	cfg.AlternateObjectDirPaths = []string{filepath.Join(altRepoPath, ".git", "objects")}
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	require.NoError(t, b.Init(ginternals.Master))
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	t.Run("should read the loose objects of the alternates", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("b07e28976ac8972715598f390964d53cf4dbc1bd")
+		require.NoError(t, err)
+		o, err := b.Object(oid)
+		require.NoError(t, err)
+		assert.Equal(t, oid, o.ID())
+	})
+
+	t.Run("should read the packed objects of the alternates", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		o, err := b.Object(oid)
+		require.NoError(t, err)
+		assert.Equal(t, oid, o.ID())
+	})
+
+	t.Run("should write the objects in the repository", func(t *testing.T) {
+		t.Parallel()
+
+		o := object.New(object.TypeBlob, []byte("not in the alternate"))
+		oid, err := b.WriteObject(o)
+		require.NoError(t, err)
+		assert.FileExists(t, ginternals.LooseObjectPath(cfg, oid.String()))
+		assert.NoFileExists(t, filepath.Join(cfg.AlternateObjectDirPaths[0], oid.String()[:2], oid.String()[2:]))
+
+		// An object that is already in an alternate should not be
+		// copied
+		existing, err := ginternals.NewOidFromStr("b07e28976ac8972715598f390964d53cf4dbc1bd")
+		require.NoError(t, err)
+		assert.NoFileExists(t, ginternals.LooseObjectPath(cfg, existing.String()))
+	})
 }
 
 func TestHasObject(t *testing.T) {
@@ -196,6 +328,68 @@ func TestHasObject(t *testing.T) {
 	})
 }
 
+func TestCacheSize(t *testing.T) {
+	t.Parallel()
+
+	oid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+	require.NoError(t, err)
+
+	t.Run("objects should be cached by default", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		b, err := NewFS(confutil.NewCommonConfig(t, repoPath))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		o, err := b.Object(oid)
+		require.NoError(t, err)
+		_, found := b.cache.Get(oid)
+		assert.True(t, found, "the object should have been cached")
+		assert.Equal(t, len(o.Bytes()), b.cache.Size())
+	})
+
+	t.Run("objects bigger than the cache should not be cached", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{CacheSize: 10})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		_, err = b.Object(oid)
+		require.NoError(t, err)
+		_, found := b.cache.Get(oid)
+		assert.False(t, found, "the object should not have been cached")
+	})
+
+	t.Run("a negative size should disable the cache", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		b, err := NewWithOptions(confutil.NewCommonConfig(t, repoPath), afero.NewOsFs(), Options{CacheSize: -1})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		assert.Nil(t, b.cache)
+		o, err := b.Object(oid)
+		require.NoError(t, err)
+		assert.Equal(t, oid, o.ID())
+	})
+}
+
 func TestWriteObject(t *testing.T) {
 	t.Parallel()
 
@@ -229,6 +423,13 @@ func TestWriteObject(t *testing.T) {
 		info, err := os.Stat(p)
 		require.NoError(t, err)
 		assert.Equal(t, os.FileMode(0o444), info.Mode(), "objects should be read only")
+
+		// make sure the temporary file has been removed
+		entries, err := os.ReadDir(filepath.Dir(p))
+		require.NoError(t, err)
+		for _, e := range entries {
+			assert.False(t, strings.HasPrefix(e.Name(), tmpObjectPrefix), "%s should have been removed", e.Name())
+		}
 	})
 
 	t.Run("Writing the same object twice should not trigger a rewrite", func(t *testing.T) {
@@ -397,7 +598,7 @@ func TestIsLooseObjectDir(t *testing.T) {
 
 		for i := int64(0); i < 256; i++ {
 			hex := fmt.Sprintf("%02x", 255)
-			assert.True(t, b.isLooseObjectDir(hex), "%s (%d) should pass", hex, i)
+			assert.True(t, b.layout.IsLooseObjectDir(hex), "%s (%d) should pass", hex, i)
 		}
 	})
 
@@ -429,7 +630,7 @@ func TestIsLooseObjectDir(t *testing.T) {
 		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
 			t.Parallel()
 
-			assert.Equal(t, !b.isLooseObjectDir(tc.name), tc.expected)
+			assert.Equal(t, !b.layout.IsLooseObjectDir(tc.name), tc.expected)
 		})
 	}
 }
@@ -0,0 +1,488 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/spf13/afero"
+)
+
+const (
+	// packedRefsTraitsPrefix is the prefix of the first line of a
+	// packed-refs file that lists the traits of the file
+	packedRefsTraitsPrefix = "# pack-refs with:"
+	// packedRefsHeader is the header we write at the top of the
+	// packed-refs files. It's the same as the one used by git.
+	// "peeled" and "fully-peeled" mean that all the references
+	// targeting an annotated tag are followed by the object the tag
+	// targets (recursively), and "sorted" means the references are
+	// sorted by name, which allows binary searching the file
+	packedRefsHeader = packedRefsTraitsPrefix + " peeled fully-peeled sorted \n"
+
+	// packedRefsSearchThreshold is the size (in bytes) above which
+	// a sorted packed-refs file is binary searched on demand instead
+	// of being entirely loaded in memory
+	packedRefsSearchThreshold = 256 * 1024
+)
+
+// packedRefs represents a sorted packed-refs file that is binary
+// searched on demand
+type packedRefs struct {
+	path string
+	// records contains the content of the file, without the header
+	records []byte
+}
+
+// lookup returns the target of the reference matching the given
+// name, and whether the reference exists
+func (p *packedRefs) lookup(name string) ([]byte, bool, error) {
+	offset, err := p.search(name)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset == len(p.records) {
+		return nil, false, nil
+	}
+	refName, target, _, err := p.parseRecord(offset)
+	if err != nil {
+		return nil, false, err
+	}
+	if refName != name {
+		return nil, false, nil
+	}
+	return target, true, nil
+}
+
+// lookupPeeled returns the peeled value of the reference matching the
+// given name, and whether the file contains one
+func (p *packedRefs) lookupPeeled(name string) (target, peeled []byte, ok bool, err error) {
+	offset, err := p.search(name)
+	if err != nil || offset == len(p.records) {
+		return nil, nil, false, err
+	}
+	refName, target, end, err := p.parseRecord(offset)
+	if err != nil || refName != name {
+		return nil, nil, false, err
+	}
+	// The peeled value is on the line following the reference, if any
+	_, next := p.line(offset)
+	if next == end {
+		return nil, nil, false, nil
+	}
+	line, _ := p.line(next)
+	return target, line[1:], true, nil
+}
+
+// hasPrefix returns the name of a reference that starts with the given
+// prefix, if any
+func (p *packedRefs) hasPrefix(prefix string) (string, error) {
+	offset, err := p.search(prefix)
+	if err != nil {
+		return "", err
+	}
+	if offset == len(p.records) {
+		return "", nil
+	}
+	refName, _, _, err := p.parseRecord(offset)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(refName, prefix) {
+		return "", nil
+	}
+	return refName, nil
+}
+
+// walk runs the provided method on all the references of the file,
+// in order
+func (p *packedRefs) walk(f func(name string, target []byte) error) error {
+	for offset := 0; offset < len(p.records); {
+		name, target, end, err := p.parseRecord(offset)
+		if err != nil {
+			return err
+		}
+		if err = f(name, target); err != nil {
+			return err
+		}
+		offset = end
+	}
+	return nil
+}
+
+// search returns the offset of the first record that has a name
+// greater or equal to the provided one
+func (p *packedRefs) search(name string) (int, error) {
+	lo, hi := 0, len(p.records)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := p.recordStart(mid)
+		if err != nil {
+			return 0, err
+		}
+		refName, _, end, err := p.parseRecord(start)
+		if err != nil {
+			return 0, err
+		}
+		if refName < name {
+			lo = end
+			continue
+		}
+		hi = start
+	}
+	return lo, nil
+}
+
+// recordStart returns the offset of the beginning of the record that
+// contains the byte at the given offset
+func (p *packedRefs) recordStart(offset int) (int, error) {
+	start := bytes.LastIndexByte(p.records[:offset], '\n') + 1
+	// If we landed on the peeled value of a reference, we need to
+	// go back to the reference itself
+	if p.records[start] == '^' {
+		if start == 0 {
+			return 0, fmt.Errorf("%s starts with a peeled value: %w", p.path, ginternals.ErrPackedRefInvalid)
+		}
+		start = bytes.LastIndexByte(p.records[:start-1], '\n') + 1
+	}
+	return start, nil
+}
+
+// parseRecord parses the record starting at the given offset.
+// end contains the offset of the next record
+func (p *packedRefs) parseRecord(offset int) (name string, target []byte, end int, err error) {
+	line, end := p.line(offset)
+	// We expected the line to have the format:
+	// "oid ref-name"
+	parts := bytes.SplitN(line, []byte{' '}, 2)
+	if len(parts) != 2 || len(parts[0]) != ginternals.OidSize*2 || len(parts[1]) == 0 {
+		return "", nil, 0, fmt.Errorf("could not parse %s, unexpected data at offset %d: %w", p.path, offset, ginternals.ErrPackedRefInvalid)
+	}
+	// we skip the peeled value, if any
+	if end < len(p.records) && p.records[end] == '^' {
+		_, end = p.line(end)
+	}
+	return string(parts[1]), parts[0], end, nil
+}
+
+// line returns the line starting at the given offset (without
+// its \n), and the offset of the next line
+func (p *packedRefs) line(offset int) (line []byte, next int) {
+	i := bytes.IndexByte(p.records[offset:], '\n')
+	if i == -1 {
+		return p.records[offset:], len(p.records)
+	}
+	return p.records[offset : offset+i], offset + i + 1
+}
+
+// splitPackedRefsHeader returns the traits of a packed-refs file,
+// alongside the content of the file without the header
+func splitPackedRefsHeader(data []byte) (traits map[string]struct{}, records []byte) {
+	traits = map[string]struct{}{}
+	if !bytes.HasPrefix(data, []byte(packedRefsTraitsPrefix)) {
+		return traits, data
+	}
+	header := data
+	records = nil
+	if i := bytes.IndexByte(data, '\n'); i != -1 {
+		header = data[:i]
+		records = data[i+1:]
+	}
+	for _, trait := range strings.Fields(string(header[len(packedRefsTraitsPrefix):])) {
+		traits[trait] = struct{}{}
+	}
+	return traits, records
+}
+
+// loadPackedRefs loads the packed-refs file.
+// Large sorted files are binary searched on demand, the other files
+// are loaded in memory
+func (b *Backend) loadPackedRefs() error {
+	packedRefPath := ginternals.PackedRefsPath(b.config)
+	data, err := afero.ReadFile(b.fs, packedRefPath)
+	if err != nil {
+		// if the file doesn't exist then there's nothing to do
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read %s: %w", packedRefPath, err)
+	}
+
+	traits, records := splitPackedRefsHeader(data)
+	if _, sorted := traits["sorted"]; sorted && len(data) >= packedRefsSearchThreshold {
+		b.packedRefsMu.Lock()
+		b.packedRefs = &packedRefs{
+			path:    packedRefPath,
+			records: records,
+		}
+		b.packedRefsMu.Unlock()
+		return nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	// lastTarget contains the target of the previous reference, which
+	// is the object peeled by a "^" line
+	lastTarget := ""
+	for i := 1; sc.Scan(); i++ {
+		line := sc.Text()
+		// we skip empty lines and comments
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		// the peeled value of the previous reference, which is an
+		// annotated tag
+		if line[0] == '^' {
+			b.storePeeled([]byte(lastTarget), []byte(line[1:]))
+			continue
+		}
+		// We expected data to have the format:
+		// "oid ref-name"
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return fmt.Errorf("could not parse %s, unexpected data line %d: %w", packedRefPath, i, ginternals.ErrPackedRefInvalid)
+		}
+		// the name of the ref is its UNIX path
+		b.refs.Store(filepath.ToSlash(parts[1]), []byte(parts[0]))
+		lastTarget = parts[0]
+	}
+	if err = sc.Err(); err != nil {
+		return fmt.Errorf("could not parse %s: %w", packedRefPath, err)
+	}
+	return nil
+}
+
+// packedReference returns the target of a reference stored in a
+// packed-refs file that is binary searched, and whether the
+// reference exists
+func (b *Backend) packedReference(name string) ([]byte, bool, error) {
+	b.packedRefsMu.RLock()
+	defer b.packedRefsMu.RUnlock()
+	if b.packedRefs == nil {
+		return nil, false, nil
+	}
+	return b.packedRefs.lookup(name)
+}
+
+// walkPackedReferences runs the provided method on all the references
+// of a packed-refs file that is binary searched
+func (b *Backend) walkPackedReferences(f func(name string, target []byte) error) error {
+	b.packedRefsMu.RLock()
+	defer b.packedRefsMu.RUnlock()
+	if b.packedRefs == nil {
+		return nil
+	}
+	return b.packedRefs.walk(f)
+}
+
+// PackReferences packs all the references of refs/ into the
+// packed-refs file, and removes their loose version.
+// Symbolic references are not packed.
+// Like git, the file is sorted and contains the peeled value of all
+// the references that target an annotated tag.
+// This method cannot be called concurrently with other methods
+// writing references
+func (b *Backend) PackReferences() (err error) {
+	if err = b.checkRefUpdatesAllowed(); err != nil {
+		return err
+	}
+	// The packed-refs file stays locked until all the loose references
+	// have been removed, so other processes don't pack references
+	// at the same time
+	lock, err := lockfile.New(b.fs, ginternals.PackedRefsPath(b.config), b.lockOptions())
+	if err != nil {
+		return fmt.Errorf("could not lock the packed-refs file: %w", err)
+	}
+	defer lock.Rollback() //nolint:errcheck // no-op if the lock is committed
+
+	targets := map[string]ginternals.Oid{}
+	addRef := func(name string, data []byte) error {
+		if !strings.HasPrefix(name, "refs/") {
+			return nil
+		}
+		if _, ok := targets[name]; ok {
+			return nil
+		}
+		content := strings.TrimSpace(string(data))
+		// Symbolic references cannot be packed
+		if strings.HasPrefix(content, "ref: ") {
+			return nil
+		}
+		oid, err := ginternals.NewOidFromStr(content)
+		if err != nil {
+			return fmt.Errorf("could not parse reference %s: %w", name, err)
+		}
+		targets[name] = oid
+		return nil
+	}
+
+	// loose references have the priority over the packed ones
+	b.refs.Range(func(key, value interface{}) bool {
+		err = addRef(key.(string), value.([]byte))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = b.walkPackedReferences(addRef); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// each line is made of an oid, a space, the name, and a \n
+	out := make([]byte, 0, len(packedRefsHeader)+len(names)*(ginternals.OidHexSize+64))
+	out = append(out, packedRefsHeader...)
+	for _, name := range names {
+		oid := targets[name]
+		out = oid.AppendHex(out)
+		out = append(out, ' ')
+		out = append(out, name...)
+		out = append(out, '\n')
+		peeled, err := b.Peel(oid)
+		if err != nil {
+			return fmt.Errorf("could not peel %s: %w", name, err)
+		}
+		if peeled != oid {
+			out = append(out, '^')
+			out = peeled.AppendHex(out)
+			out = append(out, '\n')
+		}
+	}
+	if _, err = lock.Write(out); err != nil {
+		return fmt.Errorf("could not write the packed-refs file: %w", err)
+	}
+	// We need to commit the lock before removing the loose references
+	// otherwise they would disappear for the other processes
+	if err = lock.Commit(); err != nil {
+		return fmt.Errorf("could not write the packed-refs file: %w", err)
+	}
+
+	// Now that the references are packed we can remove the loose ones
+	// that haven't changed in the meantime
+	for _, name := range names {
+		if err = b.pruneLooseReference(name, targets[name]); err != nil {
+			return err
+		}
+	}
+
+	// We reload the file so large files are binary searched
+	b.packedRefsMu.Lock()
+	b.packedRefs = nil
+	b.packedRefsMu.Unlock()
+	return b.loadPackedRefs()
+}
+
+// pruneLooseReference removes the loose version of a packed reference
+// if it still targets the packed oid
+func (b *Backend) pruneLooseReference(name string, target ginternals.Oid) error {
+	p := b.systemPath(name)
+	if _, err := b.fs.Stat(p); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not stat reference at %s: %w", p, err)
+	}
+
+	// We lock the reference to make sure it's not being updated
+	// while we're removing it. References that are already locked are
+	// being updated, so we can just skip them
+	lock, err := lockfile.New(b.fs, p, b.lockOptions())
+	if err != nil {
+		if errors.Is(err, lockfile.ErrLocked) {
+			return nil
+		}
+		return fmt.Errorf("could not lock reference %s: %w", name, err)
+	}
+	defer lock.Rollback() //nolint:errcheck // the lock is never committed
+
+	data, err := afero.ReadFile(b.fs, p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read reference at %s: %w", p, err)
+	}
+	if strings.TrimSpace(string(data)) != target.String() {
+		return nil
+	}
+	if err = b.fs.Remove(p); err != nil {
+		return fmt.Errorf("could not remove loose reference %s: %w", name, err)
+	}
+	return nil
+}
+
+// storePeeled records the peeled value of an annotated tag found in
+// a packed-refs file. Invalid values are ignored since they would
+// only prevent an optimization
+func (b *Backend) storePeeled(target, peeled []byte) {
+	targetID, err := ginternals.NewOidFromChars(target)
+	if err != nil {
+		return
+	}
+	peeledID, err := ginternals.NewOidFromChars(peeled)
+	if err != nil {
+		return
+	}
+	b.peeled.Store(targetID, peeledID)
+}
+
+// Peel returns the object targeted by the given oid, following the
+// annotated tags recursively. The oid is returned as is if it's not
+// an annotated tag.
+// The peeled values of the packed-refs file are used when available,
+// to avoid loading the tags
+func (b *Backend) Peel(oid ginternals.Oid) (ginternals.Oid, error) {
+	if peeled, ok := b.peeled.Load(oid); ok {
+		return peeled.(ginternals.Oid), nil //nolint:forcetypeassert // the map only contains oids
+	}
+	for {
+		o, err := b.Object(oid)
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		if o.Type() != object.TypeTag {
+			return oid, nil
+		}
+		tag, err := o.AsTag()
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not parse tag %s: %w", oid.String(), err)
+		}
+		oid = tag.Target()
+	}
+}
+
+// PeelReference returns the object targeted by the given reference,
+// following the annotated tags recursively.
+// The peeled values of the packed-refs file are used when available,
+// to avoid loading the tags
+func (b *Backend) PeelReference(ref *ginternals.Reference) (ginternals.Oid, error) {
+	// The large packed-refs files are not loaded in memory, so we need
+	// to look for the peeled value of the reference in the file.
+	// The value can only be used if the reference hasn't been
+	// updated since it has been packed
+	b.packedRefsMu.RLock()
+	if b.packedRefs != nil {
+		target, peeled, ok, err := b.packedRefs.lookupPeeled(ref.Name())
+		if err != nil {
+			b.packedRefsMu.RUnlock()
+			return ginternals.NullOid, fmt.Errorf("could not look for the peeled value of %s: %w", ref.Name(), err)
+		}
+		if ok && string(target) == ref.Target().String() {
+			b.storePeeled(target, peeled)
+		}
+	}
+	b.packedRefsMu.RUnlock()
+	return b.Peel(ref.Target())
+}
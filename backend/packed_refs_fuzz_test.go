@@ -0,0 +1,34 @@
+//go:build go1.18
+// +build go1.18
+
+package backend
+
+import (
+	"testing"
+)
+
+func FuzzPackedRefs(f *testing.F) {
+	f.Add([]byte(packedRefsHeader +
+		"0eaf966ff79d8f61954a6a8e1a1b6b1fd1a8d6b7 refs/heads/master\n" +
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/annotated\n" +
+		"^0eaf966ff79d8f61954a6a8e1a1b6b1fd1a8d6b7\n" +
+		"0eaf966ff79d8f61954a6a8e1a1b6b1fd1a8d6b7 refs/tags/v1\n"))
+	f.Add([]byte("^0eaf966ff79d8f61954a6a8e1a1b6b1fd1a8d6b7\n"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, records := splitPackedRefsHeader(data)
+		p := &packedRefs{
+			path:    "packed-refs",
+			records: records,
+		}
+		_ = p.walk(func(name string, target []byte) error {
+			return nil
+		})
+		for _, name := range []string{"refs/heads/master", "refs/tags/annotated", "refs/tags/z", ""} {
+			_, _, _ = p.lookup(name)
+			_, _, _, _ = p.lookupPeeled(name)
+			_, _ = p.hasPrefix(name)
+		}
+	})
+}
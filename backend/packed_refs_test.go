@@ -0,0 +1,318 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackReferences(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	require.NoError(t, b.PackReferences())
+
+	// generated using git pack-refs --all
+	expected := strings.Join([]string{
+		"# pack-refs with: peeled fully-peeled sorted ",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master",
+		"b328320060eb503cf337c7cff281712ef236963a refs/heads/ml/cleanup-062020",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/ml/packfile/tests",
+		"f0f70144f38695250606b86a50cff2b440a417f3 refs/heads/ml/tests",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/remotes/origin/master",
+		"b328320060eb503cf337c7cff281712ef236963a refs/remotes/origin/ml/cleanup-062020",
+		"5f35f2dc6cec7356da02ca26192ce2bc3f271e79 refs/remotes/origin/ml/feat/clone",
+		"3fe6cf63fceced491a79fe634eb1e2c888225707 refs/stash",
+		"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated",
+		"^6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+	}, "\n") + "\n"
+	data, err := os.ReadFile(filepath.Join(b.Path(), "packed-refs"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+
+	// the loose references should have been removed, except for the
+	// symbolic ones
+	_, err = os.Stat(filepath.Join(b.Path(), "refs", "tags", "annotated"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "refs/tags/annotated should have been removed")
+	_, err = os.Stat(filepath.Join(b.Path(), "refs", "remotes", "origin", "HEAD"))
+	assert.NoError(t, err, "refs/remotes/origin/HEAD should not have been removed")
+	_, err = os.Stat(filepath.Join(b.Path(), "packed-refs.lock"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "the lock should have been removed")
+
+	// the references should still be usable
+	ref, err := b.Reference("refs/tags/annotated")
+	require.NoError(t, err)
+	assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", ref.Target().String())
+
+	// and should be the same once loaded from the disk
+	b2, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b2.Close())
+	})
+	ref, err = b2.Reference("refs/tags/annotated")
+	require.NoError(t, err)
+	assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", ref.Target().String())
+}
+
+func TestPackReferencesLocked(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	// Simulate another process packing the references
+	lockPath := filepath.Join(b.Path(), "packed-refs.lock")
+	require.NoError(t, os.WriteFile(lockPath, []byte{}, 0o644))
+
+	err = b.PackReferences()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+
+	// the loose references should still be there
+	_, err = os.Stat(filepath.Join(b.Path(), "refs", "tags", "annotated"))
+	require.NoError(t, err)
+	_, err = os.Stat(lockPath)
+	require.NoError(t, err, "the lock should not have been removed")
+}
+
+func TestLargePackedRefs(t *testing.T) {
+	t.Parallel()
+
+	const refCount = 5000
+	const target = "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+
+	// createRepo creates a repository containing a large packed-refs
+	// file and returns its backend
+	createRepo := func(t *testing.T, sorted bool) *Backend {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		packedRefsPath := filepath.Join(repoPath, ".git", "packed-refs")
+		original, err := os.ReadFile(packedRefsPath)
+		require.NoError(t, err)
+		_, originalRecords := splitPackedRefsHeader(original)
+
+		buf := &strings.Builder{}
+		if sorted {
+			buf.WriteString(packedRefsHeader)
+		}
+		// The generated branches are sorted before the existing
+		// references of the repo
+		for i := 0; i < refCount; i++ {
+			fmt.Fprintf(buf, "%s refs/heads/branch-%05d\n", target, i)
+			// let's add a few peeled values to make sure they
+			// don't break the binary search
+			if i%3 == 0 {
+				fmt.Fprintf(buf, "^%s\n", target)
+			}
+		}
+		buf.Write(originalRecords)
+		require.Greater(t, buf.Len(), packedRefsSearchThreshold)
+		err = os.WriteFile(packedRefsPath, []byte(buf.String()), 0o644)
+		require.NoError(t, err)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		return b
+	}
+
+	t.Run("sorted file should not be loaded in memory", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, true)
+		require.NotNil(t, b.packedRefs)
+		_, ok := b.refs.Load("refs/heads/branch-00000")
+		assert.False(t, ok, "the packed references should not be loaded in memory")
+	})
+
+	t.Run("unsorted file should be loaded in memory", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, false)
+		require.Nil(t, b.packedRefs)
+		_, ok := b.refs.Load("refs/heads/branch-00000")
+		assert.True(t, ok, "the packed references should be loaded in memory")
+	})
+
+	t.Run("should find all the references", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, true)
+		for _, i := range []int{0, 1, 2, 3, 1234, 2500, 4998, refCount - 1} {
+			ref, err := b.Reference(fmt.Sprintf("refs/heads/branch-%05d", i))
+			require.NoError(t, err, "branch-%05d", i)
+			assert.Equal(t, target, ref.Target().String())
+		}
+
+		// loose references should still be found
+		ref, err := b.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", ref.Target().String())
+
+		for _, name := range []string{"refs/heads/branch", "refs/heads/branch-99999", "refs/heads/a", "refs/z"} {
+			_, err = b.Reference(name)
+			require.Error(t, err, name)
+			assert.True(t, errors.Is(err, ginternals.ErrRefNotFound), "unexpected error for %s: %v", name, err)
+		}
+	})
+
+	t.Run("should walk all the references", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, true)
+		count := 0
+		err := b.WalkReferences(func(ref *ginternals.Reference) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		// HEAD, ORIG_HEAD, FETCH_HEAD, the 3 loose references, the 8
+		// packed references of the repo, and the generated ones
+		assert.Equal(t, refCount+14, count)
+
+		count = 0
+		err = b.WalkReferences(func(ref *ginternals.Reference) error {
+			if count == refCount {
+				return WalkStop
+			}
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, refCount, count)
+	})
+
+	t.Run("should detect conflicts with packed references", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, true)
+
+		err := b.WriteReferenceSafe(ginternals.NewReference("refs/heads/branch-00042", ginternals.NullOid))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefExists), "unexpected error: %v", err)
+
+		oid, err := ginternals.NewOidFromStr(target)
+		require.NoError(t, err)
+		err = b.WriteReference(ginternals.NewReference("refs/heads/branch-00042/child", oid))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefInvalid), "unexpected error: %v", err)
+
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/branch-00042", oid)))
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/branch-new", oid)))
+	})
+
+	t.Run("should be repacked", func(t *testing.T) {
+		t.Parallel()
+
+		b := createRepo(t, true)
+		require.NoError(t, b.PackReferences())
+		require.NotNil(t, b.packedRefs)
+
+		ref, err := b.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", ref.Target().String())
+		ref, err = b.Reference("refs/heads/branch-01000")
+		require.NoError(t, err)
+		assert.Equal(t, target, ref.Target().String())
+	})
+}
+
+func TestPeelReference(t *testing.T) {
+	t.Parallel()
+
+	const tagID = "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442"
+	const commitID = "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	// fakePeeledID is a commit that is not the target of the tag, so
+	// we know the value comes from the packed-refs file
+	const fakePeeledID = "b328320060eb503cf337c7cff281712ef236963a"
+
+	testCases := []struct {
+		desc  string
+		large bool
+	}{
+		{desc: "file loaded in memory"},
+		{desc: "binary searched file", large: true},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			buf := &strings.Builder{}
+			buf.WriteString(packedRefsHeader)
+			if tc.large {
+				for i := 0; i < 5000; i++ {
+					fmt.Fprintf(buf, "%s refs/heads/branch-%05d\n", commitID, i)
+				}
+				require.Greater(t, buf.Len(), packedRefsSearchThreshold)
+			}
+			fmt.Fprintf(buf, "%s refs/tags/packed\n^%s\n", tagID, fakePeeledID)
+			err := os.WriteFile(filepath.Join(repoPath, ".git", "packed-refs"), []byte(buf.String()), 0o644)
+			require.NoError(t, err)
+
+			cfg := confutil.NewCommonConfig(t, repoPath)
+			b, err := NewFS(cfg)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, b.Close())
+			})
+
+			ref, err := b.Reference("refs/tags/packed")
+			require.NoError(t, err)
+			peeled, err := b.PeelReference(ref)
+			require.NoError(t, err)
+			assert.Equal(t, fakePeeledID, peeled.String())
+
+			// The peeled value belongs to the tag object, so it's
+			// used for all the references targeting the tag
+			peeled, err = b.Peel(ref.Target())
+			require.NoError(t, err)
+			assert.Equal(t, fakePeeledID, peeled.String())
+
+			// The non-tag objects should be returned as is
+			commit, err := ginternals.NewOidFromStr(commitID)
+			require.NoError(t, err)
+			peeled, err = b.Peel(commit)
+			require.NoError(t, err)
+			assert.Equal(t, commit, peeled)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/fsutil"
+	"github.com/spf13/afero"
+)
+
+// PackfileInfo contains the metadata of a packfile
+type PackfileInfo struct {
+	ID   ginternals.Oid
+	Path string
+	// Size contains the size of the .pack file, in bytes
+	Size        int64
+	ObjectCount uint32
+	// Keep is set if the packfile has a .keep file, meaning it
+	// must never be deleted
+	Keep bool
+	// Promisor is set if the packfile has been fetched from a
+	// promisor remote
+	Promisor bool
+}
+
+// Packfiles returns the metadata of the packfiles of the repository,
+// including the ones of the alternate object directories, sorted
+// by path
+func (b *Backend) Packfiles() ([]PackfileInfo, error) {
+	b.packfilesMu.RLock()
+	defer b.packfilesMu.RUnlock()
+
+	packs := make([]PackfileInfo, 0, len(b.packfiles))
+	for id, pack := range b.packfiles {
+		info := PackfileInfo{
+			ID:          id,
+			Path:        pack.Path(),
+			ObjectCount: pack.ObjectCount(),
+		}
+		stat, err := b.fs.Stat(pack.Path())
+		if err != nil {
+			return nil, fmt.Errorf("could not stat %s: %w", pack.Path(), err)
+		}
+		info.Size = stat.Size()
+		if info.Keep, err = b.hasPackMarker(pack.Path(), packfile.ExtKeep); err != nil {
+			return nil, err
+		}
+		if info.Promisor, err = b.hasPackMarker(pack.Path(), packfile.ExtPromisor); err != nil {
+			return nil, err
+		}
+		packs = append(packs, info)
+	}
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Path < packs[j].Path
+	})
+	return packs, nil
+}
+
+// hasPackMarker returns whether the packfile at the given path has a
+// file with the same name and the given extension (.keep, .promisor)
+func (b *Backend) hasPackMarker(packPath, ext string) (bool, error) {
+	p := strings.TrimSuffix(packPath, packfile.ExtPackfile) + ext
+	_, err := b.fs.Stat(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check %s: %w", p, err)
+	}
+	return true, nil
+}
+
+// keepPath returns the path of the file marking the given packfile
+// as kept
+func (b *Backend) keepPath(packID ginternals.Oid) string {
+	return ginternals.PackfilePath(b.config, "pack-"+packID.String()+packfile.ExtKeep)
+}
+
+// MarkKeptPack marks the given packfile as kept, meaning it will never
+// be deleted when repacking or pruning the repository.
+// reason is stored in the .keep file, and can be empty
+func (b *Backend) MarkKeptPack(packID ginternals.Oid, reason string) error {
+	var data []byte
+	if reason != "" {
+		data = []byte(reason + "\n")
+	}
+	if err := afero.WriteFile(b.fs, b.keepPath(packID), data, b.filePerm(0o644)); err != nil {
+		return fmt.Errorf("could not create the keep file of pack %s: %w", packID.String(), err)
+	}
+	return b.chmod(b.keepPath(packID), 0o644)
+}
+
+// IsKeptPack returns whether the given packfile is marked as kept
+func (b *Backend) IsKeptPack(packID ginternals.Oid) (bool, error) {
+	_, err := b.fs.Stat(b.keepPath(packID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check the keep file of pack %s: %w", packID.String(), err)
+	}
+	return true, nil
+}
+
+// InfoPacks returns the names of the packfiles listed in
+// objects/info/packs. This file is used by the dumb protocols to
+// find the packfiles of a repository, and may be outdated.
+// An empty list is returned if the file doesn't exist
+func (b *Backend) InfoPacks() ([]string, error) {
+	p := ginternals.InfoPacksPath(b.config)
+	data, err := afero.ReadFile(b.fs, p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("could not read %s: %w", p, err)
+	}
+	names, err := packfile.ParseInfoPacks(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", p, err)
+	}
+	return names, nil
+}
+
+// UpdateInfoPacks rewrites objects/info/packs using the packfiles
+// of the repository. This is the equivalent of the packs part of
+// git update-server-info. The packfiles of the alternate object
+// directories are not listed
+func (b *Backend) UpdateInfoPacks() error {
+	packsDir := ginternals.ObjectsPacksPath(b.config)
+	names := []string{}
+	b.packfilesMu.RLock()
+	for _, pack := range b.packfiles {
+		if filepath.Dir(pack.Path()) == packsDir {
+			names = append(names, filepath.Base(pack.Path()))
+		}
+	}
+	b.packfilesMu.RUnlock()
+	sort.Strings(names)
+
+	infoDir := ginternals.ObjectsInfoPath(b.config)
+	if err := b.mkdirAll(infoDir, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %w", infoDir, err)
+	}
+	p := ginternals.InfoPacksPath(b.config)
+	if err := lockfile.WriteFile(b.fs, p, packfile.FormatInfoPacks(names), b.lockOptions()); err != nil {
+		return fmt.Errorf("could not persist %s to disk: %w", p, err)
+	}
+	return nil
+}
+
+// AddPackfile stores the given packfile and its index in the
+// repository, and makes its objects available.
+// The packfile is verified before being added, and nothing is done
+// if the repository already has it.
+// The objects that were not in the repository are sent to the indexer
+// of the backend, if any. If the indexer fails, the packfile is kept
+// and an error wrapping ErrIndexFailed is returned.
+// This method can be called concurrently
+func (b *Backend) AddPackfile(packData, idxData []byte) (packID ginternals.Oid, err error) {
+	if len(packData) < ginternals.OidSize {
+		return ginternals.NullOid, fmt.Errorf("packfile too small: %w", packfile.ErrInvalidMagic)
+	}
+	packID, err = ginternals.NewOidFromHex(packData[len(packData)-ginternals.OidSize:])
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not read the ID of the packfile: %w", err)
+	}
+	b.packfilesMu.RLock()
+	_, exists := b.packfiles[packID]
+	b.packfilesMu.RUnlock()
+	if exists {
+		return packID, nil
+	}
+
+	dir := ginternals.ObjectsPacksPath(b.config)
+	if err = b.mkdirAll(dir, 0o755); err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not create %s: %w", dir, err)
+	}
+	packPath := ginternals.PackfilePath(b.config, "pack-"+packID.String()+packfile.ExtPackfile)
+	idxPath := strings.TrimSuffix(packPath, packfile.ExtPackfile) + packfile.ExtIndex
+	// added is set once the packfile is part of the repository, and
+	// must not be removed anymore
+	added := false
+	defer func() {
+		if err != nil && !added {
+			b.fs.Remove(packPath) //nolint:errcheck // it already failed
+			b.fs.Remove(idxPath)  //nolint:errcheck // it already failed
+		}
+	}()
+
+	// The index is written first since a packfile without an index
+	// cannot be loaded
+	for _, f := range []struct {
+		path string
+		data []byte
+	}{
+		{path: idxPath, data: idxData},
+		{path: packPath, data: packData},
+	} {
+		tmp, err := fsutil.WriteTempFile(b.fs, dir, tmpPackPrefix, f.data, b.filePerm(0o444))
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not persist %s: %w", f.path, err)
+		}
+		if err = b.fs.Rename(tmp, f.path); err != nil {
+			b.fs.Remove(tmp) //nolint:errcheck // it already failed
+			return ginternals.NullOid, fmt.Errorf("could not move %s to %s: %w", tmp, f.path, err)
+		}
+	}
+
+	pack, err := packfile.NewFromFileWithOptions(b.fs, packPath, packfile.Options{
+		Mmap:      b.mmapPacks,
+		VerifyCRC: b.verifyCRC,
+		Limits:    b.limits,
+	})
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not parse packfile at %s: %w", packPath, err)
+	}
+	if err = pack.Verify(); err != nil {
+		pack.Close() //nolint:errcheck // it already failed
+		return ginternals.NullOid, fmt.Errorf("could not verify packfile: %w", err)
+	}
+	newObjects, err := b.newPackedObjects(pack)
+	if err != nil {
+		pack.Close() //nolint:errcheck // it already failed
+		return ginternals.NullOid, err
+	}
+	b.addPackfile(pack)
+	added = true
+	if err = b.indexObjects(newObjects); err != nil {
+		return packID, err
+	}
+	return packID, nil
+}
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackfiles(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	packID, err := ginternals.NewOidFromStr("0163931160835b1de2f120e1aa7e52206debeb14")
+	require.NoError(t, err)
+	packPath := filepath.Join(repoPath, ".git", "objects", "pack", "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+	stat, err := os.Stat(packPath)
+	require.NoError(t, err)
+
+	packs, err := b.Packfiles()
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	assert.Equal(t, packID, packs[0].ID)
+	assert.Equal(t, packPath, packs[0].Path)
+	assert.Equal(t, stat.Size(), packs[0].Size)
+	assert.NotZero(t, packs[0].ObjectCount)
+	assert.False(t, packs[0].Keep)
+	assert.False(t, packs[0].Promisor)
+
+	kept, err := b.IsKeptPack(packID)
+	require.NoError(t, err)
+	assert.False(t, kept)
+
+	require.NoError(t, b.MarkKeptPack(packID, "manual"))
+	kept, err = b.IsKeptPack(packID)
+	require.NoError(t, err)
+	assert.True(t, kept)
+	data, err := os.ReadFile(filepath.Join(repoPath, ".git", "objects", "pack", "pack-0163931160835b1de2f120e1aa7e52206debeb14.keep"))
+	require.NoError(t, err)
+	assert.Equal(t, "manual\n", string(data))
+
+	packs, err = b.Packfiles()
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	assert.True(t, packs[0].Keep)
+}
+
+func TestInfoPacks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should read the existing file", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		names, err := b.InfoPacks()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"}, names)
+	})
+
+	t.Run("should rebuild the file", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		infoPacksPath := filepath.Join(repoPath, ".git", "objects", "info", "packs")
+		expected, err := os.ReadFile(infoPacksPath)
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(infoPacksPath))
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		names, err := b.InfoPacks()
+		require.NoError(t, err)
+		assert.Empty(t, names)
+
+		require.NoError(t, b.UpdateInfoPacks())
+		data, err := os.ReadFile(infoPacksPath)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(data))
+	})
+}
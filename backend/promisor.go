@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+)
+
+// promisorPath returns the path of the file marking the given packfile
+// as a promisor pack
+func (b *Backend) promisorPath(packID ginternals.Oid) string {
+	return ginternals.PackfilePath(b.config, "pack-"+packID.String()+packfile.ExtPromisor)
+}
+
+// MarkPromisorPack marks the given packfile as a promisor pack.
+// A promisor pack contains objects fetched from a promisor remote
+// using a filter, meaning that the objects it references may be
+// missing locally and can be fetched later from the remote
+func (b *Backend) MarkPromisorPack(packID ginternals.Oid) error {
+	f, err := b.fs.Create(b.promisorPath(packID))
+	if err != nil {
+		return fmt.Errorf("could not create the promisor file of pack %s: %w", packID.String(), err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("could not close the promisor file of pack %s: %w", packID.String(), err)
+	}
+	return b.chmod(b.promisorPath(packID), 0o644)
+}
+
+// IsPromisorPack returns whether the given packfile is a promisor pack
+func (b *Backend) IsPromisorPack(packID ginternals.Oid) (bool, error) {
+	_, err := b.fs.Stat(b.promisorPath(packID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check the promisor file of pack %s: %w", packID.String(), err)
+	}
+	return true, nil
+}
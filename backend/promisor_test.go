@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkPromisorPack(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	packID, err := ginternals.NewOidFromStr("0163931160835b1de2f120e1aa7e52206debeb14")
+	require.NoError(t, err)
+
+	isPromisor, err := b.IsPromisorPack(packID)
+	require.NoError(t, err)
+	assert.False(t, isPromisor)
+
+	require.NoError(t, b.MarkPromisorPack(packID))
+	isPromisor, err = b.IsPromisorPack(packID)
+	require.NoError(t, err)
+	assert.True(t, isPromisor)
+
+	_, err = os.Stat(filepath.Join(repoPath, ".git", "objects", "pack", "pack-0163931160835b1de2f120e1aa7e52206debeb14.promisor"))
+	require.NoError(t, err)
+}
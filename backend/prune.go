@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/spf13/afero"
+)
+
+// DefaultPruneExpiry is the minimum age of an unreachable loose object
+// before it gets pruned, when no date is provided. This is the same
+// default as git's gc.pruneExpire
+const DefaultPruneExpiry = 14 * 24 * time.Hour
+
+// PruneStats contains the statistics of a prune
+type PruneStats struct {
+	// Scanned contains the number of loose objects that have been
+	// looked at
+	Scanned int
+	// Pruned contains the number of loose objects that have been
+	// removed
+	Pruned int
+	// Reachable contains the number of loose objects that have been
+	// kept because they are reachable
+	Reachable int
+	// Recent contains the number of unreachable loose objects that
+	// have been kept because they are too recent
+	Recent int
+	// TempFiles contains the number of temporary files left behind by
+	// an interrupted write that have been removed
+	TempFiles int
+	// FreedBytes contains the size on disk of the removed files
+	FreedBytes int64
+}
+
+// PruneLooseObjects removes the unreachable loose objects that have
+// not been modified since olderThan, as well as the temporary files
+// left behind by interrupted writes.
+// A zero olderThan means now minus DefaultPruneExpiry. The grace
+// period protects the objects that are being written by a concurrent
+// process, and that are not referenced yet.
+// reachable is called for each loose object that is old enough, and
+// should return true if the object must be kept.
+// This method can be called concurrently
+func (b *Backend) PruneLooseObjects(olderThan time.Time, reachable func(ginternals.Oid) bool) (*PruneStats, error) {
+	if olderThan.IsZero() {
+		olderThan = time.Now().Add(-DefaultPruneExpiry)
+	}
+	stats := &PruneStats{}
+
+	oids := []ginternals.Oid{}
+	err := b.WalkLooseObjectIDs(func(oid ginternals.Oid) error {
+		oids = append(oids, oid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the loose objects: %w", err)
+	}
+
+	for _, oid := range oids {
+		stats.Scanned++
+		if err = b.pruneLooseObject(oid, olderThan, reachable, stats); err != nil {
+			return stats, err
+		}
+	}
+
+	if err = b.pruneTempObjects(olderThan, stats); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// pruneLooseObject removes the given loose object if it's old enough
+// and unreachable
+func (b *Backend) pruneLooseObject(oid ginternals.Oid, olderThan time.Time, reachable func(ginternals.Oid) bool, stats *PruneStats) error {
+	b.objectMu.Lock(oid[:])
+	defer b.objectMu.Unlock(oid[:])
+
+	p := b.looseObjectPath(ginternals.ObjectsPath(b.config), oid)
+	info, err := b.fs.Stat(p)
+	if err != nil {
+		// The object may have been removed by someone else
+		if errors.Is(err, os.ErrNotExist) {
+			b.looseObjects.Delete(oid)
+			return nil
+		}
+		return fmt.Errorf("could not stat object %s: %w", oid.String(), err)
+	}
+	if !info.ModTime().Before(olderThan) {
+		stats.Recent++
+		return nil
+	}
+	if reachable != nil && reachable(oid) {
+		stats.Reachable++
+		return nil
+	}
+
+	if err = b.fs.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove object %s: %w", oid.String(), err)
+	}
+	b.looseObjects.Delete(oid)
+	if b.cache != nil {
+		b.cache.Remove(oid)
+	}
+	stats.Pruned++
+	stats.FreedBytes += info.Size()
+
+	// Like git, we remove the fan-out directory once it's empty.
+	// This will fail if the directory still contains files, which is
+	// fine
+	b.fs.Remove(filepath.Dir(p)) //nolint:errcheck // the directory is expected to not be empty
+	return nil
+}
+
+// pruneTempObjects removes the temporary objects that have not been
+// modified since olderThan
+func (b *Backend) pruneTempObjects(olderThan time.Time, stats *PruneStats) error {
+	objectsPath := ginternals.ObjectsPath(b.config)
+	dirs, err := afero.ReadDir(b.fs, objectsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read %s: %w", objectsPath, err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || !b.layout.IsLooseObjectDir(dir.Name()) {
+			continue
+		}
+		dirPath := filepath.Join(objectsPath, dir.Name())
+		files, err := afero.ReadDir(b.fs, dirPath)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", dirPath, err)
+		}
+		for _, f := range files {
+			if f.IsDir() || !strings.HasPrefix(f.Name(), tmpObjectPrefix) || !f.ModTime().Before(olderThan) {
+				continue
+			}
+			p := filepath.Join(dirPath, f.Name())
+			if err = b.fs.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not remove %s: %w", p, err)
+			}
+			stats.TempFiles++
+			stats.FreedBytes += f.Size()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneLooseObjects(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, dir)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+	require.NoError(t, b.Init(ginternals.Master))
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	write := func(content string, modTime time.Time) ginternals.Oid {
+		oid, err := b.WriteObject(object.New(object.TypeBlob, []byte(content)))
+		require.NoError(t, err)
+		p := ginternals.LooseObjectPath(cfg, oid.String())
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+		return oid
+	}
+	reachableOid := write("reachable", old)
+	unreachableOid := write("unreachable", old)
+	recentOid := write("fresh", time.Now())
+
+	// a temporary file left behind by a crash
+	tmpPath := filepath.Join(filepath.Dir(ginternals.LooseObjectPath(cfg, recentOid.String())), tmpObjectPrefix+"123456")
+	require.NoError(t, os.WriteFile(tmpPath, []byte("data"), 0o444))
+	require.NoError(t, os.Chtimes(tmpPath, old, old))
+
+	stats, err := b.PruneLooseObjects(time.Time{}, func(oid ginternals.Oid) bool {
+		return oid == reachableOid
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Scanned)
+	assert.Equal(t, 1, stats.Pruned)
+	assert.Equal(t, 1, stats.Reachable)
+	assert.Equal(t, 1, stats.Recent)
+	assert.Equal(t, 1, stats.TempFiles)
+	assert.Positive(t, stats.FreedBytes)
+
+	_, err = b.Object(unreachableOid)
+	require.ErrorIs(t, err, ginternals.ErrObjectNotFound)
+	_, err = os.Stat(filepath.Dir(ginternals.LooseObjectPath(cfg, unreachableOid.String())))
+	assert.ErrorIs(t, err, os.ErrNotExist, "the empty fan-out directory should have been removed")
+	_, err = os.Stat(tmpPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the temporary file should have been removed")
+
+	_, err = b.Object(reachableOid)
+	require.NoError(t, err)
+	_, err = b.Object(recentOid)
+	require.NoError(t, err)
+}
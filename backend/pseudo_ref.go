@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+)
+
+// PseudoReference returns the pseudo-ref matching the given name.
+// ErrNotPseudoRef is returned if name is not a supported pseudo-ref,
+// ErrRefNotFound is returned if the pseudo-ref is not set.
+// This method can be called concurrently
+func (b *Backend) PseudoReference(name string) (*ginternals.Reference, error) {
+	if !ginternals.IsPseudoRef(name) {
+		return nil, fmt.Errorf("%s: %w", name, ginternals.ErrNotPseudoRef)
+	}
+	return b.Reference(name)
+}
+
+// WritePseudoReference sets the pseudo-ref matching the given name to
+// the provided oid. If the pseudo-ref is already set, it will be
+// overwritten.
+// ErrNotPseudoRef is returned if name is not a supported pseudo-ref
+func (b *Backend) WritePseudoReference(name string, target ginternals.Oid) error {
+	if !ginternals.IsPseudoRef(name) {
+		return fmt.Errorf("%s: %w", name, ginternals.ErrNotPseudoRef)
+	}
+	if target.IsZero() {
+		return fmt.Errorf("%s cannot target a null oid: %w", name, ginternals.ErrRefInvalid)
+	}
+
+	// Pseudo-refs are stored at the root of the git directory. They
+	// cannot conflict with any other references since they are not in
+	// refs/, so we don't need to go through writeReference()
+	data := append(target.AppendHex(make([]byte, 0, ginternals.OidHexSize+1)), '\n')
+	return b.writePseudoReferenceContent(name, data, target)
+}
+
+// writePseudoReferenceContent writes the raw content of a pseudo-ref.
+// target is the oid the pseudo-ref resolves to
+func (b *Backend) writePseudoReferenceContent(name string, data []byte, target ginternals.Oid) error {
+	if err := b.checkRefUpdatesAllowed(); err != nil {
+		return err
+	}
+	p := b.systemPath(name)
+	if err := lockfile.WriteFile(b.fs, p, data, b.lockOptions()); err != nil {
+		return fmt.Errorf("could not persist %s to disk: %w", name, err)
+	}
+	b.refs.Store(name, data)
+	return b.indexReference(ginternals.NewReference(name, target))
+}
+
+// DeletePseudoReference removes the pseudo-ref matching the given name.
+// ErrNotPseudoRef is returned if name is not a supported pseudo-ref,
+// ErrRefNotFound is returned if the pseudo-ref is not set.
+func (b *Backend) DeletePseudoReference(name string) error {
+	if !ginternals.IsPseudoRef(name) {
+		return fmt.Errorf("%s: %w", name, ginternals.ErrNotPseudoRef)
+	}
+	if err := b.checkRefUpdatesAllowed(); err != nil {
+		return err
+	}
+
+	err := b.fs.Remove(b.systemPath(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			b.refs.Delete(name)
+			return fmt.Errorf(`ref "%s": %w`, name, ginternals.ErrRefNotFound)
+		}
+		return fmt.Errorf("could not remove %s: %w", name, err)
+	}
+	b.refs.Delete(name)
+	return b.removeIndexedReference(name)
+}
+
+// MergeHeads returns the commits being merged, as stored in
+// MERGE_HEAD. An octopus merge has more than one commit.
+// ErrRefNotFound is returned if no merge is in progress
+func (b *Backend) MergeHeads() ([]ginternals.Oid, error) {
+	data, err := b.referenceContent(ginternals.MergeHead)
+	if err != nil {
+		return nil, err
+	}
+	oids := []ginternals.Oid{}
+	for _, line := range strings.Fields(string(data)) {
+		oid, err := ginternals.NewOidFromStr(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid %q in %s: %w", line, ginternals.MergeHead, ginternals.ErrRefInvalid)
+		}
+		oids = append(oids, oid)
+	}
+	if len(oids) == 0 {
+		return nil, fmt.Errorf("%s is empty: %w", ginternals.MergeHead, ginternals.ErrRefInvalid)
+	}
+	return oids, nil
+}
+
+// WriteMergeHeads sets MERGE_HEAD to the given commits, one per line.
+// If MERGE_HEAD is already set, it will be overwritten
+func (b *Backend) WriteMergeHeads(oids []ginternals.Oid) error {
+	if len(oids) == 0 {
+		return fmt.Errorf("%s needs at least one oid: %w", ginternals.MergeHead, ginternals.ErrRefInvalid)
+	}
+	data := make([]byte, 0, len(oids)*(ginternals.OidHexSize+1))
+	for _, oid := range oids {
+		if oid.IsZero() {
+			return fmt.Errorf("%s cannot target a null oid: %w", ginternals.MergeHead, ginternals.ErrRefInvalid)
+		}
+		data = append(oid.AppendHex(data), '\n')
+	}
+	return b.writePseudoReferenceContent(ginternals.MergeHead, data, oids[0])
+}
+
+// FetchHead returns the refs that have been fetched, as stored in
+// FETCH_HEAD.
+// ErrRefNotFound is returned if FETCH_HEAD doesn't exist
+func (b *Backend) FetchHead() ([]ginternals.FetchHeadEntry, error) {
+	data, err := b.referenceContent(ginternals.FetchHead)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ginternals.ParseFetchHead(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", ginternals.FetchHead, err)
+	}
+	return entries, nil
+}
+
+// WriteFetchHead sets FETCH_HEAD to the given entries.
+// If FETCH_HEAD is already set, it will be overwritten
+func (b *Backend) WriteFetchHead(entries []ginternals.FetchHeadEntry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("%s needs at least one entry: %w", ginternals.FetchHead, ginternals.ErrRefInvalid)
+	}
+	for _, e := range entries {
+		if e.ID.IsZero() {
+			return fmt.Errorf("%s cannot target a null oid: %w", ginternals.FetchHead, ginternals.ErrRefInvalid)
+		}
+	}
+	return b.writePseudoReferenceContent(ginternals.FetchHead, ginternals.FormatFetchHead(entries), entries[0].ID)
+}
@@ -0,0 +1,252 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPseudoReference(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should return an existing pseudo-ref", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		ref, err := b.PseudoReference(ginternals.OrigHead)
+		require.NoError(t, err)
+		assert.Equal(t, ginternals.OrigHead, ref.Name())
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", ref.Target().String())
+	})
+
+	t.Run("should fail on a pseudo-ref that isn't set", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		_, err = b.PseudoReference(ginternals.BisectHead)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefNotFound)
+	})
+
+	t.Run("should fail on regular refs", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		_, err = b.PseudoReference(ginternals.Head)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrNotPseudoRef)
+	})
+}
+
+func TestWritePseudoReference(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should write and delete a pseudo-ref", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		target, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+		require.NoError(t, err)
+
+		err = b.WritePseudoReference(ginternals.MergeHead, target)
+		require.NoError(t, err)
+
+		ref, err := b.PseudoReference(ginternals.MergeHead)
+		require.NoError(t, err)
+		assert.Equal(t, target, ref.Target())
+
+		p := filepath.Join(b.Path(), ginternals.MergeHead)
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		assert.Equal(t, target.String()+"\n", string(data))
+
+		err = b.DeletePseudoReference(ginternals.MergeHead)
+		require.NoError(t, err)
+		assert.NoFileExists(t, p)
+
+		_, err = b.PseudoReference(ginternals.MergeHead)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefNotFound)
+
+		err = b.DeletePseudoReference(ginternals.MergeHead)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefNotFound)
+	})
+
+	t.Run("should fail on invalid data", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		target, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+		require.NoError(t, err)
+
+		err = b.WritePseudoReference("refs/heads/master", target)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrNotPseudoRef)
+
+		err = b.WritePseudoReference(ginternals.OrigHead, ginternals.NullOid)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefInvalid)
+
+		err = b.DeletePseudoReference(ginternals.Head)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrNotPseudoRef)
+	})
+}
+
+func TestFetchHead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should read an existing FETCH_HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		entries, err := b.FetchHead()
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", entries[0].ID.String())
+		assert.False(t, entries[0].NotForMerge)
+		assert.Equal(t, "branch 'master' of github.com:Nivl/git-go", entries[0].Description)
+		assert.Equal(t, "5f35f2dc6cec7356da02ca26192ce2bc3f271e79", entries[1].ID.String())
+		assert.True(t, entries[1].NotForMerge)
+
+		// FETCH_HEAD should resolve to its first entry
+		ref, err := b.Reference(ginternals.FetchHead)
+		require.NoError(t, err)
+		assert.Equal(t, entries[0].ID, ref.Target())
+	})
+
+	t.Run("should write FETCH_HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		target, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+		require.NoError(t, err)
+		entries := []ginternals.FetchHeadEntry{
+			{ID: target, Description: "branch 'main' of example.com:repo"},
+		}
+		require.NoError(t, b.WriteFetchHead(entries))
+
+		data, err := os.ReadFile(filepath.Join(b.Path(), ginternals.FetchHead))
+		require.NoError(t, err)
+		assert.Equal(t, target.String()+"\t\tbranch 'main' of example.com:repo\n", string(data))
+
+		saved, err := b.FetchHead()
+		require.NoError(t, err)
+		assert.Equal(t, entries, saved)
+
+		err = b.WriteFetchHead(nil)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefInvalid)
+	})
+}
+
+func TestMergeHeads(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	_, err = b.MergeHeads()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ginternals.ErrRefNotFound)
+
+	a, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+	require.NoError(t, err)
+	c, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	require.NoError(t, b.WriteMergeHeads([]ginternals.Oid{a, c}))
+
+	data, err := os.ReadFile(filepath.Join(b.Path(), ginternals.MergeHead))
+	require.NoError(t, err)
+	assert.Equal(t, a.String()+"\n"+c.String()+"\n", string(data))
+
+	oids, err := b.MergeHeads()
+	require.NoError(t, err)
+	assert.Equal(t, []ginternals.Oid{a, c}, oids)
+
+	// MERGE_HEAD should resolve to the first commit
+	ref, err := b.PseudoReference(ginternals.MergeHead)
+	require.NoError(t, err)
+	assert.Equal(t, a, ref.Target())
+
+	err = b.WriteMergeHeads([]ginternals.Oid{a, ginternals.NullOid})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ginternals.ErrRefInvalid)
+}
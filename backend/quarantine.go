@@ -0,0 +1,271 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/fsutil"
+	"github.com/spf13/afero"
+)
+
+// quarantinePrefix is the prefix of the temporary object directories
+// used to quarantine the objects. This is the same prefix as git,
+// which allows git gc to remove the directories left behind by a
+// crash
+const quarantinePrefix = "tmp_objdir-incoming-"
+
+// Quarantine represents a temporary object directory in which the
+// objects received by a push are written until the push is accepted,
+// the same way git receive-pack does. This way the objects of a
+// rejected push never end up in the repository.
+// The objects of the repository can still be read from the
+// quarantine, but the references cannot be updated until the
+// objects are migrated.
+type Quarantine struct {
+	path    string
+	parent  *Backend
+	backend *Backend
+}
+
+// NewQuarantine creates a new quarantine in the object directory of
+// the repository.
+// Migrate() or Discard() must be called once the quarantine is not
+// needed anymore
+func (b *Backend) NewQuarantine() (q *Quarantine, err error) {
+	objectsDir := ginternals.ObjectsPath(b.config)
+	if err = b.mkdirAll(objectsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", objectsDir, err)
+	}
+	p, err := afero.TempDir(b.fs, objectsDir, quarantinePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the quarantine directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			b.fs.RemoveAll(p) //nolint:errcheck // it already failed
+		}
+	}()
+
+	// The quarantine uses the object directory of the repository as
+	// an alternate, so all the objects are available
+	cfg := *b.config
+	cfg.ObjectDirPath = p
+	cfg.AlternateObjectDirPaths = append([]string{objectsDir}, b.config.AlternateObjectDirPaths...)
+	cfg.QuarantinePath = p
+	qb, err := NewWithOptions(&cfg, b.fs, Options{
+		ScanIgnore:      b.scanIgnore,
+		StaleLockAge:    b.staleLockAge,
+		MmapPacks:       b.mmapPacks,
+		VerifyObjectCRC: b.verifyCRC,
+		Limits:          b.limits,
+		Layout:          b.layout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load the quarantine: %w", err)
+	}
+	return &Quarantine{
+		path:    p,
+		parent:  b,
+		backend: qb,
+	}, nil
+}
+
+// Path returns the path of the quarantine directory
+func (q *Quarantine) Path() string {
+	return q.path
+}
+
+// Backend returns a Backend that writes its objects in the
+// quarantine, and reads them from both the quarantine and the
+// repository.
+// Updating a reference using this backend returns
+// ginternals.ErrRefUpdateInQuarantine.
+// The Backend is closed by Migrate() and Discard()
+func (q *Quarantine) Backend() *Backend {
+	return q.backend
+}
+
+// Env returns the environment variables to give to the processes
+// (like the pre-receive hooks) that need to access the quarantined
+// objects, in the KEY=VALUE format
+func (q *Quarantine) Env() []string {
+	alternates := q.backend.config.AlternateObjectDirPaths
+	return []string{
+		"GIT_OBJECT_DIRECTORY=" + q.path,
+		"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + strings.Join(alternates, string(filepath.ListSeparator)),
+		"GIT_QUARANTINE_PATH=" + q.path,
+	}
+}
+
+// Migrate moves the quarantined objects to the object directory of
+// the repository, makes them available to the repository, and removes
+// the quarantine.
+// The packfiles are moved before the loose objects, and their index
+// is moved before them, so the objects are never referenced by
+// something that has not been moved yet.
+// The new objects are sent to the indexer of the repository, if any.
+// If the indexer fails, all the objects are still migrated, and an
+// error wrapping ErrIndexFailed is returned
+func (q *Quarantine) Migrate() error {
+	if err := q.backend.Close(); err != nil {
+		return fmt.Errorf("could not close the quarantine: %w", err)
+	}
+
+	files := []string{}
+	err := afero.Walk(q.parent.fs, q.path, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not list the quarantined objects: %w", err)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return migrationPriority(files[i]) < migrationPriority(files[j])
+	})
+
+	objectsDir := ginternals.ObjectsPath(q.parent.config)
+	var indexErr error
+	for _, src := range files {
+		rel, err := filepath.Rel(q.path, src)
+		if err != nil {
+			return fmt.Errorf("could not get the relative path of %s: %w", src, err)
+		}
+		// Files that are not objects (tmp files, ...) are not
+		// migrated
+		if migrationPriority(src) == migrateNever {
+			continue
+		}
+		dst := filepath.Join(objectsDir, rel)
+		if err = q.parent.mkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return fmt.Errorf("could not create %s: %w", filepath.Dir(dst), err)
+		}
+		// Objects are content-addressed, so a file that already
+		// exists in the repository has the same content
+		if _, err = q.parent.fs.Stat(dst); err == nil {
+			continue
+		}
+		if err = fsutil.MoveFile(q.parent.fs, src, dst); err != nil {
+			return fmt.Errorf("could not migrate %s: %w", rel, err)
+		}
+		if err = q.parent.loadMigratedFile(dst); err != nil {
+			// The object has been migrated, only its indexing
+			// failed
+			if errors.Is(err, ErrIndexFailed) {
+				if indexErr == nil {
+					indexErr = err
+				}
+				continue
+			}
+			return err
+		}
+	}
+
+	if err = q.parent.fs.RemoveAll(q.path); err != nil {
+		return fmt.Errorf("could not remove the quarantine: %w", err)
+	}
+	return indexErr
+}
+
+// Discard removes the quarantine and all the objects it contains
+func (q *Quarantine) Discard() error {
+	if err := q.backend.Close(); err != nil {
+		return fmt.Errorf("could not close the quarantine: %w", err)
+	}
+	if err := q.parent.fs.RemoveAll(q.path); err != nil {
+		return fmt.Errorf("could not remove the quarantine: %w", err)
+	}
+	return nil
+}
+
+// migration priorities of the files of a quarantine. The files with
+// the lowest priority are migrated first
+const (
+	migrateIndex = iota
+	migratePackfile
+	migratePackMarker
+	migrateLooseObject
+	migrateNever
+)
+
+// migrationPriority returns when the given file should be migrated
+func migrationPriority(path string) int {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, tmpObjectPrefix) || strings.HasPrefix(name, tmpPackPrefix) {
+		return migrateNever
+	}
+	if filepath.Base(filepath.Dir(path)) == "pack" {
+		switch filepath.Ext(name) {
+		case packfile.ExtIndex:
+			return migrateIndex
+		case packfile.ExtPackfile:
+			return migratePackfile
+		}
+		return migratePackMarker
+	}
+	return migrateLooseObject
+}
+
+// loadMigratedFile makes the object or the packfile at the given path
+// available
+func (b *Backend) loadMigratedFile(path string) error {
+	objectsDir := ginternals.ObjectsPath(b.config)
+	if filepath.Ext(path) == packfile.ExtPackfile {
+		pack, err := packfile.NewFromFileWithOptions(b.fs, path, packfile.Options{
+			Mmap:      b.mmapPacks,
+			VerifyCRC: b.verifyCRC,
+			Limits:    b.limits,
+		})
+		if err != nil {
+			return fmt.Errorf("could not parse packfile at %s: %w", path, err)
+		}
+		newObjects, err := b.newPackedObjects(pack)
+		if err != nil {
+			pack.Close() //nolint:errcheck // it already failed
+			return err
+		}
+		b.addPackfile(pack)
+		return b.indexObjects(newObjects)
+	}
+
+	rel, err := filepath.Rel(objectsDir, path)
+	if err != nil {
+		return fmt.Errorf("could not get the relative path of %s: %w", path, err)
+	}
+	oid, ok := b.layout.LooseObjectID(filepath.ToSlash(rel))
+	if !ok {
+		// Not an object, nothing to load
+		return nil
+	}
+	// The object may already be in a packfile
+	found := true
+	if b.indexer != nil {
+		if found, err = b.HasObject(oid); err != nil {
+			return fmt.Errorf("could not check if object %s exists: %w", oid.String(), err)
+		}
+	}
+	b.looseObjects.LoadOrStore(oid, objectsDir)
+	if !found {
+		return b.indexObjects([]ginternals.Oid{oid})
+	}
+	return nil
+}
+
+// checkRefUpdatesAllowed returns ginternals.ErrRefUpdateInQuarantine
+// if the objects are quarantined
+func (b *Backend) checkRefUpdatesAllowed() error {
+	if b.config.QuarantinePath != "" {
+		return ginternals.ErrRefUpdateInQuarantine
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantine(t *testing.T) {
+	t.Parallel()
+
+	// setup creates a quarantine containing a loose object and a
+	// packfile
+	setup := func(t *testing.T) (b *Backend, q *Quarantine, loose, packed ginternals.Oid) {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		q, err = b.NewQuarantine()
+		require.NoError(t, err)
+		assert.DirExists(t, q.Path())
+
+		loose, err = q.Backend().WriteObject(object.New(object.TypeBlob, []byte("loose")))
+		require.NoError(t, err)
+		packedObject := object.New(object.TypeBlob, []byte("packed"))
+		built, err := packfile.Build([]*packfile.BuildObject{
+			{Object: packedObject},
+		}, packfile.BuildOptions{})
+		require.NoError(t, err)
+		_, err = q.Backend().AddPackfile(built.Pack, built.Index)
+		require.NoError(t, err)
+		return b, q, loose, packedObject.ID()
+	}
+
+	t.Run("should isolate the objects", func(t *testing.T) {
+		t.Parallel()
+
+		b, q, loose, packed := setup(t)
+		t.Cleanup(func() {
+			require.NoError(t, q.Discard())
+		})
+
+		for _, oid := range []ginternals.Oid{loose, packed} {
+			_, err := b.Object(oid)
+			require.ErrorIs(t, err, ginternals.ErrObjectNotFound, oid.String())
+			_, err = q.Backend().Object(oid)
+			require.NoError(t, err, oid.String())
+		}
+
+		// The objects of the repository are still available
+		head, err := b.Reference(ginternals.Head)
+		require.NoError(t, err)
+		_, err = q.Backend().Object(head.Target())
+		require.NoError(t, err)
+
+		// The references cannot be updated
+		err = q.Backend().WriteReference(ginternals.NewReference("refs/heads/quarantined", loose))
+		require.ErrorIs(t, err, ginternals.ErrRefUpdateInQuarantine)
+
+		env := q.Env()
+		assert.Contains(t, env, "GIT_QUARANTINE_PATH="+q.Path())
+		assert.Contains(t, env, "GIT_OBJECT_DIRECTORY="+q.Path())
+	})
+
+	t.Run("Migrate should move the objects to the repository", func(t *testing.T) {
+		t.Parallel()
+
+		b, q, loose, packed := setup(t)
+		require.NoError(t, q.Migrate())
+		assert.NoDirExists(t, q.Path())
+
+		for _, oid := range []ginternals.Oid{loose, packed} {
+			_, err := b.Object(oid)
+			require.NoError(t, err, oid.String())
+		}
+		require.NoError(t, b.WriteReference(ginternals.NewReference("refs/heads/migrated", loose)))
+		assert.FileExists(t, ginternals.LooseObjectPath(b.config, loose.String()))
+	})
+
+	t.Run("Discard should remove the objects", func(t *testing.T) {
+		t.Parallel()
+
+		b, q, loose, packed := setup(t)
+		require.NoError(t, q.Discard())
+		assert.NoDirExists(t, q.Path())
+
+		for _, oid := range []ginternals.Oid{loose, packed} {
+			_, err := b.Object(oid)
+			require.ErrorIs(t, err, ginternals.ErrObjectNotFound, oid.String())
+		}
+	})
+}
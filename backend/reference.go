@@ -1,16 +1,17 @@
 package backend
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/Nivl/git-go/ginternals"
-	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/ginternals/lockfile"
 	"github.com/spf13/afero"
 )
 
@@ -18,21 +19,38 @@ import (
 // ErrRefNotFound is returned if the reference doesn't exists
 // This method can be called concurrently
 func (b *Backend) Reference(name string) (*ginternals.Reference, error) {
-	finder := func(name string) ([]byte, error) {
-		data, ok := b.refs.Load(name)
-		if !ok {
-			return nil, fmt.Errorf(`ref "%s": %w`, name, ginternals.ErrRefNotFound)
-		}
+	return ginternals.ResolveReference(name, b.referenceContent)
+}
+
+// UnresolvedReference returns a stored reference from its name,
+// without following its symbolic target. The returned reference
+// has no Target() if it's a symbolic reference.
+// ErrRefNotFound is returned if the reference doesn't exists
+// This method can be called concurrently
+func (b *Backend) UnresolvedReference(name string) (*ginternals.Reference, error) {
+	return ginternals.ReadReference(name, b.referenceContent)
+}
+
+// referenceContent returns the raw content of a reference
+func (b *Backend) referenceContent(name string) ([]byte, error) {
+	if data, ok := b.refs.Load(name); ok {
 		return data.([]byte), nil
 	}
-	return ginternals.ResolveReference(name, finder)
+	data, ok, err := b.packedReference(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf(`ref "%s": %w`, name, ginternals.ErrRefNotFound)
+	}
+	return data, nil
 }
 
-// systemPath returns a path from a ref name
+// systemPath returns a path from a ref name, using the layout of
+// the backend
 // Ex.: On windows refs/heads/master would return refs\heads\master
 func (b *Backend) systemPath(name string) string {
-	name = filepath.FromSlash(name)
-	return filepath.Join(b.Path(), name)
+	return ginternals.KeyPath(b.Path(), b.layout.RefKey(name))
 }
 
 // loadRefs loads the references in memory
@@ -41,36 +59,8 @@ func (b *Backend) loadRefs() (err error) {
 	// and may or may not contain outdated information
 	// (outdated information will be overwritten once we parse the
 	// on-disk references).
-	packedRefPath := ginternals.PackedRefsPath(b.config)
-	f, err := b.fs.Open(packedRefPath)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("could not open %s: %w", packedRefPath, err)
-	}
-	// if the file doesn't exist then there's nothing to do
-	if err == nil {
-		defer errutil.Close(f, &err)
-
-		sc := bufio.NewScanner(f)
-		for i := 1; sc.Scan(); i++ {
-			i++
-			line := sc.Text()
-			// we skip empty lines, comments, and annotated tag commit
-			if line == "" || line[0] == '#' || line[0] == '^' {
-				continue
-			}
-			// We expected data to have the format:
-			// "oid ref-name"
-			parts := strings.Split(line, " ")
-			if len(parts) != 2 {
-				return fmt.Errorf("could not parse %s, unexpected data line %d: %w", packedRefPath, i, ginternals.ErrPackedRefInvalid)
-			}
-			// the name of the ref is its UNIX path
-			b.refs.Store(filepath.ToSlash(parts[1]), []byte(parts[0]))
-		}
-
-		if sc.Err() != nil {
-			return fmt.Errorf("could not parse %s: %w", packedRefPath, err)
-		}
+	if err = b.loadPackedRefs(); err != nil {
+		return err
 	}
 
 	// Now we browse all the references on disk
@@ -100,8 +90,12 @@ func (b *Backend) loadRefs() (err error) {
 		if e != nil {
 			return e //nolint:wrapcheck // the error message is already pretty descriptive
 		}
-		// the name of the ref is its UNIX path
-		b.refs.Store(filepath.ToSlash(relpath), data)
+		// the keys of the layout are UNIX paths
+		name, ok := b.layout.RefName(filepath.ToSlash(relpath))
+		if !ok {
+			return nil
+		}
+		b.refs.Store(name, data)
 		return nil
 	})
 	if err != nil {
@@ -109,16 +103,9 @@ func (b *Backend) loadRefs() (err error) {
 	}
 
 	// Now we look for the special HEADs references:
-	headPaths := []string{
-		ginternals.Head,
-		// TODO(melvin): Removed until we support the format
-		// ginternals.FetchHead,
-		ginternals.OrigHead,
-		ginternals.MergeHead,
-		ginternals.CherryPickHead,
-	}
+	headPaths := append([]string{ginternals.Head}, ginternals.PseudoRefs()...)
 	for _, path := range headPaths {
-		data, err := afero.ReadFile(b.fs, filepath.Join(b.Path(), path))
+		data, err := afero.ReadFile(b.fs, b.systemPath(path))
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				continue
@@ -134,7 +121,7 @@ func (b *Backend) loadRefs() (err error) {
 // WriteReference writes the given reference on disk. If the
 // reference already exists it will be overwritten
 func (b *Backend) WriteReference(ref *ginternals.Reference) error {
-	return b.writeReference(ref)
+	return b.writeReference(ref, true)
 }
 
 // WriteReferenceSafe writes the given reference on disk.
@@ -143,19 +130,38 @@ func (b *Backend) WriteReferenceSafe(ref *ginternals.Reference) error {
 	if _, ok := b.refs.Load(ref.Name()); ok {
 		return ginternals.ErrRefExists
 	}
-	return b.writeReference(ref)
+	_, ok, err := b.packedReference(ref.Name())
+	if err != nil {
+		return err
+	}
+	if ok {
+		return ginternals.ErrRefExists
+	}
+	return b.writeReference(ref, false)
 }
 
 // writeReference writes the given reference on disk. If the
-// reference already exists it will be overwritten
-func (b *Backend) writeReference(ref *ginternals.Reference) error {
-	if !ginternals.IsRefNameValid(ref.Name()) {
-		return ginternals.ErrRefNameInvalid
+// reference already exists it will be overwritten, unless overwrite
+// is false, in which case ErrRefExists is returned
+func (b *Backend) writeReference(ref *ginternals.Reference, overwrite bool) error {
+	if err := b.checkRefUpdatesAllowed(); err != nil {
+		return err
+	}
+	if _, err := ginternals.CheckRefFormat(ref.Name(), ginternals.RefFormatOptions{AllowOneLevel: true}); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		if err := checkWindowsRefName(ref.Name()); err != nil {
+			return err
+		}
 	}
 
 	var target string
 	switch ref.Type() {
 	case ginternals.SymbolicReference:
+		if _, err := ginternals.CheckRefFormat(ref.SymbolicTarget(), ginternals.RefFormatOptions{AllowOneLevel: true}); err != nil {
+			return fmt.Errorf("invalid symbolic target: %w", err)
+		}
 		target = fmt.Sprintf("ref: %s\n", ref.SymbolicTarget())
 	case ginternals.OidReference:
 		target = fmt.Sprintf("%s\n", ref.Target().String())
@@ -198,30 +204,71 @@ func (b *Backend) writeReference(ref *ginternals.Reference) error {
 		return false
 	})
 
+	if conflictsOn == "" {
+		var err error
+		if conflictsOn, err = b.packedConflict(ref.Name()); err != nil {
+			return err
+		}
+	}
 	if conflictsOn != "" {
 		return fmt.Errorf("reference %s conflicts with %s: %w", ref.Name(), conflictsOn, ginternals.ErrRefInvalid)
 	}
+	if err := b.checkCaseConflict(ref.Name()); err != nil {
+		return err
+	}
 
 	// Let's persist the ref on disk
 	refPath := b.systemPath(ref.Name())
 	refDir := filepath.Dir(refPath)
-	err := b.fs.MkdirAll(refDir, 0o755)
+	err := b.mkdirAll(refDir, 0o755)
 	if err != nil {
 		return fmt.Errorf("could not persist reference to disk: %w", err)
 	}
-	// We can now create the actual file
-	data := []byte(target)
-	err = afero.WriteFile(b.fs, refPath, data, 0o644)
+	// We can now create the actual file. The reference is locked
+	// during the write so we don't conflict with other processes
+	lock, err := lockfile.New(b.fs, refPath, b.lockOptions())
 	if err != nil {
+		return fmt.Errorf("could not lock reference %s: %w", ref.Name(), err)
+	}
+	defer lock.Rollback() //nolint:errcheck // no-op if the lock is committed
+
+	// The reference may have been created by another process
+	if !overwrite {
+		if _, err = b.fs.Stat(refPath); err == nil {
+			return ginternals.ErrRefExists
+		}
+	}
+	data := []byte(target)
+	if _, err = lock.Write(data); err != nil {
+		return fmt.Errorf("could not persist reference to disk: %w", err)
+	}
+	if err = lock.Commit(); err != nil {
 		return fmt.Errorf("could not persist reference to disk: %w", err)
 	}
 	b.refs.Store(ref.Name(), data)
-	return nil
+	return b.indexReference(ref)
 }
 
 // WalkReferences runs the provided method on all the references
 func (b *Backend) WalkReferences(f RefWalkFunc) error {
+	return b.walkReferences(b.Reference, f)
+}
+
+// WalkUnresolvedReferences runs the provided method on all the
+// references, without following their symbolic target. Unlike
+// WalkReferences, this doesn't fail on the symbolic references that
+// target a reference that doesn't exist, like HEAD in an empty
+// repository.
+// The Target() of the symbolic references is NullOid
+func (b *Backend) WalkUnresolvedReferences(f RefWalkFunc) error {
+	return b.walkReferences(b.UnresolvedReference, f)
+}
+
+// walkReferences runs the provided method on all the references,
+// read using the provided method
+func (b *Backend) walkReferences(read func(name string) (*ginternals.Reference, error), f RefWalkFunc) error {
 	var topError error
+	stopped := false
 	b.refs.Range(func(key, value interface{}) bool {
 		name, ok := key.(string)
 		if !ok {
@@ -229,7 +276,7 @@ func (b *Backend) WalkReferences(f RefWalkFunc) error {
 			topError = fmt.Errorf("invalid key type for %s. expected string got %T", name, key)
 			return false
 		}
-		ref, err := b.Reference(name)
+		ref, err := read(name)
 		if err != nil {
 			topError = fmt.Errorf("could not resolve reference %s: %w", name, err)
 			return false
@@ -239,10 +286,130 @@ func (b *Backend) WalkReferences(f RefWalkFunc) error {
 			if err != WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
 				topError = err
 			}
+			stopped = true
 			return false
 		}
 		return true
 	})
+	if topError != nil || stopped {
+		return topError
+	}
+
+	// We now walk the references that are only in the packed-refs
+	// file, if it hasn't been loaded in memory
+	err := b.walkPackedReferences(func(name string, target []byte) error {
+		if _, ok := b.refs.Load(name); ok {
+			return nil
+		}
+		ref, err := read(name)
+		if err != nil {
+			return fmt.Errorf("could not resolve reference %s: %w", name, err)
+		}
+		return f(ref)
+	})
+	if err != nil && err != WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+		return err
+	}
+	return nil
+}
+
+// packedConflict returns the name of the reference of the packed-refs
+// file that conflicts with the given name, if any.
+// Only the packed-refs files that haven't been loaded in memory are
+// checked
+func (b *Backend) packedConflict(name string) (string, error) {
+	b.packedRefsMu.RLock()
+	defer b.packedRefsMu.RUnlock()
+	if b.packedRefs == nil {
+		return "", nil
+	}
 
-	return topError
+	// refs/heads/master conflicts with refs/heads/master/foo, and
+	// refs/heads
+	for i := 0; i < len(name); i++ {
+		if name[i] != '/' {
+			continue
+		}
+		_, ok, err := b.packedRefs.lookup(name[:i])
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return name[:i], nil
+		}
+	}
+	return b.packedRefs.hasPrefix(name + "/")
+}
+
+// ReferenceInfo contains a reference and the information needed to
+// advertise it to a remote
+type ReferenceInfo struct {
+	*ginternals.Reference
+	// Peeled contains the object targeted by the reference once all
+	// the annotated tags have been followed.
+	// It's ginternals.NullOid if the reference doesn't target an
+	// annotated tag
+	Peeled ginternals.Oid
+}
+
+// ReferencesWithPrefix returns, sorted by name, all the references
+// that start with any of the provided prefixes. All the references
+// are returned if no prefixes are provided. The pseudo-refs (like
+// ORIG_HEAD) are never returned.
+// The symbolic target of a reference is available through
+// SymbolicTarget(). The symbolic references that target a reference
+// that doesn't exist (like HEAD in an empty repository) are returned
+// with a NullOid Target().
+// This is used to list the references of a repository in a single
+// pass, like the ls-refs command of the protocol v2 does
+func (b *Backend) ReferencesWithPrefix(prefixes []string) ([]*ReferenceInfo, error) {
+	refs := []*ReferenceInfo{}
+	err := b.WalkUnresolvedReferences(func(ref *ginternals.Reference) error {
+		if ginternals.IsPseudoRef(ref.Name()) || !hasAnyPrefix(ref.Name(), prefixes) {
+			return nil
+		}
+		if ref.Type() == ginternals.SymbolicReference {
+			resolved, err := b.Reference(ref.Name())
+			if err != nil && !errors.Is(err, ginternals.ErrRefNotFound) {
+				return fmt.Errorf("could not resolve reference %s: %w", ref.Name(), err)
+			}
+			// A dangling symbolic reference is returned as-is
+			if resolved == nil {
+				refs = append(refs, &ReferenceInfo{Reference: ref})
+				return nil
+			}
+			ref = resolved
+		}
+		peeled, err := b.Peel(ref.Target())
+		if err != nil {
+			return fmt.Errorf("could not peel %s: %w", ref.Name(), err)
+		}
+		info := &ReferenceInfo{Reference: ref}
+		if peeled != ref.Target() {
+			info.Peeled = peeled
+		}
+		refs = append(refs, info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the references: %w", err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+	return refs, nil
+}
+
+// hasAnyPrefix returns whether s starts with any of the provided
+// prefixes. true is returned if there are no prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
 }
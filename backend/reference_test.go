@@ -6,10 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/lockfile"
 	"github.com/Nivl/git-go/internal/testutil"
 	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -82,6 +85,53 @@ func TestReference(t *testing.T) {
 	})
 }
 
+func TestUnresolvedReference(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	t.Run("Should not follow a symbolic ref", func(t *testing.T) {
+		t.Parallel()
+
+		// create a symbolic ref targeting a branch that doesn't exist
+		require.NoError(t, b.WriteReference(ginternals.NewSymbolicReference("refs/heads/orphan-head", "refs/heads/orphan")))
+		ref, err := b.UnresolvedReference("refs/heads/orphan-head")
+		require.NoError(t, err)
+		assert.Equal(t, ginternals.SymbolicReference, ref.Type())
+		assert.Equal(t, "refs/heads/orphan", ref.SymbolicTarget())
+		assert.True(t, ref.Target().IsZero())
+
+		_, err = b.Reference("refs/heads/orphan-head")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefNotFound), "unexpected error returned: %v", err)
+	})
+
+	t.Run("Should return an oid ref", func(t *testing.T) {
+		t.Parallel()
+
+		ref, err := b.UnresolvedReference(ginternals.LocalBranchFullName(ginternals.Master))
+		require.NoError(t, err)
+		assert.Equal(t, ginternals.OidReference, ref.Type())
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", ref.Target().String())
+	})
+
+	t.Run("Should fail if reference doesn't exists", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := b.UnresolvedReference("refs/heads/doesnt_exists")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefNotFound), "unexpected error returned: %v", err)
+	})
+}
+
 func TestParsePackedRefs(t *testing.T) {
 	t.Parallel()
 
@@ -168,6 +218,7 @@ func TestParsePackedRefs(t *testing.T) {
 		expected := map[string][]byte{
 			"HEAD":                                  []byte("ref: refs/heads/ml/packfile/tests\n"),
 			"ORIG_HEAD":                             []byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089\n"),
+			"FETCH_HEAD":                            []byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089\t\tbranch 'master' of github.com:Nivl/git-go\n5f35f2dc6cec7356da02ca26192ce2bc3f271e79\tnot-for-merge\tbranch 'ml/feat/clone' of github.com:Nivl/git-go\n"),
 			"refs/heads/master":                     []byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089"),
 			"refs/heads/ml/cleanup-062020":          []byte("b328320060eb503cf337c7cff281712ef236963a"),
 			"refs/heads/ml/packfile/tests":          []byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089"),
@@ -266,6 +317,26 @@ func TestWriteReference(t *testing.T) {
 		require.True(t, errors.Is(err, ginternals.ErrRefNameInvalid), "unexpected error")
 	})
 
+	t.Run("should fail if the symbolic target is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		ref := ginternals.NewSymbolicReference("refs/heads/symbolic", "refs/heads/ma..ster")
+		err = b.WriteReference(ref)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ginternals.ErrRefNameInvalid), "unexpected error")
+	})
+
 	t.Run("should pass overwriting a symbolic reference", func(t *testing.T) {
 		t.Parallel()
 
@@ -370,9 +441,71 @@ func TestWriteReference(t *testing.T) {
 		ref = ginternals.NewReference("ml/tests/references", target)
 		err = b.WriteReference(ref)
 		require.Error(t, err)
-		// TODO(melvin): check error type. Windows doesn't fail on the MkdirAll
-		// Making it hard to have a cross-platform test right now.
-		// require.Contains(t, err.Error(), "not a directory")
+		// The conflict is detected before touching the filesystem, so
+		// we get the same error on all platforms
+		require.True(t, errors.Is(err, ginternals.ErrRefInvalid), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail writing a locked reference", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		// Simulate another process updating HEAD
+		lockPath := filepath.Join(b.Path(), "HEAD.lock")
+		require.NoError(t, os.WriteFile(lockPath, []byte("ref: refs/heads/master\n"), 0o644))
+
+		ref := ginternals.NewSymbolicReference("HEAD", "refs/heads/ml/tests")
+		err = b.WriteReference(ref)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+
+		// let's make sure the data have not changed
+		data, err := os.ReadFile(filepath.Join(b.Path(), "HEAD"))
+		require.NoError(t, err)
+		assert.Equal(t, "ref: refs/heads/ml/packfile/tests\n", string(data))
+		data, err = os.ReadFile(lockPath)
+		require.NoError(t, err)
+		assert.Equal(t, "ref: refs/heads/master\n", string(data), "the lock should not have been touched")
+	})
+
+	t.Run("should remove stale locks", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		b, err := NewWithOptions(cfg, afero.NewOsFs(), Options{
+			StaleLockAge: time.Minute,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		// Simulate a process that crashed while updating HEAD
+		lockPath := filepath.Join(b.Path(), "HEAD.lock")
+		require.NoError(t, os.WriteFile(lockPath, []byte("ref: refs/heads/master\n"), 0o644))
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(lockPath, old, old))
+
+		ref := ginternals.NewSymbolicReference("HEAD", "refs/heads/ml/tests")
+		require.NoError(t, b.WriteReference(ref))
+
+		data, err := os.ReadFile(filepath.Join(b.Path(), "HEAD"))
+		require.NoError(t, err)
+		assert.Equal(t, "ref: refs/heads/ml/tests\n", string(data))
+		_, err = os.Stat(lockPath)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "the lock should have been removed")
 	})
 
 	t.Run("validate name", func(t *testing.T) {
@@ -637,3 +770,110 @@ func TestWalkReferences(t *testing.T) {
 		assert.ErrorIs(t, err, someError)
 	})
 }
+
+func TestWalkUnresolvedReferences(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should not fail on an unborn HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		// WalkReferences cannot resolve HEAD
+		err = b.WalkReferences(func(ref *ginternals.Reference) error {
+			return nil
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ginternals.ErrRefNotFound)
+
+		refs := []*ginternals.Reference{}
+		err = b.WalkUnresolvedReferences(func(ref *ginternals.Reference) error {
+			refs = append(refs, ref)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		assert.Equal(t, ginternals.Head, refs[0].Name())
+		assert.Equal(t, ginternals.SymbolicReference, refs[0].Type())
+		assert.Equal(t, "refs/heads/master", refs[0].SymbolicTarget())
+		assert.True(t, refs[0].Target().IsZero())
+	})
+}
+
+func TestReferencesWithPrefix(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	t.Run("should return the matching references", func(t *testing.T) {
+		t.Parallel()
+
+		refs, err := b.ReferencesWithPrefix([]string{"refs/tags/", "HEAD"})
+		require.NoError(t, err)
+		require.Len(t, refs, 3)
+
+		assert.Equal(t, "HEAD", refs[0].Name())
+		assert.Equal(t, "refs/heads/ml/packfile/tests", refs[0].SymbolicTarget())
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", refs[0].Target().String())
+		assert.True(t, refs[0].Peeled.IsZero())
+
+		assert.Equal(t, "refs/tags/annotated", refs[1].Name())
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", refs[1].Target().String())
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", refs[1].Peeled.String())
+
+		assert.Equal(t, "refs/tags/lightweight", refs[2].Name())
+		assert.True(t, refs[2].Peeled.IsZero())
+	})
+
+	t.Run("should return all the references without prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		refs, err := b.ReferencesWithPrefix(nil)
+		require.NoError(t, err)
+		// generated using git for-each-ref | wc -l, +1 for HEAD
+		require.Len(t, refs, 12)
+		for _, ref := range refs {
+			assert.False(t, ginternals.IsPseudoRef(ref.Name()), "%s should not be returned", ref.Name())
+		}
+	})
+
+	t.Run("should return an unborn HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		refs, err := b.ReferencesWithPrefix(nil)
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		assert.Equal(t, ginternals.Head, refs[0].Name())
+		assert.Equal(t, "refs/heads/master", refs[0].SymbolicTarget())
+		assert.True(t, refs[0].Target().IsZero(), "an unborn HEAD should not have a target")
+		assert.True(t, refs[0].Peeled.IsZero())
+	})
+}
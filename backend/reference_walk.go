@@ -0,0 +1,200 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/pathspec"
+)
+
+// RefSort represents the order in which the references are walked
+type RefSort int8
+
+const (
+	// RefSortNone walks the references in no particular order.
+	// This is the fastest
+	RefSortNone RefSort = iota
+	// RefSortName walks the references sorted by name
+	RefSortName
+	// RefSortCommitterDate walks the references sorted by the date
+	// of the commit they target, oldest first. The references that
+	// don't target a commit come first.
+	// All the references need to be resolved before the walk starts
+	RefSortCommitterDate
+)
+
+// WalkReferencesOptions represents the options that can be used to
+// walk the references
+type WalkReferencesOptions struct {
+	// Pattern contains the pattern the references need to match to be
+	// walked. Like git for-each-ref, a reference matches if the
+	// pattern matches its name using wildmatch, or if the pattern is
+	// a prefix of its name ending at a "/" (ex. refs/heads matches
+	// refs/heads/master).
+	// Defaults to all the references
+	Pattern string
+	// Sort sets the order of the walk
+	Sort RefSort
+	// Descending reverses the order of the walk
+	Descending bool
+}
+
+// WalkReferencesWithOptions works like WalkReferences, but uses the
+// provided options.
+// Only the names of the references are loaded upfront, the
+// references themselves are resolved as they are walked, unless they
+// are sorted by date
+func (b *Backend) WalkReferencesWithOptions(opts WalkReferencesOptions, f RefWalkFunc) error {
+	names, err := b.referenceNames(opts.Pattern)
+	if err != nil {
+		return err
+	}
+
+	var refs []*ginternals.Reference
+	switch opts.Sort {
+	case RefSortNone:
+	case RefSortName:
+		sort.Strings(names)
+	case RefSortCommitterDate:
+		if refs, err = b.referencesByDate(names); err != nil {
+			return err
+		}
+	}
+	if opts.Descending {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+		for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+			refs[i], refs[j] = refs[j], refs[i]
+		}
+	}
+
+	for i, name := range names {
+		var ref *ginternals.Reference
+		if refs != nil {
+			ref = refs[i]
+		} else if ref, err = b.Reference(name); err != nil {
+			return fmt.Errorf("could not resolve reference %s: %w", name, err)
+		}
+		if err = f(ref); err != nil {
+			if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ReferencesGlob returns, sorted by name, all the references matching
+// the given pattern. See WalkReferencesOptions.Pattern for the
+// syntax of the pattern
+func (b *Backend) ReferencesGlob(pattern string) ([]*ginternals.Reference, error) {
+	refs := []*ginternals.Reference{}
+	opts := WalkReferencesOptions{
+		Pattern: pattern,
+		Sort:    RefSortName,
+	}
+	err := b.WalkReferencesWithOptions(opts, func(ref *ginternals.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// referenceNames returns the names of all the references matching
+// the given pattern, in no particular order
+func (b *Backend) referenceNames(pattern string) ([]string, error) {
+	names := []string{}
+	b.refs.Range(func(key, value interface{}) bool {
+		if name, ok := key.(string); ok && refMatchesPattern(name, pattern) {
+			names = append(names, name)
+		}
+		return true
+	})
+
+	// The references that are only in the packed-refs file haven't
+	// been loaded in memory
+	err := b.walkPackedReferences(func(name string, target []byte) error {
+		if _, ok := b.refs.Load(name); !ok && refMatchesPattern(name, pattern) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the packed references: %w", err)
+	}
+	return names, nil
+}
+
+// referencesByDate resolves the given references and sorts them by
+// the date of the commit they target. The names are sorted the same
+// way as the returned references
+func (b *Backend) referencesByDate(names []string) ([]*ginternals.Reference, error) {
+	sort.Strings(names)
+	refs := make([]*ginternals.Reference, len(names))
+	dates := make(map[string]time.Time, len(names))
+	for i, name := range names {
+		ref, err := b.Reference(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve reference %s: %w", name, err)
+		}
+		refs[i] = ref
+		if dates[name], err = b.committerDate(ref.Target()); err != nil {
+			return nil, fmt.Errorf("could not get the date of %s: %w", name, err)
+		}
+	}
+
+	// The references are sorted by name first, so the ones with the
+	// same date stay sorted by name
+	sort.SliceStable(refs, func(i, j int) bool {
+		return dates[refs[i].Name()].Before(dates[refs[j].Name()])
+	})
+	for i, ref := range refs {
+		names[i] = ref.Name()
+	}
+	return refs, nil
+}
+
+// committerDate returns the committer date of the commit targeted by
+// the given object. A zero time is returned if the object doesn't
+// lead to a commit
+func (b *Backend) committerDate(oid ginternals.Oid) (time.Time, error) {
+	peeled, err := b.Peel(oid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	o, err := b.Object(peeled)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not get object %s: %w", peeled.String(), err)
+	}
+	if o.Type() != object.TypeCommit {
+		return time.Time{}, nil
+	}
+	c, err := o.AsCommit()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse commit %s: %w", peeled.String(), err)
+	}
+	return c.Committer().Time, nil
+}
+
+// refMatchesPattern returns whether the name of a reference matches
+// the given pattern, the same way git for-each-ref does
+func refMatchesPattern(name, pattern string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.HasPrefix(name, pattern) {
+		if len(name) == len(pattern) || strings.HasSuffix(pattern, "/") || name[len(pattern)] == '/' {
+			return true
+		}
+	}
+	return pathspec.Wildmatch(pattern, name, true)
+}
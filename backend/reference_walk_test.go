@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencesGlob(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	// The expected values have been generated using
+	// git for-each-ref --format='%(refname)' <pattern>
+	testCases := []struct {
+		desc     string
+		pattern  string
+		expected []string
+	}{
+		{
+			desc:     "a prefix should match the refs under it",
+			pattern:  "refs/tags",
+			expected: []string{"refs/tags/annotated", "refs/tags/lightweight"},
+		},
+		{
+			desc:     "a prefix should stop at a slash",
+			pattern:  "refs/heads/ma",
+			expected: []string{},
+		},
+		{
+			desc:     "a wildcard should not match a slash",
+			pattern:  "refs/heads/ml/*",
+			expected: []string{"refs/heads/ml/cleanup-062020", "refs/heads/ml/tests"},
+		},
+		{
+			desc:     "a double star should match a slash",
+			pattern:  "refs/**/master",
+			expected: []string{"refs/heads/master", "refs/remotes/origin/master"},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			refs, err := b.ReferencesGlob(tc.pattern)
+			require.NoError(t, err)
+			names := make([]string, 0, len(refs))
+			for _, ref := range refs {
+				names = append(names, ref.Name())
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestWalkReferencesWithOptions(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	walk := func(t *testing.T, opts WalkReferencesOptions) []string {
+		t.Helper()
+
+		names := []string{}
+		err := b.WalkReferencesWithOptions(opts, func(ref *ginternals.Reference) error {
+			names = append(names, ref.Name())
+			return nil
+		})
+		require.NoError(t, err)
+		return names
+	}
+
+	t.Run("should sort by name", func(t *testing.T) {
+		t.Parallel()
+
+		names := walk(t, WalkReferencesOptions{
+			Pattern:    "refs/heads",
+			Sort:       RefSortName,
+			Descending: true,
+		})
+		expected := []string{
+			"refs/heads/ml/tests",
+			"refs/heads/ml/packfile/tests",
+			"refs/heads/ml/cleanup-062020",
+			"refs/heads/master",
+		}
+		assert.Equal(t, expected, names)
+	})
+
+	t.Run("should sort by committer date", func(t *testing.T) {
+		t.Parallel()
+
+		// generated using
+		// git for-each-ref --sort=committerdate --format='%(refname)' refs/heads
+		names := walk(t, WalkReferencesOptions{
+			Pattern: "refs/heads",
+			Sort:    RefSortCommitterDate,
+		})
+		expected := []string{
+			"refs/heads/ml/tests",
+			"refs/heads/ml/cleanup-062020",
+			"refs/heads/master",
+			"refs/heads/ml/packfile/tests",
+		}
+		assert.Equal(t, expected, names)
+	})
+
+	t.Run("should stop when asked", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := b.WalkReferencesWithOptions(WalkReferencesOptions{Sort: RefSortName}, func(ref *ginternals.Reference) error {
+			count++
+			return WalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/reflog"
+	"github.com/Nivl/git-go/internal/errutil"
+)
+
+// Reflog returns the reflog of the given reference, oldest entry
+// first. An empty list is returned if the reference has no reflog.
+// This method can be called concurrently
+func (b *Backend) Reflog(name string) (entries []*reflog.Entry, err error) {
+	p := ginternals.ReflogPath(b.config, name)
+	f, err := b.fs.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*reflog.Entry{}, nil
+		}
+		return nil, fmt.Errorf("could not open %s: %w", p, err)
+	}
+	defer errutil.Close(f, &err)
+
+	entries, err = reflog.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", p, err)
+	}
+	return entries, nil
+}
+
+// AppendReflog adds an entry at the end of the reflog of the given
+// reference. The reflog is created if it doesn't exist
+func (b *Backend) AppendReflog(name string, e *reflog.Entry) (err error) {
+	p := ginternals.ReflogPath(b.config, name)
+	if err = b.mkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("could not create the directory of %s: %w", p, err)
+	}
+	f, err := b.fs.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, b.filePerm(0o644))
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", p, err)
+	}
+	defer errutil.Close(f, &err)
+	if err = b.chmod(p, 0o644); err != nil {
+		return err
+	}
+
+	if _, err = f.Write([]byte(e.String() + "\n")); err != nil {
+		return fmt.Errorf("could not write to %s: %w", p, err)
+	}
+	return nil
+}
+
+// HasReflog returns whether the given reference has a reflog
+func (b *Backend) HasReflog(name string) (bool, error) {
+	_, err := b.fs.Stat(ginternals.ReflogPath(b.config, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check the reflog of %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/reflog"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReflog(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	t.Run("should return the entries of an existing reflog", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := b.Reflog("refs/stash")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.True(t, entries[0].Old.IsZero())
+		assert.Equal(t, "3fe6cf63fceced491a79fe634eb1e2c888225707", entries[0].New.String())
+		assert.Equal(t, "Melvin Laplanche", entries[0].Committer.Name)
+		assert.Equal(t, "WIP on tests: f0f7014 refactor: Update codebase to go 1.13", entries[0].Message)
+	})
+
+	t.Run("should return the reflog of HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := b.Reflog("HEAD")
+		require.NoError(t, err)
+		require.NotEmpty(t, entries)
+		assert.Equal(t, "clone: from git@github.com:Nivl/git-go.git", entries[0].Message)
+	})
+
+	t.Run("should return nothing for refs without reflog", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := b.Reflog("refs/heads/does-not-exist")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestAppendReflog(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	oid, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	entry := &reflog.Entry{
+		New:       oid,
+		Committer: object.NewSignature("Committer", "committer@domain.tld"),
+		Message:   "branch: Created from HEAD",
+	}
+
+	t.Run("should create the reflog", func(t *testing.T) {
+		t.Parallel()
+
+		name := "refs/heads/new/branch"
+		hasReflog, err := b.HasReflog(name)
+		require.NoError(t, err)
+		require.False(t, hasReflog)
+
+		require.NoError(t, b.AppendReflog(name, entry))
+		hasReflog, err = b.HasReflog(name)
+		require.NoError(t, err)
+		require.True(t, hasReflog)
+
+		entries, err := b.Reflog(name)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, oid, entries[0].New)
+		assert.Equal(t, "Committer", entries[0].Committer.Name)
+		assert.Equal(t, entry.Message, entries[0].Message)
+	})
+
+	t.Run("should append to an existing reflog", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := b.Reflog("refs/stash")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		require.NoError(t, b.AppendReflog("refs/stash", entry))
+		entries, err = b.Reflog("refs/stash")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "3fe6cf63fceced491a79fe634eb1e2c888225707", entries[0].New.String())
+		assert.Equal(t, oid, entries[1].New)
+	})
+}
@@ -0,0 +1,102 @@
+package remotecache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+)
+
+// ErrUnexpectedStatus is returned when the server returns an
+// unexpected HTTP status
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// Make sure Client implements backend.ObjectCache
+var _ backend.ObjectCache = (*Client)(nil)
+
+// Client is a backend.ObjectCache that stores objects on a remote
+// server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// DefaultTimeout is the timeout of the requests sent by a Client
+// created without an HTTP client
+const DefaultTimeout = 30 * time.Second
+
+// NewClient returns a new Client that will talk to the server located
+// at baseURL.
+// An HTTP client using DefaultTimeout is used if httpClient is nil.
+// Since the cache is queried every time an object is missing
+// locally, the provided client should have a timeout
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: DefaultTimeout,
+		}
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// GetObjects returns the objects matching the provided oids.
+// Objects that are not in the cache are omitted from the result
+func (c *Client) GetObjects(oids []ginternals.Oid) (objects []*object.Object, err error) {
+	if len(oids) == 0 {
+		return []*object.Object{}, nil
+	}
+
+	body := &bytes.Buffer{}
+	if err = encodeOids(body, oids); err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Post(c.baseURL+PathGet, "text/plain", body) //nolint:noctx // the ObjectCache interface has no context
+	if err != nil {
+		return nil, fmt.Errorf("could not query the cache: %w", err)
+	}
+	defer errutil.Close(res.Body, &err)
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, res.Body) //nolint:errcheck // we're only draining the body so the connection can be reused
+		return nil, fmt.Errorf("got %d: %w", res.StatusCode, ErrUnexpectedStatus)
+	}
+	objects, err = decodeObjects(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the response: %w", err)
+	}
+	return objects, nil
+}
+
+// PutObjects adds the provided objects to the cache
+func (c *Client) PutObjects(objects []*object.Object) (err error) {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	body := &bytes.Buffer{}
+	if err = encodeObjects(body, objects); err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(c.baseURL+PathPut, "application/octet-stream", body) //nolint:noctx // the ObjectCache interface has no context
+	if err != nil {
+		return fmt.Errorf("could not send the objects to the cache: %w", err)
+	}
+	defer errutil.Close(res.Body, &err)
+	io.Copy(io.Discard, res.Body) //nolint:errcheck // we're only draining the body so the connection can be reused
+
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("got %d: %w", res.StatusCode, ErrUnexpectedStatus)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package remotecache
+
+import (
+	"sync"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// Make sure Memory implements backend.ObjectCache
+var _ backend.ObjectCache = (*Memory)(nil)
+
+// Memory is a backend.ObjectCache that keeps all the objects in memory.
+// It can be used with NewHandler to run a simple cache server
+type Memory struct {
+	objects sync.Map
+}
+
+// NewMemory returns a new empty in-memory cache
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// GetObjects returns the objects matching the provided oids.
+// Objects that are not in the cache are omitted from the result
+func (m *Memory) GetObjects(oids []ginternals.Oid) ([]*object.Object, error) {
+	objects := make([]*object.Object, 0, len(oids))
+	for _, oid := range oids {
+		if o, found := m.objects.Load(oid); found {
+			objects = append(objects, o.(*object.Object))
+		}
+	}
+	return objects, nil
+}
+
+// PutObjects adds the provided objects to the cache
+func (m *Memory) PutObjects(objects []*object.Object) error {
+	for _, o := range objects {
+		m.objects.Store(o.ID(), o)
+	}
+	return nil
+}
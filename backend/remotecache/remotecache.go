@@ -0,0 +1,130 @@
+// Package remotecache contains an experimental implementation of
+// backend.ObjectCache that stores the objects on a remote server using
+// a simple HTTP protocol.
+//
+// The protocol has 2 endpoints:
+//
+// POST /objects/get: the body contains the hex oids of the objects to
+// retrieve, one per line. The response contains the objects that have
+// been found, encoded as described below.
+//
+// POST /objects/put: the body contains the objects to store, encoded
+// as described below. The server responds with a 204 on success.
+//
+// An encoded object is a header "<oid> <type> <size>\n" directly
+// followed by the raw content of the object (size bytes). Multiple
+// objects are stored back-to-back.
+package remotecache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// List of the paths of the endpoints
+const (
+	PathGet = "/objects/get"
+	PathPut = "/objects/put"
+)
+
+// maxObjectSize is the maximum size of an object we accept to decode.
+// It prevents a malicious or buggy peer from making us allocate
+// an absurd amount of memory
+const maxObjectSize = 1 << 30
+
+// ErrInvalidPayload is returned when a payload could not be decoded
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// encodeObjects writes the given objects to w
+func encodeObjects(w io.Writer, objects []*object.Object) error {
+	for _, o := range objects {
+		if _, err := fmt.Fprintf(w, "%s %s %d\n", o.ID().String(), o.Type().String(), o.Size()); err != nil {
+			return fmt.Errorf("could not write header of %s: %w", o.ID().String(), err)
+		}
+		if _, err := w.Write(o.Bytes()); err != nil {
+			return fmt.Errorf("could not write content of %s: %w", o.ID().String(), err)
+		}
+	}
+	return nil
+}
+
+// decodeObjects reads all the objects contained in r.
+// The oid of each object is verified against its content
+func decodeObjects(r io.Reader) ([]*object.Object, error) {
+	objects := []*object.Object{}
+	buf := bufio.NewReader(r)
+	for i := 1; ; i++ {
+		header, err := buf.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && header == "" {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("could not read header of object %d: %w", i, err)
+		}
+
+		parts := strings.Split(strings.TrimSuffix(header, "\n"), " ")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed header for object %d: %w", i, ErrInvalidPayload)
+		}
+		oid, err := ginternals.NewOidFromStr(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid for object %d: %w", i, ErrInvalidPayload)
+		}
+		typ, err := object.NewTypeFromString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid type for object %s: %w", parts[0], ErrInvalidPayload)
+		}
+		size, err := strconv.Atoi(parts[2])
+		if err != nil || size < 0 || size > maxObjectSize {
+			return nil, fmt.Errorf("invalid size for object %s: %w", parts[0], ErrInvalidPayload)
+		}
+
+		content := make([]byte, size)
+		if _, err = io.ReadFull(buf, content); err != nil {
+			return nil, fmt.Errorf("could not read content of object %s: %w", parts[0], err)
+		}
+		o := object.New(typ, content)
+		if o.ID() != oid {
+			return nil, fmt.Errorf("object %s has the content of %s: %w", parts[0], o.ID().String(), ErrInvalidPayload)
+		}
+		objects = append(objects, o)
+	}
+}
+
+// encodeOids writes the given oids to w, one per line
+func encodeOids(w io.Writer, oids []ginternals.Oid) error {
+	for _, oid := range oids {
+		if _, err := fmt.Fprintln(w, oid.String()); err != nil {
+			return fmt.Errorf("could not write oid %s: %w", oid.String(), err)
+		}
+	}
+	return nil
+}
+
+// decodeOids reads all the oids contained in r
+func decodeOids(r io.Reader) ([]ginternals.Oid, error) {
+	oids := []ginternals.Oid{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		oid, err := ginternals.NewOidFromStr(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid %s: %w", line, ErrInvalidPayload)
+		}
+		oids = append(oids, oid)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the oids: %w", err)
+	}
+	return oids, nil
+}
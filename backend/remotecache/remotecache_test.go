@@ -0,0 +1,113 @@
+package remotecache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/backend/remotecache"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	t.Run("objects should round-trip", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(remotecache.NewHandler(remotecache.NewMemory()))
+		t.Cleanup(srv.Close)
+		c := remotecache.NewClient(srv.URL, srv.Client())
+
+		blob := object.New(object.TypeBlob, []byte("some content\nwith\nlines"))
+		empty := object.New(object.TypeBlob, []byte{})
+		require.NoError(t, c.PutObjects([]*object.Object{blob, empty}))
+
+		missing := ginternals.NewOidFromContent([]byte("missing"))
+		objects, err := c.GetObjects([]ginternals.Oid{blob.ID(), missing, empty.ID()})
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		assert.Equal(t, blob.ID(), objects[0].ID())
+		assert.Equal(t, object.TypeBlob, objects[0].Type())
+		assert.Equal(t, blob.Bytes(), objects[0].Bytes())
+		assert.Equal(t, empty.ID(), objects[1].ID())
+	})
+
+	t.Run("empty requests should not hit the server", func(t *testing.T) {
+		t.Parallel()
+
+		c := remotecache.NewClient("http://127.0.0.1:0", nil)
+		objects, err := c.GetObjects(nil)
+		require.NoError(t, err)
+		assert.Empty(t, objects)
+		require.NoError(t, c.PutObjects(nil))
+	})
+
+	t.Run("server errors should be returned", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}))
+		t.Cleanup(srv.Close)
+		c := remotecache.NewClient(srv.URL, srv.Client())
+
+		_, err := c.GetObjects([]ginternals.Oid{ginternals.NewOidFromContent([]byte("a"))})
+		require.ErrorIs(t, err, remotecache.ErrUnexpectedStatus)
+		err = c.PutObjects([]*object.Object{object.New(object.TypeBlob, []byte("a"))})
+		require.ErrorIs(t, err, remotecache.ErrUnexpectedStatus)
+	})
+
+	t.Run("objects with the wrong content should be rejected", func(t *testing.T) {
+		t.Parallel()
+
+		oid := ginternals.NewOidFromContent([]byte("a"))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(oid.String() + " blob 1\nb")) //nolint:errcheck
+		}))
+		t.Cleanup(srv.Close)
+		c := remotecache.NewClient(srv.URL, srv.Client())
+
+		_, err := c.GetObjects([]ginternals.Oid{oid})
+		require.ErrorIs(t, err, remotecache.ErrInvalidPayload)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(remotecache.NewHandler(remotecache.NewMemory()))
+	t.Cleanup(srv.Close)
+
+	t.Run("GET should not be allowed", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := srv.Client().Get(srv.URL + remotecache.PathGet)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+
+	t.Run("invalid oids should be rejected", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := srv.Client().Post(srv.URL+remotecache.PathGet, "text/plain", strings.NewReader("not-an-oid\n"))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("truncated objects should be rejected", func(t *testing.T) {
+		t.Parallel()
+
+		oid := ginternals.NewOidFromContent([]byte("a"))
+		res, err := srv.Client().Post(srv.URL+remotecache.PathPut, "application/octet-stream", strings.NewReader(oid.String()+" blob 10\nabc"))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -0,0 +1,51 @@
+package remotecache
+
+import (
+	"net/http"
+
+	"github.com/Nivl/git-go/backend"
+)
+
+// maxRequestSize is the maximum size of the body of a request
+const maxRequestSize = 2 * maxObjectSize
+
+// NewHandler returns an http.Handler that serves the objects of the
+// provided cache using the protocol understood by Client
+func NewHandler(cache backend.ObjectCache) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathGet, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		oids, err := decodeOids(http.MaxBytesReader(w, req.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		objects, err := cache.GetObjects(oids)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		encodeObjects(w, objects) //nolint:errcheck // the headers are already sent, nothing we can do
+	})
+	mux.HandleFunc(PathPut, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		objects, err := decodeObjects(http.MaxBytesReader(w, req.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = cache.PutObjects(objects); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
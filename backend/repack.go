@@ -0,0 +1,409 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+)
+
+// ErrInvalidRepackOptions is returned when the options used to repack
+// a repository are incompatible or out of range
+var ErrInvalidRepackOptions = errors.New("invalid repack options")
+
+// RepackOptions represents the options used to repack the objects
+// of a repository
+type RepackOptions struct {
+	// Window contains the number of objects each object is compared
+	// to when looking for a delta base. 0 disables the delta
+	// compression.
+	// This is the equivalent of --window
+	Window int
+	// Depth contains the maximum length of the delta chains.
+	// 0 disables the delta compression.
+	// This is the equivalent of --depth
+	Depth int
+	// NoReuseDelta computes all the deltas again, instead of reusing
+	// the ones of the existing packfiles.
+	// This is the equivalent of -f
+	NoReuseDelta bool
+	// All repacks the objects of all the packfiles, instead of only
+	// packing the loose objects.
+	// This is the equivalent of -a
+	All bool
+	// Geometric contains a factor used to only repack the smallest
+	// packfiles, so that each remaining packfile contains at least
+	// Geometric times as many objects as the next smaller one.
+	// 0 disables the geometric repack. Cannot be used with All.
+	// This is the equivalent of --geometric
+	Geometric int
+	// RemoveRedundant removes the packfiles and the loose objects
+	// that are in the new packfile, as well as the loose objects
+	// that are in the packfiles that have not been repacked.
+	// This is the equivalent of -d
+	RemoveRedundant bool
+	// Hints contains objects in the order they should be written,
+	// with their path. The path is used to find objects that are
+	// likely to be similar. The objects that are not listed are
+	// written after, sorted by oid
+	Hints []ObjectHint
+}
+
+// ObjectHint contains information about an object that helps
+// packing it
+type ObjectHint struct {
+	ID ginternals.Oid
+	// Path contains the path of the object in the repository, or the
+	// name of the tag for annotated tags. It's empty for commits and
+	// root trees
+	Path string
+}
+
+// RepackResult contains the result of a repack
+type RepackResult struct {
+	// PackID contains the ID of the new packfile. NullOid if there
+	// was nothing to pack
+	PackID ginternals.Oid
+	// Objects contains the number of objects in the new packfile
+	Objects int
+	// Deltas contains the number of deltified objects in the new
+	// packfile, reused deltas included
+	Deltas int
+	// ReusedDeltas contains the number of deltas that have been
+	// copied from the existing packfiles
+	ReusedDeltas int
+	// RemovedPacks contains the IDs of the packfiles that have been
+	// removed
+	RemovedPacks []ginternals.Oid
+	// RemovedLooseObjects contains the number of loose objects that
+	// have been removed
+	RemovedLooseObjects int
+}
+
+// Repack packs the objects of the repository into a new packfile.
+// By default only the loose objects that are not packed yet are
+// packed, the same way git repack does. RepackOptions.All and
+// RepackOptions.Geometric can be used to also repack the existing
+// packfiles.
+// The objects of the kept and promisor packfiles, as well as the
+// objects of the alternate object directories, are never repacked.
+// The whole packfile is built in memory before being written.
+// This method can be called concurrently, but only one repack should
+// be running at the same time
+func (b *Backend) Repack(opts RepackOptions) (*RepackResult, error) {
+	if opts.All && opts.Geometric != 0 {
+		return nil, fmt.Errorf("all and geometric cannot be used together: %w", ErrInvalidRepackOptions)
+	}
+	if opts.Geometric < 0 || opts.Geometric == 1 {
+		return nil, fmt.Errorf("invalid geometric factor %d: %w", opts.Geometric, ErrInvalidRepackOptions)
+	}
+
+	packsDir := ginternals.ObjectsPacksPath(b.config)
+	res := &RepackResult{}
+
+	b.packfilesMu.RLock()
+	candidates := []*packfile.Pack{}
+	keptPacks := []*packfile.Pack{}
+	for _, pack := range b.packfiles {
+		if filepath.Dir(pack.Path()) != packsDir {
+			continue
+		}
+		kept, err := b.isPackExcludedFromRepack(pack)
+		if err != nil {
+			b.packfilesMu.RUnlock()
+			return nil, err
+		}
+		if kept {
+			keptPacks = append(keptPacks, pack)
+			continue
+		}
+		candidates = append(candidates, pack)
+	}
+	oldPacks := []*packfile.Pack{}
+	switch {
+	case opts.All:
+		oldPacks = candidates
+	case opts.Geometric > 0:
+		var rest []*packfile.Pack
+		oldPacks, rest = geometricSplit(candidates, opts.Geometric)
+		keptPacks = append(keptPacks, rest...)
+	default:
+		keptPacks = append(keptPacks, candidates...)
+	}
+
+	// sources maps the oid of the objects to pack to the packfile
+	// containing them, or to nil for the loose objects
+	sources := map[ginternals.Oid]*packfile.Pack{}
+	for _, pack := range oldPacks {
+		pack := pack
+		err := pack.WalkOids(func(oid ginternals.Oid) error {
+			sources[oid] = pack
+			return nil
+		})
+		if err != nil {
+			b.packfilesMu.RUnlock()
+			return nil, fmt.Errorf("could not list the objects of %s: %w", pack.Path(), err)
+		}
+	}
+	objectsDir := ginternals.ObjectsPath(b.config)
+	b.looseObjects.Range(func(key, value interface{}) bool {
+		oid := key.(ginternals.Oid)
+		if _, ok := sources[oid]; !ok && value.(string) == objectsDir {
+			sources[oid] = nil
+		}
+		return true
+	})
+	// The objects that are in a packfile we keep are already packed.
+	// The loose copies of those objects are redundant, so they get
+	// removed alongside the others, like git prune-packed does
+	redundantLoose := []ginternals.Oid{}
+	for _, pack := range keptPacks {
+		err := pack.WalkOids(func(oid ginternals.Oid) error {
+			if src, ok := sources[oid]; ok {
+				if src == nil {
+					redundantLoose = append(redundantLoose, oid)
+				}
+				delete(sources, oid)
+			}
+			return nil
+		})
+		if err != nil {
+			b.packfilesMu.RUnlock()
+			return nil, fmt.Errorf("could not list the objects of %s: %w", pack.Path(), err)
+		}
+	}
+
+	toPack, err := b.repackObjects(sources, opts)
+	b.packfilesMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(toPack) == 0 {
+		if opts.RemoveRedundant {
+			err = b.removeRedundantLooseObjects(redundantLoose, res)
+		}
+		return res, err
+	}
+
+	built, err := packfile.Build(toPack, packfile.BuildOptions{
+		Window: opts.Window,
+		Depth:  opts.Depth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not build the packfile: %w", err)
+	}
+	res.Objects = built.ObjectCount
+	res.Deltas = built.Deltas
+	res.ReusedDeltas = built.ReusedDeltas
+	if res.PackID, err = b.AddPackfile(built.Pack, built.Index); err != nil {
+		return nil, fmt.Errorf("could not add the new packfile: %w", err)
+	}
+
+	if opts.RemoveRedundant {
+		for _, pack := range oldPacks {
+			if pack.ID() == res.PackID {
+				continue
+			}
+			if err = b.removePackfile(pack); err != nil {
+				return res, err
+			}
+			res.RemovedPacks = append(res.RemovedPacks, pack.ID())
+		}
+		for oid, pack := range sources {
+			if pack == nil {
+				redundantLoose = append(redundantLoose, oid)
+			}
+		}
+		if err = b.removeRedundantLooseObjects(redundantLoose, res); err != nil {
+			return res, err
+		}
+	}
+
+	// The dumb protocols need objects/info/packs to be up to date
+	if _, err = b.fs.Stat(ginternals.InfoPacksPath(b.config)); err == nil {
+		if err = b.UpdateInfoPacks(); err != nil {
+			return res, fmt.Errorf("could not update the list of packfiles: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// geometricSplit splits the given packfiles in two groups: the small
+// packfiles that need to be rolled up into a new packfile, and the
+// packfiles that already form a geometric progression, in which each
+// packfile contains at least factor times as many objects as the
+// next smaller one.
+// This is the same algorithm as git repack --geometric
+func geometricSplit(packs []*packfile.Pack, factor int) (rollup, rest []*packfile.Pack) {
+	sorted := make([]*packfile.Pack, len(packs))
+	copy(sorted, packs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ObjectCount() < sorted[j].ObjectCount()
+	})
+
+	// We look for the biggest packfile that breaks the progression,
+	// starting from the biggest packfiles
+	split := len(sorted) - 1
+	for ; split > 0; split-- {
+		ours := uint64(sorted[split].ObjectCount())
+		prev := uint64(sorted[split-1].ObjectCount())
+		if ours < uint64(factor)*prev {
+			break
+		}
+	}
+	// The biggest packfile of the last compared pair is not part of
+	// the progression
+	if split > 0 {
+		split++
+	}
+
+	// Rolling up the small packfiles creates a new packfile that may
+	// be big enough to break the progression of the bigger packfiles,
+	// in which case they need to be rolled up too
+	total := uint64(0)
+	for _, pack := range sorted[:split] {
+		total += uint64(pack.ObjectCount())
+	}
+	for ; split < len(sorted); split++ {
+		count := uint64(sorted[split].ObjectCount())
+		if count >= uint64(factor)*total {
+			break
+		}
+		total += count
+	}
+	return sorted[:split], sorted[split:]
+}
+
+// removeRedundantLooseObjects removes the given loose objects, which
+// are expected to be packed, and updates the stats of res
+func (b *Backend) removeRedundantLooseObjects(oids []ginternals.Oid, res *RepackResult) error {
+	for _, oid := range oids {
+		removed, err := b.removePackedLooseObject(oid)
+		if err != nil {
+			return err
+		}
+		if removed {
+			res.RemovedLooseObjects++
+		}
+	}
+	return nil
+}
+
+// isPackExcludedFromRepack returns whether the objects of the given
+// packfile must stay in it
+func (b *Backend) isPackExcludedFromRepack(pack *packfile.Pack) (bool, error) {
+	for _, ext := range []string{packfile.ExtKeep, packfile.ExtPromisor} {
+		found, err := b.hasPackMarker(pack.Path(), ext)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// repackObjects returns the objects to pack, in the order they
+// should be written.
+// packfilesMu is expected to be locked
+func (b *Backend) repackObjects(sources map[ginternals.Oid]*packfile.Pack, opts RepackOptions) ([]*packfile.BuildObject, error) {
+	ordered := make([]ObjectHint, 0, len(sources))
+	seen := make(map[ginternals.Oid]struct{}, len(sources))
+	for _, hint := range opts.Hints {
+		if _, ok := sources[hint.ID]; !ok {
+			continue
+		}
+		if _, ok := seen[hint.ID]; ok {
+			continue
+		}
+		seen[hint.ID] = struct{}{}
+		ordered = append(ordered, hint)
+	}
+	rest := []ginternals.Oid{}
+	for oid := range sources {
+		if _, ok := seen[oid]; !ok {
+			rest = append(rest, oid)
+		}
+	}
+	ginternals.SortOids(rest)
+	for _, oid := range rest {
+		ordered = append(ordered, ObjectHint{ID: oid})
+	}
+
+	toPack := make([]*packfile.BuildObject, 0, len(ordered))
+	for _, hint := range ordered {
+		var o *packfile.BuildObject
+		pack := sources[hint.ID]
+		if pack != nil {
+			obj, err := pack.GetObject(hint.ID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get object %s: %w", hint.ID.String(), err)
+			}
+			o = &packfile.BuildObject{Object: obj}
+			if !opts.NoReuseDelta {
+				if o.DeltaBase, o.Delta, err = pack.RawDelta(hint.ID); err != nil {
+					return nil, fmt.Errorf("could not get the delta of %s: %w", hint.ID.String(), err)
+				}
+			}
+		} else {
+			obj, err := b.looseObject(hint.ID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get object %s: %w", hint.ID.String(), err)
+			}
+			o = &packfile.BuildObject{Object: obj}
+		}
+		o.Path = hint.Path
+		toPack = append(toPack, o)
+	}
+	return toPack, nil
+}
+
+// removePackfile unloads the given packfile and removes it from the
+// disk alongside its index
+func (b *Backend) removePackfile(pack *packfile.Pack) error {
+	b.packfilesMu.Lock()
+	delete(b.packfiles, pack.ID())
+	b.packfilesMu.Unlock()
+	if err := pack.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %w", pack.Path(), err)
+	}
+
+	// The packfile is removed first since a packfile without an
+	// index cannot be loaded
+	base := strings.TrimSuffix(pack.Path(), packfile.ExtPackfile)
+	for _, ext := range []string{packfile.ExtPackfile, packfile.ExtIndex} {
+		if err := b.fs.Remove(base + ext); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove %s: %w", base+ext, err)
+		}
+	}
+	return nil
+}
+
+// removePackedLooseObject removes the loose object that has the
+// given oid, once it has been packed
+func (b *Backend) removePackedLooseObject(oid ginternals.Oid) (removed bool, err error) {
+	b.objectMu.Lock(oid[:])
+	defer b.objectMu.Unlock(oid[:])
+
+	p := b.looseObjectPath(ginternals.ObjectsPath(b.config), oid)
+	if err = b.fs.Remove(p); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			b.looseObjects.Delete(oid)
+			return false, nil
+		}
+		return false, fmt.Errorf("could not remove object %s: %w", oid.String(), err)
+	}
+	b.looseObjects.Delete(oid)
+
+	// Like git, we remove the fan-out directory once it's empty.
+	// This will fail if the directory still contains files, which is
+	// fine
+	b.fs.Remove(filepath.Dir(p)) //nolint:errcheck // the directory is expected to not be empty
+	return true, nil
+}
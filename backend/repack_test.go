@@ -0,0 +1,206 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should pack everything in a single packfile", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		before, err := b.Stats()
+		require.NoError(t, err)
+		oids := []ginternals.Oid{}
+		require.NoError(t, b.WalkPackedObjectIDs(func(oid ginternals.Oid) error {
+			oids = append(oids, oid)
+			return nil
+		}))
+		require.NoError(t, b.WalkLooseObjectIDs(func(oid ginternals.Oid) error {
+			oids = append(oids, oid)
+			return nil
+		}))
+
+		res, err := b.Repack(RepackOptions{
+			Window:          packfile.DefaultWindow,
+			Depth:           packfile.DefaultDepth,
+			All:             true,
+			RemoveRedundant: true,
+		})
+		require.NoError(t, err)
+		assert.False(t, res.PackID.IsZero())
+		assert.Equal(t, before.PackedObjects+before.LooseObjects, res.Objects)
+		assert.NotZero(t, res.Deltas)
+		assert.NotZero(t, res.ReusedDeltas)
+		assert.Len(t, res.RemovedPacks, 1)
+		assert.Equal(t, before.LooseObjects, res.RemovedLooseObjects)
+		require.NoError(t, b.Close())
+
+		// We reload the repository to make sure everything is
+		// coming from the disk
+		b, err = NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		after, err := b.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 0, after.LooseObjects)
+		assert.Equal(t, 1, after.Packs)
+		assert.Equal(t, res.Objects, after.PackedObjects)
+		for _, oid := range oids {
+			_, err := b.Object(oid)
+			require.NoError(t, err, oid.String())
+		}
+	})
+
+	t.Run("should not repack the kept packfiles", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		before, err := b.Stats()
+		require.NoError(t, err)
+
+		packPath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+		keepPath := strings.TrimSuffix(packPath, packfile.ExtPackfile) + packfile.ExtKeep
+		require.NoError(t, os.WriteFile(keepPath, nil, 0o644))
+
+		res, err := b.Repack(RepackOptions{
+			Window:          packfile.DefaultWindow,
+			Depth:           packfile.DefaultDepth,
+			All:             true,
+			RemoveRedundant: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, before.LooseObjects, res.Objects)
+		assert.Empty(t, res.RemovedPacks)
+		assert.FileExists(t, packPath)
+		assert.FileExists(t, filepath.Join(filepath.Dir(packPath), "pack-"+res.PackID.String()+packfile.ExtPackfile))
+	})
+	t.Run("should only pack the loose objects by default", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		before, err := b.Stats()
+		require.NoError(t, err)
+
+		res, err := b.Repack(RepackOptions{
+			Window:          packfile.DefaultWindow,
+			Depth:           packfile.DefaultDepth,
+			RemoveRedundant: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, before.LooseObjects, res.Objects)
+		assert.Equal(t, before.LooseObjects, res.RemovedLooseObjects)
+		assert.Empty(t, res.RemovedPacks)
+
+		after, err := b.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 0, after.LooseObjects)
+		assert.Equal(t, before.Packs+1, after.Packs)
+
+		// Running it again should do nothing
+		res, err = b.Repack(RepackOptions{RemoveRedundant: true})
+		require.NoError(t, err)
+		assert.True(t, res.PackID.IsZero())
+		assert.Zero(t, res.Objects)
+	})
+
+	t.Run("should roll up the small packfiles with geometric", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		// We add 2 packfiles of 1 object each, which breaks the
+		// progression
+		smallPacks := []ginternals.Oid{}
+		for _, content := range []string{"first", "second"} {
+			built, err := packfile.Build([]*packfile.BuildObject{
+				{Object: object.New(object.TypeBlob, []byte(content))},
+			}, packfile.BuildOptions{})
+			require.NoError(t, err)
+			id, err := b.AddPackfile(built.Pack, built.Index)
+			require.NoError(t, err)
+			smallPacks = append(smallPacks, id)
+		}
+		before, err := b.Stats()
+		require.NoError(t, err)
+		require.Equal(t, 3, before.Packs)
+
+		res, err := b.Repack(RepackOptions{
+			Geometric:       2,
+			RemoveRedundant: true,
+		})
+		require.NoError(t, err)
+		// The 2 small packs and the loose objects
+		assert.Equal(t, 2+before.LooseObjects, res.Objects)
+		assert.ElementsMatch(t, smallPacks, res.RemovedPacks)
+
+		after, err := b.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 0, after.LooseObjects)
+		assert.Equal(t, 2, after.Packs)
+		assert.Equal(t, before.PackedObjects+before.LooseObjects, after.PackedObjects)
+	})
+
+	t.Run("should fail with invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		_, err = b.Repack(RepackOptions{All: true, Geometric: 2})
+		require.ErrorIs(t, err, ErrInvalidRepackOptions)
+		_, err = b.Repack(RepackOptions{Geometric: 1})
+		require.ErrorIs(t, err, ErrInvalidRepackOptions)
+	})
+}
@@ -0,0 +1,25 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/pathutil"
+)
+
+// checkWindowsRefName returns an error wrapping ErrRefNameInvalid if
+// the provided reference cannot be stored as a loose reference on
+// Windows. refs/heads/con would target the CON device instead of a
+// file, and refs/heads/foo. would be stored in refs/heads/foo.
+// The chars used to access the NTFS alternate data streams (like in
+// refs/heads/foo:stream) are already refused by CheckRefFormat,
+// but are checked anyway
+func checkWindowsRefName(name string) error {
+	for _, component := range strings.Split(name, "/") {
+		if !pathutil.IsValidWindowsName(component) {
+			return fmt.Errorf("%q cannot be used on Windows: %w", component, ginternals.ErrRefNameInvalid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWindowsRefName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		isValid bool
+	}{
+		{name: "refs/heads/main", isValid: true},
+		{name: "refs/heads/console", isValid: true},
+		{name: "refs/heads/con", isValid: false},
+		{name: "refs/heads/NUL.txt", isValid: false},
+		{name: "refs/aux/main", isValid: false},
+		{name: "refs/heads/com1", isValid: false},
+		{name: "refs/heads/foo:stream", isValid: false},
+		{name: "refs/heads/foo./bar", isValid: false},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.name), func(t *testing.T) {
+			t.Parallel()
+
+			err := checkWindowsRefName(tc.name)
+			if tc.isValid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ginternals.ErrRefNameInvalid)
+		})
+	}
+}
+
+func TestWriteReferenceWindowsNames(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "windows" {
+		t.Skip("the reserved names are only refused on Windows")
+	}
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	b, err := NewFS(confutil.NewCommonConfig(t, repoPath))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	oid, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	err = b.WriteReference(ginternals.NewReference("refs/heads/con", oid))
+	require.ErrorIs(t, err, ginternals.ErrRefNameInvalid)
+}
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultScanIgnore contains the patterns of the files that are
+// ignored by default when scanning the repository.
+// Those files are usually created by the OS or by cloud-storage
+// clients and are not part of the repository
+var DefaultScanIgnore = []string{
+	".DS_Store",
+	"._*",
+	"Thumbs.db",
+	"desktop.ini",
+	"*.icloud",
+	// temporary files created by git while writing objects
+	tmpObjectPrefix + "*",
+}
+
+// ScanWarning represents a non-fatal problem found while scanning
+// the repository
+type ScanWarning struct {
+	// Path contains the path of the file or directory that could not
+	// be processed
+	Path string
+	// Err contains the reason why the path has been skipped
+	Err error
+}
+
+// String returns a human readable version of the warning
+func (w ScanWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Path, w.Err.Error())
+}
+
+// ScanWarnings returns the list of all the non-fatal problems found
+// while scanning the repository.
+// This method can be called concurrently
+func (b *Backend) ScanWarnings() []ScanWarning {
+	b.scanWarningsMu.Lock()
+	defer b.scanWarningsMu.Unlock()
+
+	warnings := make([]ScanWarning, len(b.scanWarnings))
+	copy(warnings, b.scanWarnings)
+	return warnings
+}
+
+// addScanWarning records a non-fatal problem found while scanning
+// the repository
+func (b *Backend) addScanWarning(path string, err error) {
+	b.scanWarningsMu.Lock()
+	defer b.scanWarningsMu.Unlock()
+
+	b.scanWarnings = append(b.scanWarnings, ScanWarning{
+		Path: path,
+		Err:  err,
+	})
+}
+
+// isScanIgnored returns whether the file or directory with the
+// given name should be skipped when scanning the repository
+func (b *Backend) isScanIgnored(name string) bool {
+	for _, pattern := range b.scanIgnore {
+		// The patterns have been validated when the backend was
+		// created, so there's no error to check
+		if match, _ := filepath.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
+
+// validateScanIgnore makes sure all the patterns are valid
+func validateScanIgnore(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanIgnore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ignored files should not prevent loading objects", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		// .DS_Store is sorted before the object of the directory
+		objectsPath := ginternals.ObjectsPath(cfg)
+		require.NoError(t, os.WriteFile(filepath.Join(objectsPath, "b0", ".DS_Store"), []byte{}, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(objectsPath, ".DS_Store"), []byte{}, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(objectsPath, "pack", "._pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"), []byte{}, 0o644))
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		assert.Empty(t, b.ScanWarnings())
+
+		oid, err := ginternals.NewOidFromStr("b07e28976ac8972715598f390964d53cf4dbc1bd")
+		require.NoError(t, err)
+		found, err := b.HasObject(oid)
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("unknown files should be reported as warnings", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		invalidPath := filepath.Join(ginternals.ObjectsPath(cfg), "b0", "not-an-object")
+		require.NoError(t, os.WriteFile(invalidPath, []byte{}, 0o644))
+
+		b, err := NewWithOptions(cfg, afero.NewOsFs(), Options{ScanIgnore: []string{}})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		warnings := b.ScanWarnings()
+		require.Len(t, warnings, 1)
+		assert.Equal(t, invalidPath, warnings[0].Path)
+
+		oid, err := ginternals.NewOidFromStr("b07e28976ac8972715598f390964d53cf4dbc1bd")
+		require.NoError(t, err)
+		found, err := b.HasObject(oid)
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("unreadable directories should be reported as warnings", func(t *testing.T) {
+		t.Parallel()
+
+		if os.Geteuid() == 0 {
+			t.Skip("permissions are not enforced for root")
+		}
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		dirPath := filepath.Join(ginternals.ObjectsPath(cfg), "b0")
+		require.NoError(t, os.Chmod(dirPath, 0o000))
+		t.Cleanup(func() {
+			require.NoError(t, os.Chmod(dirPath, 0o755))
+		})
+
+		b, err := NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		warnings := b.ScanWarnings()
+		require.Len(t, warnings, 1)
+		assert.Equal(t, dirPath, warnings[0].Path)
+		assert.ErrorIs(t, warnings[0].Err, os.ErrPermission)
+	})
+
+	t.Run("invalid patterns should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		_, err := NewWithOptions(cfg, afero.NewOsFs(), Options{ScanIgnore: []string{"[a-"}})
+		require.Error(t, err)
+	})
+}
+
+func TestVerifyPacks(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+
+	// We truncate the content of the packfile but keep its footer
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+	// packfiles are read-only
+	require.NoError(t, os.Chmod(packFilePath, 0o644))
+	data, err := os.ReadFile(packFilePath)
+	require.NoError(t, err)
+	data = append(data[:len(data)-ginternals.OidSize-1], data[len(data)-ginternals.OidSize:]...)
+	require.NoError(t, os.WriteFile(packFilePath, data, 0o644))
+
+	b, err := NewFS(cfg)
+	require.NoError(t, err, "packfiles should not be verified by default")
+	require.NoError(t, b.Close())
+
+	_, err = NewWithOptions(cfg, afero.NewOsFs(), Options{VerifyPacks: true})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, packfile.ErrChecksumMismatch), "unexpected error: %v", err)
+	assert.Contains(t, err.Error(), packFilePath)
+}
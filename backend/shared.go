@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/spf13/afero"
+)
+
+// filePerm returns the permissions to give to a new file of the
+// repository, based on core.sharedRepository
+func (b *Backend) filePerm(perm os.FileMode) os.FileMode {
+	return b.shared.FilePerm(perm)
+}
+
+// chmod sets the permissions of the given file if the repository
+// is shared
+func (b *Backend) chmod(p string, perm os.FileMode) error {
+	return chmodShared(b.fs, b.shared, p, b.shared.FilePerm(perm))
+}
+
+// mkdirAll creates the given directory and all its missing parents.
+// If the repository is shared, the permissions of the new directories
+// are updated to match core.sharedRepository
+func (b *Backend) mkdirAll(p string, perm os.FileMode) error {
+	if !b.shared.IsShared() {
+		return b.fs.MkdirAll(p, perm)
+	}
+
+	// We need to know which directories are going to be created, so
+	// we don't change the permissions of the existing ones
+	missing := []string{}
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if _, err := b.fs.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if err := b.fs.MkdirAll(p, perm); err != nil {
+		return err
+	}
+	for _, dir := range missing {
+		if err := chmodShared(b.fs, b.shared, dir, b.shared.DirPerm(perm)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chmodShared sets the permissions of the given file or directory if
+// the repository is shared. This is needed because the permissions of
+// the new files are affected by the umask.
+// Like git, nothing is done if the file already has the right
+// permissions, since only its owner can change them
+func chmodShared(fs afero.Fs, shared config.SharedRepository, p string, perm os.FileMode) error {
+	if !shared.IsShared() {
+		return nil
+	}
+	info, err := fs.Stat(p)
+	if err != nil {
+		return fmt.Errorf("could not check the permissions of %s: %w", p, err)
+	}
+	const mask = os.ModePerm | os.ModeSetgid
+	if info.Mode()&mask == perm&mask {
+		return nil
+	}
+	if err = fs.Chmod(p, perm); err != nil {
+		return fmt.Errorf("could not set the permissions of %s: %w", p, err)
+	}
+	return nil
+}
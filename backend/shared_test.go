@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/ginternals/reflog"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedRepository(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't support unix permissions")
+	}
+
+	testCases := []struct {
+		desc         string
+		value        string
+		expectedRO   os.FileMode
+		expectedFile os.FileMode
+		expectedDir  os.FileMode
+	}{
+		{
+			desc:         "group",
+			value:        "group",
+			expectedRO:   0o444,
+			expectedFile: 0o664,
+			expectedDir:  0o775 | os.ModeSetgid,
+		},
+		{
+			desc:         "octal value",
+			value:        "0640",
+			expectedRO:   0o440,
+			expectedFile: 0o640,
+			expectedDir:  0o750 | os.ModeSetgid,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			cfgPath := filepath.Join(repoPath, ".git", "config")
+			f, err := os.OpenFile(cfgPath, os.O_APPEND|os.O_WRONLY, 0o644)
+			require.NoError(t, err)
+			_, err = f.WriteString("[core]\n\tsharedrepository = " + tc.value + "\n")
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+
+			cfg := confutil.NewCommonConfig(t, repoPath)
+			b, err := NewFS(cfg)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, b.Close())
+			})
+
+			checkPerm := func(t *testing.T, p string, expected os.FileMode) {
+				t.Helper()
+				info, err := os.Stat(p)
+				require.NoError(t, err)
+				assert.Equal(t, expected, info.Mode()&(os.ModePerm|os.ModeSetgid), "unexpected permissions for %s", p)
+			}
+
+			// Loose objects
+			oid, err := b.WriteObject(object.New(object.TypeBlob, []byte("shared "+tc.desc)))
+			require.NoError(t, err)
+			p := ginternals.LooseObjectPath(cfg, oid.String())
+			checkPerm(t, p, tc.expectedRO)
+			checkPerm(t, filepath.Dir(p), tc.expectedDir)
+
+			// References and reflogs
+			refName := "refs/heads/shared/branch"
+			require.NoError(t, b.WriteReference(ginternals.NewReference(refName, oid)))
+			checkPerm(t, filepath.Join(b.Path(), refName), tc.expectedFile)
+			checkPerm(t, filepath.Join(b.Path(), "refs", "heads", "shared"), tc.expectedDir)
+
+			require.NoError(t, b.AppendReflog(refName, &reflog.Entry{
+				New:       oid,
+				Committer: object.NewSignature("Committer", "committer@domain.tld"),
+				Message:   "branch: Created from HEAD",
+			}))
+			checkPerm(t, ginternals.ReflogPath(cfg, refName), tc.expectedFile)
+
+			// Packfiles
+			built, err := packfile.Build([]*packfile.BuildObject{
+				{Object: object.New(object.TypeBlob, []byte("packed "+tc.desc))},
+			}, packfile.BuildOptions{})
+			require.NoError(t, err)
+			packID, err := b.AddPackfile(built.Pack, built.Index)
+			require.NoError(t, err)
+			checkPerm(t, ginternals.PackfilePath(cfg, "pack-"+packID.String()+packfile.ExtPackfile), tc.expectedRO)
+			checkPerm(t, ginternals.PackfilePath(cfg, "pack-"+packID.String()+packfile.ExtIndex), tc.expectedRO)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/spf13/afero"
+)
+
+// largestObjectsCount is the number of objects reported in
+// Stats.LargestObjects
+const largestObjectsCount = 10
+
+// ObjectDiskUsage contains the space used by an object on disk
+type ObjectDiskUsage struct {
+	ID ginternals.Oid
+	// Size contains the size of the object on disk, in bytes. This
+	// is the size of the file of a loose object, or the size of the
+	// entry of a packed object (which may be a delta)
+	Size int64
+	// Packed is set if the object is stored in a packfile
+	Packed bool
+}
+
+// Stats contains statistics about the objects and the references of
+// a repository.
+// Only the objects of the repository are counted, the objects of the
+// alternate object directories are ignored
+type Stats struct {
+	// LooseObjects contains the number of loose objects
+	LooseObjects int
+	// LooseSize contains the size of the loose objects on disk,
+	// in bytes
+	LooseSize int64
+	// PrunePackable contains the number of loose objects that are
+	// also in a packfile
+	PrunePackable int
+	// Garbage contains the number of files of the object directory
+	// that are neither objects nor valid packfiles (temporary files,
+	// packfiles without index, etc.)
+	Garbage int
+	// GarbageSize contains the size of the garbage files on disk,
+	// in bytes
+	GarbageSize int64
+
+	// Packs contains the number of packfiles
+	Packs int
+	// PackedObjects contains the number of objects in the packfiles
+	PackedObjects int
+	// PackSize contains the size of the packfiles and of their
+	// indexes on disk, in bytes
+	PackSize int64
+	// DeltaChains contains the number of packed objects for each
+	// delta chain length. The objects that are not deltified have
+	// a length of 0
+	DeltaChains map[int]int
+
+	// LargestObjects contains the objects that use the most space
+	// on disk, largest first
+	LargestObjects []ObjectDiskUsage
+
+	// References contains the number of references, symbolic
+	// references included
+	References int
+	// Branches contains the number of references in refs/heads/
+	Branches int
+	// Tags contains the number of references in refs/tags/
+	Tags int
+	// RemoteBranches contains the number of references in
+	// refs/remotes/
+	RemoteBranches int
+}
+
+// Stats returns statistics about the objects and the references of
+// the repository. This is the equivalent of git count-objects -v,
+// with extra data about the packfiles and the references.
+// The symbolic references are counted even if they target a
+// reference that doesn't exist, like HEAD in an empty repository.
+// Only the headers of the packed objects are read
+func (b *Backend) Stats() (*Stats, error) {
+	stats := &Stats{
+		DeltaChains: map[int]int{},
+	}
+	largest := []ObjectDiskUsage{}
+
+	packed := map[ginternals.Oid]struct{}{}
+	if err := b.packStats(stats, packed, &largest); err != nil {
+		return nil, err
+	}
+	if err := b.looseStats(stats, packed, &largest); err != nil {
+		return nil, err
+	}
+	if err := b.garbageStats(stats); err != nil {
+		return nil, err
+	}
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Size != largest[j].Size {
+			return largest[i].Size > largest[j].Size
+		}
+		return largest[i].ID.String() < largest[j].ID.String()
+	})
+	if len(largest) > largestObjectsCount {
+		largest = largest[:largestObjectsCount]
+	}
+	stats.LargestObjects = largest
+
+	err := b.WalkUnresolvedReferences(func(ref *ginternals.Reference) error {
+		stats.References++
+		switch {
+		case strings.HasPrefix(ref.Name(), "refs/heads/"):
+			stats.Branches++
+		case strings.HasPrefix(ref.Name(), "refs/tags/"):
+			stats.Tags++
+		case strings.HasPrefix(ref.Name(), "refs/remotes/"):
+			stats.RemoteBranches++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the references: %w", err)
+	}
+	return stats, nil
+}
+
+// looseStats adds the statistics of the loose objects of the
+// repository to stats. packed contains the IDs of the packed objects
+func (b *Backend) looseStats(stats *Stats, packed map[ginternals.Oid]struct{}, largest *[]ObjectDiskUsage) error {
+	objectsPath := ginternals.ObjectsPath(b.config)
+	oids := []ginternals.Oid{}
+	b.looseObjects.Range(func(key, value interface{}) bool {
+		if value.(string) == objectsPath {
+			oids = append(oids, key.(ginternals.Oid))
+		}
+		return true
+	})
+
+	for _, oid := range oids {
+		p := b.looseObjectPath(ginternals.ObjectsPath(b.config), oid)
+		info, err := b.fs.Stat(p)
+		if err != nil {
+			return fmt.Errorf("could not stat %s: %w", p, err)
+		}
+		stats.LooseObjects++
+		stats.LooseSize += info.Size()
+		*largest = append(*largest, ObjectDiskUsage{
+			ID:   oid,
+			Size: info.Size(),
+		})
+		if _, ok := packed[oid]; ok {
+			stats.PrunePackable++
+		}
+	}
+	return nil
+}
+
+// packStats adds the statistics of the packfiles of the repository
+// to stats, and the IDs of their objects to packed
+func (b *Backend) packStats(stats *Stats, packed map[ginternals.Oid]struct{}, largest *[]ObjectDiskUsage) error {
+	packsPath := ginternals.ObjectsPacksPath(b.config)
+	b.packfilesMu.RLock()
+	packs := make([]*packfile.Pack, 0, len(b.packfiles))
+	for _, pack := range b.packfiles {
+		if filepath.Dir(pack.Path()) == packsPath {
+			packs = append(packs, pack)
+		}
+	}
+	b.packfilesMu.RUnlock()
+
+	for _, pack := range packs {
+		stats.Packs++
+		for _, p := range []string{pack.Path(), strings.TrimSuffix(pack.Path(), packfile.ExtPackfile) + packfile.ExtIndex} {
+			info, err := b.fs.Stat(p)
+			if err != nil {
+				return fmt.Errorf("could not stat %s: %w", p, err)
+			}
+			stats.PackSize += info.Size()
+		}
+
+		err := pack.WalkEntries(func(e *packfile.EntryInfo) error {
+			stats.PackedObjects++
+			packed[e.ID] = struct{}{}
+			stats.DeltaChains[e.Depth]++
+			*largest = append(*largest, ObjectDiskUsage{
+				ID:     e.ID,
+				Size:   int64(e.PackedSize),
+				Packed: true,
+			})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not walk the objects of %s: %w", pack.Path(), err)
+		}
+	}
+	return nil
+}
+
+// packDirExts contains the extensions of the files that can be
+// found in the pack directory, along with a packfile
+//
+//nolint:gochecknoglobals // Treat it as a const
+var packDirExts = map[string]struct{}{
+	packfile.ExtPackfile: {},
+	packfile.ExtIndex:    {},
+	packfile.ExtKeep:     {},
+	packfile.ExtPromisor: {},
+	".bitmap":            {},
+	".rev":               {},
+	".mtimes":            {},
+}
+
+// garbageStats adds the garbage files of the object directory to
+// stats. Like git, the garbage files are the files of the loose
+// object directories that are not objects, and the files of the pack
+// directory that don't belong to a packfile with an index
+func (b *Backend) garbageStats(stats *Stats) error {
+	objectsPath := ginternals.ObjectsPath(b.config)
+	dirs, err := afero.ReadDir(b.fs, objectsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read %s: %w", objectsPath, err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || !b.layout.IsLooseObjectDir(dir.Name()) {
+			continue
+		}
+		dirPath := filepath.Join(objectsPath, dir.Name())
+		files, err := afero.ReadDir(b.fs, dirPath)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", dirPath, err)
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			if _, ok := b.layout.LooseObjectID(dir.Name() + "/" + f.Name()); ok {
+				continue
+			}
+			stats.Garbage++
+			stats.GarbageSize += f.Size()
+		}
+	}
+
+	packsPath := ginternals.ObjectsPacksPath(b.config)
+	files, err := afero.ReadDir(b.fs, packsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read %s: %w", packsPath, err)
+	}
+	// The files are grouped by packfile, so we can know which
+	// packfiles are complete
+	groups := map[string][]os.FileInfo{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(f.Name())
+		if _, ok := packDirExts[ext]; !ok {
+			stats.Garbage++
+			stats.GarbageSize += f.Size()
+			continue
+		}
+		base := strings.TrimSuffix(f.Name(), ext)
+		groups[base] = append(groups[base], f)
+	}
+	for _, group := range groups {
+		var hasPack, hasIndex bool
+		for _, f := range group {
+			hasPack = hasPack || filepath.Ext(f.Name()) == packfile.ExtPackfile
+			hasIndex = hasIndex || filepath.Ext(f.Name()) == packfile.ExtIndex
+		}
+		if hasPack && hasIndex {
+			continue
+		}
+		for _, f := range group {
+			stats.Garbage++
+			stats.GarbageSize += f.Size()
+		}
+	}
+	return nil
+}
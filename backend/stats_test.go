@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+
+	// We duplicate a packed object as a loose object so it can be
+	// pruned
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	packedOid, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	o, err := b.Object(packedOid)
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+	data, err := o.Compress()
+	require.NoError(t, err)
+	p := ginternals.LooseObjectPath(cfg, packedOid.String())
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, data, 0o444))
+
+	b, err = NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	stats, err := b.Stats()
+	require.NoError(t, err)
+
+	// generated using git count-objects -v and git verify-pack -v
+	assert.Equal(t, 3, stats.LooseObjects)
+	assert.Equal(t, 1, stats.PrunePackable)
+	assert.Equal(t, 1, stats.Packs)
+	assert.Equal(t, 364, stats.PackedObjects)
+	assert.Equal(t, int64(303), stats.PackSize>>10)
+	assert.Equal(t, map[int]int{
+		0: 240,
+		1: 70,
+		2: 33,
+		3: 14,
+		4: 5,
+		5: 2,
+	}, stats.DeltaChains)
+
+	require.Len(t, stats.LargestObjects, largestObjectsCount)
+	assert.Equal(t, "c7e8983034329ff4bf8e208dc7829a5d366a2f5f", stats.LargestObjects[0].ID.String())
+	assert.True(t, stats.LargestObjects[0].Packed)
+	for i := 1; i < len(stats.LargestObjects); i++ {
+		assert.GreaterOrEqual(t, stats.LargestObjects[i-1].Size, stats.LargestObjects[i].Size)
+	}
+
+	assert.Equal(t, 4, stats.Branches)
+	assert.Equal(t, 2, stats.Tags)
+	assert.Equal(t, 4, stats.RemoteBranches)
+	assert.GreaterOrEqual(t, stats.References, 10)
+	assert.Equal(t, 0, stats.Garbage)
+	assert.Equal(t, int64(0), stats.GarbageSize)
+}
+
+func TestStatsEmptyRepo(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	cfg := confutil.NewCommonConfig(t, dir)
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+	require.NoError(t, b.Init(ginternals.Master))
+
+	stats, err := b.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.LooseObjects)
+	assert.Equal(t, 0, stats.Packs)
+	assert.Equal(t, 0, stats.Garbage)
+	assert.Equal(t, 0, stats.Branches)
+	// HEAD is counted even if it's unborn
+	assert.Equal(t, 1, stats.References)
+}
+
+func TestStatsGarbage(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+
+	garbage := map[string]string{
+		// Temporary object in a fan-out directory
+		filepath.Join(ginternals.ObjectsPath(cfg), "ab", "tmp_obj_123"): "a",
+		// Index without packfile
+		filepath.Join(ginternals.ObjectsPacksPath(cfg), "pack-0000000000000000000000000000000000000000.idx"): "bb",
+		// Unknown file in the pack directory
+		filepath.Join(ginternals.ObjectsPacksPath(cfg), "tmp_pack_123"): "ccc",
+	}
+	for p, content := range garbage {
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	b, err := NewFS(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	stats, err := b.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Garbage)
+	assert.Equal(t, int64(6), stats.GarbageSize)
+	assert.Equal(t, 1, stats.Packs)
+}
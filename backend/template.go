@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// copyTemplate copies the content of the given template directory into
+// the git directory.
+// Like git, the existing files are left untouched, the files starting
+// with a "." are ignored, and nothing is copied if the template
+// directory doesn't exist.
+// The config file of the template is ignored
+func (b *Backend) copyTemplate(templateDir string) error {
+	if _, err := b.fs.Stat(templateDir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not check template %s: %w", templateDir, err)
+	}
+
+	err := afero.Walk(b.fs, templateDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == "config" {
+			return nil
+		}
+
+		dst := filepath.Join(b.Path(), rel)
+		if info.IsDir() {
+			if err = b.mkdirAll(dst, 0o750); err != nil {
+				return fmt.Errorf("could not create directory %s: %w", dst, err)
+			}
+			return nil
+		}
+		if _, err = b.fs.Stat(dst); err == nil {
+			return nil
+		}
+		data, err := afero.ReadFile(b.fs, p)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", p, err)
+		}
+		// The permissions are kept so the hooks stay executable
+		perm := info.Mode().Perm()
+		if err = afero.WriteFile(b.fs, dst, data, b.filePerm(perm)); err != nil {
+			return fmt.Errorf("could not create %s: %w", dst, err)
+		}
+		return b.chmod(dst, perm)
+	})
+	if err != nil {
+		return fmt.Errorf("could not copy template %s: %w", templateDir, err)
+	}
+	return nil
+}
+
+// templateHasFile returns whether the given template directory
+// contains the given file of the git directory
+func (b *Backend) templateHasFile(templateDir, p string) bool {
+	if templateDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(b.Path(), p)
+	if err != nil {
+		return false
+	}
+	info, err := b.fs.Stat(filepath.Join(templateDir, rel))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,168 @@
+package backend_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitTemplate(t *testing.T) {
+	t.Parallel()
+
+	// newTemplate creates a template directory containing a hook, an
+	// exclude file, a description, a config, and a dotfile
+	newTemplate := func(t *testing.T) string {
+		t.Helper()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "hooks"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "info"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "hooks", "pre-commit"), []byte("#!/bin/sh\n"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "info", "exclude"), []byte("*.log\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "description"), []byte("my template\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("[core]\n\tbare = true\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("hidden\n"), 0o644))
+		return dir
+	}
+
+	t.Run("should copy the template", func(t *testing.T) {
+		t.Parallel()
+
+		templateDir := newTemplate(t)
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := backend.NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		require.NoError(t, b.InitWithOptions(ginternals.Master, backend.InitOptions{
+			TemplateDir: templateDir,
+		}))
+
+		gitDir := ginternals.DotGitPath(cfg)
+		data, err := os.ReadFile(filepath.Join(gitDir, "info", "exclude"))
+		require.NoError(t, err)
+		assert.Equal(t, "*.log\n", string(data))
+
+		data, err = os.ReadFile(filepath.Join(gitDir, "description"))
+		require.NoError(t, err)
+		assert.Equal(t, "my template\n", string(data), "the description of the template should be used")
+
+		info, err := os.Stat(filepath.Join(gitDir, "hooks", "pre-commit"))
+		require.NoError(t, err)
+		if runtime.GOOS != "windows" {
+			assert.NotZero(t, info.Mode().Perm()&0o100, "the hook should be executable")
+		}
+
+		assert.NoFileExists(t, filepath.Join(gitDir, ".hidden"))
+
+		data, err = os.ReadFile(filepath.Join(gitDir, "config"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "bare = true", "the config of the template should be ignored")
+	})
+
+	t.Run("should not overwrite existing files", func(t *testing.T) {
+		t.Parallel()
+
+		templateDir := newTemplate(t)
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		gitDir := ginternals.DotGitPath(cfg)
+		require.NoError(t, os.MkdirAll(filepath.Join(gitDir, "info"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(gitDir, "info", "exclude"), []byte("*.tmp\n"), 0o644))
+
+		b, err := backend.NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		require.NoError(t, b.InitWithOptions(ginternals.Master, backend.InitOptions{
+			TemplateDir: templateDir,
+		}))
+
+		data, err := os.ReadFile(filepath.Join(gitDir, "info", "exclude"))
+		require.NoError(t, err)
+		assert.Equal(t, "*.tmp\n", string(data))
+	})
+
+	t.Run("should ignore a missing template", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := backend.NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		require.NoError(t, b.InitWithOptions(ginternals.Master, backend.InitOptions{
+			TemplateDir: filepath.Join(dir, "does-not-exist"),
+		}))
+		assert.FileExists(t, filepath.Join(ginternals.DotGitPath(cfg), "description"))
+	})
+
+	t.Run("should set the permissions of a shared repository", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("Windows doesn't support unix permissions")
+		}
+
+		templateDir := newTemplate(t)
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cfg := confutil.NewCommonConfig(t, dir)
+		b, err := backend.NewFS(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		require.NoError(t, b.InitWithOptions(ginternals.Master, backend.InitOptions{
+			TemplateDir: templateDir,
+			Shared:      config.SharedGroup(),
+		}))
+
+		gitDir := ginternals.DotGitPath(cfg)
+		info, err := os.Stat(filepath.Join(gitDir, "hooks", "pre-commit"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o775), info.Mode().Perm())
+
+		info, err = os.Stat(filepath.Join(gitDir, "info", "exclude"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o664), info.Mode().Perm())
+
+		info, err = os.Stat(ginternals.ObjectsPath(cfg))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o770), info.Mode().Perm())
+		assert.NotZero(t, info.Mode()&os.ModeSetgid, "setgid should be set")
+
+		require.NoError(t, cfg.Reload())
+		shared, ok, err := cfg.FromFile().SharedRepository()
+		require.NoError(t, err)
+		assert.True(t, ok, "core.sharedRepository should be set")
+		assert.Equal(t, config.SharedGroup(), shared)
+	})
+}
@@ -0,0 +1,55 @@
+package bench_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkCheckoutTree(b *testing.B) {
+	for _, parallelism := range []int{1, 4, 0} {
+		parallelism := parallelism
+		desc := fmt.Sprintf("parallelism=%d", parallelism)
+		if parallelism == 0 {
+			desc = "parallelism=default"
+		}
+		b.Run(desc, func(b *testing.B) {
+			// We don't want to write in the working tree of the fixture
+			// since it's shared by all the benchmarks
+			workTree, err := os.MkdirTemp("", "git-go-bench-checkout_")
+			require.NoError(b, err)
+			b.Cleanup(func() {
+				require.NoError(b, os.RemoveAll(workTree))
+			})
+			cfg, err := config.LoadConfigSkipEnv(config.LoadConfigOptions{
+				WorkTreePath: workTree,
+				GitDirPath:   filepath.Join(packedRepo.Path, config.DefaultDotGitDirName),
+			})
+			require.NoError(b, err)
+			r, err := git.OpenRepositoryWithParams(cfg, git.OpenOptions{})
+			require.NoError(b, err)
+			b.Cleanup(func() {
+				require.NoError(b, r.Close())
+			})
+
+			head, err := r.Commit(packedRepo.Head)
+			require.NoError(b, err)
+			tree, err := r.Tree(head.TreeID())
+			require.NoError(b, err)
+			opts := git.CheckoutOptions{
+				Parallelism: parallelism,
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, r.CheckoutTreeWithOptions(tree, opts))
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package bench contains reproducible benchmarks of the most common
+// operations (reading objects, resolving deltas, walking the history,
+// listing the references, ...).
+//
+// The benchmarks run on repositories generated by repogen from a
+// fixed set of options, so two runs of the benchmarks always work on
+// the exact same objects. This makes it possible to compare the
+// results of two versions of the code using benchstat:
+//
+//	go test -run XXX -bench . -benchmem -count 10 ./bench > old.txt
+//	# apply the changes
+//	go test -run XXX -bench . -benchmem -count 10 ./bench > new.txt
+//	benchstat old.txt new.txt
+package bench
@@ -0,0 +1,118 @@
+package bench_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// looseRepo contains a small repository where all the objects
+	// are loose
+	looseRepo *repogen.Result
+	// packedRepo contains a large repository where all the objects
+	// are packed with long delta chains, and where the references
+	// are in packed-refs
+	packedRepo *repogen.Result
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run generates the fixtures, runs the benchmarks, and removes the
+// fixtures.
+// It's a different function than TestMain so the defers are called
+// before os.Exit()
+func run(m *testing.M) int {
+	// There's no need to generate the fixtures if we're not running
+	// the benchmarks
+	flag.Parse()
+	if flag.Lookup("test.bench").Value.String() == "" {
+		return m.Run()
+	}
+
+	dir, err := os.MkdirTemp("", "git-go-bench_")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not create the temp dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck // it's a temp dir
+
+	fixtures := []struct {
+		fixture **repogen.Result
+		name    string
+		opts    repogen.Options
+	}{
+		{
+			fixture: &looseRepo,
+			name:    "loose",
+			opts: repogen.Options{
+				Commits:   200,
+				Width:     50,
+				FileLines: 20,
+			},
+		},
+		{
+			fixture: &packedRepo,
+			name:    "packed",
+			opts: repogen.Options{
+				Commits:    2000,
+				Width:      100,
+				FileLines:  20,
+				Branches:   500,
+				Tags:       500,
+				Packs:      1,
+				PackedRefs: true,
+			},
+		},
+	}
+	for _, f := range fixtures {
+		*f.fixture, err = repogen.Generate(filepath.Join(dir, f.name), f.opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not generate the %s repository: %v\n", f.name, err)
+			return 1
+		}
+	}
+	return m.Run()
+}
+
+// openBackend returns a backend for the given fixture
+func openBackend(b *testing.B, f *repogen.Result, opts backend.Options) *backend.Backend {
+	b.Helper()
+
+	cfg, err := config.LoadConfigSkipEnv(config.LoadConfigOptions{
+		WorkTreePath: f.Path,
+		GitDirPath:   filepath.Join(f.Path, config.DefaultDotGitDirName),
+	})
+	require.NoError(b, err)
+	dotGit, err := backend.NewWithOptions(cfg, afero.NewOsFs(), opts)
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		require.NoError(b, dotGit.Close())
+	})
+	return dotGit
+}
+
+// openRepo returns a repository for the given fixture
+func openRepo(b *testing.B, f *repogen.Result, opts backend.Options) *git.Repository {
+	b.Helper()
+
+	r, err := git.OpenRepositoryWithOptions(f.Path, git.OpenOptions{
+		GitBackend: openBackend(b, f, opts),
+	})
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		require.NoError(b, r.Close())
+	})
+	return r
+}
@@ -0,0 +1,86 @@
+package bench_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkReadObject(b *testing.B) {
+	testCases := []struct {
+		desc    string
+		fixture *repogen.Result
+		opts    backend.Options
+	}{
+		{
+			desc:    "loose",
+			fixture: looseRepo,
+			opts:    backend.Options{CacheSize: -1},
+		},
+		{
+			desc:    "packed",
+			fixture: packedRepo,
+			opts:    backend.Options{CacheSize: -1},
+		},
+		{
+			desc:    "packed with mmap",
+			fixture: packedRepo,
+			opts:    backend.Options{CacheSize: -1, MmapPacks: true},
+		},
+		{
+			desc:    "packed with cache",
+			fixture: packedRepo,
+			opts:    backend.Options{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		b.Run(tc.desc, func(b *testing.B) {
+			dotGit := openBackend(b, tc.fixture, tc.opts)
+			blobs := tc.fixture.Blobs
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := dotGit.Object(blobs[i%len(blobs)])
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+// BenchmarkResolveDeltas reads the oldest blobs of the packed
+// repository, which are at the end of the longest delta chains since
+// the newest version of each file is used as the base
+func BenchmarkResolveDeltas(b *testing.B) {
+	testCases := []struct {
+		desc string
+		opts backend.Options
+	}{
+		{
+			desc: "no cache",
+			opts: backend.Options{CacheSize: -1},
+		},
+		{
+			desc: "no cache with mmap",
+			opts: backend.Options{CacheSize: -1, MmapPacks: true},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		b.Run(tc.desc, func(b *testing.B) {
+			require.NotZero(b, packedRepo.Deltas, "the packfile should contain deltas")
+			dotGit := openBackend(b, packedRepo, tc.opts)
+			blobs := packedRepo.Blobs[:100]
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := dotGit.Object(blobs[i%len(blobs)])
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package bench_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/stretchr/testify/require"
+)
+
+// packedRepoFile returns the content of the file of the packfile of
+// the packed repository that has the given extension
+func packedRepoFile(b *testing.B, ext string) []byte {
+	b.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(packedRepo.Path, ".git", "objects", "pack", "*"+ext))
+	require.NoError(b, err)
+	require.Len(b, paths, 1)
+	data, err := os.ReadFile(paths[0])
+	require.NoError(b, err)
+	return data
+}
+
+func BenchmarkParsePackIndex(b *testing.B) {
+	data := packedRepoFile(b, packfile.ExtIndex)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx, err := packfile.NewIndex(bufio.NewReader(bytes.NewReader(data)))
+		require.NoError(b, err)
+		// the index is parsed on the first lookup
+		_, err = idx.GetObjectOffset(packedRepo.Head)
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	data := packedRepoFile(b, packfile.ExtPackfile)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := packfile.BuildIndex(data)
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,46 @@
+package bench_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkWalkReferences(b *testing.B) {
+	r := openRepo(b, packedRepo, backend.Options{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := r.WalkReferences(func(ref *ginternals.Reference) error {
+			return nil
+		})
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkReferencesWithPrefix(b *testing.B) {
+	r := openRepo(b, packedRepo, backend.Options{})
+	prefixes := []string{"refs/tags/"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := r.ReferencesWithPrefix(prefixes)
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkReference(b *testing.B) {
+	r := openRepo(b, packedRepo, backend.Options{})
+	name := ginternals.LocalBranchFullName("branch-0250")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := r.Reference(name)
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,60 @@
+package bench_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkWalkCommits(b *testing.B) {
+	testCases := []struct {
+		desc string
+		opts backend.Options
+	}{
+		{
+			desc: "default",
+			opts: backend.Options{},
+		},
+		{
+			desc: "mmap",
+			opts: backend.Options{MmapPacks: true},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		b.Run(tc.desc, func(b *testing.B) {
+			r := openRepo(b, packedRepo, tc.opts)
+			from := []ginternals.Oid{packedRepo.Head}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				count := 0
+				err := r.WalkCommits(from, git.WalkOptions{}, func(c *object.Commit) error {
+					count++
+					return nil
+				})
+				require.NoError(b, err)
+				require.Equal(b, len(packedRepo.Commits), count)
+			}
+		})
+	}
+}
+
+func BenchmarkWalkObjects(b *testing.B) {
+	r := openRepo(b, packedRepo, backend.Options{})
+	from := []ginternals.Oid{packedRepo.Head}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := r.WalkObjects(from, git.WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			return nil
+		})
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrBisectInvalidRange is an error thrown when a bisect cannot be
+// started because the bad commit is reachable from a good commit
+var ErrBisectInvalidRange = errors.New("the bad commit is an ancestor of a good commit")
+
+// BisectVerdict represents the result of the test of a commit
+// during a bisect
+type BisectVerdict int8
+
+const (
+	// BisectGood means that the commit doesn't contain the change
+	// being looked for
+	BisectGood BisectVerdict = iota + 1
+	// BisectBad means that the commit contains the change being
+	// looked for
+	BisectBad
+	// BisectSkip means that the commit cannot be tested
+	BisectSkip
+)
+
+// BisectFunc represents a function used to test a commit during
+// a bisect
+type BisectFunc = func(c *object.Commit) (BisectVerdict, error)
+
+// BisectResult contains the outcome of a bisect
+type BisectResult struct {
+	// FirstBad contains the first bad commit. It's nil if the first
+	// bad commit could not be found because of skipped commits
+	FirstBad *object.Commit
+	// Candidates contains the commits that could be the first bad
+	// commit, when skipped commits prevented from finding it
+	Candidates []*object.Commit
+	// Tested contains the number of commits that have been tested
+	Tested int
+}
+
+// Bisect finds the first bad commit between the bad commit and the
+// good ones, using a binary search.
+// test is called on the commits to test, one at a time. Before each
+// call, BISECT_HEAD is set to the commit being tested (like with
+// git bisect --no-checkout), so external tools can inspect it. The
+// pseudo-ref is removed once the bisect is over.
+// ErrBisectInvalidRange is returned if the bad commit is reachable
+// from one of the good commits
+func (r *Repository) Bisect(bad ginternals.Oid, good []ginternals.Oid, test BisectFunc) (res *BisectResult, err error) {
+	badCommit, err := r.peelToCommit(bad)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the bad commit: %w", err)
+	}
+	good = append([]ginternals.Oid{}, good...)
+	skipped := map[ginternals.Oid]struct{}{}
+	defer func() {
+		e := r.dotGit.DeletePseudoReference(ginternals.BisectHead)
+		if e != nil && !errors.Is(e, ginternals.ErrRefNotFound) && err == nil {
+			err = fmt.Errorf("could not remove %s: %w", ginternals.BisectHead, e)
+		}
+	}()
+
+	res = &BisectResult{}
+	for {
+		candidates := []*object.Commit{}
+		err = r.WalkCommits([]ginternals.Oid{badCommit.ID()}, WalkOptions{Exclude: good}, func(c *object.Commit) error {
+			candidates = append(candidates, c)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not list the commits to test: %w", err)
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%s: %w", badCommit.ID().String(), ErrBisectInvalidRange)
+		}
+
+		next := bisectMidpoint(candidates, badCommit.ID(), skipped)
+		if next == nil {
+			if len(candidates) == 1 {
+				res.FirstBad = badCommit
+				return res, nil
+			}
+			// Only skipped commits are left, so the first bad commit
+			// is either one of them, or the bad commit
+			res.Candidates = candidates
+			return res, nil
+		}
+
+		if err = r.dotGit.WritePseudoReference(ginternals.BisectHead, next.ID()); err != nil {
+			return nil, fmt.Errorf("could not update %s: %w", ginternals.BisectHead, err)
+		}
+		verdict, err := test(next)
+		if err != nil {
+			return nil, fmt.Errorf("could not test %s: %w", next.ID().String(), err)
+		}
+		res.Tested++
+		switch verdict {
+		case BisectGood:
+			good = append(good, next.ID())
+		case BisectBad:
+			badCommit = next
+		case BisectSkip:
+			skipped[next.ID()] = struct{}{}
+		default:
+			//nolint:goerr113 // no need to wrap the error, this would only be caused by a bug in the test function
+			return nil, fmt.Errorf("invalid verdict %d for %s", verdict, next.ID().String())
+		}
+	}
+}
+
+// bisectMidpoint returns the commit that splits the candidates the
+// most evenly, or nil if there are no commits left to test.
+// The candidates are expected to be all the commits reachable from
+// the bad commit that have not been marked as good, most recent
+// first.
+// Like git, a commit is considered to split the candidates evenly
+// when the number of candidates it can reach is the closest to
+// half of the candidates
+func bisectMidpoint(candidates []*object.Commit, bad ginternals.Oid, skipped map[ginternals.Oid]struct{}) *object.Commit {
+	index := make(map[ginternals.Oid]int, len(candidates))
+	for i, c := range candidates {
+		index[c.ID()] = i
+	}
+
+	var best *object.Commit
+	bestDistance := -1
+	seen := make([]int, len(candidates))
+	for i, c := range candidates {
+		if c.ID() == bad {
+			continue
+		}
+		if _, ok := skipped[c.ID()]; ok {
+			continue
+		}
+
+		// We count the number of candidates reachable from c. seen
+		// uses i+1 as a marker so we don't have to reset it between
+		// each commit
+		weight := 0
+		stack := []int{i}
+		seen[i] = i + 1
+		for len(stack) > 0 {
+			current := candidates[stack[len(stack)-1]]
+			stack = stack[:len(stack)-1]
+			weight++
+			for _, p := range current.ParentIDs() {
+				j, ok := index[p]
+				if !ok || seen[j] == i+1 {
+					continue
+				}
+				seen[j] = i + 1
+				stack = append(stack, j)
+			}
+		}
+
+		distance := weight
+		if len(candidates)-weight < distance {
+			distance = len(candidates) - weight
+		}
+		if distance > bestDistance {
+			best = c
+			bestDistance = distance
+		}
+	}
+	return best
+}
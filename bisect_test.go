@@ -0,0 +1,123 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisect(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns a new copy of the small repo
+	newRepo := func(t *testing.T) *Repository {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		return r
+	}
+
+	oid := func(t *testing.T, sha string) ginternals.Oid {
+		t.Helper()
+		oid, err := ginternals.NewOidFromStr(sha)
+		require.NoError(t, err)
+		return oid
+	}
+
+	initialCommit := "077fe611f58db33a6fdb15fc262f8016301ddb15"
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	// go.mod was added by 1dcdadc "build: switch to go module"
+	firstBad := "1dcdadc2a420225783794fbffd51e2e137a69646"
+
+	// hasGoMod returns whether the commit contains a go.mod file
+	hasGoMod := func(t *testing.T, r *Repository, c *object.Commit) bool {
+		t.Helper()
+		tree, err := r.Tree(c.TreeID())
+		require.NoError(t, err)
+		_, found := tree.Entry("go.mod")
+		return found
+	}
+
+	t.Run("should find the first bad commit", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		initial := oid(t, initialCommit)
+		tested := []string{}
+		res, err := r.Bisect(oid(t, head), []ginternals.Oid{initial}, func(c *object.Commit) (BisectVerdict, error) {
+			tested = append(tested, c.ID().String())
+			// BISECT_HEAD should point to the commit being tested
+			ref, err := r.Reference(ginternals.BisectHead)
+			require.NoError(t, err)
+			assert.Equal(t, c.ID(), ref.Target())
+
+			if hasGoMod(t, r, c) {
+				return BisectBad, nil
+			}
+			return BisectGood, nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res.FirstBad)
+		assert.Equal(t, firstBad, res.FirstBad.ID().String())
+		// generated using git bisect run
+		assert.Equal(t, []string{
+			"f0f70144f38695250606b86a50cff2b440a417f3",
+			"1dcdadc2a420225783794fbffd51e2e137a69646",
+			"645bda6fdb1a0651ac564394f8edb32b02dde7b3",
+			"f96f63e52cb8862b2c2d1a8b868229259c57854e",
+		}, tested)
+		assert.Equal(t, 4, res.Tested)
+
+		_, err = r.Reference(ginternals.BisectHead)
+		assert.True(t, errors.Is(err, ginternals.ErrRefNotFound), "BISECT_HEAD should have been removed")
+	})
+
+	t.Run("should return the candidates when commits are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		bad := "645bda6fdb1a0651ac564394f8edb32b02dde7b3"
+		res, err := r.Bisect(oid(t, bad), []ginternals.Oid{oid(t, initialCommit)}, func(c *object.Commit) (BisectVerdict, error) {
+			return BisectSkip, nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, res.FirstBad)
+		require.Len(t, res.Candidates, 2)
+		assert.Equal(t, bad, res.Candidates[0].ID().String())
+		assert.Equal(t, "fcfe68a0e44e04bd7fd564fc0b75f1ae457e18b3", res.Candidates[1].ID().String())
+	})
+
+	t.Run("should fail if bad is an ancestor of good", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		_, err := r.Bisect(oid(t, initialCommit), []ginternals.Oid{oid(t, head)}, func(c *object.Commit) (BisectVerdict, error) {
+			return BisectGood, nil
+		})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrBisectInvalidRange), "unexpected error: %v", err)
+	})
+
+	t.Run("should bubble up the errors of the test", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		testErr := errors.New("test failed")
+		_, err := r.Bisect(oid(t, head), []ginternals.Oid{oid(t, initialCommit)}, func(c *object.Commit) (BisectVerdict, error) {
+			return 0, testErr
+		})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, testErr), "unexpected error: %v", err)
+	})
+}
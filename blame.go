@@ -0,0 +1,336 @@
+package git
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// BlameHunk represents a set of consecutive lines of a file that
+// have been last modified by the same commit
+type BlameHunk struct {
+	// CommitID contains the ID of the commit that last modified the
+	// lines
+	CommitID ginternals.Oid
+	// OrigPath contains the path of the file in CommitID.
+	// Renames are not followed, so it's always the path of the
+	// blamed file, and the lines of a renamed file are attributed to
+	// the commit that renamed it
+	OrigPath string
+	// StartLine contains the (1-based) number of the first line of
+	// the hunk in the blamed version of the file
+	StartLine int
+	// OrigStartLine contains the (1-based) number of the first line
+	// of the hunk in the version of the file of CommitID
+	OrigStartLine int
+	// Lines contains the number of lines of the hunk
+	Lines int
+	// Boundary is set when the history of the lines could not be
+	// followed further than CommitID, either because it's a root
+	// commit or because it has been excluded
+	Boundary bool
+}
+
+// BlameFunc represents a function that will be applied on all the
+// hunks found by BlameIncremental()
+type BlameFunc = func(h BlameHunk) error
+
+// BlameOptions represents the options that can be used to blame
+// a file
+type BlameOptions struct {
+	// Exclude contains the commits at which the history stops being
+	// walked, alongside all their ancestors. The lines that come from
+	// those commits are attributed to the first excluded commit
+	// reached, and are flagged as boundary, like git prints them
+	// as ^commit.
+	// This is the equivalent of ^commit
+	Exclude []ginternals.Oid
+}
+
+// Blame returns, for each line of the file at the given path, the
+// commit that last modified it. The history is walked starting
+// from the commit targeted by from.
+// The hunks are sorted by line.
+// ErrPathNotFound is returned if the file doesn't exist in from
+func (r *Repository) Blame(from ginternals.Oid, path string, opts BlameOptions) ([]BlameHunk, error) {
+	hunks := []BlameHunk{}
+	err := r.BlameIncremental(from, path, opts, func(h BlameHunk) error {
+		hunks = append(hunks, h)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(hunks, func(i, j int) bool {
+		return hunks[i].StartLine < hunks[j].StartLine
+	})
+	return hunks, nil
+}
+
+// BlameIncremental works like Blame() but runs the provided method
+// on each hunk as soon as the commit that last modified it has been
+// found, instead of waiting for the whole file to be blamed.
+// This is the equivalent of blame --incremental.
+// The hunks are not sent in any particular order, but every line of
+// the file is part of exactly one hunk.
+// Returning WalkStop from f stops the process without error
+func (r *Repository) BlameIncremental(from ginternals.Oid, path string, opts BlameOptions, f BlameFunc) error {
+	excluded, err := r.reachableCommits(opts.Exclude)
+	if err != nil {
+		return fmt.Errorf("could not list the excluded commits: %w", err)
+	}
+
+	start, err := r.peelToCommit(from)
+	if err != nil {
+		return err
+	}
+	file, err := r.blameFile(start.TreeID(), path)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return fmt.Errorf("could not find %s in %s: %w", path, start.ID().String(), ErrPathNotFound)
+	}
+
+	b := &blamer{
+		repo:     r,
+		path:     path,
+		contents: map[ginternals.Oid][]string{},
+	}
+	content, err := b.content(file.ID)
+	if err != nil {
+		return err
+	}
+	if len(content) == 0 {
+		return nil
+	}
+
+	suspects := map[ginternals.Oid]*blameSuspect{
+		start.ID(): {
+			commit: start,
+			file:   file,
+			entries: []blameEntry{
+				{count: len(content)},
+			},
+		},
+	}
+	queue := &commitQueue{}
+	heap.Push(queue, start)
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(*object.Commit)
+		s, ok := suspects[c.ID()]
+		if !ok {
+			continue
+		}
+		delete(suspects, c.ID())
+
+		_, isExcluded := excluded[c.ID()]
+		boundary := isExcluded || len(c.ParentIDs()) == 0
+		if !boundary {
+			// Like git, the parents are looked at in order, which
+			// means the first parent always has the priority
+			for _, parentID := range c.ParentIDs() {
+				if len(s.entries) == 0 {
+					break
+				}
+				parent, err := r.Commit(parentID)
+				if err != nil {
+					return fmt.Errorf("could not get parent %s of %s: %w", parentID.String(), c.ID().String(), err)
+				}
+				parentFile, err := r.blameFile(parent.TreeID(), path)
+				if err != nil {
+					return err
+				}
+				if parentFile == nil {
+					continue
+				}
+
+				passed := s.entries
+				s.entries = nil
+				if parentFile.ID != s.file.ID {
+					passed, s.entries, err = b.passToParent(s.file, passed, parentFile)
+					if err != nil {
+						return err
+					}
+				}
+				if len(passed) == 0 {
+					continue
+				}
+
+				parentSuspect, ok := suspects[parentID]
+				if !ok {
+					parentSuspect = &blameSuspect{
+						commit: parent,
+						file:   parentFile,
+					}
+					suspects[parentID] = parentSuspect
+					heap.Push(queue, parent)
+				}
+				parentSuspect.entries = append(parentSuspect.entries, passed...)
+			}
+		}
+
+		for _, e := range coalesceBlameEntries(s.entries) {
+			err = f(BlameHunk{
+				CommitID:      c.ID(),
+				OrigPath:      path,
+				StartLine:     e.finalStart + 1,
+				OrigStartLine: e.origStart + 1,
+				Lines:         e.count,
+				Boundary:      boundary,
+			})
+			if err != nil {
+				if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// blameEntry represents a set of consecutive lines that are being
+// blamed
+type blameEntry struct {
+	// finalStart contains the (0-based) number of the first line
+	// in the blamed version of the file
+	finalStart int
+	// origStart contains the (0-based) number of the first line
+	// in the version of the file of the suspect
+	origStart int
+	count     int
+}
+
+// blameSuspect represents a commit that may be responsible for
+// some lines of the blamed file
+type blameSuspect struct {
+	commit  *object.Commit
+	file    *object.TreeEntry
+	entries []blameEntry
+}
+
+// blamer contains the data shared during a blame
+type blamer struct {
+	repo *Repository
+	path string
+	// contents contains the lines of all the versions of the file
+	// that have been loaded, indexed by blob ID
+	contents map[ginternals.Oid][]string
+}
+
+// content returns the lines of the given blob
+func (b *blamer) content(oid ginternals.Oid) ([]string, error) {
+	if lines, ok := b.contents[oid]; ok {
+		return lines, nil
+	}
+	o, err := b.repo.dotGit.Object(oid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get blob %s of %s: %w", oid.String(), b.path, err)
+	}
+	lines := diff.SplitLines(o.Bytes())
+	b.contents[oid] = lines
+	return lines, nil
+}
+
+// passToParent splits the entries of a suspect between the lines
+// that are also in the parent's version of the file (passed), and
+// the ones that have been introduced by the suspect (kept)
+func (b *blamer) passToParent(file *object.TreeEntry, entries []blameEntry, parentFile *object.TreeEntry) (passed, kept []blameEntry, err error) {
+	parentLines, err := b.content(parentFile.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines, err := b.content(file.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// mapping contains, for each line of the suspect, the matching
+	// line in the parent, or -1
+	mapping := make([]int, 0, len(lines))
+	parentLine := 0
+	for _, l := range diff.Strings(parentLines, lines) {
+		switch l.Op {
+		case diff.OpEqual:
+			mapping = append(mapping, parentLine)
+			parentLine++
+		case diff.OpDelete:
+			parentLine++
+		case diff.OpInsert:
+			mapping = append(mapping, -1)
+		}
+	}
+
+	// add adds a line to the list, extending the last entry if
+	// possible
+	add := func(entries []blameEntry, finalLine, origLine int) []blameEntry {
+		if len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			if last.finalStart+last.count == finalLine && last.origStart+last.count == origLine {
+				last.count++
+				return entries
+			}
+		}
+		return append(entries, blameEntry{
+			finalStart: finalLine,
+			origStart:  origLine,
+			count:      1,
+		})
+	}
+	for _, e := range entries {
+		for i := 0; i < e.count; i++ {
+			if parentLine := mapping[e.origStart+i]; parentLine != -1 {
+				passed = add(passed, e.finalStart+i, parentLine)
+				continue
+			}
+			kept = add(kept, e.finalStart+i, e.origStart+i)
+		}
+	}
+	return passed, kept, nil
+}
+
+// coalesceBlameEntries sorts the entries and merges the ones that
+// are consecutive in both versions of the file
+func coalesceBlameEntries(entries []blameEntry) []blameEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].finalStart < entries[j].finalStart
+	})
+	out := make([]blameEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(out) > 0 {
+			last := &out[len(out)-1]
+			if last.finalStart+last.count == e.finalStart && last.origStart+last.count == e.origStart {
+				last.count += e.count
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// blameFile returns the entry of the file at the given path, or nil
+// if the tree doesn't contain a file at this path
+func (r *Repository) blameFile(treeID ginternals.Oid, path string) (*object.TreeEntry, error) {
+	tree, err := r.Tree(treeID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get tree %s: %w", treeID.String(), err)
+	}
+	entry, err := tree.EntryByPath(r.dotGit, path)
+	if err != nil {
+		if errors.Is(err, object.ErrTreeEntryNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not get %s: %w", path, err)
+	}
+	if entry.Mode.ObjectType() != object.TypeBlob {
+		return nil, nil
+	}
+	return &entry, nil
+}
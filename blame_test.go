@@ -0,0 +1,140 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlame(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	head, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	cleanupBranch, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+	require.NoError(t, err)
+
+	// hunk returns a BlameHunk, panicking on error
+	hunk := func(sha string, origStart, start, lines int, boundary bool) BlameHunk {
+		oid, err := ginternals.NewOidFromStr(sha)
+		if err != nil {
+			panic(err)
+		}
+		return BlameHunk{
+			CommitID:      oid,
+			OrigPath:      "go.mod",
+			StartLine:     start,
+			OrigStartLine: origStart,
+			Lines:         lines,
+			Boundary:      boundary,
+		}
+	}
+
+	t.Run("should return the commit of each line", func(t *testing.T) {
+		t.Parallel()
+
+		hunks, err := r.Blame(head, "go.mod", BlameOptions{})
+		require.NoError(t, err)
+
+		// generated using git blame --incremental HEAD -- go.mod
+		expected := []BlameHunk{
+			hunk("0499018e26f79d37ad056611b75730dcb12918fb", 1, 1, 1, false),
+			hunk("1dcdadc2a420225783794fbffd51e2e137a69646", 2, 2, 1, false),
+			hunk("2f2e900b4e87ab0d51809642eaf0c5a12a97d927", 3, 3, 1, false),
+			hunk("1dcdadc2a420225783794fbffd51e2e137a69646", 4, 4, 2, false),
+			hunk("5c283d5284084a0615e0a4b08c15297f067ddd04", 8, 6, 1, false),
+			hunk("d26b5b27935e59022de19939bb16c39f6b38a0f0", 7, 7, 2, false),
+			hunk("5c283d5284084a0615e0a4b08c15297f067ddd04", 11, 9, 2, false),
+			hunk("d26b5b27935e59022de19939bb16c39f6b38a0f0", 11, 11, 1, false),
+			hunk("1dcdadc2a420225783794fbffd51e2e137a69646", 12, 12, 1, false),
+		}
+		assert.Equal(t, expected, hunks)
+	})
+
+	t.Run("excluded commits should be boundaries", func(t *testing.T) {
+		t.Parallel()
+
+		hunks, err := r.Blame(head, "go.mod", BlameOptions{
+			Exclude: []ginternals.Oid{cleanupBranch},
+		})
+		require.NoError(t, err)
+
+		// generated using git blame --incremental ml/cleanup-062020..HEAD -- go.mod
+		// The last line is ambiguous (it's a closing parenthesis) and
+		// git matches it with line 13 instead of 27 because it uses
+		// a different diff algorithm
+		expected := []BlameHunk{
+			hunk("0499018e26f79d37ad056611b75730dcb12918fb", 1, 1, 1, false),
+			hunk("f0f70144f38695250606b86a50cff2b440a417f3", 2, 2, 1, true),
+			hunk("2f2e900b4e87ab0d51809642eaf0c5a12a97d927", 3, 3, 1, false),
+			hunk("f0f70144f38695250606b86a50cff2b440a417f3", 4, 4, 2, true),
+			hunk("5c283d5284084a0615e0a4b08c15297f067ddd04", 8, 6, 1, false),
+			hunk("d26b5b27935e59022de19939bb16c39f6b38a0f0", 7, 7, 2, false),
+			hunk("5c283d5284084a0615e0a4b08c15297f067ddd04", 11, 9, 2, false),
+			hunk("d26b5b27935e59022de19939bb16c39f6b38a0f0", 11, 11, 1, false),
+			hunk("f0f70144f38695250606b86a50cff2b440a417f3", 27, 12, 1, true),
+		}
+		assert.Equal(t, expected, hunks)
+	})
+
+	t.Run("incremental blame should send the hunks as soon as they are found", func(t *testing.T) {
+		t.Parallel()
+
+		hunks := []BlameHunk{}
+		err := r.BlameIncremental(head, "go.mod", BlameOptions{}, func(h BlameHunk) error {
+			hunks = append(hunks, h)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, hunks, 9)
+		// the most recent commits are processed first
+		assert.Equal(t, "d26b5b27935e59022de19939bb16c39f6b38a0f0", hunks[0].CommitID.String())
+
+		lines := 0
+		for _, h := range hunks {
+			lines += h.Lines
+		}
+		assert.Equal(t, 12, lines)
+	})
+
+	t.Run("WalkStop should stop the blame", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.BlameIncremental(head, "go.mod", BlameOptions{}, func(h BlameHunk) error {
+			count++
+			return WalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("should fail on unknown path", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := r.Blame(head, "does/not/exist", BlameOptions{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrPathNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail on directories", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := r.Blame(head, "internal", BlameOptions{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrPathNotFound), "unexpected error: %v", err)
+	})
+}
@@ -0,0 +1,176 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+var (
+	// ErrBranchNotFound is returned when a branch doesn't exist
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrNoUpstream is returned when a branch doesn't track any
+	// other branch
+	ErrNoUpstream = errors.New("no upstream configured for branch")
+)
+
+// Branch represents a local branch (refs/heads/) or a remote
+// branch (refs/remotes/)
+type Branch struct {
+	*RefEntry
+}
+
+// Name returns the full name of the branch
+// (ex. refs/heads/main)
+func (b *Branch) Name() string {
+	return b.Reference.Name()
+}
+
+// IsRemote returns whether the branch is a remote branch
+// (ex. refs/remotes/origin/main)
+func (b *Branch) IsRemote() bool {
+	return ginternals.RemoteBranchShortName(b.Reference.Name()) != b.Reference.Name()
+}
+
+// Commit returns the commit targeted by the branch
+func (b *Branch) Commit() (*object.Commit, error) {
+	oid, err := b.Peel()
+	if err != nil {
+		return nil, fmt.Errorf("could not peel %s: %w", b.Name(), err)
+	}
+	return b.repo.peelToCommit(oid)
+}
+
+// Upstream returns the branch tracked by the branch, as set in
+// branch.<name>.remote and branch.<name>.merge.
+// ErrNoUpstream is returned if the branch doesn't track another
+// branch, which is always the case for the remote branches.
+// ginternals.ErrRefNotFound is returned if the tracked branch doesn't
+// exist (ex. it hasn't been fetched yet)
+func (b *Branch) Upstream() (*Branch, error) {
+	name, err := b.UpstreamName()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := b.repo.dotGit.Reference(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get upstream %s: %w", name, err)
+	}
+	return b.repo.newBranch(ref), nil
+}
+
+// UpstreamName returns the full name of the branch tracked by the
+// branch (ex. refs/remotes/origin/main), without checking that it
+// exists.
+// The remote branches are expected to follow the default layout
+// (refs/remotes/<remote>/<branch>).
+// ErrNoUpstream is returned if the branch doesn't track another
+// branch
+func (b *Branch) UpstreamName() (string, error) {
+	if b.IsRemote() {
+		return "", fmt.Errorf("%s: %w", b.ShortName, ErrNoUpstream)
+	}
+	cfg := b.repo.Config.FromFile()
+	remote, ok := cfg.BranchRemote(b.ShortName)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", b.ShortName, ErrNoUpstream)
+	}
+	merge, ok := cfg.BranchMerge(b.ShortName)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", b.ShortName, ErrNoUpstream)
+	}
+	// "." means the branch tracks a local branch
+	if remote == "." {
+		return merge, nil
+	}
+	return ginternals.RemoteBranchFullName(remote, ginternals.LocalBranchShortName(merge)), nil
+}
+
+// newBranch returns a Branch wrapping the given reference
+func (r *Repository) newBranch(ref *ginternals.Reference) *Branch {
+	shortName := ginternals.LocalBranchShortName(ref.Name())
+	if shortName == ref.Name() {
+		shortName = ginternals.RemoteBranchShortName(ref.Name())
+	}
+	return &Branch{
+		RefEntry: &RefEntry{
+			repo:      r,
+			Reference: ref,
+			ShortName: shortName,
+		},
+	}
+}
+
+// LookupBranch returns the local branch matching the given short name
+// (ex. main for refs/heads/main).
+// ErrBranchNotFound is returned if the branch doesn't exist
+func (r *Repository) LookupBranch(name string) (*Branch, error) {
+	return r.lookupBranch(ginternals.LocalBranchFullName(name))
+}
+
+// LookupRemoteBranch returns the branch of the given remote matching
+// the given short name (ex. origin and main for
+// refs/remotes/origin/main).
+// ErrBranchNotFound is returned if the branch doesn't exist
+func (r *Repository) LookupRemoteBranch(remote, name string) (*Branch, error) {
+	return r.lookupBranch(ginternals.RemoteBranchFullName(remote, name))
+}
+
+// lookupBranch returns the branch matching the given full name
+func (r *Repository) lookupBranch(fullName string) (*Branch, error) {
+	ref, err := r.dotGit.Reference(fullName)
+	if err != nil {
+		if errors.Is(err, ginternals.ErrRefNotFound) {
+			return nil, fmt.Errorf("%s: %w", fullName, ErrBranchNotFound)
+		}
+		return nil, fmt.Errorf("could not get %s: %w", fullName, err)
+	}
+	return r.newBranch(ref), nil
+}
+
+// Branches returns all the local branches of the repository, sorted
+// by name
+func (r *Repository) Branches() ([]*Branch, error) {
+	branches := []*Branch{}
+	err := r.WalkBranches(func(e *RefEntry) error {
+		branches = append(branches, &Branch{RefEntry: e})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+// RemoteBranches returns all the branches of the given remote, sorted
+// by name.
+// The branches of all the remotes are returned if remote is empty
+func (r *Repository) RemoteBranches(remote string) ([]*Branch, error) {
+	branches := []*Branch{}
+	err := r.WalkRemoteBranches(remote, func(e *RefEntry) error {
+		branches = append(branches, &Branch{RefEntry: e})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+// RemoteHeads returns the HEAD of the given remote, or the HEAD of
+// all the remotes if remote is empty, sorted by name.
+// The HEAD of a remote is not a branch, but is usually a symbolic
+// reference targeting the default branch of the remote
+func (r *Repository) RemoteHeads(remote string) ([]*Branch, error) {
+	heads := []*Branch{}
+	err := r.WalkRemoteHeads(remote, func(e *RefEntry) error {
+		heads = append(heads, &Branch{RefEntry: e})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return heads, nil
+}
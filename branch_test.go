@@ -0,0 +1,183 @@
+package git
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSmallTestRepo returns a copy of testutil.RepoSmall
+func newSmallTestRepo(t *testing.T) *Repository {
+	t.Helper()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+	return r
+}
+
+// appendConfig adds the given content to the local config of the
+// repository, and reloads the config
+func appendConfig(t *testing.T, r *Repository, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(ginternals.ConfigPath(r.Config), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, r.Config.Reload())
+}
+
+func TestBranches(t *testing.T) {
+	t.Parallel()
+
+	r := newSmallTestRepo(t)
+	branches, err := r.Branches()
+	require.NoError(t, err)
+	names := make([]string, 0, len(branches))
+	for _, b := range branches {
+		names = append(names, b.ShortName)
+		assert.False(t, b.IsRemote(), "%s should not be remote", b.Name())
+	}
+	assert.Equal(t, []string{"master", "ml/cleanup-062020", "ml/packfile/tests", "ml/tests"}, names)
+}
+
+func TestRemoteBranches(t *testing.T) {
+	t.Parallel()
+
+	r := newSmallTestRepo(t)
+	branches, err := r.RemoteBranches("origin")
+	require.NoError(t, err)
+	names := make([]string, 0, len(branches))
+	for _, b := range branches {
+		names = append(names, b.ShortName)
+		assert.True(t, b.IsRemote(), "%s should be remote", b.Name())
+	}
+	assert.Equal(t, []string{"origin/master", "origin/ml/cleanup-062020", "origin/ml/feat/clone"}, names)
+}
+
+func TestRemoteHeads(t *testing.T) {
+	t.Parallel()
+
+	r := newSmallTestRepo(t)
+	heads, err := r.RemoteHeads("")
+	require.NoError(t, err)
+	require.Len(t, heads, 1)
+	assert.Equal(t, "origin/HEAD", heads[0].ShortName)
+	assert.Equal(t, ginternals.SymbolicReference, heads[0].Reference.Type())
+	assert.Equal(t, "refs/remotes/origin/master", heads[0].Reference.SymbolicTarget())
+	assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", heads[0].Reference.Target().String())
+
+	heads, err = r.RemoteHeads("upstream")
+	require.NoError(t, err)
+	assert.Empty(t, heads)
+}
+
+func TestLookupBranch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should return the local branch", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		b, err := r.LookupBranch("ml/tests")
+		require.NoError(t, err)
+		assert.Equal(t, "refs/heads/ml/tests", b.Name())
+		assert.Equal(t, "ml/tests", b.ShortName)
+		assert.False(t, b.IsRemote())
+
+		c, err := b.Commit()
+		require.NoError(t, err)
+		assert.Equal(t, "f0f70144f38695250606b86a50cff2b440a417f3", c.ID().String())
+	})
+
+	t.Run("should return the remote branch", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		b, err := r.LookupRemoteBranch("origin", "ml/feat/clone")
+		require.NoError(t, err)
+		assert.Equal(t, "refs/remotes/origin/ml/feat/clone", b.Name())
+		assert.Equal(t, "origin/ml/feat/clone", b.ShortName)
+		assert.True(t, b.IsRemote())
+	})
+
+	t.Run("should fail on unknown branches", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		_, err := r.LookupBranch("nope")
+		require.ErrorIs(t, err, ErrBranchNotFound)
+		_, err = r.LookupRemoteBranch("upstream", "master")
+		require.ErrorIs(t, err, ErrBranchNotFound)
+	})
+}
+
+func TestBranchUpstream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should return the tracked remote branch", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		b, err := r.LookupBranch("ml/cleanup-062020")
+		require.NoError(t, err)
+		upstream, err := b.Upstream()
+		require.NoError(t, err)
+		assert.Equal(t, "refs/remotes/origin/ml/cleanup-062020", upstream.Name())
+		assert.Equal(t, "origin/ml/cleanup-062020", upstream.ShortName)
+		assert.True(t, upstream.IsRemote())
+	})
+
+	t.Run("should return the tracked local branch", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		appendConfig(t, r, "[branch \"ml/tests\"]\n\tremote = .\n\tmerge = refs/heads/master\n")
+
+		b, err := r.LookupBranch("ml/tests")
+		require.NoError(t, err)
+		upstream, err := b.Upstream()
+		require.NoError(t, err)
+		assert.Equal(t, "refs/heads/master", upstream.Name())
+		assert.False(t, upstream.IsRemote())
+	})
+
+	t.Run("should fail if nothing is tracked", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		b, err := r.LookupBranch("ml/tests")
+		require.NoError(t, err)
+		_, err = b.Upstream()
+		require.ErrorIs(t, err, ErrNoUpstream)
+
+		b, err = r.LookupRemoteBranch("origin", "master")
+		require.NoError(t, err)
+		_, err = b.Upstream()
+		require.ErrorIs(t, err, ErrNoUpstream)
+	})
+
+	t.Run("should fail if the tracked branch doesn't exist", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		appendConfig(t, r, "[branch \"ml/tests\"]\n\tremote = upstream\n\tmerge = refs/heads/master\n")
+		b, err := r.LookupBranch("ml/tests")
+		require.NoError(t, err)
+		_, err = b.Upstream()
+		require.ErrorIs(t, err, ginternals.ErrRefNotFound)
+		name, err := b.UpstreamName()
+		require.NoError(t, err)
+		assert.Equal(t, "refs/remotes/upstream/master", name)
+	})
+}
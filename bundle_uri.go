@@ -0,0 +1,54 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/transport"
+)
+
+// bundleRefsPrefix is the prefix of the references created from the
+// references of a bundle
+const bundleRefsPrefix = "refs/bundles/"
+
+// FetchBundleOptions represents the options that can be used to
+// fetch a bundle
+type FetchBundleOptions struct {
+	// Transport contains the options used to download the bundle.
+	// Transport.URIDownloader can be set to change how the bundle
+	// is downloaded
+	Transport transport.Options
+}
+
+// FetchBundle downloads the bundle at the given URI, adds its objects
+// to the repository, and creates a reference in refs/bundles/ for each
+// of its references (refs/heads/main becomes refs/bundles/heads/main),
+// the same way git clone --bundle-uri does.
+// This is meant to be used before fetching from the remote of a new
+// clone: the refs/bundles/ references are then sent as haves, so only
+// the objects that are not in the bundle are downloaded from the
+// remote.
+// The created references are returned
+func (r *Repository) FetchBundle(uri string, opts FetchBundleOptions) ([]*ginternals.Reference, error) {
+	b, err := transport.FetchBundleURI(r.dotGit, uri, opts.Transport)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the error already contains the URI
+	}
+
+	refs := make([]*ginternals.Reference, 0, len(b.Refs))
+	for _, bundleRef := range b.Refs {
+		name := strings.TrimPrefix(bundleRef.Name, "refs/")
+		if name == bundleRef.Name {
+			// HEAD and the other references outside of refs/ are
+			// not kept
+			continue
+		}
+		ref, err := r.NewReference(bundleRefsPrefix+name, bundleRef.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not create a reference for %s: %w", bundleRef.Name, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
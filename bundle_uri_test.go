@@ -0,0 +1,72 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchBundle(t *testing.T) {
+	t.Parallel()
+
+	// We create a bundle containing the last commit of the small repo
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	src, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, src.Close(), "failed closing repo")
+	})
+	head, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	parent, err := ginternals.NewOidFromStr("6097a04b7a327c4be68f222ca66e61b8e1abe5c1")
+	require.NoError(t, err)
+
+	objects := []*packfile.BuildObject{}
+	err = src.WalkObjects([]ginternals.Oid{head}, WalkOptions{Shallow: []ginternals.Oid{head}}, func(oid ginternals.Oid, typ object.Type, path string) error {
+		o, err := src.Object(oid)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &packfile.BuildObject{Object: o, Path: path})
+		return nil
+	})
+	require.NoError(t, err)
+	pack, err := packfile.Build(objects, packfile.BuildOptions{})
+	require.NoError(t, err)
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	bundlePath := filepath.Join(dir, "repo.bundle")
+	bundle := append([]byte("# v2 git bundle\n"+
+		head.String()+" HEAD\n"+
+		head.String()+" refs/heads/master\n\n"), pack.Pack...)
+	require.NoError(t, os.WriteFile(bundlePath, bundle, 0o644))
+
+	r, err := InitRepository(filepath.Join(dir, "clone"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	refs, err := r.FetchBundle("file://"+bundlePath, FetchBundleOptions{})
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, "refs/bundles/heads/master", refs[0].Name())
+
+	ref, err := r.Reference("refs/bundles/heads/master")
+	require.NoError(t, err)
+	assert.Equal(t, head, ref.Target())
+	c, err := r.Commit(head)
+	require.NoError(t, err)
+	assert.Equal(t, []ginternals.Oid{parent}, c.ParentIDs())
+	_, err = r.Tree(c.TreeID())
+	require.NoError(t, err)
+}
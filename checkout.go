@@ -0,0 +1,391 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/spf13/afero"
+)
+
+// CheckoutProgress contains the state of a checkout
+type CheckoutProgress struct {
+	// Files contains the number of files that have been written,
+	// symbolic links and submodules included
+	Files int
+	// Total contains the number of files to write
+	Total int
+	// Bytes contains the number of bytes written
+	Bytes int64
+}
+
+// CheckoutOptions represents the options that can be used to check
+// out a tree
+type CheckoutOptions struct {
+	// Paths limits the checkout to the given files and directories of
+	// the tree. The paths are relative to the root of the tree, and
+	// use "/" as separator.
+	// ErrPathNotFound is returned if a path is not in the tree.
+	// Defaults to the whole tree
+	Paths []string
+	// Progress is called once before anything is written, and then
+	// every time a file has been written.
+	// Defaults to nothing
+	Progress func(CheckoutProgress)
+	// Parallelism contains the number of files that are inflated and
+	// written at the same time. The directories are always created
+	// first, one after the other. Progress is never called
+	// concurrently.
+	// Defaults to the number of CPUs
+	Parallelism int
+}
+
+// CheckoutTree writes the content of the given tree in the working
+// tree of the repository:
+//   - The regular and executable files are written with the 0o644 and
+//     0o755 permissions
+//   - The symbolic links are created as symbolic links, or as text
+//     files containing the target of the link if core.symlinks is
+//     false or if the filesystem doesn't support symbolic links
+//   - The gitlinks (submodules) are created as empty directories,
+//     their content is not checked out
+//
+// ErrInvalidPath is returned if the tree contains a name that could
+// target something outside of the working tree, like "..", or that
+// could target a .git directory, like ".GIT" or "git~1". On Windows,
+// the names that cannot be used on Windows (like "con", "nul.txt",
+// or "a:b") are also refused.
+// A directory of the working tree that is a symbolic link is replaced
+// by a directory, the link is never followed.
+//
+// The files of the working tree that are part of the tree are
+// overwritten, the other ones are left untouched. Neither the index
+// nor HEAD are updated.
+// ErrBareRepository is returned if the repository is bare
+func (r *Repository) CheckoutTree(tree *object.Tree) error {
+	return r.CheckoutTreeWithOptions(tree, CheckoutOptions{})
+}
+
+// CheckoutTreeWithOptions works like CheckoutTree() using the
+// provided options.
+// The whole tree is listed before anything is written, so the number
+// of files to write is known when the progress is reported, and
+// nothing is written if the tree contains an invalid name
+func (r *Repository) CheckoutTreeWithOptions(tree *object.Tree, opts CheckoutOptions) error {
+	if r.IsBare() {
+		return ErrBareRepository
+	}
+	c := r.newTreeCheckout(r.worktreeOptions(), opts.Progress)
+	c.parallelism = opts.Parallelism
+	if len(opts.Paths) == 0 {
+		if err := c.addTree(tree, r.Config.WorkTreePath); err != nil {
+			return err
+		}
+	}
+	for _, p := range opts.Paths {
+		if err := c.addPath(tree, p); err != nil {
+			return err
+		}
+	}
+	return c.run()
+}
+
+// checkoutTree writes the entries of the given tree in dir
+func (r *Repository) checkoutTree(tree *object.Tree, dir string, opts worktreeOptions) error {
+	c := r.newTreeCheckout(opts, nil)
+	if err := c.addTree(tree, dir); err != nil {
+		return err
+	}
+	return c.run()
+}
+
+// checkoutFile represents a file, symbolic link or gitlink to write
+// in the working tree
+type checkoutFile struct {
+	entry object.TreeEntry
+	path  string
+}
+
+// treeCheckout contains the state of a checkout. The entries to write
+// are listed first, and then written by run()
+type treeCheckout struct {
+	repo        *Repository
+	opts        worktreeOptions
+	progress    func(CheckoutProgress)
+	parallelism int
+
+	// dirs contains the directories to create, the parents always
+	// being before their children
+	dirs    []string
+	dirsSet map[string]struct{}
+	files   []checkoutFile
+	// seen contains the paths of the entries that have already been
+	// added, in case the same path is requested multiple times
+	seen  map[string]struct{}
+	state CheckoutProgress
+}
+
+// newTreeCheckout returns an empty checkout
+func (r *Repository) newTreeCheckout(opts worktreeOptions, progress func(CheckoutProgress)) *treeCheckout {
+	return &treeCheckout{
+		repo:        r,
+		opts:        opts,
+		progress:    progress,
+		parallelism: runtime.NumCPU(),
+		dirsSet:     map[string]struct{}{},
+		seen:        map[string]struct{}{},
+	}
+}
+
+// addDir adds a directory to create, if it hasn't been added yet
+func (c *treeCheckout) addDir(path string) {
+	if _, ok := c.dirsSet[path]; ok {
+		return
+	}
+	c.dirsSet[path] = struct{}{}
+	c.dirs = append(c.dirs, path)
+}
+
+// checkName returns ErrInvalidPath if the given name of a tree entry
+// cannot be written in the working tree
+func (c *treeCheckout) checkName(name, path string) error {
+	if !pathutil.IsValidTreeEntryName(name) {
+		return fmt.Errorf("%s: %w", path, ErrInvalidPath)
+	}
+	if c.opts.protectWindows && !pathutil.IsValidWindowsName(name) {
+		return fmt.Errorf("%s: %w", path, ErrInvalidPath)
+	}
+	return nil
+}
+
+// addTree adds all the entries of the given tree, which is written
+// in dir
+func (c *treeCheckout) addTree(tree *object.Tree, dir string) error {
+	for _, e := range tree.Entries() {
+		if err := c.addEntry(e, filepath.Join(dir, e.Path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addEntry adds the given entry of a tree, which is written at path.
+// The content of the directories is added recursively
+func (c *treeCheckout) addEntry(e object.TreeEntry, path string) error {
+	if err := c.checkName(e.Path, path); err != nil {
+		return err
+	}
+	if _, ok := c.seen[path]; ok {
+		return nil
+	}
+	c.seen[path] = struct{}{}
+
+	switch e.Mode {
+	case object.ModeDirectory:
+		c.addDir(path)
+		sub, err := c.repo.Tree(e.ID)
+		if err != nil {
+			return fmt.Errorf("could not get tree %s: %w", e.ID.String(), err)
+		}
+		return c.addTree(sub, path)
+	case object.ModeGitLink, object.ModeFile, object.ModeExecutable, object.ModeSymLink:
+		c.files = append(c.files, checkoutFile{entry: e, path: path})
+		c.state.Total++
+		return nil
+	default:
+		return fmt.Errorf("%s: %o: %w", path, e.Mode, object.ErrTreeModeInvalid)
+	}
+}
+
+// addPath adds the entry of the given tree located at the given path,
+// alongside the directories containing it
+func (c *treeCheckout) addPath(tree *object.Tree, p string) error {
+	p = strings.Trim(p, "/")
+	if p == "" || p == "." {
+		return c.addTree(tree, c.repo.Config.WorkTreePath)
+	}
+	dir := c.repo.Config.WorkTreePath
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		e, ok := tree.Entry(name)
+		if !ok {
+			return fmt.Errorf("%s: %w", p, ErrPathNotFound)
+		}
+		if i == len(names)-1 {
+			return c.addEntry(e, path)
+		}
+		if e.Mode != object.ModeDirectory {
+			return fmt.Errorf("%s: %w", p, ErrPathNotFound)
+		}
+		if err := c.checkName(name, path); err != nil {
+			return err
+		}
+		c.addDir(path)
+		var err error
+		if tree, err = c.repo.Tree(e.ID); err != nil {
+			return fmt.Errorf("could not get tree %s: %w", e.ID.String(), err)
+		}
+		dir = path
+	}
+	return nil
+}
+
+// run writes the entries of the checkout in the working tree.
+// The directories are created first so the files can be written
+// in any order by a pool of workers
+func (c *treeCheckout) run() error {
+	if c.progress != nil {
+		c.progress(c.state)
+	}
+	for _, dir := range c.dirs {
+		if err := c.repo.checkoutDirectory(dir); err != nil {
+			return err
+		}
+	}
+
+	workers := c.parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(c.files) {
+		workers = len(c.files)
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	files := make(chan checkoutFile)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range files {
+				written, err := c.writeFile(f)
+
+				mu.Lock()
+				switch {
+				case err != nil && firstErr == nil:
+					firstErr = err
+				case err == nil:
+					c.state.Files++
+					c.state.Bytes += int64(written)
+					if c.progress != nil {
+						c.progress(c.state)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, f := range c.files {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		files <- f
+	}
+	close(files)
+	wg.Wait()
+	return firstErr
+}
+
+// writeFile writes the given file in the working tree, and returns
+// the number of bytes written
+func (c *treeCheckout) writeFile(f checkoutFile) (written int, err error) {
+	if f.entry.Mode == object.ModeGitLink {
+		return 0, c.repo.checkoutDirectory(f.path)
+	}
+	return c.repo.checkoutBlob(f.entry, f.path, c.opts.symlinks)
+}
+
+// checkoutDirectory creates the directory at the given path,
+// replacing any file that may already be there
+func (r *Repository) checkoutDirectory(path string) error {
+	info, err := r.lstatWorktreePath(path)
+	switch {
+	case err == nil && info.IsDir():
+		return nil
+	case err == nil:
+		if err = r.workTree.Remove(path); err != nil {
+			return fmt.Errorf("could not remove %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("could not check %s: %w", path, err)
+	}
+	if err = r.workTree.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkoutBlob writes the blob of the given entry at the given
+// path, replacing whatever may already be there.
+// Returns the number of bytes written
+func (r *Repository) checkoutBlob(e object.TreeEntry, path string, symlinks bool) (written int, err error) {
+	blob, err := r.Blob(e.ID)
+	if err != nil {
+		return 0, fmt.Errorf("could not get blob %s: %w", e.ID.String(), err)
+	}
+
+	// We always remove what's already there to make sure we never
+	// write through an existing symbolic link, and to make sure
+	// the file gets the right permissions
+	if _, err = r.lstatWorktreePath(path); err == nil {
+		if err = r.workTree.RemoveAll(path); err != nil {
+			return 0, fmt.Errorf("could not remove %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("could not check %s: %w", path, err)
+	}
+
+	if e.Mode == object.ModeSymLink && symlinks {
+		// symlinks is only true if the FS is an afero.Linker
+		linker := r.workTree.(afero.Linker) //nolint:forcetypeassert // checked by worktreeOptions()
+		if err = linker.SymlinkIfPossible(string(blob.Bytes()), path); err != nil {
+			return 0, fmt.Errorf("could not create symbolic link %s: %w", path, err)
+		}
+		return len(blob.Bytes()), nil
+	}
+
+	perm := os.FileMode(0o644)
+	if e.Mode == object.ModeExecutable {
+		perm = 0o755
+	}
+	// O_EXCL makes sure we fail instead of following a symbolic link
+	// that would have been created since we removed the file
+	f, err := r.workTree.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|pathutil.ONoFollow, perm)
+	if err != nil {
+		return 0, fmt.Errorf("could not create %s: %w", path, err)
+	}
+	if written, err = f.Write(blob.Bytes()); err != nil {
+		f.Close() //nolint:errcheck // it already failed
+		return 0, fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err = f.Close(); err != nil {
+		return 0, fmt.Errorf("could not close %s: %w", path, err)
+	}
+	return written, nil
+}
+
+// lstatWorktreePath returns the info of the given path of the
+// working tree, without following the symbolic links if the
+// filesystem supports it
+func (r *Repository) lstatWorktreePath(path string) (os.FileInfo, error) {
+	if lstater, ok := r.workTree.(afero.Lstater); ok {
+		info, _, err := lstater.LstatIfPossible(path)
+		return info, err //nolint:wrapcheck // the caller wraps the error
+	}
+	return r.workTree.Stat(path) //nolint:wrapcheck // the caller wraps the error
+}
@@ -0,0 +1,342 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWorktreeTestRepo returns an empty repository using the given
+// config, and a tree containing all the types of entry
+func newWorktreeTestRepo(t *testing.T, config string) (r *Repository, tree *object.Tree) {
+	t.Helper()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	r, err := InitRepository(dir)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	if config != "" {
+		f, err := os.OpenFile(filepath.Join(dir, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = f.WriteString(config)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	r, err = OpenRepository(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	content, err := r.NewBlob([]byte("content\n"))
+	require.NoError(t, err)
+	target, err := r.NewBlob([]byte("file"))
+	require.NoError(t, err)
+
+	tb := r.NewTreeBuilder()
+	require.NoError(t, tb.Insert("nested", content.ID(), object.ModeExecutable))
+	subtree, err := tb.Write()
+	require.NoError(t, err)
+
+	submodule, err := ginternals.NewOidFromStr("0348308155fbeb0015970d1a30666e51a2ea0e9f")
+	require.NoError(t, err)
+
+	tb = r.NewTreeBuilder()
+	require.NoError(t, tb.Insert("file", content.ID(), object.ModeFile))
+	require.NoError(t, tb.Insert("exec", content.ID(), object.ModeExecutable))
+	require.NoError(t, tb.Insert("link", target.ID(), object.ModeSymLink))
+	require.NoError(t, tb.Insert("submodule", submodule, object.ModeGitLink))
+	require.NoError(t, tb.Insert("dir", subtree.ID(), object.ModeDirectory))
+	tree, err = tb.Write()
+	require.NoError(t, err)
+	return r, tree
+}
+
+func TestCheckoutTree(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should write all the entries", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links are disabled by default on Windows")
+		}
+
+		r, tree := newWorktreeTestRepo(t, "")
+		require.NoError(t, r.CheckoutTree(tree))
+		root := r.Config.WorkTreePath
+
+		data, err := os.ReadFile(filepath.Join(root, "file"))
+		require.NoError(t, err)
+		assert.Equal(t, "content\n", string(data))
+		info, err := os.Stat(filepath.Join(root, "file"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0), info.Mode().Perm()&0o111, "file should not be executable")
+
+		info, err = os.Stat(filepath.Join(root, "exec"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o111), info.Mode().Perm()&0o111, "exec should be executable")
+
+		info, err = os.Stat(filepath.Join(root, "dir", "nested"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o111), info.Mode().Perm()&0o111, "dir/nested should be executable")
+
+		target, err := os.Readlink(filepath.Join(root, "link"))
+		require.NoError(t, err)
+		assert.Equal(t, "file", target)
+
+		info, err = os.Stat(filepath.Join(root, "submodule"))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir(), "the gitlink should be a directory")
+		entries, err := os.ReadDir(filepath.Join(root, "submodule"))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("should write symlinks as files if core.symlinks is false", func(t *testing.T) {
+		t.Parallel()
+
+		r, tree := newWorktreeTestRepo(t, "[core]\n\tsymlinks = false\n")
+		require.NoError(t, r.CheckoutTree(tree))
+
+		info, err := os.Lstat(filepath.Join(r.Config.WorkTreePath, "link"))
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular(), "link should be a regular file")
+		data, err := os.ReadFile(filepath.Join(r.Config.WorkTreePath, "link"))
+		require.NoError(t, err)
+		assert.Equal(t, "file", string(data))
+	})
+
+	t.Run("should replace existing files without following symlinks", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links need special permissions on Windows")
+		}
+
+		r, tree := newWorktreeTestRepo(t, "")
+		root := r.Config.WorkTreePath
+
+		outside, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		outsideFile := filepath.Join(outside, "file")
+		require.NoError(t, os.WriteFile(outsideFile, []byte("outside"), 0o644))
+		require.NoError(t, os.Symlink(outsideFile, filepath.Join(root, "file")))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "dir"), []byte("not a dir"), 0o644))
+
+		require.NoError(t, r.CheckoutTree(tree))
+
+		data, err := os.ReadFile(outsideFile)
+		require.NoError(t, err)
+		assert.Equal(t, "outside", string(data), "the file outside the worktree should not have changed")
+
+		info, err := os.Lstat(filepath.Join(root, "file"))
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular(), "file should be a regular file")
+
+		data, err = os.ReadFile(filepath.Join(root, "dir", "nested"))
+		require.NoError(t, err)
+		assert.Equal(t, "content\n", string(data))
+	})
+
+	t.Run("should refuse the names targeting .git or the parent directories", func(t *testing.T) {
+		t.Parallel()
+
+		for i, name := range []string{".git", ".GIT", "git~1", ".git.", "..", "."} {
+			r, _ := newWorktreeTestRepo(t, "")
+			blob, err := r.NewBlob([]byte("content"))
+			require.NoError(t, err, "test %d", i)
+			tree := object.NewTree([]object.TreeEntry{
+				{Path: name, ID: blob.ID(), Mode: object.ModeFile},
+			})
+			err = r.CheckoutTree(tree)
+			require.ErrorIs(t, err, ErrInvalidPath, "test %d", i)
+		}
+	})
+
+	t.Run("should not write through a directory that is a symbolic link", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links need special permissions on Windows")
+		}
+
+		r, tree := newWorktreeTestRepo(t, "")
+		root := r.Config.WorkTreePath
+
+		outside, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		require.NoError(t, os.Symlink(outside, filepath.Join(root, "dir")))
+
+		require.NoError(t, r.CheckoutTree(tree))
+
+		_, err := os.Lstat(filepath.Join(outside, "nested"))
+		require.ErrorIs(t, err, os.ErrNotExist, "nothing should have been written outside of the worktree")
+
+		info, err := os.Lstat(filepath.Join(root, "dir"))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir(), "dir should be a directory")
+	})
+
+	t.Run("should refuse the names reserved by Windows", func(t *testing.T) {
+		t.Parallel()
+
+		for i, name := range []string{"con", "NUL.txt", "file:stream", "file."} {
+			r, _ := newWorktreeTestRepo(t, "")
+			blob, err := r.NewBlob([]byte("content"))
+			require.NoError(t, err, "test %d", i)
+			tree := object.NewTree([]object.TreeEntry{
+				{Path: name, ID: blob.ID(), Mode: object.ModeFile},
+			})
+
+			// We call checkoutTree directly so the names are also
+			// checked on the other platforms
+			opts := r.worktreeOptions()
+			opts.protectWindows = true
+			err = r.checkoutTree(tree, r.Config.WorkTreePath, opts)
+			require.ErrorIs(t, err, ErrInvalidPath, "test %d", i)
+			_, err = os.Lstat(filepath.Join(r.Config.WorkTreePath, name))
+			require.ErrorIs(t, err, os.ErrNotExist, "test %d: nothing should have been written", i)
+		}
+	})
+
+	t.Run("should support long paths on Windows", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS != "windows" {
+			t.Skip("only Windows limits the length of the paths")
+		}
+
+		r, _ := newWorktreeTestRepo(t, "")
+		blob, err := r.NewBlob([]byte("content"))
+		require.NoError(t, err)
+
+		// We create a path longer than 260 chars (MAX_PATH) using
+		// nested directories of 50 chars
+		name := strings.Repeat("d", 50)
+		tree := object.NewTree([]object.TreeEntry{
+			{Path: "file", ID: blob.ID(), Mode: object.ModeFile},
+		})
+		for i := 0; i < 6; i++ {
+			o, err := r.WriteObject(tree.ToObject())
+			require.NoError(t, err)
+			tree = object.NewTree([]object.TreeEntry{
+				{Path: name, ID: o, Mode: object.ModeDirectory},
+			})
+		}
+		_, err = r.WriteObject(tree.ToObject())
+		require.NoError(t, err)
+
+		require.NoError(t, r.CheckoutTree(tree))
+		p := filepath.Join(r.Config.WorkTreePath, name, name, name, name, name, name, "file")
+		require.Greater(t, len(p), 260)
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+	})
+
+	t.Run("should fail on a bare repository", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepositoryWithOptions(dir, InitOptions{IsBare: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		err = r.CheckoutTree(object.NewTree(nil))
+		require.ErrorIs(t, err, ErrBareRepository)
+	})
+}
+
+func TestCheckoutTreeWithOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should report the progress", func(t *testing.T) {
+		t.Parallel()
+
+		r, tree := newWorktreeTestRepo(t, "[core]\n\tsymlinks = false\n")
+		var reports []CheckoutProgress
+		err := r.CheckoutTreeWithOptions(tree, CheckoutOptions{
+			Progress: func(p CheckoutProgress) {
+				reports = append(reports, p)
+			},
+		})
+		require.NoError(t, err)
+
+		// file, exec, link, submodule, and dir/nested
+		require.Len(t, reports, 6)
+		assert.Equal(t, CheckoutProgress{Total: 5}, reports[0])
+		for i, p := range reports {
+			assert.Equal(t, i, p.Files, "unexpected number of files for report %d", i)
+			assert.Equal(t, 5, p.Total, "unexpected total for report %d", i)
+		}
+		// 3 files of 8 bytes, and a link of 4 bytes
+		assert.Equal(t, int64(28), reports[5].Bytes)
+	})
+
+	t.Run("should only write the requested paths", func(t *testing.T) {
+		t.Parallel()
+
+		r, tree := newWorktreeTestRepo(t, "")
+		var last CheckoutProgress
+		err := r.CheckoutTreeWithOptions(tree, CheckoutOptions{
+			Paths: []string{"dir/nested", "file", "dir/", "file"},
+			Progress: func(p CheckoutProgress) {
+				last = p
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CheckoutProgress{Files: 2, Total: 2, Bytes: 16}, last)
+
+		root := r.Config.WorkTreePath
+		assert.FileExists(t, filepath.Join(root, "file"))
+		assert.FileExists(t, filepath.Join(root, "dir", "nested"))
+		assert.NoFileExists(t, filepath.Join(root, "exec"))
+	})
+
+	t.Run("should write the same files whatever the parallelism", func(t *testing.T) {
+		t.Parallel()
+
+		for _, parallelism := range []int{1, 2, 10} {
+			r, tree := newWorktreeTestRepo(t, "[core]\n\tsymlinks = false\n")
+			var last CheckoutProgress
+			err := r.CheckoutTreeWithOptions(tree, CheckoutOptions{
+				Parallelism: parallelism,
+				Progress: func(p CheckoutProgress) {
+					last = p
+				},
+			})
+			require.NoError(t, err, "parallelism %d", parallelism)
+			assert.Equal(t, CheckoutProgress{Files: 5, Total: 5, Bytes: 28}, last, "parallelism %d", parallelism)
+
+			for _, p := range []string{"file", "exec", "link", filepath.Join("dir", "nested")} {
+				assert.FileExists(t, filepath.Join(r.Config.WorkTreePath, p), "parallelism %d", parallelism)
+			}
+			assert.DirExists(t, filepath.Join(r.Config.WorkTreePath, "submodule"), "parallelism %d", parallelism)
+		}
+	})
+
+	t.Run("should fail if a path is not in the tree", func(t *testing.T) {
+		t.Parallel()
+
+		r, tree := newWorktreeTestRepo(t, "")
+		err := r.CheckoutTreeWithOptions(tree, CheckoutOptions{
+			Paths: []string{"file", "file/nested"},
+		})
+		require.ErrorIs(t, err, ErrPathNotFound)
+		assert.NoFileExists(t, filepath.Join(r.Config.WorkTreePath, "file"), "nothing should have been written")
+	})
+}
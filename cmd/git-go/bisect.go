@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+// bisectSkipExitCode is the exit code used by a command to tell
+// that the current commit cannot be tested
+const bisectSkipExitCode = 125
+
+func newBisectCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bisect",
+		Short: "Use binary search to find the commit that introduced a bug",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "run BAD [GOOD...] -- CMD [ARGS...]",
+		Short: "Find the first bad commit by running a command on the commits to test",
+		Long: `Find the first bad commit by running a command on the commits to test.
+The working tree is not updated: BISECT_HEAD points to the commit being
+tested, like with git bisect --no-checkout.
+The command should exit with 0 if the commit is good, 125 if the commit
+cannot be tested, and any other code between 1 and 127 if the commit is
+bad. Any other exit code aborts the bisect.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 1 || dash == len(args) {
+				return errors.New("usage: bisect run BAD [GOOD...] -- CMD [ARGS...]")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			return bisectRunCmd(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), cfg, bisectRunParams{
+				bad:     args[0],
+				good:    args[1:dash],
+				command: args[dash:],
+			})
+		},
+	})
+	return cmd
+}
+
+type bisectRunParams struct {
+	bad     string
+	good    []string
+	command []string
+}
+
+func bisectRunCmd(ctx context.Context, out, errOut io.Writer, cfg *globalFlags, p bisectRunParams) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	bad, err := r.RevParse(p.bad)
+	if err != nil {
+		return wrapRevisionError(err)
+	}
+	good := make([]ginternals.Oid, 0, len(p.good))
+	for _, rev := range p.good {
+		oid, err := r.RevParse(rev)
+		if err != nil {
+			return wrapRevisionError(err)
+		}
+		good = append(good, oid)
+	}
+
+	commandLine := strings.Join(p.command, " ")
+	res, err := r.Bisect(bad, good, func(c *object.Commit) (git.BisectVerdict, error) {
+		fmt.Fprintf(out, "running '%s' on %s\n", commandLine, c.ID().String())
+		// The command may be slow, so we want the user to know what's
+		// running
+		if err := flushOutput(out); err != nil {
+			return 0, fmt.Errorf("could not write the output: %w", err)
+		}
+		command := exec.CommandContext(ctx, p.command[0], p.command[1:]...) //nolint:gosec // running a user-provided command is the whole point
+		command.Dir = cfg.C.String()
+		command.Stdout = out
+		command.Stderr = errOut
+		err := command.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			return 0, ctx.Err()
+		case err == nil:
+			return git.BisectGood, nil
+		case !errors.As(err, &exitErr):
+			return 0, fmt.Errorf("could not run '%s': %w", commandLine, err)
+		}
+
+		code := exitErr.ExitCode()
+		switch {
+		case code == bisectSkipExitCode:
+			return git.BisectSkip, nil
+		case code > 0 && code < 128:
+			return git.BisectBad, nil
+		}
+		return 0, fmt.Errorf("bisect run failed: exit code %d from '%s' is < 0 or >= 128", code, commandLine)
+	})
+	if err != nil {
+		return err
+	}
+
+	if res.FirstBad == nil {
+		fmt.Fprintln(out, "There are only 'skip'ped commits left to test.")
+		fmt.Fprintln(out, "The first bad commit could be any of:")
+		for _, c := range res.Candidates {
+			fmt.Fprintln(out, c.ID().String())
+		}
+		return errors.New("we cannot bisect more")
+	}
+	fmt.Fprintf(out, "%s is the first bad commit\n", res.FirstBad.ID().String())
+	fmt.Fprintf(out, "%s\n", res.FirstBad.Subject())
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisectRun(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is needed to run this test")
+	}
+
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	good := "645bda6fdb1a0651ac564394f8edb32b02dde7b3"
+
+	testCases := []struct {
+		desc           string
+		exitCode       int
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			desc:           "all bad",
+			exitCode:       1,
+			expectedOutput: "f96f63e52cb8862b2c2d1a8b868229259c57854e is the first bad commit\ndoc(readme): update short term goals\n",
+		},
+		{
+			desc:           "all good",
+			exitCode:       0,
+			expectedOutput: head + " is the first bad commit\n",
+		},
+		{
+			desc:           "all skipped",
+			exitCode:       125,
+			expectedError:  "we cannot bisect more",
+			expectedOutput: "There are only 'skip'ped commits left to test.\n",
+		},
+		{
+			desc:          "invalid exit code",
+			exitCode:      200,
+			expectedError: "bisect run failed: exit code 200",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			outBuf := bytes.NewBufferString("")
+			cmd := newRootCmd(repoPath, env.NewFromOs())
+			cmd.SetOut(outBuf)
+			cmd.SetErr(bytes.NewBufferString(""))
+			cmd.SetArgs([]string{"bisect", "run", head, good, "--", "sh", "-c", fmt.Sprintf("exit %d", tc.exitCode)})
+			err := cmd.Execute()
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			out := outBuf.String()
+			assert.True(t, strings.HasPrefix(out, "running 'sh -c exit "), "unexpected output: %s", out)
+			assert.Contains(t, out, tc.expectedOutput)
+		})
+	}
+
+	t.Run("should fail without a command", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(bytes.NewBufferString(""))
+		cmd.SetArgs([]string{"bisect", "run", head, good})
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "usage: bisect run")
+	})
+}
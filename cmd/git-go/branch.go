@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+type branchParams struct {
+	remotes bool
+	all     bool
+	verbose int
+}
+
+func newBranchCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "branch [-r | -a] [-v [-v]]",
+		Short: "List branches",
+		Args:  cobra.NoArgs,
+	}
+
+	p := branchParams{}
+	cmd.Flags().BoolVarP(&p.remotes, "remotes", "r", false, "List the remote-tracking branches.")
+	cmd.Flags().BoolVarP(&p.all, "all", "a", false, "List both remote-tracking branches and local branches.")
+	cmd.Flags().CountVarP(&p.verbose, "verbose", "v", "Show sha1 and commit subject line for each head, along with relationship to upstream branch (if any). If given twice, print the name of the upstream branch, as well.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return branchCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+// branchCmd lists the branches the same way git branch does.
+// The HEAD of the remotes are listed alongside the remote branches,
+// using the "origin/HEAD -> origin/main" format
+func branchCmd(out io.Writer, cfg *globalFlags, p branchParams) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("could not get HEAD: %w", err)
+	}
+
+	var branches []*git.Branch
+	if !p.remotes || p.all {
+		local, err := r.Branches()
+		if err != nil {
+			return fmt.Errorf("could not list the branches: %w", err)
+		}
+		branches = append(branches, local...)
+	}
+	if p.remotes || p.all {
+		remote, err := r.RemoteBranches("")
+		if err != nil {
+			return fmt.Errorf("could not list the remote branches: %w", err)
+		}
+		heads, err := r.RemoteHeads("")
+		if err != nil {
+			return fmt.Errorf("could not list the HEAD of the remotes: %w", err)
+		}
+		remote = append(remote, heads...)
+		sort.Slice(remote, func(i, j int) bool {
+			return remote[i].Name() < remote[j].Name()
+		})
+		branches = append(branches, remote...)
+	}
+
+	// git prefixes the remote branches when they are listed alongside
+	// the local ones
+	names := make([]string, len(branches))
+	width := 0
+	for i, b := range branches {
+		names[i] = b.ShortName
+		if p.all && b.IsRemote() {
+			names[i] = "remotes/" + b.ShortName
+		}
+		if len(names[i]) > width {
+			width = len(names[i])
+		}
+	}
+
+	for i, b := range branches {
+		prefix := "  "
+		if b.Name() == head.Branch {
+			prefix = "* "
+		}
+		// Symbolic refs are printed along with the branch they target
+		if b.Reference.Type() == ginternals.SymbolicReference {
+			target, err := r.ShortenRefName(b.Reference.SymbolicTarget())
+			if err != nil {
+				return fmt.Errorf("could not shorten %s: %w", b.Reference.SymbolicTarget(), err)
+			}
+			if p.verbose == 0 {
+				fmt.Fprintf(out, "%s%s -> %s\n", prefix, names[i], target)
+				continue
+			}
+			fmt.Fprintf(out, "%s%-*s -> %s\n", prefix, width, names[i], target)
+			continue
+		}
+		if p.verbose == 0 {
+			fmt.Fprintf(out, "%s%s\n", prefix, names[i])
+			continue
+		}
+
+		c, err := b.Commit()
+		if err != nil {
+			return fmt.Errorf("could not get the commit of %s: %w", b.ShortName, err)
+		}
+		tracking, err := branchTracking(r, b, p.verbose > 1)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s%-*s %s %s%s\n", prefix, width, names[i], c.ID().String()[:7], tracking, c.Subject())
+	}
+	return nil
+}
+
+// branchTracking returns the relationship between the given branch
+// and its upstream, like "[ahead 1, behind 2] ". The name of the
+// upstream is included if withName is true.
+// An empty string is returned if the branch has no upstream
+func branchTracking(r *git.Repository, b *git.Branch, withName bool) (string, error) {
+	upstream, err := b.Upstream()
+	switch {
+	case errors.Is(err, git.ErrNoUpstream):
+		return "", nil
+	case errors.Is(err, ginternals.ErrRefNotFound):
+		if !withName {
+			return "[gone] ", nil
+		}
+		name, err := b.UpstreamName()
+		if err != nil {
+			return "", fmt.Errorf("could not get the upstream of %s: %w", b.ShortName, err)
+		}
+		return fmt.Sprintf("[%s: gone] ", ginternals.ShortenRefName(name)), nil
+	case err != nil:
+		return "", fmt.Errorf("could not get the upstream of %s: %w", b.ShortName, err)
+	}
+
+	local, err := b.Peel()
+	if err != nil {
+		return "", fmt.Errorf("could not peel %s: %w", b.ShortName, err)
+	}
+	remote, err := upstream.Peel()
+	if err != nil {
+		return "", fmt.Errorf("could not peel %s: %w", upstream.ShortName, err)
+	}
+	ahead, behind, err := r.AheadBehind(local, remote)
+	if err != nil {
+		return "", fmt.Errorf("could not compare %s and %s: %w", b.ShortName, upstream.ShortName, err)
+	}
+
+	var diff []string
+	if ahead > 0 {
+		diff = append(diff, fmt.Sprintf("ahead %d", ahead))
+	}
+	if behind > 0 {
+		diff = append(diff, fmt.Sprintf("behind %d", behind))
+	}
+	switch {
+	case withName && len(diff) > 0:
+		return fmt.Sprintf("[%s: %s] ", upstream.ShortName, strings.Join(diff, ", ")), nil
+	case withName:
+		return fmt.Sprintf("[%s] ", upstream.ShortName), nil
+	case len(diff) > 0:
+		return fmt.Sprintf("[%s] ", strings.Join(diff, ", ")), nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc: "should list the local branches",
+			args: []string{"branch"},
+			expected: "  master\n" +
+				"  ml/cleanup-062020\n" +
+				"* ml/packfile/tests\n" +
+				"  ml/tests\n",
+		},
+		{
+			desc: "-r should list the remote branches",
+			args: []string{"branch", "-r"},
+			expected: "  origin/HEAD -> origin/master\n" +
+				"  origin/master\n" +
+				"  origin/ml/cleanup-062020\n" +
+				"  origin/ml/feat/clone\n",
+		},
+		{
+			// generated using git branch -v
+			desc: "-v should print the commits",
+			args: []string{"branch", "-v"},
+			expected: "  master            bbb720a doc: Update TODOs in readme\n" +
+				"  ml/cleanup-062020 b328320 doc: Update TODOs in readme\n" +
+				"* ml/packfile/tests bbb720a doc: Update TODOs in readme\n" +
+				"  ml/tests          f0f7014 refactor: Update codebase to go 1.13\n",
+		},
+		{
+			// generated using git branch -a -vv
+			desc: "-a -vv should print the upstreams of all the branches",
+			args: []string{"branch", "-a", "-vv"},
+			expected: "  master                           bbb720a [origin/master] doc: Update TODOs in readme\n" +
+				"  ml/cleanup-062020                b328320 [origin/ml/cleanup-062020] doc: Update TODOs in readme\n" +
+				"* ml/packfile/tests                bbb720a doc: Update TODOs in readme\n" +
+				"  ml/tests                         f0f7014 refactor: Update codebase to go 1.13\n" +
+				"  remotes/origin/HEAD              -> origin/master\n" +
+				"  remotes/origin/master            bbb720a doc: Update TODOs in readme\n" +
+				"  remotes/origin/ml/cleanup-062020 b328320 doc: Update TODOs in readme\n" +
+				"  remotes/origin/ml/feat/clone     5f35f2d stash\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			outBuf := bytes.NewBufferString("")
+			cmd := newRootCmd(repoPath, env.NewFromOs())
+			cmd.SetOut(outBuf)
+			cmd.SetArgs(tc.args)
+			require.NoError(t, cmd.Execute(), "test %d", i)
+			assert.Equal(t, tc.expected, outBuf.String(), "test %d", i)
+		})
+	}
+}
+
+// newClone creates a repository with one commit on master, clones it
+// using git, and returns the path of the clone.
+// The test is skipped if git is not installed
+func newClone(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is needed to run this test")
+	}
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	src := filepath.Join(dir, "src")
+	clone := filepath.Join(dir, "clone")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "master", src},
+		{"-C", src, "-c", "user.name=author", "-c", "user.email=author@domain.tld", "commit", "-q", "--allow-empty", "-m", "initial commit"},
+		{"clone", "-q", src, clone},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	return clone
+}
+
+func TestBranchClone(t *testing.T) {
+	t.Parallel()
+
+	clone := newClone(t)
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(clone, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"branch", "-a"})
+	require.NoError(t, cmd.Execute())
+
+	// generated using git branch -a
+	assert.Equal(t, "* master\n"+
+		"  remotes/origin/HEAD -> origin/master\n"+
+		"  remotes/origin/master\n", outBuf.String())
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
-	"github.com/Nivl/git-go/internal/errutil"
-
+	git "github.com/Nivl/git-go"
 	"github.com/Nivl/git-go/ginternals"
 	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
 	"github.com/spf13/cobra"
 )
 
@@ -19,14 +22,32 @@ func newCatFileCmd(cfg *globalFlags) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cat-file [TYPE] OBJECT",
 		Short: "Provide content or type and size information for repository objects",
-		Args:  cobra.RangeArgs(1, 2),
+		Args:  cobra.RangeArgs(0, 2),
 	}
 
 	typeOnly := cmd.Flags().BoolS("type", "t", false, "Instead of the content, show the object type identified by <object>.")
 	sizeOnly := cmd.Flags().BoolS("size", "s", false, "Instead of the content, show the object size identified by <object>.")
 	prettyPrint := cmd.Flags().BoolS("pretty-print", "p", false, "Pretty-print the contents of <object> based on its type.")
+	batchCheck := cmd.Flags().Bool("batch-check", false, "Print the type and size of each object provided on stdin.")
+	textconv := cmd.Flags().Bool("textconv", false, "Show the content of <rev>:<path> as transformed by the textconv filter of its diff driver.")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *batchCheck {
+			if len(args) > 0 || *typeOnly || *sizeOnly || *prettyPrint {
+				return errors.New("option --batch-check doesn't accept arguments or other options")
+			}
+			return catFileBatchCheckCmd(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout(), cfg)
+		}
+		if *textconv {
+			if len(args) != 1 || *typeOnly || *sizeOnly || *prettyPrint {
+				return errors.New("option --textconv requires a single <rev>:<path> and no other options")
+			}
+			return catFileTextconvCmd(cmd.OutOrStdout(), cfg, args[0])
+		}
+		if len(args) == 0 {
+			return errors.New("type and object required")
+		}
+
 		p := catFileParams{
 			typeOnly:    *typeOnly,
 			sizeOnly:    *sizeOnly,
@@ -77,37 +98,26 @@ func catFileCmd(out io.Writer, cfg *globalFlags, p catFileParams) (err error) {
 	}
 	defer errutil.Close(r, &err)
 
-	oid, err := ginternals.NewOidFromStr(p.objectName)
+	oid, err := r.RevParse(p.objectName)
 	if err != nil {
-		// If that failed it means we might have provided different name,
-		// like a reference
-		toTry := []string{
-			// catches stuff like HEADS or refs/heads/master
-			p.objectName,
-			// catches heads/master
-			ginternals.RefFullName(p.objectName),
-			// catches local branch names
-			ginternals.LocalBranchFullName(p.objectName),
-			// catches local tag names
-			ginternals.LocalTagFullName(p.objectName),
-		}
-
-		for _, refName := range toTry {
-			ref, err := r.Reference(refName)
-			if err == nil {
-				oid = ref.Target()
-				break
-			}
-
-			// if the ref doesn't exist we test the the next one
-			if !errors.Is(err, ginternals.ErrRefNotFound) {
-				return fmt.Errorf("could not check if ref %s exists: %w", refName, err)
-			}
+		if errors.Is(err, git.ErrUnknownRevision) {
+			return fmt.Errorf("not a valid object name %s", p.objectName)
 		}
+		return fmt.Errorf("could not resolve %s: %w", p.objectName, err)
+	}
 
-		if oid.IsZero() {
-			return fmt.Errorf("not a valid object name %s", p.objectName)
+	// The type and the size don't require to load the object
+	if p.typeOnly || p.sizeOnly {
+		typ, size, err := r.ObjectInfo(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		if p.sizeOnly {
+			fmt.Fprintln(out, strconv.Itoa(size))
+			return nil
 		}
+		fmt.Fprintln(out, typ.String())
+		return nil
 	}
 
 	o, err := r.Object(oid)
@@ -127,42 +137,22 @@ func catFileCmd(out io.Writer, cfg *globalFlags, p catFileParams) (err error) {
 	}
 
 	switch {
-	case p.sizeOnly:
-		fmt.Fprintln(out, strconv.Itoa(o.Size()))
-	case p.typeOnly:
-		fmt.Fprintln(out, o.Type().String())
 	case p.prettyPrint:
 		switch o.Type() {
+		// Like git, the commits and tags are printed as they are
+		// stored, so the headers keep their order and the signatures
+		// are not altered. We still parse them to make sure they are
+		// valid
 		case object.TypeCommit:
-			c, err := o.AsCommit()
-			if err != nil {
+			if _, err = o.AsCommit(); err != nil {
 				return fmt.Errorf("could not get commit %w", err)
 			}
-			fmt.Fprintf(out, "tree %s\n", c.TreeID().String())
-			for _, id := range c.ParentIDs() {
-				fmt.Fprintf(out, "parent %s\n", id.String())
-			}
-			fmt.Fprintf(out, "author %s\n", c.Author().String())
-			fmt.Fprintf(out, "committer %s\n", c.Committer().String())
-			if c.GPGSig() != "" {
-				fmt.Fprintf(out, "gpgsig %s \n", c.GPGSig())
-			}
-			fmt.Fprintln(out, "")
-			fmt.Fprint(out, c.Message())
+			fmt.Fprint(out, string(o.Bytes()))
 		case object.TypeTag:
-			tag, err := o.AsTag()
-			if err != nil {
+			if _, err = o.AsTag(); err != nil {
 				return fmt.Errorf("could not get tag %w", err)
 			}
-			fmt.Fprintf(out, "object %s\n", tag.Target().String())
-			fmt.Fprintf(out, "type %s\n", tag.Type().String())
-			fmt.Fprintf(out, "tag %s\n", tag.Name())
-			fmt.Fprintf(out, "tagger %s\n", tag.Tagger().String())
-			if tag.GPGSig() != "" {
-				fmt.Fprintf(out, "gpgsig %s \n", tag.GPGSig())
-			}
-			fmt.Fprintln(out, "")
-			fmt.Fprint(out, tag.Message())
+			fmt.Fprint(out, string(o.Bytes()))
 		case object.TypeTree:
 			tree, err := o.AsTree()
 			if err != nil {
@@ -183,3 +173,94 @@ func catFileCmd(out io.Writer, cfg *globalFlags, p catFileParams) (err error) {
 	}
 	return nil
 }
+
+// catFileTextconvCmd prints the content of the file targeted by the
+// given <rev>:<path>, converted by its textconv filter
+func catFileTextconvCmd(out io.Writer, cfg *globalFlags, objectName string) (err error) {
+	i := strings.IndexByte(objectName, ':')
+	if i <= 0 || i == len(objectName)-1 {
+		return fmt.Errorf("<rev>:<path> required with --textconv, got %s", objectName)
+	}
+	rev, path := objectName[:i], objectName[i+1:]
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	treeID, err := r.RevParse(rev + "^{tree}")
+	if err != nil {
+		if errors.Is(err, git.ErrUnknownRevision) {
+			return fmt.Errorf("not a valid object name %s", objectName)
+		}
+		return fmt.Errorf("could not resolve %s: %w", rev, err)
+	}
+	content, err := r.Textconv(treeID, path)
+	if err != nil {
+		if errors.Is(err, git.ErrPathNotFound) {
+			return fmt.Errorf("not a valid object name %s", objectName)
+		}
+		return err
+	}
+	if _, err = out.Write(content); err != nil {
+		return fmt.Errorf("could not write the output: %w", err)
+	}
+	return nil
+}
+
+// catFileBatchCheckCmd prints "<oid> <type> <size>" for each object
+// name read from in, or "<name> missing" if the object doesn't exist.
+// The output is flushed after each object, so it can be used
+// interactively
+func catFileBatchCheckCmd(ctx context.Context, in io.Reader, out io.Writer, cfg *globalFlags) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = catFileBatchCheckObject(out, r, scanner.Text()); err != nil {
+			return err
+		}
+		if err = flushOutput(out); err != nil {
+			return fmt.Errorf("could not write the output: %w", err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("could not read stdin: %w", err)
+	}
+	return nil
+}
+
+// catFileBatchCheckObject prints the type and size of the object
+// matching the given name
+func catFileBatchCheckObject(out io.Writer, r *git.Repository, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	oid, err := r.RevParse(name)
+	if err != nil {
+		if !errors.Is(err, git.ErrUnknownRevision) {
+			return fmt.Errorf("could not resolve %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "%s missing\n", name)
+		return nil
+	}
+	typ, size, err := r.ObjectInfo(oid)
+	if err != nil {
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		fmt.Fprintf(out, "%s missing\n", name)
+		return nil
+	}
+	fmt.Fprintf(out, "%s %s %d\n", oid.String(), typ.String(), size)
+	return nil
+}
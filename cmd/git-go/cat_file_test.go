@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -54,6 +55,22 @@ func TestCatFileParams(t *testing.T) {
 			desc: "sha required when no -p -s -t",
 			args: []string{"cat-file", "blob"},
 		},
+		{
+			desc: "no object allowed with --batch-check",
+			args: []string{"cat-file", "--batch-check", "642480605b8b0fd464ab5762e044269cf29a60a3"},
+		},
+		{
+			desc: "-t cannot be used with --batch-check",
+			args: []string{"cat-file", "--batch-check", "-t"},
+		},
+		{
+			desc: "--textconv requires a path",
+			args: []string{"cat-file", "--textconv", "HEAD"},
+		},
+		{
+			desc: "-p cannot be used with --textconv",
+			args: []string{"cat-file", "--textconv", "-p", "HEAD:README.md"},
+		},
 	}
 	for i, tc := range testCases {
 		tc := tc
@@ -204,3 +221,104 @@ func TestCatFile(t *testing.T) {
 		})
 	}
 }
+
+func TestCatFileBatchCheck(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(cwd, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetIn(strings.NewReader("642480605b8b0fd464ab5762e044269cf29a60a3\nHEAD\n\nnope\n2dcdadc2a420225783794fbffd51e2e137a69646\n"))
+	cmd.SetArgs([]string{"-C", repoPath, "cat-file", "--batch-check"})
+
+	require.NotPanics(t, func() {
+		err = cmd.Execute()
+	})
+	require.NoError(t, err)
+
+	expected := "642480605b8b0fd464ab5762e044269cf29a60a3 blob 453\n" +
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089 commit 260\n" +
+		"nope missing\n" +
+		"2dcdadc2a420225783794fbffd51e2e137a69646 missing\n"
+	assert.Equal(t, expected, outBuf.String())
+}
+
+func TestCatFileTextconv(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	dotGit := filepath.Join(repoPath, ".git")
+	require.NoError(t, os.MkdirAll(filepath.Join(dotGit, "info"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dotGit, "info", "attributes"), []byte("*.md diff=upper\n"), 0o644))
+	f, err := os.OpenFile(filepath.Join(dotGit, "config"), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("[diff \"upper\"]\n\ttextconv = tr a-z A-Z <\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	raw := bytes.NewBufferString("")
+	cmd := newRootCmd(repoPath, env.NewFromOs())
+	cmd.SetOut(raw)
+	cmd.SetArgs([]string{"cat-file", "blob", "642480605b8b0fd464ab5762e044269cf29a60a3"})
+	require.NoError(t, cmd.Execute())
+
+	out := bytes.NewBufferString("")
+	cmd = newRootCmd(repoPath, env.NewFromOs())
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"cat-file", "--textconv", "HEAD:README.md"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, strings.ToUpper(raw.String()), out.String())
+
+	cmd = newRootCmd(repoPath, env.NewFromOs())
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"cat-file", "--textconv", "HEAD:nope.md"})
+	require.Error(t, cmd.Execute())
+}
+
+func TestCatFilePrettyPrintSigned(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	out, err := exec.Command("git", "init", "-q", dir).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// The signature contains an empty line, which is stored as a
+	// single space, and the signature is followed by another header
+	signature := "-----BEGIN PGP SIGNATURE-----\n" +
+		" \n" +
+		" iQEzBAABCAAdFiEEfake0signature0data0AAAAAAAAAAAAAAAAAAAAAAAA\n" +
+		" =AbCd\n" +
+		" -----END PGP SIGNATURE-----"
+	commit := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author author <author@domain.tld> 1600000000 +0200\n" +
+		"committer committer <committer@domain.tld> 1600000000 +0200\n" +
+		"gpgsig " + signature + "\n" +
+		"x-custom-header value\n" +
+		"\n" +
+		"signed commit\n"
+	hashCmd := exec.Command("git", "-C", dir, "hash-object", "-t", "commit", "-w", "--stdin")
+	hashCmd.Stdin = strings.NewReader(commit)
+	out, err = hashCmd.Output()
+	require.NoError(t, err)
+	sha := strings.TrimSpace(string(out))
+
+	expected, err := exec.Command("git", "-C", dir, "cat-file", "-p", sha).Output()
+	require.NoError(t, err)
+	require.Equal(t, commit, string(expected), "git doesn't print the commit as expected")
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(dir, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"cat-file", "-p", sha})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, string(expected), outBuf.String())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+type countObjectsParams struct {
+	verbose  bool
+	extended bool
+}
+
+func newCountObjectsCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "count-objects [-v] [--extended]",
+		Short: "Count unpacked number of objects and their disk consumption",
+		Args:  cobra.NoArgs,
+	}
+
+	p := countObjectsParams{}
+	cmd.Flags().BoolVarP(&p.verbose, "verbose", "v", false, "Report the packfiles and the garbage files in addition to the loose objects.")
+	cmd.Flags().BoolVar(&p.extended, "extended", false, "Also report the references, the delta chains, and the largest objects. This is not supported by git.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return countObjectsCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+func countObjectsCmd(out io.Writer, cfg *globalFlags, p countObjectsParams) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	stats, err := r.Stats()
+	if err != nil {
+		return fmt.Errorf("could not get the stats of the repository: %w", err)
+	}
+
+	// The sizes are reported in KiB, like git
+	if p.verbose {
+		fmt.Fprintf(out, "count: %d\n", stats.LooseObjects)
+		fmt.Fprintf(out, "size: %d\n", stats.LooseSize>>10)
+		fmt.Fprintf(out, "in-pack: %d\n", stats.PackedObjects)
+		fmt.Fprintf(out, "packs: %d\n", stats.Packs)
+		fmt.Fprintf(out, "size-pack: %d\n", stats.PackSize>>10)
+		fmt.Fprintf(out, "prune-packable: %d\n", stats.PrunePackable)
+		fmt.Fprintf(out, "garbage: %d\n", stats.Garbage)
+		fmt.Fprintf(out, "size-garbage: %d\n", stats.GarbageSize>>10)
+	} else {
+		fmt.Fprintf(out, "%d objects, %d kilobytes\n", stats.LooseObjects, stats.LooseSize>>10)
+	}
+	if !p.extended {
+		return nil
+	}
+
+	// Everything below is not reported by git
+	fmt.Fprintf(out, "refs: %d\n", stats.References)
+	fmt.Fprintf(out, "branches: %d\n", stats.Branches)
+	fmt.Fprintf(out, "tags: %d\n", stats.Tags)
+	fmt.Fprintf(out, "remote-branches: %d\n", stats.RemoteBranches)
+	depths := make([]int, 0, len(stats.DeltaChains))
+	for depth := range stats.DeltaChains {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for _, depth := range depths {
+		fmt.Fprintf(out, "chain-length-%d: %d\n", depth, stats.DeltaChains[depth])
+	}
+	for _, o := range stats.LargestObjects {
+		where := "loose"
+		if o.Packed {
+			where = "packed"
+		}
+		fmt.Fprintf(out, "largest: %s %d %s\n", o.ID.String(), o.Size, where)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountObjects(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return outBuf.String()
+	}
+
+	t.Run("should print the loose objects by default", func(t *testing.T) {
+		t.Parallel()
+
+		out := run(t, "count-objects")
+		assert.Regexp(t, `^2 objects, \d+ kilobytes\n$`, out)
+	})
+
+	t.Run("-v should print the packfiles", func(t *testing.T) {
+		t.Parallel()
+
+		out := run(t, "count-objects", "-v")
+		lines := strings.Split(out, "\n")
+		// generated using git count-objects -v
+		assert.Contains(t, lines, "count: 2")
+		assert.Contains(t, lines, "in-pack: 364")
+		assert.Contains(t, lines, "packs: 1")
+		assert.Contains(t, lines, "size-pack: 303")
+		assert.Contains(t, lines, "prune-packable: 0")
+		assert.Contains(t, lines, "garbage: 0")
+		assert.Contains(t, lines, "size-garbage: 0")
+		// The output should match git's
+		assert.NotContains(t, out, "chain-length-")
+		assert.NotContains(t, out, "largest:")
+		assert.NotContains(t, out, "refs:")
+	})
+
+	t.Run("--extended should print the extra stats", func(t *testing.T) {
+		t.Parallel()
+
+		out := run(t, "count-objects", "-v", "--extended")
+		lines := strings.Split(out, "\n")
+		assert.Contains(t, lines, "count: 2")
+		assert.Contains(t, lines, "branches: 4")
+		assert.Contains(t, lines, "chain-length-1: 70")
+		assert.Contains(t, lines, "largest: c7e8983034329ff4bf8e208dc7829a5d366a2f5f 11319 packed")
+	})
+
+	t.Run("should work on an empty repository", func(t *testing.T) {
+		t.Parallel()
+
+		dirPath, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		cmd := newRootCmd(dirPath, env.NewFromOs())
+		cmd.SetOut(bytes.NewBufferString(""))
+		cmd.SetArgs([]string{"init"})
+		require.NoError(t, cmd.Execute())
+
+		outBuf := bytes.NewBufferString("")
+		cmd = newRootCmd(dirPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs([]string{"count-objects", "-v"})
+		require.NoError(t, cmd.Execute())
+		// generated using git count-objects -v
+		expected := "count: 0\nsize: 0\nin-pack: 0\npacks: 0\nsize-pack: 0\nprune-packable: 0\ngarbage: 0\nsize-garbage: 0\n"
+		assert.Equal(t, expected, outBuf.String())
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes used by git
+const (
+	// exitCodeError is the exit code used when a command fails
+	exitCodeError = 1
+	// exitCodeSignal is the exit code used when the process is killed
+	// by a signal, to which the number of the signal is added
+	exitCodeSignal = 128
+	// exitCodeUsage is the exit code used when a command is not used
+	// correctly (unknown flag, wrong number of arguments, etc.)
+	exitCodeUsage = 129
+)
+
+// usageError is an error returned when a command is not used
+// correctly
+type usageError struct {
+	cmd *cobra.Command
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// markUsageErrors makes the flag and argument errors of the given
+// command and its sub-commands return a usageError
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &usageError{cmd: c, err: err}
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validate(c, args); err != nil {
+				return &usageError{cmd: c, err: err}
+			}
+			return nil
+		}
+	}
+	for _, c := range cmd.Commands() {
+		markUsageErrors(c)
+	}
+}
+
+// exitCode returns the exit code to use for the given error, sig being
+// the signal that interrupted the command, if any
+func exitCode(err error, sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return exitCodeSignal + int(s)
+	}
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		return exitCodeUsage
+	default:
+		return exitCodeError
+	}
+}
+
+// interruptHandler cancels a context when the process receives a
+// signal
+type interruptHandler struct {
+	mu  sync.Mutex
+	sig os.Signal
+}
+
+// handleInterrupts returns a context that is cancelled once a signal
+// is received on sigs. force is called if the command is still running
+// after the grace period, or if another signal is received.
+// The returned handler can be used to know which signal was received
+func handleInterrupts(parent context.Context, sigs <-chan os.Signal, grace time.Duration, force func(os.Signal)) (context.Context, *interruptHandler) {
+	ctx, cancel := context.WithCancel(parent)
+	h := &interruptHandler{}
+	go func() {
+		select {
+		case <-parent.Done():
+			return
+		case sig := <-sigs:
+			h.mu.Lock()
+			h.sig = sig
+			h.mu.Unlock()
+			cancel()
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-parent.Done():
+		case <-timer.C:
+			force(h.Signal())
+		case sig := <-sigs:
+			force(sig)
+		}
+	}()
+	return ctx, h
+}
+
+// Signal returns the signal that was received, if any
+func (h *interruptHandler) Signal() os.Signal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sig
+}
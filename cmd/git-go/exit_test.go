@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		err      error
+		sig      os.Signal
+		expected int
+	}{
+		{
+			desc:     "success",
+			expected: 0,
+		},
+		{
+			desc:     "generic error",
+			err:      errors.New("nope"),
+			expected: 1,
+		},
+		{
+			desc:     "usage error",
+			err:      &usageError{err: errors.New("nope")},
+			expected: 129,
+		},
+		{
+			desc:     "SIGINT",
+			err:      context.Canceled,
+			sig:      syscall.SIGINT,
+			expected: 130,
+		},
+		{
+			desc:     "SIGTERM without error",
+			sig:      syscall.SIGTERM,
+			expected: 143,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, exitCode(tc.err, tc.sig), "test %d", i)
+		})
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc    string
+		args    []string
+		isUsage bool
+	}{
+		{
+			desc:    "unknown flag",
+			args:    []string{"tag", "--nope"},
+			isUsage: true,
+		},
+		{
+			desc:    "too many arguments",
+			args:    []string{"branch", "nope"},
+			isUsage: true,
+		},
+		{
+			desc:    "custom validation",
+			args:    []string{"bisect", "run", "HEAD"},
+			isUsage: true,
+		},
+		{
+			desc:    "runtime error",
+			args:    []string{"rev-list", "nope"},
+			isUsage: false,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			cmd := newRootCmd(repoPath, env.NewFromOs())
+			cmd.SetOut(io.Discard)
+			cmd.SetArgs(tc.args)
+			err := cmd.Execute()
+			require.Error(t, err, "test %d", i)
+			var usageErr *usageError
+			assert.Equal(t, tc.isUsage, errors.As(err, &usageErr), "test %d: unexpected error %v", i, err)
+		})
+	}
+}
+
+func TestHandleInterrupts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should cancel the context", func(t *testing.T) {
+		t.Parallel()
+
+		parent, stop := context.WithCancel(context.Background())
+		t.Cleanup(stop)
+		sigs := make(chan os.Signal, 1)
+		forced := make(chan os.Signal, 1)
+		ctx, h := handleInterrupts(parent, sigs, time.Hour, func(sig os.Signal) {
+			forced <- sig
+		})
+		assert.NoError(t, ctx.Err())
+		assert.Nil(t, h.Signal())
+
+		sigs <- syscall.SIGINT
+		<-ctx.Done()
+		assert.Equal(t, syscall.SIGINT, h.Signal())
+
+		// A second signal should force the exit
+		sigs <- syscall.SIGTERM
+		assert.Equal(t, syscall.SIGTERM, <-forced)
+	})
+
+	t.Run("should force the exit after the grace period", func(t *testing.T) {
+		t.Parallel()
+
+		parent, stop := context.WithCancel(context.Background())
+		t.Cleanup(stop)
+		sigs := make(chan os.Signal, 1)
+		forced := make(chan os.Signal, 1)
+		_, _ = handleInterrupts(parent, sigs, time.Millisecond, func(sig os.Signal) {
+			forced <- sig
+		})
+		sigs <- syscall.SIGTERM
+		assert.Equal(t, syscall.SIGTERM, <-forced)
+	})
+
+	t.Run("a cancelled command should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{"rev-list", "HEAD"})
+		require.ErrorIs(t, cmd.ExecuteContext(ctx), context.Canceled)
+	})
+}
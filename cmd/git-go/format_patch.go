@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+func newFormatPatchCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "format-patch [OPTIONS] REVISION_RANGE",
+		Short: "Prepare patches for e-mail submission",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	stdout := cmd.Flags().Bool("stdout", false, "Print all commits to the standard output in mbox format, instead of creating a file for each one.")
+	outputDir := cmd.Flags().StringP("output-directory", "o", "", "Use <dir> to store the resulting files, instead of the current working directory.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return formatPatchCmd(cmd.Context(), cmd.OutOrStdout(), cfg, formatPatchParams{
+			rangeSpec: args[0],
+			stdout:    *stdout,
+			outputDir: *outputDir,
+		})
+	}
+	return cmd
+}
+
+type formatPatchParams struct {
+	rangeSpec string
+	outputDir string
+	stdout    bool
+}
+
+func formatPatchCmd(ctx context.Context, out io.Writer, cfg *globalFlags, p formatPatchParams) (err error) {
+	if p.stdout && p.outputDir != "" {
+		return errors.New("--stdout and --output-directory are mutually exclusive")
+	}
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	if p.stdout {
+		if err = r.FormatPatch(p.rangeSpec, out); err != nil {
+			return wrapRevisionError(err)
+		}
+		return nil
+	}
+
+	commits, err := r.PatchCommits(p.rangeSpec)
+	if err != nil {
+		return wrapRevisionError(err)
+	}
+
+	dir := p.outputDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cfg.C.String(), dir)
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %w", dir, err)
+	}
+
+	for i, c := range commits {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, git.PatchFileName(c, i+1))
+		if err = writePatchFile(r, path, i+1, len(commits), c); err != nil {
+			return err
+		}
+		// Like git we print the path relative to the output directory
+		// the user provided
+		fmt.Fprintln(out, filepath.Join(p.outputDir, filepath.Base(path)))
+	}
+	return nil
+}
+
+// writePatchFile writes the patch of the given commit in a new file
+func writePatchFile(r *git.Repository, path string, number, total int, c *object.Commit) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer errutil.Close(f, &err)
+
+	return r.WritePatch(f, c, number, total)
+}
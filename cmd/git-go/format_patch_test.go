@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPatch(t *testing.T) {
+	t.Parallel()
+
+	const rangeSpec = "3a78491a3bfb77d1d3b1bb3c5e808c3bba1e7da6..2f2e900b4e87ab0d51809642eaf0c5a12a97d927"
+
+	t.Run("--stdout should print the patches", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		expected, err := os.ReadFile(filepath.Join(testutil.TestdataPath(t), "patch_2f2e900b4e87ab0d51809642eaf0c5a12a97d927"))
+		require.NoError(t, err)
+
+		cwd, err := os.Getwd()
+		require.NoError(t, err)
+
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(cwd, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs([]string{"-C", repoPath, "format-patch", "--stdout", rangeSpec})
+
+		require.NotPanics(t, func() {
+			err = cmd.Execute()
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), outBuf.String())
+	})
+
+	t.Run("-o should write the patches in the directory", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		expected, err := os.ReadFile(filepath.Join(testutil.TestdataPath(t), "patch_2f2e900b4e87ab0d51809642eaf0c5a12a97d927"))
+		require.NoError(t, err)
+
+		cwd, err := os.Getwd()
+		require.NoError(t, err)
+
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(cwd, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs([]string{"-C", repoPath, "format-patch", "-o", "patches", rangeSpec})
+
+		require.NotPanics(t, func() {
+			err = cmd.Execute()
+		})
+		require.NoError(t, err)
+
+		fileName := filepath.Join("patches", "0001-refactor-switch-to-go-1.14-and-golangci-action.patch")
+		assert.Equal(t, fileName+"\n", outBuf.String())
+
+		content, err := os.ReadFile(filepath.Join(repoPath, fileName))
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(content))
+	})
+
+	t.Run("--stdout and -o should be mutually exclusive", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		cwd, err := os.Getwd()
+		require.NoError(t, err)
+
+		cmd := newRootCmd(cwd, env.NewFromOs())
+		cmd.SetOut(bytes.NewBufferString(""))
+		cmd.SetArgs([]string{"-C", repoPath, "format-patch", "--stdout", "-o", "patches", rangeSpec})
+
+		require.NotPanics(t, func() {
+			err = cmd.Execute()
+		})
+		require.Error(t, err)
+	})
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"time"
+
 	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/trace"
 	"github.com/Nivl/git-go/internal/pathutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -35,10 +39,42 @@ func newRootCmd(cwd string, e *env.Env) *cobra.Command {
 
 	// porcelain
 	cmd.AddCommand(newInitCmd(cfg))
+	cmd.AddCommand(newBisectCmd(cfg))
+	cmd.AddCommand(newBranchCmd(cfg))
+	cmd.AddCommand(newFormatPatchCmd(cfg))
+	cmd.AddCommand(newRestoreCmd(cfg))
+	cmd.AddCommand(newShortlogCmd(cfg))
+	cmd.AddCommand(newStashCmd(cfg))
+	cmd.AddCommand(newTagCmd(cfg))
+	cmd.AddCommand(newWorktreeCmd(cfg))
 
 	// plumbing
 	cmd.AddCommand(newCatFileCmd(cfg))
+	cmd.AddCommand(newCountObjectsCmd(cfg))
 	cmd.AddCommand(newHashObjectCmd())
+	cmd.AddCommand(newLsRemoteCmd(cfg))
+	cmd.AddCommand(newRepackCmd(cfg))
+	cmd.AddCommand(newRevListCmd(cfg))
+	cmd.AddCommand(newVerifyPackCmd(cfg))
 
+	markUsageErrors(cmd)
 	return cmd
 }
+
+// execute runs the command matching the given arguments.
+// Like git, the command is traced if GIT_TRACE is set, and its
+// duration is traced if GIT_TRACE_PERFORMANCE is set.
+// Unlike git, the traced command contains the global options
+// (ex. -C).
+// ctx is used to stop the command early
+func execute(ctx context.Context, cmd *cobra.Command, e *env.Env, args []string) error {
+	trace.New(e, trace.KeyTrace).Command(args)
+	perf := trace.New(e, trace.KeyPerformance)
+	start := time.Now()
+	defer func() {
+		perf.Performance(args, time.Since(start))
+	}()
+
+	cmd.SetArgs(args)
+	return cmd.ExecuteContext(ctx)
+}
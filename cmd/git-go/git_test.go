@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should trace the command", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		tracePath := filepath.Join(dir, "trace")
+
+		e := env.NewFromKVList([]string{
+			"GIT_TRACE=" + tracePath,
+			"GIT_TRACE_PERFORMANCE=" + tracePath,
+		})
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, e)
+		cmd.SetOut(outBuf)
+		require.NoError(t, execute(context.Background(), cmd, e, []string{"tag"}))
+		assert.Equal(t, "annotated\nlightweight\n", outBuf.String())
+
+		traces, err := os.ReadFile(tracePath)
+		require.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^\S+ git\.go:\d+ +trace: built-in: git tag\n\S+ git\.go:\d+ +performance: \d+\.\d{9} s: git command: git tag\n$`), string(traces))
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 
 	git "github.com/Nivl/git-go"
 	"github.com/Nivl/git-go/ginternals/config"
@@ -23,3 +25,34 @@ func loadRepository(cfg *globalFlags) (*git.Repository, error) {
 		IsBare: cfg.Bare,
 	})
 }
+
+// wrapRevisionError returns a user friendly error when a revision
+// could not be resolved. Other errors are returned as-is
+func wrapRevisionError(err error) error {
+	if errors.Is(err, git.ErrUnknownRevision) {
+		return fmt.Errorf("bad revision: %w", err)
+	}
+	return err
+}
+
+// parseSubmoduleFormat returns the submodule format matching the
+// value of --submodule
+func parseSubmoduleFormat(format string) (git.SubmoduleFormat, error) {
+	switch format {
+	case "short":
+		return git.SubmoduleShort, nil
+	case "log":
+		return git.SubmoduleLog, nil
+	}
+	return 0, fmt.Errorf("failed to parse --submodule option parameter: '%s'", format)
+}
+
+// flushOutput writes the buffered output, if out is buffered.
+// This is needed when the output has to be seen right away, like
+// when reading commands from stdin
+func flushOutput(out io.Writer) error {
+	if f, ok := out.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -19,6 +19,8 @@ import (
 type initCmdFlags struct {
 	initialBranch  string
 	separateGitDir string
+	template       string
+	shared         string
 	quiet          bool
 }
 
@@ -34,6 +36,9 @@ func newInitCmd(cfg *globalFlags) *cobra.Command {
 	cmd.Flags().StringVarP(&flags.initialBranch, "initial-branch", "b", "", "Use the specified name for the initial branch in the newly created repository. If not specified, fall back to the default name (currently master, but this is subject to change in the future; the name can be customized via the init.defaultBranch configuration variable).")
 	cmd.Flags().BoolVarP(&flags.quiet, "quiet", "q", false, "Only print error and warning messages; all other output will be suppressed.")
 	cmd.Flags().StringVar(&flags.separateGitDir, "separate-git-dir", "", "Instead of initializing the repository as a directory to either $GIT_DIR or ./.git/, create a text file there containing the path to the actual repository. This file acts as filesystem-agnostic Git symbolic link to the repository.\n\nIf this is reinitialization, the repository will be moved to the specified path.")
+	cmd.Flags().StringVar(&flags.template, "template", "", "Specify the directory from which templates will be used.")
+	cmd.Flags().StringVar(&flags.shared, "shared", "", "Specify that the Git repository is to be shared amongst several users. This allows users belonging to the same group to push into that repository. The value can be one of false, true, umask, group, all, world, everybody, or an octal number (ex. 0660).")
+	cmd.Flags().Lookup("shared").NoOptDefVal = "group"
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		directory := ""
@@ -60,6 +65,15 @@ func initCmd(out io.Writer, cfg *globalFlags, flags initCmdFlags, optionalDirect
 		gitDir = flags.separateGitDir
 	}
 
+	shared := config.SharedRepository{}
+	if flags.shared != "" {
+		var err error
+		shared, err = config.ParseSharedRepository(flags.shared)
+		if err != nil {
+			return fmt.Errorf("invalid --shared value: %w", err)
+		}
+	}
+
 	workingDirectory := cfg.C.String()
 	if optionalDirectory != "" {
 		workingDirectory = optionalDirectory
@@ -88,6 +102,8 @@ func initCmd(out io.Writer, cfg *globalFlags, flags initCmdFlags, optionalDirect
 		IsBare:            cfg.Bare,
 		InitialBranchName: flags.initialBranch,
 		Symlink:           flags.separateGitDir != "",
+		TemplateDir:       flags.template,
+		Shared:            shared,
 	})
 	if err != nil {
 		return err
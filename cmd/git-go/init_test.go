@@ -27,6 +27,18 @@ func TestInitParams(t *testing.T) {
 			desc: "should work with no options",
 			args: []string{"init"},
 		},
+		{
+			desc: "should work with --shared",
+			args: []string{"init", "--shared"},
+		},
+		{
+			desc: "should work with --shared=<perm>",
+			args: []string{"init", "--shared=0640"},
+		},
+		{
+			desc: "should work with --template",
+			args: []string{"init", "--template", "does-not-exist"},
+		},
 	}
 	for i, tc := range testCases {
 		tc := tc
@@ -159,6 +171,46 @@ func TestInit(t *testing.T) {
 		assert.Empty(t, sdtout.String(), "no output was expected")
 	})
 
+	t.Run("--shared should be saved in the config", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		err := initCmd(io.Discard,
+			&globalFlags{
+				env: env.NewFromKVList([]string{}),
+				C:   &testutil.StringValue{Value: dir},
+			},
+			initCmdFlags{
+				shared: "all",
+			}, "")
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, config.DefaultDotGitDirName, "config"))
+		require.NoError(t, err)
+		assert.Regexp(t, `sharedrepository\s+= 2`, string(data))
+	})
+
+	t.Run("--shared should fail with an invalid value", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		err := initCmd(io.Discard,
+			&globalFlags{
+				env: env.NewFromKVList([]string{}),
+				C:   &testutil.StringValue{Value: dir},
+			},
+			initCmdFlags{
+				shared: "0111",
+			}, "")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, config.ErrInvalidSharedRepository)
+		assert.NoDirExists(t, filepath.Join(dir, config.DefaultDotGitDirName))
+	})
+
 	t.Run("--separate-git-dir", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/transport"
+	"github.com/spf13/cobra"
+)
+
+type lsRemoteParams struct {
+	url        string
+	heads      bool
+	tags       bool
+	symref     bool
+	uploadPack string
+}
+
+func newLsRemoteCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls-remote [--heads] [--tags] [--symref] [--upload-pack=EXEC] REPOSITORY",
+		Short: "List references in a remote repository",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	p := lsRemoteParams{}
+	cmd.Flags().BoolVar(&p.heads, "heads", false, "Limit to refs/heads")
+	cmd.Flags().BoolVar(&p.tags, "tags", false, "Limit to refs/tags")
+	cmd.Flags().BoolVar(&p.symref, "symref", false, "Show the reference targeted by the symbolic references")
+	cmd.Flags().StringVar(&p.uploadPack, "upload-pack", "", "Path to git-upload-pack, to list the references of a local repository")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		p.url = args[0]
+		// local repositories are relative to -C
+		if ep, err := transport.ParseEndpoint(p.url); err == nil && ep.Protocol == transport.ProtocolFile && !filepath.IsAbs(ep.Path) {
+			p.url = filepath.Join(cfg.C.String(), ep.Path)
+		}
+		return lsRemoteCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+func lsRemoteCmd(out io.Writer, cfg *globalFlags, p lsRemoteParams) error {
+	opts := transport.Options{
+		Env:        cfg.env,
+		UploadPack: p.uploadPack,
+	}
+	// Like git, the HTTP settings of the current repository are used
+	// when there's one
+	repoCfg, err := config.LoadConfig(cfg.env, config.LoadConfigOptions{
+		WorkingDirectory: cfg.C.String(),
+		GitDirPath:       cfg.GitDir,
+		WorkTreePath:     cfg.WorkTree,
+		IsBare:           cfg.Bare,
+	})
+	if err == nil {
+		opts.ApplyConfig(repoCfg.FromFile())
+	}
+
+	refs, err := git.LsRemote(p.url, git.LsRemoteOptions{
+		Heads:     p.heads,
+		Tags:      p.tags,
+		Transport: opts,
+	})
+	if err != nil {
+		return err //nolint:wrapcheck // the error already contains the URL
+	}
+	for _, ref := range refs {
+		if p.symref && ref.SymbolicTarget != "" {
+			fmt.Fprintf(out, "ref: %s\t%s\n", ref.SymbolicTarget, ref.Name)
+		}
+		fmt.Fprintf(out, "%s\t%s\n", ref.ID.String(), ref.Name)
+		if !ref.Peeled.IsZero() {
+			fmt.Fprintf(out, "%s\t%s^{}\n", ref.Peeled.String(), ref.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLsRemote(t *testing.T) {
+	t.Parallel()
+
+	// the server advertises a subset of the refs of the small repo
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		for _, line := range []string{
+			"# service=git-upload-pack\n",
+			"",
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD\x00ofs-delta symref=HEAD:refs/heads/ml/packfile/tests\n",
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/ml/packfile/tests\n",
+			"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated\n",
+			"6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refs/tags/annotated^{}\n",
+			"",
+		} {
+			if line == "" {
+				w.Write([]byte(pktline.Flush)) //nolint:errcheck // the test will fail anyway
+				continue
+			}
+			w.Write(pktline.Encode([]byte(line))) //nolint:errcheck // the test will fail anyway
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	// no repository is needed
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	run := func(args ...string) (string, error) {
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(dir, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return outBuf.String(), err
+	}
+
+	// generated using git ls-remote --symref
+	out, err := run("ls-remote", "--symref", srv.URL+"/repo.git")
+	require.NoError(t, err)
+	assert.Equal(t, "ref: refs/heads/ml/packfile/tests\tHEAD\n"+
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\tHEAD\n"+
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/heads/ml/packfile/tests\n"+
+		"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442\trefs/tags/annotated\n"+
+		"6097a04b7a327c4be68f222ca66e61b8e1abe5c1\trefs/tags/annotated^{}\n", out)
+
+	out, err = run("ls-remote", "--tags", srv.URL+"/repo.git")
+	require.NoError(t, err)
+	assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442\trefs/tags/annotated\n"+
+		"6097a04b7a327c4be68f222ca66e61b8e1abe5c1\trefs/tags/annotated^{}\n", out)
+
+	_, err = run("ls-remote")
+	require.Error(t, err)
+}
+
+func TestLsRemoteLocal(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(filepath.Dir(repoPath), env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"ls-remote", "--heads", filepath.Base(repoPath)})
+	require.NoError(t, cmd.Execute())
+
+	// generated using git ls-remote --heads
+	assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/heads/master\n"+
+		"b328320060eb503cf337c7cff281712ef236963a\trefs/heads/ml/cleanup-062020\n"+
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/heads/ml/packfile/tests\n"+
+		"f0f70144f38695250606b86a50cff2b440a417f3\trefs/heads/ml/tests\n", outBuf.String())
+}
+
+func TestLsRemoteLocalEmpty(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	cmd := newRootCmd(dir, env.NewFromOs())
+	cmd.SetOut(bytes.NewBufferString(""))
+	cmd.SetArgs([]string{"init"})
+	require.NoError(t, cmd.Execute())
+
+	outBuf := bytes.NewBufferString("")
+	cmd = newRootCmd(dir, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"ls-remote", "--symref", dir})
+	require.NoError(t, cmd.Execute())
+
+	// git ls-remote doesn't print anything on an empty repository
+	assert.Empty(t, outBuf.String())
+}
+
+func TestLsRemoteLocalClone(t *testing.T) {
+	t.Parallel()
+
+	clone := newClone(t)
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(clone, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"ls-remote", "--symref", "."})
+	require.NoError(t, cmd.Execute())
+
+	// generated using git ls-remote --symref
+	lines := strings.Split(outBuf.String(), "\n")
+	assert.Contains(t, lines, "ref: refs/heads/master\tHEAD")
+	assert.Contains(t, lines, "ref: refs/remotes/origin/master\trefs/remotes/origin/HEAD")
+}
@@ -1,25 +1,69 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/lockfile"
 )
 
-func exitError(err error) {
-	fmt.Fprintln(os.Stderr, err)
-	os.Exit(1)
-}
+// interruptGracePeriod is the time given to a command to stop once it
+// has been interrupted, before the process is killed
+const interruptGracePeriod = 2 * time.Second
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command matching the given arguments, and returns the
+// exit code of the process
+func run(args []string) int {
 	cwd, err := os.Getwd()
 	if err != nil {
-		exitError(err)
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeError
 	}
 
-	root := newRootCmd(cwd, env.NewFromOs())
-	if err = root.Execute(); err != nil {
-		exitError(err)
+	// The commands are given some time to stop gracefully when the
+	// process is interrupted, so they can release their locks and
+	// print what they have
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	parent, stop := context.WithCancel(context.Background())
+	defer stop()
+	ctx, interrupts := handleInterrupts(parent, sigs, interruptGracePeriod, func(sig os.Signal) {
+		lockfile.RollbackAll() //nolint:errcheck // we're exiting anyway
+		os.Exit(exitCode(nil, sig))
+	})
+
+	out := bufio.NewWriter(os.Stdout)
+	e := env.NewFromOs()
+	root := newRootCmd(cwd, e)
+	root.SetOut(out)
+	err = execute(ctx, root, e, args)
+	out.Flush() //nolint:errcheck // there's nothing we can do about it
+
+	sig := interrupts.Signal()
+	if sig != nil {
+		// Like git, nothing is printed when the process is
+		// interrupted
+		lockfile.RollbackAll() //nolint:errcheck // we're exiting anyway
+		return exitCode(err, sig)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprint(os.Stderr, usageErr.cmd.UsageString())
+		}
 	}
+	return exitCode(err, nil)
 }
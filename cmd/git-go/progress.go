@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// progressMeter prints a git-style progress line, like
+// "Updating files:  45% (9000/20000)".
+// The line is only printed again when the percentage changes
+type progressMeter struct {
+	out     io.Writer
+	title   string
+	percent int
+	started bool
+}
+
+// newProgressMeter returns a progressMeter writing in out
+func newProgressMeter(out io.Writer, title string) *progressMeter {
+	return &progressMeter{
+		out:     out,
+		title:   title,
+		percent: -1,
+	}
+}
+
+// update prints the progress, if it changed since the last update
+func (p *progressMeter) update(done, total int) {
+	percent := 100
+	if total > 0 {
+		percent = done * 100 / total
+	}
+	if percent == p.percent {
+		return
+	}
+	p.percent = percent
+	p.started = true
+	fmt.Fprintf(p.out, "%s: %3d%% (%d/%d)\r", p.title, percent, done, total)
+}
+
+// done ends the progress line, if anything has been printed
+func (p *progressMeter) done(done, total int) {
+	if !p.started {
+		return
+	}
+	fmt.Fprintf(p.out, "%s: %3d%% (%d/%d), done.\n", p.title, p.percent, done, total)
+}
+
+// isTerminal returns whether the given writer is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+type repackParams struct {
+	all          bool
+	removeRedund bool
+	noReuseDelta bool
+	quiet        bool
+	window       int
+	depth        int
+	geometric    int
+}
+
+func newRepackCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repack [-a] [-d] [-f] [-q] [--window=N] [--depth=N] [--geometric=FACTOR]",
+		Short: "Pack unpacked objects in a repository",
+		Args:  cobra.NoArgs,
+	}
+
+	p := repackParams{}
+	cmd.Flags().BoolVarP(&p.all, "all", "a", false, "Pack everything referenced into a single pack.")
+	cmd.Flags().BoolVarP(&p.removeRedund, "delete", "d", false, "After packing, remove the redundant packs and loose objects.")
+	cmd.Flags().BoolVarP(&p.noReuseDelta, "no-reuse-delta", "f", false, "Compute all the deltas again instead of reusing the existing ones.")
+	cmd.Flags().BoolVarP(&p.quiet, "quiet", "q", false, "Don't print the summary.")
+	cmd.Flags().IntVar(&p.window, "window", packfile.DefaultWindow, "Number of objects considered when looking for a delta base.")
+	cmd.Flags().IntVar(&p.depth, "depth", packfile.DefaultDepth, "Maximum length of the delta chains.")
+	cmd.Flags().IntVarP(&p.geometric, "geometric", "g", 0, "Only repack the smallest packfiles so that each packfile contains at least FACTOR times as many objects as the next smaller one.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return repackCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+func repackCmd(out io.Writer, cfg *globalFlags, p repackParams) (err error) {
+	if p.all && p.geometric != 0 {
+		return errors.New("-a and --geometric cannot be used together")
+	}
+	if p.window < 0 {
+		return errors.New("--window cannot be negative")
+	}
+	if p.depth < 0 {
+		return errors.New("--depth cannot be negative")
+	}
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	res, err := r.Repack(backend.RepackOptions{
+		Window:          p.window,
+		Depth:           p.depth,
+		NoReuseDelta:    p.noReuseDelta,
+		All:             p.all,
+		Geometric:       p.geometric,
+		RemoveRedundant: p.removeRedund,
+	})
+	if err != nil {
+		return fmt.Errorf("could not repack the objects: %w", err)
+	}
+	if p.quiet {
+		return nil
+	}
+	if res.Objects == 0 {
+		fmt.Fprintln(out, "Nothing new to pack.")
+		return nil
+	}
+	// Same summary as git pack-objects
+	fmt.Fprintf(out, "Total %d (delta %d), reused %d (delta %d)\n", res.Objects, res.Deltas, res.ReusedDeltas, res.ReusedDeltas)
+	return nil
+}
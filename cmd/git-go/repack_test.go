@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepack(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, repoPath string, args ...string) (string, error) {
+		t.Helper()
+
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return outBuf.String(), err
+	}
+
+	t.Run("-ad should pack everything", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		out, err := run(t, repoPath, "repack", "-ad")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Total 366 \(delta \d+\), reused \d+ \(delta \d+\)\n$`, out)
+
+		out, err = run(t, repoPath, "count-objects", "-v")
+		require.NoError(t, err)
+		assert.Contains(t, out, "count: 0\n")
+		assert.Contains(t, out, "in-pack: 366\n")
+		assert.Contains(t, out, "packs: 1\n")
+	})
+
+	t.Run("-d should only pack the loose objects", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		out, err := run(t, repoPath, "repack", "-d")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Total 2 \(delta \d+\), reused \d+ \(delta \d+\)\n$`, out)
+
+		out, err = run(t, repoPath, "repack", "-d")
+		require.NoError(t, err)
+		assert.Equal(t, "Nothing new to pack.\n", out)
+
+		out, err = run(t, repoPath, "count-objects", "-v")
+		require.NoError(t, err)
+		assert.Contains(t, out, "count: 0\n")
+		assert.Contains(t, out, "packs: 2\n")
+	})
+
+	t.Run("should fail with -a and --geometric", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		_, err := run(t, repoPath, "repack", "-a", "--geometric=2")
+		require.Error(t, err)
+	})
+}
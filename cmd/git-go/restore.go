@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+type restoreParams struct {
+	source     string
+	progress   bool
+	noProgress bool
+}
+
+func newRestoreCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore --source=<tree-ish> [--progress | --no-progress] [--] <pathspec>...",
+		Short: "Restore working tree files",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	p := restoreParams{}
+	cmd.Flags().StringVarP(&p.source, "source", "s", "", "Restore the working tree files with the content from the given tree.")
+	cmd.Flags().BoolVar(&p.progress, "progress", false, "Report the progress even if the standard error is not a terminal.")
+	cmd.Flags().BoolVar(&p.noProgress, "no-progress", false, "Don't report the progress.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		showProgress := !p.noProgress && (p.progress || isTerminal(cmd.ErrOrStderr()))
+		var meter *progressMeter
+		if showProgress {
+			meter = newProgressMeter(cmd.ErrOrStderr(), "Updating files")
+		}
+		return restoreCmd(meter, cfg, p, args)
+	}
+	return cmd
+}
+
+func restoreCmd(meter *progressMeter, cfg *globalFlags, p restoreParams, pathspecs []string) (err error) {
+	// There's no index to restore the files from
+	if p.source == "" {
+		return errors.New("--source is required")
+	}
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	if r.IsBare() {
+		return errors.New("this operation must be run in a work tree")
+	}
+
+	oid, err := r.RevParse(p.source)
+	if err != nil {
+		return wrapRevisionError(err)
+	}
+	o, err := r.Object(oid)
+	if err != nil {
+		return fmt.Errorf("could not get %s: %w", p.source, err)
+	}
+	o, err = r.PeelObject(o, object.TypeTree)
+	if err != nil {
+		return fmt.Errorf("could not get the tree of %s: %w", p.source, err)
+	}
+	tree, err := r.Tree(o.ID())
+	if err != nil {
+		return fmt.Errorf("could not get the tree of %s: %w", p.source, err)
+	}
+
+	// The pathspecs are relative to the current directory, but the
+	// paths of the tree are relative to the root of the working tree
+	paths := make([]string, len(pathspecs))
+	for i, spec := range pathspecs {
+		abs := spec
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(cfg.C.String(), spec)
+		}
+		rel, err := filepath.Rel(r.Config.WorkTreePath, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s: is outside repository at '%s'", spec, r.Config.WorkTreePath)
+		}
+		paths[i] = filepath.ToSlash(rel)
+	}
+
+	opts := git.CheckoutOptions{
+		Paths: paths,
+	}
+	var last git.CheckoutProgress
+	if meter != nil {
+		opts.Progress = func(p git.CheckoutProgress) {
+			last = p
+			meter.update(p.Files, p.Total)
+		}
+	}
+	err = r.CheckoutTreeWithOptions(tree, opts)
+	if err != nil {
+		if errors.Is(err, git.ErrPathNotFound) {
+			return fmt.Errorf("pathspec did not match any file known to git: %w", err)
+		}
+		return fmt.Errorf("could not restore the files: %w", err)
+	}
+	if meter != nil {
+		meter.done(last.Files, last.Total)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+
+	run := func(dir string, args ...string) (string, error) {
+		errBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(dir, env.NewFromOs())
+		cmd.SetOut(bytes.NewBufferString(""))
+		cmd.SetErr(errBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return errBuf.String(), err
+	}
+
+	t.Run("should restore the files and report the progress", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		objectDir := filepath.Join(repoPath, "plumbing", "object")
+		require.NoError(t, os.RemoveAll(objectDir))
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("changed"), 0o644))
+
+		out, err := run(repoPath, "restore", "--source", "HEAD", "--progress", "--", "plumbing/object")
+		require.NoError(t, err)
+		lines := strings.Split(out, "\r")
+		assert.Equal(t, "Updating files:   0% (0/5)", lines[0])
+		assert.Equal(t, "Updating files: 100% (5/5), done.\n", lines[len(lines)-1])
+
+		entries, err := os.ReadDir(objectDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 5)
+		data, err := os.ReadFile(filepath.Join(repoPath, "README.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "changed", string(data), "README.md should not have been restored")
+	})
+
+	t.Run("should use paths relative to the current directory", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		p := filepath.Join(repoPath, "plumbing", "oid.go")
+		require.NoError(t, os.Remove(p))
+
+		out, err := run(filepath.Join(repoPath, "plumbing"), "restore", "--source=HEAD", "oid.go")
+		require.NoError(t, err)
+		assert.Empty(t, out, "the progress should only be reported on a terminal")
+		assert.FileExists(t, p)
+	})
+
+	t.Run("should fail without source", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		_, err := run(repoPath, "restore", "README.md")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--source is required")
+	})
+
+	t.Run("should fail on unknown paths", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		_, err := run(repoPath, "restore", "--source=HEAD", "nope")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pathspec did not match")
+	})
+}
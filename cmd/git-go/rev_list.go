@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/gitdate"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/pretty"
+	"github.com/spf13/cobra"
+)
+
+func newRevListCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rev-list [OPTIONS] COMMIT...",
+		Short: "Lists commit objects in reverse chronological order",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	count := cmd.Flags().Bool("count", false, "Print a number stating how many commits (or objects with --objects) would have been listed.")
+	objects := cmd.Flags().Bool("objects", false, "Print the object IDs of any object referenced by the listed commits.")
+	maxCount := cmd.Flags().IntP("max-count", "n", 0, "Limit the number of commits to output.")
+	since := cmd.Flags().String("since", "", "Show commits more recent than a specific date.")
+	until := cmd.Flags().String("until", "", "Show commits older than a specific date.")
+	format := cmd.Flags().String("format", "", "Print the commits using the given format, like git log --format.")
+	date := cmd.Flags().String("date", "", "Set the format of the dates printed by --format (relative, iso, iso-strict, rfc, short, raw, unix).")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return revListCmd(cmd.Context(), cmd.OutOrStdout(), cfg, revListParams{
+			revisions: args,
+			count:     *count,
+			objects:   *objects,
+			maxCount:  *maxCount,
+			since:     *since,
+			until:     *until,
+			format:    *format,
+			date:      *date,
+		})
+	}
+	return cmd
+}
+
+type revListParams struct {
+	revisions []string
+	since     string
+	until     string
+	format    string
+	date      string
+	maxCount  int
+	count     bool
+	objects   bool
+}
+
+func revListCmd(ctx context.Context, out io.Writer, cfg *globalFlags, p revListParams) (err error) {
+	if p.maxCount < 0 {
+		return errors.New("--max-count cannot be negative")
+	}
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	rng, err := r.ResolveRevisionRange(p.revisions...)
+	if err != nil {
+		return wrapRevisionError(err)
+	}
+	opts := rng.WalkOptions()
+	opts.MaxCount = p.maxCount
+	now := time.Now()
+	if p.since != "" {
+		if opts.Since, err = gitdate.ParseApprox(p.since, now); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if p.until != "" {
+		if opts.Until, err = gitdate.ParseApprox(p.until, now); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	include := rng.Include
+
+	var formatter *pretty.Formatter
+	if p.format != "" {
+		if p.objects {
+			return errors.New("--format cannot be used with --objects")
+		}
+		dateFormat, err := pretty.ParseDateFormat(p.date)
+		if err != nil {
+			return fmt.Errorf("invalid --date: %w", err)
+		}
+		formatter = pretty.New(p.format, pretty.Options{Date: dateFormat})
+	}
+
+	total := 0
+	if !p.objects {
+		err = r.WalkCommits(include, opts, func(c *object.Commit) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			total++
+			if p.count {
+				return nil
+			}
+			// Like git, the formatted commits are preceded by their ID
+			if formatter != nil {
+				fmt.Fprintf(out, "commit %s\n%s\n", c.ID().String(), formatter.Commit(c))
+				return nil
+			}
+			fmt.Fprintln(out, c.ID().String())
+			return nil
+		})
+	} else {
+		err = r.WalkObjects(include, opts, func(oid ginternals.Oid, typ object.Type, path string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			total++
+			if p.count {
+				return nil
+			}
+			// Like git, commits are the only objects printed without
+			// a path (even an empty one)
+			if typ == object.TypeCommit {
+				fmt.Fprintln(out, oid.String())
+				return nil
+			}
+			fmt.Fprintf(out, "%s %s\n", oid.String(), path)
+			return nil
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("could not walk the history: %w", err)
+	}
+
+	if p.count {
+		fmt.Fprintln(out, total)
+	}
+	return nil
+}
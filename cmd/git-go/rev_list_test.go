@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevList(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	testCases := []struct {
+		desc           string
+		args           []string
+		expectedOutput string
+		expectError    bool
+	}{
+		{
+			desc: "--max-count should limit the output",
+			args: []string{"rev-list", "--max-count", "3", "HEAD"},
+			expectedOutput: strings.Join([]string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+				"6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+				"add862f16c9befc4b88a24e22fda2fa9b68c1653",
+			}, "\n") + "\n",
+		},
+		{
+			desc:           "--since and --until should filter the commits",
+			args:           []string{"rev-list", "--count", "--since", "1566185593", "--until", "2019-08-24 06:07:52 +0000", "HEAD"},
+			expectedOutput: "5\n",
+		},
+		{
+			desc:        "invalid --since should fail",
+			args:        []string{"rev-list", "--since", "not a date", "HEAD"},
+			expectError: true,
+		},
+		{
+			desc:           "--count should print the number of commits",
+			args:           []string{"rev-list", "--count", "HEAD"},
+			expectedOutput: "17\n",
+		},
+		{
+			desc:           "--format should format the commits",
+			args:           []string{"rev-list", "--format", "%h %an %ad%n%s", "--date", "iso", "-n", "1", "HEAD"},
+			expectedOutput: "commit bbb720a96e4c29b9950a4c577c98470a4d5dd089\nbbb720a Melvin Laplanche 2020-06-19 18:16:17 -0700\ndoc: Update TODOs in readme\n",
+		},
+		{
+			desc:        "invalid --date should fail",
+			args:        []string{"rev-list", "--format", "%ad", "--date", "nope", "HEAD"},
+			expectError: true,
+		},
+		{
+			desc:           "--count --objects should print the number of objects",
+			args:           []string{"rev-list", "--count", "--objects", "HEAD"},
+			expectedOutput: "280\n",
+		},
+		{
+			desc:           "^rev should exclude commits",
+			args:           []string{"rev-list", "--count", "master", "^ml/cleanup-062020"},
+			expectedOutput: "8\n",
+		},
+		{
+			desc:           "a..b should exclude commits",
+			args:           []string{"rev-list", "--count", "ml/cleanup-062020..master"},
+			expectedOutput: "8\n",
+		},
+		{
+			desc:           "a..b should exclude objects",
+			args:           []string{"rev-list", "--count", "--objects", "ml/cleanup-062020..master"},
+			expectedOutput: "71\n",
+		},
+		{
+			desc: "--objects should print the path of the objects",
+			args: []string{"rev-list", "--objects", "-n", "1", "HEAD"},
+			expectedOutput: strings.Join([]string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+				"e5b9e846e1b468bc9597ff95d71dfacda8bd54e3 ",
+				"f8b43dc7c5ff26296ae2720b356564f7db729b2c .github",
+				"8741a2a2864e83638bc8347307d7539073a4e7a7 .github/workflows",
+				"d55aca68dd3bee5055521e5900ab6251e76d9a17 .github/workflows/go.yml",
+			}, "\n"),
+		},
+		{
+			desc:        "unknown revision should fail",
+			args:        []string{"rev-list", "does-not-exist"},
+			expectError: true,
+		},
+		{
+			desc:        "negative --max-count should fail",
+			args:        []string{"rev-list", "-n", "-1", "HEAD"},
+			expectError: true,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			cwd, err := os.Getwd()
+			require.NoError(t, err)
+
+			outBuf := bytes.NewBufferString("")
+			cmd := newRootCmd(cwd, env.NewFromOs())
+			cmd.SetOut(outBuf)
+			args := append([]string{"-C", repoPath}, tc.args...)
+			cmd.SetArgs(args)
+
+			require.NotPanics(t, func() {
+				err = cmd.Execute()
+			})
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			out, err := io.ReadAll(outBuf)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(out), tc.expectedOutput), "unexpected output:\n%s", string(out))
+		})
+	}
+}
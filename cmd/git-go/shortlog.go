@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+func newShortlogCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shortlog [OPTIONS] [REVISION_RANGE]",
+		Short: "Summarize git log output",
+		Args:  cobra.MaximumNArgs(1),
+	}
+
+	summary := cmd.Flags().BoolP("summary", "s", false, "Suppress commit description and provide a commit count summary only.")
+	numbered := cmd.Flags().BoolP("numbered", "n", false, "Sort output according to the number of commits per author instead of author alphabetic order.")
+	email := cmd.Flags().BoolP("email", "e", false, "Show the email address of each author.")
+	committer := cmd.Flags().BoolP("committer", "c", false, "Collect and show committer identities instead of authors.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		rangeSpec := ginternals.Head
+		if len(args) > 0 {
+			rangeSpec = args[0]
+		}
+		return shortlogCmd(cmd.OutOrStdout(), cfg, shortlogParams{
+			rangeSpec: rangeSpec,
+			summary:   *summary,
+			opts: git.ShortlogOptions{
+				Committer:   *committer,
+				Email:       *email,
+				SortByCount: *numbered,
+			},
+		})
+	}
+	return cmd
+}
+
+type shortlogParams struct {
+	rangeSpec string
+	opts      git.ShortlogOptions
+	summary   bool
+}
+
+func shortlogCmd(out io.Writer, cfg *globalFlags, p shortlogParams) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	entries, err := r.Shortlog(p.rangeSpec, p.opts)
+	if err != nil {
+		if errors.Is(err, git.ErrUnknownRevision) {
+			return wrapRevisionError(err)
+		}
+		return err
+	}
+	for _, e := range entries {
+		if p.summary {
+			fmt.Fprintf(out, "%6d\t%s\n", len(e.Subjects), e.String())
+			continue
+		}
+		fmt.Fprintf(out, "%s (%d):\n", e.String(), len(e.Subjects))
+		for _, subject := range e.Subjects {
+			fmt.Fprintf(out, "      %s\n", subject)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortlog(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	outBuf := bytes.NewBufferString("")
+	cmd := newRootCmd(repoPath, env.NewFromOs())
+	cmd.SetOut(outBuf)
+	cmd.SetArgs([]string{"shortlog", "-sn", "HEAD"})
+	require.NoError(t, cmd.Execute())
+
+	// generated using git shortlog -sn HEAD
+	assert.Equal(t, "    12\tMelvin Laplanche\n     5\tMelvin\n", outBuf.String())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+func newStashCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stash",
+		Short: "Inspect the stashed changes",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the stash entries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stashListCmd(cmd.OutOrStdout(), cfg)
+		},
+	})
+
+	showCmd := &cobra.Command{
+		Use:   "show [OPTIONS] [STASH]",
+		Short: "Show the changes recorded in a stash entry",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	patch := showCmd.Flags().BoolP("patch", "p", false, "Show the changes as a patch.")
+	includeUntracked := showCmd.Flags().BoolP("include-untracked", "u", false, "Show the untracked files in the stash entry as part of the diff.")
+	submodule := showCmd.Flags().String("submodule", "short", "Specify how differences in submodules are shown (short, log).")
+	showCmd.Flags().Lookup("submodule").NoOptDefVal = "log"
+	showCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		name := "stash@{0}"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		return stashShowCmd(cmd.OutOrStdout(), cfg, stashShowParams{
+			name:             name,
+			patch:            *patch,
+			includeUntracked: *includeUntracked,
+			submodule:        *submodule,
+		})
+	}
+	cmd.AddCommand(showCmd)
+	return cmd
+}
+
+func stashListCmd(out io.Writer, cfg *globalFlags) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	stashes, err := r.Stashes()
+	if err != nil {
+		return err
+	}
+	for _, s := range stashes {
+		fmt.Fprintf(out, "%s: %s\n", s.Name(), s.Message)
+	}
+	return nil
+}
+
+type stashShowParams struct {
+	name             string
+	patch            bool
+	includeUntracked bool
+	submodule        string
+}
+
+func stashShowCmd(out io.Writer, cfg *globalFlags, p stashShowParams) (err error) {
+	if !p.patch {
+		return errors.New("only --patch is supported")
+	}
+	submoduleFormat, err := parseSubmoduleFormat(p.submodule)
+	if err != nil {
+		return err
+	}
+	index, err := parseStashName(p.name)
+	if err != nil {
+		return err
+	}
+
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	s, err := r.Stash(index)
+	if err != nil {
+		return err
+	}
+	diffOpts := git.DefaultDiffOptions()
+	diffOpts.Submodule = submoduleFormat
+	patches, err := r.StashDiff(s, git.StashDiffOptions{
+		DiffOptions:      diffOpts,
+		IncludeUntracked: p.includeUntracked,
+	})
+	if err != nil {
+		return err
+	}
+	for _, patch := range patches {
+		if _, err = patch.WriteTo(out); err != nil {
+			return fmt.Errorf("could not write the patch: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseStashName returns the index of a stash from its name.
+// Both "stash@{n}" and "n" are supported
+func parseStashName(name string) (int, error) {
+	s := name
+	if strings.HasPrefix(s, "stash@{") && strings.HasSuffix(s, "}") {
+		s = s[len("stash@{") : len(s)-1]
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%s is not a valid reference", name)
+	}
+	return index, nil
+}
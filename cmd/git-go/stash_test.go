@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStash(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	run := func(args ...string) (string, error) {
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return outBuf.String(), err
+	}
+
+	// generated using git stash list
+	out, err := run("stash", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "stash@{0}: WIP on tests: f0f7014 refactor: Update codebase to go 1.13\n", out)
+
+	// generated using git stash show -p
+	out, err = run("stash", "show", "-p", "stash@{0}")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "diff --git a/vendor/github.com/davecgh/go-spew/LICENSE b/vendor/github.com/davecgh/go-spew/LICENSE\ndeleted file mode 100644\n"), "unexpected output: %.200s", out)
+
+	_, err = run("stash", "show", "-p", "stash@{1}")
+	require.Error(t, err)
+
+	// the stash doesn't contain any submodule
+	out, err = run("stash", "show", "-p", "--submodule")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "diff --git a/vendor/github.com/davecgh/go-spew/LICENSE"), "unexpected output: %.200s", out)
+
+	_, err = run("stash", "show", "-p", "--submodule=diff")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse --submodule option parameter")
+}
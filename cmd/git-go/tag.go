@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+type tagParams struct {
+	annotations bool
+}
+
+func newTagCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag [-n]",
+		Short: "List tags",
+		Args:  cobra.NoArgs,
+	}
+
+	p := tagParams{}
+	cmd.Flags().BoolVarP(&p.annotations, "annotations", "n", false, "Print the first line of the annotation of each tag, or the subject of the commit for the lightweight tags.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return tagCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+func tagCmd(out io.Writer, cfg *globalFlags, p tagParams) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	tags, err := r.Tags()
+	if err != nil {
+		return fmt.Errorf("could not list the tags: %w", err)
+	}
+	for _, tag := range tags {
+		if !p.annotations {
+			fmt.Fprintln(out, tag.ShortName)
+			continue
+		}
+		line, err := tagFirstLine(tag)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%-15s %s\n", tag.ShortName, line)
+	}
+	return nil
+}
+
+// tagFirstLine returns the first line of the annotation of the given
+// tag, or the subject of the commit it targets for a lightweight tag.
+// An empty string is returned for the lightweight tags that don't
+// target a commit
+func tagFirstLine(tag *git.Tag) (string, error) {
+	annotation, err := tag.Annotation()
+	if err == nil {
+		return strings.SplitN(annotation.Message(), "\n", 2)[0], nil
+	}
+	if !errors.Is(err, git.ErrTagNotAnnotated) {
+		return "", fmt.Errorf("could not get the annotation of %s: %w", tag.ShortName, err)
+	}
+
+	c, err := tag.Commit()
+	if err != nil {
+		if errors.Is(err, object.ErrObjectInvalid) {
+			return "", nil
+		}
+		return "", fmt.Errorf("could not get the commit of %s: %w", tag.ShortName, err)
+	}
+	return c.Subject(), nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		args     []string
+		expected string
+	}{
+		{
+			desc:     "should list the tags",
+			args:     []string{"tag"},
+			expected: "annotated\nlightweight\n",
+		},
+		{
+			// generated using git tag -n
+			desc:     "-n should print the annotations",
+			args:     []string{"tag", "-n"},
+			expected: "annotated       annotated tag\nlightweight     doc: Update TODOs in readme\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+			t.Cleanup(cleanup)
+
+			outBuf := bytes.NewBufferString("")
+			cmd := newRootCmd(repoPath, env.NewFromOs())
+			cmd.SetOut(outBuf)
+			cmd.SetArgs(tc.args)
+			require.NoError(t, cmd.Execute(), "test %d", i)
+			assert.Equal(t, tc.expected, outBuf.String(), "test %d", i)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+type verifyPackParams struct {
+	paths   []string
+	verbose bool
+}
+
+func newVerifyPackCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-pack [-v] PACK.idx...",
+		Short: "Validate packed Git archive files",
+		Args:  cobra.MinimumNArgs(1),
+	}
+
+	p := verifyPackParams{}
+	cmd.Flags().BoolVarP(&p.verbose, "verbose", "v", false, "After verifying the pack, show the list of objects contained in the pack and a histogram of delta chain length.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		p.paths = args
+		return verifyPackCmd(cmd.OutOrStdout(), cfg, p)
+	}
+	return cmd
+}
+
+func verifyPackCmd(out io.Writer, cfg *globalFlags, p verifyPackParams) error {
+	for _, path := range p.paths {
+		// Like git, we report the path of the packfile even if the
+		// index was provided
+		packPath := strings.TrimSuffix(strings.TrimSuffix(path, packfile.ExtIndex), packfile.ExtPackfile) + packfile.ExtPackfile
+		fullPath := packPath
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(cfg.C.String(), fullPath)
+		}
+
+		report, err := packfile.Verify(afero.NewOsFs(), fullPath)
+		if err != nil {
+			if p.verbose {
+				fmt.Fprintf(out, "%s: bad\n", packPath)
+			}
+			return fmt.Errorf("could not verify %s: %w", packPath, err)
+		}
+		if !p.verbose {
+			continue
+		}
+
+		for _, o := range report.Objects {
+			fmt.Fprintf(out, "%s %-6s %d %d %d", o.ID.String(), o.Type.String(), o.Size, o.PackedSize, o.Offset)
+			if o.Depth > 0 {
+				fmt.Fprintf(out, " %d %s", o.Depth, o.Base.String())
+			}
+			fmt.Fprintln(out)
+		}
+		lengths := report.ChainLengths()
+		depths := make([]int, 0, len(lengths))
+		for depth := range lengths {
+			depths = append(depths, depth)
+		}
+		sort.Ints(depths)
+		for _, depth := range depths {
+			if depth == 0 {
+				fmt.Fprintf(out, "non delta: %d %s\n", lengths[depth], objectsLabel(lengths[depth]))
+				continue
+			}
+			fmt.Fprintf(out, "chain length = %d: %d %s\n", depth, lengths[depth], objectsLabel(lengths[depth]))
+		}
+		fmt.Fprintf(out, "%s: ok\n", packPath)
+	}
+	return nil
+}
+
+// objectsLabel returns "object" or "objects" depending on the given
+// count
+func objectsLabel(count int) string {
+	if count == 1 {
+		return "object"
+	}
+	return "objects"
+}
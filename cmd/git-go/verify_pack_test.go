@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPack(t *testing.T) {
+	t.Parallel()
+
+	idxPath := filepath.Join(".git", "objects", "pack", "pack-0163931160835b1de2f120e1aa7e52206debeb14.idx")
+	packPath := filepath.Join(".git", "objects", "pack", "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+
+	run := func(t *testing.T, repoPath string, args ...string) (string, error) {
+		t.Helper()
+
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return outBuf.String(), err
+	}
+
+	t.Run("should print nothing by default", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		out, err := run(t, repoPath, "verify-pack", idxPath)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("-v should list the objects", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		out, err := run(t, repoPath, "verify-pack", "-v", idxPath)
+		require.NoError(t, err)
+
+		// generated using git verify-pack -v
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		require.Len(t, lines, 371)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089 commit 260 203 12", lines[0])
+		assert.Contains(t, lines, "d55aca68dd3bee5055521e5900ab6251e76d9a17 blob   226 176 27234 1 c30d3fcd40885d8b33459243d1764266b0904345")
+		assert.Equal(t, []string{
+			"non delta: 240 objects",
+			"chain length = 1: 70 objects",
+			"chain length = 2: 33 objects",
+			"chain length = 3: 14 objects",
+			"chain length = 4: 5 objects",
+			"chain length = 5: 2 objects",
+			packPath + ": ok",
+		}, lines[364:])
+	})
+
+	t.Run("should fail on a corrupted packfile", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		fullPath := filepath.Join(repoPath, packPath)
+		require.NoError(t, os.Chmod(fullPath, 0o644))
+		data, err := os.ReadFile(fullPath)
+		require.NoError(t, err)
+		data[12] = ^data[12]
+		require.NoError(t, os.WriteFile(fullPath, data, 0o644))
+
+		out, err := run(t, repoPath, "verify-pack", "-v", packPath)
+		require.Error(t, err)
+		assert.Equal(t, packPath+": bad\n", out)
+	})
+}
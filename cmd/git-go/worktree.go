@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/cobra"
+)
+
+func newWorktreeCmd(cfg *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worktree",
+		Short: "Manage multiple working trees",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "move WORKTREE NEW-PATH",
+		Short: "Move a working tree to a new location",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return worktreeMoveCmd(cfg, args[0], args[1])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "repair [PATH...]",
+		Short: "Repair the administrative files of the working trees",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return worktreeRepairCmd(cmd.OutOrStdout(), cfg, args)
+		},
+	})
+	return cmd
+}
+
+func worktreeMoveCmd(cfg *globalFlags, src, dst string) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	return r.MoveWorktree(src, dst)
+}
+
+func worktreeRepairCmd(out io.Writer, cfg *globalFlags, paths []string) (err error) {
+	r, err := loadRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer errutil.Close(r, &err)
+
+	repaired, err := r.RepairWorktrees(paths...)
+	for _, p := range repaired {
+		fmt.Fprintf(out, "repair: %s\n", p)
+	}
+	return err
+}
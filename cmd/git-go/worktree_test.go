@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktree(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	dir, cleanupDir := testutil.TempDir(t)
+	t.Cleanup(cleanupDir)
+
+	// We create a linked worktree the same way git worktree add does
+	adminDir := filepath.Join(repoPath, ".git", "worktrees", "linked")
+	wtPath := filepath.Join(dir, "linked")
+	require.NoError(t, os.MkdirAll(adminDir, 0o755))
+	require.NoError(t, os.MkdirAll(wtPath, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(adminDir, "gitdir"), []byte(filepath.Join(wtPath, ".git")+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtPath, ".git"), []byte("gitdir: "+adminDir+"\n"), 0o644))
+
+	run := func(args ...string) (string, error) {
+		outBuf := bytes.NewBufferString("")
+		cmd := newRootCmd(repoPath, env.NewFromOs())
+		cmd.SetOut(outBuf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return outBuf.String(), err
+	}
+
+	// move
+	movedPath := filepath.Join(dir, "moved")
+	_, err := run("worktree", "move", wtPath, movedPath)
+	require.NoError(t, err)
+	gitdir, err := os.ReadFile(filepath.Join(adminDir, "gitdir"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(movedPath, ".git")+"\n", string(gitdir))
+
+	// repair after a manual move
+	require.NoError(t, os.Rename(movedPath, wtPath))
+	out, err := run("worktree", "repair", wtPath)
+	require.NoError(t, err)
+	assert.Equal(t, "repair: "+filepath.Join(adminDir, "gitdir")+"\n", out)
+	gitdir, err = os.ReadFile(filepath.Join(adminDir, "gitdir"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(wtPath, ".git")+"\n", string(gitdir))
+}
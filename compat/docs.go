@@ -0,0 +1,10 @@
+// Package compat contains tests that run the same operations with
+// the library and with the git binary, and make sure both produce
+// the exact same objects and files.
+//
+// The tests require git and are behind the compat build tag:
+//
+//	go test -tags compat ./compat
+//
+// New operations can be tested by adding a gitcompat.Case.
+package compat
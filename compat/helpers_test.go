@@ -0,0 +1,55 @@
+//go:build compat
+// +build compat
+
+package compat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil/gitcompat"
+	"github.com/stretchr/testify/require"
+)
+
+// openRepo opens the repository at the given path
+func openRepo(t *testing.T, repoPath string) *git.Repository {
+	t.Helper()
+
+	r, err := git.OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+	return r
+}
+
+// gitOid runs git and parses its output as an oid
+func gitOid(t *testing.T, repoPath string, stdin []byte, args ...string) ginternals.Oid {
+	t.Helper()
+
+	out := gitcompat.Git(t, repoPath, stdin, args...)
+	oid, err := ginternals.NewOidFromStr(strings.TrimSpace(out))
+	require.NoError(t, err)
+	return oid
+}
+
+// setupHistory creates a repository containing a few commits
+// using git
+func setupHistory(t *testing.T, repoPath string) {
+	t.Helper()
+
+	gitcompat.InitRepo(t, repoPath)
+	var parent ginternals.Oid
+	for i := 0; i < 3; i++ {
+		blob := gitOid(t, repoPath, []byte(strings.Repeat("line\n", i+1)), "hash-object", "-w", "--stdin")
+		tree := gitOid(t, repoPath, []byte("100644 blob "+blob.String()+"\tfile\n"), "mktree")
+		args := []string{"commit-tree", tree.String(), "-m", "commit"}
+		if !parent.IsZero() {
+			args = append(args, "-p", parent.String())
+		}
+		parent = gitOid(t, repoPath, nil, args...)
+	}
+	gitcompat.Git(t, repoPath, nil, "update-ref", "refs/heads/master", parent.String())
+}
@@ -0,0 +1,175 @@
+//go:build compat
+// +build compat
+
+package compat_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil/gitcompat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc    string
+		content []byte
+	}{
+		{desc: "empty", content: []byte{}},
+		{desc: "text", content: []byte("content\n")},
+		{desc: "binary", content: []byte{0, 1, 2, 0xff, '\n', 0}},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			gitcompat.Run(t, gitcompat.Case{
+				Lib: func(t *testing.T, repoPath string) string {
+					blob, err := openRepo(t, repoPath).NewBlob(tc.content)
+					require.NoError(t, err, "test %d", i)
+					return blob.ID().String()
+				},
+				Git: func(t *testing.T, repoPath string) string {
+					return gitOid(t, repoPath, tc.content, "hash-object", "-w", "--stdin").String()
+				},
+			})
+		})
+	}
+}
+
+func TestTrees(t *testing.T) {
+	t.Parallel()
+
+	blob := object.New(object.TypeBlob, []byte("content\n"))
+	subtree := object.NewTree([]object.TreeEntry{
+		{Path: "nested", ID: blob.ID(), Mode: object.ModeFile},
+	})
+	submodule, err := ginternals.NewOidFromStr("0348308155fbeb0015970d1a30666e51a2ea0e9f")
+	require.NoError(t, err)
+
+	// The names are chosen to check the sorting of the entries, since
+	// the directories are sorted as if their name ended with a "/"
+	entries := []object.TreeEntry{
+		{Path: "a.txt", ID: blob.ID(), Mode: object.ModeFile},
+		{Path: "a", ID: subtree.ID(), Mode: object.ModeDirectory},
+		{Path: "a-b", ID: blob.ID(), Mode: object.ModeFile},
+		{Path: "a0", ID: blob.ID(), Mode: object.ModeFile},
+		{Path: "exec", ID: blob.ID(), Mode: object.ModeExecutable},
+		{Path: "link", ID: blob.ID(), Mode: object.ModeSymLink},
+		{Path: "submodule", ID: submodule, Mode: object.ModeGitLink},
+	}
+
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: func(t *testing.T, repoPath string) {
+			gitcompat.InitRepo(t, repoPath)
+			gitOid(t, repoPath, blob.Bytes(), "hash-object", "-w", "--stdin")
+			gitOid(t, repoPath, []byte("100644 blob "+blob.ID().String()+"\tnested\n"), "mktree")
+		},
+		Lib: func(t *testing.T, repoPath string) string {
+			tb := openRepo(t, repoPath).NewTreeBuilder()
+			for _, e := range entries {
+				require.NoError(t, tb.Insert(e.Path, e.ID, e.Mode))
+			}
+			tree, err := tb.Write()
+			require.NoError(t, err)
+			return tree.ID().String()
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			input := ""
+			for _, e := range entries {
+				input += fmt.Sprintf("%06o %s %s\t%s\n", e.Mode, e.Mode.ObjectType(), e.ID.String(), e.Path)
+			}
+			return gitOid(t, repoPath, []byte(input), "mktree", "--missing").String()
+		},
+	})
+}
+
+func TestCommits(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc    string
+		message string
+		parent  bool
+	}{
+		{desc: "root commit", message: "initial commit\n"},
+		{desc: "commit with a parent", message: "second commit\n", parent: true},
+		{desc: "multi-line message", message: "subject\n\nbody\nof the commit\n", parent: true},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			gitcompat.Run(t, gitcompat.Case{
+				Setup: setupHistory,
+				Lib: func(t *testing.T, repoPath string) string {
+					r := openRepo(t, repoPath)
+					master, err := r.Reference("refs/heads/master")
+					require.NoError(t, err, "test %d", i)
+					head, err := r.Commit(master.Target())
+					require.NoError(t, err, "test %d", i)
+					tree, err := r.Tree(head.TreeID())
+					require.NoError(t, err, "test %d", i)
+
+					opts := &object.CommitOptions{
+						Message:   tc.message,
+						Committer: gitcompat.Signature(),
+					}
+					if tc.parent {
+						opts.ParentsID = []ginternals.Oid{head.ID()}
+					}
+					c, err := r.NewCommit("refs/heads/master", tree, gitcompat.Signature(), opts)
+					require.NoError(t, err, "test %d", i)
+					return c.ID().String()
+				},
+				Git: func(t *testing.T, repoPath string) string {
+					args := []string{"commit-tree", "master^{tree}"}
+					if tc.parent {
+						args = append(args, "-p", "master")
+					}
+					oid := gitOid(t, repoPath, []byte(tc.message), args...)
+					gitcompat.Git(t, repoPath, nil, "update-ref", "refs/heads/master", oid.String())
+					return oid.String()
+				},
+				Files: []string{"refs/heads/*"},
+			})
+		})
+	}
+}
+
+func TestAnnotatedTags(t *testing.T) {
+	t.Parallel()
+
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: setupHistory,
+		Lib: func(t *testing.T, repoPath string) string {
+			r := openRepo(t, repoPath)
+			master, err := r.Reference("refs/heads/master")
+			require.NoError(t, err)
+			target, err := r.Object(master.Target())
+			require.NoError(t, err)
+			tag, err := r.NewTag(&object.TagParams{
+				Target:  target,
+				Name:    "v1.0.0",
+				Tagger:  gitcompat.Signature(),
+				Message: "version 1.0.0\n",
+			})
+			require.NoError(t, err)
+			return tag.ID().String()
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			gitcompat.Git(t, repoPath, nil, "tag", "-a", "-m", "version 1.0.0", "v1.0.0", "master")
+			return gitOid(t, repoPath, nil, "rev-parse", "refs/tags/v1.0.0").String()
+		},
+		Files: []string{"refs/tags/*"},
+	})
+}
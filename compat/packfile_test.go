@@ -0,0 +1,109 @@
+//go:build compat
+// +build compat
+
+package compat_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil/gitcompat"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/stretchr/testify/require"
+)
+
+// packPath returns the path of the only packfile of the repository,
+// without its extension
+func packPath(t *testing.T, repoPath string) string {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(repoPath, ".git", "objects", "pack", "*"+packfile.ExtPackfile))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	return paths[0][:len(paths[0])-len(packfile.ExtPackfile)]
+}
+
+func TestIndexPack(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc string
+		opts repogen.Options
+	}{
+		{
+			desc: "packfile with deltas",
+			opts: repogen.Options{Commits: 30, Width: 3, Depth: 1, FileLines: 10, Packs: 1},
+		},
+		{
+			desc: "packfile with binary files",
+			opts: repogen.Options{Commits: 5, Width: 2, BinaryFiles: 3, BinarySize: 10_000, Packs: 1},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			gitcompat.Run(t, gitcompat.Case{
+				// The index of the generated packfile is removed so
+				// each side can build it
+				Setup: func(t *testing.T, repoPath string) {
+					_, err := repogen.Generate(repoPath, tc.opts)
+					require.NoError(t, err, "test %d", i)
+					require.NoError(t, os.Remove(packPath(t, repoPath)+packfile.ExtIndex), "test %d", i)
+				},
+				Lib: func(t *testing.T, repoPath string) string {
+					p := packPath(t, repoPath)
+					pack, err := os.ReadFile(p + packfile.ExtPackfile)
+					require.NoError(t, err, "test %d", i)
+					idx, err := packfile.BuildIndex(pack)
+					require.NoError(t, err, "test %d", i)
+					require.NoError(t, os.WriteFile(p+packfile.ExtIndex, idx, 0o444), "test %d", i)
+					return ""
+				},
+				Git: func(t *testing.T, repoPath string) string {
+					gitcompat.Git(t, repoPath, nil, "index-pack", packPath(t, repoPath)+packfile.ExtPackfile)
+					return ""
+				},
+				Files: []string{"objects/pack/*.idx"},
+			})
+		})
+	}
+}
+
+func TestBuildIndexLargeOffsets(t *testing.T) {
+	t.Parallel()
+
+	// git index-pack can store the offsets in layer5 to test the
+	// support of the packfiles bigger than 2GB, like the generator.
+	// Each side generates its own repository
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: func(t *testing.T, repoPath string) {},
+		Lib: func(t *testing.T, repoPath string) string {
+			_, err := repogen.Generate(repoPath, repogen.Options{
+				Commits:              10,
+				Width:                2,
+				Packs:                1,
+				LargeOffsetThreshold: 1,
+			})
+			require.NoError(t, err)
+			return ""
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			_, err := repogen.Generate(repoPath, repogen.Options{
+				Commits: 10,
+				Width:   2,
+				Packs:   1,
+			})
+			require.NoError(t, err)
+			p := packPath(t, repoPath)
+			require.NoError(t, os.Remove(p+packfile.ExtIndex))
+			gitcompat.Git(t, repoPath, nil, "index-pack", "--index-version=2,12", p+packfile.ExtPackfile)
+			return ""
+		},
+		Files: []string{"objects/pack/*.idx"},
+	})
+}
@@ -0,0 +1,94 @@
+//go:build compat
+// +build compat
+
+package compat_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil/gitcompat"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+	t.Parallel()
+
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: setupHistory,
+		Lib: func(t *testing.T, repoPath string) string {
+			r := openRepo(t, repoPath)
+			master, err := r.Reference("refs/heads/master")
+			require.NoError(t, err)
+			_, err = r.NewReference("refs/heads/feature/new", master.Target())
+			require.NoError(t, err)
+			_, err = r.NewSymbolicReference("refs/heads/alias", "refs/heads/master")
+			require.NoError(t, err)
+			return ""
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			gitcompat.Git(t, repoPath, nil, "update-ref", "refs/heads/feature/new", "master")
+			gitcompat.Git(t, repoPath, nil, "symbolic-ref", "refs/heads/alias", "refs/heads/master")
+			return ""
+		},
+		Files: []string{"refs/heads/*", "refs/heads/feature/*"},
+	})
+}
+
+func TestPackReferences(t *testing.T) {
+	t.Parallel()
+
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: func(t *testing.T, repoPath string) {
+			_, err := repogen.Generate(repoPath, repogen.Options{
+				Commits:  10,
+				Width:    2,
+				Branches: 3,
+				Tags:     3,
+			})
+			require.NoError(t, err)
+			// lightweight tag
+			gitcompat.Git(t, repoPath, nil, "tag", "lightweight", "master~2")
+		},
+		Lib: func(t *testing.T, repoPath string) string {
+			require.NoError(t, openRepo(t, repoPath).PackReferences())
+			return ""
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			gitcompat.Git(t, repoPath, nil, "pack-refs", "--all")
+			return ""
+		},
+		Files: []string{"packed-refs", "refs/heads/*", "refs/tags/*"},
+	})
+}
+
+func TestPeeledReferences(t *testing.T) {
+	t.Parallel()
+
+	// The library should read the packed-refs written by git
+	gitcompat.Run(t, gitcompat.Case{
+		Setup: func(t *testing.T, repoPath string) {
+			_, err := repogen.Generate(repoPath, repogen.Options{
+				Commits:    10,
+				Width:      2,
+				Tags:       3,
+				PackedRefs: true,
+			})
+			require.NoError(t, err)
+		},
+		Lib: func(t *testing.T, repoPath string) string {
+			r := openRepo(t, repoPath)
+			ref, err := r.Reference(ginternals.LocalTagFullName("v0.0.1"))
+			require.NoError(t, err)
+			o, err := r.Object(ref.Target())
+			require.NoError(t, err)
+			tag, err := o.AsTag()
+			require.NoError(t, err)
+			return tag.Target().String()
+		},
+		Git: func(t *testing.T, repoPath string) string {
+			return gitOid(t, repoPath, nil, "rev-parse", "v0.0.1^{}").String()
+		},
+	})
+}
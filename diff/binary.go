@@ -0,0 +1,281 @@
+package diff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/delta"
+)
+
+// base85Alphabet contains the characters used by git to encode
+// binary data in base85
+const base85Alphabet = "0123456789" +
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+	"abcdefghijklmnopqrstuvwxyz" +
+	"!#$%&()*+-;<=>?@^_`{|}~"
+
+// maxBinaryLineLen is the maximum number of bytes encoded on a single
+// line of a binary patch
+const maxBinaryLineLen = 52
+
+// binaryPatchHeader is the line that starts a binary patch
+const binaryPatchHeader = "GIT binary patch"
+
+// ErrInvalidBinaryPatch is an error thrown when a binary patch
+// cannot be parsed
+var ErrInvalidBinaryPatch = errors.New("invalid binary patch")
+
+// BinaryHunkType represents the way the data of a BinaryHunk are
+// stored
+type BinaryHunkType int8
+
+const (
+	// BinaryLiteral means that the hunk contains the whole content
+	BinaryLiteral BinaryHunkType = iota
+	// BinaryDelta means that the hunk contains a delta to apply on
+	// the other version of the content
+	BinaryDelta
+)
+
+// String returns the name used by git for the type
+func (t BinaryHunkType) String() string {
+	if t == BinaryDelta {
+		return "delta"
+	}
+	return "literal"
+}
+
+// BinaryHunk represents the data needed to go from a version of a
+// binary file to another
+type BinaryHunk struct {
+	Type BinaryHunkType
+	// Data contains either the content of the file, or a delta,
+	// depending on Type
+	Data []byte
+}
+
+// BinaryPatch represents the changes made to a binary file, in a
+// form that can be applied in both directions
+type BinaryPatch struct {
+	// Forward contains the data to go from the old version to the
+	// new version of the file
+	Forward BinaryHunk
+	// Reverse contains the data to go from the new version to the
+	// old version of the file
+	Reverse BinaryHunk
+}
+
+// NewBinaryPatch returns the binary patch needed to go from
+// oldContent to newContent.
+// Like git, a delta is used when it's smaller than the new content
+func NewBinaryPatch(oldContent, newContent []byte) *BinaryPatch {
+	return &BinaryPatch{
+		Forward: newBinaryHunk(oldContent, newContent),
+		Reverse: newBinaryHunk(newContent, oldContent),
+	}
+}
+
+// newBinaryHunk returns the hunk needed to go from "from" to "to"
+func newBinaryHunk(from, to []byte) BinaryHunk {
+	h := BinaryHunk{
+		Type: BinaryLiteral,
+		Data: to,
+	}
+	if len(from) == 0 || len(to) == 0 {
+		return h
+	}
+	d := delta.Create(from, to)
+	if len(deflate(d)) < len(deflate(to)) {
+		h.Type = BinaryDelta
+		h.Data = d
+	}
+	return h
+}
+
+// Apply applies the hunk on the given content
+func (h BinaryHunk) Apply(content []byte) ([]byte, error) {
+	if h.Type == BinaryLiteral {
+		return h.Data, nil
+	}
+	return delta.Apply(content, h.Data)
+}
+
+// String returns the patch using the git format
+func (p *BinaryPatch) String() string {
+	b := &strings.Builder{}
+	b.WriteString(binaryPatchHeader + "\n")
+	writeBinaryHunk(b, p.Forward)
+	writeBinaryHunk(b, p.Reverse)
+	return b.String()
+}
+
+// writeBinaryHunk writes the hunk using the git format: a header
+// containing the type and the size of the data, followed by the
+// deflated data encoded in base85, and an empty line.
+// Each line of data starts with a char containing the number of bytes
+// encoded on the line: A-Z for 1-26, a-z for 27-52
+func writeBinaryHunk(b *strings.Builder, h BinaryHunk) {
+	fmt.Fprintf(b, "%s %d\n", h.Type.String(), len(h.Data))
+	data := deflate(h.Data)
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxBinaryLineLen {
+			n = maxBinaryLineLen
+		}
+		if n <= 26 {
+			b.WriteByte(byte('A' + n - 1))
+		} else {
+			b.WriteByte(byte('a' + n - 27))
+		}
+		b.WriteString(encodeBase85(data[:n]))
+		b.WriteByte('\n')
+		data = data[n:]
+	}
+	b.WriteByte('\n')
+}
+
+// ParseBinaryPatch parses a binary patch written using the git
+// format. The patch is expected to start with "GIT binary patch"
+func ParseBinaryPatch(patch string) (*BinaryPatch, error) {
+	lines := strings.Split(patch, "\n")
+	if len(lines) == 0 || lines[0] != binaryPatchHeader {
+		return nil, fmt.Errorf("missing header: %w", ErrInvalidBinaryPatch)
+	}
+	lines = lines[1:]
+
+	p := &BinaryPatch{}
+	var err error
+	if p.Forward, lines, err = parseBinaryHunk(lines); err != nil {
+		return nil, fmt.Errorf("could not parse the forward hunk: %w", err)
+	}
+	if p.Reverse, _, err = parseBinaryHunk(lines); err != nil {
+		return nil, fmt.Errorf("could not parse the reverse hunk: %w", err)
+	}
+	return p, nil
+}
+
+// parseBinaryHunk parses a hunk written by writeBinaryHunk, and
+// returns the remaining lines
+func parseBinaryHunk(lines []string) (h BinaryHunk, rest []string, err error) {
+	if len(lines) == 0 {
+		return h, nil, fmt.Errorf("missing hunk: %w", ErrInvalidBinaryPatch)
+	}
+	header := strings.Fields(lines[0])
+	if len(header) != 2 {
+		return h, nil, fmt.Errorf("invalid header %q: %w", lines[0], ErrInvalidBinaryPatch)
+	}
+	switch header[0] {
+	case BinaryLiteral.String():
+		h.Type = BinaryLiteral
+	case BinaryDelta.String():
+		h.Type = BinaryDelta
+	default:
+		return h, nil, fmt.Errorf("invalid hunk type %q: %w", header[0], ErrInvalidBinaryPatch)
+	}
+	size, err := strconv.Atoi(header[1])
+	if err != nil {
+		return h, nil, fmt.Errorf("invalid size %q: %w", header[1], ErrInvalidBinaryPatch)
+	}
+
+	compressed := []byte{}
+	lines = lines[1:]
+	for len(lines) > 0 && lines[0] != "" {
+		line := lines[0]
+		lines = lines[1:]
+		var n int
+		switch c := line[0]; {
+		case c >= 'A' && c <= 'Z':
+			n = int(c-'A') + 1
+		case c >= 'a' && c <= 'z':
+			n = int(c-'a') + 27
+		default:
+			return h, nil, fmt.Errorf("invalid line length %q: %w", c, ErrInvalidBinaryPatch)
+		}
+		data, err := decodeBase85(line[1:], n)
+		if err != nil {
+			return h, nil, err
+		}
+		compressed = append(compressed, data...)
+	}
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return h, nil, fmt.Errorf("could not inflate the data: %w", ErrInvalidBinaryPatch)
+	}
+	defer zr.Close() //nolint:errcheck // we only read from memory
+	if h.Data, err = io.ReadAll(zr); err != nil {
+		return h, nil, fmt.Errorf("could not inflate the data: %w", ErrInvalidBinaryPatch)
+	}
+	if len(h.Data) != size {
+		return h, nil, fmt.Errorf("expected %d bytes, got %d: %w", size, len(h.Data), ErrInvalidBinaryPatch)
+	}
+	return h, lines, nil
+}
+
+// deflate returns the zlib compressed version of data
+func deflate(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	// Writing to a bytes.Buffer cannot fail
+	zw.Write(data) //nolint:errcheck // see above
+	zw.Close()     //nolint:errcheck // see above
+	return buf.Bytes()
+}
+
+// encodeBase85 encodes data using the base85 flavor of git.
+// Each group of 4 bytes is encoded on 5 characters. The last group
+// is padded with zeros
+func encodeBase85(data []byte) string {
+	b := &strings.Builder{}
+	for len(data) > 0 {
+		var acc uint32
+		for i := 0; i < 4; i++ {
+			acc <<= 8
+			if i < len(data) {
+				acc |= uint32(data[i])
+			}
+		}
+		chunk := make([]byte, 5)
+		for i := 4; i >= 0; i-- {
+			chunk[i] = base85Alphabet[acc%85]
+			acc /= 85
+		}
+		b.Write(chunk)
+		if len(data) < 4 {
+			break
+		}
+		data = data[4:]
+	}
+	return b.String()
+}
+
+// decodeBase85 decodes the n bytes encoded in data by encodeBase85
+func decodeBase85(data string, n int) ([]byte, error) {
+	if len(data) != (n+3)/4*5 {
+		return nil, fmt.Errorf("expected %d chars to decode %d bytes, got %d: %w", (n+3)/4*5, n, len(data), ErrInvalidBinaryPatch)
+	}
+	out := make([]byte, 0, len(data)/5*4)
+	for i := 0; i < len(data); i += 5 {
+		var acc uint64
+		for j := 0; j < 5; j++ {
+			v := strings.IndexByte(base85Alphabet, data[i+j])
+			if v == -1 {
+				return nil, fmt.Errorf("invalid base85 char %q: %w", data[i+j], ErrInvalidBinaryPatch)
+			}
+			acc = acc*85 + uint64(v)
+		}
+		if acc > 0xffffffff {
+			return nil, fmt.Errorf("invalid base85 data: %w", ErrInvalidBinaryPatch)
+		}
+		out = append(out, byte(acc>>24), byte(acc>>16), byte(acc>>8), byte(acc))
+	}
+	return out[:n], nil
+}
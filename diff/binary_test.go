@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryPatch(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte("0123456789abcdef\x00"), 100)
+	largeModified := append(append([]byte{}, large...), "new data"...)
+
+	testCases := []struct {
+		desc            string
+		oldContent      []byte
+		newContent      []byte
+		expectedForward BinaryHunkType
+		expectedReverse BinaryHunkType
+	}{
+		{
+			desc:            "new file",
+			oldContent:      nil,
+			newContent:      []byte("hello\x00world"),
+			expectedForward: BinaryLiteral,
+			expectedReverse: BinaryLiteral,
+		},
+		{
+			desc:            "small change",
+			oldContent:      []byte("hello\x00world"),
+			newContent:      []byte("hello\x00there"),
+			expectedForward: BinaryLiteral,
+			expectedReverse: BinaryLiteral,
+		},
+		{
+			desc:            "large file",
+			oldContent:      large,
+			newContent:      largeModified,
+			expectedForward: BinaryDelta,
+			expectedReverse: BinaryDelta,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			p := NewBinaryPatch(tc.oldContent, tc.newContent)
+			assert.Equal(t, tc.expectedForward, p.Forward.Type)
+			assert.Equal(t, tc.expectedReverse, p.Reverse.Type)
+
+			parsed, err := ParseBinaryPatch(p.String())
+			require.NoError(t, err)
+			assert.Equal(t, p.Forward.Type, parsed.Forward.Type)
+			assert.Equal(t, p.Reverse.Type, parsed.Reverse.Type)
+
+			out, err := parsed.Forward.Apply(tc.oldContent)
+			require.NoError(t, err)
+			assert.Equal(t, tc.newContent, out)
+			out, err = parsed.Reverse.Apply(tc.newContent)
+			require.NoError(t, err)
+			assert.Equal(t, string(tc.oldContent), string(out))
+		})
+	}
+}
+
+func TestParseBinaryPatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse a patch generated by git", func(t *testing.T) {
+		t.Parallel()
+
+		patch := "GIT binary patch\n" +
+			"literal 18\n" +
+			"Zcmc~u&B@7UD9K1IN>wP&FUm>b0sues27dqm\n" +
+			"\n" +
+			"literal 12\n" +
+			"Tcmc~u&B@7UD9<m-N#Ozj9g+k`\n" +
+			"\n"
+		p, err := ParseBinaryPatch(patch)
+		require.NoError(t, err)
+		assert.Equal(t, BinaryLiteral, p.Forward.Type)
+		assert.Equal(t, "hello\x00there world\n", string(p.Forward.Data))
+		assert.Equal(t, BinaryLiteral, p.Reverse.Type)
+		assert.Equal(t, "hello\x00world\n", string(p.Reverse.Data))
+	})
+
+	t.Run("should fail on invalid patches", func(t *testing.T) {
+		t.Parallel()
+
+		invalid := []string{
+			"",
+			"GIT binary patch\n",
+			"GIT binary patch\nunknown 12\n",
+			"GIT binary patch\nliteral 12\nTcmc~u&B@7UD9<m-N#Ozj9g+k\n\n",
+			"GIT binary patch\nliteral 13\nTcmc~u&B@7UD9<m-N#Ozj9g+k`\n\n",
+		}
+		for _, patch := range invalid {
+			_, err := ParseBinaryPatch(patch)
+			assert.ErrorIs(t, err, ErrInvalidBinaryPatch, patch)
+		}
+	})
+}
+
+func TestFilePatchStringBinaryPatch(t *testing.T) {
+	t.Parallel()
+
+	fromContent := "hello\x00world\n"
+	toContent := "hello\x00there world\n"
+	from := newFile("f", object.ModeFile, fromContent)
+	to := newFile("f", object.ModeFile, toContent)
+	p := NewFilePatchWithOptions(from, []byte(fromContent), to, []byte(toContent), FilePatchOptions{
+		ContextLines: DefaultContextLines,
+		BinaryPatch:  true,
+	})
+	require.NotNil(t, p.BinaryPatch)
+	out := p.String()
+	assert.Contains(t, out, "index 8e5da76b24d9a89e507982011ad21f4c191580f6..319a97ece87b279588aa2d40273ab7a8c0899afb 100644\n")
+	assert.Contains(t, out, "GIT binary patch\nliteral 18\n")
+	assert.NotContains(t, out, "Binary files")
+}
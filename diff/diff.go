@@ -0,0 +1,205 @@
+// Package diff contains methods to compute and format the differences
+// between two versions of a content
+package diff
+
+import (
+	"bytes"
+)
+
+// Operation represents the type of change applied to a line
+type Operation int8
+
+const (
+	// OpEqual represents a line that is in both versions
+	OpEqual Operation = iota
+	// OpDelete represents a line that only exists in the old version
+	OpDelete
+	// OpInsert represents a line that only exists in the new version
+	OpInsert
+)
+
+// Line represents a single line of a diff
+type Line struct {
+	// Content contains the content of the line, including its
+	// trailing \n (if any)
+	Content string
+	Op      Operation
+}
+
+// HasNewline returns whether the line ends with a \n.
+// Only the last line of a content may not end with a \n
+func (l Line) HasNewline() bool {
+	return len(l.Content) > 0 && l.Content[len(l.Content)-1] == '\n'
+}
+
+// SplitLines splits the content into lines. The \n are kept
+func SplitLines(content []byte) []string {
+	lines := make([]string, 0, bytes.Count(content, []byte{'\n'})+1)
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i == -1 {
+			lines = append(lines, string(content))
+			break
+		}
+		lines = append(lines, string(content[:i+1]))
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// Lines returns the line-by-line differences between oldContent
+// and newContent.
+// Within a block of changes, the deleted lines always come before
+// the inserted lines
+func Lines(oldContent, newContent []byte) []Line {
+	return Strings(SplitLines(oldContent), SplitLines(newContent))
+}
+
+// Strings returns the differences between 2 list of lines.
+// Within a block of changes, the deleted lines always come before
+// the inserted lines
+func Strings(a, b []string) []Line {
+	// We don't need to run the algorithm on the lines that are the
+	// same at the beginning and at the end
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		lines = append(lines, Line{Content: l, Op: OpEqual})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Content: l, Op: OpEqual})
+	}
+	return lines
+}
+
+// myers returns the shortest edit script to go from a to b using
+// Eugene W. Myers' algorithm.
+// http://www.xmailserver.org/diff2.pdf
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		lines := make([]Line, 0, n+m)
+		for _, l := range a {
+			lines = append(lines, Line{Content: l, Op: OpDelete})
+		}
+		for _, l := range b {
+			lines = append(lines, Line{Content: l, Op: OpInsert})
+		}
+		return lines
+	}
+
+	// v contains the furthest x reached for each diagonal k (k = x-y).
+	// Because k goes from -max to max, we use an offset to store
+	// the diagonals in the slice.
+	// trace contains a copy of the useful part of v after each step,
+	// so we can backtrack the path once we reached the end
+	maxSteps := n + m
+	offset := maxSteps
+	v := make([]int, 2*maxSteps+2)
+	trace := [][]int{}
+
+	d := 0
+search:
+	for ; d <= maxSteps; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			// We either move down (insertion) or right (deletion)
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			// we follow the diagonal (lines that are equal)
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	// We now backtrack to find the path we took, starting from the end
+	lines := make([]Line, 0, n+m)
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1] // value of v after step d-1, indexed by k+d-1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, Line{Content: a[x], Op: OpEqual})
+		}
+		if x == prevX {
+			y--
+			lines = append(lines, Line{Content: b[y], Op: OpInsert})
+		} else {
+			x--
+			lines = append(lines, Line{Content: a[x], Op: OpDelete})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		lines = append(lines, Line{Content: a[x], Op: OpEqual})
+	}
+
+	// The lines are in reverse order
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return groupChanges(lines)
+}
+
+// groupChanges makes sure that the deleted lines of a block of
+// changes are all before the inserted lines
+func groupChanges(lines []Line) []Line {
+	for start := 0; start < len(lines); {
+		if lines[start].Op == OpEqual {
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && lines[end].Op != OpEqual {
+			end++
+		}
+		block := make([]Line, 0, end-start)
+		for _, l := range lines[start:end] {
+			if l.Op == OpDelete {
+				block = append(block, l)
+			}
+		}
+		for _, l := range lines[start:end] {
+			if l.Op == OpInsert {
+				block = append(block, l)
+			}
+		}
+		copy(lines[start:end], block)
+		start = end
+	}
+	return lines
+}
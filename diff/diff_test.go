@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLines(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		content  string
+		expected []string
+	}{
+		{
+			desc:     "empty content should have no lines",
+			content:  "",
+			expected: []string{},
+		},
+		{
+			desc:     "newlines should be kept",
+			content:  "a\nb\n",
+			expected: []string{"a\n", "b\n"},
+		},
+		{
+			desc:     "last line may not have a newline",
+			content:  "a\nb",
+			expected: []string{"a\n", "b"},
+		},
+		{
+			desc:     "empty lines should be kept",
+			content:  "\n\n",
+			expected: []string{"\n", "\n"},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, SplitLines([]byte(tc.content)))
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		old      string
+		new      string
+		expected []Line
+	}{
+		{
+			desc: "same content should only have equal lines",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			expected: []Line{
+				{Content: "a\n", Op: OpEqual},
+				{Content: "b\n", Op: OpEqual},
+			},
+		},
+		{
+			desc: "new content should only have inserted lines",
+			old:  "",
+			new:  "a\nb\n",
+			expected: []Line{
+				{Content: "a\n", Op: OpInsert},
+				{Content: "b\n", Op: OpInsert},
+			},
+		},
+		{
+			desc: "removed content should only have deleted lines",
+			old:  "a\nb\n",
+			new:  "",
+			expected: []Line{
+				{Content: "a\n", Op: OpDelete},
+				{Content: "b\n", Op: OpDelete},
+			},
+		},
+		{
+			desc: "deleted lines should come before inserted lines",
+			old:  "a\nb\nc\nd\n",
+			new:  "a\nB\nC\nd\n",
+			expected: []Line{
+				{Content: "a\n", Op: OpEqual},
+				{Content: "b\n", Op: OpDelete},
+				{Content: "c\n", Op: OpDelete},
+				{Content: "B\n", Op: OpInsert},
+				{Content: "C\n", Op: OpInsert},
+				{Content: "d\n", Op: OpEqual},
+			},
+		},
+		{
+			desc: "adding a newline at the end of the file should change the last line",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			expected: []Line{
+				{Content: "a\n", Op: OpEqual},
+				{Content: "b", Op: OpDelete},
+				{Content: "b\n", Op: OpInsert},
+			},
+		},
+		{
+			desc: "changes should be found in the middle of the content",
+			old:  "a\nb\nc\nd\ne\n",
+			new:  "a\nc\nd\nx\ne\n",
+			expected: []Line{
+				{Content: "a\n", Op: OpEqual},
+				{Content: "b\n", Op: OpDelete},
+				{Content: "c\n", Op: OpEqual},
+				{Content: "d\n", Op: OpEqual},
+				{Content: "x\n", Op: OpInsert},
+				{Content: "e\n", Op: OpEqual},
+			},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, Lines([]byte(tc.old), []byte(tc.new)))
+		})
+	}
+}
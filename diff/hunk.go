@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContextLines is the default number of unchanged lines
+// displayed around the changes
+const DefaultContextLines = 3
+
+// maxSectionLength is the maximum length of the section heading
+// displayed in the hunk header (the same as git)
+const maxSectionLength = 80
+
+// Hunk represents a group of changes, alongside the unchanged lines
+// surrounding them
+type Hunk struct {
+	// Section contains the heading of the section the hunk is in
+	// (usually the signature of a function), if any
+	Section string
+	Lines   []Line
+	// OldStart contains the (1-based) number of the first line of the
+	// hunk in the old version. When OldLines is 0, this contains the
+	// number of the line after which the lines would be inserted
+	OldStart int
+	// OldLines contains the number of lines of the hunk in the old
+	// version
+	OldLines int
+	// NewStart contains the (1-based) number of the first line of the
+	// hunk in the new version. When NewLines is 0, this contains the
+	// number of the line after which the lines have been deleted
+	NewStart int
+	// NewLines contains the number of lines of the hunk in the new
+	// version
+	NewLines int
+}
+
+// Header returns the header of the hunk.
+// Ex: @@ -1,3 +1,4 @@ func main()
+func (h *Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// formatRange formats a range the same way git does
+func formatRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Hunks groups the changes of the provided diff into hunks, keeping
+// contextLines unchanged lines around each change.
+// Changes separated by less than 2*contextLines unchanged lines are
+// merged into the same hunk
+func Hunks(lines []Line, contextLines int) []Hunk {
+	if contextLines < 0 {
+		contextLines = 0
+	}
+
+	// We need the old version of the content to find the section
+	// of each hunk
+	oldLines := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l.Op != OpInsert {
+			oldLines = append(oldLines, l.Content)
+		}
+	}
+
+	hunks := []Hunk{}
+	for i := 0; i < len(lines); {
+		if lines[i].Op == OpEqual {
+			i++
+			continue
+		}
+
+		// We found a change, we now need to find where the hunk ends,
+		// which is after the last change that is close enough
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			// we skip the changes
+			for end < len(lines) && lines[end].Op != OpEqual {
+				end++
+			}
+			// we count the unchanged lines until the next change
+			next := end
+			for next < len(lines) && lines[next].Op == OpEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*contextLines {
+				break
+			}
+			end = next
+		}
+		end += contextLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		hunks = append(hunks, newHunk(lines, start, end, oldLines))
+		i = end
+	}
+	return hunks
+}
+
+// newHunk creates a hunk containing lines[start:end]
+func newHunk(lines []Line, start, end int, oldLines []string) Hunk {
+	// We first need to know the number of the first line of
+	// the hunk in both versions
+	oldStart, newStart := 1, 1
+	for _, l := range lines[:start] {
+		if l.Op != OpInsert {
+			oldStart++
+		}
+		if l.Op != OpDelete {
+			newStart++
+		}
+	}
+
+	h := Hunk{
+		Lines:    lines[start:end],
+		OldStart: oldStart,
+		NewStart: newStart,
+	}
+	for _, l := range h.Lines {
+		if l.Op != OpInsert {
+			h.OldLines++
+		}
+		if l.Op != OpDelete {
+			h.NewLines++
+		}
+	}
+	// Like git, an empty range refers to the line before
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	h.Section = findSection(oldLines, oldStart-1)
+	return h
+}
+
+// findSection returns the closest line before the given line (0-based)
+// that looks like the beginning of a section. The default heuristic
+// of git is used: a section starts with a letter, a _, or a $
+func findSection(oldLines []string, line int) string {
+	for i := line - 1; i >= 0; i-- {
+		l := oldLines[i]
+		if l == "" {
+			continue
+		}
+		c := l[0]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$' {
+			if len(l) > maxSectionLength {
+				l = l[:maxSectionLength]
+			}
+			return strings.TrimRight(l, " \t\r\n\f\v")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numberedLines returns a content made of the numbers from 1 to n,
+// one per line. The lines in replaced are replaced by "x"
+func numberedLines(n int, replaced ...int) []byte {
+	b := &strings.Builder{}
+	for i := 1; i <= n; i++ {
+		line := fmt.Sprintf("%d", i)
+		for _, r := range replaced {
+			if r == i {
+				line = "x"
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	return []byte(b.String())
+}
+
+func TestHunks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc            string
+		old             []byte
+		new             []byte
+		contextLines    int
+		expectedHeaders []string
+	}{
+		{
+			desc:            "close changes should be in the same hunk",
+			old:             numberedLines(20),
+			new:             numberedLines(20, 5, 12),
+			contextLines:    3,
+			expectedHeaders: []string{"@@ -2,14 +2,14 @@"},
+		},
+		{
+			desc:            "far away changes should be in different hunks",
+			old:             numberedLines(20),
+			new:             numberedLines(20, 5, 13),
+			contextLines:    3,
+			expectedHeaders: []string{"@@ -2,7 +2,7 @@", "@@ -10,7 +10,7 @@"},
+		},
+		{
+			desc:            "single line ranges should omit the count",
+			old:             numberedLines(20),
+			new:             numberedLines(20, 5),
+			contextLines:    0,
+			expectedHeaders: []string{"@@ -5 +5 @@"},
+		},
+		{
+			desc:            "deleted line should point to the previous line",
+			old:             []byte("1\n2\n3\n"),
+			new:             []byte("1\n3\n"),
+			contextLines:    0,
+			expectedHeaders: []string{"@@ -2 +1,0 @@"},
+		},
+		{
+			desc:            "inserted line should point to the previous line",
+			old:             []byte("1\n3\n"),
+			new:             []byte("1\n2\n3\n"),
+			contextLines:    0,
+			expectedHeaders: []string{"@@ -1,0 +2 @@"},
+		},
+		{
+			desc:            "new content should start at 0",
+			old:             []byte(""),
+			new:             []byte("1\n2\n"),
+			contextLines:    3,
+			expectedHeaders: []string{"@@ -0,0 +1,2 @@"},
+		},
+		{
+			desc:            "same content should have no hunks",
+			old:             numberedLines(5),
+			new:             numberedLines(5),
+			contextLines:    3,
+			expectedHeaders: []string{},
+		},
+		{
+			desc:            "section should contain the closest heading",
+			old:             []byte("func main() {\n\t1\n\t2\n\t3\n\t4\n\t5\n}\n"),
+			new:             []byte("func main() {\n\t1\n\t2\n\t3\n\t4\n\tx\n}\n"),
+			contextLines:    1,
+			expectedHeaders: []string{"@@ -5,3 +5,3 @@ func main() {"},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			hunks := Hunks(Lines(tc.old, tc.new), tc.contextLines)
+			require.Len(t, hunks, len(tc.expectedHeaders))
+			for i, h := range hunks {
+				assert.Equal(t, tc.expectedHeaders[i], h.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// binarySniffLen is the number of bytes looked at to find out if a
+// content is binary (the same as git)
+const binarySniffLen = 8000
+
+// shortOidLen is the number of chars used to display an oid
+const shortOidLen = 7
+
+// File represents one side of a FilePatch
+type File struct {
+	Path string
+	ID   ginternals.Oid
+	Mode object.TreeObjectMode
+}
+
+// FilePatch represents the changes made to a single file
+type FilePatch struct {
+	// From contains the old version of the file. It's nil if the file
+	// has been created
+	From *File
+	// To contains the new version of the file. It's nil if the file
+	// has been deleted
+	To *File
+	// Hunks contains the changes made to the content of the file.
+	// It's always empty for binary files
+	Hunks []Hunk
+	// IsBinary is set if any of the versions of the file is binary
+	IsBinary bool
+	// Submodule contains the list of commits that changed in a
+	// submodule. When set, the patch is displayed as a summary of the
+	// submodule instead of a "Subproject commit" diff
+	Submodule *SubmoduleLog
+	// Similarity contains how similar (in percent) the 2 versions of
+	// the file are. It's only set for renames and copies
+	Similarity int
+	// IsCopy is set if To has been copied from From, which still
+	// exists. It's only used when the paths of From and To differ
+	IsCopy bool
+	// BinaryPatch contains the data needed to apply the changes made
+	// to a binary file. When nil, the patch only reports that the
+	// binary files differ
+	BinaryPatch *BinaryPatch
+}
+
+// FilePatchOptions represents the options that can be used to create
+// a FilePatch
+type FilePatchOptions struct {
+	// ContextLines is the number of unchanged lines displayed
+	// around the changes
+	ContextLines int
+	// ForceBinary treats the file as binary, regardless of its
+	// content. This is the equivalent of the -diff attribute
+	ForceBinary bool
+	// ForceText treats the file as text, regardless of its content.
+	// This is the equivalent of the diff attribute, or of --text
+	ForceText bool
+	// BinaryPatch generates the data needed to apply the changes made
+	// to the binary files, instead of only reporting that they differ.
+	// This is the equivalent of --binary
+	BinaryPatch bool
+}
+
+// NewFilePatch returns the patch needed to go from the "from"
+// version of a file to the "to" version.
+// from should be nil if the file has been created, and to should be
+// nil if the file has been deleted. The content of a missing file
+// is ignored.
+func NewFilePatch(from *File, fromContent []byte, to *File, toContent []byte, contextLines int) *FilePatch {
+	return NewFilePatchWithOptions(from, fromContent, to, toContent, FilePatchOptions{
+		ContextLines: contextLines,
+	})
+}
+
+// NewFilePatchWithOptions works like NewFilePatch but uses the
+// provided options
+func NewFilePatchWithOptions(from *File, fromContent []byte, to *File, toContent []byte, opts FilePatchOptions) *FilePatch {
+	p := &FilePatch{
+		From: from,
+		To:   to,
+	}
+	if from == nil {
+		fromContent = nil
+	}
+	if to == nil {
+		toContent = nil
+	}
+
+	isBinary := opts.ForceBinary || IsBinary(fromContent) || IsBinary(toContent)
+	if isBinary && !opts.ForceText {
+		p.IsBinary = !bytes.Equal(fromContent, toContent)
+		if p.IsBinary && opts.BinaryPatch {
+			p.BinaryPatch = NewBinaryPatch(fromContent, toContent)
+		}
+		return p
+	}
+	p.Hunks = Hunks(Lines(fromContent, toContent), opts.ContextLines)
+	return p
+}
+
+// IsBinary returns whether the provided content looks like binary
+// data. Like git, a content is considered binary if it contains a
+// NUL byte in its first 8000 bytes
+func IsBinary(content []byte) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// Path returns the path of the file. The new path is returned
+// unless the file has been deleted
+func (p *FilePatch) Path() string {
+	if p.To != nil {
+		return p.To.Path
+	}
+	return p.From.Path
+}
+
+// Change returns the type of change made to the file
+func (p *FilePatch) Change() ChangeType {
+	switch {
+	case p.From == nil:
+		return ChangeAdded
+	case p.To == nil:
+		return ChangeDeleted
+	case p.From.Path != p.To.Path && p.IsCopy:
+		return ChangeCopied
+	case p.From.Path != p.To.Path:
+		return ChangeRenamed
+	default:
+		return ChangeModified
+	}
+}
+
+// WriteTo writes the patch to w using the git format
+func (p *FilePatch) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, p.String())
+	return int64(n), err
+}
+
+// String returns the patch using the git format
+func (p *FilePatch) String() string {
+	if p.Submodule != nil {
+		return p.submoduleString()
+	}
+	return p.format(writeHunkLines)
+}
+
+// WordDiff returns the patch using the format of
+// git diff --word-diff=plain. The deleted words are displayed
+// as [-word-] and the inserted words as {+word+}
+func (p *FilePatch) WordDiff() string {
+	if p.Submodule != nil {
+		return p.submoduleString()
+	}
+	return p.format(writeHunkWords)
+}
+
+// format returns the patch using the git format, using writeHunk
+// to write the content of each hunk
+func (p *FilePatch) format(writeHunk func(b *strings.Builder, h *Hunk)) string {
+	fromPath := p.Path()
+	toPath := p.Path()
+	if p.From != nil {
+		fromPath = p.From.Path
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "diff --git %s %s\n", quotePath("a/"+fromPath), quotePath("b/"+toPath))
+
+	switch {
+	case p.From == nil:
+		fmt.Fprintf(b, "new file mode %06o\n", p.To.Mode)
+		fmt.Fprintf(b, "index %s..%s\n", p.abbrev(ginternals.NullOid), p.abbrev(p.To.ID))
+	case p.To == nil:
+		fmt.Fprintf(b, "deleted file mode %06o\n", p.From.Mode)
+		fmt.Fprintf(b, "index %s..%s\n", p.abbrev(p.From.ID), p.abbrev(ginternals.NullOid))
+	default:
+		if p.From.Mode != p.To.Mode {
+			fmt.Fprintf(b, "old mode %06o\n", p.From.Mode)
+			fmt.Fprintf(b, "new mode %06o\n", p.To.Mode)
+		}
+		if change := p.Change(); change == ChangeRenamed || change == ChangeCopied {
+			op := "rename"
+			if change == ChangeCopied {
+				op = "copy"
+			}
+			fmt.Fprintf(b, "similarity index %d%%\n", p.Similarity)
+			fmt.Fprintf(b, "%s from %s\n", op, quotePath(fromPath))
+			fmt.Fprintf(b, "%s to %s\n", op, quotePath(toPath))
+		}
+		if p.From.ID != p.To.ID {
+			fmt.Fprintf(b, "index %s..%s", p.abbrev(p.From.ID), p.abbrev(p.To.ID))
+			if p.From.Mode == p.To.Mode {
+				fmt.Fprintf(b, " %06o", p.To.Mode)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	oldName := "/dev/null"
+	if p.From != nil {
+		oldName = quotePath("a/" + fromPath)
+	}
+	newName := "/dev/null"
+	if p.To != nil {
+		newName = quotePath("b/" + toPath)
+	}
+
+	if p.IsBinary && p.BinaryPatch != nil {
+		b.WriteString(p.BinaryPatch.String())
+		return b.String()
+	}
+	if p.IsBinary {
+		fmt.Fprintf(b, "Binary files %s and %s differ\n", oldName, newName)
+		return b.String()
+	}
+	if len(p.Hunks) == 0 {
+		return b.String()
+	}
+
+	fmt.Fprintf(b, "--- %s\n", oldName)
+	fmt.Fprintf(b, "+++ %s\n", newName)
+	for i := range p.Hunks {
+		h := &p.Hunks[i]
+		b.WriteString(h.Header())
+		b.WriteString("\n")
+		writeHunk(b, h)
+	}
+	return b.String()
+}
+
+// writeHunkLines writes the lines of the hunk using the unified
+// format
+func writeHunkLines(b *strings.Builder, h *Hunk) {
+	for _, l := range h.Lines {
+		switch l.Op {
+		case OpEqual:
+			b.WriteString(" ")
+		case OpDelete:
+			b.WriteString("-")
+		case OpInsert:
+			b.WriteString("+")
+		}
+		b.WriteString(l.Content)
+		if !l.HasNewline() {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// writeHunkWords writes the word diff of the hunk.
+// Like git, the changes spanning multiple lines are split so each
+// line has its own markers
+func writeHunkWords(b *strings.Builder, h *Hunk) {
+	content := &strings.Builder{}
+	for _, w := range h.Words() {
+		if w.Op == OpEqual {
+			content.WriteString(w.Content)
+			continue
+		}
+		prefix, suffix := "[-", "-]"
+		if w.Op == OpInsert {
+			prefix, suffix = "{+", "+}"
+		}
+		for i, part := range strings.Split(w.Content, "\n") {
+			if i > 0 {
+				content.WriteString("\n")
+			}
+			if part != "" {
+				content.WriteString(prefix + part + suffix)
+			}
+		}
+	}
+	b.WriteString(content.String())
+	if !strings.HasSuffix(content.String(), "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// abbrev returns the version of the oid displayed in the index line
+// of the patch. Like git, the full oids are used with binary patches
+func (p *FilePatch) abbrev(oid ginternals.Oid) string {
+	if p.BinaryPatch != nil {
+		return oid.String()
+	}
+	return shortOid(oid)
+}
+
+// shortOid returns the abbreviated version of an oid
+func shortOid(oid ginternals.Oid) string {
+	return oid.String()[:shortOidLen]
+}
+
+// quotePath quotes a path the same way git does when it contains
+// special characters (non-ascii, control characters, quotes, and
+// backslashes)
+func quotePath(p string) string {
+	needsQuote := false
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			needsQuote = true
+			break
+		}
+	}
+	if !needsQuote {
+		return p
+	}
+
+	b := &strings.Builder{}
+	b.WriteByte('"')
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(b, `\%03o`, c)
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
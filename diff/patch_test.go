@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFile returns a File containing the provided content
+func newFile(path string, mode object.TreeObjectMode, content string) *File {
+	return &File{
+		Path: path,
+		Mode: mode,
+		ID:   object.New(object.TypeBlob, []byte(content)).ID(),
+	}
+}
+
+func TestFilePatchString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc        string
+		from        *File
+		fromContent string
+		to          *File
+		toContent   string
+		similarity  int
+		isCopy      bool
+		expected    string
+	}{
+		{
+			desc:      "new file",
+			to:        newFile("README.md", object.ModeFile, "a\nb\n"),
+			toContent: "a\nb\n",
+			expected: "diff --git a/README.md b/README.md\n" +
+				"new file mode 100644\n" +
+				"index 0000000..422c2b7\n" +
+				"--- /dev/null\n" +
+				"+++ b/README.md\n" +
+				"@@ -0,0 +1,2 @@\n" +
+				"+a\n" +
+				"+b\n",
+		},
+		{
+			desc:        "deleted file",
+			from:        newFile("README.md", object.ModeFile, "a\nb\n"),
+			fromContent: "a\nb\n",
+			expected: "diff --git a/README.md b/README.md\n" +
+				"deleted file mode 100644\n" +
+				"index 422c2b7..0000000\n" +
+				"--- a/README.md\n" +
+				"+++ /dev/null\n" +
+				"@@ -1,2 +0,0 @@\n" +
+				"-a\n" +
+				"-b\n",
+		},
+		{
+			desc:        "mode change only",
+			from:        newFile("run.sh", object.ModeFile, "a\n"),
+			fromContent: "a\n",
+			to:          newFile("run.sh", object.ModeExecutable, "a\n"),
+			toContent:   "a\n",
+			expected: "diff --git a/run.sh b/run.sh\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n",
+		},
+		{
+			desc:        "missing newline at the end of the file",
+			from:        newFile("README.md", object.ModeFile, "a\nb"),
+			fromContent: "a\nb",
+			to:          newFile("README.md", object.ModeFile, "a\nb\n"),
+			toContent:   "a\nb\n",
+			expected: "diff --git a/README.md b/README.md\n" +
+				"index 0a207c0..422c2b7 100644\n" +
+				"--- a/README.md\n" +
+				"+++ b/README.md\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" a\n" +
+				"-b\n" +
+				"\\ No newline at end of file\n" +
+				"+b\n",
+		},
+		{
+			desc:        "binary file",
+			from:        newFile("bin", object.ModeFile, "a\x00"),
+			fromContent: "a\x00",
+			to:          newFile("bin", object.ModeFile, "b\x00"),
+			toContent:   "b\x00",
+			expected: "diff --git a/bin b/bin\n" +
+				"index 90802fe..28eacf2 100644\n" +
+				"Binary files a/bin and b/bin differ\n",
+		},
+		{
+			desc:        "renamed file",
+			from:        newFile("a", object.ModeFile, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"),
+			fromContent: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n",
+			to:          newFile("c", object.ModeExecutable, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n21\n"),
+			toContent:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n21\n",
+			similarity:  94,
+			expected: "diff --git a/a b/c\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n" +
+				"similarity index 94%\n" +
+				"rename from a\n" +
+				"rename to c\n" +
+				"index 0ff3bbb..d4de868\n" +
+				"--- a/a\n" +
+				"+++ b/c\n" +
+				"@@ -18,3 +18,4 @@\n" +
+				" 18\n" +
+				" 19\n" +
+				" 20\n" +
+				"+21\n",
+		},
+		{
+			desc:        "copied file without changes",
+			from:        newFile("a", object.ModeFile, "a\n"),
+			fromContent: "a\n",
+			to:          newFile("b", object.ModeFile, "a\n"),
+			toContent:   "a\n",
+			similarity:  100,
+			isCopy:      true,
+			expected: "diff --git a/a b/b\n" +
+				"similarity index 100%\n" +
+				"copy from a\n" +
+				"copy to b\n",
+		},
+		{
+			desc:        "special chars in path should be quoted",
+			from:        newFile("été.txt", object.ModeFile, "a\n"),
+			fromContent: "a\n",
+			to:          newFile("été.txt", object.ModeFile, "b\n"),
+			toContent:   "b\n",
+			expected: "diff --git \"a/\\303\\251t\\303\\251.txt\" \"b/\\303\\251t\\303\\251.txt\"\n" +
+				"index 7898192..6178079 100644\n" +
+				"--- \"a/\\303\\251t\\303\\251.txt\"\n" +
+				"+++ \"b/\\303\\251t\\303\\251.txt\"\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			p := NewFilePatch(tc.from, []byte(tc.fromContent), tc.to, []byte(tc.toContent), DefaultContextLines)
+			p.Similarity = tc.similarity
+			p.IsCopy = tc.isCopy
+			assert.Equal(t, tc.expected, p.String())
+		})
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, IsBinary([]byte("hello\nworld\n")))
+	assert.True(t, IsBinary([]byte("hello\x00world")))
+	// Only the beginning of the content is checked
+	assert.False(t, IsBinary(append([]byte(strings.Repeat("a", 8000)), 0)))
+}
@@ -0,0 +1,112 @@
+package diff
+
+import "bytes"
+
+// DefaultRenameThreshold is the default minimum similarity (in
+// percent) between 2 files for them to be considered a rename or a
+// copy (the same as git)
+const DefaultRenameThreshold = 50
+
+// DefaultRenameLimit is the default maximum number of files on each
+// side of a diff for which the inexact renames are looked for (the
+// same as git's diff.renameLimit)
+const DefaultRenameLimit = 1000
+
+// maxChunkLen is the maximum length of the chunks used to compute
+// the similarity of 2 contents (the same as git)
+const maxChunkLen = 64
+
+// ChangeType represents the type of change made to a file
+type ChangeType int8
+
+const (
+	// ChangeModified represents a file whose content or mode changed
+	ChangeModified ChangeType = iota
+	// ChangeAdded represents a file that has been created
+	ChangeAdded
+	// ChangeDeleted represents a file that has been removed
+	ChangeDeleted
+	// ChangeRenamed represents a file that has been moved, and
+	// possibly modified
+	ChangeRenamed
+	// ChangeCopied represents a file that has been created from
+	// another file that still exists
+	ChangeCopied
+)
+
+// String returns the letter used by git to represent the change
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "A"
+	case ChangeDeleted:
+		return "D"
+	case ChangeRenamed:
+		return "R"
+	case ChangeCopied:
+		return "C"
+	default:
+		return "M"
+	}
+}
+
+// Similarity returns how similar 2 contents are, in percent.
+// Like git, the contents are split into lines (or chunks of 64 bytes
+// for long lines), and the score is the number of bytes of a that
+// are found in b, divided by the size of the biggest content
+func Similarity(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return 100
+	}
+	maxLen := len(a)
+	if len(b) > maxLen {
+		maxLen = len(b)
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	chunksA := chunkSizes(a)
+	chunksB := chunkSizes(b)
+	copied := 0
+	for chunk, sizeA := range chunksA {
+		sizeB := chunksB[chunk]
+		if sizeB < sizeA {
+			sizeA = sizeB
+		}
+		copied += sizeA
+	}
+	return copied * 100 / maxLen
+}
+
+// CanBeSimilar returns whether 2 contents of the given sizes can
+// reach the given similarity. This allows skipping the pairs of files
+// that are too different without having to read them
+func CanBeSimilar(sizeA, sizeB, threshold int) bool {
+	minSize, maxSize := sizeA, sizeB
+	if minSize > maxSize {
+		minSize, maxSize = maxSize, minSize
+	}
+	if maxSize == 0 {
+		return true
+	}
+	return minSize*100 >= threshold*maxSize
+}
+
+// chunkSizes returns the total number of bytes of each distinct
+// chunk of the content
+func chunkSizes(content []byte) map[string]int {
+	chunks := map[string]int{}
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 || end > maxChunkLen {
+			end = maxChunkLen
+			if end > len(content) {
+				end = len(content)
+			}
+		}
+		chunks[string(content[:end])] += end
+		content = content[end:]
+	}
+	return chunks
+}
@@ -0,0 +1,69 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		a        string
+		b        string
+		expected int
+	}{
+		{
+			desc:     "same content",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: 100,
+		},
+		{
+			desc:     "empty content",
+			a:        "a\nb\n",
+			b:        "",
+			expected: 0,
+		},
+		{
+			desc:     "nothing in common",
+			a:        "a\nb\n",
+			b:        "c\nd\n",
+			expected: 0,
+		},
+		{
+			desc:     "added line",
+			a:        "1\n2\n3\n",
+			b:        "1\n2\n3\n4\n",
+			expected: 75,
+		},
+		{
+			desc:     "long lines are split in chunks",
+			a:        strings.Repeat("a", 128),
+			b:        strings.Repeat("a", 64) + strings.Repeat("b", 64),
+			expected: 50,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, Similarity([]byte(tc.a), []byte(tc.b)))
+			assert.Equal(t, tc.expected, Similarity([]byte(tc.b), []byte(tc.a)), "the score should be symmetric")
+		})
+	}
+}
+
+func TestCanBeSimilar(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, CanBeSimilar(0, 0, 50))
+	assert.True(t, CanBeSimilar(50, 100, 50))
+	assert.False(t, CanBeSimilar(49, 100, 50))
+	assert.False(t, CanBeSimilar(100, 49, 50))
+}
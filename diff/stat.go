@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileStat represents the number of lines changed in a file
+type FileStat struct {
+	// OldPath contains the previous path of the file. It's only set
+	// for renames and copies
+	OldPath string
+	Path    string
+	// Insertions contains the number of lines that have been added
+	Insertions int
+	// Deletions contains the number of lines that have been removed
+	Deletions int
+	// IsBinary is set if the file is binary, in which case no lines
+	// are counted
+	IsBinary bool
+}
+
+// Stat returns the number of lines changed by the patch
+func (p *FilePatch) Stat() FileStat {
+	s := FileStat{
+		Path:     p.Path(),
+		IsBinary: p.IsBinary,
+	}
+	if c := p.Change(); c == ChangeRenamed || c == ChangeCopied {
+		s.OldPath = p.From.Path
+	}
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			switch l.Op {
+			case OpInsert:
+				s.Insertions++
+			case OpDelete:
+				s.Deletions++
+			}
+		}
+	}
+	return s
+}
+
+// DisplayPath returns the path of the file as displayed by git.
+// Renames and copies are displayed as "old => new", with the common
+// directories factored out (ex. "dir/{old => new}")
+func (s FileStat) DisplayPath() string {
+	if s.OldPath == "" {
+		return quotePath(s.Path)
+	}
+	a, b := s.OldPath, s.Path
+	if quotePath(a) != a || quotePath(b) != b {
+		return quotePath(a) + " => " + quotePath(b)
+	}
+
+	// The common prefix has to end with a slash
+	prefixLen := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			prefixLen = i + 1
+		}
+	}
+
+	// The common suffix has to start with a slash. If there's a
+	// common prefix, we let the loop go one char into it so it can
+	// see its slash.
+	// charAt returns 0 past the end of the path, which makes
+	// both ends of the paths always match
+	charAt := func(s string, i int) byte {
+		if i == len(s) {
+			return 0
+		}
+		return s[i]
+	}
+	adjust := 0
+	if prefixLen > 0 {
+		adjust = 1
+	}
+	suffixLen := 0
+	for i, j := len(a), len(b); i >= prefixLen-adjust && j >= prefixLen-adjust && charAt(a, i) == charAt(b, j); i, j = i-1, j-1 {
+		if charAt(a, i) == '/' {
+			suffixLen = len(a) - i
+		}
+	}
+
+	if prefixLen+suffixLen == 0 {
+		return a + " => " + b
+	}
+	aMid := ""
+	if end := len(a) - suffixLen; end > prefixLen {
+		aMid = a[prefixLen:end]
+	}
+	bMid := ""
+	if end := len(b) - suffixLen; end > prefixLen {
+		bMid = b[prefixLen:end]
+	}
+	return fmt.Sprintf("%s{%s => %s}%s", a[:prefixLen], aMid, bMid, a[len(a)-suffixLen:])
+}
+
+// Stats represents the number of lines changed by a set of patches
+type Stats struct {
+	Files []FileStat
+	// Insertions contains the total number of lines that have
+	// been added
+	Insertions int
+	// Deletions contains the total number of lines that have
+	// been removed
+	Deletions int
+}
+
+// NewStats returns the number of lines changed by the given patches
+func NewStats(patches []*FilePatch) *Stats {
+	s := &Stats{
+		Files: make([]FileStat, 0, len(patches)),
+	}
+	for _, p := range patches {
+		fs := p.Stat()
+		s.Files = append(s.Files, fs)
+		s.Insertions += fs.Insertions
+		s.Deletions += fs.Deletions
+	}
+	return s
+}
+
+// Summary returns the summary line of git diff --stat
+// (ex. " 2 files changed, 3 insertions(+), 1 deletion(-)").
+// Like git, the insertions or the deletions are omitted when there
+// are none, unless both are 0
+func (s *Stats) Summary() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, " %d %s changed", len(s.Files), plural(len(s.Files), "file", "files"))
+	if s.Insertions > 0 || s.Deletions == 0 {
+		fmt.Fprintf(b, ", %d %s(+)", s.Insertions, plural(s.Insertions, "insertion", "insertions"))
+	}
+	if s.Deletions > 0 || s.Insertions == 0 {
+		fmt.Fprintf(b, ", %d %s(-)", s.Deletions, plural(s.Deletions, "deletion", "deletions"))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// Numstat returns the stats using the format of git diff --numstat:
+// one line per file containing the number of insertions, the number
+// of deletions, and the path of the file, separated by tabs.
+// The counts of the binary files are replaced by "-"
+func (s *Stats) Numstat() string {
+	b := &strings.Builder{}
+	for _, f := range s.Files {
+		if f.IsBinary {
+			fmt.Fprintf(b, "-\t-\t%s\n", f.DisplayPath())
+			continue
+		}
+		fmt.Fprintf(b, "%d\t%d\t%s\n", f.Insertions, f.Deletions, f.DisplayPath())
+	}
+	return b.String()
+}
+
+// plural returns singular if n is 1, plural otherwise
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStatDisplayPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		oldPath  string
+		path     string
+		expected string
+	}{
+		{
+			desc:     "no rename",
+			path:     "dir/file.go",
+			expected: "dir/file.go",
+		},
+		{
+			desc:     "no common dir",
+			oldPath:  "file.go",
+			path:     "dir/file.go",
+			expected: "file.go => dir/file.go",
+		},
+		{
+			desc:     "common prefix",
+			oldPath:  "cmd/agit/main.go",
+			path:     "cmd/git-go/main.go",
+			expected: "cmd/{agit => git-go}/main.go",
+		},
+		{
+			desc:     "common dir",
+			oldPath:  "dir/a.go",
+			path:     "dir/b.go",
+			expected: "dir/{a.go => b.go}",
+		},
+		{
+			desc:     "moved to a subdir",
+			oldPath:  "dir/a.go",
+			path:     "dir/sub/a.go",
+			expected: "dir/{ => sub}/a.go",
+		},
+		{
+			desc:     "special chars",
+			oldPath:  "a\tb",
+			path:     "c",
+			expected: `"a\tb" => c`,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			s := FileStat{OldPath: tc.oldPath, Path: tc.path}
+			assert.Equal(t, tc.expected, s.DisplayPath())
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	modified := NewFilePatch(
+		newFile("a", object.ModeFile, "a\nb\nc\n"), []byte("a\nb\nc\n"),
+		newFile("a", object.ModeFile, "a\nB\nc\nd\n"), []byte("a\nB\nc\nd\n"),
+		DefaultContextLines,
+	)
+	added := NewFilePatch(nil, nil, newFile("b", object.ModeFile, "b\n"), []byte("b\n"), DefaultContextLines)
+	binary := NewFilePatch(
+		newFile("c", object.ModeFile, "c\x00"), []byte("c\x00"),
+		newFile("c", object.ModeFile, "d\x00"), []byte("d\x00"),
+		DefaultContextLines,
+	)
+	deleted := NewFilePatch(newFile("d", object.ModeFile, "d\n"), []byte("d\n"), nil, nil, DefaultContextLines)
+	renamed := NewFilePatch(
+		newFile("e", object.ModeFile, "e\n"), []byte("e\n"),
+		newFile("f", object.ModeFile, "e\n"), []byte("e\n"),
+		DefaultContextLines,
+	)
+
+	testCases := []struct {
+		desc            string
+		patches         []*FilePatch
+		expectedSummary string
+		expectedNumstat string
+	}{
+		{
+			desc:            "insertions and deletions",
+			patches:         []*FilePatch{modified, added, binary},
+			expectedSummary: " 3 files changed, 3 insertions(+), 1 deletion(-)\n",
+			expectedNumstat: "2\t1\ta\n1\t0\tb\n-\t-\tc\n",
+		},
+		{
+			desc:            "insertions only",
+			patches:         []*FilePatch{added},
+			expectedSummary: " 1 file changed, 1 insertion(+)\n",
+			expectedNumstat: "1\t0\tb\n",
+		},
+		{
+			desc:            "deletions only",
+			patches:         []*FilePatch{deleted},
+			expectedSummary: " 1 file changed, 1 deletion(-)\n",
+			expectedNumstat: "0\t1\td\n",
+		},
+		{
+			desc:            "no changes",
+			patches:         []*FilePatch{renamed},
+			expectedSummary: " 1 file changed, 0 insertions(+), 0 deletions(-)\n",
+			expectedNumstat: "0\t0\te => f\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			s := NewStats(tc.patches)
+			assert.Equal(t, tc.expectedSummary, s.Summary())
+			assert.Equal(t, tc.expectedNumstat, s.Numstat())
+		})
+	}
+}
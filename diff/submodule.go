@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+)
+
+// SubmoduleLog contains the summary of the changes made to a
+// submodule, used to display the changes as a list of commits
+// instead of a "Subproject commit" patch.
+// This is the format used by git diff --submodule=log
+type SubmoduleLog struct {
+	// Message contains the reason why the commits are not listed
+	// (ex. "new submodule", "commits not present"). It's empty when
+	// the commits have been listed
+	Message string
+	// FastForward is set when the new commit is a descendant of the
+	// old one
+	FastForward bool
+	// Rewind is set when the new commit is an ancestor of the old one
+	Rewind bool
+	// Commits contains the commits that are only reachable from one
+	// side of the change, most recent first
+	Commits []SubmoduleCommit
+}
+
+// SubmoduleCommit represents a commit of a submodule that has been
+// added or removed
+type SubmoduleCommit struct {
+	// Subject contains the first line of the message of the commit
+	Subject string
+	// Removed is set if the commit is only reachable from the old
+	// commit of the submodule
+	Removed bool
+}
+
+// submoduleString returns the patch using the format of
+// git diff --submodule=log
+func (p *FilePatch) submoduleString() string {
+	path := p.Path()
+	if p.From != nil {
+		path = p.From.Path
+	}
+	from, to := shortOid(ginternals.NullOid), shortOid(ginternals.NullOid)
+	if p.From != nil {
+		from = shortOid(p.From.ID)
+	}
+	if p.To != nil {
+		to = shortOid(p.To.ID)
+	}
+
+	log := p.Submodule
+	b := &strings.Builder{}
+	separator := "..."
+	if log.FastForward || log.Rewind {
+		separator = ".."
+	}
+	fmt.Fprintf(b, "Submodule %s %s%s%s", path, from, separator, to)
+	switch {
+	case log.Message != "":
+		fmt.Fprintf(b, " (%s)\n", log.Message)
+		return b.String()
+	case log.Rewind:
+		b.WriteString(" (rewind):\n")
+	default:
+		b.WriteString(":\n")
+	}
+
+	for _, c := range log.Commits {
+		marker := ">"
+		if c.Removed {
+			marker = "<"
+		}
+		fmt.Fprintf(b, "  %s %s\n", marker, c.Subject)
+	}
+	return b.String()
+}
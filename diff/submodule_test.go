@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePatchStringSubmodule(t *testing.T) {
+	t.Parallel()
+
+	// newGitlink returns a File representing a submodule
+	newGitlink := func(t *testing.T, sha string) *File {
+		t.Helper()
+		oid, err := ginternals.NewOidFromStr(sha)
+		require.NoError(t, err)
+		return &File{Path: "sub", ID: oid, Mode: object.ModeGitLink}
+	}
+
+	// Generated using git diff --submodule=log
+	testCases := []struct {
+		desc     string
+		from     string
+		to       string
+		log      *SubmoduleLog
+		expected string
+	}{
+		{
+			desc: "fast-forward",
+			from: "811a1f17df9944c32dbb8970f81393adc5171820",
+			to:   "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+			log: &SubmoduleLog{
+				FastForward: true,
+				Commits: []SubmoduleCommit{
+					{Subject: "sub commit 3"},
+					{Subject: "sub commit 2"},
+				},
+			},
+			expected: "Submodule sub 811a1f1..0348308:\n" +
+				"  > sub commit 3\n" +
+				"  > sub commit 2\n",
+		},
+		{
+			desc: "rewind",
+			from: "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+			to:   "811a1f17df9944c32dbb8970f81393adc5171820",
+			log: &SubmoduleLog{
+				Rewind: true,
+				Commits: []SubmoduleCommit{
+					{Subject: "sub commit 3", Removed: true},
+					{Subject: "sub commit 2", Removed: true},
+				},
+			},
+			expected: "Submodule sub 0348308..811a1f1 (rewind):\n" +
+				"  < sub commit 3\n" +
+				"  < sub commit 2\n",
+		},
+		{
+			desc: "diverged",
+			from: "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+			to:   "f5c3f3e2c8e8e3f4e09c0e7a9f16d0b5a2f1b6a1",
+			log: &SubmoduleLog{
+				Commits: []SubmoduleCommit{
+					{Subject: "side commit"},
+					{Subject: "sub commit 3", Removed: true},
+					{Subject: "sub commit 2", Removed: true},
+				},
+			},
+			expected: "Submodule sub 0348308...f5c3f3e:\n" +
+				"  > side commit\n" +
+				"  < sub commit 3\n" +
+				"  < sub commit 2\n",
+		},
+		{
+			desc:     "new submodule",
+			to:       "f5c3f3e2c8e8e3f4e09c0e7a9f16d0b5a2f1b6a1",
+			log:      &SubmoduleLog{Message: "new submodule"},
+			expected: "Submodule sub 0000000...f5c3f3e (new submodule)\n",
+		},
+		{
+			desc:     "deleted submodule",
+			from:     "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+			log:      &SubmoduleLog{Message: "submodule deleted"},
+			expected: "Submodule sub 0348308...0000000 (submodule deleted)\n",
+		},
+		{
+			desc:     "commits not present",
+			from:     "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+			to:       "f5c3f3e2c8e8e3f4e09c0e7a9f16d0b5a2f1b6a1",
+			log:      &SubmoduleLog{Message: "commits not present"},
+			expected: "Submodule sub 0348308...f5c3f3e (commits not present)\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			p := &FilePatch{Submodule: tc.log}
+			if tc.from != "" {
+				p.From = newGitlink(t, tc.from)
+			}
+			if tc.to != "" {
+				p.To = newGitlink(t, tc.to)
+			}
+			assert.Equal(t, tc.expected, p.String())
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word represents a piece of text of a word diff
+type Word struct {
+	// Content contains the text. It may contain multiple words, as
+	// well as the spaces between them
+	Content string
+	Op      Operation
+}
+
+// Range represents a range of bytes of a line
+type Range struct {
+	// Start contains the offset of the first byte of the range
+	Start int
+	// End contains the offset of the byte following the range
+	End int
+}
+
+// IntralineChange represents the changes made to a line that has
+// been modified
+type IntralineChange struct {
+	// Old contains the index, in Hunk.Lines, of the old version of
+	// the line
+	Old int
+	// New contains the index, in Hunk.Lines, of the new version of
+	// the line
+	New int
+	// Deleted contains the ranges of the old version of the line
+	// that have been removed
+	Deleted []Range
+	// Inserted contains the ranges of the new version of the line
+	// that have been added
+	Inserted []Range
+}
+
+// token represents a word (or a separator) of a content
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+// Words returns the word by word differences of the hunk, using the
+// same rules as git diff --word-diff: words are sequences of
+// non-whitespace characters, and the unchanged text is taken from
+// the new version of the content
+func (h *Hunk) Words() []Word {
+	oldText, newText := h.contents()
+	oldWords := splitWords(oldText)
+	newWords := splitWords(newText)
+
+	words := []Word{}
+	add := func(content string, op Operation) {
+		if content != "" {
+			words = append(words, Word{Content: content, Op: op})
+		}
+	}
+
+	// newPos contains the position of the first byte of the new
+	// content that hasn't been added yet
+	newPos := 0
+	forEachChange(oldWords, newWords, func(oldFirst, oldLen, newFirst, newLen int) {
+		// Like git, an empty range is located at the end of the
+		// previous word
+		var newStart, newEnd int
+		switch {
+		case newLen > 0:
+			newStart = newWords[newFirst].start
+			newEnd = newWords[newFirst+newLen-1].end
+		case newFirst > 0:
+			newStart = newWords[newFirst-1].end
+			newEnd = newStart
+		}
+		add(newText[newPos:newStart], OpEqual)
+		if oldLen > 0 {
+			add(oldText[oldWords[oldFirst].start:oldWords[oldFirst+oldLen-1].end], OpDelete)
+		}
+		add(newText[newStart:newEnd], OpInsert)
+		newPos = newEnd
+	})
+	add(newText[newPos:], OpEqual)
+	return words
+}
+
+// Intraline returns the changes made within each modified line of
+// the hunk. In a block of changes, the nth deleted line is paired
+// with the nth inserted line. The lines that cannot be paired are
+// entirely deleted or inserted, and are not returned
+func (h *Hunk) Intraline() []IntralineChange {
+	changes := []IntralineChange{}
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Op != OpDelete {
+			i++
+			continue
+		}
+		// Within a block of changes, the deleted lines always come
+		// before the inserted lines
+		delStart := i
+		for i < len(h.Lines) && h.Lines[i].Op == OpDelete {
+			i++
+		}
+		insStart := i
+		for i < len(h.Lines) && h.Lines[i].Op == OpInsert {
+			i++
+		}
+		for j := 0; delStart+j < insStart && insStart+j < i; j++ {
+			change := IntralineChange{
+				Old: delStart + j,
+				New: insStart + j,
+			}
+			change.Deleted, change.Inserted = diffTokens(h.Lines[change.Old].Content, h.Lines[change.New].Content)
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// contents returns the old and new versions of the content of
+// the hunk
+func (h *Hunk) contents() (oldText, newText string) {
+	oldB := &strings.Builder{}
+	newB := &strings.Builder{}
+	for _, l := range h.Lines {
+		if l.Op != OpInsert {
+			oldB.WriteString(l.Content)
+		}
+		if l.Op != OpDelete {
+			newB.WriteString(l.Content)
+		}
+	}
+	return oldB.String(), newB.String()
+}
+
+// diffTokens returns the ranges of a and b that are different, using
+// a character-level tokenization
+func diffTokens(a, b string) (deleted, inserted []Range) {
+	aTokens := splitTokens(a)
+	bTokens := splitTokens(b)
+	forEachChange(aTokens, bTokens, func(aFirst, aLen, bFirst, bLen int) {
+		if aLen > 0 {
+			deleted = append(deleted, Range{Start: aTokens[aFirst].start, End: aTokens[aFirst+aLen-1].end})
+		}
+		if bLen > 0 {
+			inserted = append(inserted, Range{Start: bTokens[bFirst].start, End: bTokens[bFirst+bLen-1].end})
+		}
+	})
+	return deleted, inserted
+}
+
+// forEachChange diffs the 2 lists of tokens, and calls f on each
+// block of changes with the index and length of the block in
+// both lists
+func forEachChange(a, b []token, f func(aFirst, aLen, bFirst, bLen int)) {
+	aTexts := make([]string, len(a))
+	for i, t := range a {
+		aTexts[i] = t.text
+	}
+	bTexts := make([]string, len(b))
+	for i, t := range b {
+		bTexts[i] = t.text
+	}
+
+	ai, bi := 0, 0
+	ops := Strings(aTexts, bTexts)
+	for i := 0; i < len(ops); {
+		if ops[i].Op == OpEqual {
+			ai++
+			bi++
+			i++
+			continue
+		}
+		aFirst, bFirst := ai, bi
+		for ; i < len(ops) && ops[i].Op != OpEqual; i++ {
+			if ops[i].Op == OpDelete {
+				ai++
+			} else {
+				bi++
+			}
+		}
+		f(aFirst, ai-aFirst, bFirst, bi-bFirst)
+	}
+}
+
+// splitWords returns the sequences of non-whitespace characters of
+// the text
+func splitWords(text string) []token {
+	tokens := []token{}
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start != -1 {
+				tokens = append(tokens, token{text: text[start:i], start: start, end: i})
+				start = -1
+			}
+			continue
+		}
+		if start == -1 {
+			start = i
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{text: text[start:], start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// splitTokens splits the text into sequences of letters and digits,
+// sequences of whitespace, and single punctuation characters
+func splitTokens(text string) []token {
+	tokens := []token{}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		var class func(rune) bool
+		switch {
+		case isWordRune(r):
+			class = isWordRune
+		case unicode.IsSpace(r):
+			class = unicode.IsSpace
+		}
+		for class != nil && end < len(text) {
+			next, size := utf8.DecodeRuneInString(text[end:])
+			if !class(next) {
+				break
+			}
+			end += size
+		}
+		tokens = append(tokens, token{text: text[i:end], start: i, end: end})
+		i = end
+	}
+	return tokens
+}
+
+// isWordRune returns whether the rune can be part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
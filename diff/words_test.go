@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordDiff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc        string
+		fromContent string
+		toContent   string
+		expected    string
+	}{
+		{
+			desc:        "words changed in multiple lines",
+			fromContent: "hello world foo\nsecond line\nthird(a, b)\nlast\n",
+			toContent:   "hello brave world bar\nsecond line\nthird(a, c)\nnew line\nlast\n",
+			expected: "diff --git a/file b/file\n" +
+				"index a80b0d1..b00af21 100644\n" +
+				"--- a/file\n" +
+				"+++ b/file\n" +
+				"@@ -1,4 +1,5 @@\n" +
+				"hello {+brave+} world [-foo-]{+bar+}\n" +
+				"second line\n" +
+				"third(a, [-b)-]{+c)+}\n" +
+				"{+new line+}\n" +
+				"last\n",
+		},
+		{
+			desc:        "change spanning multiple lines",
+			fromContent: "x\n",
+			toContent:   "y\nz\n",
+			expected: "diff --git a/file b/file\n" +
+				"index 587be6b..2795c87 100644\n" +
+				"--- a/file\n" +
+				"+++ b/file\n" +
+				"@@ -1 +1,2 @@\n" +
+				"[-x-]{+y+}\n" +
+				"{+z+}\n",
+		},
+		{
+			desc:        "deleted words",
+			fromContent: "a b c\n",
+			toContent:   "a c\n",
+			expected: "diff --git a/file b/file\n" +
+				"index 3774da6..6435bc0 100644\n" +
+				"--- a/file\n" +
+				"+++ b/file\n" +
+				"@@ -1 +1 @@\n" +
+				"a[-b-] c\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			from := newFile("file", object.ModeFile, tc.fromContent)
+			to := newFile("file", object.ModeFile, tc.toContent)
+			p := NewFilePatch(from, []byte(tc.fromContent), to, []byte(tc.toContent), DefaultContextLines)
+			assert.Equal(t, tc.expected, p.WordDiff())
+		})
+	}
+}
+
+func TestHunkWords(t *testing.T) {
+	t.Parallel()
+
+	hunks := Hunks(Lines([]byte("a b c\n"), []byte("a d c\n")), DefaultContextLines)
+	require.Len(t, hunks, 1)
+	expected := []Word{
+		{Content: "a ", Op: OpEqual},
+		{Content: "b", Op: OpDelete},
+		{Content: "d", Op: OpInsert},
+		{Content: " c\n", Op: OpEqual},
+	}
+	assert.Equal(t, expected, hunks[0].Words())
+}
+
+func TestHunkIntraline(t *testing.T) {
+	t.Parallel()
+
+	oldContent := "func main() {\n\tfmt.Println(\"hello\")\n\treturn\n}\n"
+	newContent := "func main() {\n\tfmt.Printf(\"hello %s\", name)\n\tos.Exit(0)\n\textra()\n}\n"
+	hunks := Hunks(Lines([]byte(oldContent), []byte(newContent)), DefaultContextLines)
+	require.Len(t, hunks, 1)
+
+	changes := hunks[0].Intraline()
+	expected := []IntralineChange{
+		{
+			// fmt.Println("hello") -> fmt.Printf("hello %s", name)
+			Old:      1,
+			New:      3,
+			Deleted:  []Range{{Start: 5, End: 12}},
+			Inserted: []Range{{Start: 5, End: 11}, {Start: 18, End: 21}, {Start: 22, End: 28}},
+		},
+		{
+			// return -> os.Exit(0)
+			Old:      2,
+			New:      4,
+			Deleted:  []Range{{Start: 1, End: 7}},
+			Inserted: []Range{{Start: 1, End: 11}},
+		},
+	}
+	assert.Equal(t, expected, changes)
+}
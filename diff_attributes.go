@@ -0,0 +1,81 @@
+package git
+
+import (
+	"path"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/attributes"
+)
+
+// treeAttributes gives access to the attributes defined by the
+// gitattributes files of a tree, and by $GIT_DIR/info/attributes
+type treeAttributes struct {
+	r      *Repository
+	treeID ginternals.Oid
+	info   []byte
+	// files contains the content of the gitattributes file of each
+	// directory that has been looked at. The content is nil if the
+	// directory doesn't have a gitattributes file
+	files map[string][]byte
+}
+
+// newTreeAttributes returns the attributes of the given tree.
+// The gitattributes files are only loaded when needed
+func (r *Repository) newTreeAttributes(treeID ginternals.Oid) (*treeAttributes, error) {
+	info, err := r.dotGit.InfoAttributes()
+	if err != nil {
+		return nil, err
+	}
+	return &treeAttributes{
+		r:      r,
+		treeID: treeID,
+		info:   info,
+		files:  map[string][]byte{},
+	}, nil
+}
+
+// get returns the value of the given attribute for the given path
+func (a *treeAttributes) get(p, name string) (attributes.Attribute, error) {
+	// The attributes files are added from the root to the directory
+	// of the file, so the most specific rules take precedence
+	dirs := []string{}
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	dirs = append([]string{""}, dirs...)
+
+	attrs := attributes.New()
+	for _, dir := range dirs {
+		content, err := a.file(dir)
+		if err != nil {
+			return attributes.Attribute{}, err
+		}
+		attrs.Add(dir, content)
+	}
+	attrs.Add("", a.info)
+	return attrs.Get(p, name), nil
+}
+
+// file returns the content of the gitattributes file of the given
+// directory, or nil if there are none
+func (a *treeAttributes) file(dir string) ([]byte, error) {
+	if content, ok := a.files[dir]; ok {
+		return content, nil
+	}
+	var content []byte
+	if !a.treeID.IsZero() {
+		entry, err := a.r.blameFile(a.treeID, joinTreePath(dir, attributes.FileName))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			o, err := a.r.dotGit.Object(entry.ID)
+			if err != nil {
+				return nil, err
+			}
+			content = o.Bytes()
+		}
+	}
+	a.files[dir] = content
+	return content, nil
+}
@@ -0,0 +1,148 @@
+package git
+
+import (
+	"sort"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+)
+
+// renameCandidate represents a pair of changes that could be a
+// rename or a copy
+type renameCandidate struct {
+	src   int
+	dst   int
+	score int
+}
+
+// detectRenames pairs the created files with the deleted (and
+// modified, when looking for copies) files they are similar to.
+// The paired created files are turned into renames or copies, and
+// the renamed deleted files are removed from the list
+func (r *Repository) detectRenames(changes []treeChange, opts DiffOptions) ([]treeChange, error) {
+	threshold := opts.RenameThreshold
+	if threshold <= 0 {
+		threshold = diff.DefaultRenameThreshold
+	}
+	limit := opts.RenameLimit
+	if limit == 0 {
+		limit = diff.DefaultRenameLimit
+	}
+
+	sources := []int{}
+	dests := []int{}
+	for i, c := range changes {
+		switch {
+		case c.isSubmodule():
+			continue
+		case c.to == nil:
+			sources = append(sources, i)
+		case c.from == nil:
+			dests = append(dests, i)
+		case opts.DetectCopies:
+			sources = append(sources, i)
+		}
+	}
+	if len(sources) == 0 || len(dests) == 0 {
+		return changes, nil
+	}
+	// Finding the inexact renames requires comparing every pair of
+	// files, which gets too slow on big diffs
+	inexact := limit < 0 || len(sources)*len(dests) <= limit*limit
+
+	contents := map[ginternals.Oid][]byte{}
+	content := func(f *diff.File) ([]byte, error) {
+		if c, ok := contents[f.ID]; ok {
+			return c, nil
+		}
+		c, err := r.fileContent(f)
+		if err != nil {
+			return nil, err
+		}
+		contents[f.ID] = c
+		return c, nil
+	}
+
+	candidates := []renameCandidate{}
+	for _, dst := range dests {
+		to := changes[dst].to
+		for _, src := range sources {
+			from := changes[src].from
+			if fileType(from.Mode) != fileType(to.Mode) {
+				continue
+			}
+			if from.ID == to.ID {
+				candidates = append(candidates, renameCandidate{src: src, dst: dst, score: 100})
+				continue
+			}
+			if !inexact {
+				continue
+			}
+			fromContent, err := content(from)
+			if err != nil {
+				return nil, err
+			}
+			toContent, err := content(to)
+			if err != nil {
+				return nil, err
+			}
+			// Like git, empty files are only paired when they are
+			// identical
+			if len(fromContent) == 0 || len(toContent) == 0 || !diff.CanBeSimilar(len(fromContent), len(toContent), threshold) {
+				continue
+			}
+			if score := diff.Similarity(fromContent, toContent); score >= threshold {
+				candidates = append(candidates, renameCandidate{src: src, dst: dst, score: score})
+			}
+		}
+	}
+
+	// The best matches are used first. On equal scores we prefer
+	// renames over copies, and keep the tree order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return changes[a.src].to == nil && changes[b.src].to != nil
+	})
+
+	paired := map[int]bool{}
+	renamed := map[int]bool{}
+	for _, c := range candidates {
+		if paired[c.dst] {
+			continue
+		}
+		src := changes[c.src]
+		// A deleted file can only be renamed once, but it can be the
+		// source of multiple copies
+		isCopy := src.to != nil
+		if !isCopy && renamed[c.src] {
+			if !opts.DetectCopies {
+				continue
+			}
+			isCopy = true
+		}
+		if !isCopy {
+			renamed[c.src] = true
+		}
+		paired[c.dst] = true
+		changes[c.dst] = treeChange{
+			from:       src.from,
+			to:         changes[c.dst].to,
+			similarity: c.score,
+			isCopy:     isCopy,
+		}
+	}
+
+	if len(renamed) == 0 {
+		return changes, nil
+	}
+	res := make([]treeChange, 0, len(changes)-len(renamed))
+	for i, c := range changes {
+		if !renamed[i] {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
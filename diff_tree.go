@@ -0,0 +1,314 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/attributes"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/pathspec"
+)
+
+// DiffOptions represents the options that can be used to compute
+// a diff
+type DiffOptions struct {
+	// ContextLines is the number of unchanged lines displayed
+	// around the changes
+	ContextLines int
+	// Pathspec limits the diff to the paths it matches.
+	// All the paths are diffed when nil
+	Pathspec *pathspec.Pathspec
+	// Submodule is the format used to display the changes made to
+	// the submodules.
+	// Defaults to SubmoduleShort
+	Submodule SubmoduleFormat
+	// DetectRenames pairs the deleted files with the created files
+	// that are similar enough, and reports them as renames (-M)
+	DetectRenames bool
+	// DetectCopies reports the created files that are similar enough
+	// to a modified or deleted file as copies (-C).
+	// Implies DetectRenames
+	DetectCopies bool
+	// RenameThreshold is the minimum similarity (in percent) for 2
+	// files to be considered a rename or a copy.
+	// Defaults to diff.DefaultRenameThreshold
+	RenameThreshold int
+	// RenameLimit is the maximum number of files on each side of the
+	// diff for which the inexact renames are looked for. Only the
+	// exact renames are detected past this limit.
+	// Defaults to diff.DefaultRenameLimit. Use a negative value to
+	// remove the limit
+	RenameLimit int
+	// Text treats all the files as text.
+	// This is the equivalent of --text
+	Text bool
+	// BinaryPatch generates the data needed to apply the changes made
+	// to the binary files, instead of only reporting that they differ.
+	// This is the equivalent of --binary
+	BinaryPatch bool
+}
+
+// DefaultDiffOptions returns the options used by git by default
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{
+		ContextLines: diff.DefaultContextLines,
+	}
+}
+
+// DiffTrees returns the changes needed to go from the tree "from"
+// to the tree "to", ordered the same way as the entries of a tree.
+// ginternals.NullOid can be used to represent an empty tree.
+// A change of type (ex. a file replaced by a symlink) is returned as
+// a deletion followed by a creation.
+// When renames are detected, a renamed file is returned at the
+// position of its new path.
+// The diff attribute of the files, as defined in the gitattributes
+// files of "to", is used to find out if a file is binary
+func (r *Repository) DiffTrees(from, to ginternals.Oid, opts DiffOptions) ([]*diff.FilePatch, error) {
+	allChanges := []treeChange{}
+	if err := r.diffTrees(from, to, "", &allChanges); err != nil {
+		return nil, err
+	}
+	changes := make([]treeChange, 0, len(allChanges))
+	for _, change := range allChanges {
+		if opts.Pathspec == nil || opts.Pathspec.Match(change.path()) {
+			changes = append(changes, change)
+		}
+	}
+	if opts.DetectRenames || opts.DetectCopies {
+		var err error
+		if changes, err = r.detectRenames(changes, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	attrsTree := to
+	if attrsTree.IsZero() {
+		attrsTree = from
+	}
+	attrs, err := r.newTreeAttributes(attrsTree)
+	if err != nil {
+		return nil, err
+	}
+
+	patches := make([]*diff.FilePatch, 0, len(changes))
+	for _, change := range changes {
+		if opts.Submodule == SubmoduleLog && change.isSubmodule() {
+			log, err := r.submoduleLog(change.path(), change.from, change.to)
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, &diff.FilePatch{From: change.from, To: change.to, Submodule: log})
+			continue
+		}
+		fromContent, err := r.fileContent(change.from)
+		if err != nil {
+			return nil, err
+		}
+		toContent, err := r.fileContent(change.to)
+		if err != nil {
+			return nil, err
+		}
+		fileOpts := diff.FilePatchOptions{
+			ContextLines: opts.ContextLines,
+			ForceText:    opts.Text,
+			BinaryPatch:  opts.BinaryPatch,
+		}
+		diffAttr, err := attrs.get(change.path(), "diff")
+		if err != nil {
+			return nil, err
+		}
+		switch diffAttr.State {
+		case attributes.Unset:
+			fileOpts.ForceBinary = true
+		case attributes.Set:
+			fileOpts.ForceText = true
+		}
+		patch := diff.NewFilePatchWithOptions(change.from, fromContent, change.to, toContent, fileOpts)
+		patch.Similarity = change.similarity
+		patch.IsCopy = change.isCopy
+		patches = append(patches, patch)
+	}
+	return patches, nil
+}
+
+// treeChange represents a file that is different between 2 trees
+type treeChange struct {
+	from *diff.File
+	to   *diff.File
+
+	// similarity and isCopy are only set for renames and copies
+	similarity int
+	isCopy     bool
+}
+
+// path returns the path of the file that changed
+func (c treeChange) path() string {
+	if c.to != nil {
+		return c.to.Path
+	}
+	return c.from.Path
+}
+
+// isSubmodule returns whether the change only concerns a submodule
+func (c treeChange) isSubmodule() bool {
+	return (c.from == nil || c.from.Mode == object.ModeGitLink) &&
+		(c.to == nil || c.to.Mode == object.ModeGitLink)
+}
+
+// diffTrees appends to changes all the files that are different
+// between the 2 trees
+func (r *Repository) diffTrees(from, to ginternals.Oid, basePath string, changes *[]treeChange) error {
+	if from == to {
+		return nil
+	}
+
+	fromEntries, err := r.treeEntries(from)
+	if err != nil {
+		return err
+	}
+	toEntries, err := r.treeEntries(to)
+	if err != nil {
+		return err
+	}
+
+	// The entries of both trees are sorted, so we can walk them
+	// at the same time
+	i, j := 0, 0
+	for i < len(fromEntries) || j < len(toEntries) {
+		var cmp int
+		switch {
+		case i == len(fromEntries):
+			cmp = 1
+		case j == len(toEntries):
+			cmp = -1
+		default:
+			cmp = compareTreeEntries(fromEntries[i], toEntries[j])
+		}
+
+		switch {
+		case cmp < 0:
+			if err = r.diffEntries(&fromEntries[i], nil, basePath, changes); err != nil {
+				return err
+			}
+			i++
+		case cmp > 0:
+			if err = r.diffEntries(nil, &toEntries[j], basePath, changes); err != nil {
+				return err
+			}
+			j++
+		default:
+			if err = r.diffEntries(&fromEntries[i], &toEntries[j], basePath, changes); err != nil {
+				return err
+			}
+			i++
+			j++
+		}
+	}
+	return nil
+}
+
+// diffEntries appends to changes all the files that are different
+// between 2 entries having the same path. A nil entry means that
+// the entry doesn't exist
+func (r *Repository) diffEntries(from, to *object.TreeEntry, basePath string, changes *[]treeChange) error {
+	if from != nil && to != nil && from.ID == to.ID && from.Mode == to.Mode {
+		return nil
+	}
+
+	// Directories are both represented by the same mode, so if one
+	// is a directory, both are
+	if (from != nil && from.Mode == object.ModeDirectory) || (to != nil && to.Mode == object.ModeDirectory) {
+		fromID, toID := ginternals.NullOid, ginternals.NullOid
+		path := ""
+		if from != nil {
+			fromID = from.ID
+			path = from.Path
+		}
+		if to != nil {
+			toID = to.ID
+			path = to.Path
+		}
+		return r.diffTrees(fromID, toID, joinTreePath(basePath, path), changes)
+	}
+
+	var fromFile, toFile *diff.File
+	if from != nil {
+		fromFile = &diff.File{Path: joinTreePath(basePath, from.Path), ID: from.ID, Mode: from.Mode}
+	}
+	if to != nil {
+		toFile = &diff.File{Path: joinTreePath(basePath, to.Path), ID: to.ID, Mode: to.Mode}
+	}
+
+	// A change of type is treated as a deletion and a creation
+	if fromFile != nil && toFile != nil && fileType(fromFile.Mode) != fileType(toFile.Mode) {
+		*changes = append(*changes, treeChange{from: fromFile}, treeChange{to: toFile})
+		return nil
+	}
+	*changes = append(*changes, treeChange{from: fromFile, to: toFile})
+	return nil
+}
+
+// joinTreePath returns the path of an entry relative to the root
+// of the repository
+func joinTreePath(basePath, name string) string {
+	if basePath == "" {
+		return name
+	}
+	return basePath + "/" + name
+}
+
+// fileType returns the type of file represented by a mode.
+// Regular files and executables are of the same type
+func fileType(mode object.TreeObjectMode) object.TreeObjectMode {
+	if mode == object.ModeExecutable {
+		return object.ModeFile
+	}
+	return mode
+}
+
+// treeEntries returns the entries of a tree. An empty list is
+// returned for NullOid
+func (r *Repository) treeEntries(oid ginternals.Oid) ([]object.TreeEntry, error) {
+	if oid.IsZero() {
+		return []object.TreeEntry{}, nil
+	}
+	tree, err := r.Tree(oid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get tree %s: %w", oid.String(), err)
+	}
+	return tree.Entries(), nil
+}
+
+// fileContent returns the content of a file.
+// The content of a gitlink (submodule) is the same as the one used
+// by git
+func (r *Repository) fileContent(f *diff.File) ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if f.Mode == object.ModeGitLink {
+		return []byte(fmt.Sprintf("Subproject commit %s\n", f.ID.String())), nil
+	}
+	o, err := r.dotGit.Object(f.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get blob %s of %s: %w", f.ID.String(), f.Path, err)
+	}
+	return o.Bytes(), nil
+}
+
+// compareTreeEntries compares 2 entries the same way they are sorted
+// in a tree: directories are compared as if their name ended with
+// a /
+func compareTreeEntries(a, b object.TreeEntry) int {
+	aName, bName := a.Path, b.Path
+	if a.Mode == object.ModeDirectory {
+		aName += "/"
+	}
+	if b.Mode == object.ModeDirectory {
+		bName += "/"
+	}
+	return strings.Compare(aName, bName)
+}
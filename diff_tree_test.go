@@ -0,0 +1,422 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTrees(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	// treeOf returns the tree of the given commit
+	treeOf := func(t *testing.T, commitSHA string) ginternals.Oid {
+		t.Helper()
+		oid, err := ginternals.NewOidFromStr(commitSHA)
+		require.NoError(t, err)
+		c, err := r.Commit(oid)
+		require.NoError(t, err)
+		return c.TreeID()
+	}
+
+	t.Run("should list the changed files in tree order", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "3a78491a3bfb77d1d3b1bb3c5e808c3bba1e7da6")
+		to := treeOf(t, "2f2e900b4e87ab0d51809642eaf0c5a12a97d927")
+		patches, err := r.DiffTrees(from, to, DefaultDiffOptions())
+		require.NoError(t, err)
+
+		type change struct {
+			path    string
+			created bool
+			deleted bool
+		}
+		expected := []change{
+			{path: ".github/workflows/go.yml"},
+			{path: ".golangci.yml"},
+			{path: "commit.go"},
+			{path: "git.go", created: true},
+			{path: "go.mod"},
+			{path: "go.sum"},
+			{path: "packfile.go"},
+			{path: "repo.go"},
+			{path: "tools/lint.sh", deleted: true},
+			{path: "tools/test.sh", deleted: true},
+			{path: "tools/tools.go", deleted: true},
+		}
+		changes := make([]change, 0, len(patches))
+		for _, p := range patches {
+			changes = append(changes, change{
+				path:    p.Path(),
+				created: p.From == nil,
+				deleted: p.To == nil,
+			})
+		}
+		assert.Equal(t, expected, changes)
+	})
+
+	t.Run("should only list the files matching the pathspec", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "3a78491a3bfb77d1d3b1bb3c5e808c3bba1e7da6")
+		to := treeOf(t, "2f2e900b4e87ab0d51809642eaf0c5a12a97d927")
+		ps, err := r.Pathspec("tools", "*.mod")
+		require.NoError(t, err)
+		opts := DefaultDiffOptions()
+		opts.Pathspec = ps
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+
+		paths := make([]string, 0, len(patches))
+		for _, p := range patches {
+			paths = append(paths, p.Path())
+		}
+		assert.Equal(t, []string{"go.mod", "tools/lint.sh", "tools/test.sh", "tools/tools.go"}, paths)
+	})
+
+	t.Run("same trees should have no changes", func(t *testing.T) {
+		t.Parallel()
+
+		tree := treeOf(t, "2f2e900b4e87ab0d51809642eaf0c5a12a97d927")
+		patches, err := r.DiffTrees(tree, tree, DefaultDiffOptions())
+		require.NoError(t, err)
+		assert.Empty(t, patches)
+	})
+
+	t.Run("NullOid should be treated as an empty tree", func(t *testing.T) {
+		t.Parallel()
+
+		tree := treeOf(t, "2f2e900b4e87ab0d51809642eaf0c5a12a97d927")
+		patches, err := r.DiffTrees(ginternals.NullOid, tree, DefaultDiffOptions())
+		require.NoError(t, err)
+		require.NotEmpty(t, patches)
+		for _, p := range patches {
+			assert.Nil(t, p.From, "%s should be a new file", p.Path())
+		}
+	})
+
+	t.Run("should detect renames", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "d26b5b27935e59022de19939bb16c39f6b38a0f0")
+		to := treeOf(t, "add862f16c9befc4b88a24e22fda2fa9b68c1653")
+		opts := DefaultDiffOptions()
+		opts.DetectRenames = true
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+
+		// Same as git diff -M --raw
+		expected := []string{
+			"M cmd/agit/main.go",
+			"M const.go",
+			"R064 readutil.go internal/readutil/read_to.go",
+			"A internal/readutil/readutil.go",
+			"A plumbing/errors.go",
+			"R061 blob.go plumbing/object/blob.go",
+			"R090 commit.go plumbing/object/commit.go",
+			"R099 commit_test.go plumbing/object/commit_test.go",
+			"R074 object.go plumbing/object/object.go",
+			"R087 object_test.go plumbing/object/object_test.go",
+			"R098 oid.go plumbing/oid.go",
+			"A plumbing/packfile/const.go",
+			"R084 packfile.go plumbing/packfile/packfile.go",
+			"R095 packindex.go plumbing/packfile/packindex.go",
+			"A plumbing/plumbing.go",
+			"M repo.go",
+		}
+		changes := make([]string, 0, len(patches))
+		for _, p := range patches {
+			if p.Change() == diff.ChangeRenamed {
+				changes = append(changes, fmt.Sprintf("R%03d %s %s", p.Similarity, p.From.Path, p.To.Path))
+				continue
+			}
+			changes = append(changes, p.Change().String()+" "+p.Path())
+		}
+		assert.Equal(t, expected, changes)
+	})
+
+	t.Run("should respect the rename threshold", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "d26b5b27935e59022de19939bb16c39f6b38a0f0")
+		to := treeOf(t, "add862f16c9befc4b88a24e22fda2fa9b68c1653")
+		opts := DefaultDiffOptions()
+		opts.DetectRenames = true
+		opts.RenameThreshold = 95
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+
+		renames := []string{}
+		for _, p := range patches {
+			if p.Change() == diff.ChangeRenamed {
+				renames = append(renames, p.To.Path)
+			}
+		}
+		assert.Equal(t, []string{"plumbing/object/commit_test.go", "plumbing/oid.go", "plumbing/packfile/packindex.go"}, renames)
+	})
+
+	t.Run("should compute the same stats as git", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "d26b5b27935e59022de19939bb16c39f6b38a0f0")
+		to := treeOf(t, "add862f16c9befc4b88a24e22fda2fa9b68c1653")
+		opts := DefaultDiffOptions()
+		opts.DetectRenames = true
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+
+		// generated with git diff --numstat -M d26b5b2 add862f
+		expected := "7\t5\tcmd/agit/main.go\n" +
+			"0\t6\tconst.go\n" +
+			"3\t3\treadutil.go => internal/readutil/read_to.go\n" +
+			"2\t0\tinternal/readutil/readutil.go\n" +
+			"7\t0\tplumbing/errors.go\n" +
+			"3\t3\tblob.go => plumbing/object/blob.go\n" +
+			"9\t7\tcommit.go => plumbing/object/commit.go\n" +
+			"1\t1\tcommit_test.go => plumbing/object/commit_test.go\n" +
+			"53\t40\tobject.go => plumbing/object/object.go\n" +
+			"7\t11\tobject_test.go => plumbing/object/object_test.go\n" +
+			"1\t1\toid.go => plumbing/oid.go\n" +
+			"7\t0\tplumbing/packfile/const.go\n" +
+			"47\t49\tpackfile.go => plumbing/packfile/packfile.go\n" +
+			"12\t17\tpackindex.go => plumbing/packfile/packindex.go\n" +
+			"3\t0\tplumbing/plumbing.go\n" +
+			"25\t20\trepo.go\n"
+		stats := diff.NewStats(patches)
+		assert.Equal(t, expected, stats.Numstat())
+		assert.Equal(t, " 16 files changed, 187 insertions(+), 163 deletions(-)\n", stats.Summary())
+	})
+
+	t.Run("should only detect exact renames past the rename limit", func(t *testing.T) {
+		t.Parallel()
+
+		from := treeOf(t, "81d0f8bafa5351633d3c29e0054498abf0b9867b")
+		to := treeOf(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1")
+		opts := DefaultDiffOptions()
+		opts.DetectRenames = true
+		opts.RenameLimit = 1
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+
+		for _, p := range patches {
+			if p.Change() == diff.ChangeRenamed {
+				assert.Equal(t, 100, p.Similarity, "%s should be an exact rename", p.To.Path)
+			}
+		}
+	})
+
+}
+
+func TestDiffTreesCopies(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	r, err := InitRepository(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	// newTree returns a tree containing the given files
+	newTree := func(t *testing.T, files map[string]string) ginternals.Oid {
+		t.Helper()
+		tb := r.NewTreeBuilder()
+		for path, content := range files {
+			blob, err := r.NewBlob([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, tb.Insert(path, blob.ID(), object.ModeFile))
+		}
+		tree, err := tb.Write()
+		require.NoError(t, err)
+		return tree.ID()
+	}
+	lines := func(count int, extra string) string {
+		b := &strings.Builder{}
+		for i := 1; i <= count; i++ {
+			fmt.Fprintf(b, "%d\n", i)
+		}
+		return b.String() + extra
+	}
+	from := newTree(t, map[string]string{
+		"a": lines(20, ""),
+		"b": lines(10, ""),
+	})
+	to := newTree(t, map[string]string{
+		"b": lines(10, "y\n"),
+		"c": lines(20, "21\n"),
+		"d": lines(10, "x\n"),
+	})
+
+	testCases := []struct {
+		desc     string
+		copies   bool
+		expected []string
+	}{
+		{
+			desc:     "renames only",
+			expected: []string{"M b", "R094 a c", "A d"},
+		},
+		{
+			desc:     "copies",
+			copies:   true,
+			expected: []string{"M b", "R094 a c", "C091 b d"},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			opts := DefaultDiffOptions()
+			opts.DetectRenames = true
+			opts.DetectCopies = tc.copies
+			patches, err := r.DiffTrees(from, to, opts)
+			require.NoError(t, err)
+
+			changes := make([]string, 0, len(patches))
+			for _, p := range patches {
+				switch p.Change() {
+				case diff.ChangeRenamed, diff.ChangeCopied:
+					changes = append(changes, fmt.Sprintf("%s%03d %s %s", p.Change().String(), p.Similarity, p.From.Path, p.To.Path))
+				default:
+					changes = append(changes, p.Change().String()+" "+p.Path())
+				}
+			}
+			assert.Equal(t, tc.expected, changes)
+		})
+	}
+}
+
+func TestDiffTreesAttributes(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	r, err := InitRepository(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	// newTree returns a tree containing the given files. The files
+	// of the subdirectories are put in their own tree
+	var newTree func(t *testing.T, files map[string]string) ginternals.Oid
+	newTree = func(t *testing.T, files map[string]string) ginternals.Oid {
+		t.Helper()
+		tb := r.NewTreeBuilder()
+		subdirs := map[string]map[string]string{}
+		for path, content := range files {
+			if i := strings.IndexByte(path, '/'); i != -1 {
+				if subdirs[path[:i]] == nil {
+					subdirs[path[:i]] = map[string]string{}
+				}
+				subdirs[path[:i]][path[i+1:]] = content
+				continue
+			}
+			blob, err := r.NewBlob([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, tb.Insert(path, blob.ID(), object.ModeFile))
+		}
+		for dir, files := range subdirs {
+			require.NoError(t, tb.Insert(dir, newTree(t, files), object.ModeDirectory))
+		}
+		tree, err := tb.Write()
+		require.NoError(t, err)
+		return tree.ID()
+	}
+	attrs := "*.txt -diff\n*.bin diff\n"
+	from := newTree(t, map[string]string{
+		".gitattributes": attrs,
+		"a.txt":          "a\n",
+		"b.bin":          "b\x00\n",
+		"c.md":           "c\n",
+		"dir/d.txt":      "d\n",
+	})
+	to := newTree(t, map[string]string{
+		".gitattributes": attrs,
+		"a.txt":          "aa\n",
+		"b.bin":          "bb\x00\n",
+		"c.md":           "cc\x00\n",
+		"dir/d.txt":      "dd\n",
+	})
+
+	testCases := []struct {
+		desc     string
+		opts     DiffOptions
+		expected map[string]bool
+	}{
+		{
+			desc: "should use the diff attribute",
+			opts: DefaultDiffOptions(),
+			expected: map[string]bool{
+				"a.txt":     true,
+				"b.bin":     false,
+				"c.md":      true,
+				"dir/d.txt": true,
+			},
+		},
+		{
+			desc: "Text should treat all the files as text",
+			opts: DiffOptions{ContextLines: diff.DefaultContextLines, Text: true},
+			expected: map[string]bool{
+				"a.txt":     false,
+				"b.bin":     false,
+				"c.md":      false,
+				"dir/d.txt": false,
+			},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			patches, err := r.DiffTrees(from, to, tc.opts)
+			require.NoError(t, err)
+			binary := map[string]bool{}
+			for _, p := range patches {
+				binary[p.Path()] = p.IsBinary
+			}
+			assert.Equal(t, tc.expected, binary)
+		})
+	}
+
+	t.Run("BinaryPatch should generate binary patches", func(t *testing.T) {
+		t.Parallel()
+
+		opts := DefaultDiffOptions()
+		opts.BinaryPatch = true
+		patches, err := r.DiffTrees(from, to, opts)
+		require.NoError(t, err)
+		for _, p := range patches {
+			if p.Path() != "c.md" {
+				continue
+			}
+			require.NotNil(t, p.BinaryPatch)
+			out, err := p.BinaryPatch.Forward.Apply([]byte("c\n"))
+			require.NoError(t, err)
+			assert.Equal(t, "cc\x00\n", string(out))
+			assert.Contains(t, p.String(), "GIT binary patch\n")
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/internal/pathutil"
+)
+
+// DiscoverOptions represents the options used to find a repository
+type DiscoverOptions struct {
+	// CeilingDirectories contains absolute paths of directories the
+	// lookup should not go into. The starting directory is always
+	// checked, even if it's a ceiling.
+	// This is the equivalent of $GIT_CEILING_DIRECTORIES
+	CeilingDirectories []string
+	// AcrossFilesystem allows the lookup to continue on a different
+	// filesystem than the one of the starting directory.
+	// This is the equivalent of $GIT_DISCOVERY_ACROSS_FILESYSTEM
+	AcrossFilesystem bool
+}
+
+// Discover looks for the repository containing startPath by walking
+// up the tree, the same way git does, and returns its config.
+// At each level, a directory is considered to be the root of a
+// repository if it contains a .git directory (or a .git file pointing
+// to one), and is considered to be a bare repository if it contains
+// a HEAD, an objects directory, and a refs directory.
+// The WorkTreePath of the returned config is empty for bare
+// repositories.
+// ErrRepositoryNotExist is returned if no repositories are found
+func Discover(startPath string, opts DiscoverOptions) (*config.Config, error) {
+	startPath, err := filepath.Abs(startPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the absolute path of %s: %w", startPath, err)
+	}
+
+	var loadOpts config.LoadConfigOptions
+	_, err = pathutil.FindUp(startPath, pathutil.DiscoveryOptions{
+		CeilingDirectories:       opts.CeilingDirectories,
+		StopAtFilesystemBoundary: !opts.AcrossFilesystem,
+	}, func(dir string) (bool, error) {
+		gitDir, err := dotGitPath(filepath.Join(dir, config.DefaultDotGitDirName))
+		if err != nil {
+			return false, err
+		}
+		if gitDir != "" {
+			loadOpts = config.LoadConfigOptions{
+				WorkTreePath: dir,
+				GitDirPath:   gitDir,
+			}
+			return true, nil
+		}
+		if isGitDirectory(dir) {
+			loadOpts = config.LoadConfigOptions{
+				GitDirPath: dir,
+				IsBare:     true,
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		if errors.Is(err, pathutil.ErrNoRepo) {
+			return nil, ErrRepositoryNotExist
+		}
+		return nil, err
+	}
+
+	cfg, err := config.LoadConfigSkipEnv(loadOpts)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the config of %s: %w", loadOpts.GitDirPath, err)
+	}
+	return cfg, nil
+}
+
+// dotGitPath returns the path of the git directory pointed by p, or
+// an empty string if p is neither a git directory nor a gitfile
+func dotGitPath(p string) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("could not check %s: %w", p, err)
+	}
+	if info.IsDir() {
+		if isGitDirectory(p) {
+			return p, nil
+		}
+		return "", nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", p, err)
+	}
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, gitfilePrefix) {
+		return "", fmt.Errorf("%s: %w", p, config.ErrInvalidGitfileFormat)
+	}
+	gitDir := strings.TrimPrefix(content, gitfilePrefix)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(p), gitDir)
+	}
+	return gitDir, nil
+}
+
+// isGitDirectory returns whether the directory looks like a git
+// directory: it needs a valid HEAD, an objects directory, and a
+// refs directory
+func isGitDirectory(dir string) bool {
+	for _, name := range []string{"objects", "refs"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ginternals.Head))
+	if err != nil {
+		return false
+	}
+	head := strings.TrimSpace(string(data))
+	if strings.HasPrefix(head, "ref: refs/") {
+		return true
+	}
+	_, err = ginternals.NewOidFromStr(head)
+	return err == nil
+}
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should find the repo from a sub directory", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		start := filepath.Join(repoPath, "a", "b")
+		require.NoError(t, os.MkdirAll(start, 0o755))
+
+		cfg, err := Discover(start, DiscoverOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, repoPath, cfg.WorkTreePath)
+		assert.Equal(t, filepath.Join(repoPath, ".git"), cfg.GitDirPath)
+
+		r, err := OpenRepositoryWithParams(cfg, OpenOptions{})
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	})
+
+	t.Run("should detect bare repos", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepositoryWithOptions(d, InitOptions{IsBare: true})
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		cfg, err := Discover(filepath.Join(d, "refs", "heads"), DiscoverOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, d, cfg.GitDirPath)
+		assert.Empty(t, cfg.WorkTreePath)
+		isBare, _ := cfg.FromFile().IsBare()
+		assert.True(t, isBare)
+	})
+
+	t.Run("should follow gitfiles", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		gitDir := filepath.Join(d, "separate-git-dir")
+		r, err := InitRepositoryWithOptions(gitDir, InitOptions{IsBare: true})
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		wt := filepath.Join(d, "wt")
+		require.NoError(t, os.MkdirAll(wt, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: ../separate-git-dir\n"), 0o644))
+
+		cfg, err := Discover(wt, DiscoverOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, wt, cfg.WorkTreePath)
+		assert.Equal(t, gitDir, cfg.GitDirPath)
+	})
+
+	t.Run("should stop at the ceilings", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		start := filepath.Join(repoPath, "a", "b")
+		require.NoError(t, os.MkdirAll(start, 0o755))
+
+		_, err := Discover(start, DiscoverOptions{
+			CeilingDirectories: []string{filepath.Join(repoPath, "a")},
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRepositoryNotExist)
+	})
+
+	t.Run("should ignore directories that are not repos", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		// Looks like a repo, but has no HEAD
+		require.NoError(t, os.MkdirAll(filepath.Join(d, "refs"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(d, "objects"), 0o755))
+
+		_, err := Discover(d, DiscoverOptions{
+			CeilingDirectories: []string{filepath.Dir(d)},
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRepositoryNotExist)
+	})
+}
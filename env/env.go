@@ -4,6 +4,7 @@ package env
 
 import (
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -24,7 +25,11 @@ func NewFromKVList(env []string) *Env {
 		make(map[string]string, len(env)),
 	}
 	for _, kv := range env {
-		data := strings.Split(kv, "=")
+		// the value may contain an =
+		data := strings.SplitN(kv, "=", 2)
+		if len(data) != 2 {
+			continue
+		}
 		e.env[data[0]] = data[1]
 	}
 	return e
@@ -47,3 +52,15 @@ func (e *Env) Get(key string) string {
 	}
 	return v
 }
+
+// List returns the environment as a list of string in the form
+// "key=value", sorted by key.
+// This is the format expected by exec.Cmd.Env
+func (e *Env) List() []string {
+	list := make([]string, 0, len(e.env))
+	for k, v := range e.env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
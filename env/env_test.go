@@ -24,16 +24,31 @@ func TestNewFromKVList(t *testing.T) {
 		"ENABLE=true",
 		"PATH=a:b:c",
 		"X=",
+		"GIT_SSH_COMMAND=ssh -o User=git",
+		"INVALID",
 	})
-	assert.Len(t, e.env, 4)
+	assert.Len(t, e.env, 5)
 	assert.Equal(t, map[string]string{
-		"VERSION": "1",
-		"ENABLE":  "true",
-		"PATH":    "a:b:c",
-		"X":       "",
+		"VERSION":         "1",
+		"ENABLE":          "true",
+		"PATH":            "a:b:c",
+		"X":               "",
+		"GIT_SSH_COMMAND": "ssh -o User=git",
 	}, e.env)
 }
 
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	e := NewFromKVList([]string{
+		"VERSION=1",
+		"ENABLE=true",
+		"X=",
+		"OPTS=a=b",
+	})
+	assert.Equal(t, []string{"ENABLE=true", "OPTS=a=b", "VERSION=1", "X="}, e.List())
+}
+
 func TestGet(t *testing.T) {
 	t.Parallel()
 
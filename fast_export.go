@@ -0,0 +1,377 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrSignedTag is an error thrown when a signed tag is exported
+// using SignedTagsAbort
+var ErrSignedTag = errors.New("signed tag")
+
+// signatureHeaders contains the lines starting the signatures that
+// can be found at the end of the message of a tag
+var signatureHeaders = []string{ //nolint:gochecknoglobals // read-only list
+	"-----BEGIN PGP SIGNATURE-----",
+	"-----BEGIN PGP MESSAGE-----",
+	"-----BEGIN SSH SIGNATURE-----",
+	"-----BEGIN SIGNED MESSAGE-----",
+}
+
+// SignedTagsMode represents what to do with the signed tags when
+// exporting a repository
+type SignedTagsMode int8
+
+const (
+	// SignedTagsAbort fails the export when a signed tag is found.
+	// This is the default, like git
+	SignedTagsAbort SignedTagsMode = iota
+	// SignedTagsVerbatim exports the signatures as part of the
+	// message of the tags. The signatures will be invalid if the
+	// stream is modified
+	SignedTagsVerbatim
+	// SignedTagsStrip removes the signatures of the tags
+	SignedTagsStrip
+)
+
+// FastExportOptions represents the options that can be used to
+// export a repository
+type FastExportOptions struct {
+	// Refs contains the full names of the references to export,
+	// alongside their history (ex. refs/heads/master).
+	// Defaults to all the branches and tags
+	Refs []string
+	// SignedTags sets what to do with the signed tags.
+	// This is the equivalent of --signed-tags
+	SignedTags SignedTagsMode
+}
+
+// fastExportRef represents a reference being exported
+type fastExportRef struct {
+	name     string
+	commitID ginternals.Oid
+	// tag is set if the reference targets an annotated tag
+	tag *object.Tag
+}
+
+// fastExporter contains the state of an export
+type fastExporter struct {
+	r    *Repository
+	w    *bufio.Writer
+	opts FastExportOptions
+
+	marks    map[ginternals.Oid]int
+	lastMark int
+}
+
+// FastExport writes the history of the given references to w, using
+// the format of git fast-import. This is the equivalent of
+// git fast-export.
+// The commits are exported parents first, with the files that changed
+// compared to their first parent. The blobs and commits are given a
+// mark the first time they are exported.
+// The signatures of the commits are not exported.
+// https://git-scm.com/docs/git-fast-import
+func (r *Repository) FastExport(w io.Writer, opts FastExportOptions) (err error) {
+	refs, err := r.fastExportRefs(opts)
+	if err != nil {
+		return err
+	}
+
+	order, names, err := r.fastExportOrder(refs)
+	if err != nil {
+		return err
+	}
+
+	e := &fastExporter{
+		r:     r,
+		w:     bufio.NewWriter(w),
+		opts:  opts,
+		marks: map[ginternals.Oid]int{},
+	}
+	defer func() {
+		if flushErr := e.w.Flush(); flushErr != nil && err == nil {
+			err = fmt.Errorf("could not write the stream: %w", flushErr)
+		}
+	}()
+
+	for _, c := range order {
+		if err = e.writeCommit(c, names[c.ID()]); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.tag != nil {
+			if err = e.writeTag(ref); err != nil {
+				return err
+			}
+			continue
+		}
+		// The reference is already set if its commit has been
+		// exported using its name
+		if names[ref.commitID] != ref.name {
+			fmt.Fprintf(e.w, "reset %s\nfrom :%d\n\n", ref.name, e.marks[ref.commitID])
+		}
+	}
+	return nil
+}
+
+// fastExportRefs returns the references to export
+func (r *Repository) fastExportRefs(opts FastExportOptions) ([]*fastExportRef, error) {
+	names := opts.Refs
+	if len(names) == 0 {
+		err := r.dotGit.WalkReferences(func(ref *ginternals.Reference) error {
+			if strings.HasPrefix(ref.Name(), "refs/heads/") || strings.HasPrefix(ref.Name(), "refs/tags/") {
+				names = append(names, ref.Name())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not list the references: %w", err)
+		}
+		sort.Strings(names)
+	}
+
+	refs := make([]*fastExportRef, 0, len(names))
+	for _, name := range names {
+		ref, err := r.dotGit.Reference(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not get reference %s: %w", name, err)
+		}
+		o, err := r.dotGit.Object(ref.Target())
+		if err != nil {
+			return nil, fmt.Errorf("could not get the target of %s: %w", name, err)
+		}
+
+		exported := &fastExportRef{name: name}
+		if o.Type() == object.TypeTag {
+			if exported.tag, err = o.AsTag(); err != nil {
+				return nil, fmt.Errorf("could not parse tag %s: %w", o.ID().String(), err)
+			}
+			if exported.tag.Type() != object.TypeCommit {
+				return nil, fmt.Errorf("tag %s targets a %s: %w", name, exported.tag.Type().String(), object.ErrObjectInvalid)
+			}
+		}
+		c, err := r.peelToCommit(o.ID())
+		if err != nil {
+			return nil, fmt.Errorf("could not export %s: %w", name, err)
+		}
+		exported.commitID = c.ID()
+		refs = append(refs, exported)
+	}
+	return refs, nil
+}
+
+// fastExportOrder returns all the commits reachable from the given
+// references, parents first, as well as the name of the reference
+// used to export each commit.
+// A commit is exported using the first reference it's reachable from
+func (r *Repository) fastExportOrder(refs []*fastExportRef) (order []*object.Commit, names map[ginternals.Oid]string, err error) {
+	names = map[ginternals.Oid]string{}
+	type frame struct {
+		commit *object.Commit
+		// next contains the index of the next parent to visit
+		next int
+	}
+
+	for _, ref := range refs {
+		if _, ok := names[ref.commitID]; ok {
+			continue
+		}
+		c, err := r.Commit(ref.commitID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get commit %s: %w", ref.commitID.String(), err)
+		}
+		names[c.ID()] = ref.name
+		stack := []*frame{{commit: c}}
+		for len(stack) > 0 {
+			f := stack[len(stack)-1]
+			parents := f.commit.ParentIDs()
+			if f.next == len(parents) {
+				order = append(order, f.commit)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			parentID := parents[f.next]
+			f.next++
+			if _, ok := names[parentID]; ok {
+				continue
+			}
+			parent, err := r.Commit(parentID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not get commit %s: %w", parentID.String(), err)
+			}
+			names[parentID] = ref.name
+			stack = append(stack, &frame{commit: parent})
+		}
+	}
+	return order, names, nil
+}
+
+// mark returns a new mark for the given object
+func (e *fastExporter) mark(oid ginternals.Oid) int {
+	e.lastMark++
+	e.marks[oid] = e.lastMark
+	return e.lastMark
+}
+
+// writeCommit writes the blobs that have been added or modified by
+// the commit, then the commit itself
+func (e *fastExporter) writeCommit(c *object.Commit, refName string) error {
+	parentTree := ginternals.NullOid
+	if parents := c.ParentIDs(); len(parents) > 0 {
+		parent, err := e.r.Commit(parents[0])
+		if err != nil {
+			return fmt.Errorf("could not get commit %s: %w", parents[0].String(), err)
+		}
+		parentTree = parent.TreeID()
+	}
+	changes := []treeChange{}
+	if err := e.r.diffTrees(parentTree, c.TreeID(), "", &changes); err != nil {
+		return fmt.Errorf("could not diff commit %s: %w", c.ID().String(), err)
+	}
+
+	for _, change := range changes {
+		if change.to == nil || change.to.Mode == object.ModeGitLink {
+			continue
+		}
+		if _, ok := e.marks[change.to.ID]; ok {
+			continue
+		}
+		if err := e.writeBlob(change.to.ID); err != nil {
+			return err
+		}
+	}
+
+	if len(c.ParentIDs()) == 0 {
+		fmt.Fprintf(e.w, "reset %s\n", refName)
+	}
+	fmt.Fprintf(e.w, "commit %s\n", refName)
+	fmt.Fprintf(e.w, "mark :%d\n", e.mark(c.ID()))
+	fmt.Fprintf(e.w, "author %s\n", c.Author().String())
+	fmt.Fprintf(e.w, "committer %s\n", c.Committer().String())
+	fmt.Fprintf(e.w, "data %d\n%s", len(c.Message()), c.Message())
+	for i, parentID := range c.ParentIDs() {
+		cmd := "merge"
+		if i == 0 {
+			cmd = "from"
+		}
+		fmt.Fprintf(e.w, "%s :%d\n", cmd, e.marks[parentID])
+	}
+
+	// The deletions are written first so a file can be replaced by
+	// a directory of the same name
+	for _, change := range changes {
+		if change.to == nil {
+			fmt.Fprintf(e.w, "D %s\n", fastExportPath(change.from.Path))
+		}
+	}
+	for _, change := range changes {
+		switch {
+		case change.to == nil:
+		case change.to.Mode == object.ModeGitLink:
+			fmt.Fprintf(e.w, "M %06o %s %s\n", change.to.Mode, change.to.ID.String(), fastExportPath(change.to.Path))
+		default:
+			fmt.Fprintf(e.w, "M %06o :%d %s\n", change.to.Mode, e.marks[change.to.ID], fastExportPath(change.to.Path))
+		}
+	}
+	e.w.WriteString("\n") //nolint:errcheck // the error is returned by Flush
+	return nil
+}
+
+// writeBlob writes the given blob
+func (e *fastExporter) writeBlob(oid ginternals.Oid) error {
+	blob, err := e.r.Blob(oid)
+	if err != nil {
+		return fmt.Errorf("could not get blob %s: %w", oid.String(), err)
+	}
+	data := blob.Bytes()
+	fmt.Fprintf(e.w, "blob\nmark :%d\ndata %d\n", e.mark(oid), len(data))
+	e.w.Write(data)       //nolint:errcheck // the error is returned by Flush
+	e.w.WriteString("\n") //nolint:errcheck // the error is returned by Flush
+	return nil
+}
+
+// writeTag writes the annotated tag targeted by the given reference
+func (e *fastExporter) writeTag(ref *fastExportRef) error {
+	tag := ref.tag
+	message := tag.Message()
+	sigStart := tagSignatureStart(message)
+	if sigStart != -1 || tag.GPGSig() != "" {
+		switch e.opts.SignedTags {
+		case SignedTagsAbort:
+			return fmt.Errorf("%s: %w", ref.name, ErrSignedTag)
+		case SignedTagsStrip:
+			if sigStart != -1 {
+				message = message[:sigStart]
+			}
+		case SignedTagsVerbatim:
+		}
+	}
+
+	fmt.Fprintf(e.w, "tag %s\n", tag.Name())
+	fmt.Fprintf(e.w, "from :%d\n", e.marks[tag.Target()])
+	if !tag.Tagger().IsZero() {
+		fmt.Fprintf(e.w, "tagger %s\n", tag.Tagger().String())
+	}
+	fmt.Fprintf(e.w, "data %d\n%s\n", len(message), message)
+	return nil
+}
+
+// tagSignatureStart returns the offset of the signature of a tag
+// message, or -1 if the message is not signed.
+// Like git, the signature is the last line starting with one of the
+// known signature headers, and everything that follows it
+func tagSignatureStart(message string) int {
+	start := -1
+	for offset := 0; offset < len(message); {
+		end := strings.IndexByte(message[offset:], '\n')
+		if end == -1 {
+			end = len(message)
+		} else {
+			end += offset + 1
+		}
+		line := strings.TrimRight(message[offset:end], "\n")
+		for _, header := range signatureHeaders {
+			if line == header {
+				start = offset
+				break
+			}
+		}
+		offset = end
+	}
+	return start
+}
+
+// fastExportPath returns the path as it should be written in a
+// fast-import stream. The paths are quoted when they would be
+// ambiguous, which is when they start with a quote or contain
+// a new line
+func fastExportPath(p string) string {
+	if !strings.HasPrefix(p, `"`) && !strings.Contains(p, "\n") {
+		return p
+	}
+	b := &strings.Builder{}
+	b.WriteByte('"')
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFastExport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should export the branches and tags", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		out := &bytes.Buffer{}
+		err = r.FastExport(out, FastExportOptions{
+			Refs: []string{"refs/heads/master", "refs/tags/annotated", "refs/tags/lightweight"},
+		})
+		require.NoError(t, err)
+		stream := out.String()
+
+		assert.True(t, strings.HasPrefix(stream, "blob\nmark :1\n"), "the stream should start with a blob")
+		assert.Contains(t, stream, "reset refs/heads/master\ncommit refs/heads/master\nmark :3\nauthor Melvin <Nivl@users.noreply.github.com> 1563691982 -0700\ncommitter GitHub <noreply@github.com> 1563691982 -0700\ndata 14\nInitial commitM 100644 :1 .gitignore\nM 100644 :2 README.md\n\n")
+		assert.NotContains(t, stream, "commit refs/tags/")
+		assert.Contains(t, stream, "reset refs/tags/lightweight\nfrom :")
+		assert.Contains(t, stream, "tag annotated\nfrom :")
+		assert.Contains(t, stream, "tagger Melvin Laplanche <melvin.wont.reply@gmail.com> 1599958561 -0700\ndata 14\nannotated tag\n\n")
+	})
+
+	t.Run("signed tags", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		targetID, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		target, err := r.Object(targetID)
+		require.NoError(t, err)
+		signature := "-----BEGIN PGP SIGNATURE-----\n\nabcdef\n-----END PGP SIGNATURE-----\n"
+		_, err = r.NewTag(&object.TagParams{
+			Target:  target,
+			Name:    "signed",
+			Tagger:  object.NewSignature("tagger", "tagger@domain.tld"),
+			Message: "signed tag\n" + signature,
+		})
+		require.NoError(t, err)
+
+		testCases := []struct {
+			desc            string
+			mode            SignedTagsMode
+			expectedMessage string
+			expectedError   error
+		}{
+			{
+				desc:          "abort",
+				mode:          SignedTagsAbort,
+				expectedError: ErrSignedTag,
+			},
+			{
+				desc:            "verbatim",
+				mode:            SignedTagsVerbatim,
+				expectedMessage: "signed tag\n" + signature,
+			},
+			{
+				desc:            "strip",
+				mode:            SignedTagsStrip,
+				expectedMessage: "signed tag\n",
+			},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				out := &bytes.Buffer{}
+				err := r.FastExport(out, FastExportOptions{
+					Refs:       []string{"refs/tags/signed"},
+					SignedTags: tc.mode,
+				})
+				if tc.expectedError != nil {
+					require.ErrorIs(t, err, tc.expectedError)
+					return
+				}
+				require.NoError(t, err)
+				assert.True(t, strings.HasSuffix(out.String(), fmt.Sprintf("data %d\n%s\n", len(tc.expectedMessage), tc.expectedMessage)))
+			})
+		}
+	})
+
+	t.Run("should be importable by git", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		out := &bytes.Buffer{}
+		require.NoError(t, r.FastExport(out, FastExportOptions{}))
+
+		dest, cleanupDest := testutil.TempDir(t)
+		t.Cleanup(cleanupDest)
+		require.NoError(t, exec.Command("git", "init", "-q", "--bare", dest).Run())
+		cmd := exec.Command("git", "-C", dest, "fast-import", "--quiet")
+		cmd.Stdin = out
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+
+		// The commit signatures are not exported, so only the trees
+		// are expected to be the same
+		for _, name := range []string{"refs/heads/master", "refs/heads/ml/tests", "refs/tags/annotated"} {
+			ref, err := r.Reference(name)
+			require.NoError(t, err)
+			c, err := r.peelToCommit(ref.Target())
+			require.NoError(t, err)
+			output, err := exec.Command("git", "-C", dest, "rev-parse", name+"^{tree}").Output()
+			require.NoError(t, err)
+			assert.Equal(t, c.TreeID().String(), strings.TrimSpace(string(output)), name)
+		}
+		output, err = exec.Command("git", "-C", dest, "cat-file", "-t", "refs/tags/annotated").Output()
+		require.NoError(t, err)
+		assert.Equal(t, "tag", strings.TrimSpace(string(output)))
+	})
+}
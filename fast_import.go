@@ -0,0 +1,1041 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/spf13/afero"
+)
+
+// List of errors returned by FastImport
+var (
+	// ErrInvalidFastImportStream is an error thrown when a fast-import
+	// stream cannot be parsed
+	ErrInvalidFastImportStream = errors.New("invalid fast-import stream")
+	// ErrUnsupportedFastImportCommand is an error thrown when a
+	// fast-import stream contains a command or a feature that is
+	// not supported
+	ErrUnsupportedFastImportCommand = errors.New("unsupported fast-import command")
+	// ErrNotFastForward is an error thrown when a branch cannot be
+	// updated because its new commit doesn't contain its current one
+	ErrNotFastForward = errors.New("not a fast-forward")
+)
+
+// FastImportProgress contains the state of an import
+type FastImportProgress struct {
+	// Message contains the message of the progress command being
+	// reported. It's empty for the report sent at the end of
+	// the import
+	Message string
+	Blobs   int
+	Commits int
+	Tags    int
+}
+
+// FastImportOptions represents the options that can be used to
+// import a fast-import stream
+type FastImportOptions struct {
+	// ImportMarksPath contains the path of a marks file to load
+	// before the import, so the stream can reference objects imported
+	// by a previous run.
+	// This is the equivalent of --import-marks
+	ImportMarksPath string
+	// ExportMarksPath contains the path of the file to which the
+	// marks are written at every checkpoint and at the end of the
+	// import.
+	// This is the equivalent of --export-marks
+	ExportMarksPath string
+	// Force updates the branches even if their new commit doesn't
+	// contain their current commit.
+	// This is the equivalent of --force
+	Force bool
+	// Progress is called every time the stream contains a progress
+	// command, and once at the end of the import
+	Progress func(FastImportProgress)
+}
+
+// fastImportTree represents a directory being modified by an import
+type fastImportTree struct {
+	entries map[string]*fastImportEntry
+}
+
+// fastImportEntry represents an entry of a fastImportTree
+type fastImportEntry struct {
+	mode object.TreeObjectMode
+	// id contains the oid of the entry. It's NullOid for the
+	// directories that have been modified and need to be written
+	id ginternals.Oid
+	// tree contains the content of the directory. It's nil until
+	// the directory is needed
+	tree *fastImportTree
+}
+
+// fastImportBranch represents a branch being modified by an import
+type fastImportBranch struct {
+	tip ginternals.Oid
+	// tree contains the tree of the tip. It's nil until the tree
+	// is needed
+	tree *fastImportTree
+}
+
+// fastImporter contains the state of an import
+type fastImporter struct {
+	r    *Repository
+	opts FastImportOptions
+	in   *bufio.Reader
+	// unread contains a line that has been read but not processed
+	unread    string
+	hasUnread bool
+
+	marks    map[int]ginternals.Oid
+	branches map[string]*fastImportBranch
+	// tags maps the reference of the annotated tags to their oid
+	tags map[string]ginternals.Oid
+
+	requiresDone bool
+	progress     FastImportProgress
+}
+
+// FastImport reads a stream using the format of git fast-import, and
+// writes the objects and references it contains in the repository.
+// This is the equivalent of git fast-import.
+// The references are updated at every checkpoint and at the end of
+// the import.
+// The notes, the encoding of the commits, and the commands used to
+// read data back (ls, cat-blob, get-mark) are not supported.
+// https://git-scm.com/docs/git-fast-import
+func (r *Repository) FastImport(in io.Reader, opts FastImportOptions) error {
+	im := &fastImporter{
+		r:        r,
+		opts:     opts,
+		in:       bufio.NewReader(in),
+		marks:    map[int]ginternals.Oid{},
+		branches: map[string]*fastImportBranch{},
+		tags:     map[string]ginternals.Oid{},
+	}
+	if opts.ImportMarksPath != "" {
+		if err := im.importMarks(opts.ImportMarksPath, false); err != nil {
+			return err
+		}
+	}
+
+	if err := im.run(); err != nil {
+		return err
+	}
+	if err := im.checkpoint(); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		im.progress.Message = ""
+		opts.Progress(im.progress)
+	}
+	return nil
+}
+
+// run processes the commands of the stream
+func (im *fastImporter) run() error {
+	for {
+		line, ok, err := im.readLine()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if im.requiresDone {
+				return fmt.Errorf("stream ended without a done command: %w", ErrInvalidFastImportStream)
+			}
+			return nil
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case line == "blob":
+			err = im.blob()
+		case strings.HasPrefix(line, "commit "):
+			err = im.commit(strings.TrimPrefix(line, "commit "))
+		case strings.HasPrefix(line, "tag "):
+			err = im.tag(strings.TrimPrefix(line, "tag "))
+		case strings.HasPrefix(line, "reset "):
+			err = im.reset(strings.TrimPrefix(line, "reset "))
+		case line == "checkpoint":
+			err = im.checkpoint()
+		case strings.HasPrefix(line, "progress "):
+			if im.opts.Progress != nil {
+				im.progress.Message = strings.TrimPrefix(line, "progress ")
+				im.opts.Progress(im.progress)
+			}
+		case line == "done":
+			return nil
+		case strings.HasPrefix(line, "feature "):
+			err = im.feature(strings.TrimPrefix(line, "feature "))
+		case strings.HasPrefix(line, "option "):
+			// Like git, the options of other programs are ignored,
+			// and so are the options of git, since they only change
+			// the output of git fast-import
+		default:
+			return fmt.Errorf("%q: %w", line, ErrUnsupportedFastImportCommand)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLine returns the next line of the stream, without its LF.
+// ok is false once the end of the stream has been reached
+func (im *fastImporter) readLine() (line string, ok bool, err error) {
+	if im.hasUnread {
+		im.hasUnread = false
+		return im.unread, true, nil
+	}
+	line, err = im.in.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return line, line != "", nil
+		}
+		return "", false, fmt.Errorf("could not read the stream: %w", err)
+	}
+	return strings.TrimSuffix(line, "\n"), true, nil
+}
+
+// unreadLine puts back a line so it's returned by the next call
+// of readLine
+func (im *fastImporter) unreadLine(line string) {
+	im.unread = line
+	im.hasUnread = true
+}
+
+// readOptional returns the argument of the next line if it's the
+// given command. The line is put back otherwise
+func (im *fastImporter) readOptional(cmd string) (arg string, ok bool, err error) {
+	line, ok, err := im.readLine()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if !strings.HasPrefix(line, cmd+" ") {
+		im.unreadLine(line)
+		return "", false, nil
+	}
+	return strings.TrimPrefix(line, cmd+" "), true, nil
+}
+
+// readMark reads the optional mark command
+func (im *fastImporter) readMark() (mark int, err error) {
+	arg, ok, err := im.readOptional("mark")
+	if err != nil || !ok {
+		return 0, err
+	}
+	return parseFastImportMark(arg)
+}
+
+// readData reads a data command, which can either contain the size
+// of the data (data 42) or a delimiter (data <<EOF)
+func (im *fastImporter) readData() ([]byte, error) {
+	arg, ok, err := im.readOptional("data")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected a data command: %w", ErrInvalidFastImportStream)
+	}
+
+	if strings.HasPrefix(arg, "<<") {
+		delim := arg[2:]
+		data := &bytes.Buffer{}
+		for {
+			line, ok, err := im.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("missing delimiter %s: %w", delim, ErrInvalidFastImportStream)
+			}
+			if line == delim {
+				return data.Bytes(), nil
+			}
+			data.WriteString(line)
+			data.WriteByte('\n')
+		}
+	}
+
+	size, err := strconv.Atoi(arg)
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid data size %q: %w", arg, ErrInvalidFastImportStream)
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(im.in, data); err != nil {
+		return nil, fmt.Errorf("could not read %d bytes of data: %w", size, err)
+	}
+	// The data can be followed by an optional LF
+	if next, err := im.in.Peek(1); err == nil && next[0] == '\n' {
+		im.in.Discard(1) //nolint:errcheck // the byte has already been read by Peek
+	}
+	return data, nil
+}
+
+// blob processes a blob command
+func (im *fastImporter) blob() error {
+	mark, err := im.readMark()
+	if err != nil {
+		return err
+	}
+	if _, _, err = im.readOptional("original-oid"); err != nil {
+		return err
+	}
+	data, err := im.readData()
+	if err != nil {
+		return err
+	}
+	blob, err := im.r.NewBlob(data)
+	if err != nil {
+		return err
+	}
+	im.progress.Blobs++
+	im.setMark(mark, blob.ID())
+	return nil
+}
+
+// commit processes a commit command
+func (im *fastImporter) commit(refName string) error {
+	mark, err := im.readMark()
+	if err != nil {
+		return err
+	}
+	if _, _, err = im.readOptional("original-oid"); err != nil {
+		return err
+	}
+	author, hasAuthor, err := im.readSignature("author")
+	if err != nil {
+		return err
+	}
+	committer, ok, err := im.readSignature("committer")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("commit %s has no committer: %w", refName, ErrInvalidFastImportStream)
+	}
+	if !hasAuthor {
+		author = committer
+	}
+	if _, ok, err = im.readOptional("encoding"); err != nil || ok {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("encoding: %w", ErrUnsupportedFastImportCommand)
+	}
+	message, err := im.readData()
+	if err != nil {
+		return err
+	}
+
+	b, err := im.branch(refName)
+	if err != nil {
+		return err
+	}
+	from, hasFrom, err := im.readOptional("from")
+	if err != nil {
+		return err
+	}
+	if hasFrom {
+		if b.tip, err = im.resolveCommit(from); err != nil {
+			return err
+		}
+		b.tree = nil
+	}
+	parents := []ginternals.Oid{}
+	if !b.tip.IsZero() {
+		parents = append(parents, b.tip)
+	}
+	for {
+		merge, ok, err := im.readOptional("merge")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		parent, err := im.resolveCommit(merge)
+		if err != nil {
+			return err
+		}
+		parents = append(parents, parent)
+	}
+
+	if err = im.loadBranchTree(b); err != nil {
+		return err
+	}
+	if err = im.fileCommands(b); err != nil {
+		return err
+	}
+
+	treeID, err := im.writeTree(b.tree)
+	if err != nil {
+		return err
+	}
+	if treeID.IsZero() {
+		o := object.NewTree([]object.TreeEntry{}).ToObject()
+		if treeID, err = im.r.dotGit.WriteObject(o); err != nil {
+			return fmt.Errorf("could not write the empty tree: %w", err)
+		}
+	}
+	c := object.NewCommit(treeID, author, &object.CommitOptions{
+		Message:   string(message),
+		Committer: committer,
+		ParentsID: parents,
+	})
+	if _, err = im.r.dotGit.WriteObject(c.ToObject()); err != nil {
+		return fmt.Errorf("could not write the commit: %w", err)
+	}
+	b.tip = c.ID()
+	im.progress.Commits++
+	im.setMark(mark, c.ID())
+	return nil
+}
+
+// readSignature reads the optional author, committer, or tagger
+// command. Only the raw date format is supported
+func (im *fastImporter) readSignature(cmd string) (sig object.Signature, ok bool, err error) {
+	arg, ok, err := im.readOptional(cmd)
+	if err != nil || !ok {
+		return sig, false, err
+	}
+	sig, err = object.NewSignatureFromBytes([]byte(arg))
+	if err != nil {
+		return sig, false, fmt.Errorf("invalid %s %q: %w", cmd, arg, ErrInvalidFastImportStream)
+	}
+	return sig, true, nil
+}
+
+// fileCommands processes the file commands of a commit, which end
+// at the first line that is not a file command
+func (im *fastImporter) fileCommands(b *fastImportBranch) error {
+	for {
+		line, ok, err := im.readLine()
+		if err != nil || !ok {
+			return err
+		}
+		switch {
+		case line == "":
+			return nil
+		case strings.HasPrefix(line, "M "):
+			err = im.fileModify(b, strings.TrimPrefix(line, "M "))
+		case strings.HasPrefix(line, "D "):
+			var path string
+			if path, _, err = parseFastImportPath(strings.TrimPrefix(line, "D "), false); err == nil {
+				err = im.removeEntry(b.tree, path)
+			}
+		case strings.HasPrefix(line, "C "), strings.HasPrefix(line, "R "):
+			err = im.fileCopy(b, line[2:], line[0] == 'R')
+		case line == "deleteall":
+			b.tree = &fastImportTree{entries: map[string]*fastImportEntry{}}
+		case strings.HasPrefix(line, "N "):
+			return fmt.Errorf("notes: %w", ErrUnsupportedFastImportCommand)
+		default:
+			im.unreadLine(line)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// fileModify processes a filemodify command (M <mode> <dataref> <path>)
+func (im *fastImporter) fileModify(b *fastImportBranch, args string) error {
+	fields := strings.SplitN(args, " ", 3)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid filemodify %q: %w", args, ErrInvalidFastImportStream)
+	}
+	mode, err := parseFastImportMode(fields[0])
+	if err != nil {
+		return err
+	}
+	path, _, err := parseFastImportPath(fields[2], false)
+	if err != nil {
+		return err
+	}
+
+	var oid ginternals.Oid
+	if fields[1] == "inline" {
+		data, err := im.readData()
+		if err != nil {
+			return err
+		}
+		blob, err := im.r.NewBlob(data)
+		if err != nil {
+			return err
+		}
+		im.progress.Blobs++
+		oid = blob.ID()
+	} else if oid, err = im.resolveObject(fields[1]); err != nil {
+		return err
+	}
+
+	if path == "" {
+		if mode != object.ModeDirectory {
+			return fmt.Errorf("only a tree can replace the root: %w", ErrInvalidFastImportStream)
+		}
+		b.tree, err = im.loadTree(oid)
+		return err
+	}
+	return im.setEntry(b.tree, path, &fastImportEntry{mode: mode, id: oid})
+}
+
+// fileCopy processes a filecopy or a filerename command
+// (C <src> <dst> and R <src> <dst>)
+func (im *fastImporter) fileCopy(b *fastImportBranch, args string, rename bool) error {
+	src, rest, err := parseFastImportPath(args, true)
+	if err != nil {
+		return err
+	}
+	dst, _, err := parseFastImportPath(rest, false)
+	if err != nil {
+		return err
+	}
+
+	entry, err := im.entry(b.tree, src)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("%s: %w", src, ErrPathNotFound)
+	}
+	// The content of the directories may have been modified, so we
+	// need to write them to get their oid
+	copied := &fastImportEntry{mode: entry.mode, id: entry.id}
+	if entry.mode == object.ModeDirectory && entry.id.IsZero() {
+		if copied.id, err = im.writeTree(entry.tree); err != nil {
+			return err
+		}
+		if copied.id.IsZero() {
+			return fmt.Errorf("%s: %w", src, ErrPathNotFound)
+		}
+	}
+	if rename {
+		if err = im.removeEntry(b.tree, src); err != nil {
+			return err
+		}
+	}
+	return im.setEntry(b.tree, dst, copied)
+}
+
+// tag processes a tag command
+func (im *fastImporter) tag(name string) error {
+	mark, err := im.readMark()
+	if err != nil {
+		return err
+	}
+	from, ok, err := im.readOptional("from")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("tag %s has no from command: %w", name, ErrInvalidFastImportStream)
+	}
+	targetID, err := im.resolveObject(from)
+	if err != nil {
+		return err
+	}
+	if _, _, err = im.readOptional("original-oid"); err != nil {
+		return err
+	}
+	tagger, _, err := im.readSignature("tagger")
+	if err != nil {
+		return err
+	}
+	message, err := im.readData()
+	if err != nil {
+		return err
+	}
+
+	target, err := im.r.dotGit.Object(targetID)
+	if err != nil {
+		return fmt.Errorf("could not get the target of tag %s: %w", name, err)
+	}
+	tag := object.NewTag(&object.TagParams{
+		Target:  target,
+		Name:    name,
+		Tagger:  tagger,
+		Message: string(message),
+	})
+	o := tag.ToObject()
+	if _, err = im.r.dotGit.WriteObject(o); err != nil {
+		return fmt.Errorf("could not write tag %s: %w", name, err)
+	}
+	im.tags[ginternals.LocalTagFullName(name)] = o.ID()
+	im.progress.Tags++
+	im.setMark(mark, o.ID())
+	return nil
+}
+
+// reset processes a reset command, which (re)creates a branch
+func (im *fastImporter) reset(refName string) error {
+	b := &fastImportBranch{}
+	from, ok, err := im.readOptional("from")
+	if err != nil {
+		return err
+	}
+	if ok {
+		if b.tip, err = im.resolveCommit(from); err != nil {
+			return err
+		}
+	}
+	im.branches[refName] = b
+	delete(im.tags, refName)
+	return nil
+}
+
+// feature processes a feature command
+func (im *fastImporter) feature(feature string) error {
+	switch {
+	case feature == "done":
+		im.requiresDone = true
+	case feature == "date-format=raw", feature == "force" && im.opts.Force:
+	case feature == "force":
+		im.opts.Force = true
+	case strings.HasPrefix(feature, "import-marks="), strings.HasPrefix(feature, "import-marks-if-exists="):
+		// Like git, the marks files provided by the caller take
+		// precedence over the ones of the stream
+		if im.opts.ImportMarksPath != "" {
+			return nil
+		}
+		i := strings.IndexByte(feature, '=')
+		return im.importMarks(feature[i+1:], strings.HasPrefix(feature, "import-marks-if-exists="))
+	case strings.HasPrefix(feature, "export-marks="):
+		if im.opts.ExportMarksPath == "" {
+			im.opts.ExportMarksPath = strings.TrimPrefix(feature, "export-marks=")
+		}
+	default:
+		return fmt.Errorf("feature %s: %w", feature, ErrUnsupportedFastImportCommand)
+	}
+	return nil
+}
+
+// checkpoint updates the references and exports the marks
+func (im *fastImporter) checkpoint() error {
+	names := make([]string, 0, len(im.branches)+len(im.tags))
+	targets := make(map[string]ginternals.Oid, len(im.branches)+len(im.tags))
+	for name, b := range im.branches {
+		// A branch that has been reset without a commit is not
+		// created
+		if !b.tip.IsZero() {
+			names = append(names, name)
+			targets[name] = b.tip
+		}
+	}
+	for name, oid := range im.tags {
+		if _, ok := targets[name]; !ok {
+			names = append(names, name)
+		}
+		targets[name] = oid
+	}
+	sort.Strings(names)
+
+	// Like git, we update as many references as possible, and
+	// report the first failure
+	var firstErr error
+	for _, name := range names {
+		if err := im.updateReference(name, targets[name]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if im.opts.ExportMarksPath != "" {
+		return im.exportMarks(im.opts.ExportMarksPath)
+	}
+	return nil
+}
+
+// updateReference updates the given reference. The branches are only
+// updated if the change is a fast-forward, unless Force is set
+func (im *fastImporter) updateReference(name string, target ginternals.Oid) error {
+	current, err := im.r.dotGit.Reference(name)
+	if err != nil && !errors.Is(err, ginternals.ErrRefNotFound) {
+		return fmt.Errorf("could not get reference %s: %w", name, err)
+	}
+	if current != nil {
+		if current.Target() == target {
+			return nil
+		}
+		if _, isTag := im.tags[name]; !isTag && !im.opts.Force {
+			ok, err := im.r.IsAncestor(current.Target(), target)
+			if err != nil {
+				return fmt.Errorf("could not check if %s can be updated: %w", name, err)
+			}
+			if !ok {
+				return fmt.Errorf("%s: %w", name, ErrNotFastForward)
+			}
+		}
+	}
+	if _, err = im.r.NewReference(name, target); err != nil {
+		return fmt.Errorf("could not update reference %s: %w", name, err)
+	}
+	return nil
+}
+
+// setMark records the object of the given mark. 0 means no mark
+func (im *fastImporter) setMark(mark int, oid ginternals.Oid) {
+	if mark != 0 {
+		im.marks[mark] = oid
+	}
+}
+
+// importMarks loads the marks of the given file, which contains one
+// ":<mark> <oid>" line per mark
+func (im *fastImporter) importMarks(path string, ignoreMissing bool) error {
+	data, err := afero.ReadFile(im.r.Config.FS, path)
+	if err != nil {
+		if ignoreMissing && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read the marks: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid mark %q: %w", line, ErrInvalidFastImportStream)
+		}
+		mark, err := parseFastImportMark(fields[0])
+		if err != nil {
+			return err
+		}
+		oid, err := ginternals.NewOidFromStr(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid oid in mark %q: %w", line, ErrInvalidFastImportStream)
+		}
+		im.marks[mark] = oid
+	}
+	return nil
+}
+
+// exportMarks writes all the marks to the given file
+func (im *fastImporter) exportMarks(path string) error {
+	marks := make([]int, 0, len(im.marks))
+	for mark := range im.marks {
+		marks = append(marks, mark)
+	}
+	sort.Ints(marks)
+
+	buf := &bytes.Buffer{}
+	for _, mark := range marks {
+		fmt.Fprintf(buf, ":%d %s\n", mark, im.marks[mark].String())
+	}
+	if err := afero.WriteFile(im.r.Config.FS, path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("could not write the marks: %w", err)
+	}
+	return nil
+}
+
+// branch returns the branch matching the given reference. Branches
+// that are not part of the import yet start at the commit targeted
+// by the reference in the repository, if any
+func (im *fastImporter) branch(refName string) (*fastImportBranch, error) {
+	if b, ok := im.branches[refName]; ok {
+		return b, nil
+	}
+	b := &fastImportBranch{}
+	ref, err := im.r.dotGit.Reference(refName)
+	switch {
+	case err == nil:
+		c, err := im.r.peelToCommit(ref.Target())
+		if err != nil {
+			return nil, fmt.Errorf("could not get the commit of %s: %w", refName, err)
+		}
+		b.tip = c.ID()
+	case !errors.Is(err, ginternals.ErrRefNotFound):
+		return nil, fmt.Errorf("could not get reference %s: %w", refName, err)
+	}
+	im.branches[refName] = b
+	return b, nil
+}
+
+// resolveObject returns the object referenced by a dataref or a
+// committish, which can be a mark (:42), an oid, or a reference
+func (im *fastImporter) resolveObject(ref string) (ginternals.Oid, error) {
+	if strings.HasPrefix(ref, ":") {
+		mark, err := parseFastImportMark(ref)
+		if err != nil {
+			return ginternals.NullOid, err
+		}
+		oid, ok := im.marks[mark]
+		if !ok {
+			return ginternals.NullOid, fmt.Errorf("mark %s not declared: %w", ref, ErrInvalidFastImportStream)
+		}
+		return oid, nil
+	}
+	if len(ref) == ginternals.OidHexSize {
+		if oid, err := ginternals.NewOidFromStr(ref); err == nil {
+			return oid, nil
+		}
+	}
+
+	name := strings.TrimSuffix(ref, "^0")
+	if b, ok := im.branches[name]; ok && !b.tip.IsZero() {
+		return b.tip, nil
+	}
+	if oid, ok := im.tags[name]; ok {
+		return oid, nil
+	}
+	r, err := im.r.dotGit.Reference(name)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not resolve %s: %w", ref, err)
+	}
+	return r.Target(), nil
+}
+
+// resolveCommit works like resolveObject, but peels the tags to
+// their commit
+func (im *fastImporter) resolveCommit(ref string) (ginternals.Oid, error) {
+	oid, err := im.resolveObject(ref)
+	if err != nil {
+		return ginternals.NullOid, err
+	}
+	c, err := im.r.peelToCommit(oid)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not get commit %s: %w", ref, err)
+	}
+	return c.ID(), nil
+}
+
+// loadBranchTree loads the tree of the tip of the branch, if it's not
+// loaded already
+func (im *fastImporter) loadBranchTree(b *fastImportBranch) (err error) {
+	if b.tree != nil {
+		return nil
+	}
+	treeID := ginternals.NullOid
+	if !b.tip.IsZero() {
+		c, err := im.r.Commit(b.tip)
+		if err != nil {
+			return fmt.Errorf("could not get commit %s: %w", b.tip.String(), err)
+		}
+		treeID = c.TreeID()
+	}
+	b.tree, err = im.loadTree(treeID)
+	return err
+}
+
+// loadTree returns the content of the given tree. The subtrees are
+// loaded when needed
+func (im *fastImporter) loadTree(oid ginternals.Oid) (*fastImportTree, error) {
+	entries, err := im.r.treeEntries(oid)
+	if err != nil {
+		return nil, err
+	}
+	t := &fastImportTree{entries: make(map[string]*fastImportEntry, len(entries))}
+	for _, e := range entries {
+		t.entries[e.Path] = &fastImportEntry{mode: e.Mode, id: e.ID}
+	}
+	return t, nil
+}
+
+// subtree returns the content of the given directory
+func (im *fastImporter) subtree(e *fastImportEntry) (*fastImportTree, error) {
+	if e.tree == nil {
+		t, err := im.loadTree(e.id)
+		if err != nil {
+			return nil, err
+		}
+		e.tree = t
+	}
+	return e.tree, nil
+}
+
+// parentTree returns the directory containing the given path, as well
+// as the name of the entry in this directory.
+// If modify is set, the missing directories are created, and all the
+// directories are marked as modified. Otherwise nil is returned if
+// a directory is missing
+func (im *fastImporter) parentTree(root *fastImportTree, path string, modify bool) (*fastImportTree, string, error) {
+	parts := strings.Split(path, "/")
+	t := root
+	for _, name := range parts[:len(parts)-1] {
+		e, ok := t.entries[name]
+		if !ok || e.mode != object.ModeDirectory {
+			if !modify {
+				return nil, "", nil
+			}
+			e = &fastImportEntry{
+				mode: object.ModeDirectory,
+				tree: &fastImportTree{entries: map[string]*fastImportEntry{}},
+			}
+			t.entries[name] = e
+		}
+		sub, err := im.subtree(e)
+		if err != nil {
+			return nil, "", err
+		}
+		if modify {
+			e.id = ginternals.NullOid
+		}
+		t = sub
+	}
+	return t, parts[len(parts)-1], nil
+}
+
+// entry returns the entry at the given path, or nil
+func (im *fastImporter) entry(root *fastImportTree, path string) (*fastImportEntry, error) {
+	t, name, err := im.parentTree(root, path, false)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return t.entries[name], nil
+}
+
+// setEntry adds or replaces the entry at the given path
+func (im *fastImporter) setEntry(root *fastImportTree, path string, e *fastImportEntry) error {
+	t, name, err := im.parentTree(root, path, true)
+	if err != nil {
+		return err
+	}
+	t.entries[name] = e
+	return nil
+}
+
+// removeEntry removes the entry at the given path. Nothing happens
+// if the entry doesn't exist
+func (im *fastImporter) removeEntry(root *fastImportTree, path string) error {
+	if e, err := im.entry(root, path); err != nil || e == nil {
+		return err
+	}
+	t, name, err := im.parentTree(root, path, true)
+	if err != nil {
+		return err
+	}
+	delete(t.entries, name)
+	return nil
+}
+
+// writeTree writes the given tree and the subtrees that have been
+// modified. Like git, the empty directories are removed. NullOid is
+// returned, and nothing is written, if the tree is empty
+func (im *fastImporter) writeTree(t *fastImportTree) (ginternals.Oid, error) {
+	entries := make([]object.TreeEntry, 0, len(t.entries))
+	for name, e := range t.entries {
+		if e.mode == object.ModeDirectory && e.id.IsZero() {
+			id, err := im.writeTree(e.tree)
+			if err != nil {
+				return ginternals.NullOid, err
+			}
+			if id.IsZero() {
+				delete(t.entries, name)
+				continue
+			}
+			e.id = id
+		}
+		entries = append(entries, object.TreeEntry{Path: name, ID: e.id, Mode: e.mode})
+	}
+	if len(entries) == 0 {
+		return ginternals.NullOid, nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compareTreeEntries(entries[i], entries[j]) < 0
+	})
+
+	o := object.NewTree(entries).ToObject()
+	if _, err := im.r.dotGit.WriteObject(o); err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not write tree: %w", err)
+	}
+	return o.ID(), nil
+}
+
+// parseFastImportMark parses a mark (:42)
+func parseFastImportMark(mark string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(mark, ":"))
+	if err != nil || n <= 0 || !strings.HasPrefix(mark, ":") {
+		return 0, fmt.Errorf("invalid mark %q: %w", mark, ErrInvalidFastImportStream)
+	}
+	return n, nil
+}
+
+// parseFastImportMode parses the mode of a filemodify command. Like
+// git, the short version of the modes of the files is accepted
+func parseFastImportMode(s string) (object.TreeObjectMode, error) {
+	switch s {
+	case "644":
+		return object.ModeFile, nil
+	case "755":
+		return object.ModeExecutable, nil
+	}
+	v, err := strconv.ParseUint(s, 8, 32)
+	mode := object.TreeObjectMode(v)
+	if err != nil || !mode.IsValid() {
+		return 0, fmt.Errorf("invalid mode %q: %w", s, ErrInvalidFastImportStream)
+	}
+	return mode, nil
+}
+
+// parseFastImportPath parses the path at the beginning of s, and
+// returns the rest of s.
+// A quoted path uses the C-style escapes. A path that is not quoted
+// ends at the first space if untilSpace is set, or at the end of s
+func parseFastImportPath(s string, untilSpace bool) (path, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		if i := strings.IndexByte(s, ' '); untilSpace && i != -1 {
+			return s[:i], s[i+1:], nil
+		}
+		return s, "", nil
+	}
+
+	b := &strings.Builder{}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), strings.TrimPrefix(s[i+1:], " "), nil
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			break
+		}
+		switch c = s[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '"', '\\':
+			b.WriteByte(c)
+		default:
+			// Octal escape (\303)
+			if i+3 > len(s) {
+				return "", "", fmt.Errorf("invalid escape in %s: %w", s, ErrInvalidFastImportStream)
+			}
+			v, err := strconv.ParseUint(s[i:i+3], 8, 8)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid escape in %s: %w", s, ErrInvalidFastImportStream)
+			}
+			b.WriteByte(byte(v))
+			i += 2
+		}
+	}
+	return "", "", fmt.Errorf("unterminated path %s: %w", s, ErrInvalidFastImportStream)
+}
@@ -0,0 +1,311 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastImportFile returns the content of the file at the given path,
+// in the commit targeted by the given reference
+func fastImportFile(t *testing.T, r *Repository, refName, path string) (string, bool) {
+	t.Helper()
+
+	ref, err := r.Reference(refName)
+	require.NoError(t, err)
+	c, err := r.peelToCommit(ref.Target())
+	require.NoError(t, err)
+	tree, err := r.Tree(c.TreeID())
+	require.NoError(t, err)
+
+	parts := strings.Split(path, "/")
+	for i, name := range parts {
+		entry, ok := tree.Entry(name)
+		if !ok {
+			return "", false
+		}
+		if i == len(parts)-1 {
+			blob, err := r.Blob(entry.ID)
+			require.NoError(t, err)
+			return string(blob.Bytes()), true
+		}
+		tree, err = r.Tree(entry.ID)
+		require.NoError(t, err)
+	}
+	return "", false
+}
+
+func TestFastImport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should import a stream", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		stream := strings.Join([]string{
+			"feature done",
+			"blob",
+			"mark :1",
+			"data 6",
+			"hello",
+			"",
+			"commit refs/heads/master",
+			"mark :2",
+			"author Author <author@domain.tld> 1600000000 +0200",
+			"committer Committer <committer@domain.tld> 1600000001 +0200",
+			"data <<EOF",
+			"first commit",
+			"EOF",
+			"M 644 :1 README.md",
+			"M 100755 inline dir/sub/script.sh",
+			"data 10",
+			"#!/bin/sh",
+			"M 644 :1 \"quoted\\tname\"",
+			"",
+			"progress first commit imported",
+			"commit refs/heads/master",
+			"committer Committer <committer@domain.tld> 1600000002 +0200",
+			"data 13",
+			"second commitR dir/sub moved",
+			"C README.md \"copy of README.md\"",
+			"D \"quoted\\tname\"",
+			"",
+			"tag v1.0.0",
+			"from :2",
+			"tagger Tagger <tagger@domain.tld> 1600000003 +0200",
+			"data 8",
+			"release",
+			"reset refs/heads/old",
+			"from :2",
+			"",
+			"done",
+			"",
+		}, "\n")
+
+		marksPath := filepath.Join(dir, "marks")
+		progress := []FastImportProgress{}
+		err = r.FastImport(strings.NewReader(stream), FastImportOptions{
+			ExportMarksPath: marksPath,
+			Progress: func(p FastImportProgress) {
+				progress = append(progress, p)
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, progress, 2)
+		assert.Equal(t, FastImportProgress{Message: "first commit imported", Blobs: 2, Commits: 1}, progress[0])
+		assert.Equal(t, FastImportProgress{Blobs: 2, Commits: 2, Tags: 1}, progress[1])
+
+		master, err := r.Reference("refs/heads/master")
+		require.NoError(t, err)
+		c, err := r.Commit(master.Target())
+		require.NoError(t, err)
+		assert.Equal(t, "second commit", c.Message())
+		assert.Equal(t, "Committer", c.Author().Name, "the committer should be used when there's no author")
+		require.Len(t, c.ParentIDs(), 1)
+
+		first, err := r.Commit(c.ParentIDs()[0])
+		require.NoError(t, err)
+		assert.Equal(t, "first commit\n", first.Message())
+		assert.Equal(t, "Author", first.Author().Name)
+		assert.Empty(t, first.ParentIDs())
+
+		content, ok := fastImportFile(t, r, "refs/heads/master", "moved/script.sh")
+		require.True(t, ok)
+		assert.Equal(t, "#!/bin/sh\n", content)
+		content, ok = fastImportFile(t, r, "refs/heads/master", "copy of README.md")
+		require.True(t, ok)
+		assert.Equal(t, "hello\n", content)
+		_, ok = fastImportFile(t, r, "refs/heads/master", "README.md")
+		assert.True(t, ok, "the source of a copy should be kept")
+		_, ok = fastImportFile(t, r, "refs/heads/master", "quoted\tname")
+		assert.False(t, ok, "the file should have been deleted")
+		_, ok = fastImportFile(t, r, "refs/heads/master", "dir")
+		assert.False(t, ok, "the empty directories should have been removed")
+		content, ok = fastImportFile(t, r, "refs/heads/old", "quoted\tname")
+		require.True(t, ok)
+		assert.Equal(t, "hello\n", content)
+
+		tagRef, err := r.Reference("refs/tags/v1.0.0")
+		require.NoError(t, err)
+		o, err := r.Object(tagRef.Target())
+		require.NoError(t, err)
+		tag, err := o.AsTag()
+		require.NoError(t, err)
+		assert.Equal(t, "release\n", tag.Message())
+		assert.Equal(t, first.ID(), tag.Target())
+
+		marks, err := os.ReadFile(marksPath)
+		require.NoError(t, err)
+		blobs, err := r.NewBlob([]byte("hello\n"))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(":1 %s\n:2 %s\n", blobs.ID().String(), first.ID().String()), string(marks))
+	})
+
+	t.Run("should import an exported repository", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		src, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, src.Close())
+		})
+		stream := &bytes.Buffer{}
+		require.NoError(t, src.FastExport(stream, FastExportOptions{}))
+
+		dir, cleanupDest := testutil.TempDir(t)
+		t.Cleanup(cleanupDest)
+		dest, err := InitRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, dest.Close())
+		})
+		require.NoError(t, dest.FastImport(stream, FastImportOptions{}))
+
+		// The commit signatures are not exported, so only the trees
+		// are expected to be the same
+		for _, name := range []string{"refs/heads/master", "refs/heads/ml/tests", "refs/tags/annotated"} {
+			ref, err := src.Reference(name)
+			require.NoError(t, err)
+			expected, err := src.peelToCommit(ref.Target())
+			require.NoError(t, err)
+
+			ref, err = dest.Reference(name)
+			require.NoError(t, err)
+			c, err := dest.peelToCommit(ref.Target())
+			require.NoError(t, err)
+			assert.Equal(t, expected.TreeID(), c.TreeID(), name)
+		}
+		ref, err := dest.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+		o, err := dest.Object(ref.Target())
+		require.NoError(t, err)
+		assert.Equal(t, object.TypeTag, o.Type())
+
+		// Make sure git can read what we wrote
+		if _, err := exec.LookPath("git"); err == nil {
+			output, err := exec.Command("git", "-C", dir, "fsck", "--no-dangling").CombinedOutput()
+			require.NoError(t, err, string(output))
+		}
+	})
+
+	t.Run("should use the marks of a previous import", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		marksPath := filepath.Join(dir, "marks")
+		first := "blob\nmark :1\ndata 3\nfoo\n"
+		err = r.FastImport(strings.NewReader(first), FastImportOptions{ExportMarksPath: marksPath})
+		require.NoError(t, err)
+
+		second := "commit refs/heads/master\ncommitter a <a@domain.tld> 1600000000 +0000\ndata 0\nM 644 :1 foo\n"
+		err = r.FastImport(strings.NewReader(second), FastImportOptions{})
+		require.ErrorIs(t, err, ErrInvalidFastImportStream, "the mark should not exist without the marks file")
+
+		err = r.FastImport(strings.NewReader(second), FastImportOptions{ImportMarksPath: marksPath})
+		require.NoError(t, err)
+		content, ok := fastImportFile(t, r, "refs/heads/master", "foo")
+		require.True(t, ok)
+		assert.Equal(t, "foo", content)
+	})
+
+	t.Run("should refuse to rewrite a branch", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		commit := "reset refs/heads/master\ncommit refs/heads/master\ncommitter a <a@domain.tld> %d +0000\ndata 0\n"
+		err = r.FastImport(strings.NewReader(fmt.Sprintf(commit, 1600000000)), FastImportOptions{})
+		require.NoError(t, err)
+		master, err := r.Reference("refs/heads/master")
+		require.NoError(t, err)
+
+		err = r.FastImport(strings.NewReader(fmt.Sprintf(commit, 1600000001)), FastImportOptions{})
+		require.ErrorIs(t, err, ErrNotFastForward)
+		ref, err := r.Reference("refs/heads/master")
+		require.NoError(t, err)
+		assert.Equal(t, master.Target(), ref.Target())
+
+		err = r.FastImport(strings.NewReader(fmt.Sprintf(commit, 1600000001)), FastImportOptions{Force: true})
+		require.NoError(t, err)
+		ref, err = r.Reference("refs/heads/master")
+		require.NoError(t, err)
+		assert.NotEqual(t, master.Target(), ref.Target())
+	})
+
+	t.Run("should fail on unsupported commands", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		err = r.FastImport(strings.NewReader("ls \"foo\"\n"), FastImportOptions{})
+		require.ErrorIs(t, err, ErrUnsupportedFastImportCommand)
+		err = r.FastImport(strings.NewReader("feature done\n"), FastImportOptions{})
+		require.ErrorIs(t, err, ErrInvalidFastImportStream)
+	})
+}
+
+func TestParseFastImportPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc         string
+		in           string
+		untilSpace   bool
+		expectedPath string
+		expectedRest string
+	}{
+		{desc: "plain path with spaces", in: "a b/c", expectedPath: "a b/c"},
+		{desc: "plain source", in: "a b/c", untilSpace: true, expectedPath: "a", expectedRest: "b/c"},
+		{desc: "quoted path", in: `"a \"b\"\n\303\251" rest`, untilSpace: true, expectedPath: "a \"b\"\né", expectedRest: "rest"},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			path, rest, err := parseFastImportPath(tc.in, tc.untilSpace)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, path)
+			assert.Equal(t, tc.expectedRest, rest)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package git
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// FileHistoryOptions represents the options that can be used to
+// get the history of a file
+type FileHistoryOptions struct {
+	// From contains the commit to start from.
+	// Defaults to HEAD
+	From ginternals.Oid
+	// Follow continues listing the history of the file beyond
+	// renames.
+	// This is the equivalent of --follow
+	Follow bool
+	// RenameThreshold is the minimum similarity (in percent) for a
+	// deleted file to be considered the previous version of the file.
+	// Defaults to diff.DefaultRenameThreshold
+	RenameThreshold int
+	// MaxCount represents the maximum number of commits to return.
+	// 0 means no limit
+	MaxCount int
+}
+
+// FileHistoryEntry represents a commit that modified a file
+type FileHistoryEntry struct {
+	Commit *object.Commit
+	// Path contains the path of the file in Commit. It differs from
+	// the requested path if the file has been renamed since Commit
+	Path string
+	// PreviousPath contains the path of the file in the parent of
+	// Commit if Commit renamed the file. It's empty otherwise
+	PreviousPath string
+}
+
+// FileHistoryFunc represents a function that will be applied on all
+// the commits found by FileHistory()
+type FileHistoryFunc = func(e FileHistoryEntry) error
+
+// FileHistory runs the provided method on all the commits that
+// modified, created, or deleted the file at the given path, most
+// recent commits (by committer date) first.
+// Like git, a merge commit is skipped if the file is the same in one
+// of its parents, in which case only the history of this parent is
+// walked.
+// Returning WalkStop from f stops the walk without error
+func (r *Repository) FileHistory(path string, opts FileHistoryOptions, f FileHistoryFunc) error {
+	from := opts.From
+	if from.IsZero() {
+		head, err := r.Head()
+		if err != nil {
+			return err
+		}
+		if head.IsUnborn() {
+			return nil
+		}
+		from = head.Target
+	}
+	start, err := r.peelToCommit(from)
+	if err != nil {
+		return err
+	}
+
+	// paths contains the path of the file in each of the commits
+	// to walk
+	paths := map[ginternals.Oid]string{start.ID(): path}
+	queue := &commitQueue{}
+	heap.Push(queue, start)
+	push := func(c *object.Commit, path string) {
+		if _, ok := paths[c.ID()]; ok {
+			return
+		}
+		paths[c.ID()] = path
+		heap.Push(queue, c)
+	}
+
+	for count := 0; queue.Len() > 0; {
+		if opts.MaxCount > 0 && count >= opts.MaxCount {
+			break
+		}
+		c := heap.Pop(queue).(*object.Commit)
+		entry := FileHistoryEntry{
+			Commit: c,
+			Path:   paths[c.ID()],
+		}
+		file, err := r.blameFile(c.TreeID(), entry.Path)
+		if err != nil {
+			return err
+		}
+
+		parents := make([]*object.Commit, 0, len(c.ParentIDs()))
+		parentFiles := make([]*object.TreeEntry, 0, len(c.ParentIDs()))
+		touched := true
+		for _, parentID := range c.ParentIDs() {
+			parent, err := r.Commit(parentID)
+			if err != nil {
+				return fmt.Errorf("could not get parent %s of %s: %w", parentID.String(), c.ID().String(), err)
+			}
+			parentFile, err := r.blameFile(parent.TreeID(), entry.Path)
+			if err != nil {
+				return err
+			}
+			// If the file didn't change compared to a parent, the
+			// history of the file is in this parent
+			if sameTreeEntry(file, parentFile) {
+				touched = false
+				parents = []*object.Commit{parent}
+				parentFiles = []*object.TreeEntry{parentFile}
+				break
+			}
+			parents = append(parents, parent)
+			parentFiles = append(parentFiles, parentFile)
+		}
+		// A root commit only touched the file if it created it
+		if len(parents) == 0 {
+			touched = file != nil
+		}
+
+		for i, parent := range parents {
+			parentPath := entry.Path
+			if touched && opts.Follow && file != nil && parentFiles[i] == nil {
+				oldPath, err := r.renamedFrom(parent.TreeID(), c.TreeID(), entry.Path, opts.RenameThreshold)
+				if err != nil {
+					return err
+				}
+				if oldPath != "" {
+					parentPath = oldPath
+					entry.PreviousPath = oldPath
+				}
+			}
+			push(parent, parentPath)
+		}
+
+		if !touched {
+			continue
+		}
+		count++
+		if err = f(entry); err != nil {
+			if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTreeEntry returns whether 2 entries, which may be nil,
+// represent the same file
+func sameTreeEntry(a, b *object.TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.Mode == b.Mode
+}
+
+// renamedFrom returns the path the file at the given path had in the
+// tree "from", if it has been renamed between the 2 trees.
+// An empty string is returned if the file hasn't been renamed
+func (r *Repository) renamedFrom(from, to ginternals.Oid, path string, threshold int) (string, error) {
+	opts := DefaultDiffOptions()
+	opts.DetectRenames = true
+	opts.RenameThreshold = threshold
+	changes := []treeChange{}
+	if err := r.diffTrees(from, to, "", &changes); err != nil {
+		return "", err
+	}
+	changes, err := r.detectRenames(changes, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range changes {
+		if c.to != nil && c.to.Path == path && c.from != nil && c.from.Path != path {
+			return c.from.Path, nil
+		}
+	}
+	return "", nil
+}
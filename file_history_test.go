@@ -0,0 +1,138 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHistory(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	// The entries are formatted as "sha path" or
+	// "sha path previous_path" for renames
+	testCases := []struct {
+		desc     string
+		path     string
+		from     string
+		opts     FileHistoryOptions
+		expected []string
+	}{
+		{
+			desc: "should list the commits that modified the file",
+			path: "go.mod",
+			expected: []string{
+				"d26b5b27935e59022de19939bb16c39f6b38a0f0 go.mod",
+				"5c283d5284084a0615e0a4b08c15297f067ddd04 go.mod",
+				"0499018e26f79d37ad056611b75730dcb12918fb go.mod",
+				"2f2e900b4e87ab0d51809642eaf0c5a12a97d927 go.mod",
+				"f0f70144f38695250606b86a50cff2b440a417f3 go.mod",
+				"24d4f7fb1f89b8a9ffca1650baed70a79f261c28 go.mod",
+				"1dcdadc2a420225783794fbffd51e2e137a69646 go.mod",
+			},
+		},
+		{
+			desc: "should respect MaxCount",
+			path: "README.md",
+			opts: FileHistoryOptions{MaxCount: 2},
+			expected: []string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 README.md",
+				"f96f63e52cb8862b2c2d1a8b868229259c57854e README.md",
+			},
+		},
+		{
+			desc: "should stop at the rename without Follow",
+			path: "plumbing/packfile/packfile.go",
+			expected: []string{
+				"add862f16c9befc4b88a24e22fda2fa9b68c1653 plumbing/packfile/packfile.go",
+			},
+		},
+		{
+			desc: "should follow the renames",
+			path: "plumbing/packfile/packfile.go",
+			opts: FileHistoryOptions{Follow: true},
+			expected: []string{
+				"add862f16c9befc4b88a24e22fda2fa9b68c1653 plumbing/packfile/packfile.go packfile.go",
+				"5c283d5284084a0615e0a4b08c15297f067ddd04 packfile.go",
+				"2f2e900b4e87ab0d51809642eaf0c5a12a97d927 packfile.go",
+				"f0f70144f38695250606b86a50cff2b440a417f3 packfile.go",
+				"925718a17eae5fc2c70ba547d20b6ed6674c898c packfile.go",
+				"d70260b4430fbc6416442545e44b4112ebfb504d packfile.go",
+				"645bda6fdb1a0651ac564394f8edb32b02dde7b3 packfile.go",
+			},
+		},
+		{
+			desc: "should not follow renames below the threshold",
+			path: "plumbing/packfile/packfile.go",
+			opts: FileHistoryOptions{Follow: true, RenameThreshold: 90},
+			expected: []string{
+				"add862f16c9befc4b88a24e22fda2fa9b68c1653 plumbing/packfile/packfile.go",
+			},
+		},
+		{
+			desc: "should start from the given commit",
+			path: "go.mod",
+			from: "2f2e900b4e87ab0d51809642eaf0c5a12a97d927",
+			expected: []string{
+				"2f2e900b4e87ab0d51809642eaf0c5a12a97d927 go.mod",
+				"f0f70144f38695250606b86a50cff2b440a417f3 go.mod",
+				"24d4f7fb1f89b8a9ffca1650baed70a79f261c28 go.mod",
+				"1dcdadc2a420225783794fbffd51e2e137a69646 go.mod",
+			},
+		},
+		{
+			desc:     "unknown file should have no history",
+			path:     "does/not/exist",
+			expected: []string{},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			if tc.from != "" {
+				var err error
+				tc.opts.From, err = ginternals.NewOidFromStr(tc.from)
+				require.NoError(t, err)
+			}
+			entries := []string{}
+			err := r.FileHistory(tc.path, tc.opts, func(e FileHistoryEntry) error {
+				entry := e.Commit.ID().String() + " " + e.Path
+				if e.PreviousPath != "" {
+					entry += " " + e.PreviousPath
+				}
+				entries = append(entries, entry)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, entries)
+		})
+	}
+
+	t.Run("should stop the walk", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.FileHistory("go.mod", FileHistoryOptions{}, func(e FileHistoryEntry) error {
+			count++
+			return WalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,307 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+const (
+	// patchDateFormat is the format of the dates used in the headers
+	// of a patch (RFC 2822)
+	patchDateFormat = "Mon, 2 Jan 2006 15:04:05 -0700"
+	// patchSignature is appended at the end of each patch
+	patchSignature = "git-go"
+	// maxPatchHeaderLength is the max length of a line of the header
+	// before it gets folded
+	maxPatchHeaderLength = 78
+	// maxEncodedWordLength is the max length of a line containing
+	// a RFC 2047 encoded-word
+	maxEncodedWordLength = 76
+	// maxPatchFileNameLength is the max length of the name of a
+	// patch file
+	maxPatchFileNameLength = 64
+)
+
+// FormatPatch writes all the commits of the range as a series of
+// patches, oldest first, using the mbox format.
+// rangeSpec can either be a range ("from..to"), or a single
+// revision, which is equivalent to "revision..HEAD".
+// Like git, merge commits and commits that don't introduce any
+// change are skipped
+func (r *Repository) FormatPatch(rangeSpec string, w io.Writer) error {
+	commits, err := r.PatchCommits(rangeSpec)
+	if err != nil {
+		return err
+	}
+	for i, c := range commits {
+		// Like git, the patches are separated by an empty line
+		if i > 0 {
+			if _, err = io.WriteString(w, "\n"); err != nil {
+				return fmt.Errorf("could not write the patch of %s: %w", c.ID().String(), err)
+			}
+		}
+		if err = r.WritePatch(w, c, i+1, len(commits)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchCommits returns the commits of the range that FormatPatch()
+// would generate a patch for, oldest first
+func (r *Repository) PatchCommits(rangeSpec string) ([]*object.Commit, error) {
+	if !strings.Contains(rangeSpec, "..") {
+		rangeSpec += ".."
+	}
+	rng, err := r.ResolveRevisionRange(rangeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", rangeSpec, err)
+	}
+
+	commits := []*object.Commit{}
+	err = r.WalkCommits(rng.Include, rng.WalkOptions(), func(c *object.Commit) error {
+		if len(c.ParentIDs()) > 1 {
+			return nil
+		}
+		parentTreeID := ginternals.NullOid
+		if len(c.ParentIDs()) == 1 {
+			parent, err := r.Commit(c.ParentIDs()[0])
+			if err != nil {
+				return fmt.Errorf("could not get parent of %s: %w", c.ID().String(), err)
+			}
+			parentTreeID = parent.TreeID()
+		}
+		if parentTreeID == c.TreeID() {
+			return nil
+		}
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the commits: %w", err)
+	}
+
+	// We walked the commits from the newest to the oldest
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// WritePatch writes the patch of a single commit to w, using the
+// email format used by FormatPatch().
+// number and total are used to number the patch in the subject
+// ([PATCH number/total]). The number is omitted if total is 1
+func (r *Repository) WritePatch(w io.Writer, c *object.Commit, number, total int) error {
+	parentTreeID := ginternals.NullOid
+	if len(c.ParentIDs()) > 0 {
+		parent, err := r.Commit(c.ParentIDs()[0])
+		if err != nil {
+			return fmt.Errorf("could not get parent of %s: %w", c.ID().String(), err)
+		}
+		parentTreeID = parent.TreeID()
+	}
+	patches, err := r.DiffTrees(parentTreeID, c.TreeID(), DefaultDiffOptions())
+	if err != nil {
+		return fmt.Errorf("could not diff %s: %w", c.ID().String(), err)
+	}
+
+	subject, body := splitCommitMessage(c.Message())
+	prefix := "[PATCH] "
+	if total > 1 {
+		prefix = fmt.Sprintf("[PATCH %d/%d] ", number, total)
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "From %s Mon Sep 17 00:00:00 2001\n", c.ID().String())
+	fmt.Fprintf(b, "From: %s <%s>\n", formatPatchName(c.Author().Name), c.Author().Email)
+	fmt.Fprintf(b, "Date: %s\n", c.Author().Time.Format(patchDateFormat))
+	header := "Subject: " + prefix
+	b.WriteString(header)
+	if isASCII(subject) {
+		b.WriteString(foldHeader(subject, len(header)))
+	} else {
+		b.WriteString(encodeHeader(subject, len(header), false))
+	}
+	b.WriteString("\n")
+	if !isASCII(c.Author().Name) || !isASCII(c.Message()) {
+		b.WriteString("MIME-Version: 1.0\n")
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\n")
+		b.WriteString("Content-Transfer-Encoding: 8bit\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(body)
+	b.WriteString("\n")
+	for _, p := range patches {
+		b.WriteString(p.String())
+	}
+	fmt.Fprintf(b, "-- \n%s\n\n", patchSignature)
+
+	if _, err = io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("could not write the patch of %s: %w", c.ID().String(), err)
+	}
+	return nil
+}
+
+// PatchFileName returns the name of the file that should contain the
+// patch of the given commit. Ex: 0001-fix-a-bug.patch
+func PatchFileName(c *object.Commit, number int) string {
+	prefix := fmt.Sprintf("%04d-", number)
+	suffix := ".patch"
+	subject, _ := splitCommitMessage(c.Message())
+
+	// Like git, we only keep the alphanumeric chars, the _ and the .
+	// (without repetition), and we replace everything else by a
+	// single -
+	name := &strings.Builder{}
+	needsDash := false
+	for i := 0; i < len(subject); i++ {
+		ch := subject[i]
+		isAllowed := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '.'
+		if !isAllowed {
+			needsDash = name.Len() > 0
+			continue
+		}
+		if ch == '.' && i > 0 && subject[i-1] == '.' {
+			continue
+		}
+		if needsDash {
+			name.WriteByte('-')
+			needsDash = false
+		}
+		name.WriteByte(ch)
+	}
+	sanitized := strings.TrimRight(name.String(), ".-")
+
+	// -1 to leave room for a NUL char, like git
+	maxLength := maxPatchFileNameLength - len(prefix) - len(suffix) - 1
+	if len(sanitized) > maxLength {
+		sanitized = sanitized[:maxLength]
+	}
+	return prefix + sanitized + suffix
+}
+
+// splitCommitMessage splits a commit message into a subject (the
+// first paragraph, on a single line) and a body
+func splitCommitMessage(msg string) (subject, body string) {
+	lines := strings.Split(strings.TrimLeft(msg, "\n"), "\n")
+	subjectLines := []string{}
+	i := 0
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		subjectLines = append(subjectLines, strings.TrimSpace(lines[i]))
+	}
+	subject = strings.Join(subjectLines, " ")
+
+	body = strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+	body = strings.TrimRight(body, "\n")
+	if body != "" {
+		body += "\n"
+	}
+	return subject, body
+}
+
+// formatPatchName formats the name of a person to be used in an
+// email header
+func formatPatchName(name string) string {
+	if !isASCII(name) {
+		return encodeHeader(name, len("From: "), true)
+	}
+	if strings.ContainsAny(name, `()<>@,;:\".[]`) {
+		name = strings.ReplaceAll(name, `\`, `\\`)
+		name = strings.ReplaceAll(name, `"`, `\"`)
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// foldHeader wraps the value of a header so that no line is
+// longer than maxPatchHeaderLength. Continuation lines start with
+// a space.
+// offset is the length of what's already on the first line
+func foldHeader(value string, offset int) string {
+	b := &strings.Builder{}
+	lineLen := offset
+	for i, word := range strings.Fields(value) {
+		if i > 0 {
+			if lineLen+1+len(word) > maxPatchHeaderLength {
+				b.WriteString("\n")
+				lineLen = 0
+			}
+			b.WriteString(" ")
+			lineLen++
+		}
+		b.WriteString(word)
+		lineLen += len(word)
+	}
+	return b.String()
+}
+
+// encodeHeader encodes the value of a header using RFC 2047
+// (Q-encoding), the same way git does.
+// offset is the length of what's already on the first line.
+// isAddress should be set when encoding the name part of an address
+// since more characters need to be encoded
+func encodeHeader(value string, offset int, isAddress bool) string {
+	const start = "=?UTF-8?q?"
+	const end = "?="
+
+	b := &strings.Builder{}
+	b.WriteString(start)
+	lineLen := offset + len(start)
+	for len(value) > 0 {
+		_, size := utf8.DecodeRuneInString(value)
+		chunk := value[:size]
+		value = value[size:]
+
+		encoded := chunk
+		if size > 1 || needsEncoding(chunk[0], isAddress) {
+			encoded = ""
+			for i := 0; i < len(chunk); i++ {
+				encoded += fmt.Sprintf("=%02X", chunk[i])
+			}
+		}
+		// We need to make sure the closing ?= fits on the line
+		if lineLen+len(encoded)+len(end) > maxEncodedWordLength {
+			b.WriteString(end + "\n " + start)
+			lineLen = len(start) + 1
+		}
+		b.WriteString(encoded)
+		lineLen += len(encoded)
+	}
+	b.WriteString(end)
+	return b.String()
+}
+
+// needsEncoding returns whether a char needs to be encoded in a
+// RFC 2047 encoded-word
+func needsEncoding(ch byte, isAddress bool) bool {
+	if ch < 0x20 || ch >= 0x7f {
+		return true
+	}
+	// spaces could be encoded with _, but like git we use =20
+	if ch == ' ' || ch == '=' || ch == '?' || ch == '_' {
+		return true
+	}
+	if !isAddress {
+		return false
+	}
+	if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') {
+		return false
+	}
+	return !strings.ContainsRune("!*+-/", rune(ch))
+}
+
+// isASCII returns whether s only contains ASCII chars
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
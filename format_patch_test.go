@@ -0,0 +1,175 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPatch(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	t.Run("should match the output of git", func(t *testing.T) {
+		t.Parallel()
+
+		expected, err := os.ReadFile(filepath.Join(testutil.TestdataPath(t), "patch_2f2e900b4e87ab0d51809642eaf0c5a12a97d927"))
+		require.NoError(t, err)
+
+		out := &bytes.Buffer{}
+		err = r.FormatPatch("3a78491a3bfb77d1d3b1bb3c5e808c3bba1e7da6..2f2e900b4e87ab0d51809642eaf0c5a12a97d927", out)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), out.String())
+	})
+
+	t.Run("should number the patches of a series", func(t *testing.T) {
+		t.Parallel()
+
+		commits, err := r.PatchCommits("ml/cleanup-062020..master")
+		require.NoError(t, err)
+		require.Len(t, commits, 8)
+
+		out := &bytes.Buffer{}
+		err = r.FormatPatch("ml/cleanup-062020..master", out)
+		require.NoError(t, err)
+		for i, c := range commits {
+			assert.Contains(t, out.String(), fmt.Sprintf("From %s Mon Sep 17 00:00:00 2001\n", c.ID().String()))
+			assert.Contains(t, out.String(), fmt.Sprintf("Subject: [PATCH %d/8] ", i+1))
+		}
+	})
+
+	t.Run("should fail with an unknown revision", func(t *testing.T) {
+		t.Parallel()
+
+		err := r.FormatPatch("does-not-exist", &bytes.Buffer{})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrUnknownRevision)
+	})
+}
+
+func TestPatchFileName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		message  string
+		number   int
+		expected string
+	}{
+		{
+			desc:     "simple subject",
+			message:  "Fix a bug\n",
+			number:   1,
+			expected: "0001-Fix-a-bug.patch",
+		},
+		{
+			desc:     "special chars should be replaced by a single dash",
+			message:  "feat(api): add   a  thing...\n\nwith a body\n",
+			number:   12,
+			expected: "0012-feat-api-add-a-thing.patch",
+		},
+		{
+			desc:     "long subjects should be truncated",
+			message:  "feat(scope): this is a very long subject line that goes on and on and on forever, really!\n",
+			number:   1,
+			expected: "0001-feat-scope-this-is-a-very-long-subject-line-that-goe.patch",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			c := object.NewCommit(ginternals.NullOid, object.NewSignature("John Doe", "john@domain.tld"), &object.CommitOptions{
+				Message: tc.message,
+			})
+			assert.Equal(t, tc.expected, PatchFileName(c, tc.number))
+		})
+	}
+}
+
+func TestWritePatchHeaders(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	headID, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	head, err := r.Commit(headID)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc            string
+		name            string
+		message         string
+		expectedHeaders []string
+	}{
+		{
+			desc:    "names with special chars should be quoted",
+			name:    "John D. Doe",
+			message: "Fix a bug\n",
+			expectedHeaders: []string{
+				"From: \"John D. Doe\" <john@domain.tld>\n",
+				"Subject: [PATCH] Fix a bug\n",
+			},
+		},
+		{
+			desc:    "non ascii values should be encoded",
+			name:    "Jöhn D. Doe",
+			message: "Fix a bug in café\n",
+			expectedHeaders: []string{
+				"From: =?UTF-8?q?J=C3=B6hn=20D=2E=20Doe?= <john@domain.tld>\n",
+				"Subject: [PATCH] =?UTF-8?q?Fix=20a=20bug=20in=20caf=C3=A9?=\n",
+				"Content-Type: text/plain; charset=UTF-8\n",
+			},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			sig := object.Signature{
+				Name:  tc.name,
+				Email: "john@domain.tld",
+				Time:  time.Unix(1600000000, 0).UTC(),
+			}
+			c := object.NewCommit(head.TreeID(), sig, &object.CommitOptions{
+				Message:   tc.message,
+				Committer: sig,
+				ParentsID: []ginternals.Oid{head.ID()},
+			})
+
+			out := &bytes.Buffer{}
+			require.NoError(t, r.WritePatch(out, c, 1, 1))
+			for _, h := range tc.expectedHeaders {
+				assert.Contains(t, out.String(), h)
+			}
+			assert.Contains(t, out.String(), "Date: Sun, 13 Sep 2020 12:26:40 +0000\n")
+		})
+	}
+}
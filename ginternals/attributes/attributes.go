@@ -0,0 +1,159 @@
+// Package attributes contains methods to parse and query the
+// gitattributes files
+// https://git-scm.com/docs/gitattributes
+package attributes
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/pathspec"
+)
+
+// FileName is the name of the files containing the attributes of the
+// files of a directory
+const FileName = ".gitattributes"
+
+// State represents the state of an attribute for a path
+type State int8
+
+const (
+	// Unspecified means that no rule sets the attribute for the path
+	Unspecified State = iota
+	// Set means that the attribute has been set (ex. "text")
+	Set
+	// Unset means that the attribute has been unset (ex. "-text")
+	Unset
+	// Valued means that the attribute has been set to a value
+	// (ex. "eol=lf")
+	Valued
+)
+
+// Attribute represents the value of an attribute for a path
+type Attribute struct {
+	State State
+	// Value contains the value of the attribute when State is Valued
+	Value string
+}
+
+// builtinMacros contains the macros defined by git
+var builtinMacros = map[string][]string{
+	"binary": {"-diff", "-merge", "-text"},
+}
+
+// assignment represents an attribute set by a rule
+type assignment struct {
+	name string
+	attr Attribute
+}
+
+// rule represents a line of a gitattributes file
+type rule struct {
+	// dir contains the directory of the file defining the rule,
+	// relative to the root of the repository
+	dir         string
+	pattern     string
+	basename    bool
+	assignments []assignment
+}
+
+// Attributes contains the rules of a set of gitattributes files
+type Attributes struct {
+	rules []rule
+}
+
+// New returns an empty set of attributes
+func New() *Attributes {
+	return &Attributes{}
+}
+
+// Add parses the content of a gitattributes file located in dir.
+// dir is relative to the root of the repository, and is empty for
+// the root.
+// The rules added last take precedence, which means the files need
+// to be added from the least specific (the root) to the most
+// specific ($GIT_DIR/info/attributes).
+// Invalid lines are ignored, like git does
+func (a *Attributes) Add(dir string, content []byte) {
+	dir = strings.Trim(dir, "/")
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// Negative patterns are forbidden, and we don't support
+		// the macro definitions
+		if strings.HasPrefix(fields[0], "!") || strings.HasPrefix(fields[0], "[attr]") {
+			continue
+		}
+
+		pattern := fields[0]
+		r := rule{
+			dir:      dir,
+			pattern:  strings.TrimPrefix(pattern, "/"),
+			basename: !strings.Contains(pattern, "/"),
+		}
+		for _, field := range fields[1:] {
+			r.assignments = append(r.assignments, parseAssignments(field)...)
+		}
+		a.rules = append(a.rules, r)
+	}
+}
+
+// parseAssignments parses a single attribute of a rule, and expands
+// it if it's a macro
+func parseAssignments(field string) []assignment {
+	var as assignment
+	switch {
+	case strings.HasPrefix(field, "-"):
+		as = assignment{name: field[1:], attr: Attribute{State: Unset}}
+	case strings.HasPrefix(field, "!"):
+		as = assignment{name: field[1:], attr: Attribute{State: Unspecified}}
+	case strings.Contains(field, "="):
+		i := strings.IndexByte(field, '=')
+		as = assignment{name: field[:i], attr: Attribute{State: Valued, Value: field[i+1:]}}
+	default:
+		as = assignment{name: field, attr: Attribute{State: Set}}
+	}
+
+	res := []assignment{as}
+	if expansion, ok := builtinMacros[as.name]; ok && as.attr.State == Set {
+		for _, f := range expansion {
+			res = append(res, parseAssignments(f)...)
+		}
+	}
+	return res
+}
+
+// match returns whether the rule applies to the given path
+func (r rule) match(path string) bool {
+	if r.dir != "" {
+		if !strings.HasPrefix(path, r.dir+"/") {
+			return false
+		}
+		path = path[len(r.dir)+1:]
+	}
+	if r.basename {
+		if i := strings.LastIndexByte(path, '/'); i != -1 {
+			path = path[i+1:]
+		}
+	}
+	return pathspec.Wildmatch(r.pattern, path, true)
+}
+
+// Get returns the value of the given attribute for the given path.
+// path is expected to be a UNIX path relative to the root of the
+// repository
+func (a *Attributes) Get(path, name string) Attribute {
+	attr := Attribute{}
+	for _, r := range a.rules {
+		for _, as := range r.assignments {
+			if as.name == name && r.match(path) {
+				attr = as.attr
+			}
+		}
+	}
+	return attr
+}
@@ -0,0 +1,114 @@
+package attributes_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/attributes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	attrs := attributes.New()
+	attrs.Add("", []byte(`# comment
+*.png binary
+*.txt text eol=lf
+/root.dat -diff
+docs/**/*.md diff=markdown
+!negative text
+`))
+	attrs.Add("sub", []byte(`
+*.txt -text
+data/*.dat diff
+`))
+
+	testCases := []struct {
+		desc     string
+		path     string
+		name     string
+		expected attributes.Attribute
+	}{
+		{
+			desc:     "set attribute",
+			path:     "file.txt",
+			name:     "text",
+			expected: attributes.Attribute{State: attributes.Set},
+		},
+		{
+			desc:     "valued attribute",
+			path:     "a/b/file.txt",
+			name:     "eol",
+			expected: attributes.Attribute{State: attributes.Valued, Value: "lf"},
+		},
+		{
+			desc:     "unspecified attribute",
+			path:     "file.txt",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Unspecified},
+		},
+		{
+			desc:     "macro should be expanded",
+			path:     "img/logo.png",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Unset},
+		},
+		{
+			desc:     "macro should be set",
+			path:     "img/logo.png",
+			name:     "binary",
+			expected: attributes.Attribute{State: attributes.Set},
+		},
+		{
+			desc:     "anchored pattern should only match at the root",
+			path:     "root.dat",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Unset},
+		},
+		{
+			desc:     "anchored pattern should not match in a sub directory",
+			path:     "a/root.dat",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Unspecified},
+		},
+		{
+			desc:     "double star",
+			path:     "docs/a/b/readme.md",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Valued, Value: "markdown"},
+		},
+		{
+			desc:     "more specific file should take precedence",
+			path:     "sub/a/file.txt",
+			name:     "text",
+			expected: attributes.Attribute{State: attributes.Unset},
+		},
+		{
+			desc:     "patterns with a slash are relative to the file",
+			path:     "sub/data/file.dat",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Set},
+		},
+		{
+			desc:     "rules of a sub directory should not apply outside",
+			path:     "data/file.dat",
+			name:     "diff",
+			expected: attributes.Attribute{State: attributes.Unspecified},
+		},
+		{
+			desc:     "negative patterns should be ignored",
+			path:     "negative",
+			name:     "text",
+			expected: attributes.Attribute{State: attributes.Unspecified},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, attrs.Get(tc.path, tc.name))
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package commitgraph contains methods to read and write commit-graph
+// files, either as a single file or as a chain of layers
+// https://git-scm.com/docs/commit-graph-format
+package commitgraph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Nivl/git-go/ginternals"
+)
+
+const (
+	// headerSize contains the size of the header of a commit-graph.
+	// The first 4 bytes contain the magic, then 1 byte for the version,
+	// 1 byte for the hash version, 1 byte for the number of chunks, and
+	// 1 byte for the number of base graphs
+	headerSize = 8
+	// chunkLookupEntrySize contains the size of an entry of the chunk
+	// lookup table: a 4 bytes chunk ID and a 8 bytes offset
+	chunkLookupEntrySize = 12
+	// fanoutSize contains the size of the OIDF chunk: 256 entries
+	// of 4 bytes
+	fanoutSize = 256 * 4
+	// commitDataSize contains the size of an entry of the CDAT chunk:
+	// the tree ID, 2 parent positions of 4 bytes, and 8 bytes for the
+	// generation and the commit time
+	commitDataSize = ginternals.OidSize + 16
+
+	version     = 1
+	hashVersion = 1 // SHA-1
+
+	// parentNone is the parent position used when a commit doesn't
+	// have a parent
+	parentNone = 0x70000000
+	// parentExtraEdges is the bit set on the second parent position
+	// when the commit has more than 2 parents. The other bits contain
+	// the position of the parents in the EDGE chunk
+	parentExtraEdges = 0x80000000
+	// lastEdge is the bit set on the last parent of the EDGE chunk
+	lastEdge = 0x80000000
+
+	// maxGeneration is the max generation number that can be stored
+	// in a commit-graph
+	maxGeneration = 0x3FFFFFFF
+	// maxCommitTime is the max commit time that can be stored in a
+	// commit-graph (34 bits)
+	maxCommitTime = 0x3FFFFFFFF
+)
+
+// List of the chunks IDs
+var (
+	chunkOidFanout   = [4]byte{'O', 'I', 'D', 'F'}
+	chunkOidLookup   = [4]byte{'O', 'I', 'D', 'L'}
+	chunkCommitData  = [4]byte{'C', 'D', 'A', 'T'}
+	chunkExtraEdges  = [4]byte{'E', 'D', 'G', 'E'}
+	chunkBaseGraphs  = [4]byte{'B', 'A', 'S', 'E'}
+	commitGraphMagic = []byte{'C', 'G', 'P', 'H'}
+)
+
+var (
+	// ErrInvalidMagic is an error thrown when a file doesn't have
+	// the expected magic
+	ErrInvalidMagic = errors.New("invalid magic")
+	// ErrInvalidVersion is an error thrown when a file has an
+	// unsupported version
+	ErrInvalidVersion = errors.New("invalid version")
+	// ErrInvalidGraph is an error thrown when a commit-graph
+	// is not valid
+	ErrInvalidGraph = errors.New("invalid commit-graph")
+	// ErrCommitNotFound is an error thrown when a commit is not in
+	// the commit-graph
+	ErrCommitNotFound = errors.New("commit not found in commit-graph")
+	// ErrNoGraph is an error thrown when a repository doesn't have
+	// a commit-graph
+	ErrNoGraph = errors.New("no commit-graph")
+)
+
+// Commit contains the data stored in a commit-graph about a commit
+type Commit struct {
+	ID        ginternals.Oid
+	TreeID    ginternals.Oid
+	ParentIDs []ginternals.Oid
+	// CommitTime contains the committer date of the commit, as a
+	// UNIX timestamp
+	CommitTime int64
+	// Generation contains the topological level of the commit:
+	// 1 for root commits, and 1 + the max generation of the parents
+	// for the other commits.
+	// This value is ignored when writing a graph since it's computed
+	// automatically
+	Generation uint32
+}
+
+// Layer represents a single commit-graph file. A layer may be built
+// on top of other layers (a chain) and reference their commits
+type Layer struct {
+	id   ginternals.Oid
+	data []byte
+
+	fanout      []byte
+	oidLookup   []byte
+	commitData  []byte
+	extraEdges  []byte
+	baseGraphs  []ginternals.Oid
+	commitCount int
+
+	// base contains the layer this layer is built on top of, if any
+	base *Layer
+	// baseCount contains the number of commits in all the lower layers
+	baseCount int
+}
+
+// ParseLayer parses the content of a commit-graph file.
+// The checksum of the file is verified
+func ParseLayer(data []byte) (*Layer, error) {
+	if len(data) < headerSize+chunkLookupEntrySize+ginternals.OidSize {
+		return nil, fmt.Errorf("file too small: %w", ErrInvalidGraph)
+	}
+	if !bytes.Equal(data[:4], commitGraphMagic) {
+		return nil, fmt.Errorf("invalid header: %w", ErrInvalidMagic)
+	}
+	if data[4] != version {
+		return nil, fmt.Errorf("version %d: %w", data[4], ErrInvalidVersion)
+	}
+	if data[5] != hashVersion {
+		return nil, fmt.Errorf("hash version %d: %w", data[5], ErrInvalidVersion)
+	}
+
+	content := data[:len(data)-ginternals.OidSize]
+	checksum := data[len(data)-ginternals.OidSize:]
+	id := ginternals.NewOidFromContent(content)
+	if !bytes.Equal(id.Bytes(), checksum) {
+		return nil, fmt.Errorf("checksum mismatch: %w", ErrInvalidGraph)
+	}
+
+	l := &Layer{
+		id:   id,
+		data: data,
+	}
+
+	chunkCount := int(data[6])
+	baseCount := int(data[7])
+	lookupEnd := headerSize + (chunkCount+1)*chunkLookupEntrySize
+	if lookupEnd > len(content) {
+		return nil, fmt.Errorf("chunk lookup table out of bound: %w", ErrInvalidGraph)
+	}
+	for i := 0; i < chunkCount; i++ {
+		entry := data[headerSize+i*chunkLookupEntrySize:]
+		next := data[headerSize+(i+1)*chunkLookupEntrySize:]
+		start := binary.BigEndian.Uint64(entry[4:12])
+		end := binary.BigEndian.Uint64(next[4:12])
+		if start < uint64(lookupEnd) || end < start || end > uint64(len(content)) {
+			return nil, fmt.Errorf("chunk %q out of bound: %w", entry[:4], ErrInvalidGraph)
+		}
+		chunk := data[start:end]
+
+		var chunkID [4]byte
+		copy(chunkID[:], entry[:4])
+		switch chunkID {
+		case chunkOidFanout:
+			l.fanout = chunk
+		case chunkOidLookup:
+			l.oidLookup = chunk
+		case chunkCommitData:
+			l.commitData = chunk
+		case chunkExtraEdges:
+			l.extraEdges = chunk
+		case chunkBaseGraphs:
+			if len(chunk) != baseCount*ginternals.OidSize {
+				return nil, fmt.Errorf("invalid BASE chunk size: %w", ErrInvalidGraph)
+			}
+			for j := 0; j < baseCount; j++ {
+				oid, err := ginternals.NewOidFromHex(chunk[j*ginternals.OidSize : (j+1)*ginternals.OidSize])
+				if err != nil {
+					return nil, fmt.Errorf("invalid base graph %d: %w", j, err)
+				}
+				l.baseGraphs = append(l.baseGraphs, oid)
+			}
+		}
+		// Like git, we ignore the chunks we don't know about
+	}
+
+	if len(l.fanout) != fanoutSize {
+		return nil, fmt.Errorf("missing or invalid OIDF chunk: %w", ErrInvalidGraph)
+	}
+	l.commitCount = int(binary.BigEndian.Uint32(l.fanout[fanoutSize-4:]))
+	if len(l.oidLookup) != l.commitCount*ginternals.OidSize {
+		return nil, fmt.Errorf("missing or invalid OIDL chunk: %w", ErrInvalidGraph)
+	}
+	if len(l.commitData) != l.commitCount*commitDataSize {
+		return nil, fmt.Errorf("missing or invalid CDAT chunk: %w", ErrInvalidGraph)
+	}
+	if len(l.baseGraphs) != baseCount {
+		return nil, fmt.Errorf("expected %d base graphs, got %d: %w", baseCount, len(l.baseGraphs), ErrInvalidGraph)
+	}
+	return l, nil
+}
+
+// ID returns the ID of the layer, which is the checksum of the file
+func (l *Layer) ID() ginternals.Oid {
+	return l.id
+}
+
+// Len returns the number of commits in the layer
+func (l *Layer) Len() int {
+	return l.commitCount
+}
+
+// BaseGraphs returns the IDs of the layers this layer is built on,
+// from the bottom of the chain to the top
+func (l *Layer) BaseGraphs() []ginternals.Oid {
+	out := make([]ginternals.Oid, len(l.baseGraphs))
+	copy(out, l.baseGraphs)
+	return out
+}
+
+// oidAt returns the oid of the commit at the given (local) position
+func (l *Layer) oidAt(pos int) ginternals.Oid {
+	var oid ginternals.Oid
+	copy(oid[:], l.oidLookup[pos*ginternals.OidSize:])
+	return oid
+}
+
+// position returns the local position of the given oid in the layer,
+// and whether the oid has been found
+func (l *Layer) position(oid ginternals.Oid) (int, bool) {
+	lo := 0
+	if oid[0] > 0 {
+		lo = int(binary.BigEndian.Uint32(l.fanout[(int(oid[0])-1)*4:]))
+	}
+	hi := int(binary.BigEndian.Uint32(l.fanout[int(oid[0])*4:]))
+	if hi > l.commitCount || lo > hi {
+		return 0, false
+	}
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(l.oidLookup[(lo+i)*ginternals.OidSize:(lo+i+1)*ginternals.OidSize], oid[:]) >= 0
+	})
+	if i < hi && l.oidAt(i) == oid {
+		return i, true
+	}
+	return 0, false
+}
+
+// find returns the layer containing the given oid, alongside the
+// local position of the oid in this layer
+func (l *Layer) find(oid ginternals.Oid) (*Layer, int, bool) {
+	for layer := l; layer != nil; layer = layer.base {
+		if pos, ok := layer.position(oid); ok {
+			return layer, pos, true
+		}
+	}
+	return nil, 0, false
+}
+
+// oidAtGlobal returns the oid of the commit at the given position
+// in the chain
+func (l *Layer) oidAtGlobal(pos int) (ginternals.Oid, error) {
+	for layer := l; layer != nil; layer = layer.base {
+		if pos >= layer.baseCount {
+			if pos-layer.baseCount >= layer.commitCount {
+				break
+			}
+			return layer.oidAt(pos - layer.baseCount), nil
+		}
+	}
+	return ginternals.NullOid, fmt.Errorf("commit position %d out of bound: %w", pos, ErrInvalidGraph)
+}
+
+// commitAt returns the commit at the given local position
+func (l *Layer) commitAt(pos int) (*Commit, error) {
+	data := l.commitData[pos*commitDataSize : (pos+1)*commitDataSize]
+	c := &Commit{
+		ID:        l.oidAt(pos),
+		ParentIDs: []ginternals.Oid{},
+	}
+	copy(c.TreeID[:], data[:ginternals.OidSize])
+	data = data[ginternals.OidSize:]
+
+	parent1 := binary.BigEndian.Uint32(data[0:4])
+	parent2 := binary.BigEndian.Uint32(data[4:8])
+	if parent1 != parentNone {
+		oid, err := l.oidAtGlobal(int(parent1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid first parent of %s: %w", c.ID.String(), err)
+		}
+		c.ParentIDs = append(c.ParentIDs, oid)
+	}
+	switch {
+	case parent2 == parentNone:
+	case parent2&parentExtraEdges != 0:
+		for i := int(parent2 &^ parentExtraEdges); ; i++ {
+			if (i+1)*4 > len(l.extraEdges) {
+				return nil, fmt.Errorf("extra edge %d of %s out of bound: %w", i, c.ID.String(), ErrInvalidGraph)
+			}
+			edge := binary.BigEndian.Uint32(l.extraEdges[i*4:])
+			oid, err := l.oidAtGlobal(int(edge &^ lastEdge))
+			if err != nil {
+				return nil, fmt.Errorf("invalid parent of %s: %w", c.ID.String(), err)
+			}
+			c.ParentIDs = append(c.ParentIDs, oid)
+			if edge&lastEdge != 0 {
+				break
+			}
+		}
+	default:
+		oid, err := l.oidAtGlobal(int(parent2))
+		if err != nil {
+			return nil, fmt.Errorf("invalid second parent of %s: %w", c.ID.String(), err)
+		}
+		c.ParentIDs = append(c.ParentIDs, oid)
+	}
+
+	genAndTime := binary.BigEndian.Uint64(data[8:16])
+	c.Generation = uint32(genAndTime >> 34)
+	c.CommitTime = int64(genAndTime & maxCommitTime)
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package commitgraph_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHistory returns a list of commits where each commit has the
+// previous one as parent. The first commit has the provided parents
+func newHistory(name string, count int, parents ...ginternals.Oid) []*commitgraph.Commit {
+	commits := make([]*commitgraph.Commit, 0, count)
+	for i := 0; i < count; i++ {
+		c := &commitgraph.Commit{
+			ID:         ginternals.NewOidFromContent([]byte(fmt.Sprintf("%s-%d", name, i))),
+			TreeID:     ginternals.NewOidFromContent([]byte(fmt.Sprintf("tree-%s-%d", name, i))),
+			ParentIDs:  append([]ginternals.Oid{}, parents...),
+			CommitTime: int64(1600000000 + i),
+		}
+		commits = append(commits, c)
+		parents = []ginternals.Oid{c.ID}
+	}
+	return commits
+}
+
+func TestEncodeLayer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should round trip", func(t *testing.T) {
+		t.Parallel()
+
+		main := newHistory("main", 10)
+		branch1 := newHistory("branch1", 3, main[4].ID)
+		branch2 := newHistory("branch2", 2, main[2].ID)
+		octopus := &commitgraph.Commit{
+			ID:         ginternals.NewOidFromContent([]byte("octopus")),
+			TreeID:     ginternals.NewOidFromContent([]byte("tree-octopus")),
+			ParentIDs:  []ginternals.Oid{main[9].ID, branch1[2].ID, branch2[1].ID},
+			CommitTime: 1700000000,
+		}
+		merge := &commitgraph.Commit{
+			ID:         ginternals.NewOidFromContent([]byte("merge")),
+			TreeID:     ginternals.NewOidFromContent([]byte("tree-merge")),
+			ParentIDs:  []ginternals.Oid{octopus.ID, branch2[1].ID},
+			CommitTime: 1700000001,
+		}
+		commits := []*commitgraph.Commit{merge, octopus}
+		commits = append(commits, branch2...)
+		commits = append(commits, branch1...)
+		commits = append(commits, main...)
+
+		buf := &bytes.Buffer{}
+		id, err := commitgraph.EncodeLayer(buf, commits, nil)
+		require.NoError(t, err)
+		l, err := commitgraph.ParseLayer(buf.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, id, l.ID())
+		assert.Equal(t, len(commits), l.Len())
+		assert.Empty(t, l.BaseGraphs())
+
+		g, err := commitgraph.NewGraph(l)
+		require.NoError(t, err)
+		for _, c := range commits {
+			got, err := g.Commit(c.ID)
+			require.NoError(t, err)
+			assert.Equal(t, c.TreeID, got.TreeID)
+			assert.Equal(t, c.ParentIDs, got.ParentIDs)
+			assert.Equal(t, c.CommitTime, got.CommitTime)
+		}
+
+		generations := map[ginternals.Oid]uint32{
+			main[0].ID:    1,
+			main[9].ID:    10,
+			branch1[2].ID: 8,
+			branch2[1].ID: 5,
+			octopus.ID:    11,
+			merge.ID:      12,
+		}
+		for oid, gen := range generations {
+			c, err := g.Commit(oid)
+			require.NoError(t, err)
+			assert.Equal(t, gen, c.Generation, "unexpected generation for %s", oid.String())
+		}
+
+		_, err = g.Commit(ginternals.NewOidFromContent([]byte("nope")))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, commitgraph.ErrCommitNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail on missing parents", func(t *testing.T) {
+		t.Parallel()
+
+		commits := newHistory("main", 2, ginternals.NewOidFromContent([]byte("nope")))
+		_, err := commitgraph.EncodeLayer(&bytes.Buffer{}, commits, nil)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, commitgraph.ErrCommitNotFound), "unexpected error: %v", err)
+	})
+}
+
+func TestParseLayer(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	_, err := commitgraph.EncodeLayer(buf, newHistory("main", 3), nil)
+	require.NoError(t, err)
+	valid := buf.Bytes()
+
+	testCases := []struct {
+		desc          string
+		data          func() []byte
+		expectedError error
+	}{
+		{
+			desc: "valid file should pass",
+			data: func() []byte {
+				return valid
+			},
+		},
+		{
+			desc: "invalid magic should fail",
+			data: func() []byte {
+				data := append([]byte{}, valid...)
+				data[0] = 'X'
+				return data
+			},
+			expectedError: commitgraph.ErrInvalidMagic,
+		},
+		{
+			desc: "invalid version should fail",
+			data: func() []byte {
+				data := append([]byte{}, valid...)
+				data[4] = 2
+				return data
+			},
+			expectedError: commitgraph.ErrInvalidVersion,
+		},
+		{
+			desc: "invalid checksum should fail",
+			data: func() []byte {
+				data := append([]byte{}, valid...)
+				data[len(data)-1]++
+				return data
+			},
+			expectedError: commitgraph.ErrInvalidGraph,
+		},
+		{
+			desc: "truncated file should fail",
+			data: func() []byte {
+				return valid[:10]
+			},
+			expectedError: commitgraph.ErrInvalidGraph,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			l, err := commitgraph.ParseLayer(tc.data())
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 3, l.Len())
+		})
+	}
+}
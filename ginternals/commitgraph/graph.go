@@ -0,0 +1,187 @@
+package commitgraph
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/spf13/afero"
+)
+
+// List of the files used to store a commit-graph, relative to the
+// objects/info directory
+const (
+	// GraphFileName is the name of the file containing a commit-graph
+	// made of a single layer
+	GraphFileName = "commit-graph"
+	// ChainDirName is the name of the directory containing the layers
+	// of a chain, and the chain file
+	ChainDirName = "commit-graphs"
+	// ChainFileName is the name of the file containing the IDs of the
+	// layers of a chain, one per line, from the bottom to the top
+	ChainFileName = "commit-graph-chain"
+)
+
+// LayerFileName returns the name of the file of a layer of a chain
+func LayerFileName(id ginternals.Oid) string {
+	return fmt.Sprintf("graph-%s.graph", id.String())
+}
+
+// Graph represents the commit-graph of a repository, made of one or
+// multiple layers
+type Graph struct {
+	// layers contains the layers of the graph from the bottom to
+	// the top
+	layers []*Layer
+}
+
+// NewGraph returns a graph made of the given layers, ordered from the
+// bottom of the chain to the top.
+// ErrInvalidGraph is returned if the layers don't form a valid chain
+func NewGraph(layers ...*Layer) (*Graph, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("a graph needs at least one layer: %w", ErrInvalidGraph)
+	}
+	baseCount := 0
+	for i, l := range layers {
+		if len(l.baseGraphs) != i {
+			return nil, fmt.Errorf("layer %s has %d base graphs, expected %d: %w", l.id.String(), len(l.baseGraphs), i, ErrInvalidGraph)
+		}
+		for j, base := range l.baseGraphs {
+			if layers[j].id != base {
+				return nil, fmt.Errorf("layer %s expects base %s at position %d, got %s: %w", l.id.String(), base.String(), j, layers[j].id.String(), ErrInvalidGraph)
+			}
+		}
+		if i > 0 {
+			l.base = layers[i-1]
+		}
+		l.baseCount = baseCount
+		baseCount += l.commitCount
+	}
+	return &Graph{
+		layers: layers,
+	}, nil
+}
+
+// Open loads the commit-graph stored in the given objects/info
+// directory. Like git, a commit-graph made of a single file has the
+// priority over a chain.
+// ErrNoGraph is returned if the repository doesn't have a
+// commit-graph
+func Open(fs afero.Fs, infoPath string) (*Graph, error) {
+	graphPath := filepath.Join(infoPath, GraphFileName)
+	data, err := afero.ReadFile(fs, graphPath)
+	if err == nil {
+		l, err := ParseLayer(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", graphPath, err)
+		}
+		return NewGraph(l)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read %s: %w", graphPath, err)
+	}
+
+	ids, err := readChain(fs, infoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoGraph
+	}
+	layers := make([]*Layer, 0, len(ids))
+	for _, id := range ids {
+		layerPath := filepath.Join(infoPath, ChainDirName, LayerFileName(id))
+		data, err := afero.ReadFile(fs, layerPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", layerPath, err)
+		}
+		l, err := ParseLayer(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", layerPath, err)
+		}
+		if l.id != id {
+			return nil, fmt.Errorf("%s has an unexpected checksum %s: %w", layerPath, l.id.String(), ErrInvalidGraph)
+		}
+		layers = append(layers, l)
+	}
+	return NewGraph(layers...)
+}
+
+// readChain returns the IDs of the layers listed in the chain file.
+// An empty list is returned if there's no chain
+func readChain(fs afero.Fs, infoPath string) ([]ginternals.Oid, error) {
+	chainPath := filepath.Join(infoPath, ChainDirName, ChainFileName)
+	data, err := afero.ReadFile(fs, chainPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []ginternals.Oid{}, nil
+		}
+		return nil, fmt.Errorf("could not read %s: %w", chainPath, err)
+	}
+
+	ids := []ginternals.Oid{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if sc.Text() == "" {
+			continue
+		}
+		id, err := ginternals.NewOidFromStr(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid layer %q in %s: %w", sc.Text(), chainPath, ErrInvalidGraph)
+		}
+		ids = append(ids, id)
+	}
+	if err = sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", chainPath, err)
+	}
+	return ids, nil
+}
+
+// Layers returns the layers of the graph, from the bottom of the
+// chain to the top
+func (g *Graph) Layers() []*Layer {
+	out := make([]*Layer, len(g.layers))
+	copy(out, g.layers)
+	return out
+}
+
+// Len returns the number of commits in the graph
+func (g *Graph) Len() int {
+	top := g.layers[len(g.layers)-1]
+	return top.baseCount + top.commitCount
+}
+
+// HasCommit returns whether the given commit is in the graph
+func (g *Graph) HasCommit(oid ginternals.Oid) bool {
+	_, _, ok := g.layers[len(g.layers)-1].find(oid)
+	return ok
+}
+
+// Commit returns the data of the given commit.
+// ErrCommitNotFound is returned if the commit is not in the graph
+func (g *Graph) Commit(oid ginternals.Oid) (*Commit, error) {
+	l, pos, ok := g.layers[len(g.layers)-1].find(oid)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", oid.String(), ErrCommitNotFound)
+	}
+	return l.commitAt(pos)
+}
+
+// Commits returns all the commits of the given layer of the graph,
+// sorted by ID
+func (l *Layer) Commits() ([]*Commit, error) {
+	commits := make([]*Commit, 0, l.commitCount)
+	for i := 0; i < l.commitCount; i++ {
+		c, err := l.commitAt(i)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
@@ -0,0 +1,440 @@
+package commitgraph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/spf13/afero"
+)
+
+// DefaultSizeMultiple is the default value of WriteOptions.SizeMultiple.
+// It's the same as git
+const DefaultSizeMultiple = 2
+
+// WriteOptions represents the options that can be used to write a
+// commit-graph
+type WriteOptions struct {
+	// Split writes the new commits in a new layer on top of the
+	// existing chain, instead of rewriting the whole graph in a
+	// single file
+	Split bool
+	// SizeMultiple controls when the layers of a chain get merged.
+	// The top layer of the chain is merged into the new layer as
+	// long as it doesn't contain more than SizeMultiple times the
+	// number of commits of the new layer.
+	// Defaults to DefaultSizeMultiple
+	SizeMultiple int
+	// Shared sets the permissions of the files of a shared
+	// repository (see core.sharedRepository).
+	// Defaults to a non-shared repository
+	Shared config.SharedRepository
+}
+
+// Write adds the given commits to the commit-graph stored in the
+// given objects/info directory, and returns the new graph.
+// current contains the current commit-graph of the repository, if
+// any. The parents of the commits must either be in current or in
+// commits. Commits that are already in current are ignored.
+// With opts.Split, only the new commits (and the layers that need to
+// be merged) are written, otherwise the whole graph is rewritten in
+// a single file
+func Write(fs afero.Fs, infoPath string, current *Graph, commits []*Commit, opts WriteOptions) (*Graph, error) {
+	if opts.SizeMultiple <= 0 {
+		opts.SizeMultiple = DefaultSizeMultiple
+	}
+
+	newCommits := make([]*Commit, 0, len(commits))
+	seen := make(map[ginternals.Oid]struct{}, len(commits))
+	for _, c := range commits {
+		if _, ok := seen[c.ID]; ok {
+			continue
+		}
+		seen[c.ID] = struct{}{}
+		if current != nil && current.HasCommit(c.ID) {
+			continue
+		}
+		newCommits = append(newCommits, c)
+	}
+
+	kept := []*Layer{}
+	if current != nil {
+		kept = current.Layers()
+	}
+	if opts.Split {
+		// A graph made of a single file cannot be part of a chain,
+		// so its commits are moved to the new layer
+		_, err := fs.Stat(filepath.Join(infoPath, GraphFileName))
+		if err == nil {
+			for _, l := range kept {
+				layerCommits, err := l.Commits()
+				if err != nil {
+					return nil, fmt.Errorf("could not read layer %s: %w", l.id.String(), err)
+				}
+				newCommits = append(newCommits, layerCommits...)
+			}
+			kept = []*Layer{}
+		}
+		if len(newCommits) == 0 {
+			return current, nil
+		}
+	}
+
+	// Without split, all the layers are merged into a single file
+	for len(kept) > 0 && (!opts.Split || kept[len(kept)-1].commitCount <= opts.SizeMultiple*len(newCommits)) {
+		top := kept[len(kept)-1]
+		layerCommits, err := top.Commits()
+		if err != nil {
+			return nil, fmt.Errorf("could not read layer %s: %w", top.id.String(), err)
+		}
+		newCommits = append(newCommits, layerCommits...)
+		kept = kept[:len(kept)-1]
+	}
+
+	var base *Graph
+	if len(kept) > 0 {
+		var err error
+		if base, err = NewGraph(kept...); err != nil {
+			return nil, err
+		}
+	}
+	buf := &bytes.Buffer{}
+	if _, err := EncodeLayer(buf, newCommits, base); err != nil {
+		return nil, err
+	}
+	layer, err := ParseLayer(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the new layer: %w", err)
+	}
+
+	if !opts.Split {
+		if err = writeFile(fs, filepath.Join(infoPath, GraphFileName), buf.Bytes(), opts.Shared); err != nil {
+			return nil, err
+		}
+		if err = removeChain(fs, infoPath, nil); err != nil {
+			return nil, err
+		}
+		return NewGraph(layer)
+	}
+
+	chainDir := filepath.Join(infoPath, ChainDirName)
+	if err = mkdir(fs, chainDir, opts.Shared); err != nil {
+		return nil, err
+	}
+	if err = writeFile(fs, filepath.Join(chainDir, LayerFileName(layer.id)), buf.Bytes(), opts.Shared); err != nil {
+		return nil, err
+	}
+	layers := append(kept, layer) //nolint:gocritic // kept is a copy, it's fine to reuse it
+	chain := &strings.Builder{}
+	for _, l := range layers {
+		chain.WriteString(l.id.String() + "\n")
+	}
+	if err = writeFile(fs, filepath.Join(chainDir, ChainFileName), []byte(chain.String()), opts.Shared); err != nil {
+		return nil, err
+	}
+
+	// The single file has the priority over the chain, so we need
+	// to remove it
+	graphPath := filepath.Join(infoPath, GraphFileName)
+	if err = fs.Remove(graphPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not remove %s: %w", graphPath, err)
+	}
+	if err = removeChain(fs, infoPath, layers); err != nil {
+		return nil, err
+	}
+	return NewGraph(layers...)
+}
+
+// removeChain removes the layers of the chain that are not in the
+// provided list. The chain file is removed if the list is empty
+func removeChain(fs afero.Fs, infoPath string, keep []*Layer) error {
+	chainDir := filepath.Join(infoPath, ChainDirName)
+	if len(keep) == 0 {
+		chainPath := filepath.Join(chainDir, ChainFileName)
+		if err := fs.Remove(chainPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove %s: %w", chainPath, err)
+		}
+	}
+
+	kept := make(map[string]struct{}, len(keep))
+	for _, l := range keep {
+		kept[LayerFileName(l.id)] = struct{}{}
+	}
+	files, err := afero.ReadDir(fs, chainDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not list the layers of the chain: %w", err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), "graph-") || !strings.HasSuffix(f.Name(), ".graph") {
+			continue
+		}
+		if _, ok := kept[f.Name()]; ok {
+			continue
+		}
+		p := filepath.Join(chainDir, f.Name())
+		if err = fs.Remove(p); err != nil {
+			return fmt.Errorf("could not remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// writeFile atomically writes data to the given path.
+// Like git, the commit-graph files are read-only
+func writeFile(fs afero.Fs, path string, data []byte, shared config.SharedRepository) error {
+	return lockfile.WriteFile(fs, path, data, lockfile.Options{
+		Perm:        shared.FilePerm(0o444),
+		IgnoreUmask: shared.IsShared(),
+	})
+}
+
+// mkdir creates the given directory if it doesn't exist yet.
+// The permissions of the directory are updated if the repository is
+// shared
+func mkdir(fs afero.Fs, path string, shared config.SharedRepository) error {
+	if _, err := fs.Stat(path); err == nil {
+		return nil
+	}
+	if err := fs.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	if !shared.IsShared() {
+		return nil
+	}
+	if err := fs.Chmod(path, shared.DirPerm(0o755)); err != nil {
+		return fmt.Errorf("could not set the permissions of %s: %w", path, err)
+	}
+	return nil
+}
+
+// EncodeLayer writes a commit-graph file containing the given commits
+// on top of the given base graph (nil if the layer is not part of a
+// chain), and returns the ID of the layer.
+// The parents of the commits must either be in the base graph or
+// in commits
+func EncodeLayer(w io.Writer, commits []*Commit, base *Graph) (ginternals.Oid, error) {
+	sorted := make([]*Commit, len(commits))
+	copy(sorted, commits)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID.Less(sorted[j].ID)
+	})
+
+	baseCount := 0
+	var top *Layer
+	if base != nil {
+		baseCount = base.Len()
+		top = base.layers[len(base.layers)-1]
+	}
+	positions := make(map[ginternals.Oid]int, len(sorted))
+	for i, c := range sorted {
+		if i > 0 && sorted[i-1].ID == c.ID {
+			return ginternals.NullOid, fmt.Errorf("commit %s is duplicated: %w", c.ID.String(), ErrInvalidGraph)
+		}
+		positions[c.ID] = baseCount + i
+	}
+	// position returns the position of a commit in the whole chain
+	position := func(oid ginternals.Oid) (int, error) {
+		if pos, ok := positions[oid]; ok {
+			return pos, nil
+		}
+		if top != nil {
+			if l, pos, ok := top.find(oid); ok {
+				return l.baseCount + pos, nil
+			}
+		}
+		return 0, fmt.Errorf("%s: %w", oid.String(), ErrCommitNotFound)
+	}
+
+	generations, err := computeGenerations(sorted, positions, baseCount, base)
+	if err != nil {
+		return ginternals.NullOid, err
+	}
+
+	fanout := make([]byte, fanoutSize)
+	oidLookup := make([]byte, 0, len(sorted)*ginternals.OidSize)
+	commitData := make([]byte, 0, len(sorted)*commitDataSize)
+	extraEdges := []byte{}
+	for i, c := range sorted {
+		oidLookup = append(oidLookup, c.ID[:]...)
+
+		commitData = append(commitData, c.TreeID[:]...)
+		parents := make([]uint32, 0, len(c.ParentIDs))
+		for _, parentID := range c.ParentIDs {
+			pos, err := position(parentID)
+			if err != nil {
+				return ginternals.NullOid, fmt.Errorf("invalid parent of %s: %w", c.ID.String(), err)
+			}
+			parents = append(parents, uint32(pos))
+		}
+		parent1, parent2 := uint32(parentNone), uint32(parentNone)
+		switch {
+		case len(parents) == 0:
+		case len(parents) <= 2:
+			parent1 = parents[0]
+			if len(parents) == 2 {
+				parent2 = parents[1]
+			}
+		default:
+			parent1 = parents[0]
+			parent2 = parentExtraEdges | uint32(len(extraEdges)/4)
+			for j, p := range parents[1:] {
+				if j == len(parents)-2 {
+					p |= lastEdge
+				}
+				extraEdges = appendUint32(extraEdges, p)
+			}
+		}
+		commitData = appendUint32(commitData, parent1)
+		commitData = appendUint32(commitData, parent2)
+
+		commitTime := c.CommitTime
+		if commitTime < 0 {
+			commitTime = 0
+		}
+		if commitTime > maxCommitTime {
+			commitTime = maxCommitTime
+		}
+		commitData = appendUint64(commitData, uint64(generations[i])<<34|uint64(commitTime))
+	}
+	// OIDF contains the cumulative number of commits starting by
+	// each byte
+	for i, c := range sorted {
+		for b := int(c.ID[0]); b < 256; b++ {
+			binary.BigEndian.PutUint32(fanout[b*4:], uint32(i+1))
+		}
+	}
+
+	type chunk struct {
+		id   [4]byte
+		data []byte
+	}
+	chunks := []chunk{
+		{id: chunkOidFanout, data: fanout},
+		{id: chunkOidLookup, data: oidLookup},
+		{id: chunkCommitData, data: commitData},
+	}
+	if len(extraEdges) > 0 {
+		chunks = append(chunks, chunk{id: chunkExtraEdges, data: extraEdges})
+	}
+	baseGraphCount := 0
+	if base != nil {
+		baseGraphCount = len(base.layers)
+		baseGraphs := make([]byte, 0, baseGraphCount*ginternals.OidSize)
+		for _, l := range base.layers {
+			baseGraphs = append(baseGraphs, l.id[:]...)
+		}
+		chunks = append(chunks, chunk{id: chunkBaseGraphs, data: baseGraphs})
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(commitGraphMagic)
+	buf.Write([]byte{version, hashVersion, byte(len(chunks)), byte(baseGraphCount)})
+	offset := uint64(headerSize + (len(chunks)+1)*chunkLookupEntrySize)
+	for _, c := range chunks {
+		buf.Write(c.id[:])
+		buf.Write(appendUint64(nil, offset))
+		offset += uint64(len(c.data))
+	}
+	buf.Write([]byte{0, 0, 0, 0})
+	buf.Write(appendUint64(nil, offset))
+	for _, c := range chunks {
+		buf.Write(c.data)
+	}
+	id := ginternals.NewOidFromContent(buf.Bytes())
+	buf.Write(id[:])
+
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not write the commit-graph: %w", err)
+	}
+	return id, nil
+}
+
+// computeGenerations returns the generation number of all the commits,
+// in the same order.
+// positions contains the position of each commit in the chain
+func computeGenerations(commits []*Commit, positions map[ginternals.Oid]int, baseCount int, base *Graph) ([]uint32, error) {
+	generations := make([]uint32, len(commits))
+	// generation returns the generation of a commit if it has been
+	// computed, or 0
+	generation := func(oid ginternals.Oid) (uint32, error) {
+		if pos, ok := positions[oid]; ok {
+			return generations[pos-baseCount], nil
+		}
+		if base == nil {
+			return 0, fmt.Errorf("%s: %w", oid.String(), ErrCommitNotFound)
+		}
+		c, err := base.Commit(oid)
+		if err != nil {
+			return 0, err
+		}
+		return c.Generation, nil
+	}
+
+	// We use a stack instead of recursion since the history can
+	// be very deep
+	for i := range commits {
+		stack := []int{i}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			if generations[current] != 0 {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			gen := uint32(0)
+			missingParent := false
+			for _, parentID := range commits[current].ParentIDs {
+				parentGen, err := generation(parentID)
+				if err != nil {
+					return nil, fmt.Errorf("invalid parent of %s: %w", commits[current].ID.String(), err)
+				}
+				// graphs written by old versions of git may not
+				// contain generation numbers, so a 0 coming from
+				// the base graph is valid
+				if _, isNew := positions[parentID]; isNew && parentGen == 0 {
+					stack = append(stack, positions[parentID]-baseCount)
+					missingParent = true
+					continue
+				}
+				if parentGen > gen {
+					gen = parentGen
+				}
+			}
+			if missingParent {
+				continue
+			}
+			if gen < maxGeneration {
+				gen++
+			}
+			generations[current] = gen
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return generations, nil
+}
+
+// appendUint32 appends the big-endian representation of v to b
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appendUint64 appends the big-endian representation of v to b
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
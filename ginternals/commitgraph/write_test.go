@@ -0,0 +1,193 @@
+package commitgraph_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	const infoPath = "objects/info"
+	chainPath := filepath.Join(infoPath, commitgraph.ChainDirName, commitgraph.ChainFileName)
+	graphPath := filepath.Join(infoPath, commitgraph.GraphFileName)
+
+	// layerFiles returns the layer files present on disk
+	layerFiles := func(t *testing.T, fs afero.Fs) []string {
+		t.Helper()
+
+		matches, err := afero.Glob(fs, filepath.Join(infoPath, commitgraph.ChainDirName, "graph-*.graph"))
+		require.NoError(t, err)
+		return matches
+	}
+
+	t.Run("should write a single file", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		history := newHistory("main", 10)
+
+		g, err := commitgraph.Write(fs, infoPath, nil, history[:5], commitgraph.WriteOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 5, g.Len())
+
+		g, err = commitgraph.Write(fs, infoPath, g, history, commitgraph.WriteOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 10, g.Len())
+		assert.Len(t, g.Layers(), 1)
+
+		g, err = commitgraph.Open(fs, infoPath)
+		require.NoError(t, err)
+		assert.Equal(t, 10, g.Len())
+		for _, c := range history {
+			assert.True(t, g.HasCommit(c.ID))
+		}
+	})
+
+	t.Run("should add layers to the chain", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		history := newHistory("main", 200)
+		opts := commitgraph.WriteOptions{Split: true}
+
+		g, err := commitgraph.Write(fs, infoPath, nil, history[:100], opts)
+		require.NoError(t, err)
+		require.Len(t, g.Layers(), 1)
+
+		// 100 > 2*20, so a new layer should be created
+		g, err = commitgraph.Write(fs, infoPath, g, history[:120], opts)
+		require.NoError(t, err)
+		layers := g.Layers()
+		require.Len(t, layers, 2)
+		assert.Equal(t, 100, layers[0].Len())
+		assert.Equal(t, 20, layers[1].Len())
+		assert.Equal(t, []ginternals.Oid{layers[0].ID()}, layers[1].BaseGraphs())
+
+		// Nothing new, nothing should change
+		g2, err := commitgraph.Write(fs, infoPath, g, history[:120], opts)
+		require.NoError(t, err)
+		assert.Same(t, g, g2)
+
+		// 20 <= 2*15 so the top layer should be merged with the new
+		// commits, but 100 > 2*35
+		g, err = commitgraph.Write(fs, infoPath, g, history[:135], opts)
+		require.NoError(t, err)
+		layers = g.Layers()
+		require.Len(t, layers, 2)
+		assert.Equal(t, 100, layers[0].Len())
+		assert.Equal(t, 35, layers[1].Len())
+		assert.Len(t, layerFiles(t, fs), 2, "the merged layer should have been removed")
+
+		// 35 <= 2*65, and then 100 <= 2*100, so everything should
+		// be merged
+		g, err = commitgraph.Write(fs, infoPath, g, history, opts)
+		require.NoError(t, err)
+		layers = g.Layers()
+		require.Len(t, layers, 1)
+		assert.Equal(t, 200, layers[0].Len())
+		assert.Len(t, layerFiles(t, fs), 1, "the merged layers should have been removed")
+
+		g, err = commitgraph.Open(fs, infoPath)
+		require.NoError(t, err)
+		assert.Equal(t, 200, g.Len())
+		c, err := g.Commit(history[199].ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(200), c.Generation)
+	})
+
+	t.Run("should compute generations across layers", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		history := newHistory("main", 10)
+		branch := newHistory("branch", 2, history[9].ID)
+
+		g, err := commitgraph.Write(fs, infoPath, nil, history, commitgraph.WriteOptions{Split: true})
+		require.NoError(t, err)
+		g, err = commitgraph.Write(fs, infoPath, g, branch, commitgraph.WriteOptions{Split: true})
+		require.NoError(t, err)
+		require.Len(t, g.Layers(), 2)
+
+		g, err = commitgraph.Open(fs, infoPath)
+		require.NoError(t, err)
+		require.Len(t, g.Layers(), 2)
+		c, err := g.Commit(branch[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(12), c.Generation)
+		c, err = g.Commit(branch[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, history[9].ID, c.ParentIDs[0])
+	})
+
+	t.Run("should convert between single file and chain", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		history := newHistory("main", 10)
+
+		g, err := commitgraph.Write(fs, infoPath, nil, history[:8], commitgraph.WriteOptions{})
+		require.NoError(t, err)
+
+		// The single file should be merged in the chain
+		g, err = commitgraph.Write(fs, infoPath, g, history[:9], commitgraph.WriteOptions{Split: true})
+		require.NoError(t, err)
+		require.Len(t, g.Layers(), 1)
+		assert.Equal(t, 9, g.Len())
+		_, err = fs.Stat(graphPath)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "the single file should have been removed")
+		_, err = fs.Stat(chainPath)
+		require.NoError(t, err)
+
+		// The chain should be merged in a single file
+		_, err = commitgraph.Write(fs, infoPath, g, history, commitgraph.WriteOptions{})
+		require.NoError(t, err)
+		_, err = fs.Stat(chainPath)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "the chain should have been removed")
+		assert.Empty(t, layerFiles(t, fs))
+
+		g, err = commitgraph.Open(fs, infoPath)
+		require.NoError(t, err)
+		assert.Equal(t, 10, g.Len())
+	})
+
+	t.Run("should use the permissions of a shared repository", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		history := newHistory("main", 10)
+		opts := commitgraph.WriteOptions{
+			Split:  true,
+			Shared: config.SharedRepository{Perm: 0o640, Exact: true},
+		}
+
+		_, err := commitgraph.Write(fs, infoPath, nil, history, opts)
+		require.NoError(t, err)
+
+		info, err := fs.Stat(chainPath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o440), info.Mode().Perm())
+
+		info, err = fs.Stat(filepath.Dir(chainPath))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+		assert.NotZero(t, info.Mode()&os.ModeSetgid, "setgid should be set")
+	})
+
+	t.Run("should fail without graph", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := commitgraph.Open(afero.NewMemMapFs(), infoPath)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, commitgraph.ErrNoGraph), "unexpected error: %v", err)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/debuglog"
 	"github.com/Nivl/git-go/internal/pathutil"
 	"github.com/spf13/afero"
 )
@@ -29,6 +30,7 @@ const (
 	DefaultDotGitDirName  = ".git"
 	defaultConfigDirName  = "config"
 	defaultObjectsDirName = "objects"
+	defaultIndexFileName  = "index"
 )
 
 // Config represents the config of a repository, whether it's from
@@ -69,6 +71,32 @@ type Config struct {
 	// Maps to $GIT_OBJECT_DIRECTORY.
 	// Defaults to $(CommonDirPath)/.git/objects.
 	ObjectDirPath string
+	// AlternateObjectDirPaths contains the paths of extra object
+	// directories, from which objects can be read but never written.
+	// Maps to $GIT_ALTERNATE_OBJECT_DIRECTORIES.
+	// Defaults to nothing.
+	AlternateObjectDirPaths []string
+	// QuarantinePath contains the path of the temporary object
+	// directory in which the objects of a push are written until
+	// the push is accepted. References cannot be updated while it's
+	// set.
+	// Maps to $GIT_QUARANTINE_PATH.
+	// Defaults to nothing.
+	QuarantinePath string
+	// IndexFilePath represents the path to the index file.
+	// Maps to $GIT_INDEX_FILE.
+	// Defaults to $(GitDirPath)/index.
+	IndexFilePath string
+	// CeilingDirectories contains the directories the lookup of the
+	// .git directory will not go into.
+	// Maps to $GIT_CEILING_DIRECTORIES.
+	// Defaults to nothing.
+	CeilingDirectories []string
+	// DiscoveryAcrossFilesystem allows the lookup of the .git
+	// directory to continue on a different filesystem.
+	// Maps to $GIT_DISCOVERY_ACROSS_FILESYSTEM.
+	// Defaults to false.
+	DiscoveryAcrossFilesystem bool
 	// LocalConfig represents the config file to load.
 	// Maps to $GIT_CONFIG.
 	// Defaults to $(GitDirPath)/config if not sets.
@@ -82,6 +110,11 @@ type Config struct {
 	// Maps to $GIT_CONFIG_NOSYSTEM.
 	// Defaults to false.
 	SkipSystemConfig bool
+	// Logger receives the debug events emitted when using the
+	// repository, like the loading of the packfiles, or the lookup
+	// of the objects.
+	// Defaults to nothing.
+	Logger debuglog.Logger
 }
 
 // FromFile returns a FileAggregate containing all the config values
@@ -90,6 +123,16 @@ func (cfg *Config) FromFile() *FileAggregate {
 	return cfg.fromFiles
 }
 
+// Env returns the environment used to load the config.
+// An empty environment is returned if the config has been created
+// manually
+func (cfg *Config) Env() *env.Env {
+	if cfg.env == nil {
+		return env.NewFromKVList([]string{})
+	}
+	return cfg.env
+}
+
 // Reload reloads all of git's config file
 func (cfg *Config) Reload() (err error) {
 	cfg.fromFiles, err = NewFileAggregate(cfg.env, cfg)
@@ -136,21 +179,20 @@ type LoadConfigOptions struct {
 // git.
 // If you want something more direct without control, use NewGitOptionsSkipEnv()
 func LoadConfig(e *env.Env, p LoadConfigOptions) (*Config, error) {
-	SkipSystemConfig := false
-	switch strings.ToLower(e.Get("GIT_CONFIG_NOSYSTEM")) {
-	case "yes", "1", "true":
-		SkipSystemConfig = true
-	}
-
 	opts := &Config{
-		GitDirPath:       e.Get("GIT_DIR"),
-		CommonDirPath:    e.Get("GIT_COMMON_DIR"),
-		WorkTreePath:     e.Get("GIT_WORK_TREE"),
-		ObjectDirPath:    e.Get("GIT_OBJECT_DIRECTORY"),
-		SkipSystemConfig: SkipSystemConfig,
-		LocalConfig:      e.Get("GIT_CONFIG"),
-		Prefix:           e.Get("PREFIX"),
-		env:              e,
+		GitDirPath:                e.Get("GIT_DIR"),
+		CommonDirPath:             e.Get("GIT_COMMON_DIR"),
+		WorkTreePath:              e.Get("GIT_WORK_TREE"),
+		ObjectDirPath:             e.Get("GIT_OBJECT_DIRECTORY"),
+		AlternateObjectDirPaths:   splitPathList(e.Get("GIT_ALTERNATE_OBJECT_DIRECTORIES")),
+		QuarantinePath:            e.Get("GIT_QUARANTINE_PATH"),
+		IndexFilePath:             e.Get("GIT_INDEX_FILE"),
+		CeilingDirectories:        splitPathList(e.Get("GIT_CEILING_DIRECTORIES")),
+		DiscoveryAcrossFilesystem: envBool(e, "GIT_DISCOVERY_ACROSS_FILESYSTEM"),
+		SkipSystemConfig:          envBool(e, "GIT_CONFIG_NOSYSTEM"),
+		LocalConfig:               e.Get("GIT_CONFIG"),
+		Prefix:                    e.Get("PREFIX"),
+		env:                       e,
 	}
 
 	if err := setConfig(e, opts, p); err != nil {
@@ -159,6 +201,31 @@ func LoadConfig(e *env.Env, p LoadConfigOptions) (*Config, error) {
 	return opts, nil
 }
 
+// envBool returns whether the given env variable is set to a value
+// that means true
+func envBool(e *env.Env, key string) bool {
+	switch strings.ToLower(e.Get(key)) {
+	case "yes", "1", "true", "on":
+		return true
+	}
+	return false
+}
+
+// splitPathList splits a list of paths separated by the OS-specific
+// list separator (":" on UNIX). Empty entries are dropped
+func splitPathList(list string) []string {
+	paths := []string{}
+	for _, p := range filepath.SplitList(list) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths
+}
+
 // LoadConfigSkipEnv returns a new Config that skips the env
 // and uses the default values
 func LoadConfigSkipEnv(opts LoadConfigOptions) (*Config, error) {
@@ -208,7 +275,10 @@ func setConfig(e *env.Env, p *Config, opts LoadConfigOptions) error {
 		p.GitDirPath = opts.WorkingDirectory
 		if !opts.IsBare {
 			if !opts.SkipGitDirLookUp {
-				guessedWorkingTree, err = pathutil.WorkingTreeFromPath(opts.WorkingDirectory, DefaultDotGitDirName)
+				guessedWorkingTree, err = pathutil.WorkingTreeFromPathWithOptions(opts.WorkingDirectory, DefaultDotGitDirName, pathutil.DiscoveryOptions{
+					CeilingDirectories:       p.CeilingDirectories,
+					StopAtFilesystemBoundary: !p.DiscoveryAcrossFilesystem,
+				})
 				if err != nil {
 					return fmt.Errorf("could not find working tree: %w", err)
 				}
@@ -291,6 +361,31 @@ func setConfig(e *env.Env, p *Config, opts LoadConfigOptions) error {
 		p.ObjectDirPath = filepath.Join(opts.WorkingDirectory, p.ObjectDirPath)
 	}
 
+	// AlternateObjectDirPaths rules:
+	// - p.AlternateObjectDirPaths contains either nothing or the
+	//   paths of $GIT_ALTERNATE_OBJECT_DIRECTORIES
+	//
+	// If relative, the paths will be appended to the current working
+	// directory.
+	for i, path := range p.AlternateObjectDirPaths {
+		if !filepath.IsAbs(path) {
+			p.AlternateObjectDirPaths[i] = filepath.Join(opts.WorkingDirectory, path)
+		}
+	}
+
+	// IndexFilePath rules:
+	// - p.IndexFilePath contains either nothing or $GIT_INDEX_FILE
+	// - Fallback to $(GitDirPath)/index
+	//
+	// If relative, the path will be appended to the current working
+	// directory.
+	if p.IndexFilePath == "" {
+		p.IndexFilePath = filepath.Join(p.GitDirPath, defaultIndexFileName)
+	}
+	if !filepath.IsAbs(p.IndexFilePath) {
+		p.IndexFilePath = filepath.Join(opts.WorkingDirectory, p.IndexFilePath)
+	}
+
 	p.fromFiles, err = NewFileAggregate(e, p)
 	if err != nil {
 		return fmt.Errorf("could not load config files: %w", err)
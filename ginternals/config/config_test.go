@@ -58,6 +58,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     currentRepoRoot,
 				GitDirPath:       filepath.Join(currentRepoRoot, DefaultDotGitDirName),
+				IndexFilePath:    filepath.Join(filepath.Join(currentRepoRoot, DefaultDotGitDirName), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(currentRepoRoot, DefaultDotGitDirName),
 				LocalConfig:      filepath.Join(currentRepoRoot, DefaultDotGitDirName, defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(currentRepoRoot, DefaultDotGitDirName, defaultObjectsDirName),
@@ -92,13 +93,16 @@ func TestLoadConfig(t *testing.T) {
 				"GIT_CONFIG=" + filepath.Join(dir, "gitconfig"),
 				"PREFIX=" + filepath.Join(dir, "sysconf"),
 				"GIT_CONFIG_NOSYSTEM=1",
+				"GIT_QUARANTINE_PATH=" + filepath.Join(dir, "objects", "incoming"),
 			}),
 			expectedParams: &Config{
 				WorkTreePath:     filepath.Join(dir, "wt"),
 				GitDirPath:       filepath.Join(dir, "git"),
+				IndexFilePath:    filepath.Join(filepath.Join(dir, "git"), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(dir, "git"),
 				LocalConfig:      filepath.Join(dir, "gitconfig"),
 				ObjectDirPath:    filepath.Join(dir, "objects"),
+				QuarantinePath:   filepath.Join(dir, "objects", "incoming"),
 				Prefix:           filepath.Join(dir, "sysconf"),
 				SkipSystemConfig: true,
 			},
@@ -120,6 +124,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     filepath.Join(dir, "custom", "wt"),
 				GitDirPath:       filepath.Join(dir, "custom", "git"),
+				IndexFilePath:    filepath.Join(filepath.Join(dir, "custom", "git"), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(dir, "custom", "git"),
 				LocalConfig:      filepath.Join(dir, "gitconfig"),
 				ObjectDirPath:    filepath.Join(dir, "objects"),
@@ -137,6 +142,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     validRepoRoot,
 				GitDirPath:       filepath.Join(validRepoRoot, DefaultDotGitDirName),
+				IndexFilePath:    filepath.Join(filepath.Join(validRepoRoot, DefaultDotGitDirName), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(validRepoRoot, DefaultDotGitDirName),
 				LocalConfig:      filepath.Join(validRepoRoot, DefaultDotGitDirName, defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(validRepoRoot, DefaultDotGitDirName, defaultObjectsDirName),
@@ -157,6 +163,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:  filepath.Join(cwd, "wt"),
 				GitDirPath:    filepath.Join(cwd, "git"),
+				IndexFilePath: filepath.Join(filepath.Join(cwd, "git"), defaultIndexFileName),
 				CommonDirPath: filepath.Join(cwd, "git"),
 				LocalConfig:   filepath.Join(cwd, "gitconfig"),
 				ObjectDirPath: filepath.Join(cwd, "objects"),
@@ -177,6 +184,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:  filepath.Join(cwd, "wd", "wt"),
 				GitDirPath:    filepath.Join(cwd, "wd", "git"),
+				IndexFilePath: filepath.Join(filepath.Join(cwd, "wd", "git"), defaultIndexFileName),
 				CommonDirPath: filepath.Join(cwd, "wd", "git"),
 				LocalConfig:   filepath.Join(cwd, "wd", "gitconfig"),
 				ObjectDirPath: filepath.Join(cwd, "wd", "objects"),
@@ -195,6 +203,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     dir,
 				GitDirPath:       filepath.Join(dir, DefaultDotGitDirName),
+				IndexFilePath:    filepath.Join(filepath.Join(dir, DefaultDotGitDirName), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(dir, "common"),
 				LocalConfig:      filepath.Join(dir, "common", defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(dir, "common", defaultObjectsDirName),
@@ -213,6 +222,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     dir,
 				GitDirPath:       gitDirWithCommonDir,
+				IndexFilePath:    filepath.Join(gitDirWithCommonDir, defaultIndexFileName),
 				CommonDirPath:    filepath.Join(dir, "common"),
 				LocalConfig:      filepath.Join(dir, "common", defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(dir, "common", defaultObjectsDirName),
@@ -233,6 +243,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     dir,
 				GitDirPath:       filepath.Join(dir, DefaultDotGitDirName),
+				IndexFilePath:    filepath.Join(filepath.Join(dir, DefaultDotGitDirName), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(dir, DefaultDotGitDirName, "common"),
 				LocalConfig:      filepath.Join(dir, DefaultDotGitDirName, "common", defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(dir, DefaultDotGitDirName, "common", defaultObjectsDirName),
@@ -251,12 +262,37 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:  "",
 				GitDirPath:    filepath.Join(cwd, "wd"),
+				IndexFilePath: filepath.Join(filepath.Join(cwd, "wd"), defaultIndexFileName),
 				CommonDirPath: filepath.Join(cwd, "wd"),
 				LocalConfig:   filepath.Join(cwd, "wd", "config"),
 				ObjectDirPath: filepath.Join(cwd, "wd", "objects"),
 			},
 			expectedError: nil,
 		},
+		{
+			desc: "extra env variables should be used when available",
+			cfg: LoadConfigOptions{
+				GitDirPath: filepath.Join(dir, "git"),
+			},
+			e: env.NewFromKVList([]string{
+				"GIT_INDEX_FILE=index",
+				"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + filepath.Join(dir, "alt1") + string(os.PathListSeparator) + "alt2",
+				"GIT_CEILING_DIRECTORIES=" + filepath.Join(dir, "ceiling"),
+				"GIT_DISCOVERY_ACROSS_FILESYSTEM=true",
+			}),
+			expectedParams: &Config{
+				WorkTreePath:              cwd,
+				GitDirPath:                filepath.Join(dir, "git"),
+				CommonDirPath:             filepath.Join(dir, "git"),
+				LocalConfig:               filepath.Join(dir, "git", "config"),
+				ObjectDirPath:             filepath.Join(dir, "git", "objects"),
+				IndexFilePath:             filepath.Join(cwd, "index"),
+				AlternateObjectDirPaths:   []string{filepath.Join(dir, "alt1"), filepath.Join(cwd, "alt2")},
+				CeilingDirectories:        []string{filepath.Join(dir, "ceiling")},
+				DiscoveryAcrossFilesystem: true,
+			},
+			expectedError: nil,
+		},
 		{
 			desc: "repo with a .git file should work",
 			cfg: LoadConfigOptions{
@@ -266,6 +302,7 @@ func TestLoadConfig(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:  wtWithGitfile,
 				GitDirPath:    filepath.Join(dir, ".git"),
+				IndexFilePath: filepath.Join(filepath.Join(dir, ".git"), defaultIndexFileName),
 				CommonDirPath: filepath.Join(dir, ".git"),
 				LocalConfig:   filepath.Join(dir, ".git", "config"),
 				ObjectDirPath: filepath.Join(dir, ".git", "objects"),
@@ -315,6 +352,7 @@ func TestNewGitOptionsSkipEnv(t *testing.T) {
 			expectedParams: &Config{
 				WorkTreePath:     currentRepoRoot,
 				GitDirPath:       filepath.Join(currentRepoRoot, DefaultDotGitDirName),
+				IndexFilePath:    filepath.Join(filepath.Join(currentRepoRoot, DefaultDotGitDirName), defaultIndexFileName),
 				CommonDirPath:    filepath.Join(currentRepoRoot, DefaultDotGitDirName),
 				LocalConfig:      filepath.Join(currentRepoRoot, DefaultDotGitDirName, defaultConfigDirName),
 				ObjectDirPath:    filepath.Join(currentRepoRoot, DefaultDotGitDirName, defaultObjectsDirName),
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -8,8 +9,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/lockfile"
 	"gopkg.in/ini.v1"
 )
 
@@ -48,13 +51,20 @@ func defaultConfig() (*ini.File, error) {
 // impacting a repository
 type FileAggregate struct {
 	cfg    *Config
+	env    *env.Env
 	global *ini.File
 	local  *ini.File
 }
 
-// Save persists the changes made to the config files
+// Save persists the changes made to the config files.
+// The file is locked while being written, and lockfile.ErrLocked
+// is returned if it's already locked by another process
 func (cfg *FileAggregate) Save() error {
-	return cfg.local.SaveTo(cfg.cfg.LocalConfig)
+	buf := &bytes.Buffer{}
+	if _, err := cfg.local.WriteTo(buf); err != nil {
+		return fmt.Errorf("could not generate the config: %w", err)
+	}
+	return lockfile.WriteFile(cfg.cfg.FS, cfg.cfg.LocalConfig, buf.Bytes(), lockfile.Options{})
 }
 
 // RepoFormatVersion returns the version of the format of the repo
@@ -122,11 +132,218 @@ func (cfg *FileAggregate) UpdateIsBare(isBare bool) {
 	cfg.local.Section("core").Key("bare").SetValue(strconv.FormatBool(isBare))
 }
 
+// SharedRepository returns how the repository is shared between
+// several users, set in core.sharedRepository.
+// ErrInvalidSharedRepository is returned if the value is not valid
+func (cfg *FileAggregate) SharedRepository() (shared SharedRepository, ok bool, err error) {
+	v, ok := cfg.value("core", "sharedrepository")
+	if !ok {
+		return SharedRepository{}, false, nil
+	}
+	shared, err = ParseSharedRepository(v)
+	if err != nil {
+		return SharedRepository{}, false, err
+	}
+	return shared, true, nil
+}
+
+// UpdateSharedRepository updates the core.sharedRepository option
+func (cfg *FileAggregate) UpdateSharedRepository(shared SharedRepository) {
+	cfg.local.Section("core").Key("sharedrepository").SetValue(shared.String())
+}
+
+// InitTemplateDir returns the directory containing the files copied
+// into the new repositories, set in init.templateDir.
+// Like git, a leading "~/" is replaced by $HOME
+func (cfg *FileAggregate) InitTemplateDir() (dir string, ok bool) {
+	dir, ok = cfg.value("init", "templateDir")
+	if ok && strings.HasPrefix(dir, "~/") {
+		dir = filepath.Join(cfg.env.Get("HOME"), dir[2:])
+	}
+	return dir, ok
+}
+
+// LogAllRefUpdates returns the value of core.logAllRefUpdates, which
+// sets which reference updates should be logged in the reflogs.
+// The value is either "true", "false", or "always"
+func (cfg *FileAggregate) LogAllRefUpdates() (value string, ok bool) {
+	source := cfg.global
+	if cfg.local.Section("core").HasKey("logallrefupdates") {
+		source = cfg.local
+	}
+
+	key := source.Section("core").Key("logallrefupdates")
+	if strings.EqualFold(key.String(), "always") {
+		return "always", true
+	}
+	v, err := key.Bool()
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatBool(v), true
+}
+
+// FileMode returns whether the executable bit of the files of the
+// working tree should be trusted, set in core.fileMode. When false,
+// the executable bit of the files is ignored, and the mode recorded
+// in git is kept.
+func (cfg *FileAggregate) FileMode() (enabled, ok bool) {
+	return cfg.boolValue("core", "filemode")
+}
+
+// IgnoreCase returns whether the filesystem of the working tree is
+// case-insensitive, set in core.ignoreCase. When true, a file of the
+// working tree matches a file known by git even if their case is
+// different.
+func (cfg *FileAggregate) IgnoreCase() (enabled, ok bool) {
+	return cfg.boolValue("core", "ignorecase")
+}
+
+// PrecomposeUnicode returns whether the paths read from the working
+// tree should be converted to precomposed unicode (NFC) before being
+// used by git, set in core.precomposeUnicode. This is needed on macOS,
+// which returns the paths using decomposed unicode (NFD).
+func (cfg *FileAggregate) PrecomposeUnicode() (enabled, ok bool) {
+	return cfg.boolValue("core", "precomposeunicode")
+}
+
+// Symlinks returns whether the symbolic links should be checked out
+// as symbolic links, set in core.symlinks. When false, they are
+// checked out as text files containing the target of the link.
+func (cfg *FileAggregate) Symlinks() (enabled, ok bool) {
+	return cfg.boolValue("core", "symlinks")
+}
+
+// UserName returns the name to use in the signatures, set in
+// user.name.
+// When set, author.name or committer.name should be used instead
+func (cfg *FileAggregate) UserName() (name string, ok bool) {
+	return cfg.value("user", "name")
+}
+
+// UserEmail returns the email address to use in the signatures, set
+// in user.email.
+// When set, author.email or committer.email should be used instead
+func (cfg *FileAggregate) UserEmail() (email string, ok bool) {
+	return cfg.value("user", "email")
+}
+
+// AuthorName returns the name to use as author, set in author.name
+func (cfg *FileAggregate) AuthorName() (name string, ok bool) {
+	return cfg.value("author", "name")
+}
+
+// AuthorEmail returns the email address to use as author, set in
+// author.email
+func (cfg *FileAggregate) AuthorEmail() (email string, ok bool) {
+	return cfg.value("author", "email")
+}
+
+// CommitterName returns the name to use as committer, set in
+// committer.name
+func (cfg *FileAggregate) CommitterName() (name string, ok bool) {
+	return cfg.value("committer", "name")
+}
+
+// CommitterEmail returns the email address to use as committer, set
+// in committer.email
+func (cfg *FileAggregate) CommitterEmail() (email string, ok bool) {
+	return cfg.value("committer", "email")
+}
+
+// MailmapFile returns the path of an extra mailmap file, set in
+// mailmap.file
+func (cfg *FileAggregate) MailmapFile() (path string, ok bool) {
+	return cfg.value("mailmap", "file")
+}
+
+// HTTPProxy returns the URL of the proxy to use to reach the http(s)
+// remotes, set in http.proxy
+func (cfg *FileAggregate) HTTPProxy() (proxy string, ok bool) {
+	return cfg.value("http", "proxy")
+}
+
+// HTTPSSLVerify returns whether the certificates of the https remotes
+// should be verified, set in http.sslVerify
+func (cfg *FileAggregate) HTTPSSLVerify() (verify, ok bool) {
+	source := cfg.global
+	if cfg.local.Section("http").HasKey("sslVerify") {
+		source = cfg.local
+	}
+
+	v, err := source.Section("http").Key("sslVerify").Bool()
+	if err != nil {
+		return false, false
+	}
+	return v, true
+}
+
+// HTTPExtraHeader returns an extra header to send to the http(s)
+// remotes, set in http.extraHeader.
+// The header has the format "Name: value"
+func (cfg *FileAggregate) HTTPExtraHeader() (header string, ok bool) {
+	return cfg.value("http", "extraHeader")
+}
+
+// BranchRemote returns the remote tracked by the given local branch,
+// set in branch.<name>.remote.
+// "." means the branch tracks another local branch
+func (cfg *FileAggregate) BranchRemote(branch string) (remote string, ok bool) {
+	return cfg.value(subsection("branch", branch), "remote")
+}
+
+// BranchMerge returns the full name of the branch of the remote
+// tracked by the given local branch (ex. refs/heads/main), set in
+// branch.<name>.merge
+func (cfg *FileAggregate) BranchMerge(branch string) (ref string, ok bool) {
+	return cfg.value(subsection("branch", branch), "merge")
+}
+
+// DiffTextconv returns the command used to convert the files using
+// the given diff driver to text, set in diff.<driver>.textconv
+func (cfg *FileAggregate) DiffTextconv(driver string) (command string, ok bool) {
+	return cfg.value(subsection("diff", driver), "textconv")
+}
+
+// subsection returns the name of the section used by the ini files
+// to store the given subsection (ex. `branch "main"`)
+func subsection(section, name string) string {
+	return fmt.Sprintf("%s %q", section, name)
+}
+
+// boolValue returns the value of section.key as a boolean, with the
+// local config taking precedence over the global one
+func (cfg *FileAggregate) boolValue(section, key string) (v, ok bool) {
+	source := cfg.global
+	if cfg.local.Section(section).HasKey(key) {
+		source = cfg.local
+	}
+
+	v, err := source.Section(section).Key(key).Bool()
+	if err != nil {
+		return false, false
+	}
+	return v, true
+}
+
+// value returns the non-empty value of section.key, with the local
+// config taking precedence over the global one
+func (cfg *FileAggregate) value(section, key string) (v string, ok bool) {
+	source := cfg.global
+	if cfg.local.Section(section).HasKey(key) {
+		source = cfg.local
+	}
+
+	v = source.Section(section).Key(key).String()
+	return v, v != ""
+}
+
 // NewFileAggregate loads all the available config files and returns an object
 // with accessor
 func NewFileAggregate(e *env.Env, cfg *Config) (confFile *FileAggregate, err error) {
 	confFile = &FileAggregate{
 		cfg: cfg,
+		env: e,
 	}
 	configPaths := getPaths(e, cfg)
 
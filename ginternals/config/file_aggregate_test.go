@@ -75,14 +75,32 @@ func TestGetters(t *testing.T) {
 	[core]
 		worktree = local_dir
 		repositoryformatversion = 0
+		logallrefupdates = always
+		symlinks = false
+		filemode = false
+		precomposeunicode = true
+		ignorecase = true
+		sharedrepository = group
 	[init]
 		defaultBranch = main
+		templateDir = ~/templates
+	[http]
+		proxy = http://proxy.example.com:3128
+		sslVerify = false
+		extraHeader = Authorization: Bearer token
+	[branch "feat/nested"]
+		remote = origin
+		merge = refs/heads/main
+	[diff "pdf"]
+		textconv = pdftotext
 	`), 0o644)
 	require.NoError(t, err)
 
 	// Agg contains the config of both files. The local data should
 	// override the global ones
-	agg, err := NewFileAggregate(env.NewFromKVList([]string{}),
+	agg, err := NewFileAggregate(env.NewFromKVList([]string{
+		"HOME=" + dirPath,
+	}),
 		&Config{
 			LocalConfig: localConfigPath,
 			FS:          afero.NewOsFs(),
@@ -141,6 +159,200 @@ func TestGetters(t *testing.T) {
 			assert.Equal(t, "main", v)
 		})
 	})
+
+	t.Run("LogAllRefUpdates", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			v, ok := global.LogAllRefUpdates()
+			assert.False(t, ok, "expected to NOT find core.logAllRefUpdates")
+			assert.Equal(t, "", v)
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			v, ok := agg.LogAllRefUpdates()
+			assert.True(t, ok, "expected to find core.logAllRefUpdates")
+			assert.Equal(t, "always", v)
+		})
+	})
+
+	t.Run("FileMode", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.FileMode()
+			assert.False(t, ok, "expected to NOT find core.fileMode")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			v, ok := agg.FileMode()
+			assert.True(t, ok, "expected to find core.fileMode")
+			assert.False(t, v)
+		})
+	})
+
+	t.Run("IgnoreCase", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.IgnoreCase()
+			assert.False(t, ok, "expected to NOT find core.ignoreCase")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			v, ok := agg.IgnoreCase()
+			assert.True(t, ok, "expected to find core.ignoreCase")
+			assert.True(t, v)
+		})
+	})
+
+	t.Run("PrecomposeUnicode", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.PrecomposeUnicode()
+			assert.False(t, ok, "expected to NOT find core.precomposeUnicode")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			v, ok := agg.PrecomposeUnicode()
+			assert.True(t, ok, "expected to find core.precomposeUnicode")
+			assert.True(t, v)
+		})
+	})
+
+	t.Run("Symlinks", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.Symlinks()
+			assert.False(t, ok, "expected to NOT find core.symlinks")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			v, ok := agg.Symlinks()
+			assert.True(t, ok, "expected to find core.symlinks")
+			assert.False(t, v)
+		})
+	})
+
+	t.Run("Branch", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.BranchRemote("feat/nested")
+			assert.False(t, ok, "expected to NOT find branch.feat/nested.remote")
+			_, ok = global.BranchMerge("feat/nested")
+			assert.False(t, ok, "expected to NOT find branch.feat/nested.merge")
+			_, ok = agg.BranchRemote("main")
+			assert.False(t, ok, "expected to NOT find branch.main.remote")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			remote, ok := agg.BranchRemote("feat/nested")
+			assert.True(t, ok, "expected to find branch.feat/nested.remote")
+			assert.Equal(t, "origin", remote)
+
+			merge, ok := agg.BranchMerge("feat/nested")
+			assert.True(t, ok, "expected to find branch.feat/nested.merge")
+			assert.Equal(t, "refs/heads/main", merge)
+		})
+	})
+
+	t.Run("DiffTextconv", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.DiffTextconv("pdf")
+			assert.False(t, ok, "expected to NOT find diff.pdf.textconv")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			command, ok := agg.DiffTextconv("pdf")
+			assert.True(t, ok, "expected to find diff.pdf.textconv")
+			assert.Equal(t, "pdftotext", command)
+		})
+	})
+
+	t.Run("SharedRepository", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			shared, ok, err := global.SharedRepository()
+			require.NoError(t, err)
+			assert.False(t, ok, "expected to NOT find core.sharedRepository")
+			assert.False(t, shared.IsShared())
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			shared, ok, err := agg.SharedRepository()
+			require.NoError(t, err)
+			assert.True(t, ok, "expected to find core.sharedRepository")
+			assert.Equal(t, SharedGroup(), shared)
+		})
+	})
+
+	t.Run("InitTemplateDir", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.InitTemplateDir()
+			assert.False(t, ok, "expected to NOT find init.templateDir")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			dir, ok := agg.InitTemplateDir()
+			assert.True(t, ok, "expected to find init.templateDir")
+			assert.Equal(t, filepath.Join(dirPath, "templates"), dir)
+		})
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Default", func(t *testing.T) {
+			t.Parallel()
+			_, ok := global.HTTPProxy()
+			assert.False(t, ok, "expected to NOT find http.proxy")
+			_, ok = global.HTTPSSLVerify()
+			assert.False(t, ok, "expected to NOT find http.sslVerify")
+			_, ok = global.HTTPExtraHeader()
+			assert.False(t, ok, "expected to NOT find http.extraHeader")
+		})
+
+		t.Run("With value", func(t *testing.T) {
+			t.Parallel()
+			proxy, ok := agg.HTTPProxy()
+			assert.True(t, ok, "expected to find http.proxy")
+			assert.Equal(t, "http://proxy.example.com:3128", proxy)
+
+			verify, ok := agg.HTTPSSLVerify()
+			assert.True(t, ok, "expected to find http.sslVerify")
+			assert.False(t, verify)
+
+			header, ok := agg.HTTPExtraHeader()
+			assert.True(t, ok, "expected to find http.extraHeader")
+			assert.Equal(t, "Authorization: Bearer token", header)
+		})
+	})
 }
 
 func TestUpdate(t *testing.T) {
@@ -169,6 +381,10 @@ func TestUpdate(t *testing.T) {
 		bare = false
 	[init]
 		defaultBranch = main
+	[http]
+		proxy = http://proxy.example.com:3128
+		sslVerify = false
+		extraHeader = Authorization: Bearer token
 	`), 0o644)
 	require.NoError(t, err)
 
@@ -196,6 +412,16 @@ func TestUpdate(t *testing.T) {
 		assert.True(t, found, "IsBare should be found")
 		assert.True(t, v, "IsBare should be true")
 	})
+
+	t.Run("SharedRepository", func(t *testing.T) {
+		t.Parallel()
+
+		agg.UpdateSharedRepository(SharedEveryone())
+		shared, found, err := agg.SharedRepository()
+		require.NoError(t, err)
+		assert.True(t, found, "SharedRepository should be found")
+		assert.Equal(t, SharedEveryone(), shared)
+	})
 }
 
 func TestGetPaths(t *testing.T) {
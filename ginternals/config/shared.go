@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSharedRepository is returned when the value of
+// core.sharedRepository is not supported
+var ErrInvalidSharedRepository = errors.New("invalid core.sharedRepository value")
+
+// Permissions added to the files of a shared repository
+const (
+	sharedGroupPerm    os.FileMode = 0o660
+	sharedEveryonePerm os.FileMode = 0o664
+)
+
+// SharedRepository represents how a repository is shared between
+// several users, as set in core.sharedRepository.
+// The zero value means that the repository is not shared, and that
+// the permissions are only set by the umask
+type SharedRepository struct {
+	// Perm contains the permissions given to the files of the
+	// repository
+	Perm os.FileMode
+	// Exact is set when the permissions have been provided as an
+	// octal value (ex. 0640). The permissions then replace the ones
+	// of the files instead of being added to them
+	Exact bool
+}
+
+// SharedGroup returns a SharedRepository that makes the repository
+// writable by the group of its files
+func SharedGroup() SharedRepository {
+	return SharedRepository{Perm: sharedGroupPerm}
+}
+
+// SharedEveryone returns a SharedRepository that makes the repository
+// writable by the group of its files, and readable by everyone
+func SharedEveryone() SharedRepository {
+	return SharedRepository{Perm: sharedEveryonePerm}
+}
+
+// ParseSharedRepository parses the value of core.sharedRepository, or
+// of the --shared option of git init.
+// The value is either:
+//   - "umask", "false", or "0" for a non-shared repository
+//   - "group", "true", or "1" for a repository writable by its group
+//   - "all", "world", "everybody", or "2" for a repository writable by
+//     its group and readable by everyone
+//   - an octal mode (ex. 0640), which must at least contain 0600
+func ParseSharedRepository(value string) (SharedRepository, error) {
+	switch strings.ToLower(value) {
+	case "umask", "false", "no", "off", "0":
+		return SharedRepository{}, nil
+	case "group", "true", "yes", "on", "1":
+		return SharedGroup(), nil
+	case "all", "world", "everybody", "2":
+		return SharedEveryone(), nil
+	}
+
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return SharedRepository{}, fmt.Errorf("%q: %w", value, ErrInvalidSharedRepository)
+	}
+	// Like git, the owner should always be able to read and write
+	// the files, and executable bits are ignored
+	if perm&0o600 != 0o600 || perm > 0o777 {
+		return SharedRepository{}, fmt.Errorf("%q: %w", value, ErrInvalidSharedRepository)
+	}
+	return SharedRepository{
+		Perm:  os.FileMode(perm) & 0o666,
+		Exact: true,
+	}, nil
+}
+
+// IsShared returns whether the repository is shared
+func (s SharedRepository) IsShared() bool {
+	return s.Perm != 0
+}
+
+// String returns the value to store in core.sharedRepository
+func (s SharedRepository) String() string {
+	switch {
+	case s.Exact:
+		return fmt.Sprintf("0%o", s.Perm)
+	case s.Perm == sharedGroupPerm:
+		return "1"
+	case s.Perm == sharedEveryonePerm:
+		return "2"
+	default:
+		return "0"
+	}
+}
+
+// FilePerm returns the permissions to give to a file that would have
+// the given permissions in a non-shared repository.
+// Read-only files stay read-only, and the executable files get the
+// executable bit for everyone that can read them
+func (s SharedRepository) FilePerm(perm os.FileMode) os.FileMode {
+	if !s.IsShared() {
+		return perm
+	}
+	tweak := s.Perm
+	if perm&0o200 == 0 {
+		tweak &^= 0o222
+	}
+	if perm&0o100 != 0 {
+		tweak |= (tweak & 0o444) >> 2
+	}
+	if s.Exact {
+		return (perm &^ 0o777) | tweak
+	}
+	return perm | tweak
+}
+
+// DirPerm returns the permissions to give to a directory that would
+// have the given permissions in a non-shared repository.
+// Like git, the directories get the setgid bit so the files created
+// in them belong to the group of the repository
+func (s SharedRepository) DirPerm(perm os.FileMode) os.FileMode {
+	if !s.IsShared() {
+		return perm
+	}
+	perm = s.FilePerm(perm)
+	return perm | (perm&0o444)>>2 | os.ModeSetgid
+}
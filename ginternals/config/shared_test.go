@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSharedRepository(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		value          string
+		expected       SharedRepository
+		expectedString string
+		expectedErr    error
+	}{
+		{
+			desc:           "umask",
+			value:          "umask",
+			expected:       SharedRepository{},
+			expectedString: "0",
+		},
+		{
+			desc:           "false",
+			value:          "false",
+			expected:       SharedRepository{},
+			expectedString: "0",
+		},
+		{
+			desc:           "group",
+			value:          "group",
+			expected:       SharedGroup(),
+			expectedString: "1",
+		},
+		{
+			desc:           "true",
+			value:          "true",
+			expected:       SharedGroup(),
+			expectedString: "1",
+		},
+		{
+			desc:           "1",
+			value:          "1",
+			expected:       SharedGroup(),
+			expectedString: "1",
+		},
+		{
+			desc:           "everybody",
+			value:          "everybody",
+			expected:       SharedEveryone(),
+			expectedString: "2",
+		},
+		{
+			desc:           "octal",
+			value:          "0640",
+			expected:       SharedRepository{Perm: 0o640, Exact: true},
+			expectedString: "0640",
+		},
+		{
+			desc:           "octal with executable bits",
+			value:          "0775",
+			expected:       SharedRepository{Perm: 0o664, Exact: true},
+			expectedString: "0664",
+		},
+		{
+			desc:        "octal not writable by the owner",
+			value:       "0440",
+			expectedErr: ErrInvalidSharedRepository,
+		},
+		{
+			desc:        "octal too big",
+			value:       "01777",
+			expectedErr: ErrInvalidSharedRepository,
+		},
+		{
+			desc:        "invalid value",
+			value:       "nope",
+			expectedErr: ErrInvalidSharedRepository,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			shared, err := ParseSharedRepository(tc.value)
+			if tc.expectedErr != nil {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, shared)
+			assert.Equal(t, tc.expectedString, shared.String())
+		})
+	}
+}
+
+func TestSharedRepositoryPerm(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc         string
+		shared       SharedRepository
+		perm         os.FileMode
+		expectedFile os.FileMode
+		expectedDir  os.FileMode
+	}{
+		{
+			desc:         "not shared",
+			shared:       SharedRepository{},
+			perm:         0o644,
+			expectedFile: 0o644,
+			expectedDir:  0o644,
+		},
+		{
+			desc:         "group",
+			shared:       SharedGroup(),
+			perm:         0o640,
+			expectedFile: 0o660,
+			expectedDir:  0o770 | os.ModeSetgid,
+		},
+		{
+			desc:         "group on a read-only file",
+			shared:       SharedGroup(),
+			perm:         0o444,
+			expectedFile: 0o444,
+			expectedDir:  0o555 | os.ModeSetgid,
+		},
+		{
+			desc:         "everybody on an executable file",
+			shared:       SharedEveryone(),
+			perm:         0o700,
+			expectedFile: 0o775,
+			expectedDir:  0o775 | os.ModeSetgid,
+		},
+		{
+			desc:         "exact value",
+			shared:       SharedRepository{Perm: 0o640, Exact: true},
+			perm:         0o666,
+			expectedFile: 0o640,
+			expectedDir:  0o750 | os.ModeSetgid,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expectedFile, tc.shared.FilePerm(tc.perm))
+			assert.Equal(t, tc.expectedDir, tc.shared.DirPerm(tc.perm))
+		})
+	}
+}
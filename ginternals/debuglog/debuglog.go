@@ -0,0 +1,25 @@
+// Package debuglog contains the interface used by the library to
+// emit structured debug events, like the time it takes to load a
+// packfile, or where an object has been found.
+//
+// The events are only emitted when a Logger is set, so it has no
+// cost when unused.
+package debuglog
+
+// Logger receives the debug events emitted by the library.
+// Its method matches the one of slog.Logger, so a *slog.Logger can
+// be used as is.
+type Logger interface {
+	// Debug is called for every event. args contains alternating
+	// keys and values (ex. "path", "objects/pack/pack-x.pack",
+	// "duration", time.Second)
+	Debug(msg string, args ...interface{})
+}
+
+// Debug emits an event to the given logger.
+// Nothing happens if the logger is nil
+func Debug(l Logger, msg string, args ...interface{}) {
+	if l != nil {
+		l.Debug(msg, args...)
+	}
+}
@@ -0,0 +1,32 @@
+package debuglog_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/debuglog"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebug(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should forward the event", func(t *testing.T) {
+		t.Parallel()
+
+		l := &testutil.Logger{}
+		debuglog.Debug(l, "event", "key", "value", "count", 2)
+		events := l.Events("event")
+		require.Len(t, events, 1)
+		assert.Equal(t, map[string]interface{}{"key": "value", "count": 2}, events[0].Args)
+	})
+
+	t.Run("should do nothing without logger", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotPanics(t, func() {
+			debuglog.Debug(nil, "event", "key", "value")
+		})
+	})
+}
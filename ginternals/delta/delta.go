@@ -0,0 +1,231 @@
+// Package delta contains methods to create and apply git deltas.
+// A delta describes how to build a target content from a base
+// content, and is used by packfiles and binary patches
+// https://git-scm.com/docs/pack-format#_deltified_representation
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	// blockSize is the size of the blocks of the base that are
+	// indexed to find the copies
+	blockSize = 16
+	// maxInsertSize is the maximum number of bytes an insert
+	// instruction can contain
+	maxInsertSize = 0x7f
+	// maxCopySize is the maximum number of bytes copied by a single
+	// copy instruction.
+	// The format allows up to 0xffffff, but we stay under 0x10000
+	// since a size of 0 means 0x10000, which not all the readers
+	// support
+	maxCopySize = 0xffff
+	// maxCandidates is the maximum number of positions of the base
+	// looked at for each block
+	maxCandidates = 64
+)
+
+var (
+	// ErrInvalidDelta is an error thrown when a delta cannot be
+	// parsed
+	ErrInvalidDelta = errors.New("invalid delta")
+	// ErrBaseMismatch is an error thrown when a delta is applied on a
+	// base that doesn't have the expected size
+	ErrBaseMismatch = errors.New("the base doesn't match the delta")
+)
+
+// Create returns a delta that can be applied on base to get target
+func Create(base, target []byte) []byte {
+	out := &bytes.Buffer{}
+	writeSize(out, len(base))
+	writeSize(out, len(target))
+
+	// We index the position of every block of the base so we can
+	// quickly find if some data of the target are in the base
+	index := map[string][]int{}
+	for i := 0; i+blockSize <= len(base); i += blockSize {
+		block := string(base[i : i+blockSize])
+		if len(index[block]) < maxCandidates {
+			index[block] = append(index[block], i)
+		}
+	}
+
+	insertStart := 0
+	flushInsert := func(end int) {
+		for insertStart < end {
+			size := end - insertStart
+			if size > maxInsertSize {
+				size = maxInsertSize
+			}
+			out.WriteByte(byte(size))
+			out.Write(target[insertStart : insertStart+size])
+			insertStart += size
+		}
+	}
+
+	for pos := 0; pos+blockSize <= len(target); {
+		bestOffset, bestSize := 0, 0
+		for _, offset := range index[string(target[pos:pos+blockSize])] {
+			size := blockSize
+			for offset+size < len(base) && pos+size < len(target) && base[offset+size] == target[pos+size] {
+				size++
+			}
+			if size > bestSize {
+				bestOffset, bestSize = offset, size
+			}
+		}
+		if bestSize == 0 {
+			pos++
+			continue
+		}
+		// The match may also start before the block, in the data
+		// we were about to insert
+		for bestOffset > 0 && pos > insertStart && base[bestOffset-1] == target[pos-1] {
+			bestOffset--
+			pos--
+			bestSize++
+		}
+
+		flushInsert(pos)
+		for copied := 0; copied < bestSize; {
+			size := bestSize - copied
+			if size > maxCopySize {
+				size = maxCopySize
+			}
+			writeCopy(out, bestOffset+copied, size)
+			copied += size
+		}
+		pos += bestSize
+		insertStart = pos
+	}
+	flushInsert(len(target))
+	return out.Bytes()
+}
+
+// Apply applies the delta on base, and returns the target
+func Apply(base, delta []byte) ([]byte, error) {
+	baseSize, n, err := readSize(delta)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the size of the base: %w", err)
+	}
+	delta = delta[n:]
+	if baseSize != len(base) {
+		return nil, fmt.Errorf("expected a base of %d bytes, got %d: %w", baseSize, len(base), ErrBaseMismatch)
+	}
+	targetSize, n, err := readSize(delta)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the size of the target: %w", err)
+	}
+	delta = delta[n:]
+
+	// The size of the target comes from the delta, so we don't trust
+	// it when allocating memory. A copy cannot be bigger than the
+	// base, and an insert than the delta, which covers most of the
+	// deltas
+	capacity := targetSize
+	if maxCapacity := len(base) + len(delta); capacity > maxCapacity {
+		capacity = maxCapacity
+	}
+	out := make([]byte, 0, capacity)
+	for len(delta) > 0 {
+		if len(out) > targetSize {
+			return nil, fmt.Errorf("expected a target of %d bytes, got more: %w", targetSize, ErrInvalidDelta)
+		}
+
+		instr := delta[0]
+		delta = delta[1:]
+
+		// Insert
+		if instr&0x80 == 0 {
+			size := int(instr)
+			if size == 0 || size > len(delta) {
+				return nil, fmt.Errorf("invalid insert of %d bytes: %w", size, ErrInvalidDelta)
+			}
+			out = append(out, delta[:size]...)
+			delta = delta[size:]
+			continue
+		}
+
+		// Copy. The 4 lowest bits tell which bytes of the offset are
+		// present, and the 3 next bits which bytes of the size are
+		// present
+		offset, size := 0, 0
+		for i := 0; i < 7; i++ {
+			if instr&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, fmt.Errorf("truncated copy instruction: %w", ErrInvalidDelta)
+			}
+			if i < 4 {
+				offset |= int(delta[0]) << (8 * i)
+			} else {
+				size |= int(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > len(base) {
+			return nil, fmt.Errorf("copy of %d bytes at offset %d is out of bound: %w", size, offset, ErrInvalidDelta)
+		}
+		out = append(out, base[offset:offset+size]...)
+	}
+
+	if len(out) != targetSize {
+		return nil, fmt.Errorf("expected a target of %d bytes, got %d: %w", targetSize, len(out), ErrInvalidDelta)
+	}
+	return out, nil
+}
+
+// writeCopy writes a copy instruction
+func writeCopy(out *bytes.Buffer, offset, size int) {
+	instr := byte(0x80)
+	args := make([]byte, 0, 7)
+	for i := 0; i < 4; i++ {
+		if b := byte(offset >> (8 * i)); b != 0 {
+			instr |= 1 << i
+			args = append(args, b)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if b := byte(size >> (8 * i)); b != 0 {
+			instr |= 1 << (4 + i)
+			args = append(args, b)
+		}
+	}
+	out.WriteByte(instr)
+	out.Write(args)
+}
+
+// writeSize writes a size using the variable-length encoding of the
+// delta headers: 7 bits per byte, least significant bits first, with
+// the MSB set when more bytes follow
+func writeSize(out *bytes.Buffer, size int) {
+	for size >= 0x80 {
+		out.WriteByte(byte(size&0x7f) | 0x80)
+		size >>= 7
+	}
+	out.WriteByte(byte(size))
+}
+
+// readSize reads a size written by writeSize, and returns the
+// number of bytes read
+func readSize(data []byte) (size, n int, err error) {
+	shift := 0
+	for i, b := range data {
+		if shift > 56 {
+			break
+		}
+		size |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return size, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid size: %w", ErrInvalidDelta)
+}
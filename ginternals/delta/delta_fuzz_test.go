@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+package delta_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/delta"
+)
+
+func FuzzApply(f *testing.F) {
+	base := bytes.Repeat([]byte("line of the base\n"), 10)
+	target := append(append([]byte("new line\n"), base[:100]...), "end\n"...)
+	f.Add(base, delta.Create(base, target))
+	f.Add([]byte{}, []byte{0x00, 0x03, 0x03, 'a', 'b', 'c'})
+	f.Add([]byte("abc"), []byte{0x03, 0x03, 0x91, 0x00, 0x03})
+	f.Add([]byte("abc"), []byte{0x03, 0x03, 0x80})
+	f.Add([]byte("abc"), []byte{0x03, 0xff, 0xff, 0xff, 0xff, 0x0f})
+
+	f.Fuzz(func(t *testing.T, base, d []byte) {
+		_, _ = delta.Apply(base, d)
+	})
+}
+
+func FuzzCreate(f *testing.F) {
+	base := bytes.Repeat([]byte("line of the base\n"), 10)
+	f.Add(base, append([]byte("new line\n"), base[:100]...))
+	f.Add([]byte{}, []byte("content"))
+	f.Add([]byte("content"), []byte{})
+
+	f.Fuzz(func(t *testing.T, base, target []byte) {
+		out, err := delta.Apply(base, delta.Create(base, target))
+		if err != nil {
+			t.Fatalf("could not apply the delta: %v", err)
+		}
+		if !bytes.Equal(out, target) {
+			t.Fatalf("expected %q, got %q", target, out)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package delta_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/delta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	random := make([]byte, 100_000)
+	rand.New(rand.NewSource(1)).Read(random) //nolint:gosec // we don't need a secure source
+
+	testCases := []struct {
+		desc   string
+		base   []byte
+		target []byte
+		// maxSize is the maximum expected size of the delta
+		maxSize int
+	}{
+		{
+			desc:    "empty contents",
+			maxSize: 2,
+		},
+		{
+			desc:    "empty base",
+			target:  []byte("hello world"),
+			maxSize: 2 + 1 + 11,
+		},
+		{
+			desc:    "empty target",
+			base:    []byte("hello world"),
+			maxSize: 2,
+		},
+		{
+			desc:    "same content",
+			base:    random,
+			target:  random,
+			maxSize: 50,
+		},
+		{
+			desc:    "data inserted in the middle",
+			base:    random,
+			target:  append(append(append([]byte{}, random[:50_000]...), []byte("inserted")...), random[50_000:]...),
+			maxSize: 70,
+		},
+		{
+			desc:    "data removed and moved",
+			base:    random,
+			target:  append(append([]byte{}, random[70_000:]...), random[:30_000]...),
+			maxSize: 50,
+		},
+		{
+			desc:    "nothing in common",
+			base:    bytes.Repeat([]byte("a"), 1000),
+			target:  bytes.Repeat([]byte("b"), 1000),
+			maxSize: 1100,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			d := delta.Create(tc.base, tc.target)
+			assert.LessOrEqual(t, len(d), tc.maxSize, "the delta is bigger than expected")
+
+			out, err := delta.Apply(tc.base, d)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(tc.target, out), "the target doesn't match")
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	base := []byte("hello world")
+
+	t.Run("should apply copies and inserts", func(t *testing.T) {
+		t.Parallel()
+
+		// base size: 11, target size: 12
+		// copy 6 bytes at offset 0, insert "a", copy 5 bytes at offset 6
+		d := []byte{11, 12, 0x90, 6, 1, 'a', 0x91, 6, 5}
+		out, err := delta.Apply(base, d)
+		require.NoError(t, err)
+		assert.Equal(t, "hello aworld", string(out))
+	})
+
+	t.Run("should fail with the wrong base", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := delta.Apply([]byte("hello"), delta.Create(base, base))
+		require.ErrorIs(t, err, delta.ErrBaseMismatch)
+	})
+
+	t.Run("should fail on invalid deltas", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			desc  string
+			delta []byte
+		}{
+			{desc: "missing sizes", delta: []byte{}},
+			{desc: "truncated insert", delta: []byte{11, 2, 5, 'a'}},
+			{desc: "copy out of bound", delta: []byte{11, 20, 0x91, 1, 20}},
+			{desc: "wrong target size", delta: []byte{11, 2, 1, 'a'}},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				_, err := delta.Apply(base, tc.delta)
+				require.ErrorIs(t, err, delta.ErrInvalidDelta)
+			})
+		}
+	})
+}
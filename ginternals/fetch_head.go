@@ -0,0 +1,76 @@
+package ginternals
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// notForMerge is the marker used in FETCH_HEAD to flag the refs that
+// should not be merged by git pull
+const notForMerge = "not-for-merge"
+
+// FetchHeadEntry represents a ref listed in FETCH_HEAD
+type FetchHeadEntry struct {
+	ID Oid
+	// NotForMerge is set if the ref should not be merged by git pull
+	NotForMerge bool
+	// Description describes where the ref comes from.
+	// Ex: branch 'master' of github.com:Nivl/git-go
+	Description string
+}
+
+// ParseFetchHead parses the content of FETCH_HEAD. Each line contains
+// an oid, an optional "not-for-merge" marker, and a description,
+// separated by tabs. Example:
+//
+//	bbb720a96e4c29b9950a4c577c98470a4d5dd089		branch 'master' of github.com:Nivl/git-go
+//	5f35f2dc6cec7356da02ca26192ce2bc3f271e79	not-for-merge	branch 'ml/feat/clone' of github.com:Nivl/git-go
+func ParseFetchHead(data []byte) ([]FetchHeadEntry, error) {
+	entries := []FetchHeadEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 3)
+		oid, err := NewOidFromStr(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid on line %d: %w", i, ErrRefInvalid)
+		}
+		entry := FetchHeadEntry{ID: oid}
+		if len(parts) > 1 {
+			switch parts[1] {
+			case "":
+			case notForMerge:
+				entry.NotForMerge = true
+			default:
+				return nil, fmt.Errorf("invalid marker %q on line %d: %w", parts[1], i, ErrRefInvalid)
+			}
+		}
+		if len(parts) > 2 {
+			entry.Description = parts[2]
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read FETCH_HEAD: %w", err)
+	}
+	return entries, nil
+}
+
+// FormatFetchHead returns the content of a FETCH_HEAD containing the
+// given entries
+func FormatFetchHead(entries []FetchHeadEntry) []byte {
+	buf := &bytes.Buffer{}
+	for _, e := range entries {
+		marker := ""
+		if e.NotForMerge {
+			marker = notForMerge
+		}
+		fmt.Fprintf(buf, "%s\t%s\t%s\n", e.ID.String(), marker, e.Description)
+	}
+	return buf.Bytes()
+}
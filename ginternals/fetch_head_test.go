@@ -0,0 +1,42 @@
+package ginternals
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFetchHead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse and format FETCH_HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		data := "bbb720a96e4c29b9950a4c577c98470a4d5dd089\t\tbranch 'master' of github.com:Nivl/git-go\n" +
+			"5f35f2dc6cec7356da02ca26192ce2bc3f271e79\tnot-for-merge\tbranch 'ml/feat/clone' of github.com:Nivl/git-go\n"
+		entries, err := ParseFetchHead([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", entries[0].ID.String())
+		assert.False(t, entries[0].NotForMerge)
+		assert.Equal(t, "branch 'master' of github.com:Nivl/git-go", entries[0].Description)
+		assert.Equal(t, "5f35f2dc6cec7356da02ca26192ce2bc3f271e79", entries[1].ID.String())
+		assert.True(t, entries[1].NotForMerge)
+		assert.Equal(t, "branch 'ml/feat/clone' of github.com:Nivl/git-go", entries[1].Description)
+
+		assert.Equal(t, data, string(FormatFetchHead(entries)))
+	})
+
+	t.Run("should fail on invalid content", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFetchHead([]byte("not an oid\t\tbranch\n"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRefInvalid)
+
+		_, err = ParseFetchHead([]byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089\tinvalid\tbranch\n"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRefInvalid)
+	})
+}
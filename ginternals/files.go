@@ -16,6 +16,8 @@ const (
 	refsDirName      = "refs"
 	refsTagsRelPath  = refsDirName + "/tags"
 	refsHeadsRelPath = refsDirName + "/heads"
+	// refsRemotesRelPath contains the path of the remote branches
+	refsRemotesRelPath = refsDirName + "/remotes"
 )
 
 // LocalTagFullName returns the full name of a tag
@@ -42,11 +44,91 @@ func LocalBranchShortName(fullName string) string {
 	return strings.TrimPrefix(fullName, refsHeadsRelPath+"/")
 }
 
+// RemoteBranchFullName returns the full name of a remote branch
+// ex. for `origin` and `main` returns `refs/remotes/origin/main`
+func RemoteBranchFullName(remote, shortName string) string {
+	return path.Join(refsRemotesRelPath, remote, shortName)
+}
+
+// RemoteBranchShortName returns the short name of a remote branch,
+// prefixed by the name of its remote
+// ex. for `refs/remotes/origin/main` returns `origin/main`
+func RemoteBranchShortName(fullName string) string {
+	return strings.TrimPrefix(fullName, refsRemotesRelPath+"/")
+}
+
 // RefFullName returns the UNIX path of a ref
 func RefFullName(shortName string) string {
 	return path.Join("refs", shortName)
 }
 
+// refNameRules contains the rules used by git to find the full name
+// of a reference from a short name, in order of priority
+// https://git-scm.com/docs/gitrevisions#Documentation/gitrevisions.txt-emltrefnamegtemegemmasterememheadsmasterememrefsheadsmasterem
+//
+//nolint:gochecknoglobals // Treat it as a const
+var refNameRules = []struct {
+	prefix string
+	suffix string
+}{
+	// catches stuff like HEAD, ORIG_HEAD, or refs/heads/master
+	{},
+	// catches heads/master
+	{prefix: refsDirName + "/"},
+	// catches local tag names
+	{prefix: refsTagsRelPath + "/"},
+	// catches local branch names
+	{prefix: refsHeadsRelPath + "/"},
+	// catches remote branches like origin/master
+	{prefix: refsRemotesRelPath + "/"},
+	// catches remote names like origin
+	{prefix: refsRemotesRelPath + "/", suffix: "/" + Head},
+}
+
+// RefNameCandidates returns the full names a short reference name may
+// refer to, in the order git looks for them.
+// ex. for `main` returns `main`, `refs/main`, `refs/tags/main`,
+// `refs/heads/main`, `refs/remotes/main`, and `refs/remotes/main/HEAD`
+func RefNameCandidates(shortName string) []string {
+	candidates := make([]string, len(refNameRules))
+	for i, rule := range refNameRules {
+		candidates[i] = rule.prefix + shortName + rule.suffix
+	}
+	return candidates
+}
+
+// ShortenRefName returns the shortest name that git can use to find
+// the given reference, without checking if the name is ambiguous.
+// ex. for `refs/remotes/origin/main` returns `origin/main`, and for
+// `refs/remotes/origin/HEAD` returns `origin`.
+// The name is returned as-is if it cannot be shortened
+func ShortenRefName(fullName string) string {
+	if names := RefShortNames(fullName); len(names) > 0 {
+		return names[0]
+	}
+	return fullName
+}
+
+// RefShortNames returns all the short names that git can use to find
+// the given reference, shortest first. Some of the names may be
+// ambiguous.
+// ex. for `refs/heads/main` returns `main` and `heads/main`
+func RefShortNames(fullName string) []string {
+	names := []string{}
+	// The last rules produce the shortest names. The first rule is
+	// skipped since it doesn't shorten anything
+	for i := len(refNameRules) - 1; i > 0; i-- {
+		r := refNameRules[i]
+		if len(fullName) <= len(r.prefix)+len(r.suffix) {
+			continue
+		}
+		if strings.HasPrefix(fullName, r.prefix) && strings.HasSuffix(fullName, r.suffix) {
+			names = append(names, fullName[len(r.prefix):len(fullName)-len(r.suffix)])
+		}
+	}
+	return names
+}
+
 // RefsPath return the path to the directory that contains all the refs
 func RefsPath(cfg *config.Config) string {
 	return filepath.Join(cfg.CommonDirPath, "refs")
@@ -57,6 +139,16 @@ func RefPath(cfg *config.Config, name string) string {
 	return filepath.Join(cfg.CommonDirPath, "refs", filepath.FromSlash(name))
 }
 
+// ReflogPath returns the path of the reflog of a reference.
+// The reflog of HEAD is specific to the worktree, the other
+// reflogs are shared
+func ReflogPath(cfg *config.Config, name string) string {
+	if name == Head {
+		return filepath.Join(cfg.GitDirPath, "logs", Head)
+	}
+	return filepath.Join(cfg.CommonDirPath, "logs", filepath.FromSlash(name))
+}
+
 // PackedRefsPath return the local path of a the packed-refs file
 func PackedRefsPath(cfg *config.Config) string {
 	return filepath.Join(cfg.CommonDirPath, "packed-refs")
@@ -90,6 +182,12 @@ func ObjectsInfoPath(cfg *config.Config) string {
 	return filepath.Join(cfg.ObjectDirPath, "info")
 }
 
+// InfoPacksPath returns the path to the file listing the packfiles
+// of the repository, used by the dumb protocols
+func InfoPacksPath(cfg *config.Config) string {
+	return filepath.Join(ObjectsInfoPath(cfg), "packs")
+}
+
 // ObjectsPacksPath returns the path to the directory that contains
 // the packfiles
 func ObjectsPacksPath(cfg *config.Config) string {
@@ -111,11 +209,17 @@ func DescriptionFilePath(cfg *config.Config) string {
 	return filepath.Join(DotGitPath(cfg), "description")
 }
 
+// InfoAttributesPath returns the path to the file containing the
+// attributes that are specific to the repository
+func InfoAttributesPath(cfg *config.Config) string {
+	return filepath.Join(DotGitPath(cfg), "info", "attributes")
+}
+
 // LooseObjectPath returns the path of a loose object.
 // Path is .git/objects/first_2_chars_of_sha/remaining_chars_of_sha
 //
 // Ex. path of fcfe68a0e44e04bd7fd564fc0b75f1ae457e18b3 is:
 // .git/objects/fc/fe68a0e44e04bd7fd564fc0b75f1ae457e18b3
 func LooseObjectPath(cfg *config.Config, sha string) string {
-	return filepath.Join(ObjectsPath(cfg), sha[:2], sha[2:])
+	return KeyPath(ObjectsPath(cfg), looseObjectKey(sha))
 }
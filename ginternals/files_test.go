@@ -1,11 +1,13 @@
 package ginternals_test
 
 import (
+	"fmt"
 	"path/filepath"
 	"testing"
 
 	"github.com/Nivl/git-go/ginternals"
 	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,6 +43,22 @@ func TestLocalBranchShortName(t *testing.T) {
 	require.Equal(t, expect, out)
 }
 
+func TestRemoteBranchFullName(t *testing.T) {
+	t.Parallel()
+
+	out := ginternals.RemoteBranchFullName("origin", "my-branch/nested")
+	expect := "refs/remotes/origin/my-branch/nested"
+	require.Equal(t, expect, out)
+}
+
+func TestRemoteBranchShortName(t *testing.T) {
+	t.Parallel()
+
+	out := ginternals.RemoteBranchShortName("refs/remotes/origin/my-branch/nested")
+	expect := "origin/my-branch/nested"
+	require.Equal(t, expect, out)
+}
+
 func TestRefFullName(t *testing.T) {
 	t.Parallel()
 
@@ -49,6 +67,55 @@ func TestRefFullName(t *testing.T) {
 	require.Equal(t, expect, out)
 }
 
+func TestRefNameCandidates(t *testing.T) {
+	t.Parallel()
+
+	out := ginternals.RefNameCandidates("origin")
+	expect := []string{
+		"origin",
+		"refs/origin",
+		"refs/tags/origin",
+		"refs/heads/origin",
+		"refs/remotes/origin",
+		"refs/remotes/origin/HEAD",
+	}
+	require.Equal(t, expect, out)
+}
+
+func TestShortenRefName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "refs/heads/main", expected: "main"},
+		{name: "refs/heads/ml/feature", expected: "ml/feature"},
+		{name: "refs/tags/v1.0.0", expected: "v1.0.0"},
+		{name: "refs/remotes/origin/main", expected: "origin/main"},
+		{name: "refs/remotes/origin/HEAD", expected: "origin"},
+		{name: "refs/notes/commits", expected: "notes/commits"},
+		{name: "HEAD", expected: "HEAD"},
+		{name: "refs/heads/", expected: "heads/"},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, ginternals.ShortenRefName(tc.name))
+		})
+	}
+}
+
+func TestRefShortNames(t *testing.T) {
+	t.Parallel()
+
+	out := ginternals.RefShortNames("refs/remotes/origin/HEAD")
+	expect := []string{"origin", "origin/HEAD", "remotes/origin/HEAD"}
+	require.Equal(t, expect, out)
+}
+
 func TestRefsPath(t *testing.T) {
 	t.Parallel()
 
@@ -73,6 +140,23 @@ func TestRefPath(t *testing.T) {
 	require.Equal(t, expect, out)
 }
 
+func TestReflogPath(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		GitDirPath:    "gitdir",
+		CommonDirPath: "common",
+	}
+
+	out := ginternals.ReflogPath(cfg, "refs/heads/main")
+	expect := filepath.Join("common", "logs", "refs", "heads", "main")
+	require.Equal(t, expect, out)
+
+	out = ginternals.ReflogPath(cfg, "HEAD")
+	expect = filepath.Join("gitdir", "logs", "HEAD")
+	require.Equal(t, expect, out)
+}
+
 func TestPackedRefsPath(t *testing.T) {
 	t.Parallel()
 
@@ -193,6 +277,18 @@ func TestDescriptionFilePath(t *testing.T) {
 	require.Equal(t, expect, out)
 }
 
+func TestInfoAttributesPath(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		GitDirPath: ".git",
+	}
+
+	out := ginternals.InfoAttributesPath(cfg)
+	expect := filepath.Join(".git", "info", "attributes")
+	require.Equal(t, expect, out)
+}
+
 func TestLooseObjectPath(t *testing.T) {
 	t.Parallel()
 
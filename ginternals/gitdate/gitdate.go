@@ -0,0 +1,171 @@
+// Package gitdate contains methods to parse the date formats
+// supported by git, like the ones used in $GIT_COMMITTER_DATE,
+// --since, or gc.pruneExpire
+package gitdate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDate is an error thrown when a date cannot be parsed
+var ErrInvalidDate = errors.New("invalid date")
+
+// minTimestamp is the smallest number that is considered to be a
+// unix timestamp when not prefixed with "@".
+// Like git, smaller numbers are not considered timestamps because
+// they are more likely to be part of a date (like 20200620)
+const minTimestamp = 100000000
+
+// strictLayouts contains the layouts supported by Parse, on top of
+// git's internal format
+//
+//nolint:gochecknoglobals // Treat it as a const
+var strictLayouts = []string{
+	time.RFC1123Z,                    // RFC 2822
+	"Mon, 2 Jan 2006 15:04:05 -0700", // RFC 2822, with a single digit day
+	"2 Jan 2006 15:04:05 -0700",      // RFC 2822, without the day of the week
+	time.RFC3339,                     // ISO 8601, strict
+	"2006-01-02 15:04:05 -0700",      // ISO 8601, like git's --date=iso
+}
+
+// localLayouts contains the layouts supported by Parse that don't
+// contain a timezone. The dates are in the local timezone
+//
+//nolint:gochecknoglobals // Treat it as a const
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parse parses a date in one of the strict formats supported by git:
+//   - git's internal format: "<unix timestamp> <timezone offset>",
+//     like "1592616250 -0700". The timestamp can be prefixed with "@",
+//     and the offset can be omitted, in which case UTC is used
+//   - RFC 2822: "Sat, 20 Jun 2020 01:24:10 +0000"
+//   - ISO 8601: "2020-06-20T01:24:10-07:00", "2020-06-20 01:24:10 -0700".
+//     If the timezone is missing, the local one is used
+//
+// This is what git uses to parse $GIT_AUTHOR_DATE and
+// $GIT_COMMITTER_DATE
+func Parse(date string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	if t, ok, err := parseRaw(date); ok {
+		return t, err
+	}
+
+	for _, layout := range strictLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported format %q: %w", date, ErrInvalidDate)
+}
+
+// parseRaw parses a date in git's internal format. false is returned
+// if the date is not using this format
+func parseRaw(date string) (t time.Time, ok bool, err error) {
+	hasAt := strings.HasPrefix(date, "@")
+	parts := strings.Fields(strings.TrimPrefix(date, "@"))
+	if len(parts) == 0 || len(parts) > 2 {
+		return time.Time{}, false, nil
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || (!hasAt && ts < minTimestamp) {
+		return time.Time{}, false, nil
+	}
+
+	t = time.Unix(ts, 0).UTC()
+	if len(parts) == 2 {
+		tz, err := time.Parse("-0700", parts[1])
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("invalid timezone %s: %w", parts[1], ErrInvalidDate)
+		}
+		t = t.In(tz.Location())
+	}
+	return t, true, nil
+}
+
+// ParseApprox parses a date the same way git's approxidate does. On
+// top of the formats supported by Parse, the following relative
+// dates are supported:
+//   - "now"
+//   - "yesterday"
+//   - "<n> <unit> ago" or "<n>.<unit>.ago", where unit is one of
+//     second, minute, hour, day, week, month, or year (with an optional
+//     "s"). Ex: "2 weeks ago", "1.day.ago"
+//
+// The relative dates are computed from now.
+// This is what git uses to parse --since and --until
+func ParseApprox(date string, now time.Time) (time.Time, error) {
+	if t, err := Parse(date); err == nil {
+		return t, nil
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.FieldsFunc(date, func(r rune) bool {
+		return r == ' ' || r == '.' || r == '\t'
+	}), " "))
+	switch normalized {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	parts := strings.Fields(normalized)
+	if len(parts) != 3 || parts[2] != "ago" {
+		return time.Time{}, fmt.Errorf("unsupported format %q: %w", date, ErrInvalidDate)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid number %q: %w", parts[0], ErrInvalidDate)
+	}
+	switch strings.TrimSuffix(parts[1], "s") {
+	case "second":
+		return now.Add(-time.Duration(n) * time.Second), nil
+	case "minute":
+		return now.Add(-time.Duration(n) * time.Minute), nil
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, -n), nil
+	case "week":
+		return now.AddDate(0, 0, -7*n), nil
+	case "month":
+		return now.AddDate(0, -n, 0), nil
+	case "year":
+		return now.AddDate(-n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported unit %q: %w", parts[1], ErrInvalidDate)
+}
+
+// farFuture is a date used to represent the end of time
+//
+//nolint:gochecknoglobals // Treat it as a const
+var farFuture = time.Unix(1<<40, 0)
+
+// ParseExpiry parses a grace period (like gc.pruneExpire) and returns
+// the date before which the data can be removed.
+// Like git:
+//   - "never" returns the zero Time, which no dates are before
+//   - "now" and "all" returns a date far in the future, so everything
+//     can be removed
+//   - Any other values are parsed using ParseApprox
+func ParseExpiry(expiry string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(expiry)) {
+	case "never", "false":
+		return time.Time{}, nil
+	case "now", "all":
+		return farFuture, nil
+	}
+	return ParseApprox(expiry, now)
+}
@@ -0,0 +1,206 @@
+package gitdate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals/gitdate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	// The expected timestamps have been generated using
+	// GIT_COMMITTER_DATE=<date> git var GIT_COMMITTER_IDENT
+	testCases := []struct {
+		desc       string
+		date       string
+		expected   string
+		shouldFail bool
+	}{
+		{
+			desc:     "internal format",
+			date:     "1592616250 -0700",
+			expected: "1592616250 -0700",
+		},
+		{
+			desc:     "internal format with @ and no timezone",
+			date:     "@1592616250",
+			expected: "1592616250 +0000",
+		},
+		{
+			desc:     "timestamp",
+			date:     "1592616250",
+			expected: "1592616250 +0000",
+		},
+		{
+			desc:     "RFC 2822",
+			date:     "Sat, 20 Jun 2020 01:24:10 +0000",
+			expected: "1592616250 +0000",
+		},
+		{
+			desc:     "RFC 2822 without the day of the week",
+			date:     "20 Jun 2020 01:24:10 +0200",
+			expected: "1592609050 +0200",
+		},
+		{
+			desc:     "strict ISO 8601",
+			date:     "2020-06-20T01:24:10-07:00",
+			expected: "1592641450 -0700",
+		},
+		{
+			desc:     "strict ISO 8601 in UTC",
+			date:     "2020-06-20T01:24:10Z",
+			expected: "1592616250 +0000",
+		},
+		{
+			desc:     "ISO 8601",
+			date:     "2020-06-20 01:24:10 -0700",
+			expected: "1592641450 -0700",
+		},
+		{
+			desc:       "invalid timezone",
+			date:       "1592616250 PST",
+			shouldFail: true,
+		},
+		{
+			desc:       "relative dates are not supported",
+			date:       "2 weeks ago",
+			shouldFail: true,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			d, err := gitdate.Parse(tc.date)
+			if tc.shouldFail {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, gitdate.ErrInvalidDate), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, fmt.Sprintf("%d %s", d.Unix(), d.Format("-0700")))
+		})
+	}
+}
+
+func TestParseApprox(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, time.June, 20, 1, 24, 10, 0, time.UTC)
+	testCases := []struct {
+		desc       string
+		date       string
+		expected   time.Time
+		shouldFail bool
+	}{
+		{
+			desc:     "strict formats should be supported",
+			date:     "1592616250 +0000",
+			expected: now,
+		},
+		{
+			desc:     "now",
+			date:     "now",
+			expected: now,
+		},
+		{
+			desc:     "yesterday",
+			date:     "yesterday",
+			expected: now.AddDate(0, 0, -1),
+		},
+		{
+			desc:     "weeks with spaces",
+			date:     "2 weeks ago",
+			expected: now.AddDate(0, 0, -14),
+		},
+		{
+			desc:     "singular day with dots",
+			date:     "1.day.ago",
+			expected: now.AddDate(0, 0, -1),
+		},
+		{
+			desc:     "hours",
+			date:     "3 hours ago",
+			expected: now.Add(-3 * time.Hour),
+		},
+		{
+			desc:     "months",
+			date:     "2.months.ago",
+			expected: time.Date(2020, time.April, 20, 1, 24, 10, 0, time.UTC),
+		},
+		{
+			desc:     "years",
+			date:     "1 year ago",
+			expected: time.Date(2019, time.June, 20, 1, 24, 10, 0, time.UTC),
+		},
+		{
+			desc:       "unknown unit",
+			date:       "2 fortnights ago",
+			shouldFail: true,
+		},
+		{
+			desc:       "missing ago",
+			date:       "2 weeks",
+			shouldFail: true,
+		},
+		{
+			desc:       "invalid number",
+			date:       "two weeks ago",
+			shouldFail: true,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			d, err := gitdate.ParseApprox(tc.date, now)
+			if tc.shouldFail {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, gitdate.ErrInvalidDate), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(d), "expected %s, got %s", tc.expected, d)
+		})
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, time.June, 20, 1, 24, 10, 0, time.UTC)
+
+	t.Run("never should not expire anything", func(t *testing.T) {
+		t.Parallel()
+
+		d, err := gitdate.ParseExpiry("never", now)
+		require.NoError(t, err)
+		assert.True(t, d.IsZero())
+	})
+
+	t.Run("now should expire everything", func(t *testing.T) {
+		t.Parallel()
+
+		for _, expiry := range []string{"now", "all"} {
+			d, err := gitdate.ParseExpiry(expiry, now)
+			require.NoError(t, err)
+			assert.True(t, d.After(now.AddDate(1000, 0, 0)), "%s should be in the far future", expiry)
+		}
+	})
+
+	t.Run("relative dates should be supported", func(t *testing.T) {
+		t.Parallel()
+
+		d, err := gitdate.ParseExpiry("2.weeks.ago", now)
+		require.NoError(t, err)
+		assert.True(t, now.AddDate(0, 0, -14).Equal(d))
+	})
+}
@@ -0,0 +1,107 @@
+package ginternals
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Layout maps the objects and the references of a repository to the
+// keys used to store them.
+// A key is a slash-separated path relative to the object directory
+// (for the objects) or to the git directory (for the references).
+// FSLayout, which uses the same layout as git, is the default one, but
+// stores that are not file systems (object stores, key-value stores,
+// etc.) may use a different one, like a flat keyspace
+type Layout interface {
+	// LooseObjectKey returns the key of the loose object with the
+	// given ID
+	LooseObjectKey(oid Oid) string
+	// LooseObjectID returns the ID of the loose object stored at the
+	// given key. false is returned if the key doesn't belong to a
+	// loose object
+	LooseObjectID(key string) (oid Oid, ok bool)
+	// IsLooseObjectDir returns whether the given key may contain
+	// loose objects. It's used to skip the parts of the storage that
+	// don't contain any loose objects (packfiles, info, etc.) when
+	// looking for them. "." is used for the object directory itself
+	IsLooseObjectDir(key string) bool
+	// RefKey returns the key of the reference with the given name
+	RefKey(name string) string
+	// RefName returns the name of the reference stored at the given
+	// key. false is returned if the key doesn't belong to a reference
+	RefName(key string) (name string, ok bool)
+}
+
+// FSLayout is the Layout used by git. Loose objects are stored in
+// fan-out directories named after the first 2 chars of their ID, and
+// references are stored as-is.
+//
+// Ex. fcfe68a0e44e04bd7fd564fc0b75f1ae457e18b3 is stored at
+// fc/fe68a0e44e04bd7fd564fc0b75f1ae457e18b3, and refs/heads/main at
+// refs/heads/main
+type FSLayout struct{}
+
+// Make sure FSLayout implements Layout
+var _ Layout = FSLayout{}
+
+// LooseObjectKey returns the key of the loose object with the
+// given ID
+func (FSLayout) LooseObjectKey(oid Oid) string {
+	return looseObjectKey(oid.String())
+}
+
+// LooseObjectID returns the ID of the loose object stored at the
+// given key. false is returned if the key doesn't belong to a
+// loose object
+func (l FSLayout) LooseObjectID(key string) (oid Oid, ok bool) {
+	dir, name := path.Split(key)
+	if !l.IsLooseObjectDir(strings.TrimSuffix(dir, "/")) {
+		return NullOid, false
+	}
+	oid, err := NewOidFromStr(strings.TrimSuffix(dir, "/") + name)
+	if err != nil {
+		return NullOid, false
+	}
+	return oid, true
+}
+
+// IsLooseObjectDir returns whether the given key may contain
+// loose objects, meaning it's the object directory or one of the
+// fan-out directories (00 up to ff)
+func (FSLayout) IsLooseObjectDir(key string) bool {
+	if key == "." {
+		return true
+	}
+	if len(key) != 2 {
+		return false
+	}
+	for _, c := range key {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// RefKey returns the key of the reference with the given name
+func (FSLayout) RefKey(name string) string {
+	return name
+}
+
+// RefName returns the name of the reference stored at the given
+// key
+func (FSLayout) RefName(key string) (name string, ok bool) {
+	return key, key != ""
+}
+
+// KeyPath returns the path on the system of the given key of a
+// Layout, root being the directory containing the key
+func KeyPath(root, key string) string {
+	return filepath.Join(root, filepath.FromSlash(key))
+}
+
+// looseObjectKey returns the key of a loose object in the FS layout
+func looseObjectKey(sha string) string {
+	return sha[:2] + "/" + sha[2:]
+}
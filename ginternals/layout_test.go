@@ -0,0 +1,83 @@
+package ginternals_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSLayout(t *testing.T) {
+	t.Parallel()
+
+	layout := ginternals.FSLayout{}
+
+	t.Run("LooseObjectKey", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("fcfe68a0e44e04bd7fd564fc0b75f1ae457e18b3")
+		require.NoError(t, err)
+		key := layout.LooseObjectKey(oid)
+		assert.Equal(t, "fc/fe68a0e44e04bd7fd564fc0b75f1ae457e18b3", key)
+
+		found, ok := layout.LooseObjectID(key)
+		assert.True(t, ok, "the key should be a loose object")
+		assert.Equal(t, oid, found)
+	})
+
+	t.Run("LooseObjectID", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []string{
+			"fcfe68a0e44e04bd7fd564fc0b75f1ae457e18b3",
+			"fc/tmp_obj_123456",
+			"pack/fe68a0e44e04bd7fd564fc0b75f1ae457e18b3",
+			"info/packs",
+			"fc/fe68a0e44e04bd7fd564fc0b75f1ae457e18b3/nested",
+		}
+		for i, key := range testCases {
+			key := key
+			t.Run(fmt.Sprintf("%d/%s", i, key), func(t *testing.T) {
+				t.Parallel()
+
+				_, ok := layout.LooseObjectID(key)
+				assert.False(t, ok, "the key should not be a loose object")
+			})
+		}
+	})
+
+	t.Run("IsLooseObjectDir", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, layout.IsLooseObjectDir("."))
+		for i := 0; i < 256; i++ {
+			assert.True(t, layout.IsLooseObjectDir(fmt.Sprintf("%02x", i)))
+		}
+		for _, key := range []string{"pack", "info", "f", "fff", "gg", "fc/fe"} {
+			assert.False(t, layout.IsLooseObjectDir(key), "%s should not be a loose object dir", key)
+		}
+	})
+
+	t.Run("RefKey", func(t *testing.T) {
+		t.Parallel()
+
+		for _, name := range []string{ginternals.Head, "refs/heads/feat/nested"} {
+			key := layout.RefKey(name)
+			assert.Equal(t, name, key)
+
+			found, ok := layout.RefName(key)
+			assert.True(t, ok, "the key should be a reference")
+			assert.Equal(t, name, found)
+		}
+	})
+}
+
+func TestKeyPath(t *testing.T) {
+	t.Parallel()
+
+	p := ginternals.KeyPath(filepath.Join("repo", ".git"), "refs/heads/main")
+	assert.Equal(t, filepath.Join("repo", ".git", "refs", "heads", "main"), p)
+}
@@ -0,0 +1,266 @@
+// Package lockfile implements the lockfile protocol used by git to
+// atomically update files.
+// To update a file X, the new content is written to X.lock, which is
+// created exclusively, and then renamed to X. Since the lock can only
+// be created by one process at a time, concurrent writers (including
+// git itself) cannot corrupt X.
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/afero"
+)
+
+// Suffix is the suffix added to the path of a file to get the path
+// of its lock
+const Suffix = ".lock"
+
+// ErrLocked is an error thrown when a file is already locked by
+// another process
+var ErrLocked = errors.New("file is locked")
+
+// LockedError is the error returned when a file is already locked.
+// It contains the age of the existing lock so the caller can decide
+// whether the lock has been left behind by a process that crashed.
+// LockedError wraps ErrLocked
+type LockedError struct {
+	Path string
+	// Age is the age of the existing lock, or 0 if it couldn't
+	// be retrieved
+	Age time.Duration
+}
+
+func (e *LockedError) Error() string {
+	if e.Age == 0 {
+		return fmt.Sprintf("could not lock %s: %s", e.Path, ErrLocked.Error())
+	}
+	return fmt.Sprintf("could not lock %s: %s (the lock is %s old)", e.Path, ErrLocked.Error(), e.Age.Round(time.Second))
+}
+
+// Unwrap returns ErrLocked
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Options represents the optional params that can be used to
+// create a lock
+type Options struct {
+	// Perm contains the permissions of the file.
+	// Defaults to 0o644
+	Perm os.FileMode
+	// IgnoreUmask sets the permissions of the file to Perm even if
+	// they are restricted by the umask of the process, which is
+	// needed by the shared repositories
+	IgnoreUmask bool
+	// StaleAge is the age after which an existing lock is considered
+	// stale (left behind by a process that crashed), and removed.
+	// Like git, existing locks are never removed by default (zero
+	// or negative value)
+	StaleAge time.Duration
+}
+
+// active contains the locks held by the process, so they can be
+// released if the process gets interrupted
+var active = struct {
+	sync.Mutex
+	locks map[*Lock]struct{}
+}{
+	locks: map[*Lock]struct{}{},
+}
+
+// Lock represents a locked file. The new content of the file is
+// written to the lock, and the file is updated when the lock is
+// committed
+type Lock struct {
+	fs   afero.Fs
+	path string
+	f    afero.File
+
+	mu   sync.Mutex
+	done bool
+}
+
+// New locks the file at the given path by creating its lock file.
+// The file doesn't need to exist.
+// A *LockedError wrapping ErrLocked is returned if the file is
+// already locked
+func New(fs afero.Fs, path string, opts Options) (*Lock, error) {
+	if opts.Perm == 0 {
+		opts.Perm = 0o644
+	}
+
+	lockPath := path + Suffix
+	f, err := fs.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, opts.Perm)
+	if err != nil && errors.Is(err, os.ErrExist) {
+		var age time.Duration
+		if age, err = removeStale(fs, lockPath, opts.StaleAge); err != nil {
+			return nil, err
+		}
+		if age != 0 {
+			return nil, &LockedError{Path: path, Age: age}
+		}
+		f, err = fs.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, opts.Perm)
+		if err != nil && errors.Is(err, os.ErrExist) {
+			return nil, &LockedError{Path: path}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", lockPath, err)
+	}
+	if opts.IgnoreUmask {
+		if err = fs.Chmod(lockPath, opts.Perm); err != nil {
+			f.Close()           //nolint:errcheck // it already failed
+			fs.Remove(lockPath) //nolint:errcheck // it already failed
+			return nil, fmt.Errorf("could not set the permissions of %s: %w", lockPath, err)
+		}
+	}
+	l := &Lock{
+		fs:   fs,
+		path: path,
+		f:    f,
+	}
+	active.Lock()
+	active.locks[l] = struct{}{}
+	active.Unlock()
+	return l, nil
+}
+
+// release marks the lock as done and removes it from the active locks.
+// Returns false if the lock was already released.
+// l.mu is expected to be held
+func (l *Lock) release() bool {
+	if l.done {
+		return false
+	}
+	l.done = true
+	active.Lock()
+	delete(active.locks, l)
+	active.Unlock()
+	return true
+}
+
+// removeStale removes the given lock if it's older than maxAge.
+// A zero or negative maxAge never removes the lock.
+// Returns the age of the lock if it's still there, or 0 if it has been
+// removed
+func removeStale(fs afero.Fs, lockPath string, maxAge time.Duration) (time.Duration, error) {
+	info, err := fs.Stat(lockPath)
+	if err != nil {
+		// The lock has been released in the meantime
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("could not stat %s: %w", lockPath, err)
+	}
+	// The age is never 0 to not be confused with a removed lock
+	age := time.Since(info.ModTime())
+	if age <= 0 {
+		age = time.Nanosecond
+	}
+	if maxAge <= 0 || age <= maxAge {
+		return age, nil
+	}
+	if err = fs.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("could not remove stale lock %s: %w", lockPath, err)
+	}
+	return 0, nil
+}
+
+// Path returns the path of the locked file
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Write writes the given data to the lock
+func (l *Lock) Write(p []byte) (int, error) {
+	return l.f.Write(p)
+}
+
+// Commit replaces the locked file by the content written to the
+// lock, and releases the lock.
+// If the commit fails, the lock is released and the file is left
+// untouched
+func (l *Lock) Commit() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.release() {
+		return nil
+	}
+	lockPath := l.path + Suffix
+	defer func() {
+		if err != nil {
+			l.fs.Remove(lockPath) //nolint:errcheck // it already failed
+		}
+	}()
+
+	// Some filesystems (like windows) don't allow renaming an
+	// opened file, so we need to close it first
+	if err = l.f.Close(); err != nil {
+		return fmt.Errorf("could not write %s: %w", lockPath, err)
+	}
+	if err = l.fs.Rename(lockPath, l.path); err != nil {
+		return fmt.Errorf("could not move %s to %s: %w", lockPath, l.path, err)
+	}
+	return nil
+}
+
+// Rollback releases the lock without updating the locked file.
+// Calling Rollback on a committed lock does nothing, which allows
+// deferring Rollback right after creating the lock
+func (l *Lock) Rollback() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.release() {
+		return nil
+	}
+	lockPath := l.path + Suffix
+	errutil.Close(l.f, &err)
+	if e := l.fs.Remove(lockPath); e != nil && !errors.Is(e, os.ErrNotExist) && err == nil {
+		err = fmt.Errorf("could not remove %s: %w", lockPath, e)
+	}
+	return err
+}
+
+// RollbackAll releases all the locks held by the process without
+// updating the locked files. This is meant to be used when the process
+// is interrupted, so no lock files are left behind.
+// The first error is returned, but all the locks are released anyway
+func RollbackAll() error {
+	active.Lock()
+	locks := make([]*Lock, 0, len(active.locks))
+	for l := range active.locks {
+		locks = append(locks, l)
+	}
+	active.Unlock()
+
+	var err error
+	for _, l := range locks {
+		if e := l.Rollback(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// WriteFile atomically replaces the content of the file at the
+// given path.
+// A *LockedError wrapping ErrLocked is returned if the file is
+// already locked
+func WriteFile(fs afero.Fs, path string, data []byte, opts Options) error {
+	l, err := New(fs, path, opts)
+	if err != nil {
+		return err
+	}
+	defer l.Rollback() //nolint:errcheck // no-op if the lock is committed
+
+	if _, err = l.Write(data); err != nil {
+		return fmt.Errorf("could not write %s: %w", path+Suffix, err)
+	}
+	return l.Commit()
+}
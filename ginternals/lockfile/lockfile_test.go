@@ -0,0 +1,191 @@
+package lockfile_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Commit should replace the file", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "file", []byte("old"), 0o644))
+
+		l, err := lockfile.New(fs, "file", lockfile.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, "file", l.Path())
+		_, err = l.Write([]byte("new"))
+		require.NoError(t, err)
+
+		// The file should not change until the lock is committed
+		data, err := afero.ReadFile(fs, "file")
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+
+		require.NoError(t, l.Commit())
+		data, err = afero.ReadFile(fs, "file")
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+		_, err = fs.Stat("file.lock")
+		assert.True(t, errors.Is(err, os.ErrNotExist), "the lock should have been removed")
+
+		// Rollback should be a no-op after a commit
+		require.NoError(t, l.Rollback())
+		_, err = fs.Stat("file")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rollback should leave the file untouched", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "file", []byte("old"), 0o644))
+
+		l, err := lockfile.New(fs, "file", lockfile.Options{})
+		require.NoError(t, err)
+		_, err = l.Write([]byte("new"))
+		require.NoError(t, err)
+		require.NoError(t, l.Rollback())
+
+		data, err := afero.ReadFile(fs, "file")
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+		_, err = fs.Stat("file.lock")
+		assert.True(t, errors.Is(err, os.ErrNotExist), "the lock should have been removed")
+	})
+
+	t.Run("should fail if the file is already locked", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		l, err := lockfile.New(fs, "file", lockfile.Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, l.Rollback())
+		})
+
+		_, err = lockfile.New(fs, "file", lockfile.Options{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+
+		err = lockfile.WriteFile(fs, "file", []byte("data"), lockfile.Options{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+	})
+
+	t.Run("should never remove existing locks by default", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "file.lock", []byte("garbage"), 0o644))
+		old := time.Now().Add(-24 * time.Hour)
+		require.NoError(t, fs.Chtimes("file.lock", old, old))
+
+		_, err := lockfile.New(fs, "file", lockfile.Options{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+		var lockErr *lockfile.LockedError
+		require.True(t, errors.As(err, &lockErr), "unexpected error: %v", err)
+		assert.Equal(t, "file", lockErr.Path)
+		assert.GreaterOrEqual(t, int64(lockErr.Age), int64(24*time.Hour))
+
+		data, err := afero.ReadFile(fs, "file.lock")
+		require.NoError(t, err)
+		assert.Equal(t, "garbage", string(data), "the lock should not have been touched")
+	})
+
+	t.Run("should remove stale locks when asked to", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "file.lock", []byte("garbage"), 0o644))
+		old := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, fs.Chtimes("file.lock", old, old))
+
+		// The lock is not old enough
+		_, err := lockfile.New(fs, "file", lockfile.Options{StaleAge: 3 * time.Hour})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, lockfile.ErrLocked), "unexpected error: %v", err)
+
+		require.NoError(t, lockfile.WriteFile(fs, "file", []byte("data"), lockfile.Options{StaleAge: time.Hour}))
+		data, err := afero.ReadFile(fs, "file")
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	})
+
+	t.Run("should use the provided permissions", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, lockfile.WriteFile(fs, "file", []byte("data"), lockfile.Options{Perm: 0o444}))
+		info, err := fs.Stat("file")
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+	})
+
+	t.Run("IgnoreUmask should not restrict the permissions", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("Windows doesn't support unix permissions")
+		}
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		p := filepath.Join(dir, "file")
+		fs := afero.NewOsFs()
+		require.NoError(t, lockfile.WriteFile(fs, p, []byte("data"), lockfile.Options{
+			Perm:        0o666,
+			IgnoreUmask: true,
+		}))
+		info, err := fs.Stat(p)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o666), info.Mode().Perm())
+	})
+}
+
+// TestRollbackAll doesn't run in parallel, since it releases the
+// locks of the whole process
+func TestRollbackAll(t *testing.T) { //nolint:paralleltest // releases the locks of the other tests
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "file", []byte("old"), 0o644))
+
+	committed, err := lockfile.New(fs, "committed", lockfile.Options{})
+	require.NoError(t, err)
+	require.NoError(t, committed.Commit())
+	l1, err := lockfile.New(fs, "file", lockfile.Options{})
+	require.NoError(t, err)
+	_, err = l1.Write([]byte("new"))
+	require.NoError(t, err)
+	_, err = lockfile.New(fs, "other", lockfile.Options{})
+	require.NoError(t, err)
+
+	require.NoError(t, lockfile.RollbackAll())
+	for _, path := range []string{"file.lock", "other.lock"} {
+		_, err = fs.Stat(path)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "%s should have been removed", path)
+	}
+	data, err := afero.ReadFile(fs, "file")
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	_, err = fs.Stat("committed")
+	require.NoError(t, err, "committed locks should be left untouched")
+
+	// The locks should have been released
+	require.NoError(t, l1.Rollback())
+	require.NoError(t, lockfile.RollbackAll())
+}
@@ -0,0 +1,146 @@
+// Package mailmap contains methods to parse .mailmap files, used to
+// map the names and emails of the authors and committers to their
+// canonical version
+// https://git-scm.com/docs/gitmailmap
+package mailmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// identity represents the canonical name and email of a person.
+// Empty values mean that the original value should be kept
+type identity struct {
+	name  string
+	email string
+}
+
+// entry represents all the mappings of an email
+type entry struct {
+	identity
+	// byName contains the mappings that only apply when the name
+	// also matches. The keys are lowercase
+	byName map[string]identity
+}
+
+// Mailmap represents the content of one or more .mailmap files
+type Mailmap struct {
+	// entries contains the mappings by lowercase email
+	entries map[string]*entry
+}
+
+// New returns an empty Mailmap
+func New() *Mailmap {
+	return &Mailmap{
+		entries: map[string]*entry{},
+	}
+}
+
+// Parse reads a .mailmap file and adds its mappings to the Mailmap.
+// Mappings that already exist are overwritten.
+// The following formats are supported:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+//
+// Lines starting with "#" are comments, and invalid lines are ignored
+func (m *Mailmap) Parse(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name1, email1, rest, ok := parseNameAndEmail(line)
+		if !ok {
+			continue
+		}
+		name2, email2, _, ok := parseNameAndEmail(rest)
+		if !ok {
+			// Single "Name <email>" mapping: the email is the one
+			// found in the commits
+			m.add(name1, "", "", email1)
+			continue
+		}
+		m.add(name1, email1, name2, email2)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("could not read the mailmap: %w", err)
+	}
+	return nil
+}
+
+// parseNameAndEmail parses the "Name <email>" at the beginning of s,
+// and returns the remaining of the string. The name is optional
+func parseNameAndEmail(s string) (name, email, rest string, ok bool) {
+	start := strings.IndexByte(s, '<')
+	if start == -1 {
+		return "", "", "", false
+	}
+	end := strings.IndexByte(s[start:], '>')
+	if end == -1 {
+		return "", "", "", false
+	}
+	end += start
+	return strings.TrimSpace(s[:start]), s[start+1 : end], s[end+1:], true
+}
+
+// add adds a mapping. oldName can be empty if the mapping applies
+// to any names
+func (m *Mailmap) add(newName, newEmail, oldName, oldEmail string) {
+	key := strings.ToLower(oldEmail)
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{}
+		m.entries[key] = e
+	}
+
+	if oldName == "" {
+		if newName != "" {
+			e.name = newName
+		}
+		if newEmail != "" {
+			e.email = newEmail
+		}
+		return
+	}
+	if e.byName == nil {
+		e.byName = map[string]identity{}
+	}
+	e.byName[strings.ToLower(oldName)] = identity{
+		name:  newName,
+		email: newEmail,
+	}
+}
+
+// Lookup returns the canonical name and email of the provided
+// person. The provided values are returned if there are no mappings.
+// The lookup is case-insensitive
+func (m *Mailmap) Lookup(name, email string) (canonicalName, canonicalEmail string) {
+	e, ok := m.entries[strings.ToLower(email)]
+	if !ok {
+		return name, email
+	}
+
+	id := e.identity
+	if byName, ok := e.byName[strings.ToLower(name)]; ok {
+		id = byName
+	}
+	if id.name != "" {
+		name = id.name
+	}
+	if id.email != "" {
+		email = id.email
+	}
+	return name, email
+}
+
+// Len returns the number of emails that have a mapping
+func (m *Mailmap) Len() int {
+	return len(m.entries)
+}
@@ -0,0 +1,98 @@
+package mailmap_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/mailmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	m := mailmap.New()
+	err := m.Parse(strings.NewReader(strings.Join([]string{
+		"# comment <ignored@domain.tld>",
+		"Proper Name <commit@domain.tld>",
+		"<proper@domain.tld> <Old@Domain.tld>",
+		"Both <both@domain.tld> <old-both@domain.tld>",
+		"Other Name <other@domain.tld> Commit Name <shared@domain.tld>",
+		"invalid line",
+	}, "\n")))
+	require.NoError(t, err)
+	assert.Equal(t, 4, m.Len())
+
+	testCases := []struct {
+		desc          string
+		name          string
+		email         string
+		expectedName  string
+		expectedEmail string
+	}{
+		{
+			desc:          "name only",
+			name:          "Old Name",
+			email:         "commit@domain.tld",
+			expectedName:  "Proper Name",
+			expectedEmail: "commit@domain.tld",
+		},
+		{
+			desc:          "email only, case insensitive",
+			name:          "Old Name",
+			email:         "old@domain.tld",
+			expectedName:  "Old Name",
+			expectedEmail: "proper@domain.tld",
+		},
+		{
+			desc:          "name and email",
+			name:          "Old Name",
+			email:         "old-both@domain.tld",
+			expectedName:  "Both",
+			expectedEmail: "both@domain.tld",
+		},
+		{
+			desc:          "name and email matching the name",
+			name:          "commit name",
+			email:         "shared@domain.tld",
+			expectedName:  "Other Name",
+			expectedEmail: "other@domain.tld",
+		},
+		{
+			desc:          "name and email not matching the name",
+			name:          "Someone Else",
+			email:         "shared@domain.tld",
+			expectedName:  "Someone Else",
+			expectedEmail: "shared@domain.tld",
+		},
+		{
+			desc:          "unknown email",
+			name:          "Someone",
+			email:         "someone@domain.tld",
+			expectedName:  "Someone",
+			expectedEmail: "someone@domain.tld",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			name, email := m.Lookup(tc.name, tc.email)
+			assert.Equal(t, tc.expectedName, name)
+			assert.Equal(t, tc.expectedEmail, email)
+		})
+	}
+}
+
+func TestParseOverwrite(t *testing.T) {
+	t.Parallel()
+
+	m := mailmap.New()
+	require.NoError(t, m.Parse(strings.NewReader("First <commit@domain.tld>\n")))
+	require.NoError(t, m.Parse(strings.NewReader("Second <commit@domain.tld>\n")))
+	name, _ := m.Lookup("name", "commit@domain.tld")
+	assert.Equal(t, "Second", name)
+}
@@ -111,6 +111,16 @@ func NewSignatureFromBytes(b []byte) (Signature, error) {
 	return sig, nil
 }
 
+// ExtraHeader represents a header of a commit that doesn't have a
+// dedicated field, like "encoding", "mergetag", or a custom header
+type ExtraHeader struct {
+	Key string
+	// Value contains the value of the header. The lines of a
+	// multi-line value are separated by a \n, without the space
+	// git adds at the beginning of the continuation lines
+	Value string
+}
+
 // CommitOptions represents all the optional data available to create a commit
 type CommitOptions struct {
 	Message string
@@ -119,6 +129,27 @@ type CommitOptions struct {
 	// If not provided, the author will be used as committer
 	Committer Signature
 	ParentsID []ginternals.Oid
+	// ExtraHeaders contains the headers to write after the committer,
+	// in order. The signature is written after them
+	ExtraHeaders []ExtraHeader
+}
+
+// Fixup sets the message of the commit so it will be squashed into
+// target by an autosquash rebase, without changing the message of
+// target. This is the same as git commit --fixup
+func (opts *CommitOptions) Fixup(target *Commit) {
+	opts.Message = "fixup! " + target.Subject() + "\n"
+}
+
+// Squash sets the message of the commit so it will be squashed into
+// target by an autosquash rebase. The provided message (if any) will
+// be appended to the message of target during the rebase.
+// This is the same as git commit --squash
+func (opts *CommitOptions) Squash(target *Commit, message string) {
+	opts.Message = "squash! " + target.Subject() + "\n"
+	if message = strings.TrimSpace(message); message != "" {
+		opts.Message += "\n" + message + "\n"
+	}
 }
 
 // Commit represents a commit object
@@ -133,6 +164,8 @@ type Commit struct {
 
 	parentIDs []ginternals.Oid
 	treeID    ginternals.Oid
+
+	extraHeaders []ExtraHeader
 }
 
 // NewCommit creates a new Commit object
@@ -146,6 +179,10 @@ func NewCommit(treeID ginternals.Oid, author Signature, opts *CommitOptions) *Co
 		parentIDs: opts.ParentsID,
 		gpgSig:    opts.GPGSig,
 	}
+	if len(opts.ExtraHeaders) > 0 {
+		c.extraHeaders = make([]ExtraHeader, len(opts.ExtraHeaders))
+		copy(c.extraHeaders, opts.ExtraHeaders)
+	}
 
 	if c.committer.IsZero() {
 		c.committer = author
@@ -176,6 +213,13 @@ func NewCommit(treeID ginternals.Oid, author Signature, opts *CommitOptions) *Co
 //   A merge commit has 2 or more parents
 // - The gpgsig is optional
 func NewCommitFromObject(o *Object) (*Commit, error) {
+	return NewCommitFromObjectWithLimits(o, Limits{})
+}
+
+// NewCommitFromObjectWithLimits creates a commit from a raw object,
+// making sure the object doesn't exceed the given limits.
+// ErrLimitExceeded is returned if a limit is reached
+func NewCommitFromObjectWithLimits(o *Object, limits Limits) (*Commit, error) {
 	if o.typ != TypeCommit {
 		return nil, fmt.Errorf("type %s is not a commit: %w", o.typ, ErrObjectInvalid)
 	}
@@ -185,6 +229,7 @@ func NewCommitFromObject(o *Object) (*Commit, error) {
 	offset := 0
 	objData := o.Bytes()
 	for {
+		start := offset
 		line := readutil.ReadTo(objData[offset:], '\n')
 		offset += len(line) + 1 // +1 to count the \n
 
@@ -204,35 +249,54 @@ func NewCommitFromObject(o *Object) (*Commit, error) {
 
 		// Otherwise we're getting a key/value pair, separated by a space
 		kv := bytes.SplitN(line, []byte{' '}, 2)
+		// A malformed header may not have a value
+		var value []byte
+		if len(kv) == 2 {
+			value = kv[1]
+		}
 		var err error
 		switch string(kv[0]) {
 		case "tree":
-			ci.treeID, err = ginternals.NewOidFromChars(kv[1])
+			ci.treeID, err = ginternals.NewOidFromChars(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse tree id %#v: %w", kv[1], err)
+				return nil, fmt.Errorf("could not parse tree id %#v: %w", value, err)
 			}
 		case "parent":
-			oid, err := ginternals.NewOidFromChars(kv[1])
+			oid, err := ginternals.NewOidFromChars(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse parent id %#v: %w", kv[1], err)
+				return nil, fmt.Errorf("could not parse parent id %#v: %w", value, err)
 			}
 			ci.parentIDs = append(ci.parentIDs, oid)
 		case "author":
-			ci.author, err = NewSignatureFromBytes(kv[1])
+			ci.author, err = NewSignatureFromBytes(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse author signature [%s]: %w", string(kv[1]), err)
+				return nil, fmt.Errorf("could not parse author signature [%s]: %w", string(value), err)
 			}
 		case "committer":
-			ci.committer, err = NewSignatureFromBytes(kv[1])
+			ci.committer, err = NewSignatureFromBytes(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse committer signature [%s]: %w", string(kv[1]), err)
+				return nil, fmt.Errorf("could not parse committer signature [%s]: %w", string(value), err)
+			}
+		case signatureHeader:
+			ci.gpgSig, offset = readSignatureHeader(objData, offset, value)
+		default:
+			// The unknown headers are kept as is, so the commit can be
+			// re-created without changing its ID
+			h := ExtraHeader{
+				Key: string(kv[0]),
+			}
+			if len(kv) == 2 {
+				h.Value = string(kv[1])
+			}
+			for offset < len(objData) && objData[offset] == ' ' {
+				var continuation []byte
+				continuation, offset = nextLine(objData, offset)
+				h.Value += "\n" + string(continuation[1:])
 			}
-		case "gpgsig":
-			begin := string(kv[1]) + "\n"
-			end := "-----END PGP SIGNATURE-----"
-			i := bytes.Index(objData[offset:], []byte(end))
-			ci.gpgSig = begin + string(objData[offset:offset+i]) + end
-			offset += len(end) + i + 1 // +1 to count the \n
+			ci.extraHeaders = append(ci.extraHeaders, h)
+		}
+		if err := limits.checkHeader(offset-start, offset); err != nil {
+			return nil, fmt.Errorf("invalid header at offset %d: %w", start, err)
 		}
 	}
 
@@ -267,6 +331,21 @@ func (c *Commit) Message() string {
 	return c.message
 }
 
+// Subject returns the subject of the commit, which is the first
+// paragraph of its message on a single line
+func (c *Commit) Subject() string {
+	lines := strings.Split(strings.TrimLeft(c.message, "\n"), "\n")
+	subject := []string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		subject = append(subject, line)
+	}
+	return strings.Join(subject, " ")
+}
+
 // ParentIDs returns the list of SHA of the parent commits (if any)
 // - The first commit of an orphan branch has 0 parents
 // - A regular commit or the result of a fast-forward merge has 1 parent
@@ -287,6 +366,27 @@ func (c *Commit) GPGSig() string {
 	return c.gpgSig
 }
 
+// ExtraHeaders returns, in order, the headers of the commit that
+// don't have a dedicated method, like "encoding" or "mergetag".
+// Giving them to NewCommit() allows a parsed commit to be re-created
+// without changing its ID
+func (c *Commit) ExtraHeaders() []ExtraHeader {
+	out := make([]ExtraHeader, len(c.extraHeaders))
+	copy(out, c.extraHeaders)
+	return out
+}
+
+// SignedPayload returns the exact bytes covered by the signature of
+// the commit, which is the raw content of the commit without its
+// gpgsig headers. This is what needs to be given to a verifier,
+// along with GPGSig().
+// The raw content of the commit is returned if the commit is not
+// signed
+func (c *Commit) SignedPayload() []byte {
+	payload, _ := removeSignatureHeaders(c.ToObject().Bytes())
+	return payload
+}
+
 // ToObject returns the underlying Object
 func (c *Commit) ToObject() *Object {
 	if c.rawObject != nil {
@@ -296,13 +396,14 @@ func (c *Commit) ToObject() *Object {
 	// Quick reminder that the Write* methods on bytes.Buffer never fails,
 	// the error returned is always nil
 	buf := new(bytes.Buffer)
+	var oid [ginternals.OidHexSize]byte
 	buf.WriteString("tree ")
-	buf.WriteString(c.treeID.String())
+	buf.Write(c.treeID.AppendHex(oid[:0]))
 	buf.WriteByte('\n')
 
 	for _, p := range c.parentIDs {
 		buf.WriteString("parent ")
-		buf.WriteString(p.String())
+		buf.Write(p.AppendHex(oid[:0]))
 		buf.WriteByte('\n')
 	}
 
@@ -314,6 +415,16 @@ func (c *Commit) ToObject() *Object {
 	buf.WriteString(c.Committer().String())
 	buf.WriteByte('\n')
 
+	for _, h := range c.extraHeaders {
+		buf.WriteString(h.Key)
+		if h.Value != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(strings.ReplaceAll(h.Value, "\n", "\n "))
+		}
+		buf.WriteByte('\n')
+	}
+
+	// Like git, the signature is written after all the other headers
 	if c.gpgSig != "" {
 		buf.WriteString("gpgsig ")
 		buf.WriteString(c.gpgSig)
@@ -464,3 +464,196 @@ func TestNewCommitFromObject(t *testing.T) {
 		}
 	})
 }
+
+func TestCommitSubject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc            string
+		message         string
+		expectedSubject string
+	}{
+		{
+			desc:            "single line",
+			message:         "feat: add A\n",
+			expectedSubject: "feat: add A",
+		},
+		{
+			desc:            "multiple paragraphs",
+			message:         "\nfeat: add B\n  second line\n\nbody\n",
+			expectedSubject: "feat: add B second line",
+		},
+		{
+			desc:            "empty message",
+			message:         "",
+			expectedSubject: "",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			ci := object.NewCommit(ginternals.NullOid, object.NewSignature("author", "email"), &object.CommitOptions{
+				Message: tc.message,
+			})
+			assert.Equal(t, tc.expectedSubject, ci.Subject())
+		})
+	}
+}
+
+func TestCommitOptionsFixup(t *testing.T) {
+	t.Parallel()
+
+	target := object.NewCommit(ginternals.NullOid, object.NewSignature("author", "email"), &object.CommitOptions{
+		Message: "feat: add B\nsecond line\n\nbody\n",
+	})
+
+	t.Run("Fixup", func(t *testing.T) {
+		t.Parallel()
+
+		opts := &object.CommitOptions{}
+		opts.Fixup(target)
+		assert.Equal(t, "fixup! feat: add B second line\n", opts.Message)
+	})
+
+	t.Run("Squash without message", func(t *testing.T) {
+		t.Parallel()
+
+		opts := &object.CommitOptions{}
+		opts.Squash(target, "")
+		assert.Equal(t, "squash! feat: add B second line\n", opts.Message)
+	})
+
+	t.Run("Squash with message", func(t *testing.T) {
+		t.Parallel()
+
+		opts := &object.CommitOptions{}
+		opts.Squash(target, "extra details\n")
+		assert.Equal(t, "squash! feat: add B second line\n\nextra details\n", opts.Message)
+	})
+}
+
+func TestCommitSignedPayload(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc            string
+		raw             string
+		expectedPayload string
+		expectedSig     string
+	}{
+		{
+			desc: "ssh signature",
+			raw: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"gpgsig -----BEGIN SSH SIGNATURE-----\n" +
+				" U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAghr9G8cXVTi1dBT7w4Pw4QzLCnh\n" +
+				" 7NWikRb1to+oaqmcjuZQc=\n" +
+				" -----END SSH SIGNATURE-----\n" +
+				"\n" +
+				"signed msg\n",
+			expectedPayload: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"\n" +
+				"signed msg\n",
+			expectedSig: "-----BEGIN SSH SIGNATURE-----\n" +
+				" U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAghr9G8cXVTi1dBT7w4Pw4QzLCnh\n" +
+				" 7NWikRb1to+oaqmcjuZQc=\n" +
+				" -----END SSH SIGNATURE-----",
+		},
+		{
+			desc: "x509 signature without end marker, and sha256 signature",
+			raw: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"gpgsig -----BEGIN SIGNED MESSAGE-----\n" +
+				" MIIEBgYJKoZIhvcNAQcCoIID9zCCA/MCAQExDTALBglghkgBZQMEAgEwCwYJKoZI\n" +
+				"gpgsig-sha256 -----BEGIN PGP SIGNATURE-----\n" +
+				" iQIzBAABCAAdFiEE9vjmBp5ZMl+LWBekLDB+DQQTNEsFAl1ZCE0ACgkQLDB+DQQT\n" +
+				" -----END PGP SIGNATURE-----\n" +
+				"\n" +
+				"signed msg\n",
+			expectedPayload: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"\n" +
+				"signed msg\n",
+			expectedSig: "-----BEGIN SIGNED MESSAGE-----\n" +
+				" MIIEBgYJKoZIhvcNAQcCoIID9zCCA/MCAQExDTALBglghkgBZQMEAgEwCwYJKoZI",
+		},
+		{
+			desc: "unsigned commit",
+			raw: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"\n" +
+				"msg\n",
+			expectedPayload: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+				"author a <a@b.c> 1792191921 +0000\n" +
+				"committer a <a@b.c> 1792191921 +0000\n" +
+				"\n" +
+				"msg\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ci, err := object.New(object.TypeCommit, []byte(tc.raw)).AsCommit()
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, tc.expectedPayload, string(ci.SignedPayload()), "test %d", i)
+			assert.Equal(t, tc.expectedSig, ci.GPGSig(), "test %d", i)
+		})
+	}
+}
+
+func TestCommitExtraHeaders(t *testing.T) {
+	t.Parallel()
+
+	raw := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"parent bbb720a96e4c29b9950a4c577c98470a4d5dd089\n" +
+		"parent 6097a04b7a327c4be68f222ca66e61b8e1abe5c1\n" +
+		"author a <a@b.c> 1792191921 +0000\n" +
+		"committer a <a@b.c> 1792191921 +0000\n" +
+		"encoding ISO-8859-1\n" +
+		"mergetag object 6097a04b7a327c4be68f222ca66e61b8e1abe5c1\n" +
+		" type commit\n" +
+		" tag v1\n" +
+		" tagger a <a@b.c> 1792191921 +0000\n" +
+		" \n" +
+		" tag msg\n" +
+		"x-custom value\n" +
+		"gpgsig -----BEGIN SSH SIGNATURE-----\n" +
+		" U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAghr9G8cXVTi1dBT7w4Pw4QzLCnh\n" +
+		" -----END SSH SIGNATURE-----\n" +
+		"\n" +
+		"merge msg\n"
+	o := object.New(object.TypeCommit, []byte(raw))
+	ci, err := o.AsCommit()
+	require.NoError(t, err)
+
+	assert.Equal(t, []object.ExtraHeader{
+		{Key: "encoding", Value: "ISO-8859-1"},
+		{Key: "mergetag", Value: "object 6097a04b7a327c4be68f222ca66e61b8e1abe5c1\ntype commit\ntag v1\ntagger a <a@b.c> 1792191921 +0000\n\ntag msg"},
+		{Key: "x-custom", Value: "value"},
+	}, ci.ExtraHeaders())
+	assert.Equal(t, "merge msg\n", ci.Message())
+	assert.Len(t, ci.ParentIDs(), 2)
+
+	// We re-create the commit from scratch, which should give us the
+	// exact same object
+	ci2 := object.NewCommit(ci.TreeID(), ci.Author(), &object.CommitOptions{
+		Message:      ci.Message(),
+		GPGSig:       ci.GPGSig(),
+		Committer:    ci.Committer(),
+		ParentsID:    ci.ParentIDs(),
+		ExtraHeaders: ci.ExtraHeaders(),
+	})
+	assert.Equal(t, raw, string(ci2.ToObject().Bytes()))
+	assert.Equal(t, o.ID(), ci2.ID())
+}
@@ -0,0 +1,70 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is an error thrown when parsing data that exceed
+// one of the Limits
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits represents the limits enforced when parsing data that may
+// come from an untrusted source, like the packfiles received from a
+// client. They protect against data crafted to exhaust the resources
+// of the process.
+// A limit set to 0 means no limits
+type Limits struct {
+	// MaxHeaderLength is the maximum length of a single header of a
+	// commit or a tag, continuation lines included
+	MaxHeaderLength int
+	// MaxHeadersSize is the maximum size of all the headers of a
+	// commit or a tag, which is everything but the message
+	MaxHeadersSize int
+	// MaxTreeEntries is the maximum number of entries a tree can
+	// contain
+	MaxTreeEntries int
+	// MaxDeltaDepth is the maximum length of a delta chain in a
+	// packfile. The packfiles always refuse chains longer than 10000
+	// to protect against cycles
+	MaxDeltaDepth int
+	// MaxObjectSize is the maximum size of an object that can be
+	// loaded in memory, in bytes
+	MaxObjectSize uint64
+}
+
+// UntrustedLimits returns limits that are high enough to not be
+// reached by regular repositories, but low enough to safely parse
+// data coming from an untrusted source
+func UntrustedLimits() Limits {
+	return Limits{
+		MaxHeaderLength: 64 * 1024,
+		MaxHeadersSize:  1024 * 1024,
+		MaxTreeEntries:  100_000,
+		// Same as the maximum value of git's pack.depth
+		MaxDeltaDepth: 4095,
+		// Same as git's core.bigFileThreshold
+		MaxObjectSize: 512 * 1024 * 1024,
+	}
+}
+
+// CheckObjectSize returns ErrLimitExceeded if an object of the given
+// size cannot be loaded in memory
+func (l Limits) CheckObjectSize(size uint64) error {
+	if l.MaxObjectSize > 0 && size > l.MaxObjectSize {
+		return fmt.Errorf("object of %d bytes is bigger than the maximum of %d bytes: %w", size, l.MaxObjectSize, ErrLimitExceeded)
+	}
+	return nil
+}
+
+// checkHeader returns ErrLimitExceeded if a header of the given
+// length cannot be parsed, or if the headers parsed so far are too big
+func (l Limits) checkHeader(length, totalSize int) error {
+	if l.MaxHeaderLength > 0 && length > l.MaxHeaderLength {
+		return fmt.Errorf("header of %d bytes is longer than the maximum of %d bytes: %w", length, l.MaxHeaderLength, ErrLimitExceeded)
+	}
+	if l.MaxHeadersSize > 0 && totalSize > l.MaxHeadersSize {
+		return fmt.Errorf("headers are bigger than the maximum of %d bytes: %w", l.MaxHeadersSize, ErrLimitExceeded)
+	}
+	return nil
+}
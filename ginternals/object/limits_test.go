@@ -0,0 +1,109 @@
+package object_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsCheckObjectSize(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, object.Limits{}.CheckObjectSize(1<<40), "no limits should accept anything")
+
+	limits := object.Limits{MaxObjectSize: 10}
+	require.NoError(t, limits.CheckObjectSize(10))
+	require.ErrorIs(t, limits.CheckObjectSize(11), object.ErrLimitExceeded)
+}
+
+func TestUntrustedLimits(t *testing.T) {
+	t.Parallel()
+
+	limits := object.UntrustedLimits()
+	assert.NotZero(t, limits.MaxHeaderLength)
+	assert.NotZero(t, limits.MaxHeadersSize)
+	assert.NotZero(t, limits.MaxTreeEntries)
+	assert.NotZero(t, limits.MaxDeltaDepth)
+	assert.NotZero(t, limits.MaxObjectSize)
+}
+
+func TestParsingWithLimits(t *testing.T) {
+	t.Parallel()
+
+	commit := "tree 0343d67ca3d80a531d0d163f0078a81c95c9085a\n" +
+		"author John Doe <john@domain.tld> 1566115917 -0700\n" +
+		"committer John Doe <john@domain.tld> 1566115917 -0700\n" +
+		"x-header " + strings.Repeat("a", 100) + "\n" +
+		" continuation " + strings.Repeat("b", 100) + "\n" +
+		"\n" +
+		"message " + strings.Repeat("c", 1000) + "\n"
+	tag := "object 0343d67ca3d80a531d0d163f0078a81c95c9085a\n" +
+		"type commit\n" +
+		"tag " + strings.Repeat("a", 200) + "\n" +
+		"tagger John Doe <john@domain.tld> 1566115917 -0700\n" +
+		"\n" +
+		"message " + strings.Repeat("c", 1000) + "\n"
+
+	t.Run("commits", func(t *testing.T) {
+		t.Parallel()
+
+		o := object.New(object.TypeCommit, []byte(commit))
+		testCases := []struct {
+			desc        string
+			limits      object.Limits
+			expectError bool
+		}{
+			{desc: "no limits"},
+			{desc: "limits not reached", limits: object.UntrustedLimits()},
+			{desc: "continuation lines count in the header length", limits: object.Limits{MaxHeaderLength: 200}, expectError: true},
+			{desc: "the message doesn't count in the headers size", limits: object.Limits{MaxHeadersSize: 500}},
+			{desc: "headers too big", limits: object.Limits{MaxHeadersSize: 300}, expectError: true},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(tc.desc, func(t *testing.T) {
+				t.Parallel()
+
+				c, err := object.NewCommitFromObjectWithLimits(o, tc.limits)
+				if tc.expectError {
+					require.ErrorIs(t, err, object.ErrLimitExceeded, "test %d", i)
+					return
+				}
+				require.NoError(t, err, "test %d", i)
+				assert.Equal(t, o.ID(), c.ID(), "test %d", i)
+			})
+		}
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		t.Parallel()
+
+		o := object.New(object.TypeTag, []byte(tag))
+		_, err := object.NewTagFromObjectWithLimits(o, object.UntrustedLimits())
+		require.NoError(t, err)
+		_, err = object.NewTagFromObjectWithLimits(o, object.Limits{MaxHeaderLength: 100})
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+		_, err = object.NewTagFromObjectWithLimits(o, object.Limits{MaxHeadersSize: 200})
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+	})
+
+	t.Run("trees", func(t *testing.T) {
+		t.Parallel()
+
+		blob := object.New(object.TypeBlob, []byte("content"))
+		tree := object.NewTree([]object.TreeEntry{
+			{Path: "a", ID: blob.ID(), Mode: object.ModeFile},
+			{Path: "b", ID: blob.ID(), Mode: object.ModeFile},
+			{Path: "c", ID: blob.ID(), Mode: object.ModeFile},
+		})
+		o := tree.ToObject()
+		_, err := object.NewTreeFromObjectWithLimits(o, object.Limits{MaxTreeEntries: 3})
+		require.NoError(t, err)
+		_, err = object.NewTreeFromObjectWithLimits(o, object.Limits{MaxTreeEntries: 2})
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+	})
+}
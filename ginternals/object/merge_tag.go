@@ -0,0 +1,48 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeTagHeader is the header containing the tags merged by a commit
+const mergeTagHeader = "mergetag"
+
+// MergeTag represents a tag embedded in a merge commit.
+// When a signed tag is merged, git copies the tag object in a mergetag
+// header of the merge commit, so the signature of the tag can still be
+// verified later on, even if the tag has been deleted.
+// The embedded tag is the exact same object as the original tag, and
+// therefore has the same ID
+type MergeTag struct {
+	*Tag
+}
+
+// NewMergeTagHeader returns the mergetag header to add to a merge
+// commit that merges the given tag
+func NewMergeTagHeader(t *Tag) ExtraHeader {
+	return ExtraHeader{
+		Key: mergeTagHeader,
+		// The tag object ends with a \n, which is the \n that ends
+		// the header
+		Value: strings.TrimSuffix(string(t.ToObject().Bytes()), "\n"),
+	}
+}
+
+// MergeTags returns, in order, the tags embedded in the mergetag
+// headers of the commit. Those headers are also part of
+// ExtraHeaders(), so they are kept when re-creating the commit
+func (c *Commit) MergeTags() ([]*MergeTag, error) {
+	tags := []*MergeTag{}
+	for i, h := range c.extraHeaders {
+		if h.Key != mergeTagHeader {
+			continue
+		}
+		t, err := NewTagFromObject(New(TypeTag, []byte(h.Value+"\n")))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse header %d: %w", i, err)
+		}
+		tags = append(tags, &MergeTag{Tag: t})
+	}
+	return tags, nil
+}
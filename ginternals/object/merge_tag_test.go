@@ -0,0 +1,99 @@
+package object_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mergeTagCommit is a merge commit created by git when merging the
+// signed tag 0259ba91cc4bcd180aed2bb00f58644782f42fbb
+const mergeTagCommit = "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+	"parent 2565eeb1c8d4b3801a1835f33c3159cad0cd62dc\n" +
+	"parent 386957b7b5dc3421c98d92ad8ac14d2822bb1c75\n" +
+	"author a <a@b.c> 1600000000 +0000\n" +
+	"committer a <a@b.c> 1600000000 +0000\n" +
+	"mergetag object 386957b7b5dc3421c98d92ad8ac14d2822bb1c75\n" +
+	" type commit\n" +
+	" tag v2\n" +
+	" tagger a <a@b.c> 1600000000 +0000\n" +
+	" \n" +
+	" tag msg\n" +
+	" -----BEGIN SSH SIGNATURE-----\n" +
+	" U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAg5+DsvEsMvaY+AZd+9HsDMdu7P5\n" +
+	" 6PhttmJ9TYxfvYe1oAAAADZ2l0AAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1lZDI1NTE5\n" +
+	" AAAAQHIwTAojM/URyIVS0HsVot8TByyc3BZjkOzJppF17KOEfzegjVyIkd34zCHz/kglfV\n" +
+	" iXvocE4Hlgb2hrNYRUkQk=\n" +
+	" -----END SSH SIGNATURE-----\n" +
+	"\n" +
+	"merge\n"
+
+func TestCommitMergeTags(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse the embedded tags", func(t *testing.T) {
+		t.Parallel()
+
+		o := object.New(object.TypeCommit, []byte(mergeTagCommit))
+		require.Equal(t, "51a15a94e8f9afa21dd466bd0295792d7fea34ce", o.ID().String())
+		ci, err := o.AsCommit()
+		require.NoError(t, err)
+		assert.Equal(t, "merge\n", ci.Message())
+
+		tags, err := ci.MergeTags()
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		tag := tags[0]
+		assert.Equal(t, "0259ba91cc4bcd180aed2bb00f58644782f42fbb", tag.ID().String())
+		assert.Equal(t, "386957b7b5dc3421c98d92ad8ac14d2822bb1c75", tag.Target().String())
+		assert.Equal(t, object.TypeCommit, tag.Type())
+		assert.Equal(t, "v2", tag.Name())
+		assert.Equal(t, "a", tag.Tagger().Name)
+		assert.True(t, strings.HasPrefix(tag.GPGSig(), "-----BEGIN SSH SIGNATURE-----\n"), "unexpected signature %q", tag.GPGSig())
+		assert.Equal(t, "object 386957b7b5dc3421c98d92ad8ac14d2822bb1c75\ntype commit\ntag v2\ntagger a <a@b.c> 1600000000 +0000\n\ntag msg\n", string(tag.SignedPayload()))
+	})
+
+	t.Run("should re-create the same commit", func(t *testing.T) {
+		t.Parallel()
+
+		o := object.New(object.TypeCommit, []byte(mergeTagCommit))
+		ci, err := o.AsCommit()
+		require.NoError(t, err)
+		tags, err := ci.MergeTags()
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+
+		ci2 := object.NewCommit(ci.TreeID(), ci.Author(), &object.CommitOptions{
+			Message:      ci.Message(),
+			ParentsID:    ci.ParentIDs(),
+			ExtraHeaders: []object.ExtraHeader{object.NewMergeTagHeader(tags[0].Tag)},
+		})
+		assert.Equal(t, o.ID(), ci2.ID())
+	})
+
+	t.Run("should return nothing if there are no mergetag", func(t *testing.T) {
+		t.Parallel()
+
+		raw := strings.Replace(mergeTagCommit, "mergetag ", "x-mergetag ", 1)
+		ci, err := object.New(object.TypeCommit, []byte(raw)).AsCommit()
+		require.NoError(t, err)
+
+		tags, err := ci.MergeTags()
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("should fail on invalid mergetag", func(t *testing.T) {
+		t.Parallel()
+
+		raw := strings.Replace(mergeTagCommit, " type commit\n", " type nope\n", 1)
+		ci, err := object.New(object.TypeCommit, []byte(raw)).AsCommit()
+		require.NoError(t, err)
+
+		_, err = ci.MergeTags()
+		require.Error(t, err)
+	})
+}
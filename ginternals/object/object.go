@@ -10,7 +10,6 @@ import (
 	"sync"
 
 	"github.com/Nivl/git-go/ginternals"
-	"github.com/Nivl/git-go/internal/errutil"
 )
 
 var (
@@ -29,6 +28,11 @@ var (
 	// tree object
 	ErrTreeInvalid = errors.New("invalid tree")
 
+	// ErrTreeModeInvalid represents an error thrown when an entry of a
+	// tree has a malformed or an unsupported mode.
+	// It wraps ErrTreeInvalid
+	ErrTreeModeInvalid = fmt.Errorf("invalid mode: %w", ErrTreeInvalid)
+
 	// ErrCommitInvalid represents an error thrown when parsing an invalid
 	// commit object
 	ErrCommitInvalid = errors.New("invalid commit")
@@ -183,9 +187,13 @@ func (o *Object) Compress() (data []byte, err error) {
 
 	compressedContent := new(bytes.Buffer)
 	zw := zlib.NewWriter(compressedContent)
-	defer errutil.Close(zw, &err)
-
 	if _, err = zw.Write(fileContent); err != nil {
+		zw.Close() //nolint:errcheck // it already failed
+		return nil, fmt.Errorf("could not zlib the object: %w", err)
+	}
+	// The writer needs to be closed before reading the buffer, since
+	// it only flushes the end of the compressed data on close
+	if err = zw.Close(); err != nil {
 		return nil, fmt.Errorf("could not zlib the object: %w", err)
 	}
 	return compressedContent.Bytes(), nil
@@ -0,0 +1,71 @@
+//go:build go1.18
+// +build go1.18
+
+package object_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+func FuzzNewSignatureFromBytes(f *testing.F) {
+	f.Add([]byte("John Doe <john@domain.tld> 1566115917 -0700"))
+	f.Add([]byte("<> 0 +0000"))
+	f.Add([]byte("John Doe <john@domain.tld>"))
+	f.Add([]byte("John Doe <john@domain.tld> 1566115917"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sig, err := object.NewSignatureFromBytes(data)
+		if err != nil {
+			return
+		}
+		_ = sig.String()
+	})
+}
+
+func FuzzNewCommitFromObject(f *testing.F) {
+	f.Add([]byte("tree 0343d67ca3d80a531d0d163f0078a81c95c9085a\nparent 9785af758bcc96cd7f13a9b6ab4c6ac2f16ab3f5\nauthor John Doe <john@domain.tld> 1566115917 -0700\ncommitter John Doe <john@domain.tld> 1566115917 -0700\n\nmessage\n"))
+	f.Add([]byte("tree 0343d67ca3d80a531d0d163f0078a81c95c9085a\nauthor John Doe <john@domain.tld> 1566115917 -0700\ncommitter John Doe <john@domain.tld> 1566115917 -0700\nencoding ISO-8859-1\ngpgsig -----BEGIN PGP SIGNATURE-----\n \n abcd\n -----END PGP SIGNATURE-----\n\nmessage\n"))
+	f.Add([]byte("tree 0343d67ca3d80a531d0d163f0078a81c95c9085a\nauthor a <a> 0 +0000\ncommitter a <a> 0 +0000\nmergetag object 9785af758bcc96cd7f13a9b6ab4c6ac2f16ab3f5\n type commit\n tag v1\n tagger a <a> 0 +0000\n \n message\n\nmessage\n"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := object.New(object.TypeCommit, data).AsCommit()
+		if err != nil {
+			return
+		}
+		_ = c.ToObject()
+		_ = c.SignedPayload()
+		_, _ = c.MergeTags()
+	})
+}
+
+func FuzzNewTagFromObject(f *testing.F) {
+	f.Add([]byte("object 9785af758bcc96cd7f13a9b6ab4c6ac2f16ab3f5\ntype commit\ntag v1\ntagger John Doe <john@domain.tld> 1566115917 -0700\n\nmessage\n"))
+	f.Add([]byte("object 9785af758bcc96cd7f13a9b6ab4c6ac2f16ab3f5\ntype commit\ntag v1\ntagger John Doe <john@domain.tld> 1566115917 -0700\n\nmessage\n-----BEGIN PGP SIGNATURE-----\n\nabcd\n-----END PGP SIGNATURE-----\n"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tag, err := object.New(object.TypeTag, data).AsTag()
+		if err != nil {
+			return
+		}
+		_ = tag.ToObject()
+		_ = tag.SignedPayload()
+	})
+}
+
+func FuzzNewTreeFromObject(f *testing.F) {
+	f.Add([]byte("100644 file\x00\x03\x43\xd6\x7c\xa3\xd8\x0a\x53\x1d\x0d\x16\x3f\x00\x78\xa8\x1c\x95\xc9\x08\x5a40000 dir\x00\x97\x85\xaf\x75\x8b\xcc\x96\xcd\x7f\x13\xa9\xb6\xab\x4c\x6a\xc2\xf1\x6a\xb3\xf5"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tree, err := object.New(object.TypeTree, data).AsTree()
+		if err != nil {
+			return
+		}
+		_ = tree.ToObject()
+	})
+}
@@ -2,7 +2,9 @@ package object_test
 
 import (
 	"bytes"
+	"compress/zlib"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -335,11 +337,17 @@ func TestCompress(t *testing.T) {
 		require.NoError(t, err)
 
 		o := object.New(object.TypeTree, content)
-		_, err = o.Compress()
+		compressed, err := o.Compress()
 		require.NoError(t, err)
 		assert.Equal(t, treeSHA, o.ID().String())
 
-		// TODO(melvin): Test the compressed object
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.NoError(t, zr.Close())
+		expected := append([]byte(fmt.Sprintf("tree %d\x00", len(content))), content...)
+		assert.Equal(t, expected, data)
 	})
 }
 
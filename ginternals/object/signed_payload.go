@@ -0,0 +1,115 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// List of the headers that contain the signature of an object
+const (
+	signatureHeader       = "gpgsig"
+	signatureSHA256Header = "gpgsig-sha256"
+)
+
+// signatureBeginMarkers contains the first line of the signatures
+// supported by git (gpg, x509, and ssh)
+//
+//nolint:gochecknoglobals // Treat it as a const
+var signatureBeginMarkers = []string{
+	"-----BEGIN PGP SIGNATURE-----",
+	"-----BEGIN PGP MESSAGE-----",
+	"-----BEGIN SIGNED MESSAGE-----",
+	"-----BEGIN SSH SIGNATURE-----",
+}
+
+// isSignatureHeader returns whether the given header contains the
+// signature of the object
+func isSignatureHeader(key []byte) bool {
+	return string(key) == signatureHeader || string(key) == signatureSHA256Header
+}
+
+// readSignatureHeader reads the value of a signature header which
+// first line has already been read, and returns it with the offset
+// of the next header.
+// offset is expected to be the offset of the line following the first
+// line of the header.
+// The value ends at the end marker matching its begin marker
+// (-----END PGP SIGNATURE----- for -----BEGIN PGP SIGNATURE-----).
+// If there's no such marker, the value is made of the following
+// lines that start with a space, which is how git stores multi-line
+// headers
+func readSignatureHeader(objData []byte, offset int, firstLine []byte) (value string, next int) {
+	begin := string(firstLine)
+	if strings.HasPrefix(begin, "-----BEGIN ") {
+		end := "-----END " + strings.TrimPrefix(begin, "-----BEGIN ")
+		if i := bytes.Index(objData[offset:], []byte(end)); i >= 0 {
+			next = offset + i + len(end) + 1 // +1 to count the \n
+			if next > len(objData) {
+				next = len(objData)
+			}
+			return begin + "\n" + string(objData[offset:offset+i]) + end, next
+		}
+	}
+
+	value = begin
+	for offset < len(objData) && objData[offset] == ' ' {
+		var line []byte
+		line, offset = nextLine(objData, offset)
+		value += "\n" + string(line)
+	}
+	return value, offset
+}
+
+// nextLine returns the line starting at the given offset, without
+// its \n, and the offset of the following line
+func nextLine(data []byte, offset int) (line []byte, next int) {
+	i := bytes.IndexByte(data[offset:], '\n')
+	if i < 0 {
+		return data[offset:], len(data)
+	}
+	return data[offset : offset+i], offset + i + 1
+}
+
+// removeSignatureHeaders returns the given object data without its
+// signature headers, which is what the signature covers
+func removeSignatureHeaders(objData []byte) (payload []byte, found bool) {
+	payload = make([]byte, 0, len(objData))
+	offset := 0
+	for offset < len(objData) {
+		line, lineEnd := nextLine(objData, offset)
+
+		// The headers are over, everything left is the message
+		if len(line) == 0 {
+			return append(payload, objData[offset:]...), found
+		}
+
+		kv := bytes.SplitN(line, []byte{' '}, 2)
+		if len(kv) == 2 && isSignatureHeader(kv[0]) {
+			found = true
+			_, offset = readSignatureHeader(objData, lineEnd, kv[1])
+			continue
+		}
+		payload = append(payload, objData[offset:lineEnd]...)
+		offset = lineEnd
+	}
+	return payload, found
+}
+
+// inlineSignatureStart returns the offset of the signature contained
+// at the end of the given message, or -1 if the message is not
+// signed.
+// This is how git stores the signature of the tags
+func inlineSignatureStart(message []byte) int {
+	start := -1
+	for offset := 0; offset < len(message); {
+		line, next := nextLine(message, offset)
+		for _, marker := range signatureBeginMarkers {
+			if string(line) == marker {
+				start = offset
+				break
+			}
+		}
+		offset = next
+	}
+	return start
+}
@@ -63,6 +63,13 @@ func NewTag(p *TagParams) *Tag {
 // Note:
 // - The gpgsig is optional
 func NewTagFromObject(o *Object) (*Tag, error) {
+	return NewTagFromObjectWithLimits(o, Limits{})
+}
+
+// NewTagFromObjectWithLimits creates a tag from a raw object, making
+// sure the object doesn't exceed the given limits.
+// ErrLimitExceeded is returned if a limit is reached
+func NewTagFromObjectWithLimits(o *Object, limits Limits) (*Tag, error) {
 	if o.typ != TypeTag {
 		return nil, fmt.Errorf("type %s is not a tag: %w", o.typ, ErrObjectInvalid)
 	}
@@ -74,6 +81,7 @@ func NewTagFromObject(o *Object) (*Tag, error) {
 	objData := o.Bytes()
 	var err error
 	for {
+		start := offset
 		line := readutil.ReadTo(objData[offset:], '\n')
 		offset += len(line) + 1 // +1 to count the \n
 
@@ -93,30 +101,42 @@ func NewTagFromObject(o *Object) (*Tag, error) {
 
 		// Otherwise we're getting a key/value pair, separated by a space
 		kv := bytes.SplitN(line, []byte{' '}, 2)
+		// A malformed header may not have a value
+		var value []byte
+		if len(kv) == 2 {
+			value = kv[1]
+		}
 		switch string(kv[0]) {
 		case "object":
-			tag.target, err = ginternals.NewOidFromChars(kv[1])
+			tag.target, err = ginternals.NewOidFromChars(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse target id %#v: %w", kv[1], err)
+				return nil, fmt.Errorf("could not parse target id %#v: %w", value, err)
 			}
 		case "type":
-			tag.typ, err = NewTypeFromString(string(kv[1]))
+			tag.typ, err = NewTypeFromString(string(value))
 			if err != nil {
-				return nil, fmt.Errorf("invalid object type %s: %w", string(kv[1]), err)
+				return nil, fmt.Errorf("invalid object type %s: %w", string(value), err)
 			}
 		case "tagger":
-			tag.tagger, err = NewSignatureFromBytes(kv[1])
+			tag.tagger, err = NewSignatureFromBytes(value)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse tagger [%s]: %w", string(kv[1]), err)
+				return nil, fmt.Errorf("could not parse tagger [%s]: %w", string(value), err)
 			}
 		case "tag":
-			tag.tag = string(kv[1])
-		case "gpgsig":
-			begin := string(kv[1]) + "\n"
-			end := "-----END PGP SIGNATURE-----"
-			i := bytes.Index(objData[offset:], []byte(end))
-			tag.gpgSig = begin + string(objData[offset:offset+i]) + end
-			offset += len(end) + i + 1 // +1 to count the \n
+			tag.tag = string(value)
+		case signatureHeader:
+			tag.gpgSig, offset = readSignatureHeader(objData, offset, value)
+		}
+		if err = limits.checkHeader(offset-start, offset); err != nil {
+			return nil, fmt.Errorf("invalid header at offset %d: %w", start, err)
+		}
+	}
+
+	// git stores the signature of a tag at the end of its message
+	// instead of in a header
+	if tag.gpgSig == "" {
+		if i := inlineSignatureStart([]byte(tag.message)); i >= 0 {
+			tag.gpgSig = tag.message[i:]
 		}
 	}
 
@@ -164,11 +184,40 @@ func (t *Tag) Message() string {
 	return t.message
 }
 
-// GPGSig returns the GPG signature of the tag, if any
+// GPGSig returns the GPG signature of the tag, if any.
+// The signature can either come from the gpgsig header, or from
+// the end of the message, which is where git puts it. In the latter
+// case, the signature is also part of Message()
 func (t *Tag) GPGSig() string {
 	return t.gpgSig
 }
 
+// SignedPayload returns the exact bytes covered by the signature of
+// the tag. This is what needs to be given to a verifier, along with
+// GPGSig():
+//   - If the tag is signed using a gpgsig header, the payload is the
+//     raw content of the tag without its gpgsig headers
+//   - If the signature is at the end of the message, the payload is
+//     the raw content of the tag up to the signature
+//
+// The raw content of the tag is returned if the tag is not signed
+func (t *Tag) SignedPayload() []byte {
+	payload, found := removeSignatureHeaders(t.ToObject().Bytes())
+	if found {
+		return payload
+	}
+	// The headers end at the first empty line
+	msgStart := bytes.Index(payload, []byte("\n\n"))
+	if msgStart < 0 {
+		return payload
+	}
+	msgStart += 2
+	if i := inlineSignatureStart(payload[msgStart:]); i >= 0 {
+		return payload[:msgStart+i]
+	}
+	return payload
+}
+
 // ToObject returns the underlying Object
 func (t *Tag) ToObject() *Object {
 	if t.rawObject != nil {
@@ -179,17 +228,19 @@ func (t *Tag) ToObject() *Object {
 	// the error returned is always nil
 	buf := new(bytes.Buffer)
 	buf.WriteString("object ")
-	buf.WriteString(t.target.String())
-	buf.WriteByte('\n')
-
-	buf.WriteString("tag ")
-	buf.WriteString(t.Name())
+	var oid [ginternals.OidHexSize]byte
+	buf.Write(t.target.AppendHex(oid[:0]))
 	buf.WriteByte('\n')
 
+	// git expects the type to be before the name
 	buf.WriteString("type ")
 	buf.WriteString(t.Type().String())
 	buf.WriteByte('\n')
 
+	buf.WriteString("tag ")
+	buf.WriteString(t.Name())
+	buf.WriteByte('\n')
+
 	buf.WriteString("tagger ")
 	buf.WriteString(t.Tagger().String())
 	buf.WriteByte('\n')
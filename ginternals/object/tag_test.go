@@ -2,6 +2,7 @@ package object_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/Nivl/git-go/ginternals/object"
@@ -102,6 +103,8 @@ func TestTagToObject(t *testing.T) {
 		})
 
 		o := tag.ToObject()
+		expectedHeader := "object bbb720a96e4c29b9950a4c577c98470a4d5dd089\ntype commit\ntag v10.5.0\ntagger "
+		assert.True(t, strings.HasPrefix(string(o.Bytes()), expectedHeader), "the headers should be in the order expected by git")
 		tag2, err := o.AsTag()
 		require.NoError(t, err)
 
@@ -205,3 +208,59 @@ func TestNewTagFromObject(t *testing.T) {
 		}
 	})
 }
+
+func TestTagSignedPayload(t *testing.T) {
+	t.Parallel()
+
+	headers := "object e4a1c471c950b4b35481a21c531bf0b99366a822\n" +
+		"type commit\n" +
+		"tag v1\n" +
+		"tagger a <a@b.c> 1792191921 +0000\n"
+	inlineSig := "-----BEGIN SSH SIGNATURE-----\n" +
+		"U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAghr9G8cXVTi1dBT7w4Pw4QzLCnh\n" +
+		"Ld/qhUK8dXrHNLcWIZIAs=\n" +
+		"-----END SSH SIGNATURE-----\n"
+
+	testCases := []struct {
+		desc            string
+		raw             string
+		expectedPayload string
+		expectedSig     string
+	}{
+		{
+			desc:            "signature in the message",
+			raw:             headers + "\ntag msg\n" + inlineSig,
+			expectedPayload: headers + "\ntag msg\n",
+			expectedSig:     inlineSig,
+		},
+		{
+			desc: "signature in a header",
+			raw: headers +
+				"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+				" iQIzBAABCAAdFiEE9vjmBp5ZMl+LWBekLDB+DQQTNEsFAl1ZCE0ACgkQLDB+DQQT\n" +
+				" -----END PGP SIGNATURE-----\n" +
+				"\ntag msg\n",
+			expectedPayload: headers + "\ntag msg\n",
+			expectedSig: "-----BEGIN PGP SIGNATURE-----\n" +
+				" iQIzBAABCAAdFiEE9vjmBp5ZMl+LWBekLDB+DQQTNEsFAl1ZCE0ACgkQLDB+DQQT\n" +
+				" -----END PGP SIGNATURE-----",
+		},
+		{
+			desc:            "unsigned tag",
+			raw:             headers + "\ntag msg\n",
+			expectedPayload: headers + "\ntag msg\n",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			tag, err := object.New(object.TypeTag, []byte(tc.raw)).AsTag()
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, tc.expectedPayload, string(tag.SignedPayload()), "test %d", i)
+			assert.Equal(t, tc.expectedSig, tag.GPGSig(), "test %d", i)
+		})
+	}
+}
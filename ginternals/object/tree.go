@@ -2,15 +2,29 @@ package object
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/Nivl/git-go/ginternals"
 	"github.com/Nivl/git-go/internal/readutil"
 )
 
+// ErrTreeEntryNotFound represents an error thrown when a path
+// doesn't exist in a tree
+var ErrTreeEntryNotFound = errors.New("tree entry not found")
+
+// ObjectGetter represents a type that can retrieve objects from the
+// odb, like backend.Backend
+type ObjectGetter interface {
+	Object(oid ginternals.Oid) (*Object, error)
+}
+
 // TreeObjectMode represents the mode of an object inside a tree
-// Non-standard modes (like 0o100664) are not supported
+// Non-standard modes (like 0o100664) are not supported, and are
+// converted to their standard equivalent when parsing a tree
 type TreeObjectMode int32
 
 const (
@@ -38,6 +52,42 @@ func (m TreeObjectMode) IsValid() bool {
 	}
 }
 
+// parseTreeObjectMode parses the mode of a tree entry.
+// Like git, the non-standard modes created by old versions of git
+// are converted to the standard ones (0o100664 becomes 0o100644).
+// ErrTreeModeInvalid is returned if the mode is malformed, or if it
+// doesn't match any type of entry
+func parseTreeObjectMode(data []byte) (TreeObjectMode, error) {
+	// The biggest mode is 6 digits long (ex. 160000)
+	if len(data) == 0 || len(data) > 6 {
+		return 0, fmt.Errorf("%q: %w", data, ErrTreeModeInvalid)
+	}
+	var mode TreeObjectMode
+	for _, c := range data {
+		if c < '0' || c > '7' {
+			return 0, fmt.Errorf("%q: %w", data, ErrTreeModeInvalid)
+		}
+		mode = mode<<3 | TreeObjectMode(c-'0')
+	}
+
+	const typeMask = 0o170000
+	switch mode & typeMask {
+	case ModeFile & typeMask:
+		if mode&0o100 != 0 {
+			return ModeExecutable, nil
+		}
+		return ModeFile, nil
+	case ModeDirectory:
+		return ModeDirectory, nil
+	case ModeSymLink:
+		return ModeSymLink, nil
+	case ModeGitLink:
+		return ModeGitLink, nil
+	default:
+		return 0, fmt.Errorf("unsupported mode %q: %w", data, ErrTreeModeInvalid)
+	}
+}
+
 // ObjectType returns the object type associated to a mode
 func (m TreeObjectMode) ObjectType() Type {
 	switch m {
@@ -58,6 +108,11 @@ type Tree struct {
 	rawObject *Object
 	cache     map[string]TreeEntry
 
+	// subtrees contains the subtrees that have been loaded by
+	// EntryByPath, indexed by name
+	subtreesMu sync.Mutex
+	subtrees   map[string]*Tree
+
 	// We don't use pointers to make sure entries are immutable
 	// We don't use a map to map sure the order stays the same
 	entries []TreeEntry
@@ -70,7 +125,9 @@ type TreeEntry struct {
 	Mode TreeObjectMode
 }
 
-// NewTree returns a new tree with the given entries
+// NewTree returns a new tree with the given entries.
+// The modes of the entries are not validated, TreeObjectMode.IsValid()
+// can be used to make sure the tree will be readable by git
 func NewTree(entries []TreeEntry) *Tree {
 	t := &Tree{
 		entries: entries,
@@ -92,6 +149,13 @@ func NewTree(entries []TreeEntry) *Tree {
 // Note:
 // - a Tree may have multiple entries
 func NewTreeFromObject(o *Object) (*Tree, error) {
+	return NewTreeFromObjectWithLimits(o, Limits{})
+}
+
+// NewTreeFromObjectWithLimits creates a tree from a raw object,
+// making sure the object doesn't exceed the given limits.
+// ErrLimitExceeded is returned if a limit is reached
+func NewTreeFromObjectWithLimits(o *Object, limits Limits) (*Tree, error) {
 	if o.Type() != TypeTree {
 		return nil, fmt.Errorf("type %s is not a tree: %w", o.typ, ErrObjectInvalid)
 	}
@@ -105,17 +169,20 @@ func NewTreeFromObject(o *Object) (*Tree, error) {
 		// the variable i is only use for logs and error messages, not for
 		// actual processing
 		for i := 1; ; i++ {
+			if limits.MaxTreeEntries > 0 && i > limits.MaxTreeEntries {
+				return nil, fmt.Errorf("tree has more than %d entries: %w", limits.MaxTreeEntries, ErrLimitExceeded)
+			}
 			entry := TreeEntry{}
 			data := readutil.ReadTo(objData[offset:], ' ')
 			if len(data) == 0 {
 				return nil, fmt.Errorf("could not retrieve the mode of entry %d: %w", i, ErrTreeInvalid)
 			}
 			offset += len(data) + 1 // +1 for the space
-			mode, err := strconv.ParseInt(string(data), 8, 32)
+			mode, err := parseTreeObjectMode(data)
 			if err != nil {
-				return nil, fmt.Errorf("could not parse mode of entry %d: %s: %w", i, err.Error(), ErrTreeInvalid)
+				return nil, fmt.Errorf("could not parse mode of entry %d: %w", i, err)
 			}
-			entry.Mode = TreeObjectMode(mode)
+			entry.Mode = mode
 
 			data = readutil.ReadTo(objData[offset:], 0)
 			if len(data) == 0 {
@@ -162,6 +229,61 @@ func (t *Tree) Entry(path string) (entry TreeEntry, ok bool) {
 	return
 }
 
+// EntryByPath returns the entry at the given path, walking the
+// subtrees as needed (ex. "dir/sub/file.go").
+// The subtrees are loaded from odb and kept in memory, so looking up
+// multiple paths sharing the same directories only loads each
+// directory once.
+// ErrTreeEntryNotFound is returned if the path doesn't exist
+func (t *Tree) EntryByPath(odb ObjectGetter, path string) (TreeEntry, error) {
+	path = strings.Trim(path, "/")
+	tree := t
+	for {
+		i := strings.IndexByte(path, '/')
+		if i == -1 {
+			entry, ok := tree.Entry(path)
+			if !ok {
+				return TreeEntry{}, ErrTreeEntryNotFound
+			}
+			return entry, nil
+		}
+
+		var err error
+		tree, err = tree.subtree(odb, path[:i])
+		if err != nil {
+			return TreeEntry{}, err
+		}
+		path = path[i+1:]
+	}
+}
+
+// subtree returns the tree of the directory with the given name
+func (t *Tree) subtree(odb ObjectGetter, name string) (*Tree, error) {
+	t.subtreesMu.Lock()
+	defer t.subtreesMu.Unlock()
+
+	if sub, ok := t.subtrees[name]; ok {
+		return sub, nil
+	}
+	entry, ok := t.Entry(name)
+	if !ok || entry.Mode != ModeDirectory {
+		return nil, ErrTreeEntryNotFound
+	}
+	o, err := odb.Object(entry.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get tree %s: %w", entry.ID.String(), err)
+	}
+	sub, err := o.AsTree()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse tree %s: %w", entry.ID.String(), err)
+	}
+	if t.subtrees == nil {
+		t.subtrees = map[string]*Tree{}
+	}
+	t.subtrees[name] = sub
+	return sub, nil
+}
+
 // ID returns the object's ID
 // ginternals.NullOid is returned if the object doesn't have
 // an ID yet
@@ -38,6 +38,31 @@ func TestTree(t *testing.T) {
 		require.Equal(t, o.Bytes(), newO.Bytes())
 	})
 
+	t.Run("ToObject() should write all the modes like git", func(t *testing.T) {
+		t.Parallel()
+
+		blobID, err := ginternals.NewOidFromStr("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391")
+		require.NoError(t, err)
+		treeID, err := ginternals.NewOidFromStr("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+		require.NoError(t, err)
+		commitID, err := ginternals.NewOidFromStr("51a15a94e8f9afa21dd466bd0295792d7fea34ce")
+		require.NoError(t, err)
+
+		tree := object.NewTree([]object.TreeEntry{
+			{Mode: object.ModeFile, ID: blobID, Path: "a"},
+			{Mode: object.ModeExecutable, ID: blobID, Path: "b"},
+			{Mode: object.ModeSymLink, ID: blobID, Path: "c"},
+			{Mode: object.ModeGitLink, ID: commitID, Path: "d"},
+			{Mode: object.ModeDirectory, ID: treeID, Path: "e"},
+		})
+		// ID generated with git mktree
+		assert.Equal(t, "18f3d58a0a323ee154463fa1316319dd2bdf2018", tree.ID().String())
+
+		parsed, err := tree.ToObject().AsTree()
+		require.NoError(t, err)
+		assert.Equal(t, tree.Entries(), parsed.Entries())
+	})
+
 	t.Run("Entries should be immutable", func(t *testing.T) {
 		t.Parallel()
 
@@ -120,6 +145,76 @@ func TestTreeEntry(t *testing.T) {
 	}
 }
 
+func TestTreeEntryByPath(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := git.OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	treeID, err := ginternals.NewOidFromStr("e5b9e846e1b468bc9597ff95d71dfacda8bd54e3")
+	require.NoError(t, err)
+	tree, err := r.Tree(treeID)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc          string
+		path          string
+		expectedOid   string
+		expectedError error
+	}{
+		{
+			desc:        "file at the root",
+			path:        "README.md",
+			expectedOid: "642480605b8b0fd464ab5762e044269cf29a60a3",
+		},
+		{
+			desc:        "nested file",
+			path:        "plumbing/object/commit.go",
+			expectedOid: "2492dd7d2f640924f334370b4222f886cf7cee98",
+		},
+		{
+			desc:        "nested file sharing a directory",
+			path:        "plumbing/packfile/packfile.go",
+			expectedOid: "0cc4d3839051777271a4974ab2d8d31fa3e2a1a1",
+		},
+		{
+			desc:        "leading and trailing slashes are ignored",
+			path:        "/plumbing/oid.go/",
+			expectedOid: "ed841c7e912a8e0d3154e7aec712c241a35e129f",
+		},
+		{
+			desc:          "missing file",
+			path:          "plumbing/object/404.go",
+			expectedError: object.ErrTreeEntryNotFound,
+		},
+		{
+			desc:          "file used as a directory",
+			path:          "README.md/nope",
+			expectedError: object.ErrTreeEntryNotFound,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			entry, err := tree.EntryByPath(r, tc.path)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOid, entry.ID.String())
+		})
+	}
+}
+
 func TestTreeObjectMode(t *testing.T) {
 	t.Parallel()
 
@@ -254,6 +349,42 @@ func TestNewTreeFromObject(t *testing.T) {
 		assert.Len(t, tree.Entries(), 0)
 	})
 
+	t.Run("should parse all the modes", func(t *testing.T) {
+		t.Parallel()
+
+		oid := "\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10\x11\x12\x13\x14"
+		testCases := []struct {
+			desc         string
+			mode         string
+			expectedMode object.TreeObjectMode
+		}{
+			{desc: "file", mode: "100644", expectedMode: object.ModeFile},
+			{desc: "executable", mode: "100755", expectedMode: object.ModeExecutable},
+			{desc: "symlink", mode: "120000", expectedMode: object.ModeSymLink},
+			{desc: "gitlink", mode: "160000", expectedMode: object.ModeGitLink},
+			{desc: "directory", mode: "40000", expectedMode: object.ModeDirectory},
+			{desc: "zero-padded directory", mode: "040000", expectedMode: object.ModeDirectory},
+			{desc: "group-writable file", mode: "100664", expectedMode: object.ModeFile},
+			{desc: "group-executable file", mode: "100775", expectedMode: object.ModeExecutable},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				raw := tc.mode + " entry\x00" + oid
+				o := object.New(object.TypeTree, []byte(raw))
+				tree, err := object.NewTreeFromObject(o)
+				require.NoError(t, err, "test %d", i)
+				require.Len(t, tree.Entries(), 1, "test %d", i)
+				assert.Equal(t, tc.expectedMode, tree.Entries()[0].Mode, "test %d", i)
+				// the raw object should be kept so the ID doesn't change
+				assert.Equal(t, o.ID(), tree.ID(), "test %d", i)
+			})
+		}
+	})
+
 	t.Run("parsing failures", func(t *testing.T) {
 		t.Parallel()
 
@@ -277,16 +408,40 @@ func TestNewTreeFromObject(t *testing.T) {
 			},
 			{
 				desc:               "should fail if the tree ends after the mode",
-				data:               "644 ",
+				data:               "100644 ",
 				expectedError:      object.ErrTreeInvalid,
 				expectedErrorMatch: "could not retrieve the path of entry",
 			},
 			{
 				desc:               "should fail if the tree has an invalid ID",
-				data:               "644 file.go\x00invalid",
+				data:               "100644 file.go\x00invalid",
 				expectedError:      object.ErrTreeInvalid,
 				expectedErrorMatch: "not enough space to retrieve the ID of entry",
 			},
+			{
+				desc:               "should fail if the mode is not octal",
+				data:               "100694 file.go\x00",
+				expectedError:      object.ErrTreeModeInvalid,
+				expectedErrorMatch: "could not parse mode of entry 1",
+			},
+			{
+				desc:               "should fail if the mode is too long",
+				data:               "1000644 file.go\x00",
+				expectedError:      object.ErrTreeModeInvalid,
+				expectedErrorMatch: "could not parse mode of entry 1",
+			},
+			{
+				desc:               "should fail if the mode has no type",
+				data:               "644 file.go\x00",
+				expectedError:      object.ErrTreeModeInvalid,
+				expectedErrorMatch: "unsupported mode",
+			},
+			{
+				desc:               "should fail if the mode has an unsupported type",
+				data:               "170000 file.go\x00",
+				expectedError:      object.ErrTreeModeInvalid,
+				expectedErrorMatch: "unsupported mode",
+			},
 		}
 		for i, tc := range testCases {
 			tc := tc
@@ -1,17 +1,23 @@
 package ginternals
 
 import (
+	"bytes"
 	"crypto/sha1"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 )
 
 const (
 	// OidSize is the length of an oid, in bytes
 	OidSize = 20
+	// OidHexSize is the length of an oid, once hex encoded
+	OidHexSize = OidSize * 2
 )
 
+// hexChars contains the chars used to hex-encode a value
+const hexChars = "0123456789abcdef"
+
 var (
 	// NullOid is the value of an empty Oid, or one that's all 0s
 	NullOid = Oid{}
@@ -34,7 +40,28 @@ func (o Oid) Bytes() []byte {
 
 // String converts an oid to a string
 func (o Oid) String() string {
-	return hex.EncodeToString(o[:])
+	var buf [OidHexSize]byte
+	o.encodeHex(buf[:])
+	return string(buf[:])
+}
+
+// AppendHex appends the hex-encoded oid to dst and returns the
+// extended buffer.
+// No allocations are made if dst has enough capacity, which makes
+// it faster than String() when writing an oid into a buffer
+func (o Oid) AppendHex(dst []byte) []byte {
+	var buf [OidHexSize]byte
+	o.encodeHex(buf[:])
+	return append(dst, buf[:]...)
+}
+
+// encodeHex writes the hex-encoded oid into dst, which needs to be at
+// least OidHexSize long
+func (o Oid) encodeHex(dst []byte) {
+	for i, b := range o {
+		dst[i*2] = hexChars[b>>4]
+		dst[i*2+1] = hexChars[b&0x0f]
+	}
 }
 
 // NewOidFromContent returns the Oid of the given content.
@@ -59,30 +86,91 @@ func NewOidFromHex(id []byte) (Oid, error) {
 // NewOidFromChars creates an Oid from the given char bytes
 // For the SHA {'9', 'b', '9', '1', 'd', 'a', ...}
 // the oid will be {0x9b, 0x91, 0xda, ...}
+// No allocations are made unless an error is returned
 func NewOidFromChars(id []byte) (Oid, error) {
-	return NewOidFromStr(string(id))
+	if len(id) != OidHexSize {
+		return NullOid, ErrInvalidOid
+	}
+
+	var oid Oid
+	for i := range oid {
+		hi, ok1 := fromHexChar(id[i*2])
+		lo, ok2 := fromHexChar(id[i*2+1])
+		if !ok1 || !ok2 {
+			return NullOid, fmt.Errorf("could not decode string: %w", ErrInvalidOid)
+		}
+		oid[i] = hi<<4 | lo
+	}
+	return oid, nil
 }
 
 // NewOidFromStr creates an Oid from the given string
 // For the SHA 9b91da06e69613397b38e0808e0ba5ee6983251b
 // the oid will be {0x9b, 0x91, 0xda, ...}
+// No allocations are made unless an error is returned
 func NewOidFromStr(id string) (Oid, error) {
-	bytes, err := hex.DecodeString(id)
-	if err != nil {
-		return NullOid, fmt.Errorf("could not decode string: %w", err)
-	}
-
-	if len(bytes) != 20 {
+	if len(id) != OidHexSize {
 		return NullOid, ErrInvalidOid
 	}
+	// Copying the string in an array lets us reuse NewOidFromChars
+	// without allocating, since the array stays on the stack
+	var chars [OidHexSize]byte
+	copy(chars[:], id)
+	return NewOidFromChars(chars[:])
+}
 
-	var oid Oid
-	copy(oid[:], bytes)
-
-	return oid, nil
+// fromHexChar converts a hex char into its value.
+// Like encoding/hex, both upper and lower case chars are accepted
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
 }
 
 // IsZero returns whether the oid has the zero value (NullOid)
 func (o Oid) IsZero() bool {
 	return o == NullOid
 }
+
+// Compare returns an integer comparing two oids byte by byte, the
+// same way git sorts them in the packfile indexes.
+// The result is 0 if o == other, -1 if o < other, and +1 if o > other
+func (o Oid) Compare(other Oid) int {
+	return bytes.Compare(o[:], other[:])
+}
+
+// Less returns whether o sorts before other
+func (o Oid) Less(other Oid) bool {
+	return o.Compare(other) < 0
+}
+
+// SortOids sorts the given oids in increasing order
+func SortOids(oids []Oid) {
+	sort.Slice(oids, func(i, j int) bool {
+		return oids[i].Less(oids[j])
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// The oid is hex-encoded, which allows it to be used as a value or as
+// a map key by encoding/json
+func (o Oid) MarshalText() ([]byte, error) {
+	return o.AppendHex(make([]byte, 0, OidHexSize)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// The text must contain a hex-encoded oid
+func (o *Oid) UnmarshalText(text []byte) error {
+	oid, err := NewOidFromChars(text)
+	if err != nil {
+		return err
+	}
+	*o = oid
+	return nil
+}
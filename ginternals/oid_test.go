@@ -1,8 +1,10 @@
 package ginternals_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/Nivl/git-go/ginternals"
@@ -29,6 +31,11 @@ func TestNewOidFromStr(t *testing.T) {
 			id:          "0eaf96 ff79d8f61958aaefe163620d952606516",
 			expectError: true,
 		},
+		{
+			desc:        "upper case chars should work",
+			id:          "0EAF966FF79D8F61958AAEFE163620D952606516",
+			expectError: false,
+		},
 		{
 			desc:          "invalid size should fail",
 			id:            "0eaf96ff79d8f61958aaefe163620d952606",
@@ -51,7 +58,7 @@ func TestNewOidFromStr(t *testing.T) {
 				return
 			}
 			require.NoError(t, err)
-			assert.Equal(t, tc.id, oid.String())
+			assert.Equal(t, strings.ToLower(tc.id), oid.String())
 		})
 	}
 }
@@ -210,3 +217,143 @@ func TestIsZero(t *testing.T) {
 		require.True(t, ginternals.NullOid.IsZero(), "NullOid should be Zero")
 	})
 }
+
+func TestAppendHex(t *testing.T) {
+	t.Parallel()
+
+	oid, err := ginternals.NewOidFromStr("0eaf966ff79d8f61958aaefe163620d952606516")
+	require.NoError(t, err)
+
+	out := oid.AppendHex([]byte("object "))
+	assert.Equal(t, "object 0eaf966ff79d8f61958aaefe163620d952606516", string(out))
+}
+
+func TestOidCompare(t *testing.T) {
+	t.Parallel()
+
+	small, err := ginternals.NewOidFromStr("0eaf966ff79d8f61958aaefe163620d952606516")
+	require.NoError(t, err)
+	big, err := ginternals.NewOidFromStr("0eaf966ff79d8f61958aaefe163620d952606517")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, small.Compare(small))
+	assert.Equal(t, -1, small.Compare(big))
+	assert.Equal(t, 1, big.Compare(small))
+	assert.Equal(t, -1, ginternals.NullOid.Compare(small))
+
+	assert.True(t, small.Less(big))
+	assert.False(t, big.Less(small))
+	assert.False(t, small.Less(small))
+
+	oids := []ginternals.Oid{big, ginternals.NullOid, small}
+	ginternals.SortOids(oids)
+	assert.Equal(t, []ginternals.Oid{ginternals.NullOid, small, big}, oids)
+}
+
+func TestOidText(t *testing.T) {
+	t.Parallel()
+
+	sha := "0eaf966ff79d8f61958aaefe163620d952606516"
+	oid, err := ginternals.NewOidFromStr(sha)
+	require.NoError(t, err)
+
+	t.Run("should round-trip", func(t *testing.T) {
+		t.Parallel()
+
+		text, err := oid.MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, sha, string(text))
+
+		var out ginternals.Oid
+		require.NoError(t, out.UnmarshalText(text))
+		assert.Equal(t, oid, out)
+	})
+
+	t.Run("should fail on invalid oids", func(t *testing.T) {
+		t.Parallel()
+
+		for i, text := range []string{"", "0eaf", sha + "00", "zeaf966ff79d8f61958aaefe163620d952606516"} {
+			out := oid
+			err := out.UnmarshalText([]byte(text))
+			require.ErrorIs(t, err, ginternals.ErrInvalidOid, "test %d", i)
+			assert.Equal(t, oid, out, "test %d: the oid should not have changed", i)
+		}
+	})
+
+	t.Run("should be usable with encoding/json", func(t *testing.T) {
+		t.Parallel()
+
+		type payload struct {
+			ID   ginternals.Oid
+			Refs map[ginternals.Oid]string
+		}
+		in := payload{
+			ID:   oid,
+			Refs: map[ginternals.Oid]string{oid: "refs/heads/master"},
+		}
+		data, err := json.Marshal(in)
+		require.NoError(t, err)
+		assert.Equal(t, `{"ID":"`+sha+`","Refs":{"`+sha+`":"refs/heads/master"}}`, string(data))
+
+		var out payload
+		require.NoError(t, json.Unmarshal(data, &out))
+		assert.Equal(t, in, out)
+
+		err = json.Unmarshal([]byte(`{"ID":"nope"}`), &out)
+		require.ErrorIs(t, err, ginternals.ErrInvalidOid)
+	})
+}
+
+//nolint:paralleltest // testing.AllocsPerRun cannot be used in parallel tests
+func TestOidAllocs(t *testing.T) {
+	sha := "0eaf966ff79d8f61958aaefe163620d952606516"
+	chars := []byte(sha)
+	oid, err := ginternals.NewOidFromStr(sha)
+	require.NoError(t, err)
+	buf := make([]byte, 0, ginternals.OidHexSize)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = ginternals.NewOidFromStr(sha)
+		_, _ = ginternals.NewOidFromChars(chars)
+		buf = oid.AppendHex(buf[:0])
+	})
+	assert.Zero(t, allocs)
+	assert.Equal(t, sha, string(buf))
+}
+
+func BenchmarkOidString(b *testing.B) {
+	oid := ginternals.NewOidFromContent([]byte("123456789"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = oid.String()
+	}
+}
+
+func BenchmarkOidAppendHex(b *testing.B) {
+	oid := ginternals.NewOidFromContent([]byte("123456789"))
+	buf := make([]byte, 0, ginternals.OidHexSize)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = oid.AppendHex(buf[:0])
+	}
+}
+
+func BenchmarkNewOidFromStr(b *testing.B) {
+	sha := "f7c3bc1d808e04732adf679965ccc34ca7ae3441"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ginternals.NewOidFromStr(sha); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewOidFromChars(b *testing.B) {
+	sha := []byte("f7c3bc1d808e04732adf679965ccc34ca7ae3441")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ginternals.NewOidFromChars(sha); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
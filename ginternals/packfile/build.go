@@ -0,0 +1,439 @@
+package packfile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/delta"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+const (
+	// DefaultWindow is the default number of objects compared to
+	// each object when looking for a delta base. Same as git's
+	// pack.window
+	DefaultWindow = 10
+	// DefaultDepth is the default maximum length of a delta chain.
+	// Same as git's pack.depth
+	DefaultDepth = 50
+
+	// minDeltaSize is the minimum size an object needs to have to be
+	// deltified. A delta of a smaller object would not save anything
+	minDeltaSize = 50
+	// maxSmallOffset is the biggest offset that can be stored in
+	// layer4 of an index. Bigger offsets go to layer5
+	maxSmallOffset = 0x7fffffff
+)
+
+// BuildOptions represents the options used to build a packfile
+type BuildOptions struct {
+	// Window contains the number of objects each object is compared
+	// to when looking for a delta base. The objects are sorted by
+	// type, path, and size so similar objects end up next to each
+	// other.
+	// 0 disables the delta compression, but existing deltas can
+	// still be reused
+	Window int
+	// Depth contains the maximum length of the delta chains.
+	// 0 disables the delta compression, including the reuse of
+	// existing deltas
+	Depth int
+	// LargeOffsetThreshold contains the biggest offset that can be
+	// stored in layer4 of the index. The objects stored after this
+	// offset are stored in layer5, which is normally only used by the
+	// packfiles bigger than 2GB. This is useful to test the layer5
+	// without having to create huge packfiles.
+	// Like git, the first object is always stored in layer4, so
+	// smaller values are the same as the size of the packfile header.
+	// This is the equivalent of --index-version=2,<offset>.
+	// Defaults to 0x7fffffff, the maximum value
+	LargeOffsetThreshold uint64
+}
+
+// BuildObject represents an object to store in a packfile
+type BuildObject struct {
+	Object *object.Object
+	// Path contains the path of the object in the repository, if
+	// any. It's used to find the objects that are likely to be
+	// similar
+	Path string
+	// DeltaBase and Delta can be set to reuse an existing delta
+	// instead of looking for a new one. The delta is only reused if
+	// DeltaBase is part of the packfile, and if the delta chain
+	// doesn't get longer than BuildOptions.Depth
+	DeltaBase ginternals.Oid
+	Delta     []byte
+}
+
+// BuildResult contains a packfile created by Build()
+type BuildResult struct {
+	ID    ginternals.Oid
+	Pack  []byte
+	Index []byte
+	// ObjectCount contains the number of objects in the packfile
+	ObjectCount int
+	// Deltas contains the number of deltified objects, reused
+	// deltas included
+	Deltas int
+	// ReusedDeltas contains the number of deltas that have been
+	// reused as is
+	ReusedDeltas int
+	// Timings contains how long each phase of the build took
+	Timings BuildTimings
+}
+
+// BuildTimings contains how long each phase of Build() took
+type BuildTimings struct {
+	// Deltas contains the time spent reusing and looking for deltas
+	Deltas time.Duration
+	// Write contains the time spent compressing and writing the
+	// objects in the packfile
+	Write time.Duration
+	// Index contains the time spent creating the index
+	Index time.Duration
+}
+
+// buildEntry contains the state of an object being packed
+type buildEntry struct {
+	obj      *BuildObject
+	nameHash uint32
+
+	// base and delta are set if the object is deltified
+	base   *buildEntry
+	delta  []byte
+	depth  int
+	reused bool
+	// isReusedBase is set if the object is the base of a reused
+	// delta
+	isReusedBase bool
+
+	written bool
+	offset  uint64
+	crc     uint32
+}
+
+// Build creates a packfile and its index containing the given
+// objects. The objects are stored in the given order, with the bases
+// of the deltas stored before the deltas.
+// Duplicate objects are only stored once
+func Build(objects []*BuildObject, opts BuildOptions) (*BuildResult, error) {
+	entries := make([]*buildEntry, 0, len(objects))
+	byID := make(map[ginternals.Oid]*buildEntry, len(objects))
+	for _, o := range objects {
+		if _, ok := byID[o.Object.ID()]; ok {
+			continue
+		}
+		if !o.Object.Type().IsValid() || o.Object.Type() == object.ObjectDeltaOFS || o.Object.Type() == object.ObjectDeltaRef {
+			return nil, fmt.Errorf("object %s has an invalid type %d: %w", o.Object.ID().String(), o.Object.Type(), object.ErrObjectUnknown)
+		}
+		e := &buildEntry{
+			obj:      o,
+			nameHash: nameHash(o.Path),
+		}
+		entries = append(entries, e)
+		byID[o.Object.ID()] = e
+	}
+
+	res := &BuildResult{
+		ObjectCount: len(entries),
+	}
+	start := time.Now()
+	if opts.Depth > 0 {
+		reuseDeltas(entries, byID, opts.Depth)
+		if opts.Window > 0 {
+			findDeltas(entries, opts)
+		}
+	}
+	for _, e := range entries {
+		if e.base == nil {
+			continue
+		}
+		res.Deltas++
+		if e.reused {
+			res.ReusedDeltas++
+		}
+	}
+	res.Timings.Deltas = time.Since(start)
+
+	start = time.Now()
+	pack := &bytes.Buffer{}
+	pack.Write(packfileMagic())
+	pack.Write(packfileVersion())
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, uint32(len(entries)))
+	pack.Write(count)
+	for _, e := range entries {
+		if err := writeEntry(pack, e); err != nil {
+			return nil, err
+		}
+	}
+	sum := sha1.Sum(pack.Bytes()) //nolint:gosec // sha1 is used by git
+	pack.Write(sum[:])
+
+	var err error
+	res.ID, err = ginternals.NewOidFromHex(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid packfile checksum: %w", err)
+	}
+	res.Pack = pack.Bytes()
+	res.Timings.Write = time.Since(start)
+
+	start = time.Now()
+	largeOffset := opts.LargeOffsetThreshold
+	switch {
+	case largeOffset == 0 || largeOffset > maxSmallOffset:
+		largeOffset = maxSmallOffset
+	case largeOffset < packfileHeaderSize:
+		largeOffset = packfileHeaderSize
+	}
+	res.Index = buildIndex(entries, sum[:], largeOffset)
+	res.Timings.Index = time.Since(start)
+	return res, nil
+}
+
+// reuseDeltas sets the provided deltas whose base is in the packfile.
+// The deltas that would create a chain longer than maxDepth, or a
+// loop, are dropped
+func reuseDeltas(entries []*buildEntry, byID map[ginternals.Oid]*buildEntry, maxDepth int) {
+	for _, e := range entries {
+		if e.obj.DeltaBase.IsZero() || e.obj.Delta == nil {
+			continue
+		}
+		base, ok := byID[e.obj.DeltaBase]
+		if !ok || base == e || base.obj.Object.Type() != e.obj.Object.Type() {
+			continue
+		}
+		e.base = base
+		e.delta = e.obj.Delta
+		e.reused = true
+	}
+
+	// The deltas may come from different packfiles, so they may
+	// create loops. Dropping a delta only makes the other chains
+	// shorter, so a single pass is enough
+	for _, e := range entries {
+		depth := 0
+		for b := e.base; b != nil; b = b.base {
+			depth++
+			if depth > maxDepth {
+				e.base, e.delta, e.reused = nil, nil, false
+				break
+			}
+		}
+	}
+	for _, e := range entries {
+		e.depth = 0
+		for b := e.base; b != nil; b = b.base {
+			e.depth++
+		}
+		if e.base != nil {
+			e.base.isReusedBase = true
+		}
+	}
+}
+
+// findDeltas looks for a delta base for all the objects that are not
+// deltified yet, using a sliding window over the objects sorted by
+// type, path, and size
+func findDeltas(entries []*buildEntry, opts BuildOptions) {
+	sorted := make([]*buildEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.obj.Object.Type() != b.obj.Object.Type() {
+			return a.obj.Object.Type() < b.obj.Object.Type()
+		}
+		if a.nameHash != b.nameHash {
+			return a.nameHash < b.nameHash
+		}
+		// The biggest objects come first so the deltas remove data,
+		// which produces smaller deltas
+		return a.obj.Object.Size() > b.obj.Object.Size()
+	})
+
+	for i, target := range sorted {
+		// Objects that are already the base of a reused delta are
+		// not deltified since it would make the reused chains longer,
+		// or create loops
+		if target.base != nil || target.isReusedBase || target.obj.Object.Size() < minDeltaSize {
+			continue
+		}
+		targetData := target.obj.Object.Bytes()
+		targetSize := len(targetData)
+		var best []byte
+		var bestBase *buildEntry
+		for j := i - 1; j >= 0 && j >= i-opts.Window; j-- {
+			candidate := sorted[j]
+			if candidate.obj.Object.Type() != target.obj.Object.Type() {
+				break
+			}
+			if candidate.depth+1 > opts.Depth {
+				continue
+			}
+			// Like git, a delta is only worth it if it's less than
+			// half the size of the object. The deeper the base, the
+			// smaller the delta needs to be
+			maxSize := targetSize/2 - ginternals.OidSize
+			maxSize = maxSize * (opts.Depth - candidate.depth) / opts.Depth
+			if best != nil && len(best) < maxSize {
+				maxSize = len(best)
+			}
+			baseSize := candidate.obj.Object.Size()
+			if baseSize-targetSize >= maxSize || targetSize-baseSize >= maxSize || baseSize < targetSize/32 {
+				continue
+			}
+			d := delta.Create(candidate.obj.Object.Bytes(), targetData)
+			if len(d) >= maxSize {
+				continue
+			}
+			best, bestBase = d, candidate
+		}
+		if bestBase != nil {
+			target.base = bestBase
+			target.delta = best
+			target.depth = bestBase.depth + 1
+		}
+	}
+}
+
+// writeEntry writes the given entry to the packfile, after its base
+// if it has one
+func writeEntry(pack *bytes.Buffer, e *buildEntry) error {
+	if e.written {
+		return nil
+	}
+	if e.base != nil && !e.base.written {
+		if err := writeEntry(pack, e.base); err != nil {
+			return err
+		}
+	}
+
+	e.offset = uint64(pack.Len())
+	entry := &bytes.Buffer{}
+	data := e.obj.Object.Bytes()
+	if e.base == nil {
+		writeEntryHeader(entry, e.obj.Object.Type(), uint64(len(data)))
+	} else {
+		data = e.delta
+		writeEntryHeader(entry, object.ObjectDeltaOFS, uint64(len(data)))
+		writeDeltaOffset(entry, e.offset-e.base.offset)
+	}
+	zw := zlib.NewWriter(entry)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("could not compress object %s: %w", e.obj.Object.ID().String(), err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("could not compress object %s: %w", e.obj.Object.ID().String(), err)
+	}
+	e.crc = crc32.ChecksumIEEE(entry.Bytes())
+	pack.Write(entry.Bytes())
+	e.written = true
+	return nil
+}
+
+// writeEntryHeader writes the type and the size of an entry.
+// See the documentation of Pack for the format
+func writeEntryHeader(buf *bytes.Buffer, typ object.Type, size uint64) {
+	b := byte(typ)<<4 | byte(size&0b_0000_1111)
+	size >>= 4
+	for size != 0 {
+		buf.WriteByte(b | 0b_1000_0000)
+		b = byte(size & 0b_0111_1111)
+		size >>= 7
+	}
+	buf.WriteByte(b)
+}
+
+// writeDeltaOffset writes the negative offset of the base of an
+// ObjectDeltaOFS. See readDeltaOffset for the format
+func writeDeltaOffset(buf *bytes.Buffer, offset uint64) {
+	out := make([]byte, 10)
+	pos := len(out) - 1
+	out[pos] = byte(offset & 0b_0111_1111)
+	for offset >>= 7; offset != 0; offset >>= 7 {
+		offset--
+		pos--
+		out[pos] = 0b_1000_0000 | byte(offset&0b_0111_1111)
+	}
+	buf.Write(out[pos:])
+}
+
+// buildIndex returns the index of a packfile containing the given
+// entries. The entries stored after largeOffset are stored in
+// layer5. See the documentation of PackIndex for the format
+func buildIndex(entries []*buildEntry, packChecksum []byte, largeOffset uint64) []byte {
+	sorted := make([]*buildEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].obj.Object.ID().Less(sorted[j].obj.Object.ID())
+	})
+
+	idx := &bytes.Buffer{}
+	idx.Write(indexHeader())
+	buf := make([]byte, 8)
+
+	// layer1
+	fanout := [256]uint32{}
+	for _, e := range sorted {
+		fanout[e.obj.Object.ID().Bytes()[0]]++
+	}
+	cumul := uint32(0)
+	for _, count := range fanout {
+		cumul += count
+		binary.BigEndian.PutUint32(buf, cumul)
+		idx.Write(buf[:4])
+	}
+	// layer2
+	for _, e := range sorted {
+		idx.Write(e.obj.Object.ID().Bytes())
+	}
+	// layer3
+	for _, e := range sorted {
+		binary.BigEndian.PutUint32(buf, e.crc)
+		idx.Write(buf[:4])
+	}
+	// layer4 and layer5
+	largeOffsets := []uint64{}
+	for _, e := range sorted {
+		offset := uint32(e.offset)
+		if e.offset > largeOffset {
+			offset = 0x80000000 | uint32(len(largeOffsets))
+			largeOffsets = append(largeOffsets, e.offset)
+		}
+		binary.BigEndian.PutUint32(buf, offset)
+		idx.Write(buf[:4])
+	}
+	for _, offset := range largeOffsets {
+		binary.BigEndian.PutUint64(buf, offset)
+		idx.Write(buf)
+	}
+	// footer
+	idx.Write(packChecksum)
+	sum := sha1.Sum(idx.Bytes()) //nolint:gosec // sha1 is used by git
+	idx.Write(sum[:])
+	return idx.Bytes()
+}
+
+// nameHash returns a hash of the given path that puts files with
+// the same name and extension next to each other when sorted.
+// This is the same hash as git's pack_name_hash(), which gives more
+// weight to the last characters
+func nameHash(name string) uint32 {
+	hash := uint32(0)
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if unicode.IsSpace(rune(c)) {
+			continue
+		}
+		hash = (hash >> 2) + (uint32(c) << 24)
+	}
+	return hash
+}
@@ -0,0 +1,162 @@
+package packfile_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+
+	src, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, src.Close())
+	})
+
+	objects := []*object.Object{}
+	err = src.WalkOids(func(oid ginternals.Oid) error {
+		o, err := src.GetObject(oid)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, o)
+		return nil
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc         string
+		opts         packfile.BuildOptions
+		reuse        bool
+		expectDeltas bool
+	}{
+		{
+			desc: "no deltas",
+			opts: packfile.BuildOptions{},
+		},
+		{
+			desc:         "with deltas",
+			opts:         packfile.BuildOptions{Window: packfile.DefaultWindow, Depth: packfile.DefaultDepth},
+			expectDeltas: true,
+		},
+		{
+			desc:         "with short delta chains",
+			opts:         packfile.BuildOptions{Window: packfile.DefaultWindow, Depth: 1},
+			expectDeltas: true,
+		},
+		{
+			desc:         "with reused deltas",
+			opts:         packfile.BuildOptions{Depth: packfile.DefaultDepth},
+			reuse:        true,
+			expectDeltas: true,
+		},
+		{
+			desc:         "with reused deltas and short delta chains",
+			opts:         packfile.BuildOptions{Depth: 2},
+			reuse:        true,
+			expectDeltas: true,
+		},
+		{
+			desc:         "with large offsets",
+			opts:         packfile.BuildOptions{Window: packfile.DefaultWindow, Depth: packfile.DefaultDepth, LargeOffsetThreshold: 1},
+			expectDeltas: true,
+		},
+	}
+	sizes := map[string]int{}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			// Not parallel since we compare the sizes of the packs
+
+			toPack := make([]*packfile.BuildObject, 0, len(objects))
+			for _, o := range objects {
+				bo := &packfile.BuildObject{Object: o}
+				if tc.reuse {
+					bo.DeltaBase, bo.Delta, err = src.RawDelta(o.ID())
+					require.NoError(t, err)
+				}
+				toPack = append(toPack, bo)
+			}
+			res, err := packfile.Build(toPack, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, len(objects), res.ObjectCount)
+			if tc.expectDeltas {
+				assert.NotZero(t, res.Deltas)
+			} else {
+				assert.Zero(t, res.Deltas)
+			}
+			if tc.reuse {
+				assert.Equal(t, res.Deltas, res.ReusedDeltas)
+			} else {
+				assert.Zero(t, res.ReusedDeltas)
+			}
+			sizes[tc.desc] = len(res.Pack)
+			if tc.opts.LargeOffsetThreshold > 0 {
+				// All the offsets but the one of the first object
+				// should have been written in layer5, which contains
+				// 8 bytes per object
+				smallIndex := 8 + 256*4 + len(objects)*(ginternals.OidSize+4+4) + 2*ginternals.OidSize
+				assert.Equal(t, smallIndex+(len(objects)-1)*8, len(res.Index))
+			}
+
+			// We make sure the packfile can be read back
+			dir := t.TempDir()
+			p := filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtPackfile)
+			fs := afero.NewOsFs()
+			require.NoError(t, afero.WriteFile(fs, p, res.Pack, 0o644))
+			require.NoError(t, afero.WriteFile(fs, filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtIndex), res.Index, 0o644))
+			pack, err := packfile.NewFromFile(fs, p)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, pack.Close())
+			})
+			require.NoError(t, pack.Verify())
+			assert.Equal(t, res.ID, pack.ID())
+			assert.Equal(t, uint32(len(objects)), pack.ObjectCount())
+
+			for _, o := range objects {
+				got, err := pack.GetObject(o.ID())
+				require.NoError(t, err, o.ID().String())
+				assert.Equal(t, o.Type(), got.Type())
+				assert.Equal(t, o.Bytes(), got.Bytes())
+			}
+
+			err = pack.WalkEntries(func(info *packfile.EntryInfo) error {
+				assert.LessOrEqual(t, info.Depth, tc.opts.Depth)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+	assert.Less(t, sizes["with deltas"], sizes["no deltas"])
+	assert.Less(t, sizes["with reused deltas"], sizes["no deltas"])
+}
+
+func TestBuildDuplicates(t *testing.T) {
+	t.Parallel()
+
+	o := object.New(object.TypeBlob, []byte("content"))
+	res, err := packfile.Build([]*packfile.BuildObject{
+		{Object: o},
+		{Object: o},
+	}, packfile.BuildOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.ObjectCount)
+}
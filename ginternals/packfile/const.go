@@ -4,4 +4,10 @@ package packfile
 const (
 	ExtPackfile = ".pack"
 	ExtIndex    = ".idx"
+	// ExtPromisor is the extension of the file marking a packfile
+	// as coming from a promisor remote
+	ExtPromisor = ".promisor"
+	// ExtKeep is the extension of the file marking a packfile as
+	// kept, meaning it must never be deleted by a repack or a prune
+	ExtKeep = ".keep"
 )
@@ -0,0 +1,263 @@
+package packfile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// EntryInfo contains the information about how an object is stored
+// in a packfile
+type EntryInfo struct {
+	ID ginternals.Oid
+	// Type contains the type of the entry as stored in the packfile.
+	// It's either ObjectDeltaRef or ObjectDeltaOFS if the object
+	// is deltified
+	Type object.Type
+	// Size contains the size of the inflated data of the entry. For
+	// a deltified object, this is the size of the delta
+	Size uint64
+	// PackedSize contains the size of the entry in the packfile,
+	// headers included
+	PackedSize uint64
+	// Offset contains the offset of the entry in the packfile
+	Offset uint64
+	// Depth contains the length of the delta chain of the object.
+	// 0 if the object is not deltified
+	Depth int
+	// BaseID contains the ID of the base object if the object is
+	// deltified, NullOid otherwise
+	BaseID ginternals.Oid
+}
+
+// EntryWalkFunc represents a function that will be applied on all the
+// entries found by WalkEntries()
+type EntryWalkFunc = func(info *EntryInfo) error
+
+// WalkEntries walks over all the entries of the packfile, sorted by
+// offset.
+// Only the headers of the entries are read, their data is never
+// decompressed, which makes it much cheaper than Verify().
+// Returning OidWalkStop from f stops the walk without error
+func (pck *Pack) WalkEntries(f EntryWalkFunc) error {
+	entries, err := pck.entries()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err = f(e); err != nil {
+			if err == OidWalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// EntryInfo returns the information about how the object that has
+// the given oid is stored in the packfile.
+// Only the headers of the entry and of its delta chain are read.
+// If the object is not found ginternals.ErrObjectNotFound is returned
+func (pck *Pack) EntryInfo(oid ginternals.Oid) (*EntryInfo, error) {
+	pck.mu.Lock()
+	defer pck.mu.Unlock()
+
+	offset, err := pck.idx.GetObjectOffset(oid)
+	if err != nil {
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return nil, fmt.Errorf("could not get object index: %w", err)
+		}
+		return nil, err
+	}
+
+	// The data of an entry ends where the next entry starts, and the
+	// last entry ends before the footer
+	end, ok, err := pck.idx.objectEnd(offset)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the end of object %s: %w", oid.String(), err)
+	}
+	if !ok {
+		end = uint64(pck.size - ginternals.OidSize)
+	}
+	if offset < packfileHeaderSize || offset >= end {
+		return nil, fmt.Errorf("object %s has an invalid offset %d: %w", oid.String(), offset, ErrIntOverflow)
+	}
+
+	h, err := pck.readEntryHeader(offset)
+	if err != nil {
+		return nil, fmt.Errorf("could not read object %s: %w", oid.String(), err)
+	}
+	info := &EntryInfo{
+		ID:         oid,
+		Type:       h.typ,
+		Size:       h.size,
+		PackedSize: end - offset,
+		Offset:     offset,
+	}
+	switch h.typ { //nolint:exhaustive // only the deltas have a base
+	case object.ObjectDeltaRef:
+		info.BaseID = h.baseOid
+	case object.ObjectDeltaOFS:
+		baseID, ok, err := pck.idx.oidAt(h.baseOffset)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the base of %s: %w", oid.String(), err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("no base object at offset %d for %s: %w", h.baseOffset, oid.String(), ginternals.ErrObjectNotFound)
+		}
+		info.BaseID = baseID
+	}
+
+	// We follow the chain until we reach an object that isn't a delta.
+	// A chain can't be longer than the number of objects
+	for isDelta(h.typ) {
+		if uint32(info.Depth) > pck.ObjectCount() {
+			return nil, fmt.Errorf("delta chain loop for %s: %w", oid.String(), ErrInvalidObjectSize)
+		}
+		baseOffset := h.baseOffset
+		if h.typ == object.ObjectDeltaRef {
+			if baseOffset, err = pck.idx.GetObjectOffset(h.baseOid); err != nil {
+				return nil, fmt.Errorf("could not find base object %s: %w", h.baseOid.String(), err)
+			}
+		}
+		if h, err = pck.readEntryHeader(baseOffset); err != nil {
+			return nil, fmt.Errorf("could not read the base object at offset %d: %w", baseOffset, err)
+		}
+		info.Depth++
+	}
+	return info, nil
+}
+
+// RawDelta returns the ID of the base and the inflated delta of the
+// given object, as stored in the packfile, so the delta can be
+// reused without being computed again.
+// A NullOid and a nil delta are returned if the object is not
+// deltified.
+// If the object is not found ginternals.ErrObjectNotFound is returned
+func (pck *Pack) RawDelta(oid ginternals.Oid) (baseID ginternals.Oid, delta []byte, err error) {
+	pck.mu.Lock()
+	defer pck.mu.Unlock()
+
+	offset, err := pck.idx.GetObjectOffset(oid)
+	if err != nil {
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return ginternals.NullOid, nil, fmt.Errorf("could not get object index: %w", err)
+		}
+		return ginternals.NullOid, nil, err
+	}
+	o, baseID, baseOffset, err := pck.getRawObjectAt(offset)
+	if err != nil {
+		return ginternals.NullOid, nil, fmt.Errorf("could not read object %s: %w", oid.String(), err)
+	}
+	switch o.Type() { //nolint:exhaustive // only the deltas have a base
+	case object.ObjectDeltaRef:
+		return baseID, o.Bytes(), nil
+	case object.ObjectDeltaOFS:
+		baseID, ok, err := pck.idx.oidAt(baseOffset)
+		if err != nil {
+			return ginternals.NullOid, nil, fmt.Errorf("could not get the base of %s: %w", oid.String(), err)
+		}
+		if !ok {
+			return ginternals.NullOid, nil, fmt.Errorf("no base object at offset %d for %s: %w", baseOffset, oid.String(), ginternals.ErrObjectNotFound)
+		}
+		return baseID, o.Bytes(), nil
+	}
+	return ginternals.NullOid, nil, nil
+}
+
+// entries returns the information about all the entries of the
+// packfile, sorted by offset
+func (pck *Pack) entries() ([]*EntryInfo, error) {
+	pck.mu.Lock()
+	defer pck.mu.Unlock()
+
+	offsets, err := pck.idx.offsets()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*EntryInfo, 0, len(offsets))
+	oidAt := make(map[uint64]ginternals.Oid, len(offsets))
+	for oid, offset := range offsets {
+		entries = append(entries, &EntryInfo{
+			ID:     oid,
+			Offset: offset,
+		})
+		oidAt[offset] = oid
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Offset < entries[j].Offset
+	})
+
+	// The bases of the ObjectDeltaRef entries may come after the
+	// deltas, so we can't compute the depths in a single pass
+	headers := make(map[uint64]*entryHeader, len(entries))
+	for i, e := range entries {
+		// The data of an entry ends where the next entry starts, and
+		// the last entry ends before the footer
+		end := uint64(pck.size - ginternals.OidSize)
+		if i+1 < len(entries) {
+			end = entries[i+1].Offset
+		}
+		if e.Offset < packfileHeaderSize || e.Offset >= end {
+			return nil, fmt.Errorf("object %s has an invalid offset %d: %w", e.ID.String(), e.Offset, ErrIntOverflow)
+		}
+		e.PackedSize = end - e.Offset
+
+		h, err := pck.readEntryHeader(e.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not read object %s: %w", e.ID.String(), err)
+		}
+		headers[e.Offset] = h
+		e.Type = h.typ
+		e.Size = h.size
+		switch h.typ { //nolint:exhaustive // only the deltas have a base
+		case object.ObjectDeltaRef:
+			e.BaseID = h.baseOid
+		case object.ObjectDeltaOFS:
+			e.BaseID = oidAt[h.baseOffset]
+		}
+	}
+
+	depths := make(map[uint64]int, len(entries))
+	var depthAt func(offset uint64, seen int) (int, error)
+	depthAt = func(offset uint64, seen int) (int, error) {
+		if depth, ok := depths[offset]; ok {
+			return depth, nil
+		}
+		// A chain can't be longer than the number of objects
+		if seen > len(entries) {
+			return 0, fmt.Errorf("delta chain loop at offset %d: %w", offset, ErrInvalidObjectSize)
+		}
+		h, ok := headers[offset]
+		if !ok {
+			return 0, fmt.Errorf("no object at offset %d: %w", offset, ginternals.ErrObjectNotFound)
+		}
+		depth := 0
+		if isDelta(h.typ) {
+			baseOffset := h.baseOffset
+			if h.typ == object.ObjectDeltaRef {
+				var err error
+				if baseOffset, err = pck.idx.GetObjectOffset(h.baseOid); err != nil {
+					return 0, fmt.Errorf("could not find base object %s: %w", h.baseOid.String(), err)
+				}
+			}
+			baseDepth, err := depthAt(baseOffset, seen+1)
+			if err != nil {
+				return 0, err
+			}
+			depth = baseDepth + 1
+		}
+		depths[offset] = depth
+		return depth, nil
+	}
+	for _, e := range entries {
+		if e.Depth, err = depthAt(e.Offset, 0); err != nil {
+			return nil, fmt.Errorf("could not resolve the delta chain of %s: %w", e.ID.String(), err)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package packfile_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkEntries(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+
+	report, err := packfile.Verify(afero.NewOsFs(), packFilePath)
+	require.NoError(t, err)
+
+	pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, pack.Close())
+	})
+
+	// The entries should match what Verify found, without having to
+	// resolve the objects
+	entries := []*packfile.EntryInfo{}
+	err = pack.WalkEntries(func(info *packfile.EntryInfo) error {
+		entries = append(entries, info)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, len(report.Objects))
+	for i, o := range report.Objects {
+		e := entries[i]
+		assert.Equal(t, o.ID, e.ID)
+		assert.Equal(t, o.Offset, e.Offset)
+		assert.Equal(t, o.PackedSize, e.PackedSize)
+		assert.Equal(t, o.Size, e.Size)
+		assert.Equal(t, o.Depth, e.Depth)
+		assert.Equal(t, o.Base, e.BaseID)
+		if o.Depth == 0 {
+			assert.Equal(t, o.Type, e.Type)
+		} else {
+			assert.Contains(t, []object.Type{object.ObjectDeltaOFS, object.ObjectDeltaRef}, e.Type)
+		}
+	}
+
+	t.Run("OidWalkStop should stop the walk", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := pack.WalkEntries(func(info *packfile.EntryInfo) error {
+			count++
+			return packfile.OidWalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestEntryInfo(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+
+	pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, pack.Close())
+	})
+
+	t.Run("should match the entries of the pack", func(t *testing.T) {
+		t.Parallel()
+
+		err := pack.WalkEntries(func(expected *packfile.EntryInfo) error {
+			info, err := pack.EntryInfo(expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, expected, info)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("missing object should fail", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("2dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		_, err = pack.EntryInfo(oid)
+		require.ErrorIs(t, err, ginternals.ErrObjectNotFound)
+	})
+}
@@ -0,0 +1,215 @@
+package packfile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/delta"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// indexedEntry contains the state of an entry of a packfile being
+// indexed
+type indexedEntry struct {
+	header *entryHeader
+	offset uint64
+	crc    uint32
+	// data contains the inflated data of the entry, which is a
+	// delta for the deltified objects
+	data []byte
+	// object is set once the entry has been resolved
+	object *object.Object
+	// chainLength contains the length of the delta chain of the
+	// entry, once resolved. 0 if the entry is not a delta
+	chainLength int
+}
+
+// BuildIndex returns the index of the given packfile, the same way
+// git index-pack does. This is needed for the packfiles that are
+// downloaded without their index.
+// The packfile must be self-contained: the bases of all its deltas
+// must be in the packfile
+func BuildIndex(packData []byte) (idx []byte, err error) {
+	return BuildIndexWithLimits(packData, object.Limits{})
+}
+
+// BuildIndexWithLimits returns the index of the given packfile, like
+// BuildIndex, making sure the objects of the packfile don't exceed
+// the given limits. Only Limits.MaxDeltaDepth and
+// Limits.MaxObjectSize are used.
+// object.ErrLimitExceeded is returned if a limit is reached
+func BuildIndexWithLimits(packData []byte, limits object.Limits) (idx []byte, err error) {
+	size := len(packData)
+	if size < packfileHeaderSize+ginternals.OidSize {
+		return nil, fmt.Errorf("packfile too small: %w", ErrInvalidMagic)
+	}
+	if !bytes.Equal(packData[0:4], packfileMagic()) {
+		return nil, fmt.Errorf("invalid header: %w", ErrInvalidMagic)
+	}
+	if !bytes.Equal(packData[4:8], packfileVersion()) {
+		return nil, fmt.Errorf("invalid header: %w", ErrInvalidVersion)
+	}
+	footer := packData[size-ginternals.OidSize:]
+	sum := sha1.Sum(packData[:size-ginternals.OidSize]) //nolint:gosec // sha1 is used by git
+	if !bytes.Equal(sum[:], footer) {
+		return nil, fmt.Errorf("invalid packfile footer: %w", ErrChecksumMismatch)
+	}
+
+	pck := &Pack{
+		src:    bytes.NewReader(packData),
+		size:   int64(size),
+		limits: limits,
+	}
+	count := binary.BigEndian.Uint32(packData[8:packfileHeaderSize])
+	// Every entry is at least 1 byte long, we cannot trust a count
+	// that is bigger than the file when allocating memory
+	if uint64(count) > uint64(size) {
+		return nil, fmt.Errorf("packfile too small to contain %d objects: %w", count, ErrInvalidObjectSize)
+	}
+	entries := make([]*indexedEntry, 0, count)
+	byOffset := make(map[uint64]*indexedEntry, count)
+	offset := uint64(packfileHeaderSize)
+	for i := uint32(0); i < count; i++ {
+		if offset >= uint64(size-ginternals.OidSize) {
+			return nil, fmt.Errorf("expected %d objects, got %d: %w", count, i, ErrInvalidObjectSize)
+		}
+		h, err := pck.readEntryHeader(offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the entry at offset %d: %w", offset, err)
+		}
+		if err = limits.CheckObjectSize(h.size); err != nil {
+			return nil, fmt.Errorf("could not read the entry at offset %d: %w", offset, err)
+		}
+		data, end, err := inflateAt(packData, h)
+		if err != nil {
+			return nil, fmt.Errorf("could not inflate the entry at offset %d: %w", offset, err)
+		}
+		e := &indexedEntry{
+			header: h,
+			offset: offset,
+			crc:    crc32.ChecksumIEEE(packData[offset:end]),
+			data:   data,
+		}
+		entries = append(entries, e)
+		byOffset[offset] = e
+		offset = end
+	}
+	if offset != uint64(size-ginternals.OidSize) {
+		return nil, fmt.Errorf("unexpected data after the last object: %w", ErrInvalidObjectSize)
+	}
+
+	// The ref deltas can reference any object of the pack, so we
+	// need to know the ID of all the non-deltified objects first
+	byID := make(map[ginternals.Oid]*indexedEntry, count)
+	for _, e := range entries {
+		if !isDelta(e.header.typ) {
+			e.object = object.New(e.header.typ, e.data)
+			byID[e.object.ID()] = e
+		}
+	}
+	for _, e := range entries {
+		if err = pck.resolveEntry(e, byOffset, byID, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	buildEntries := make([]*buildEntry, len(entries))
+	for i, e := range entries {
+		buildEntries[i] = &buildEntry{
+			obj:    &BuildObject{Object: e.object},
+			offset: e.offset,
+			crc:    e.crc,
+		}
+	}
+	return buildIndex(buildEntries, footer, maxSmallOffset), nil
+}
+
+// zlibMaxRatio is the maximum compression ratio of zlib, which means
+// n bytes of compressed data can never be more than n*zlibMaxRatio
+// bytes once inflated
+const zlibMaxRatio = 1032
+
+// inflateAt inflates the data of the given entry, and returns the
+// offset of the end of the entry
+func inflateAt(packData []byte, h *entryHeader) (data []byte, end uint64, err error) {
+	// bytes.Reader implements io.ByteReader, so the zlib reader
+	// won't read past the end of the entry
+	r := bytes.NewReader(packData[h.dataOffset:])
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not get zlib reader: %w", err)
+	}
+	// We don't trust the size of the object when allocating memory if
+	// the compressed data cannot be that big
+	if maxSize := uint64(len(packData)-int(h.dataOffset)) * zlibMaxRatio; h.size > maxSize {
+		return nil, 0, fmt.Errorf("object size %d too big for its data: %w", h.size, ErrInvalidObjectSize)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, h.size))
+	// the data is read until EOF so the checksum of the zlib stream
+	// is consumed
+	if _, err = io.Copy(buf, zr); err != nil {
+		return nil, 0, fmt.Errorf("could not decompress: %w", err)
+	}
+	if err = zr.Close(); err != nil {
+		return nil, 0, fmt.Errorf("could not close the zlib reader: %w", err)
+	}
+	if uint64(buf.Len()) != h.size {
+		return nil, 0, fmt.Errorf("object size not valid. expecting %d, got %d: %w", h.size, buf.Len(), ErrInvalidObjectSize)
+	}
+	consumed := len(packData[h.dataOffset:]) - r.Len()
+	return buf.Bytes(), h.dataOffset + uint64(consumed), nil
+}
+
+// resolveEntry sets the object of the given entry, resolving its
+// delta chain if needed
+func (pck *Pack) resolveEntry(e *indexedEntry, byOffset map[uint64]*indexedEntry, byID map[ginternals.Oid]*indexedEntry, depth int) error {
+	if e.object != nil {
+		return nil
+	}
+	if depth > maxDeltaDepth {
+		return fmt.Errorf("delta chain of the entry at offset %d is too long: %w", e.offset, ErrIntOverflow)
+	}
+
+	var base *indexedEntry
+	switch e.header.typ { //nolint:exhaustive // only deltas are unresolved
+	case object.ObjectDeltaOFS:
+		base = byOffset[e.header.baseOffset]
+	case object.ObjectDeltaRef:
+		base = byID[e.header.baseOid]
+	}
+	if base == nil {
+		return fmt.Errorf("base of the delta at offset %d not in packfile: %w", e.offset, ginternals.ErrObjectNotFound)
+	}
+	if err := checkDeltaChain(pck.limits, depth+1); err != nil {
+		return fmt.Errorf("could not resolve the delta at offset %d: %w", e.offset, err)
+	}
+	if err := pck.resolveEntry(base, byOffset, byID, depth+1); err != nil {
+		return err
+	}
+	e.chainLength = base.chainLength + 1
+	if err := checkDeltaChain(pck.limits, e.chainLength); err != nil {
+		return fmt.Errorf("could not resolve the delta at offset %d: %w", e.offset, err)
+	}
+	targetSize, err := pck.readDeltaTargetSize(e.data)
+	if err != nil {
+		return fmt.Errorf("invalid delta at offset %d: %w", e.offset, err)
+	}
+	if err = pck.limits.CheckObjectSize(targetSize); err != nil {
+		return fmt.Errorf("could not apply the delta at offset %d: %w", e.offset, err)
+	}
+	data, err := delta.Apply(base.object.Bytes(), e.data)
+	if err != nil {
+		return fmt.Errorf("could not apply the delta at offset %d: %w", e.offset, err)
+	}
+	e.object = object.New(base.object.Type(), data)
+	byID[e.object.ID()] = e
+	// The delta is not needed anymore
+	e.data = nil
+	return nil
+}
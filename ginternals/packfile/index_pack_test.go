@@ -0,0 +1,104 @@
+package packfile_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildIndex(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should match the index of git", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+
+		fs := afero.NewOsFs()
+		packPath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+		idxPath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.idx")
+		pack, err := afero.ReadFile(fs, packPath)
+		require.NoError(t, err)
+		expected, err := afero.ReadFile(fs, idxPath)
+		require.NoError(t, err)
+
+		idx, err := packfile.BuildIndex(pack)
+		require.NoError(t, err)
+		assert.Equal(t, expected, idx)
+	})
+
+	t.Run("should index the packfiles of Build()", func(t *testing.T) {
+		t.Parallel()
+
+		objects := []*packfile.BuildObject{}
+		for i := 0; i < 10; i++ {
+			content := bytes.Repeat([]byte("line of a blob\n"), 20+i)
+			objects = append(objects, &packfile.BuildObject{
+				Object: object.New(object.TypeBlob, content),
+				Path:   "file",
+			})
+		}
+		res, err := packfile.Build(objects, packfile.BuildOptions{
+			Window: packfile.DefaultWindow,
+			Depth:  packfile.DefaultDepth,
+		})
+		require.NoError(t, err)
+		require.NotZero(t, res.Deltas)
+
+		idx, err := packfile.BuildIndex(res.Pack)
+		require.NoError(t, err)
+		assert.Equal(t, res.Index, idx)
+	})
+
+	t.Run("should fail on corrupted packfiles", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := packfile.Build([]*packfile.BuildObject{
+			{Object: object.New(object.TypeBlob, []byte("content"))},
+		}, packfile.BuildOptions{})
+		require.NoError(t, err)
+
+		pack := append([]byte{}, res.Pack...)
+		pack[len(pack)-ginternals.OidSize-1]++
+		_, err = packfile.BuildIndex(pack)
+		require.ErrorIs(t, err, packfile.ErrChecksumMismatch)
+	})
+	t.Run("should enforce the limits", func(t *testing.T) {
+		t.Parallel()
+
+		objects := []*packfile.BuildObject{}
+		for i := 0; i < 10; i++ {
+			content := bytes.Repeat([]byte("line of a blob\n"), 20+i)
+			objects = append(objects, &packfile.BuildObject{
+				Object: object.New(object.TypeBlob, content),
+				Path:   "file",
+			})
+		}
+		res, err := packfile.Build(objects, packfile.BuildOptions{
+			Window: packfile.DefaultWindow,
+			Depth:  packfile.DefaultDepth,
+		})
+		require.NoError(t, err)
+		require.NotZero(t, res.Deltas)
+
+		idx, err := packfile.BuildIndexWithLimits(res.Pack, object.UntrustedLimits())
+		require.NoError(t, err)
+		assert.Equal(t, res.Index, idx)
+
+		_, err = packfile.BuildIndexWithLimits(res.Pack, object.Limits{MaxObjectSize: 100})
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+
+		_, err = packfile.BuildIndexWithLimits(res.Pack, object.Limits{MaxDeltaDepth: 1})
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+	})
+}
@@ -0,0 +1,50 @@
+package packfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidInfoPacks is an error thrown when the content of an
+// objects/info/packs file cannot be parsed
+var ErrInvalidInfoPacks = errors.New("invalid info/packs file")
+
+// ParseInfoPacks parses the content of an objects/info/packs file,
+// and returns the names of the packfiles it lists.
+// The file contains one "P pack-<sha>.pack" line per packfile, and
+// ends with an empty line. Like git, the lines starting with an
+// unknown letter are ignored
+func ParseInfoPacks(data []byte) ([]string, error) {
+	names := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "P ") {
+			continue
+		}
+		name := strings.TrimSpace(line[2:])
+		if filepath.Ext(name) != ExtPackfile || strings.ContainsAny(name, "/\\") {
+			return nil, fmt.Errorf("invalid packfile name %q: %w", name, ErrInvalidInfoPacks)
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the file: %w", err)
+	}
+	return names, nil
+}
+
+// FormatInfoPacks returns the content of an objects/info/packs file
+// listing the given packfiles
+func FormatInfoPacks(names []string) []byte {
+	b := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(b, "P %s\n", name)
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
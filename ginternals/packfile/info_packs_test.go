@@ -0,0 +1,74 @@
+package packfile_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInfoPacks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		data          string
+		expected      []string
+		expectedError error
+	}{
+		{
+			desc:     "valid file",
+			data:     "P pack-0163931160835b1de2f120e1aa7e52206debeb14.pack\nP pack-a.pack\n\n",
+			expected: []string{"pack-0163931160835b1de2f120e1aa7e52206debeb14.pack", "pack-a.pack"},
+		},
+		{
+			desc:     "empty file",
+			data:     "",
+			expected: []string{},
+		},
+		{
+			desc:     "unknown lines are ignored",
+			data:     "D pack-a.pack pack-b.pack\nT pack-a.pack\nP pack-a.pack\n\n",
+			expected: []string{"pack-a.pack"},
+		},
+		{
+			desc:          "invalid extension",
+			data:          "P pack-a.idx\n",
+			expectedError: packfile.ErrInvalidInfoPacks,
+		},
+		{
+			desc:          "path instead of a name",
+			data:          "P ../pack-a.pack\n",
+			expectedError: packfile.ErrInvalidInfoPacks,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			names, err := packfile.ParseInfoPacks([]byte(tc.data))
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestFormatInfoPacks(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"pack-a.pack", "pack-b.pack"}
+	data := packfile.FormatInfoPacks(names)
+	assert.Equal(t, "P pack-a.pack\nP pack-b.pack\n\n", string(data))
+
+	parsed, err := packfile.ParseInfoPacks(data)
+	require.NoError(t, err)
+	assert.Equal(t, names, parsed)
+}
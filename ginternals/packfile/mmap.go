@@ -0,0 +1,36 @@
+package packfile
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// mmapFile maps the content of f in memory, read-only.
+// size is the size of the file, or -1 if it's unknown.
+// nil is returned if the file cannot be mapped, in which case it
+// should be read normally
+func mmapFile(f afero.File, size int64) []byte {
+	// Only files that are on disk can be mapped
+	osFile, ok := f.(*os.File)
+	if !ok {
+		return nil
+	}
+	if size < 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return nil
+		}
+		size = info.Size()
+	}
+	// Empty files cannot be mapped, and the size needs to fit in
+	// an int
+	if size <= 0 || int64(int(size)) != size {
+		return nil
+	}
+	data, err := mmap(osFile, int(size))
+	if err != nil {
+		return nil
+	}
+	return data
+}
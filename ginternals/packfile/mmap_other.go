@@ -0,0 +1,23 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package packfile
+
+import (
+	"errors"
+	"os"
+)
+
+// errMmapUnsupported is returned when the platform doesn't support
+// mmap
+var errMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+// mmap always fails since the platform doesn't support mmap
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+// munmap does nothing since nothing can be mapped on this platform
+func munmap(data []byte) error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package packfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/internal/zlibutil"
+)
+
+// deltaHeaderMaxSize is the maximum size of the header of a delta,
+// which contains the size of the base and the size of the target.
+// Each size is a 64 bits int, stored using 7 bits per byte
+const deltaHeaderMaxSize = 2 * 10
+
+// ObjectInfo returns the type and the size of the object that has
+// the given oid, without resolving the object.
+// For a deltified object, the headers of the delta chain are read to
+// find the type, and only the beginning of the delta is decompressed
+// to find the size.
+// If the object is not found ginternals.ErrObjectNotFound is returned
+func (pck *Pack) ObjectInfo(oid ginternals.Oid) (typ object.Type, size uint64, err error) {
+	pck.mu.Lock()
+	defer pck.mu.Unlock()
+
+	offset, err := pck.idx.GetObjectOffset(oid)
+	if err != nil {
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return 0, 0, fmt.Errorf("could not get object index: %w", err)
+		}
+		return 0, 0, err
+	}
+	h, err := pck.readEntryHeader(offset)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not read object %s: %w", oid.String(), err)
+	}
+	if !isDelta(h.typ) {
+		return h.typ, h.size, nil
+	}
+
+	if size, err = pck.deltaTargetSize(h); err != nil {
+		return 0, 0, fmt.Errorf("could not get the size of %s: %w", oid.String(), err)
+	}
+	// The type of the object is the type of the base at the end of
+	// the chain. A chain can't be longer than the number of objects
+	for i := uint32(0); isDelta(h.typ); i++ {
+		if i > pck.ObjectCount() {
+			return 0, 0, fmt.Errorf("delta chain loop for %s: %w", oid.String(), ErrInvalidObjectSize)
+		}
+		baseOffset := h.baseOffset
+		if h.typ == object.ObjectDeltaRef {
+			if baseOffset, err = pck.idx.GetObjectOffset(h.baseOid); err != nil {
+				return 0, 0, fmt.Errorf("could not get offset of base object %s: %w", h.baseOid.String(), err)
+			}
+		}
+		if h, err = pck.readEntryHeader(baseOffset); err != nil {
+			return 0, 0, fmt.Errorf("could not read the base object at offset %d: %w", baseOffset, err)
+		}
+	}
+	return h.typ, size, nil
+}
+
+// deltaTargetSize returns the size of the object created by the given
+// delta, by only decompressing the header of the delta
+func (pck *Pack) deltaTargetSize(h *entryHeader) (size uint64, err error) {
+	if int64(h.dataOffset) >= pck.size {
+		return 0, fmt.Errorf("object data offset %d is out of bound: %w", h.dataOffset, ErrIntOverflow)
+	}
+	zlibR, err := zlibutil.NewReader(io.NewSectionReader(pck.src, int64(h.dataOffset), pck.size-int64(h.dataOffset)))
+	if err != nil {
+		return 0, fmt.Errorf("could not get zlib reader: %w", err)
+	}
+	defer errutil.Close(zlibR, &err)
+
+	// The delta may be smaller than the max size of its header
+	header := make([]byte, deltaHeaderMaxSize)
+	n, err := io.ReadFull(zlibR, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("could not decompress the delta: %w", err)
+	}
+	header = header[:n]
+
+	return pck.readDeltaTargetSize(header)
+}
+
+// readDeltaTargetSize returns the size of the object created by the
+// delta starting with the given header.
+// The format of the header is:
+// - the size of the source (x bytes)
+// - the size of the target (x bytes)
+func (pck *Pack) readDeltaTargetSize(header []byte) (size uint64, err error) {
+	if len(header) == 0 {
+		return 0, fmt.Errorf("empty delta: %w", ErrInvalidObjectSize)
+	}
+	_, sourceSizeLen, err := pck.readSize(header)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read source size of delta: %w", err)
+	}
+	if sourceSizeLen == len(header) {
+		return 0, fmt.Errorf("no target size in delta: %w", ErrInvalidObjectSize)
+	}
+	size, _, err = pck.readSize(header[sourceSizeLen:])
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read target size of delta: %w", err)
+	}
+	return size, nil
+}
+
+// isDelta returns whether the given type is a delta type
+func isDelta(typ object.Type) bool {
+	return typ == object.ObjectDeltaRef || typ == object.ObjectDeltaOFS
+}
@@ -0,0 +1,58 @@
+package packfile_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectInfo(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+
+	pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, pack.Close())
+	})
+
+	t.Run("should match the resolved objects", func(t *testing.T) {
+		t.Parallel()
+
+		// The pack contains deltas up to a depth of 5, so this covers
+		// both the regular objects and the delta chains
+		count := 0
+		err := pack.WalkOids(func(oid ginternals.Oid) error {
+			count++
+			o, err := pack.GetObject(oid)
+			require.NoError(t, err)
+
+			typ, size, err := pack.ObjectInfo(oid)
+			require.NoError(t, err)
+			assert.Equal(t, o.Type(), typ, oid.String())
+			assert.Equal(t, uint64(o.Size()), size, oid.String())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int(pack.ObjectCount()), count)
+	})
+
+	t.Run("missing object should fail", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("2dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		_, _, err = pack.ObjectInfo(oid)
+		require.ErrorIs(t, err, ginternals.ErrObjectNotFound)
+	})
+}
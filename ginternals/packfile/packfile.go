@@ -4,7 +4,7 @@ package packfile
 import (
 	"bufio"
 	"bytes"
-	"compress/zlib"
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -12,11 +12,16 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/debuglog"
+	"github.com/Nivl/git-go/ginternals/delta"
 	"github.com/Nivl/git-go/ginternals/object"
 	"github.com/Nivl/git-go/internal/cache"
 	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/Nivl/git-go/internal/zlibutil"
 	"github.com/spf13/afero"
 )
 
@@ -26,6 +31,10 @@ const (
 	// version, and the last 4 bytes contains the number of objects in
 	// the packfile, for a total of 12 bytes
 	packfileHeaderSize = 12
+
+	// maxDeltaDepth is the longest delta chain we accept, which
+	// protects against cycles in corrupted packfiles
+	maxDeltaDepth = 10000
 )
 
 func packfileMagic() []byte {
@@ -49,6 +58,9 @@ var (
 	// ErrInvalidObjectSize represents a object which size doesn't
 	// match the expected size
 	ErrInvalidObjectSize = errors.New("invalid object")
+	// ErrChecksumMismatch is an error thrown when the SHA stored in the
+	// footer of a packfile doesn't match its content
+	ErrChecksumMismatch = errors.New("checksum mismatch")
 )
 
 // Pack represents a Packfile
@@ -83,10 +95,20 @@ var (
 //         Contains the SHA1 sum of the packfile (without this SHA)
 // https://github.com/git/git/blob/master/Documentation/technical/pack-format.txt
 type Pack struct {
+	path    string
 	r       afero.File
 	idxFile afero.File
 	idx     *PackIndex
 
+	// src is used to read the content of the packfile. It's either
+	// the file itself, or its content mapped in memory
+	src  io.ReaderAt
+	size int64
+	// data and idxData contain the content of the packfile and of
+	// its index when they are mapped in memory
+	data    []byte
+	idxData []byte
+
 	// baseObjectCache is a cache for all the base objects.
 	// We only cache the base objects for 2 reasons:
 	// - Base objects are fetched more often than "regular" objects since
@@ -99,14 +121,54 @@ type Pack struct {
 	id     ginternals.Oid
 	header [packfileHeaderSize]byte
 
+	// verifyCRC is set when the CRC of the objects should be checked
+	// when they are read
+	verifyCRC bool
+	// limits contains the limits enforced when reading the objects
+	limits object.Limits
+
 	// Mutex used to protect the exported methods from being called
 	// concurrently
 	mu sync.Mutex
 }
 
+// Options represents the optional params that can be used to open
+// a packfile
+type Options struct {
+	// Mmap maps the packfile and its index in memory instead of
+	// reading them using syscalls, which is faster when a lot of
+	// objects are read.
+	// The files are read normally if they cannot be mapped (ex. the
+	// platform doesn't support mmap, or the files are not on disk).
+	// Defaults to false
+	Mmap bool
+	// VerifyCRC makes sure the packed data of an object match the
+	// CRC stored in the index every time the object is read from
+	// the packfile, so corrupted data are detected before being
+	// decompressed. The objects kept in cache are not verified again.
+	// Defaults to false
+	VerifyCRC bool
+	// Limits contains the limits enforced when reading the objects.
+	// Only Limits.MaxDeltaDepth and Limits.MaxObjectSize are used.
+	// Defaults to no limits
+	Limits object.Limits
+	// Logger receives an event once the packfile is loaded, with
+	// the time it took to parse its index.
+	// Defaults to nothing
+	Logger debuglog.Logger
+}
+
 // NewFromFile returns a pack object from the given file
 // The pack will need to be closed using Close()
 func NewFromFile(fs afero.Fs, filePath string) (pack *Pack, err error) {
+	return NewFromFileWithOptions(fs, filePath, Options{})
+}
+
+// NewFromFileWithOptions returns a pack object from the given file,
+// using the provided options
+// The pack will need to be closed using Close()
+func NewFromFileWithOptions(fs afero.Fs, filePath string, opts Options) (pack *Pack, err error) {
+	start := time.Now()
 	f, err := fs.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("could not open %s: %w", filePath, err)
@@ -122,12 +184,32 @@ func NewFromFile(fs afero.Fs, filePath string) (pack *Pack, err error) {
 		return nil, fmt.Errorf("could not create LRU cache: %w", err)
 	}
 	p := &Pack{
+		path:            filePath,
 		r:               f,
+		src:             f,
 		baseObjectCache: c,
+		verifyCRC:       opts.VerifyCRC,
+		limits:          opts.Limits,
+	}
+	defer func() {
+		if err != nil {
+			munmap(p.data) //nolint:errcheck // it already failed
+		}
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat %s: %w", filePath, err)
+	}
+	p.size = info.Size()
+	if opts.Mmap {
+		if p.data = mmapFile(f, p.size); p.data != nil {
+			p.src = bytes.NewReader(p.data)
+		}
 	}
 
 	// Let's validate the header
-	_, err = f.ReadAt(p.header[:], 0)
+	_, err = p.src.ReadAt(p.header[:], 0)
 	if err != nil {
 		return nil, fmt.Errorf("could read header of packfile: %w", err)
 	}
@@ -140,11 +222,7 @@ func NewFromFile(fs afero.Fs, filePath string) (pack *Pack, err error) {
 
 	// Let's find the ID of the packfile (last element of the file)
 	id := make([]byte, ginternals.OidSize)
-	offset, err := f.Seek(-ginternals.OidSize, os.SEEK_END)
-	if err != nil {
-		return nil, fmt.Errorf("could not get to the offset of the ID: %w", err)
-	}
-	if _, err = f.ReadAt(id, offset); err != nil {
+	if _, err = p.src.ReadAt(id, p.size-ginternals.OidSize); err != nil {
 		return nil, fmt.Errorf("could not read the ID: %w", err)
 	}
 	p.id, err = ginternals.NewOidFromHex(id)
@@ -154,7 +232,7 @@ func NewFromFile(fs afero.Fs, filePath string) (pack *Pack, err error) {
 
 	// Now we load the index file
 	indexFilePath := strings.TrimSuffix(filePath, ExtPackfile) + ExtIndex
-	p.idxFile, err = os.Open(indexFilePath)
+	p.idxFile, err = os.Open(pathutil.LongPath(indexFilePath))
 	if err != nil {
 		return nil, fmt.Errorf("could not open %s: %w", indexFilePath, err)
 	}
@@ -163,22 +241,55 @@ func NewFromFile(fs afero.Fs, filePath string) (pack *Pack, err error) {
 			p.idxFile.Close() //nolint:errcheck // it already failed
 		}
 	}()
-	p.idx, err = NewIndex(bufio.NewReader(p.idxFile))
+	defer func() {
+		if err != nil {
+			munmap(p.idxData) //nolint:errcheck // it already failed
+		}
+	}()
+	var idxSrc io.Reader = p.idxFile
+	if opts.Mmap {
+		if p.idxData = mmapFile(p.idxFile, -1); p.idxData != nil {
+			idxSrc = bytes.NewReader(p.idxData)
+		}
+	}
+	p.idx, err = NewIndex(bufio.NewReader(idxSrc))
 	if err != nil {
 		return nil, fmt.Errorf("could create index for %s: %w", indexFilePath, err)
 	}
 
+	debuglog.Debug(opts.Logger, "packfile loaded",
+		"path", filePath,
+		"size", p.size,
+		"objects", p.ObjectCount(),
+		"mmap", p.data != nil,
+		"duration", time.Since(start))
 	return p, nil
 }
 
-// getRawObjectAt return the raw object located at the given offset,
-// including its base info if the object is a delta
-func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBaseSHA ginternals.Oid, deltaBaseOffset uint64, err error) {
-	_, err = pck.r.Seek(int64(objectOffset), io.SeekStart)
-	if err != nil {
-		return nil, ginternals.NullOid, 0, fmt.Errorf("could not seek from 0 to object offset %d: %w", objectOffset, err)
+// entryHeader contains the metadata stored before the data of an
+// object in a packfile
+type entryHeader struct {
+	// typ contains the type of the entry, which can be a delta
+	typ object.Type
+	// size contains the size of the inflated data of the entry. For
+	// a delta, it's the size of the delta, not of the object
+	size uint64
+	// baseOid contains the ID of the base object of an ObjectDeltaRef
+	baseOid ginternals.Oid
+	// baseOffset contains the offset of the base object of an
+	// ObjectDeltaOFS
+	baseOffset uint64
+	// dataOffset contains the offset of the zlib data of the entry
+	dataOffset uint64
+}
+
+// readEntryHeader returns the metadata of the object located at the
+// given offset, without decompressing its data
+func (pck *Pack) readEntryHeader(objectOffset uint64) (*entryHeader, error) {
+	if int64(objectOffset) >= pck.size {
+		return nil, fmt.Errorf("object offset %d is out of bound: %w", objectOffset, ErrIntOverflow)
 	}
-	buf := bufio.NewReader(pck.r)
+	buf := bufio.NewReader(io.NewSectionReader(pck.src, int64(objectOffset), pck.size-int64(objectOffset)))
 
 	// parse the metadata of the object
 	// the metadata is X bytes long and contains:
@@ -203,7 +314,7 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 	// Total: 10 bytes
 	metadata, err := buf.Peek(10)
 	if err != nil {
-		return nil, ginternals.NullOid, 0, fmt.Errorf("could not get object meta: %w", err)
+		return nil, fmt.Errorf("could not get object meta: %w", err)
 	}
 
 	// We now need to extract the type of the object. The type is a number
@@ -216,7 +327,7 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 	// >> 4        : 0000_0TTT
 	objectType := object.Type((metadata[0] & 0b_0111_0000) >> 4)
 	if !objectType.IsValid() {
-		return nil, ginternals.NullOid, 0, fmt.Errorf("object type %d: %w", objectType, object.ErrObjectUnknown)
+		return nil, fmt.Errorf("object type %d: %w", objectType, object.ErrObjectUnknown)
 	}
 
 	// The first part of the size is on the last 4 bits of the byte.
@@ -231,7 +342,7 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 	if pck.isMSBSet(metadata[0]) {
 		size, byteRead, err := pck.readSize(metadata[1:])
 		if err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("couldn't read object size: %w", err)
+			return nil, fmt.Errorf("couldn't read object size: %w", err)
 		}
 		metadataSize += byteRead
 		// we add 4bits to the right of $size, then we merge everything with |
@@ -246,7 +357,7 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 	// size), we now need to discard the right amount of bytes to move
 	// our internal cursor to the object data
 	if _, err = buf.Discard(metadataSize); err != nil {
-		return nil, ginternals.NullOid, 0, fmt.Errorf("could not skip the metadata: %w", err)
+		return nil, fmt.Errorf("could not skip the metadata: %w", err)
 	}
 
 	// Some objects are deltified and need extra parsing before getting to
@@ -256,18 +367,20 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 	// There's 2 types of delta:
 	// Refs: This delta contains the SHA of the base object
 	// ofs: This Delta contains a negative offset to the base object
-	var baseObjectOffset uint64
-	var baseObjectOid ginternals.Oid
+	h := &entryHeader{
+		typ:        objectType,
+		size:       objectSize,
+		dataOffset: objectOffset + uint64(metadataSize),
+	}
 	switch objectType { //nolint:exhaustive // only 2 types have a special treatment
 	case object.ObjectDeltaRef:
 		baseObjectSHA := make([]byte, ginternals.OidSize)
-		_, err = buf.Read(baseObjectSHA)
-		if err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("could not get base object SHA: %w", err)
+		if _, err = io.ReadFull(buf, baseObjectSHA); err != nil {
+			return nil, fmt.Errorf("could not get base object SHA: %w", err)
 		}
-		baseObjectOid, err = ginternals.NewOidFromHex(baseObjectSHA)
+		h.baseOid, err = ginternals.NewOidFromHex(baseObjectSHA)
 		if err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("could not parse base object SHA %#v: %w", baseObjectSHA, err)
+			return nil, fmt.Errorf("could not parse base object SHA %#v: %w", baseObjectSHA, err)
 		}
 	case object.ObjectDeltaOFS:
 		// we're assuming the offset is no bigger than 9 bytes to fit an int64.
@@ -275,183 +388,152 @@ func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBas
 		// so we need to read an extra byte
 		offsetParts, err := buf.Peek(9)
 		if err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("could not get base object offset: %w", err)
+			return nil, fmt.Errorf("could not get base object offset: %w", err)
 		}
 		offset, bytesRead, err := pck.readDeltaOffset(offsetParts)
 		if err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("couldn't read base object offset: %w", err)
+			return nil, fmt.Errorf("couldn't read base object offset: %w", err)
+		}
+		if offset == 0 || offset > objectOffset {
+			return nil, fmt.Errorf("base object offset %d is out of bound: %w", offset, ErrIntOverflow)
 		}
-		baseObjectOffset = objectOffset - offset
+		h.baseOffset = objectOffset - offset
+		h.dataOffset += uint64(bytesRead)
+	}
+	return h, nil
+
+}
 
-		// Since we used Peek() because we didn't know the offset size, we
-		// now need to discard the right amount of bytes to move our internal
-		// cursor to the object data
-		if _, err = buf.Discard(bytesRead); err != nil {
-			return nil, ginternals.NullOid, 0, fmt.Errorf("could not skip the offset: %w", err)
+// getRawObjectAt return the raw object located at the given offset,
+// including its base info if the object is a delta
+func (pck *Pack) getRawObjectAt(objectOffset uint64) (o *object.Object, deltaBaseSHA ginternals.Oid, deltaBaseOffset uint64, err error) {
+	if pck.verifyCRC {
+		if err = pck.checkCRC(objectOffset); err != nil {
+			return nil, ginternals.NullOid, 0, fmt.Errorf("could not verify the object: %w", err)
 		}
 	}
+	h, err := pck.readEntryHeader(objectOffset)
+	if err != nil {
+		return nil, ginternals.NullOid, 0, err
+	}
+	if int64(h.dataOffset) >= pck.size {
+		return nil, ginternals.NullOid, 0, fmt.Errorf("object data offset %d is out of bound: %w", h.dataOffset, ErrIntOverflow)
+	}
+	if err = pck.limits.CheckObjectSize(h.size); err != nil {
+		return nil, ginternals.NullOid, 0, fmt.Errorf("could not read the object at offset %d: %w", objectOffset, err)
+	}
+	buf := bufio.NewReader(io.NewSectionReader(pck.src, int64(h.dataOffset), pck.size-int64(h.dataOffset)))
 
 	// We can now fetch the actual data of the object, which is zlib encoded
-	zlibR, err := zlib.NewReader(buf)
+	zlibR, err := zlibutil.NewReader(buf)
 	if err != nil {
 		return nil, ginternals.NullOid, 0, fmt.Errorf("could not get zlib reader: %w", err)
 	}
 	defer errutil.Close(zlibR, &err)
 
 	objectData := bytes.Buffer{}
-	_, err = io.CopyN(&objectData, zlibR, int64(objectSize))
+	_, err = io.CopyN(&objectData, zlibR, int64(h.size))
 	if err != nil {
 		return nil, ginternals.NullOid, 0, fmt.Errorf("could not decompress: %w", err)
 	}
 
-	if objectData.Len() != int(objectSize) {
-		return nil, ginternals.NullOid, 0, fmt.Errorf("object size not valid. expecting %d, got %d: %w", objectSize, objectData.Len(), ErrInvalidObjectSize)
+	if objectData.Len() != int(h.size) {
+		return nil, ginternals.NullOid, 0, fmt.Errorf("object size not valid. expecting %d, got %d: %w", h.size, objectData.Len(), ErrInvalidObjectSize)
 	}
 
-	return object.New(objectType, objectData.Bytes()), baseObjectOid, baseObjectOffset, nil
+	return object.New(h.typ, objectData.Bytes()), h.baseOid, h.baseOffset, nil
 }
 
 // getObjectAt return the object located at the given offset
 func (pck *Pack) getObjectAt(objectOffset uint64) (*object.Object, error) {
+	o, _, err := pck.resolveObjectAt(objectOffset, 0)
+	return o, err
+}
+
+// cachedBase represents a base object stored in the cache
+type cachedBase struct {
+	object *object.Object
+	// chainLength contains the length of the delta chain of the
+	// object. 0 if the object is not a delta
+	chainLength int
+}
+
+// resolveObjectAt return the object located at the given offset,
+// alongside the length of its delta chain.
+// recursion contains the number of deltas being resolved to get this
+// object, and is used to detect the cycles of corrupted packfiles
+func (pck *Pack) resolveObjectAt(objectOffset uint64, recursion int) (o *object.Object, chainLength int, err error) {
 	// First we look in the cache in case we're looking for a base
 	if cachedO, found := pck.baseObjectCache.Get(objectOffset); found {
-		if o, valid := cachedO.(*object.Object); valid {
-			return o, nil
+		if base, valid := cachedO.(*cachedBase); valid {
+			return base.object, base.chainLength, nil
 		}
 	}
+	if recursion > maxDeltaDepth {
+		return nil, 0, fmt.Errorf("delta chain of the object at offset %d is too long: %w", objectOffset, ErrIntOverflow)
+	}
 
 	o, baseOid, baseOffset, err := pck.getRawObjectAt(objectOffset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// If the object is not deltified, we don't have anything to do
 	if o.Type() != object.ObjectDeltaRef && o.Type() != object.ObjectDeltaOFS {
-		return o, nil
+		return o, 0, nil
 	}
 
-	// we retrieve the base object
-	var base *object.Object
+	// we retrieve the base object. The base of a ObjectDeltaRef is
+	// located using the index
 	if !baseOid.IsZero() {
-		base, err = pck.getObject(baseOid)
+		baseOffset, err = pck.idx.GetObjectOffset(baseOid)
 		if err != nil {
-			return nil, fmt.Errorf("could not get base object %s: %w", baseOid.String(), err)
-		}
-	} else {
-		base, err = pck.getObjectAt(baseOffset)
-		if err != nil {
-			return nil, fmt.Errorf("could not get base object at offset %d: %w", baseOffset, err)
+			return nil, 0, fmt.Errorf("could not get offset of base object %s: %w", baseOid.String(), err)
 		}
 	}
+	// The object is a delta, so the chain of the object we're looking
+	// for is at least recursion+1 long. We check it before resolving
+	// the base to not decompress the whole chain for nothing
+	if err = checkDeltaChain(pck.limits, recursion+1); err != nil {
+		return nil, 0, fmt.Errorf("could not resolve the object at offset %d: %w", objectOffset, err)
+	}
+	base, baseChainLength, err := pck.resolveObjectAt(baseOffset, recursion+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not get base object at offset %d: %w", baseOffset, err)
+	}
+	// We cache the base. The cache is looked up using the offset of
+	// the object, so we need to make sure we use the offset of the
+	// base, and not the one of the current object
+	pck.baseObjectCache.Add(baseOffset, &cachedBase{
+		object:      base,
+		chainLength: baseChainLength,
+	})
 
-	// We cache the base
-	pck.baseObjectCache.Add(objectOffset, base)
-
-	// The format of a delta object is:
-	// - A header with:
-	//   - The size of the source (x bytes)
-	//   - the size of the target (x bytes)
-	// - A set of instruction (x bytes)
-	delta := o.Bytes()
-	sourceSize, sourceSizeLen, err := pck.readSize(delta)
+	chainLength = baseChainLength + 1
+	if err = checkDeltaChain(pck.limits, chainLength); err != nil {
+		return nil, 0, fmt.Errorf("could not resolve the object at offset %d: %w", objectOffset, err)
+	}
+	targetSize, err := pck.readDeltaTargetSize(o.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("couldn't read source size of delta: %w", err)
+		return nil, 0, fmt.Errorf("invalid delta at offset %d: %w", objectOffset, err)
 	}
-	if int(sourceSize) != base.Size() {
-		return nil, fmt.Errorf("invalid base object size. expected %d, got %d: %w", base.Size(), sourceSize, err)
+	if err = pck.limits.CheckObjectSize(targetSize); err != nil {
+		return nil, 0, fmt.Errorf("could not apply the delta at offset %d: %w", objectOffset, err)
 	}
-	_, tartgetSizeLen, err := pck.readSize(delta[sourceSizeLen:])
+	data, err := delta.Apply(base.Bytes(), o.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("couldn't read target size of delta: %w", err)
-	}
-	headerSize := tartgetSizeLen + sourceSizeLen
-	instructions := delta[headerSize:]
-	baseContent := base.Bytes()
-
-	// We loop over all instructions
-	// We don't do a for-range loop because an instruction can be over
-	// multiple bytes.
-	var out bytes.Buffer
-
-	for i := 0; i < len(instructions); i++ {
-		instr := instructions[i]
-
-		// there's 2 types of instruction: COPY and INSERT.
-		// If the MSB of the byte is 1 it's a COPY, otherwise it's
-		// an INSERT
-		switch pck.isMSBSet(instr) {
-		case true: // COPY
-			// the last 4 bit of the byte contains information about
-			// how many bytes to read to get the offset.
-			// Example: if the last 4 bits are 1010, we need to read
-			// 2 bytes (count the 1), and we'll have to insert to bytes
-			// of 0 in the numbers. [first_byte, byte(0), second_byte, byte(0)]
-			offsetInfo := uint(instr & 0b_0000_1111)
-			var offset uint32
-			offsetBytes := make([]byte, 4)
-			byteRead := 0
-			// our offset will be stored in $offsetBytes
-			// We need to loop over the 4 bits of info we have, find the
-			// bits that are 1 and insert the correct bytes at the correct
-			// index.
-			// For example, with 1010 we need to insert our bytes at
-			// offsetBytes[0] and offsetBytes[2], and zeros at [1] and [3].
-			for j := uint(0); j < 4; j++ {
-				offsetBytes[j] = 0
-
-				// we move the current bit to the very left and check that
-				// its value is one
-				if (offsetInfo >> j & 1) == 1 {
-					offsetBytes[j] = instructions[i+1+byteRead]
-					byteRead++
-				}
-			}
-			offset = binary.LittleEndian.Uint32(offsetBytes)
-			i += byteRead
-
-			// the next 3 bits of the byte after the MSB contains
-			// information about how many bytes to read to get the size
-			// of the copy (ie. how many bytes we're copying).
-			// Example: if the 3 bits are 110, we need to read
-			// 2 bytes (count the 1), and we'll have to insert to bytes
-			// of 0 in the numbers. [first_byte, byte(0), second_byte, byte(0)]
-			copyLenInfo := uint((instr & 0b_0111_0000) >> 4)
-			var copyLen uint32
-			copyLenBytes := make([]byte, 4)
-			byteRead = 0
-			// our size will be stored in $copyLenInfo
-			// We need to loop over the 3 bits of info we have, find the
-			// bits that are 1 and insert the correct bytes at the correct
-			// index.
-			// For example, with 101 we need to insert our bytes at
-			// copyLenInfo[0] and copyLenInfo[2], and a zero at copyLenInfo[1].
-			for j := uint(0); j < 3; j++ {
-				copyLenBytes[j] = 0
-
-				// we move the current bit to the very left and check that
-				// its value is one
-				if (copyLenInfo >> j & 1) == 1 {
-					copyLenBytes[j] = instructions[i+1+byteRead]
-					byteRead++
-				}
-			}
-			// we're working on a 32 bit number (4 bytes) but the size
-			// is only stored on 3 bits. We need to make sure the 4th byte
-			// is always set to 0
-			copyLenBytes[3] = 0
-			copyLen = binary.LittleEndian.Uint32(copyLenBytes)
-			i += byteRead
-			out.Write(baseContent[offset : offset+copyLen])
-		case false: // INSERT
-			// $instr contains the amount of bytes we need to copy from
-			// the delta to the output
-			start := i + 1
-			end := start + int(instr)
-			out.Write(instructions[start:end])
-			i += int(instr)
-		}
+		return nil, 0, fmt.Errorf("could not apply the delta at offset %d: %w", objectOffset, err)
 	}
-	return object.New(base.Type(), out.Bytes()), nil
+	return object.New(base.Type(), data), chainLength, nil
+}
+
+// checkDeltaChain returns object.ErrLimitExceeded if a delta chain of
+// the given length exceeds the limits
+func checkDeltaChain(limits object.Limits, chainLength int) error {
+	if limits.MaxDeltaDepth > 0 && chainLength > limits.MaxDeltaDepth {
+		return fmt.Errorf("delta chain is longer than the maximum of %d: %w", limits.MaxDeltaDepth, object.ErrLimitExceeded)
+	}
+	return nil
 }
 
 // GetObject returns the object that has the given SHA
@@ -483,14 +565,90 @@ func (pck *Pack) ID() ginternals.Oid {
 	return pck.id
 }
 
+// Path returns the path of the packfile
+func (pck *Pack) Path() string {
+	return pck.path
+}
+
+// Verify makes sure the SHA stored in the footer of the packfile
+// matches its content.
+// This requires reading the whole packfile
+func (pck *Pack) Verify() error {
+	pck.mu.Lock()
+	defer pck.mu.Unlock()
+
+	sum, err := checksum(pck.src, pck.size)
+	if err != nil {
+		return fmt.Errorf("could not compute the checksum of %s: %w", pck.path, err)
+	}
+	if sum != pck.id {
+		return fmt.Errorf("packfile %s is corrupted, expected %s, got %s: %w", pck.path, pck.id.String(), sum.String(), ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// VerifyFile makes sure the SHA stored in the footer of the packfile
+// at the given path matches its content.
+// This should be called after a packfile is written to disk
+func VerifyFile(fs afero.Fs, filePath string) (err error) {
+	f, err := fs.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", filePath, err)
+	}
+	defer errutil.Close(f, &err)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %w", filePath, err)
+	}
+	if info.Size() < packfileHeaderSize+ginternals.OidSize {
+		return fmt.Errorf("packfile %s is too small: %w", filePath, ErrChecksumMismatch)
+	}
+	id := make([]byte, ginternals.OidSize)
+	if _, err = f.ReadAt(id, info.Size()-ginternals.OidSize); err != nil {
+		return fmt.Errorf("could not read the ID of %s: %w", filePath, err)
+	}
+	expected, err := ginternals.NewOidFromHex(id)
+	if err != nil {
+		return fmt.Errorf("could not generate oid from %v: %w", id, err)
+	}
+	sum, err := checksum(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("could not compute the checksum of %s: %w", filePath, err)
+	}
+	if sum != expected {
+		return fmt.Errorf("packfile %s is corrupted, expected %s, got %s: %w", filePath, expected.String(), sum.String(), ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// checksum returns the SHA of a packfile of the given size, without
+// its footer
+func checksum(r io.ReaderAt, size int64) (ginternals.Oid, error) {
+	h := sha1.New() //nolint:gosec // sha1 is used by git
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size-ginternals.OidSize)); err != nil {
+		return ginternals.NullOid, err
+	}
+	return ginternals.NewOidFromHex(h.Sum(nil))
+}
+
 // Close frees the resources
 func (pck *Pack) Close() error {
 	pck.mu.Lock()
 	defer pck.mu.Unlock()
 
 	// To avoid leaks we try to close everything BEFORE cheking for errors
-	packErr := pck.r.Close()      //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
-	idxErr := pck.idxFile.Close() //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
+	packMapErr := munmap(pck.data)   //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
+	idxMapErr := munmap(pck.idxData) //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
+	packErr := pck.r.Close()         //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
+	idxErr := pck.idxFile.Close()    //nolint:ifshort,nolintlint // we want to close more things before checking for the error. Also, nolintlint returns a false positive
+	pck.data, pck.idxData = nil, nil
+	if packMapErr != nil {
+		return fmt.Errorf("could not unmap packfile : %w", packMapErr)
+	}
+	if idxMapErr != nil {
+		return fmt.Errorf("could not unmap packfile index : %w", idxMapErr)
+	}
 	if packErr != nil {
 		return fmt.Errorf("could not close packfile : %w", packErr)
 	}
@@ -504,6 +662,9 @@ func (pck *Pack) Close() error {
 // size from an object metadata.
 // This method is only to read the remaining parts of a size.
 func (pck *Pack) readSize(data []byte) (objectSize uint64, bytesRead int, err error) {
+	if len(data) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
 	for i, b := range data {
 		bytesRead++
 
@@ -536,21 +697,27 @@ func (pck *Pack) readSize(data []byte) (objectSize uint64, bytesRead int, err er
 // Each chunk of offset (except the last one) are stored -1, so we need
 // to add 1 back to each chunk.
 func (pck *Pack) readDeltaOffset(data []byte) (offset uint64, bytesRead int, err error) {
+	if len(data) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
 	for _, b := range data {
 		bytesRead++
 
 		// We set the MSB to 0 since it's not part of the offset
 		chunk := pck.unsetMSB(b)
 
+		// Offsets are big endian encoded, because why not
+		offset = pck.insertBigEndian7(offset, chunk)
+
 		// To save more space (I guess?), all the chunks beside the last one
 		// are stored with -1.
+		// The 1 has to be added to the offset and not to the chunk,
+		// otherwise a chunk of 0x7f would overflow on the previous
+		// chunk
 		if pck.isMSBSet(b) {
-			chunk++
+			offset++
 		}
 
-		// Offsets are big endian encoded, because why not
-		offset = pck.insertBigEndian7(offset, chunk)
-
 		// No more MSB? Then we're done reading the offset
 		if !pck.isMSBSet(b) {
 			break
@@ -0,0 +1,110 @@
+//go:build go1.18
+// +build go1.18
+
+package packfile_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/spf13/afero"
+)
+
+// fuzzPack returns a small packfile containing deltas, alongside its
+// index
+func fuzzPack(f *testing.F) *packfile.BuildResult {
+	f.Helper()
+
+	objects := []*packfile.BuildObject{}
+	for i := 0; i < 4; i++ {
+		content := bytes.Repeat([]byte("line of a blob\n"), 5+i)
+		objects = append(objects, &packfile.BuildObject{
+			Object: object.New(object.TypeBlob, content),
+			Path:   "file",
+		})
+	}
+	res, err := packfile.Build(objects, packfile.BuildOptions{
+		Window: packfile.DefaultWindow,
+		Depth:  packfile.DefaultDepth,
+	})
+	if err != nil {
+		f.Fatalf("could not build the packfile: %v", err)
+	}
+	if res.Deltas == 0 {
+		f.Fatal("expected the packfile to contain deltas")
+	}
+	return res
+}
+
+// withChecksum returns the given packfile content followed by its
+// checksum, so the fuzzer doesn't waste time on invalid checksums
+func withChecksum(data []byte) []byte {
+	sum := sha1.Sum(data) //nolint:gosec // sha1 is used by git
+	return append(append([]byte{}, data...), sum[:]...)
+}
+
+// FuzzPack reads all the objects of a corrupted packfile, using a
+// valid index
+func FuzzPack(f *testing.F) {
+	res := fuzzPack(f)
+	f.Add(res.Pack[:len(res.Pack)-ginternals.OidSize])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dir := t.TempDir()
+		packPath := filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtPackfile)
+		idxPath := filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtIndex)
+		if err := os.WriteFile(packPath, withChecksum(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(idxPath, res.Index, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		pack, err := packfile.NewFromFile(afero.NewOsFs(), packPath)
+		if err != nil {
+			return
+		}
+		defer pack.Close() //nolint:errcheck // only panics matter
+		_ = pack.WalkOids(func(oid ginternals.Oid) error {
+			_, _ = pack.GetObject(oid)
+			_, _, _ = pack.ObjectInfo(oid)
+			_, _, _ = pack.RawDelta(oid)
+			return nil
+		})
+		_ = pack.Verify()
+	})
+}
+
+func FuzzBuildIndex(f *testing.F) {
+	res := fuzzPack(f)
+	f.Add(res.Pack[:len(res.Pack)-ginternals.OidSize])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = packfile.BuildIndex(withChecksum(data))
+	})
+}
+
+func FuzzNewIndex(f *testing.F) {
+	res := fuzzPack(f)
+	f.Add(res.Index)
+
+	oid, err := ginternals.NewOidFromStr("0343d67ca3d80a531d0d163f0078a81c95c9085a")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		idx, err := packfile.NewIndex(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return
+		}
+		_, _ = idx.GetObjectOffset(oid)
+		_, _ = idx.ObjectCRC(oid)
+	})
+}
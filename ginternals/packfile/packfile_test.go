@@ -1,7 +1,11 @@
 package packfile_test
 
 import (
+	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/Nivl/git-go/ginternals"
@@ -53,6 +57,263 @@ func TestNewFromFile(t *testing.T) {
 	})
 }
 
+func TestNewFromFileWithOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("mmap should return the same objects", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		packFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+		mapped, err := packfile.NewFromFileWithOptions(afero.NewOsFs(), packFilePath, packfile.Options{Mmap: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, mapped.Close())
+		})
+
+		assert.Equal(t, pack.ID(), mapped.ID())
+		assert.Equal(t, pack.ObjectCount(), mapped.ObjectCount())
+		require.NoError(t, mapped.Verify())
+
+		count := 0
+		err = mapped.WalkOids(func(oid ginternals.Oid) error {
+			count++
+			expected, err := pack.GetObject(oid)
+			require.NoError(t, err)
+			o, err := mapped.GetObject(oid)
+			require.NoError(t, err)
+			assert.Equal(t, expected.Type(), o.Type(), oid.String())
+			assert.Equal(t, expected.Bytes(), o.Bytes(), oid.String())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int(pack.ObjectCount()), count)
+	})
+
+	t.Run("should enforce the limits", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		packFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		pack, err := packfile.NewFromFileWithOptions(afero.NewOsFs(), packFilePath, packfile.Options{
+			Limits: object.Limits{MaxObjectSize: 10},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+
+		// the commit is way bigger than 10 bytes
+		oid, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		_, err = pack.GetObject(oid)
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+	})
+
+	t.Run("should not resolve delta chains that exceed the limits", func(t *testing.T) {
+		t.Parallel()
+
+		objects := []*packfile.BuildObject{}
+		for i := 0; i < 10; i++ {
+			content := bytes.Repeat([]byte("line of a blob\n"), 20+i)
+			objects = append(objects, &packfile.BuildObject{
+				Object: object.New(object.TypeBlob, content),
+				Path:   "file",
+			})
+		}
+		res, err := packfile.Build(objects, packfile.BuildOptions{
+			Window: packfile.DefaultWindow,
+			Depth:  packfile.DefaultDepth,
+		})
+		require.NoError(t, err)
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		packFilePath := filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtPackfile)
+		require.NoError(t, os.WriteFile(packFilePath, res.Pack, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pack-"+res.ID.String()+packfile.ExtIndex), res.Index, 0o644))
+
+		fs := &readAtCountingFs{Fs: afero.NewOsFs(), offsets: map[int64]int{}}
+		pack, err := packfile.NewFromFileWithOptions(fs, packFilePath, packfile.Options{
+			Limits: object.Limits{MaxDeltaDepth: 2},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+
+		// We look for the object that has the longest delta chain
+		var deepest *packfile.EntryInfo
+		entryOffsets := []int64{}
+		err = pack.WalkEntries(func(info *packfile.EntryInfo) error {
+			entryOffsets = append(entryOffsets, int64(info.Offset))
+			if deepest == nil || info.Depth > deepest.Depth {
+				deepest = info
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, deepest.Depth, 4, "the delta chain is too short for the test")
+
+		fs.reset()
+		_, err = pack.GetObject(deepest.ID)
+		require.ErrorIs(t, err, object.ErrLimitExceeded)
+
+		// Only the object, and the 2 first bases should have been read
+		resolved := 0
+		for _, offset := range entryOffsets {
+			resolved += fs.readsAt(offset)
+		}
+		assert.Equal(t, 3, resolved)
+	})
+
+	t.Run("should emit an event once loaded", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		packFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		logger := &testutil.Logger{}
+		pack, err := packfile.NewFromFileWithOptions(afero.NewOsFs(), packFilePath, packfile.Options{
+			Logger: logger,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+
+		events := logger.Events("packfile loaded")
+		require.Len(t, events, 1)
+		assert.Equal(t, packFilePath, events[0].Args["path"])
+		assert.Equal(t, pack.ObjectCount(), events[0].Args["objects"])
+		assert.Equal(t, false, events[0].Args["mmap"])
+		assert.Contains(t, events[0].Args, "duration")
+	})
+}
+
+func TestVerifyCRCOption(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	packFilePath := ginternals.PackfilePath(cfg, "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack")
+	corruptIndexCRC(t, packFilePath, true)
+
+	corrupted, err := ginternals.NewOidFromStr("0026d781d9fb8ee93031d279223f46793fa98eb8")
+	require.NoError(t, err)
+	valid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+	require.NoError(t, err)
+
+	t.Run("should not check the CRC by default", func(t *testing.T) {
+		t.Parallel()
+
+		pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+		o, err := pack.GetObject(corrupted)
+		require.NoError(t, err)
+		assert.Equal(t, corrupted, o.ID())
+	})
+
+	t.Run("should fail on a CRC mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		pack, err := packfile.NewFromFileWithOptions(afero.NewOsFs(), packFilePath, packfile.Options{VerifyCRC: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+		_, err = pack.GetObject(corrupted)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, packfile.ErrCRCMismatch), "unexpected error: %v", err)
+
+		o, err := pack.GetObject(valid)
+		require.NoError(t, err)
+		assert.Equal(t, valid, o.ID())
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	packFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"
+
+	t.Run("valid packfile should pass", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+		assert.Equal(t, packFilePath, pack.Path())
+		require.NoError(t, pack.Verify())
+		require.NoError(t, packfile.VerifyFile(afero.NewOsFs(), packFilePath))
+	})
+
+	t.Run("corrupted packfile should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		// packfiles are read-only
+		require.NoError(t, os.Chmod(packFilePath, 0o644))
+		// We flip a byte right after the header
+		f, err := os.OpenFile(packFilePath, os.O_RDWR, 0)
+		require.NoError(t, err)
+		b := make([]byte, 1)
+		_, err = f.ReadAt(b, 12)
+		require.NoError(t, err)
+		_, err = f.WriteAt([]byte{^b[0]}, 12)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		pack, err := packfile.NewFromFile(afero.NewOsFs(), packFilePath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, pack.Close())
+		})
+		err = pack.Verify()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, packfile.ErrChecksumMismatch), "unexpected error: %v", err)
+		assert.Contains(t, err.Error(), packFilePath)
+
+		err = packfile.VerifyFile(afero.NewOsFs(), packFilePath)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, packfile.ErrChecksumMismatch), "unexpected error: %v", err)
+	})
+}
+
 func TestGetObject(t *testing.T) {
 	t.Parallel()
 
@@ -136,6 +397,37 @@ func TestGetObject(t *testing.T) {
 			require.Equal(t, entry, tree.Entries()[6])
 		})
 
+		t.Run("deltified object retrieved twice", func(t *testing.T) {
+			// 02d1d81 is a delta of faecfa7, which is a delta of e5b9e84
+			treeOid, err := ginternals.NewOidFromStr("02d1d818e230d897963ca392e8973e018921f37a")
+			require.NoError(t, err)
+			for i := 0; i < 2; i++ {
+				o, err := pack.GetObject(treeOid)
+				require.NoError(t, err)
+				require.Equal(t, treeOid, o.ID(), "attempt %d returned the wrong object", i+1)
+			}
+		})
+
+		t.Run("deltified objects sharing a base", func(t *testing.T) {
+			// cabcce0 and d709987 are both deltas of a1fa159. Reading
+			// one of them should never return the base, or the other
+			// delta
+			oids := []string{
+				"cabcce057c1225814864ef68fc2885bf279de999",
+				"d7099872321e6efd5a02c170f90a10a458e5e934",
+				"a1fa159c5dfab4c7252670c382a25ffba6004c0c",
+				"cabcce057c1225814864ef68fc2885bf279de999",
+				"d7099872321e6efd5a02c170f90a10a458e5e934",
+			}
+			for i, sha := range oids {
+				oid, err := ginternals.NewOidFromStr(sha)
+				require.NoError(t, err)
+				o, err := pack.GetObject(oid)
+				require.NoError(t, err)
+				require.Equal(t, oid, o.ID(), "read %d returned the wrong object", i+1)
+			}
+		})
+
 		t.Run("tag", func(t *testing.T) {
 			// TODO(melvin): we now support tags
 			t.Skip("tags not yet supported")
@@ -232,3 +524,44 @@ func TestWalkOids(t *testing.T) {
 		assert.Equal(t, 4, totalObject)
 	})
 }
+
+// readAtCountingFs is an afero.Fs that records the offsets at which
+// its files are read using ReadAt()
+type readAtCountingFs struct {
+	afero.Fs
+
+	mu      sync.Mutex
+	offsets map[int64]int
+}
+
+func (fs *readAtCountingFs) Open(name string) (afero.File, error) {
+	f, err := fs.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &readAtCountingFile{File: f, fs: fs}, nil
+}
+
+func (fs *readAtCountingFs) reset() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.offsets = map[int64]int{}
+}
+
+func (fs *readAtCountingFs) readsAt(offset int64) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.offsets[offset]
+}
+
+type readAtCountingFile struct {
+	afero.File
+	fs *readAtCountingFs
+}
+
+func (f *readAtCountingFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	f.fs.offsets[off]++
+	f.fs.mu.Unlock()
+	return f.File.ReadAt(p, off)
+}
@@ -18,6 +18,13 @@ const (
 	layer2EntrySize = ginternals.OidSize
 	layer3EntrySize = 4
 	layer4EntrySize = 4
+	layer5EntrySize = 8
+
+	// maxPreallocatedOids is the maximum number of oids we allocate
+	// memory for before reading them. The number of objects comes
+	// from the file, so a corrupted index could make us allocate
+	// gigabytes for nothing
+	maxPreallocatedOids = 1 << 16
 )
 
 // indexHeader represents the header of an index file.
@@ -90,6 +97,17 @@ type PackIndex struct {
 
 	r          readutil.BufferedReader
 	hashOffset map[ginternals.Oid]uint64
+	offsetCRC  map[uint64]uint32
+
+	// sortedOffsets contains the offsets of all the objects, sorted.
+	// It's only built when needed, see objectEnd()
+	sortedOffsets     []uint64
+	sortedOffsetsOnce sync.Once
+
+	// offsetOids contains the oid of all the objects, indexed by
+	// offset. It's only built when needed, see oidAt()
+	offsetOids     map[uint64]ginternals.Oid
+	offsetOidsOnce sync.Once
 
 	parseError error
 	parsed     bool
@@ -125,6 +143,73 @@ func (idx *PackIndex) GetObjectOffset(oid ginternals.Oid) (uint64, error) {
 	return offset, nil
 }
 
+// ObjectCRC returns the CRC32 of the packed data of the given
+// object, as stored in the index. The packed data contain the header
+// of the object and its compressed content.
+// If the object is not found ginternals.ErrObjectNotFound is returned
+func (idx *PackIndex) ObjectCRC(oid ginternals.Oid) (uint32, error) {
+	offset, err := idx.GetObjectOffset(oid)
+	if err != nil {
+		return 0, err
+	}
+	return idx.offsetCRC[offset], nil
+}
+
+// crcAt returns the CRC32 of the packed data of the object at the
+// given offset
+func (idx *PackIndex) crcAt(offset uint64) (crc uint32, ok bool, err error) {
+	if err = idx.parse(); err != nil {
+		return 0, false, fmt.Errorf("could not parse the index file: %w", err)
+	}
+	crc, ok = idx.offsetCRC[offset]
+	return crc, ok, nil
+}
+
+// objectEnd returns the offset of the object following the object at
+// the given offset, which is where the data of the object end.
+// ok is false if the object is the last one of the packfile
+func (idx *PackIndex) objectEnd(offset uint64) (end uint64, ok bool, err error) {
+	if err = idx.parse(); err != nil {
+		return 0, false, fmt.Errorf("could not parse the index file: %w", err)
+	}
+	idx.sortedOffsetsOnce.Do(func() {
+		idx.sortedOffsets = make([]uint64, 0, len(idx.offsetCRC))
+		for o := range idx.offsetCRC {
+			idx.sortedOffsets = append(idx.sortedOffsets, o)
+		}
+		sort.Slice(idx.sortedOffsets, func(i, j int) bool { return idx.sortedOffsets[i] < idx.sortedOffsets[j] })
+	})
+	i := sort.Search(len(idx.sortedOffsets), func(i int) bool { return idx.sortedOffsets[i] > offset })
+	if i == len(idx.sortedOffsets) {
+		return 0, false, nil
+	}
+	return idx.sortedOffsets[i], true, nil
+}
+
+// oidAt returns the oid of the object at the given offset.
+// ok is false if there are no objects at this offset
+func (idx *PackIndex) oidAt(offset uint64) (oid ginternals.Oid, ok bool, err error) {
+	if err = idx.parse(); err != nil {
+		return ginternals.NullOid, false, fmt.Errorf("could not parse the index file: %w", err)
+	}
+	idx.offsetOidsOnce.Do(func() {
+		idx.offsetOids = make(map[uint64]ginternals.Oid, len(idx.hashOffset))
+		for o, off := range idx.hashOffset {
+			idx.offsetOids[off] = o
+		}
+	})
+	oid, ok = idx.offsetOids[offset]
+	return oid, ok, nil
+}
+
+// offsets returns the offset of all the objects, indexed by oid
+func (idx *PackIndex) offsets() (map[ginternals.Oid]uint64, error) {
+	if err := idx.parse(); err != nil {
+		return nil, fmt.Errorf("could not parse the index file: %w", err)
+	}
+	return idx.hashOffset, nil
+}
+
 // parse extracts all the data from the index and puts them in memory.
 func (idx *PackIndex) parse() (err error) {
 	idx.mu.Lock()
@@ -170,8 +255,14 @@ func (idx *PackIndex) parse() (err error) {
 
 	// Now we can allocate the right amount of memory to store all the
 	// oids temporarily in an ordered list, and fill it by parsing
-	// layer2 which contains all oids back-to-back
-	oids := make([]ginternals.Oid, 0, objectCount)
+	// layer2 which contains all oids back-to-back.
+	// The other allocations are safe since they happen once all the
+	// oids have been read
+	capacity := objectCount
+	if capacity > maxPreallocatedOids {
+		capacity = maxPreallocatedOids
+	}
+	oids := make([]ginternals.Oid, 0, capacity)
 	// we basically need to get everything in between layer2 and
 	// layer3
 	layer2offset := len(indexHeader()) + layer1Size
@@ -197,13 +288,18 @@ func (idx *PackIndex) parse() (err error) {
 		oids = append(oids, oid)
 	}
 
-	// We don't care about layer3 just yet so we skip it
-	// TODO(melvin): parse and use layer3
+	// layer3 contains the CRC32 of the packed data of each object,
+	// in the same order as layer2. We can only index them by offset
+	// once layer4 and layer5 have been parsed
 	// https://golang.org/pkg/hash/crc32/
+	crcs := make([]uint32, 0, objectCount)
 	layer3Size := objectCount * layer3EntrySize
-	_, err = idx.r.Discard(layer3Size)
-	if err != nil {
-		return fmt.Errorf("could not skip layer3: %w", err)
+	for _, oid := range oids {
+		_, err = io.ReadFull(idx.r, bufInt32)
+		if err != nil {
+			return fmt.Errorf("couldn't read the CRC of oid %s (layer3): %w", oid.String(), err)
+		}
+		crcs = append(crcs, binary.BigEndian.Uint32(bufInt32))
 	}
 
 	// We can now allocate our final map (oid => offset) and fill it with the
@@ -272,15 +368,16 @@ func (idx *PackIndex) parse() (err error) {
 	// We need to make sure we access the offset in the right order
 	// since we won't be able to go back to a lower offset
 	sort.Slice(layer5offsets, func(i, j int) bool { return layer5offsets[i].relativeOffset < layer5offsets[j].relativeOffset })
-	currentRelativeOffset := uint64(0)
-	for _, data := range layer5offsets {
+	// The value stored in layer4 is the position of the entry in
+	// layer5, not its offset in bytes
+	for i, data := range layer5offsets {
 		// This should never happen since the offsert should be back-
 		// to-back, but it cost nothing to double check
-		if data.relativeOffset != currentRelativeOffset {
-			return fmt.Errorf("expected oid %s to be at (relative) offset %d, but is at %d instead (in layer5 %d): %w", data.oid.String(), currentRelativeOffset, data.relativeOffset, layer5Offset, os.ErrNotExist)
+		if data.relativeOffset != uint64(i) {
+			return fmt.Errorf("expected oid %s to be at position %d, but is at %d instead (in layer5 %d): %w", data.oid.String(), i, data.relativeOffset, layer5Offset, os.ErrNotExist)
 		}
 
-		entryOffset := layer5Offset + int64(data.relativeOffset)
+		entryOffset := layer5Offset + int64(data.relativeOffset)*layer5EntrySize
 		_, err = io.ReadFull(idx.r, bufInt64)
 		if err != nil {
 			return fmt.Errorf("couldn't read offset of oid %s at position %d (layer5): %w", data.oid.String(), entryOffset, err)
@@ -288,6 +385,11 @@ func (idx *PackIndex) parse() (err error) {
 		offset := binary.BigEndian.Uint64(bufInt64)
 		idx.hashOffset[data.oid] = offset
 	}
+
+	idx.offsetCRC = make(map[uint64]uint32, objectCount)
+	for i, oid := range oids {
+		idx.offsetCRC[idx.hashOffset[oid]] = crcs[i]
+	}
 	idx.parsed = true
 	return nil
 }
@@ -105,3 +105,44 @@ func TestGetObjectOffset(t *testing.T) {
 		})
 	})
 }
+
+func TestObjectCRC(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	indexFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.idx"
+	cfg := confutil.NewCommonConfig(t, repoPath)
+	indexFilePath := ginternals.PackfilePath(cfg, indexFileName)
+
+	f, err := os.Open(indexFilePath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	index, err := packfile.NewIndex(bufio.NewReader(f))
+	require.NoError(t, err)
+
+	t.Run("should work with valid oid", func(t *testing.T) {
+		t.Parallel()
+
+		// generated using git show-index
+		oid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		crc, err := index.ObjectCRC(oid)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0xf4b10fee), crc)
+	})
+
+	t.Run("should fail with invalid oid", func(t *testing.T) {
+		t.Parallel()
+
+		oid, err := ginternals.NewOidFromStr("1acdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		_, err = index.ObjectCRC(oid)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ginternals.ErrObjectNotFound), "invalid error returned: %s", err.Error())
+	})
+}
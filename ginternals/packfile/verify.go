@@ -0,0 +1,250 @@
+package packfile
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/spf13/afero"
+)
+
+// ErrCRCMismatch is an error thrown when the CRC of an object stored
+// in the index doesn't match the packed data of the object
+var ErrCRCMismatch = errors.New("CRC mismatch")
+
+// VerifiedObject contains the information about an object of a
+// verified packfile
+type VerifiedObject struct {
+	ID ginternals.Oid
+	// Type contains the type of the object once its delta chain has
+	// been resolved. It's never a delta type
+	Type object.Type
+	// Size contains the size of the object, or the size of the delta
+	// if the object is deltified
+	Size uint64
+	// PackedSize contains the size of the object in the packfile,
+	// headers included
+	PackedSize uint64
+	// Offset contains the offset of the object in the packfile
+	Offset uint64
+	// Depth contains the length of the delta chain of the object.
+	// 0 if the object is not deltified
+	Depth int
+	// Base contains the ID of the base object if the object is
+	// deltified, NullOid otherwise
+	Base ginternals.Oid
+}
+
+// VerifyReport contains the information about all the objects of a
+// verified packfile
+type VerifyReport struct {
+	// ID contains the ID of the packfile
+	ID ginternals.Oid
+	// Objects contains the objects of the packfile, sorted by offset
+	Objects []*VerifiedObject
+}
+
+// ChainLengths returns the number of objects for each delta chain
+// length. The objects that are not deltified have a length of 0
+func (r *VerifyReport) ChainLengths() map[int]int {
+	lengths := map[int]int{}
+	for _, o := range r.Objects {
+		lengths[o.Depth]++
+	}
+	return lengths
+}
+
+// Verify checks the integrity of the packfile at the given path, and
+// of its index:
+// - The checksums of the packfile and of its index match their content
+// - The CRC of each object matches the one stored in the index
+// - All the delta chains can be resolved, and each object matches
+//   its ID
+// The path can either point to the packfile or to its index.
+// This requires reading and decompressing the whole packfile
+func Verify(fs afero.Fs, packPath string) (*VerifyReport, error) {
+	packPath = strings.TrimSuffix(packPath, ExtIndex)
+	packPath = strings.TrimSuffix(packPath, ExtPackfile) + ExtPackfile
+
+	if err := VerifyFile(fs, packPath); err != nil {
+		return nil, err
+	}
+	pack, err := NewFromFile(fs, packPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", packPath, err)
+	}
+	defer pack.Close() //nolint:errcheck // we only read from the packfile
+
+	if err = verifyIndexFile(fs, strings.TrimSuffix(packPath, ExtPackfile)+ExtIndex, pack.ID()); err != nil {
+		return nil, err
+	}
+
+	pack.mu.Lock()
+	defer pack.mu.Unlock()
+	return pack.verifyObjects()
+}
+
+// verifyIndexFile makes sure the SHA stored in the footer of the index
+// at the given path matches its content, and that the index belongs
+// to the given packfile
+func verifyIndexFile(fs afero.Fs, idxPath string, packID ginternals.Oid) error {
+	data, err := afero.ReadFile(fs, idxPath)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", idxPath, err)
+	}
+	if len(data) < len(indexHeader())+layer1Size+2*ginternals.OidSize {
+		return fmt.Errorf("index %s is too small: %w", idxPath, ErrChecksumMismatch)
+	}
+	footer := data[len(data)-2*ginternals.OidSize:]
+	if !bytes.Equal(footer[:ginternals.OidSize], packID.Bytes()) {
+		return fmt.Errorf("index %s doesn't belong to packfile %s: %w", idxPath, packID.String(), ErrChecksumMismatch)
+	}
+	sum := sha1.Sum(data[:len(data)-ginternals.OidSize]) //nolint:gosec // sha1 is used by git
+	if !bytes.Equal(sum[:], footer[ginternals.OidSize:]) {
+		return fmt.Errorf("index %s is corrupted: %w", idxPath, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// checkCRC makes sure the packed data of the object at the given
+// offset match the CRC stored in the index
+func (pck *Pack) checkCRC(offset uint64) error {
+	expected, ok, err := pck.idx.crcAt(offset)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no object at offset %d: %w", offset, ginternals.ErrObjectNotFound)
+	}
+	end, ok, err := pck.idx.objectEnd(offset)
+	if err != nil {
+		return err
+	}
+	// the last object ends before the footer
+	if !ok {
+		end = uint64(pck.size - ginternals.OidSize)
+	}
+	if end <= offset || int64(end) > pck.size {
+		return fmt.Errorf("object at offset %d ends at %d: %w", offset, end, ErrIntOverflow)
+	}
+
+	h := crc32.NewIEEE()
+	if _, err = io.Copy(h, io.NewSectionReader(pck.src, int64(offset), int64(end-offset))); err != nil {
+		return fmt.Errorf("could not read the object at offset %d: %w", offset, err)
+	}
+	if h.Sum32() != expected {
+		return fmt.Errorf("object at offset %d: %w", offset, ErrCRCMismatch)
+	}
+	return nil
+}
+
+// verifyObjects checks the CRC, the delta chain, and the ID of all
+// the objects of the packfile
+func (pck *Pack) verifyObjects() (*VerifyReport, error) {
+	offsets, err := pck.idx.offsets()
+	if err != nil {
+		return nil, err
+	}
+	report := &VerifyReport{
+		ID:      pck.id,
+		Objects: make([]*VerifiedObject, 0, len(offsets)),
+	}
+	oidAt := make(map[uint64]ginternals.Oid, len(offsets))
+	for oid, offset := range offsets {
+		report.Objects = append(report.Objects, &VerifiedObject{
+			ID:     oid,
+			Offset: offset,
+		})
+		oidAt[offset] = oid
+	}
+	if uint32(len(report.Objects)) != pck.ObjectCount() {
+		return nil, fmt.Errorf("the index contains %d objects, but the packfile contains %d: %w", len(report.Objects), pck.ObjectCount(), ErrChecksumMismatch)
+	}
+	sort.Slice(report.Objects, func(i, j int) bool {
+		return report.Objects[i].Offset < report.Objects[j].Offset
+	})
+
+	// The data of an object ends where the next object starts, and
+	// the last object ends before the footer
+	for i, o := range report.Objects {
+		end := uint64(pck.size - ginternals.OidSize)
+		if i+1 < len(report.Objects) {
+			end = report.Objects[i+1].Offset
+		}
+		if o.Offset < packfileHeaderSize || o.Offset >= end {
+			return nil, fmt.Errorf("object %s has an invalid offset %d: %w", o.ID.String(), o.Offset, ErrIntOverflow)
+		}
+		o.PackedSize = end - o.Offset
+		if err = pck.checkCRC(o.Offset); err != nil {
+			return nil, fmt.Errorf("invalid object %s: %w", o.ID.String(), err)
+		}
+	}
+
+	// We now resolve all the objects. The depth of an object is
+	// computed from the depth of its base, which may not have been
+	// processed yet
+	depths := make(map[uint64]int, len(report.Objects))
+	var depthAt func(offset uint64, seen int) (int, error)
+	depthAt = func(offset uint64, seen int) (int, error) {
+		if depth, ok := depths[offset]; ok {
+			return depth, nil
+		}
+		// A chain can't be longer than the number of objects
+		if seen > len(report.Objects) {
+			return 0, fmt.Errorf("delta chain loop at offset %d: %w", offset, ErrInvalidObjectSize)
+		}
+		raw, baseOid, baseOffset, err := pck.getRawObjectAt(offset)
+		if err != nil {
+			return 0, err
+		}
+		depth := 0
+		if raw.Type() == object.ObjectDeltaRef || raw.Type() == object.ObjectDeltaOFS {
+			if !baseOid.IsZero() {
+				if baseOffset, err = pck.idx.GetObjectOffset(baseOid); err != nil {
+					return 0, fmt.Errorf("could not find base object %s: %w", baseOid.String(), err)
+				}
+			}
+			baseDepth, err := depthAt(baseOffset, seen+1)
+			if err != nil {
+				return 0, err
+			}
+			depth = baseDepth + 1
+		}
+		depths[offset] = depth
+		return depth, nil
+	}
+
+	for _, o := range report.Objects {
+		raw, baseOid, baseOffset, err := pck.getRawObjectAt(o.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not read object %s: %w", o.ID.String(), err)
+		}
+		o.Size = uint64(raw.Size())
+		if raw.Type() == object.ObjectDeltaRef || raw.Type() == object.ObjectDeltaOFS {
+			o.Base = baseOid
+			if baseOid.IsZero() {
+				o.Base = oidAt[baseOffset]
+			}
+			if o.Depth, err = depthAt(o.Offset, 0); err != nil {
+				return nil, fmt.Errorf("could not resolve the delta chain of %s: %w", o.ID.String(), err)
+			}
+		}
+
+		resolved, err := pck.getObjectAt(o.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve object %s: %w", o.ID.String(), err)
+		}
+		if resolved.ID() != o.ID {
+			return nil, fmt.Errorf("object at offset %d should be %s but is %s: %w", o.Offset, o.ID.String(), resolved.ID().String(), ErrChecksumMismatch)
+		}
+		o.Type = resolved.Type()
+	}
+	return report, nil
+}
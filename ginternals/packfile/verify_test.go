@@ -0,0 +1,128 @@
+package packfile_test
+
+import (
+	"crypto/sha1" //nolint:gosec // sha1 is used by git
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptIndexCRC flips the first byte of the CRC layer of the index
+// of the given packfile, which corresponds to the CRC of
+// 0026d781d9fb8ee93031d279223f46793fa98eb8 in the small repo.
+// If fixChecksum is set, the footer of the index is updated to match
+// its new content
+func corruptIndexCRC(t *testing.T, packFilePath string, fixChecksum bool) {
+	t.Helper()
+
+	idxPath := strings.TrimSuffix(packFilePath, packfile.ExtPackfile) + packfile.ExtIndex
+	require.NoError(t, os.Chmod(idxPath, 0o644))
+	data, err := os.ReadFile(idxPath)
+	require.NoError(t, err)
+	objectCount := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	layer3 := 8 + 1024 + objectCount*ginternals.OidSize
+	data[layer3] = ^data[layer3]
+	if fixChecksum {
+		sum := sha1.Sum(data[:len(data)-ginternals.OidSize]) //nolint:gosec // sha1 is used by git
+		copy(data[len(data)-ginternals.OidSize:], sum[:])
+	}
+	require.NoError(t, os.WriteFile(idxPath, data, 0o644))
+}
+
+func TestVerifyPack(t *testing.T) {
+	t.Parallel()
+
+	packFileName := "pack-0163931160835b1de2f120e1aa7e52206debeb14.pack"
+
+	t.Run("valid packfile should pass", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+
+		report, err := packfile.Verify(afero.NewOsFs(), packFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, "0163931160835b1de2f120e1aa7e52206debeb14", report.ID.String())
+		require.Len(t, report.Objects, 364)
+
+		// Values generated using git verify-pack -v
+		first := report.Objects[0]
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", first.ID.String())
+		assert.Equal(t, object.TypeCommit, first.Type)
+		assert.Equal(t, uint64(260), first.Size)
+		assert.Equal(t, uint64(203), first.PackedSize)
+		assert.Equal(t, uint64(12), first.Offset)
+		assert.Equal(t, 0, first.Depth)
+		assert.True(t, first.Base.IsZero())
+
+		var delta *packfile.VerifiedObject
+		for _, o := range report.Objects {
+			if o.ID.String() == "d55aca68dd3bee5055521e5900ab6251e76d9a17" {
+				delta = o
+			}
+		}
+		require.NotNil(t, delta)
+		assert.Equal(t, object.TypeBlob, delta.Type)
+		assert.Equal(t, uint64(226), delta.Size)
+		assert.Equal(t, uint64(176), delta.PackedSize)
+		assert.Equal(t, uint64(27234), delta.Offset)
+		assert.Equal(t, 1, delta.Depth)
+		assert.Equal(t, "c30d3fcd40885d8b33459243d1764266b0904345", delta.Base.String())
+
+		assert.Equal(t, map[int]int{0: 240, 1: 70, 2: 33, 3: 14, 4: 5, 5: 2}, report.ChainLengths())
+	})
+
+	t.Run("should accept the path of the index", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		idxPath := ginternals.PackfilePath(cfg, strings.TrimSuffix(packFileName, packfile.ExtPackfile)+packfile.ExtIndex)
+
+		report, err := packfile.Verify(afero.NewOsFs(), idxPath)
+		require.NoError(t, err)
+		require.Len(t, report.Objects, 364)
+	})
+
+	t.Run("corrupted index should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+		corruptIndexCRC(t, packFilePath, false)
+
+		_, err := packfile.Verify(afero.NewOsFs(), packFilePath)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, packfile.ErrChecksumMismatch), "unexpected error: %v", err)
+	})
+
+	t.Run("invalid CRC should fail", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg := confutil.NewCommonConfig(t, repoPath)
+		packFilePath := ginternals.PackfilePath(cfg, packFileName)
+		corruptIndexCRC(t, packFilePath, true)
+
+		_, err := packfile.Verify(afero.NewOsFs(), packFilePath)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, packfile.ErrCRCMismatch), "unexpected error: %v", err)
+	})
+}
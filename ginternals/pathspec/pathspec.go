@@ -0,0 +1,239 @@
+// Package pathspec contains methods to parse and match pathspecs, the
+// patterns used by git to limit a command to a subset of the paths
+// of a repository
+// https://git-scm.com/docs/gitglossary#Documentation/gitglossary.txt-aiddefpathspecapathspec
+package pathspec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/env"
+)
+
+var (
+	// ErrInvalidPathspec is an error thrown when a pathspec cannot be
+	// parsed
+	ErrInvalidPathspec = errors.New("invalid pathspec")
+	// ErrIncompatibleOptions is an error thrown when options that
+	// cannot be used together are set
+	ErrIncompatibleOptions = errors.New("incompatible pathspec options")
+)
+
+// Options represents the global options that change how all the
+// pathspecs are interpreted
+type Options struct {
+	// Literal treats all the pathspecs literally: wildcards and magic
+	// signatures are disabled.
+	// Maps to $GIT_LITERAL_PATHSPECS
+	Literal bool
+	// Glob treats all the pathspecs as glob patterns, as if they all
+	// had the "glob" magic.
+	// Maps to $GIT_GLOB_PATHSPECS
+	Glob bool
+	// NoGlob treats all the pathspecs as literals, as if they all had
+	// the "literal" magic. Unlike Literal, magic signatures are
+	// still parsed.
+	// Maps to $GIT_NOGLOB_PATHSPECS
+	NoGlob bool
+	// ICase makes all the pathspecs case-insensitive, as if they all
+	// had the "icase" magic.
+	// Maps to $GIT_ICASE_PATHSPECS
+	ICase bool
+}
+
+// OptionsFromEnv returns the options set in the provided environment.
+// An error is returned if incompatible options are set, since a
+// wrapper script setting GIT_LITERAL_PATHSPECS expects its paths to
+// never be interpreted
+func OptionsFromEnv(e *env.Env) (Options, error) {
+	opts := Options{
+		Literal: envBool(e, "GIT_LITERAL_PATHSPECS"),
+		Glob:    envBool(e, "GIT_GLOB_PATHSPECS"),
+		NoGlob:  envBool(e, "GIT_NOGLOB_PATHSPECS"),
+		ICase:   envBool(e, "GIT_ICASE_PATHSPECS"),
+	}
+	if err := opts.validate(); err != nil {
+		return Options{}, err
+	}
+	return opts, nil
+}
+
+// validate returns an error if the options cannot be used together
+func (opts Options) validate() error {
+	if opts.Glob && opts.NoGlob {
+		return fmt.Errorf("global 'glob' and 'noglob' pathspec settings are incompatible: %w", ErrIncompatibleOptions)
+	}
+	if opts.Literal && (opts.Glob || opts.NoGlob || opts.ICase) {
+		return fmt.Errorf("global 'literal' pathspec setting is incompatible with all other global pathspec settings: %w", ErrIncompatibleOptions)
+	}
+	return nil
+}
+
+// envBool returns the boolean value of an environment variable
+func envBool(e *env.Env, key string) bool {
+	switch strings.ToLower(e.Get(key)) {
+	case "yes", "1", "true", "on":
+		return true
+	}
+	return false
+}
+
+// item represents a single pathspec
+type item struct {
+	pattern string
+	literal bool
+	// glob is set when wildcards cannot match "/"
+	glob    bool
+	icase   bool
+	exclude bool
+}
+
+// Pathspec represents a list of pathspecs
+type Pathspec struct {
+	includes []item
+	excludes []item
+}
+
+// New parses the provided pathspecs.
+// The pathspecs are expected to be relative to the root of the
+// repository. The following magic signatures are supported:
+//   - :(top) and :/ (no-op since the pathspecs are already relative to
+//     the root of the repository)
+//   - :(literal)
+//   - :(glob)
+//   - :(icase)
+//   - :(exclude), :! and :^
+//
+// An empty list of pathspecs matches everything
+func New(specs []string, opts Options) (*Pathspec, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Pathspec{}
+	for _, spec := range specs {
+		it, err := parse(spec, opts)
+		if err != nil {
+			return nil, err
+		}
+		if it.exclude {
+			p.excludes = append(p.excludes, it)
+			continue
+		}
+		p.includes = append(p.includes, it)
+	}
+	return p, nil
+}
+
+// parse parses a single pathspec
+func parse(spec string, opts Options) (item, error) {
+	it := item{
+		pattern: spec,
+		literal: opts.Literal || opts.NoGlob,
+		glob:    opts.Glob,
+		icase:   opts.ICase,
+	}
+	if opts.Literal || !strings.HasPrefix(spec, ":") {
+		return it, nil
+	}
+
+	rest := spec[1:]
+	if strings.HasPrefix(rest, "(") {
+		end := strings.IndexByte(rest, ')')
+		if end == -1 {
+			return item{}, fmt.Errorf("missing ')' at the end of the magic in '%s': %w", spec, ErrInvalidPathspec)
+		}
+		var literal, glob bool
+		for _, magic := range strings.Split(rest[1:end], ",") {
+			switch strings.TrimSpace(magic) {
+			case "top", "":
+			case "literal":
+				literal = true
+				it.literal = true
+				it.glob = false
+			case "glob":
+				glob = true
+				it.glob = true
+				it.literal = false
+			case "icase":
+				it.icase = true
+			case "exclude":
+				it.exclude = true
+			default:
+				return item{}, fmt.Errorf("unsupported magic '%s' in '%s': %w", magic, spec, ErrInvalidPathspec)
+			}
+		}
+		if literal && glob {
+			return item{}, fmt.Errorf("'literal' and 'glob' are incompatible in '%s': %w", spec, ErrInvalidPathspec)
+		}
+		rest = rest[end+1:]
+	} else {
+		// Short form. The magic stops at the first char that is not
+		// a magic signature, or at an optional ':'
+	loop:
+		for len(rest) > 0 {
+			switch rest[0] {
+			case '/':
+			case '!', '^':
+				it.exclude = true
+			case ':':
+				rest = rest[1:]
+				break loop
+			default:
+				break loop
+			}
+			rest = rest[1:]
+		}
+	}
+
+	it.pattern = rest
+	return it, nil
+}
+
+// Match returns whether the provided path is matched by the
+// pathspecs. path is expected to be a UNIX path relative to the root
+// of the repository
+func (p *Pathspec) Match(path string) bool {
+	included := len(p.includes) == 0
+	for _, it := range p.includes {
+		if it.match(path) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, it := range p.excludes {
+		if it.match(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns whether the pathspec matches the provided path
+func (it item) match(path string) bool {
+	pattern := it.pattern
+	if it.icase {
+		pattern = strings.ToLower(pattern)
+		path = strings.ToLower(path)
+	}
+
+	// An empty pathspec matches everything
+	if pattern == "" || pattern == "." {
+		return true
+	}
+	// A pathspec matches the path itself, and everything it contains
+	// if it's a directory
+	pattern = strings.TrimSuffix(pattern, "/")
+	if path == pattern || strings.HasPrefix(path, pattern+"/") {
+		return true
+	}
+	if it.literal {
+		return false
+	}
+	return wildmatch(pattern, path, it.glob)
+}
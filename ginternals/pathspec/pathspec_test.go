@@ -0,0 +1,150 @@
+package pathspec_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/pathspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	// The expected results have been generated using git ls-files
+	testCases := []struct {
+		desc     string
+		specs    []string
+		opts     pathspec.Options
+		path     string
+		expected bool
+	}{
+		{desc: "no specs should match everything", specs: nil, path: "a/b.go", expected: true},
+		{desc: "exact path", specs: []string{"a/b.go"}, path: "a/b.go", expected: true},
+		{desc: "directory", specs: []string{"a"}, path: "a/b/c.go", expected: true},
+		{desc: "directory with trailing slash", specs: []string{"a/b/"}, path: "a/b/c.go", expected: true},
+		{desc: "partial name", specs: []string{"a"}, path: "ab/c.go", expected: false},
+		{desc: "wildcard should match slashes", specs: []string{"*.go"}, path: "a/b/c.go", expected: true},
+		{desc: "wildcard in a directory", specs: []string{"a/*.go"}, path: "a/b/c.go", expected: true},
+		{desc: "question mark", specs: []string{"a/?.go"}, path: "a/b.go", expected: true},
+		{desc: "class", specs: []string{"[ab].go"}, path: "b.go", expected: true},
+		{desc: "negated class", specs: []string{"[!ab].go"}, path: "b.go", expected: false},
+		{desc: "range", specs: []string{"[a-c].go"}, path: "c.go", expected: true},
+		{desc: "escaped wildcard", specs: []string{`\*.go`}, path: "a.go", expected: false},
+		{desc: "glob wildcard should not match slashes", specs: []string{":(glob)*.go"}, path: "a/b.go", expected: false},
+		{desc: "glob wildcard in a directory", specs: []string{":(glob)a/*.go"}, path: "a/b.go", expected: true},
+		{desc: "glob double star", specs: []string{":(glob)**/*.go"}, path: "a/b/c.go", expected: true},
+		{desc: "glob double star at the end", specs: []string{":(glob)a/**"}, path: "a/b/c.go", expected: true},
+		{desc: "glob double star in a name", specs: []string{":(glob)a**.go"}, path: "ab/c.go", expected: false},
+		{desc: "glob should not match directories", specs: []string{":(glob)a*"}, path: "ab/c.go", expected: false},
+		{desc: "literal", specs: []string{":(literal)*.go"}, path: "a.go", expected: false},
+		{desc: "literal with wildcard in the name", specs: []string{":(literal)*.go"}, path: "*.go", expected: true},
+		{desc: "icase", specs: []string{":(icase)README.MD"}, path: "readme.md", expected: true},
+		{desc: "case sensitive", specs: []string{"README.MD"}, path: "readme.md", expected: false},
+		{desc: "top", specs: []string{":/a.go"}, path: "a.go", expected: true},
+		{desc: "exclude", specs: []string{":!*.go"}, path: "a.go", expected: false},
+		{desc: "exclude only should include the rest", specs: []string{":!*.go"}, path: "a.md", expected: true},
+		{desc: "long exclude", specs: []string{"a", ":(exclude)a/b"}, path: "a/b/c.go", expected: false},
+		{desc: "caret exclude", specs: []string{"a", ":^a/b"}, path: "a/c.go", expected: true},
+		{desc: "multiple specs", specs: []string{"a.go", "b.go"}, path: "b.go", expected: true},
+		{desc: "env literal", specs: []string{"*.go"}, opts: pathspec.Options{Literal: true}, path: "a.go", expected: false},
+		{desc: "env literal should ignore magic", specs: []string{":!a.go"}, opts: pathspec.Options{Literal: true}, path: ":!a.go", expected: true},
+		{desc: "env glob", specs: []string{"*.go"}, opts: pathspec.Options{Glob: true}, path: "a/b.go", expected: false},
+		{desc: "env noglob", specs: []string{"*.go"}, opts: pathspec.Options{NoGlob: true}, path: "a.go", expected: false},
+		{desc: "env noglob with glob magic", specs: []string{":(glob)*.go"}, opts: pathspec.Options{NoGlob: true}, path: "a.go", expected: true},
+		{desc: "env icase", specs: []string{"A.GO"}, opts: pathspec.Options{ICase: true}, path: "a.go", expected: true},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			p, err := pathspec.New(tc.specs, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, p.Match(tc.path))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		spec          string
+		expectedError error
+	}{
+		{desc: "unterminated magic", spec: ":(glob*.go", expectedError: pathspec.ErrInvalidPathspec},
+		{desc: "unknown magic", spec: ":(attr:foo)*.go", expectedError: pathspec.ErrInvalidPathspec},
+		{desc: "literal and glob", spec: ":(literal,glob)*.go", expectedError: pathspec.ErrInvalidPathspec},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			_, err := pathspec.New([]string{tc.spec}, pathspec.Options{})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		env           []string
+		expected      pathspec.Options
+		expectedError error
+	}{
+		{
+			desc:     "no vars",
+			expected: pathspec.Options{},
+		},
+		{
+			desc:     "literal",
+			env:      []string{"GIT_LITERAL_PATHSPECS=1"},
+			expected: pathspec.Options{Literal: true},
+		},
+		{
+			desc:     "false values",
+			env:      []string{"GIT_LITERAL_PATHSPECS=0", "GIT_GLOB_PATHSPECS=false"},
+			expected: pathspec.Options{},
+		},
+		{
+			desc:     "glob and icase",
+			env:      []string{"GIT_GLOB_PATHSPECS=true", "GIT_ICASE_PATHSPECS=yes"},
+			expected: pathspec.Options{Glob: true, ICase: true},
+		},
+		{
+			desc:          "glob and noglob",
+			env:           []string{"GIT_GLOB_PATHSPECS=1", "GIT_NOGLOB_PATHSPECS=1"},
+			expectedError: pathspec.ErrIncompatibleOptions,
+		},
+		{
+			desc:          "literal and icase",
+			env:           []string{"GIT_LITERAL_PATHSPECS=1", "GIT_ICASE_PATHSPECS=1"},
+			expectedError: pathspec.ErrIncompatibleOptions,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := pathspec.OptionsFromEnv(env.NewFromKVList(tc.env))
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, opts)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package pathspec
+
+import "strings"
+
+// Wildmatch returns whether the provided text matches the pattern,
+// using the same rules as git's wildmatch. It can be used to match
+// the patterns of the gitignore and gitattributes files.
+// See wildmatch() for the supported syntax
+func Wildmatch(pattern, text string, pathname bool) bool {
+	return wildmatch(pattern, text, pathname)
+}
+
+// wildmatch returns whether the provided text matches the pattern,
+// using the same rules as git's wildmatch:
+//   - "?" matches any single character
+//   - "*" matches any string, including an empty one
+//   - "[...]" matches any character of the set. The set can be negated
+//     with "!" or "^", and can contain ranges ("a-z")
+//   - "\" escapes the next character
+//
+// When pathname is set, the wildcards don't match "/", and "**"
+// matches any number of directories when it's a full path segment
+// ("**/", "/**/", or "/**")
+func wildmatch(pattern, text string, pathname bool) bool {
+	// segmentStart is set when we're at the beginning of a path
+	// segment of the pattern
+	segmentStart := true
+	for len(pattern) > 0 {
+		c := pattern[0]
+		wasSegmentStart := segmentStart
+		segmentStart = c == '/'
+		switch c {
+		case '\\':
+			if len(pattern) < 2 {
+				return false
+			}
+			if len(text) == 0 || text[0] != pattern[1] {
+				return false
+			}
+			pattern = pattern[2:]
+			text = text[1:]
+		case '?':
+			if len(text) == 0 || (pathname && text[0] == '/') {
+				return false
+			}
+			pattern = pattern[1:]
+			text = text[1:]
+		case '[':
+			if len(text) == 0 || (pathname && text[0] == '/') {
+				return false
+			}
+			matched, size, ok := matchClass(pattern, text[0])
+			if !ok {
+				// An unterminated class is matched literally
+				if text[0] != '[' {
+					return false
+				}
+				pattern = pattern[1:]
+				text = text[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			pattern = pattern[size:]
+			text = text[1:]
+		case '*':
+			return matchStar(pattern, text, pathname, wasSegmentStart)
+		default:
+			if len(text) == 0 || text[0] != c {
+				return false
+			}
+			pattern = pattern[1:]
+			text = text[1:]
+		}
+	}
+	return len(text) == 0
+}
+
+// matchStar matches a pattern starting with "*". segmentStart is set
+// if the "*" is at the beginning of a path segment
+func matchStar(pattern, text string, pathname, segmentStart bool) bool {
+	stars := 1
+	for stars < len(pattern) && pattern[stars] == '*' {
+		stars++
+	}
+	rest := pattern[stars:]
+
+	// "**" is only special if it's a full path segment, otherwise
+	// it's the same as "*"
+	if pathname && stars >= 2 && segmentStart {
+		switch {
+		case rest == "":
+			return true
+		case rest[0] == '/':
+			// "**/" matches zero or more directories
+			if wildmatch(rest[1:], text, pathname) {
+				return true
+			}
+			for i := 0; i < len(text); i++ {
+				if text[i] == '/' && wildmatch(rest[1:], text[i+1:], pathname) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	if rest == "" {
+		return !pathname || !strings.Contains(text, "/")
+	}
+	for i := 0; i <= len(text); i++ {
+		if wildmatch(rest, text[i:], pathname) {
+			return true
+		}
+		if i < len(text) && pathname && text[i] == '/' {
+			return false
+		}
+	}
+	return false
+}
+
+// matchClass returns whether c is part of the class at the beginning
+// of pattern, and the size of the class. ok is false if the class
+// is not terminated
+func matchClass(pattern string, c byte) (matched bool, size int, ok bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+	first := true
+	for i < len(pattern) {
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		first = false
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		i++
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi = pattern[i+1]
+			if hi == '\\' && i+2 < len(pattern) {
+				i++
+				hi = pattern[i+1]
+			}
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	return false, 0, false
+}
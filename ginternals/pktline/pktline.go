@@ -0,0 +1,219 @@
+// Package pktline contains methods to read and write pkt-lines, the
+// framing used by the git protocols
+// https://git-scm.com/docs/protocol-common#_pkt_line_format
+package pktline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+const (
+	// lengthSize is the number of hex chars used to encode the length
+	// of a packet
+	lengthSize = 4
+	// MaxPacketSize is the maximum size of a packet, length included
+	MaxPacketSize = 65520
+	// MaxPayloadSize is the maximum size of the data of a packet
+	MaxPayloadSize = MaxPacketSize - lengthSize
+)
+
+// Special packets that don't contain any data
+const (
+	// Flush is the packet used to mark the end of a message
+	Flush = "0000"
+	// Delim is the packet used to separate the sections of a message
+	// in the protocol v2
+	Delim = "0001"
+	// ResponseEnd is the packet used to mark the end of a response
+	// in the protocol v2, when the connection is stateless
+	ResponseEnd = "0002"
+)
+
+// ErrInvalidPacket is an error thrown when a packet cannot be parsed
+var ErrInvalidPacket = errors.New("invalid pkt-line")
+
+// PacketType represents the type of a packet
+type PacketType int8
+
+const (
+	// DataPacket represents a packet containing data
+	DataPacket PacketType = iota
+	// FlushPacket represents a flush-pkt (0000)
+	FlushPacket
+	// DelimPacket represents a delim-pkt (0001)
+	DelimPacket
+	// ResponseEndPacket represents a response-end-pkt (0002)
+	ResponseEndPacket
+)
+
+// Options represents the options that can be used to create a Reader
+// or a Writer
+type Options struct {
+	// Trace receives all the packets read or written, as done by
+	// GIT_TRACE_PACKET. Nothing is traced if nil
+	Trace *trace.Tracer
+	// TraceIdentity is the name of the program shown in the traces.
+	// Defaults to "git"
+	TraceIdentity string
+}
+
+// traceIdentity returns the identity to use in the traces
+func (opts Options) traceIdentity() string {
+	if opts.TraceIdentity == "" {
+		return "git"
+	}
+	return opts.TraceIdentity
+}
+
+// Reader reads pkt-lines from a stream
+type Reader struct {
+	r    io.Reader
+	buf  [MaxPacketSize]byte
+	opts Options
+}
+
+// NewReader returns a Reader reading from r
+func NewReader(r io.Reader) *Reader {
+	return NewReaderWithOptions(r, Options{})
+}
+
+// NewReaderWithOptions returns a Reader reading from r
+func NewReaderWithOptions(r io.Reader, opts Options) *Reader {
+	return &Reader{
+		r:    r,
+		opts: opts,
+	}
+}
+
+// ReadPacket reads the next packet of the stream.
+// The returned data are only valid until the next call to ReadPacket.
+// io.EOF is returned if the stream ended before a new packet
+func (r *Reader) ReadPacket() (typ PacketType, data []byte, err error) {
+	length := r.buf[:lengthSize]
+	if _, err = io.ReadFull(r.r, length); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("truncated length: %w", ErrInvalidPacket)
+		}
+		return 0, nil, err
+	}
+	size, err := strconv.ParseUint(string(length), 16, 16)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid length %q: %w", length, ErrInvalidPacket)
+	}
+	switch {
+	case size == 0:
+		r.trace(length)
+		return FlushPacket, nil, nil
+	case size == 1:
+		r.trace(length)
+		return DelimPacket, nil, nil
+	case size == 2:
+		r.trace(length)
+		return ResponseEndPacket, nil, nil
+	case size < lengthSize, size > MaxPacketSize:
+		return 0, nil, fmt.Errorf("invalid length %d: %w", size, ErrInvalidPacket)
+	}
+
+	data = r.buf[lengthSize:size]
+	if _, err = io.ReadFull(r.r, data); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("truncated packet: %w", ErrInvalidPacket)
+		}
+		return 0, nil, fmt.Errorf("could not read packet: %w", err)
+	}
+	r.trace(data)
+	return DataPacket, data, nil
+}
+
+// trace traces a packet that has been read
+func (r *Reader) trace(data []byte) {
+	r.opts.Trace.Packet(r.opts.traceIdentity(), false, data)
+}
+
+// ReadLine reads the next data packet of the stream, without its
+// trailing line feed.
+// io.EOF is returned if the stream ended before a new packet, and
+// ok is set to false if the packet is a special packet
+func (r *Reader) ReadLine() (line []byte, ok bool, err error) {
+	typ, data, err := r.ReadPacket()
+	if err != nil {
+		return nil, false, err
+	}
+	if typ != DataPacket {
+		return nil, false, nil
+	}
+	if len(data) > 0 && data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+	}
+	return data, true, nil
+}
+
+// Encode returns data as a pkt-line.
+// data is expected to be smaller than MaxPayloadSize
+func Encode(data []byte) []byte {
+	out := make([]byte, lengthSize, lengthSize+len(data))
+	copy(out, fmt.Sprintf("%04x", len(data)+lengthSize))
+	return append(out, data...)
+}
+
+// Writer writes pkt-lines to a stream
+type Writer struct {
+	w    io.Writer
+	opts Options
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterWithOptions(w, Options{})
+}
+
+// NewWriterWithOptions returns a Writer writing to w
+func NewWriterWithOptions(w io.Writer, opts Options) *Writer {
+	return &Writer{
+		w:    w,
+		opts: opts,
+	}
+}
+
+// WritePacket writes data in a single packet.
+// An error is returned if the data don't fit in a single packet
+func (w *Writer) WritePacket(data []byte) error {
+	if len(data) > MaxPayloadSize {
+		return fmt.Errorf("packet of %d bytes is too big: %w", len(data), ErrInvalidPacket)
+	}
+	w.opts.Trace.Packet(w.opts.traceIdentity(), true, data)
+	if _, err := w.w.Write(Encode(data)); err != nil {
+		return fmt.Errorf("could not write packet: %w", err)
+	}
+	return nil
+}
+
+// WriteLine writes a line in a single packet, adding a trailing
+// line feed
+func (w *Writer) WriteLine(line string) error {
+	return w.WritePacket([]byte(line + "\n"))
+}
+
+// WriteFlush writes a flush-pkt
+func (w *Writer) WriteFlush() error {
+	return w.writeSpecial(Flush)
+}
+
+// WriteDelim writes a delim-pkt
+func (w *Writer) WriteDelim() error {
+	return w.writeSpecial(Delim)
+}
+
+// writeSpecial writes a packet that has no data
+func (w *Writer) writeSpecial(pkt string) error {
+	w.opts.Trace.Packet(w.opts.traceIdentity(), true, []byte(pkt))
+	if _, err := io.WriteString(w.w, pkt); err != nil {
+		return fmt.Errorf("could not write packet: %w", err)
+	}
+	return nil
+}
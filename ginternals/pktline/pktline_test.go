@@ -0,0 +1,147 @@
+package pktline_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "0006a\n", string(pktline.Encode([]byte("a\n"))))
+	assert.Equal(t, "0004", string(pktline.Encode(nil)))
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should read all the packets", func(t *testing.T) {
+		t.Parallel()
+
+		r := pktline.NewReader(strings.NewReader("0006a\n0001000bfoobar\n0000"))
+
+		typ, data, err := r.ReadPacket()
+		require.NoError(t, err)
+		assert.Equal(t, pktline.DataPacket, typ)
+		assert.Equal(t, "a\n", string(data))
+
+		typ, _, err = r.ReadPacket()
+		require.NoError(t, err)
+		assert.Equal(t, pktline.DelimPacket, typ)
+
+		line, ok, err := r.ReadLine()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "foobar", string(line))
+
+		_, ok, err = r.ReadLine()
+		require.NoError(t, err)
+		assert.False(t, ok, "a flush-pkt should have been returned")
+
+		_, _, err = r.ReadPacket()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	testCases := []struct {
+		desc  string
+		input string
+	}{
+		{
+			desc:  "truncated length",
+			input: "00",
+		},
+		{
+			desc:  "invalid length",
+			input: "zzzz",
+		},
+		{
+			desc:  "length smaller than the header",
+			input: "0003",
+		},
+		{
+			desc:  "truncated packet",
+			input: "000aabc",
+		},
+		{
+			desc:  "packet too big",
+			input: "fff1",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := pktline.NewReader(strings.NewReader(tc.input)).ReadPacket()
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, pktline.ErrInvalidPacket), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should write packets", func(t *testing.T) {
+		t.Parallel()
+
+		buf := new(bytes.Buffer)
+		w := pktline.NewWriter(buf)
+		require.NoError(t, w.WriteLine("command=ls-refs"))
+		require.NoError(t, w.WriteDelim())
+		require.NoError(t, w.WritePacket([]byte("peel")))
+		require.NoError(t, w.WriteFlush())
+		assert.Equal(t, "0014command=ls-refs\n00010008peel0000", buf.String())
+	})
+
+	t.Run("should fail with a packet too big", func(t *testing.T) {
+		t.Parallel()
+
+		w := pktline.NewWriter(io.Discard)
+		err := w.WritePacket(make([]byte, pktline.MaxPayloadSize+1))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, pktline.ErrInvalidPacket), "unexpected error: %v", err)
+	})
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	opts := pktline.Options{
+		Trace:         trace.NewWithWriter(buf),
+		TraceIdentity: "fetch",
+	}
+
+	w := pktline.NewWriterWithOptions(io.Discard, opts)
+	require.NoError(t, w.WriteLine("command=ls-refs"))
+	require.NoError(t, w.WriteFlush())
+
+	r := pktline.NewReaderWithOptions(strings.NewReader("0006a\n0000"), pktline.Options{Trace: opts.Trace})
+	_, _, err := r.ReadPacket()
+	require.NoError(t, err)
+	_, _, err = r.ReadPacket()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	expected := []string{
+		"packet:        fetch> command=ls-refs",
+		"packet:        fetch> 0000",
+		"packet:          git< a",
+		"packet:          git< 0000",
+	}
+	for i, line := range lines {
+		assert.True(t, strings.HasSuffix(line, " "+expected[i]), "unexpected trace %q", line)
+	}
+}
@@ -21,17 +21,17 @@ const (
 	// CherryPickHead is a reference to the commit that is being
 	// cherry-picked
 	CherryPickHead = "CHERRY_PICK_HEAD"
+	// BisectHead is a reference to the commit currently being tested
+	// during a bisect session
+	BisectHead = "BISECT_HEAD"
 	// Master correspond to the default branch name if none was
 	// specified
 	Master = "master"
 
-	// FetchHead is a reference to the most recently fetched branch
-	// TODO(melvin): Removed because the format is not currently
-	// supported. It's a list of commit IDs with the branch name,
-	// the origin, and other extra information. Example:
-	//     bbb720a96e4c29b9950a4c577c98470a4d5dd089		branch 'master' of github.com:Nivl/git-go
-	//     5f35f2dc6cec7356da02ca26192ce2bc3f271e79	not-for-merge	branch 'ml/feat/clone' of github.com:Nivl/git-go
-	// FetchHead = "FETCH_HEAD"
+	// FetchHead is a reference to the most recently fetched branches.
+	// It contains one line per fetched ref, and resolves to the
+	// first one. See ParseFetchHead()
+	FetchHead = "FETCH_HEAD"
 )
 
 var (
@@ -57,8 +57,46 @@ var (
 	// ErrUnknownRefType is an error thrown when the type of a reference
 	// is unknown
 	ErrUnknownRefType = errors.New("unknown reference type")
+
+	// ErrNotPseudoRef is an error thrown when a reference is used as a
+	// pseudo-ref but isn't one
+	ErrNotPseudoRef = errors.New("not a pseudo-ref")
+
+	// ErrRefCaseConflict is an error thrown when creating a reference
+	// whose name only differs by its case from an existing reference,
+	// on a case-insensitive filesystem
+	ErrRefCaseConflict = errors.New("reference conflicts with another reference on a case-insensitive filesystem")
+
+	// ErrRefUpdateInQuarantine is an error thrown when trying to
+	// update a reference while the objects are quarantined (while
+	// running a pre-receive hook, for example)
+	ErrRefUpdateInQuarantine = errors.New("ref updates forbidden inside quarantine environment")
 )
 
+// PseudoRefs returns the list of pseudo-refs supported by the library.
+// Pseudo-refs are references stored at the root of the git directory
+// and used to keep track of in-progress operations.
+func PseudoRefs() []string {
+	return []string{
+		OrigHead,
+		MergeHead,
+		CherryPickHead,
+		BisectHead,
+		FetchHead,
+	}
+}
+
+// IsPseudoRef returns whether the given name is a pseudo-ref supported
+// by the library
+func IsPseudoRef(name string) bool {
+	for _, n := range PseudoRefs() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ReferenceType represents the type of a reference
 type ReferenceType int8
 
@@ -103,6 +141,36 @@ func resolveRefs(name string, finder RefContent, visited map[string]struct{}) (*
 		return nil, fmt.Errorf(`ref "%s": %w`, name, ErrRefNameInvalid)
 	}
 
+	ref, err := readReference(name, finder)
+	if err != nil {
+		return nil, err
+	}
+
+	// if the reference is symbolic, we need to follow to get the target
+	if ref.typ == SymbolicReference {
+		target, err := resolveRefs(ref.target, finder, visited)
+		if err != nil {
+			return nil, err
+		}
+		ref.id = target.id
+	}
+	return ref, nil
+}
+
+// ReadReference returns a reference without following its symbolic
+// target. The Target() of a symbolic reference returned by this
+// method is NullOid.
+// This is useful to read a reference targeting a reference that
+// doesn't exist yet, like HEAD in an empty repository
+func ReadReference(name string, finder RefContent) (*Reference, error) {
+	if !IsRefNameValid(name) {
+		return nil, fmt.Errorf(`ref "%s": %w`, name, ErrRefNameInvalid)
+	}
+	return readReference(name, finder)
+}
+
+// readReference parses the content of a reference
+func readReference(name string, finder RefContent) (*Reference, error) {
 	data, err := finder(name)
 	if err != nil {
 		return nil, err
@@ -115,21 +183,20 @@ func resolveRefs(name string, finder RefContent, visited map[string]struct{}) (*
 		return nil, ErrRefInvalid
 	}
 
-	// if the reference is symbolic, we need to follow to get the target
 	if string(data[0:5]) == "ref: " {
-		symbolicTarget := string(data[5:])
-		ref, err := resolveRefs(symbolicTarget, finder, visited)
-		if err != nil {
-			return nil, err
-		}
 		return &Reference{
 			typ:    SymbolicReference,
 			name:   name,
-			id:     ref.id,
-			target: symbolicTarget,
+			target: string(data[5:]),
 		}, nil
 	}
 
+	// Like git, we ignore anything following the oid. This allows
+	// reading the pseudo-refs that contain more than one oid, or
+	// extra data, like MERGE_HEAD or FETCH_HEAD
+	if len(data) > OidHexSize && isSpace(data[OidHexSize]) {
+		data = data[:OidHexSize]
+	}
 	oid, err := NewOidFromChars(data)
 	if err != nil {
 		return nil, ErrRefInvalid
@@ -141,6 +208,11 @@ func resolveRefs(name string, finder RefContent, visited map[string]struct{}) (*
 	}, nil
 }
 
+// isSpace returns whether c is an ASCII whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
 // NewReference return a new Reference object that targets
 // an object
 func NewReference(name string, target Oid) *Reference {
@@ -183,59 +255,110 @@ func (ref *Reference) SymbolicTarget() string {
 	return ref.target
 }
 
-// IsRefNameValid returns whether the name of a reference is valid or not
-// https://stackoverflow.com/a/12093994/382879
-func IsRefNameValid(name string) bool {
-	// the reference name cannot:
-	// - be empty
-	// - start by a "/"
-	// - end by a "/"
-	// - end by .
-	if name == "" || name == "/" || name[len(name)-1] == '/' || name[len(name)-1] == '.' {
-		return false
-	}
-
-	// the reference name cannot contain:
-	// - *
-	// - ?
-	// - ~
-	// - :
-	// - ^
-	// - @{
-	// - \
-	// - ..
-	// - [
-	// - a space
-	// - an ASCII char below 32 or a DEL (ASCII 127)
-	for i, c := range name {
-		if c < 32 || c == 127 {
-			return false
-		}
-		if c == '*' || c == '?' || c == '!' || c == '^' {
-			return false
+// RefFormatOptions contains the options used to validate the name
+// of a reference
+type RefFormatOptions struct {
+	// AllowOneLevel allows names made of a single component, like
+	// HEAD or ORIG_HEAD. Without this option the name needs to
+	// contain at least one "/"
+	AllowOneLevel bool
+	// RefspecPattern allows the name to contain a single "*" that
+	// can be used as a wildcard, like in refs/heads/*
+	RefspecPattern bool
+	// Normalize removes the leading "/" and collapses the consecutive
+	// "/" of the name before validating it
+	Normalize bool
+}
+
+// CheckRefFormat validates the name of a reference using the same
+// rules as git check-ref-format. The name (normalized if
+// opts.Normalize is set) is returned if it's valid, otherwise an error
+// wrapping ErrRefNameInvalid is returned.
+// A name cannot:
+//   - be empty or be "@"
+//   - contain ASCII control chars, a DEL (ASCII 127), a space, "~", "^",
+//     ":", "?", "[", "\", or "*" (unless opts.RefspecPattern is set)
+//   - contain ".." or "@{"
+//   - end with a "/" or a "."
+//   - contain a component that is empty, that starts with a ".", or
+//     that ends with ".lock"
+//
+// https://git-scm.com/docs/git-check-ref-format
+func CheckRefFormat(name string, opts RefFormatOptions) (string, error) {
+	if opts.Normalize {
+		name = normalizeRefName(name)
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("name cannot be empty: %w", ErrRefNameInvalid)
+	}
+	if name == "@" {
+		return "", fmt.Errorf(`"@" is not a valid name: %w`, ErrRefNameInvalid)
+	}
+	if name[len(name)-1] == '/' || name[len(name)-1] == '.' {
+		return "", fmt.Errorf("%q cannot end with %q: %w", name, name[len(name)-1], ErrRefNameInvalid)
+	}
+
+	hasWildcard := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch c {
+		case '~', '^', ':', '?', '[', '\\', ' ':
+			return "", fmt.Errorf("%q cannot contain %q: %w", name, c, ErrRefNameInvalid)
+		case '*':
+			if !opts.RefspecPattern || hasWildcard {
+				return "", fmt.Errorf("%q cannot contain %q: %w", name, c, ErrRefNameInvalid)
+			}
+			hasWildcard = true
+		case '.':
+			if i+1 < len(name) && name[i+1] == '.' {
+				return "", fmt.Errorf(`%q cannot contain "..": %w`, name, ErrRefNameInvalid)
+			}
+		case '@':
+			if i+1 < len(name) && name[i+1] == '{' {
+				return "", fmt.Errorf(`%q cannot contain "@{": %w`, name, ErrRefNameInvalid)
+			}
 		}
-		if c == ' ' || c == '[' || c == '\\' || c == ':' {
-			return false
+		if c < 32 || c == 127 {
+			return "", fmt.Errorf("%q cannot contain control characters: %w", name, ErrRefNameInvalid)
 		}
-		if i < len(name)-1 {
-			substr := name[i : i+2]
-			if substr == "@{" || substr == ".." {
-				return false
-			}
+	}
+
+	components := strings.Split(name, "/")
+	if len(components) < 2 && !opts.AllowOneLevel {
+		return "", fmt.Errorf("%q needs to contain at least one \"/\": %w", name, ErrRefNameInvalid)
+	}
+	for _, c := range components {
+		switch {
+		case c == "":
+			return "", fmt.Errorf("%q cannot contain empty components: %w", name, ErrRefNameInvalid)
+		case c[0] == '.':
+			return "", fmt.Errorf("%q cannot contain components starting with \".\": %w", name, ErrRefNameInvalid)
+		case strings.HasSuffix(c, ".lock"):
+			return "", fmt.Errorf("%q cannot contain components ending with \".lock\": %w", name, ErrRefNameInvalid)
 		}
 	}
+	return name, nil
+}
 
-	segments := strings.Split(name, "/")
-	for _, s := range segments {
-		// no segment cannot:
-		// - be empty
-		// - start by a dot
-		// - end by a dot
-		// - end by ".lock"
-		if s == "" || s[0] == '.' || s[len(s)-1] == '.' || strings.HasSuffix(s, ".lock") {
-			return false
+// normalizeRefName removes the leading "/" of a name, and collapses
+// its consecutive "/"
+func normalizeRefName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name))
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' && (sb.Len() == 0 || name[i-1] == '/') {
+			continue
 		}
+		sb.WriteByte(name[i])
 	}
+	return sb.String()
+}
 
-	return true
+// IsRefNameValid returns whether the name of a reference is valid or
+// not. One-level names, like HEAD, are considered valid.
+// Use CheckRefFormat to get the reason why a name is invalid
+func IsRefNameValid(name string) bool {
+	_, err := CheckRefFormat(name, RefFormatOptions{AllowOneLevel: true})
+	return err == nil
 }
@@ -113,15 +113,30 @@ func TestIsRefNameValid(t *testing.T) {
 			shouldPass: false,
 		},
 		{
-			desc:       "segments cannot end with a .",
+			desc:       "segments can end with a .",
 			name:       "refs/heads./master",
-			shouldPass: false,
+			shouldPass: true,
 		},
 		{
 			desc:       "segments cannot end with .lock",
 			name:       "refs/heads.lock/master",
 			shouldPass: false,
 		},
+		{
+			desc:       "name cannot contain ~",
+			name:       "refs/heads/ma~ster",
+			shouldPass: false,
+		},
+		{
+			desc:       "name can contain !",
+			name:       "refs/heads/ma!ster",
+			shouldPass: true,
+		},
+		{
+			desc:       "name cannot be @",
+			name:       "@",
+			shouldPass: false,
+		},
 		{
 			desc:       "HEAD should be a valid reference",
 			name:       "HEAD",
@@ -139,6 +154,123 @@ func TestIsRefNameValid(t *testing.T) {
 	}
 }
 
+func TestCheckRefFormat(t *testing.T) {
+	t.Parallel()
+
+	// The expected results have been generated using
+	// git check-ref-format
+	testCases := []struct {
+		desc       string
+		name       string
+		opts       RefFormatOptions
+		expected   string
+		shouldFail bool
+	}{
+		{
+			desc:     "valid name should pass",
+			name:     "refs/heads/master",
+			expected: "refs/heads/master",
+		},
+		{
+			desc:       "one-level name should fail by default",
+			name:       "master",
+			shouldFail: true,
+		},
+		{
+			desc:     "one-level name should pass with AllowOneLevel",
+			name:     "master",
+			opts:     RefFormatOptions{AllowOneLevel: true},
+			expected: "master",
+		},
+		{
+			desc:     "@ can be a component",
+			name:     "refs/@",
+			expected: "refs/@",
+		},
+		{
+			desc:       "* should fail by default",
+			name:       "refs/heads/*",
+			shouldFail: true,
+		},
+		{
+			desc:     "* should pass with RefspecPattern",
+			name:     "refs/heads/*",
+			opts:     RefFormatOptions{RefspecPattern: true},
+			expected: "refs/heads/*",
+		},
+		{
+			desc:     "* can be in the middle of a component",
+			name:     "refs/a*b",
+			opts:     RefFormatOptions{RefspecPattern: true},
+			expected: "refs/a*b",
+		},
+		{
+			desc:       "only one * is allowed",
+			name:       "refs/*/*",
+			opts:       RefFormatOptions{RefspecPattern: true},
+			shouldFail: true,
+		},
+		{
+			desc:       "consecutive slashes should fail by default",
+			name:       "refs//heads/master",
+			shouldFail: true,
+		},
+		{
+			desc:     "slashes should be collapsed with Normalize",
+			name:     "//refs///heads/master",
+			opts:     RefFormatOptions{Normalize: true},
+			expected: "refs/heads/master",
+		},
+		{
+			desc:     "normalized one-level name should pass",
+			name:     "/master",
+			opts:     RefFormatOptions{Normalize: true, AllowOneLevel: true},
+			expected: "master",
+		},
+		{
+			desc:       "trailing slash should fail with Normalize",
+			name:       "refs/heads/master/",
+			opts:       RefFormatOptions{Normalize: true},
+			shouldFail: true,
+		},
+		{
+			desc:       "components cannot end with .lock",
+			name:       "refs/a.lock/b",
+			shouldFail: true,
+		},
+		{
+			desc:       "components cannot start with a .",
+			name:       "refs/heads/.a",
+			shouldFail: true,
+		},
+		{
+			desc:     "non-ASCII chars should pass",
+			name:     "refs/heads/é",
+			expected: "refs/heads/é",
+		},
+		{
+			desc:     "{ and @ should pass when not together",
+			name:     "refs/heads/a{b@c",
+			expected: "refs/heads/a{b@c",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			name, err := CheckRefFormat(tc.name, tc.opts)
+			if tc.shouldFail {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrRefNameInvalid), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+}
+
 func TestResolveReference(t *testing.T) {
 	t.Parallel()
 
@@ -233,6 +365,22 @@ func TestResolveReference(t *testing.T) {
 		assert.True(t, errors.Is(err, ErrRefInvalid), "invalid error returned")
 	})
 
+	t.Run("should ignore the data following the oid", func(t *testing.T) {
+		t.Parallel()
+
+		finder := func(name string) ([]byte, error) {
+			switch name {
+			case "FETCH_HEAD":
+				return []byte("0eaf966ff79d8f61958aaefe163620d952606516\t\tbranch 'master' of github.com:Nivl/git-go\n"), nil
+			default:
+				return nil, errors.New("unexpected")
+			}
+		}
+		ref, err := ResolveReference("FETCH_HEAD", finder)
+		require.NoError(t, err)
+		assert.Equal(t, "0eaf966ff79d8f61958aaefe163620d952606516", ref.Target().String())
+	})
+
 	t.Run("should fail on empty file", func(t *testing.T) {
 		t.Parallel()
 
@@ -286,3 +434,28 @@ func TestNewSymbolicReference(t *testing.T) {
 	assert.Equal(t, NullOid, ref.Target())
 	assert.Equal(t, "refs/heads/master", ref.SymbolicTarget())
 }
+
+func TestIsPseudoRef(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{name: OrigHead, expected: true},
+		{name: MergeHead, expected: true},
+		{name: CherryPickHead, expected: true},
+		{name: BisectHead, expected: true},
+		{name: Head, expected: false},
+		{name: FetchHead, expected: true},
+		{name: "refs/heads/ORIG_HEAD", expected: false},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.name), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, IsPseudoRef(tc.name))
+		})
+	}
+}
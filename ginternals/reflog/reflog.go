@@ -0,0 +1,102 @@
+// Package reflog contains methods to parse the reflogs, which record
+// when the tip of a reference was updated
+// https://git-scm.com/docs/git-reflog
+package reflog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrEntryInvalid is an error thrown when an entry of a reflog cannot
+// be parsed
+var ErrEntryInvalid = errors.New("invalid reflog entry")
+
+// Entry represents a single update of a reference
+type Entry struct {
+	// Old contains the previous target of the reference. It's
+	// ginternals.NullOid if the reference has been created
+	Old ginternals.Oid
+	// New contains the new target of the reference
+	New ginternals.Oid
+	// Committer contains the person who updated the reference,
+	// and when
+	Committer object.Signature
+	// Message contains the reason of the update
+	Message string
+}
+
+// Parse parses the content of a reflog. The entries are returned
+// in the same order as the file, oldest first
+func Parse(r io.Reader) ([]*Entry, error) {
+	entries := []*Entry{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		e, err := ParseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse entry %d: %w", len(entries), err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the reflog: %w", err)
+	}
+	return entries, nil
+}
+
+// ParseEntry parses a single line of a reflog.
+// A line has the following format:
+//
+//	old_oid new_oid User Name <user.email@domain.tld> timestamp timezone\tmessage
+func ParseEntry(line []byte) (*Entry, error) {
+	// 2 oids, each followed by a space
+	minSize := 2 * (ginternals.OidHexSize + 1)
+	if len(line) < minSize || line[ginternals.OidHexSize] != ' ' || line[minSize-1] != ' ' {
+		return nil, fmt.Errorf("could not find the oids: %w", ErrEntryInvalid)
+	}
+	e := &Entry{}
+	var err error
+	e.Old, err = ginternals.NewOidFromChars(line[:ginternals.OidHexSize])
+	if err != nil {
+		return nil, fmt.Errorf("invalid old oid: %w", err)
+	}
+	e.New, err = ginternals.NewOidFromChars(line[ginternals.OidHexSize+1 : minSize-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid new oid: %w", err)
+	}
+
+	sig := line[minSize:]
+	// The message is optional
+	if i := bytes.IndexByte(sig, '\t'); i != -1 {
+		e.Message = string(sig[i+1:])
+		sig = sig[:i]
+	}
+	e.Committer, err = object.NewSignatureFromBytes(sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	return e, nil
+}
+
+// String returns the entry as a line of a reflog, without the
+// trailing line feed.
+// Like git, the whitespaces of the message are collapsed so the
+// message stays on a single line
+func (e *Entry) String() string {
+	line := fmt.Sprintf("%s %s %s", e.Old.String(), e.New.String(), e.Committer.String())
+	if msg := strings.Join(strings.Fields(e.Message), " "); msg != "" {
+		line += "\t" + msg
+	}
+	return line
+}
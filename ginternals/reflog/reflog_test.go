@@ -0,0 +1,116 @@
+package reflog_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/reflog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Join([]string{
+		"0000000000000000000000000000000000000000 f0f70144f38695250606b86a50cff2b440a417f3 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597448 -0700\tclone: from git@github.com:Nivl/git-go.git",
+		"f0f70144f38695250606b86a50cff2b440a417f3 bbb720a96e4c29b9950a4c577c98470a4d5dd089 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597467 -0700",
+		"",
+	}, "\n")
+	entries, err := reflog.Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.True(t, entries[0].Old.IsZero())
+	assert.Equal(t, "f0f70144f38695250606b86a50cff2b440a417f3", entries[0].New.String())
+	assert.Equal(t, "Melvin Laplanche", entries[0].Committer.Name)
+	assert.Equal(t, "melvin.wont.reply@gmail.com", entries[0].Committer.Email)
+	assert.Equal(t, int64(1592597448), entries[0].Committer.Time.Unix())
+	assert.Equal(t, "clone: from git@github.com:Nivl/git-go.git", entries[0].Message)
+
+	assert.Equal(t, "f0f70144f38695250606b86a50cff2b440a417f3", entries[1].Old.String())
+	assert.Empty(t, entries[1].Message)
+}
+
+func TestParseEntry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		line          string
+		expectedError error
+	}{
+		{
+			desc:          "missing oids",
+			line:          "f0f70144f38695250606b86a50cff2b440a417f3\tmessage",
+			expectedError: reflog.ErrEntryInvalid,
+		},
+		{
+			desc:          "invalid oid",
+			line:          "z0f70144f38695250606b86a50cff2b440a417f3 f0f70144f38695250606b86a50cff2b440a417f3 Melvin <m@domain.tld> 1592597448 -0700\tmessage",
+			expectedError: nil,
+		},
+		{
+			desc:          "invalid signature",
+			line:          "f0f70144f38695250606b86a50cff2b440a417f3 f0f70144f38695250606b86a50cff2b440a417f3 Melvin\tmessage",
+			expectedError: object.ErrSignatureInvalid,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			_, err := reflog.ParseEntry([]byte(tc.line))
+			require.Error(t, err)
+			if tc.expectedError != nil {
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestEntryString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		message  string
+		expected string
+	}{
+		{
+			desc:     "entry with a message",
+			message:  "checkout: moving from master to ml/tests",
+			expected: "0000000000000000000000000000000000000000 f0f70144f38695250606b86a50cff2b440a417f3 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597448 -0700\tcheckout: moving from master to ml/tests",
+		},
+		{
+			desc:     "entry without message",
+			expected: "0000000000000000000000000000000000000000 f0f70144f38695250606b86a50cff2b440a417f3 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597448 -0700",
+		},
+		{
+			desc:     "multi-line message",
+			message:  "commit: subject\n\n  body\n",
+			expected: "0000000000000000000000000000000000000000 f0f70144f38695250606b86a50cff2b440a417f3 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597448 -0700\tcommit: subject body",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			line := "0000000000000000000000000000000000000000 f0f70144f38695250606b86a50cff2b440a417f3 Melvin Laplanche <melvin.wont.reply@gmail.com> 1592597448 -0700"
+			e, err := reflog.ParseEntry([]byte(line))
+			require.NoError(t, err)
+			e.Message = tc.message
+			assert.Equal(t, tc.expected, e.String())
+
+			// the line should be parsable
+			parsed, err := reflog.ParseEntry([]byte(e.String()))
+			require.NoError(t, err)
+			assert.Equal(t, e.New, parsed.New)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Package trace implements the GIT_TRACE, GIT_TRACE_PACKET, and
+// GIT_TRACE_PERFORMANCE environment variables, using the same output
+// format as git so the traces can be compared with the ones of git.
+//
+// Like git, a trace can be sent to stderr ("1", "2", or "true"), to an
+// already opened file descriptor ("3" to "9"), or appended to a file
+// (an absolute path). Tracing is disabled when the variable is empty,
+// "0", or "false".
+//
+// All the methods can be called on a nil Tracer, in which case they
+// do nothing.
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nivl/git-go/env"
+)
+
+const (
+	// KeyTrace is the variable used to trace the commands
+	KeyTrace = "GIT_TRACE"
+	// KeyPacket is the variable used to trace the pkt-lines sent and
+	// received by the transports
+	KeyPacket = "GIT_TRACE_PACKET"
+	// KeyPerformance is the variable used to trace how long the
+	// commands take
+	KeyPerformance = "GIT_TRACE_PERFORMANCE"
+)
+
+// contextWidth is the width of the time and location at the beginning
+// of every line, so the messages are aligned
+const contextWidth = 40
+
+// Tracer writes trace lines using the format of git
+type Tracer struct {
+	mu sync.Mutex
+	// w is where the traces are written. Ignored if path is set
+	w io.Writer
+	// path contains the path of the file the traces are appended to.
+	// The file is opened for every line, so nothing is left opened
+	path string
+	// packDisabled is set once a packfile has been traced, since we
+	// don't want to dump the packfile in the traces
+	packDisabled bool
+
+	now func() time.Time
+}
+
+// New returns a Tracer writing where the given variable says, or nil
+// if the variable doesn't enable tracing.
+// Like git, a warning is printed on stderr if the value of the
+// variable is not supported
+func New(e *env.Env, key string) *Tracer {
+	value := e.Get(key)
+	switch strings.ToLower(value) {
+	case "", "0", "false":
+		return nil
+	case "1", "2", "true":
+		return NewWithWriter(os.Stderr)
+	}
+
+	if fd, err := strconv.Atoi(value); err == nil && fd > 2 && fd < 10 {
+		return NewWithWriter(os.NewFile(uintptr(fd), key))
+	}
+	if filepath.IsAbs(value) {
+		return &Tracer{
+			path: value,
+			now:  time.Now,
+		}
+	}
+	fmt.Fprintf(os.Stderr, "warning: unknown trace value for '%s': %s\n", key, value)
+	fmt.Fprintf(os.Stderr, "         If you want to trace into a file, then please set %s\n", key)
+	fmt.Fprintf(os.Stderr, "         to an absolute pathname (starting with /)\n")
+	return nil
+}
+
+// NewWithWriter returns a Tracer writing to w
+func NewWithWriter(w io.Writer) *Tracer {
+	return &Tracer{
+		w:   w,
+		now: time.Now,
+	}
+}
+
+// Enabled returns whether the traces are written somewhere
+func (t *Tracer) Enabled() bool {
+	return t != nil
+}
+
+// Printf writes a "trace: " line, like git does with GIT_TRACE
+func (t *Tracer) Printf(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.write(2, "trace: "+fmt.Sprintf(format, args...))
+}
+
+// Command writes the line git writes when it starts running a
+// built-in command with GIT_TRACE
+// (ex. trace: built-in: git cat-file -p HEAD)
+func (t *Tracer) Command(args []string) {
+	if t == nil {
+		return
+	}
+	t.write(2, "trace: built-in: git"+quoteArgsPretty(args))
+}
+
+// Performance writes the line git writes once a command is done with
+// GIT_TRACE_PERFORMANCE
+// (ex. performance: 0.000812000 s: git command: git version)
+func (t *Tracer) Performance(args []string, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.write(2, fmt.Sprintf("performance: %.9f s: git command: git%s", elapsed.Seconds(), quoteArgsPretty(args)))
+}
+
+// Packet writes a packet sent (write is true) or received by the
+// given program (ex. "git", or "upload-pack"), like git does with
+// GIT_TRACE_PACKET.
+// The special packets are expected to be passed as-is (ex. "0000").
+// Like git, the tracing of the packets stops once a packfile is
+// sent or received
+func (t *Tracer) Packet(identity string, write bool, data []byte) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	disabled := t.packDisabled
+	t.mu.Unlock()
+	if disabled {
+		return
+	}
+
+	direction := byte('<')
+	if write {
+		direction = '>'
+	}
+	line := &strings.Builder{}
+	fmt.Fprintf(line, "packet: %12s%c ", identity, direction)
+
+	// The packfile can either be sent directly, or in sideband 1
+	if bytes.HasPrefix(data, []byte("PACK")) || bytes.HasPrefix(data, []byte("\x01PACK")) {
+		line.WriteString("PACK ...")
+		t.mu.Lock()
+		t.packDisabled = true
+		t.mu.Unlock()
+		t.write(2, line.String())
+		return
+	}
+
+	for _, c := range data {
+		switch {
+		case c == '\n':
+			// git removes the line feeds
+		case c >= 0x20 && c <= 0x7e:
+			line.WriteByte(c)
+		default:
+			fmt.Fprintf(line, "\\%o", c)
+		}
+	}
+	t.write(2, line.String())
+}
+
+// write writes the given message, prefixed by the time and by the
+// location of the caller, skip frames above write
+func (t *Tracer) write(skip int, msg string) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(t.now().Format("15:04:05.000000"))
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		fmt.Fprintf(buf, " %s:%d", filepath.Base(file), line)
+	}
+	buf.WriteByte(' ')
+	for buf.Len() < contextWidth {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(msg)
+	buf.WriteByte('\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Like git, we ignore the errors since tracing should never
+	// prevent a command from running
+	if t.path == "" {
+		t.w.Write(buf.Bytes()) //nolint:errcheck // see above
+		return
+	}
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+	if err != nil {
+		return
+	}
+	f.Write(buf.Bytes()) //nolint:errcheck // see above
+	f.Close()            //nolint:errcheck // see above
+}
+
+// quoteArgsPretty returns the arguments, each prefixed by a space.
+// The arguments containing chars that have a meaning for the shell are
+// quoted, the same way git does with sq_quote_argv_pretty()
+func quoteArgsPretty(args []string) string {
+	out := &strings.Builder{}
+	for _, arg := range args {
+		out.WriteByte(' ')
+		if arg != "" && !mustQuote(arg) {
+			out.WriteString(arg)
+			continue
+		}
+		out.WriteString(quote(arg))
+	}
+	return out.String()
+}
+
+// quote returns the given string between single quotes, with the
+// single quotes and the exclamation marks escaped for the shell
+func quote(s string) string {
+	out := &strings.Builder{}
+	out.WriteByte('\'')
+	for _, c := range s {
+		switch c {
+		case '\'', '!':
+			out.WriteString(`'\`)
+			out.WriteRune(c)
+			out.WriteByte('\'')
+		default:
+			out.WriteRune(c)
+		}
+	}
+	out.WriteByte('\'')
+	return out.String()
+}
+
+// mustQuote returns whether the given string contains chars that have
+// a meaning for the shell
+func mustQuote(s string) bool {
+	for _, c := range s {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && !strings.ContainsRune("+,-./:=@_^", c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package trace_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/trace"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineRe matches a trace line, and captures its message
+var lineRe = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{6} trace_test\.go:\d+ +(.*)$`)
+
+// messages returns the messages of the given traces, without the
+// time and location
+func messages(t *testing.T, traces string) []string {
+	t.Helper()
+
+	msgs := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(traces, "\n"), "\n") {
+		matches := lineRe.FindStringSubmatch(line)
+		require.Len(t, matches, 2, "unexpected line %q", line)
+		assert.Len(t, strings.TrimSuffix(line, matches[1]), 40, "the message of %q should be aligned", line)
+		msgs = append(msgs, matches[1])
+	}
+	return msgs
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should be disabled", func(t *testing.T) {
+		t.Parallel()
+
+		for i, value := range []string{"", "0", "false", "FALSE", "relative/path"} {
+			e := env.NewFromKVList([]string{trace.KeyTrace + "=" + value})
+			tracer := trace.New(e, trace.KeyTrace)
+			assert.Nil(t, tracer, "test %d", i)
+			assert.False(t, tracer.Enabled(), "test %d", i)
+			// should not panic
+			tracer.Printf("nope")
+		}
+	})
+
+	t.Run("should be enabled", func(t *testing.T) {
+		t.Parallel()
+
+		for i, value := range []string{"1", "2", "true", "/dev/null"} {
+			e := env.NewFromKVList([]string{trace.KeyPacket + "=" + value})
+			assert.True(t, trace.New(e, trace.KeyPacket).Enabled(), "test %d", i)
+		}
+	})
+
+	t.Run("should append to a file", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		path := filepath.Join(dir, "trace")
+		require.NoError(t, os.WriteFile(path, []byte("00:00:00.000000 trace_test.go:1         existing\n"), 0o644))
+
+		tracer := trace.New(env.NewFromKVList([]string{trace.KeyTrace + "=" + path}), trace.KeyTrace)
+		require.NotNil(t, tracer)
+		tracer.Printf("first %d", 1)
+		tracer.Printf("second")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"existing", "trace: first 1", "trace: second"}, messages(t, string(data)))
+	})
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	tracer := trace.NewWithWriter(buf)
+	tracer.Command([]string{"cat-file", "-p", "HEAD^1"})
+	tracer.Command([]string{"log", "--format=%s", "it's", ""})
+	tracer.Performance([]string{"tag", "-n"}, 1500*time.Millisecond)
+	assert.Equal(t, []string{
+		"trace: built-in: git cat-file -p HEAD^1",
+		`trace: built-in: git log '--format=%s' 'it'\''s' ''`,
+		"performance: 1.500000000 s: git command: git tag -n",
+	}, messages(t, buf.String()))
+}
+
+func TestPacket(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should trace the packets", func(t *testing.T) {
+		t.Parallel()
+
+		buf := new(bytes.Buffer)
+		tracer := trace.NewWithWriter(buf)
+		tracer.Packet("git", true, []byte("command=ls-refs\n"))
+		tracer.Packet("git", true, []byte("0000"))
+		tracer.Packet("upload-pack", false, []byte("0a8e\x00multi_ack\n"))
+		assert.Equal(t, []string{
+			"packet:          git> command=ls-refs",
+			"packet:          git> 0000",
+			`packet:  upload-pack< 0a8e\0multi_ack`,
+		}, messages(t, buf.String()))
+	})
+
+	t.Run("should stop at the packfile", func(t *testing.T) {
+		t.Parallel()
+
+		buf := new(bytes.Buffer)
+		tracer := trace.NewWithWriter(buf)
+		tracer.Packet("git", false, []byte("\x01PACK\x00\x00\x00\x02"))
+		tracer.Packet("git", false, []byte("\x01more data"))
+		tracer.Packet("git", false, []byte("0000"))
+		assert.Equal(t, []string{"packet:          git< PACK ..."}, messages(t, buf.String()))
+	})
+}
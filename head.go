@@ -0,0 +1,203 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/reflog"
+)
+
+// ErrInvalidHeadTarget is an error thrown when HEAD is set to a
+// reference outside of refs/
+var ErrInvalidHeadTarget = errors.New("HEAD can only target a reference in refs/")
+
+// Head represents the current state of HEAD
+type Head struct {
+	// Target contains the commit targeted by HEAD.
+	// It's ginternals.NullOid if HEAD targets a branch that doesn't
+	// exist yet, like in an empty repository
+	Target ginternals.Oid
+	// Branch contains the full name of the branch targeted by HEAD
+	// (ex. refs/heads/master). Empty if HEAD is detached
+	Branch string
+	// Detached is set to true when HEAD directly targets a commit
+	// instead of a branch
+	Detached bool
+}
+
+// IsUnborn returns whether HEAD targets a branch that doesn't
+// exist yet
+func (h *Head) IsUnborn() bool {
+	return !h.Detached && h.Target.IsZero()
+}
+
+// name returns the name used to refer to HEAD in the reflog
+// messages: the short name of the branch, or the commit if HEAD
+// is detached
+func (h *Head) name() string {
+	if h.Detached {
+		return h.Target.String()
+	}
+	return ginternals.LocalBranchShortName(h.Branch)
+}
+
+// Head returns the current state of HEAD
+func (r *Repository) Head() (*Head, error) {
+	ref, err := r.dotGit.UnresolvedReference(ginternals.Head)
+	if err != nil {
+		return nil, fmt.Errorf("could not read HEAD: %w", err)
+	}
+	if ref.Type() == ginternals.OidReference {
+		return &Head{
+			Target:   ref.Target(),
+			Detached: true,
+		}, nil
+	}
+
+	h := &Head{
+		Branch: ref.SymbolicTarget(),
+	}
+	target, err := r.dotGit.Reference(h.Branch)
+	switch {
+	case err == nil:
+		h.Target = target.Target()
+	case errors.Is(err, ginternals.ErrRefNotFound):
+		// The branch hasn't been created yet
+	default:
+		return nil, fmt.Errorf("could not resolve %s: %w", h.Branch, err)
+	}
+	return h, nil
+}
+
+// SetHead makes HEAD target the given reference, which must be in
+// refs/ (ex. refs/heads/master). The reference doesn't have to exist.
+// An entry is added to the reflog of HEAD, unless the reference
+// doesn't exist.
+// This is the equivalent of git symbolic-ref HEAD refname
+func (r *Repository) SetHead(refname string) error {
+	if !strings.HasPrefix(refname, "refs/") {
+		return fmt.Errorf("%s: %w", refname, ErrInvalidHeadTarget)
+	}
+	if _, err := ginternals.CheckRefFormat(refname, ginternals.RefFormatOptions{}); err != nil {
+		return fmt.Errorf("invalid reference %s: %w", refname, err)
+	}
+
+	old, err := r.Head()
+	if err != nil {
+		return err
+	}
+	newHead := &Head{Branch: refname}
+	target, err := r.dotGit.Reference(refname)
+	switch {
+	case err == nil:
+		newHead.Target = target.Target()
+	case errors.Is(err, ginternals.ErrRefNotFound):
+	default:
+		return fmt.Errorf("could not resolve %s: %w", refname, err)
+	}
+
+	if err = r.dotGit.WriteReference(ginternals.NewSymbolicReference(ginternals.Head, refname)); err != nil {
+		return fmt.Errorf("could not update HEAD: %w", err)
+	}
+	// Like git, we don't log anything when moving to an unborn branch
+	// since there's no commit to log
+	if newHead.IsUnborn() {
+		return nil
+	}
+	return r.logHeadUpdate(old, newHead)
+}
+
+// DetachHead makes HEAD directly target the given commit. If oid
+// is a tag, HEAD will target the commit it points to.
+// An entry is added to the reflog of HEAD.
+// This is the equivalent of git checkout --detach oid, without
+// updating the working tree
+func (r *Repository) DetachHead(oid ginternals.Oid) error {
+	c, err := r.peelToCommit(oid)
+	if err != nil {
+		return err
+	}
+	old, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if err = r.dotGit.WriteReference(ginternals.NewReference(ginternals.Head, c.ID())); err != nil {
+		return fmt.Errorf("could not update HEAD: %w", err)
+	}
+	return r.logHeadUpdate(old, &Head{Target: c.ID(), Detached: true})
+}
+
+// logHeadUpdate adds an entry to the reflog of HEAD to record
+// a checkout
+func (r *Repository) logHeadUpdate(old, newHead *Head) error {
+	msg := fmt.Sprintf("checkout: moving from %s to %s", old.name(), newHead.name())
+	return r.logRefUpdate(ginternals.Head, old.Target, newHead.Target, msg)
+}
+
+// logRefUpdate adds an entry to the reflog of the given reference,
+// if core.logAllRefUpdates allows it.
+// The committer of the entry is the same as the one used for
+// the commits, which means the update fails if the identity of the
+// user is not set
+func (r *Repository) logRefUpdate(name string, old, newTarget ginternals.Oid, msg string) error {
+	shouldLog, err := r.shouldLogRef(name)
+	if err != nil {
+		return err
+	}
+	if !shouldLog {
+		return nil
+	}
+
+	_, committer, err := r.DefaultSignature()
+	if err != nil {
+		return fmt.Errorf("could not generate the signature of the reflog: %w", err)
+	}
+	err = r.dotGit.AppendReflog(name, &reflog.Entry{
+		Old:       old,
+		New:       newTarget,
+		Committer: committer,
+		Message:   msg,
+	})
+	if err != nil {
+		return fmt.Errorf("could not update the reflog of %s: %w", name, err)
+	}
+	return nil
+}
+
+// shouldLogRef returns whether the updates of the given reference
+// should be logged in its reflog, based on core.logAllRefUpdates.
+// Like git, the updates of a reference that already has a reflog
+// are always logged
+func (r *Repository) shouldLogRef(name string) (bool, error) {
+	mode, ok := r.Config.FromFile().LogAllRefUpdates()
+	if !ok {
+		// The reflogs are only enabled by default in the repositories
+		// having a working tree
+		mode = "false"
+		if !r.IsBare() {
+			mode = "true"
+		}
+	}
+
+	switch mode {
+	case "always":
+		return true, nil
+	case "true":
+		if name == ginternals.Head {
+			return true, nil
+		}
+		for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/notes/"} {
+			if strings.HasPrefix(name, prefix) {
+				return true, nil
+			}
+		}
+	}
+
+	hasReflog, err := r.dotGit.HasReflog(name)
+	if err != nil {
+		return false, err
+	}
+	return hasReflog, nil
+}
@@ -0,0 +1,182 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHead(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns a new copy of the small repo, with an identity
+	// set so the reflogs can be written
+	newRepo := func(t *testing.T) *Repository {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		cfg, err := config.LoadConfig(env.NewFromKVList([]string{
+			"GIT_CONFIG_NOSYSTEM=1",
+			"GIT_COMMITTER_NAME=Committer",
+			"GIT_COMMITTER_EMAIL=committer@domain.tld",
+			"GIT_AUTHOR_NAME=Author",
+			"GIT_AUTHOR_EMAIL=author@domain.tld",
+		}), config.LoadConfigOptions{
+			WorkingDirectory: repoPath,
+		})
+		require.NoError(t, err)
+		r, err := OpenRepositoryWithParams(cfg, OpenOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		return r
+	}
+
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	forkPoint := "f0f70144f38695250606b86a50cff2b440a417f3"
+
+	t.Run("should return the branch targeted by HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.False(t, h.Detached)
+		assert.False(t, h.IsUnborn())
+		assert.Equal(t, "refs/heads/ml/packfile/tests", h.Branch)
+		assert.Equal(t, head, h.Target.String())
+	})
+
+	t.Run("should handle an empty repository", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		r, err := InitRepository(dir)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		// The repo should be openable even if its HEAD targets
+		// a branch that doesn't exist
+		r, err = OpenRepository(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.True(t, h.IsUnborn())
+		assert.Equal(t, ginternals.LocalBranchFullName(ginternals.Master), h.Branch)
+	})
+
+	t.Run("DetachHead should detach HEAD and update the reflog", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		before, err := r.dotGit.Reflog(ginternals.Head)
+		require.NoError(t, err)
+
+		oid, err := ginternals.NewOidFromStr(forkPoint)
+		require.NoError(t, err)
+		require.NoError(t, r.DetachHead(oid))
+
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.True(t, h.Detached)
+		assert.Empty(t, h.Branch)
+		assert.Equal(t, oid, h.Target)
+
+		entries, err := r.dotGit.Reflog(ginternals.Head)
+		require.NoError(t, err)
+		require.Len(t, entries, len(before)+1)
+		last := entries[len(entries)-1]
+		assert.Equal(t, head, last.Old.String())
+		assert.Equal(t, oid, last.New)
+		assert.Equal(t, "Committer", last.Committer.Name)
+		assert.Equal(t, "checkout: moving from ml/packfile/tests to "+forkPoint, last.Message)
+
+		// the branch should not have been updated
+		ref, err := r.Reference("refs/heads/ml/packfile/tests")
+		require.NoError(t, err)
+		assert.Equal(t, head, ref.Target().String())
+	})
+
+	t.Run("DetachHead should fail on a missing commit", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		oid, err := ginternals.NewOidFromStr("2dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+		err = r.DetachHead(oid)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrObjectNotFound), "unexpected error: %v", err)
+
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.False(t, h.Detached)
+	})
+
+	t.Run("SetHead should target a branch and update the reflog", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		oid, err := ginternals.NewOidFromStr(forkPoint)
+		require.NoError(t, err)
+		require.NoError(t, r.DetachHead(oid))
+		require.NoError(t, r.SetHead("refs/heads/master"))
+
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.False(t, h.Detached)
+		assert.Equal(t, "refs/heads/master", h.Branch)
+		assert.Equal(t, head, h.Target.String())
+
+		entries, err := r.dotGit.Reflog(ginternals.Head)
+		require.NoError(t, err)
+		last := entries[len(entries)-1]
+		assert.Equal(t, oid, last.Old)
+		assert.Equal(t, head, last.New.String())
+		assert.Equal(t, "checkout: moving from "+forkPoint+" to master", last.Message)
+	})
+
+	t.Run("SetHead should work with an unborn branch", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		before, err := r.dotGit.Reflog(ginternals.Head)
+		require.NoError(t, err)
+
+		require.NoError(t, r.SetHead("refs/heads/orphan"))
+		h, err := r.Head()
+		require.NoError(t, err)
+		assert.True(t, h.IsUnborn())
+		assert.Equal(t, "refs/heads/orphan", h.Branch)
+
+		entries, err := r.dotGit.Reflog(ginternals.Head)
+		require.NoError(t, err)
+		assert.Len(t, entries, len(before), "nothing should have been logged")
+	})
+
+	t.Run("SetHead should fail with a reference outside of refs/", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t)
+		err := r.SetHead("master")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrInvalidHeadTarget), "unexpected error: %v", err)
+
+		err = r.SetHead("refs/heads/invalid..name")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrRefNameInvalid), "unexpected error: %v", err)
+	})
+}
@@ -10,17 +10,41 @@ import (
 type LRU struct {
 	cache *lru.Cache
 	mu    sync.Mutex
+
+	// maxBytes is the maximum total size of the values stored in
+	// the cache. 0 means no limit
+	maxBytes int
+	bytes    int
+	sizes    map[interface{}]int
 }
 
 // NewLRU creates a new LRU Cache.
 func NewLRU(maxEntries int) (*LRU, error) {
-	cache, err := lru.New(maxEntries)
+	return NewLRUWithBudget(maxEntries, 0)
+}
+
+// NewLRUWithBudget creates a new LRU Cache that contains at most
+// maxEntries entries, and for which the total size of the values
+// added with AddWithSize() doesn't exceed maxBytes.
+// A maxBytes of 0 means no limit
+func NewLRUWithBudget(maxEntries, maxBytes int) (*LRU, error) {
+	c := &LRU{
+		maxBytes: maxBytes,
+		sizes:    map[interface{}]int{},
+	}
+	cache, err := lru.NewWithEvict(maxEntries, c.onEvict)
 	if err != nil {
 		return nil, err
 	}
-	return &LRU{
-		cache: cache,
-	}, nil
+	c.cache = cache
+	return c, nil
+}
+
+// onEvict is called by the underlying cache every time an entry is
+// removed. The lock is expected to be held by the caller
+func (c *LRU) onEvict(key, value interface{}) {
+	c.bytes -= c.sizes[key]
+	delete(c.sizes, key)
 }
 
 // Get looks up a key's value from the cache.
@@ -33,10 +57,35 @@ func (c *LRU) Get(key interface{}) (value interface{}, ok bool) {
 
 // Add adds a value to the cache.
 func (c *LRU) Add(key, value interface{}) {
+	c.AddWithSize(key, value, 0)
+}
+
+// AddWithSize adds a value of the given size to the cache, and evicts
+// the oldest entries until the budget of the cache is respected.
+// Values bigger than the budget are not added
+func (c *LRU) AddWithSize(key, value interface{}, size int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	// the key may already be in the cache with another size
+	c.cache.Remove(key)
 	c.cache.Add(key, value)
+	c.sizes[key] = size
+	c.bytes += size
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.cache.RemoveOldest()
+	}
+}
+
+// Remove removes the given key from the cache, if present
+func (c *LRU) Remove(key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.Remove(key)
 }
 
 // Clear purges all stored items from the cache.
@@ -54,3 +103,11 @@ func (c *LRU) Len() int {
 
 	return c.cache.Len()
 }
+
+// Size returns the total size of the items in the cache
+func (c *LRU) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bytes
+}
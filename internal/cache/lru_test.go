@@ -34,10 +34,49 @@ func TestLRU(t *testing.T) {
 		})
 		assert.Equal(t, 1, v, "unexpected data retrieved from cache")
 
+		c.Remove("key")
+		_, ok = c.Get("key")
+		assert.False(t, ok, "should not find data that has been removed")
+
+		c.Add("key", 1)
 		c.Clear()
 		assert.Equal(t, 0, c.Len(), "expected the cache t have been emptied")
 	})
 
+	t.Run("Should respect the byte budget", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := cache.NewLRUWithBudget(10, 10)
+		require.NoError(t, err)
+
+		c.AddWithSize("a", 1, 4)
+		c.AddWithSize("b", 2, 4)
+		assert.Equal(t, 8, c.Size())
+
+		// a is now the most recently used
+		_, ok := c.Get("a")
+		require.True(t, ok)
+
+		c.AddWithSize("c", 3, 4)
+		assert.Equal(t, 2, c.Len(), "b should have been evicted")
+		assert.Equal(t, 8, c.Size())
+		_, ok = c.Get("b")
+		assert.False(t, ok, "b should have been evicted")
+
+		// updating a value should update its size
+		c.AddWithSize("a", 1, 1)
+		assert.Equal(t, 5, c.Size())
+
+		// values bigger than the budget are ignored
+		c.AddWithSize("d", 4, 11)
+		_, ok = c.Get("d")
+		assert.False(t, ok, "d should not have been added")
+		assert.Equal(t, 5, c.Size())
+
+		c.Clear()
+		assert.Equal(t, 0, c.Size())
+	})
+
 	t.Run("Should fail on invalid limit", func(t *testing.T) {
 		t.Parallel()
 
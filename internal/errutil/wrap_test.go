@@ -0,0 +1,120 @@
+package errutil_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestErrorWrapping parses the code of the module to find the
+// error-handling patterns we don't want:
+//   - Using an error package other than the stdlib
+//   - Wrapping err in a branch where err is not the error being
+//     handled (usually meaning that err is nil)
+func TestErrorWrapping(t *testing.T) {
+	t.Parallel()
+
+	forbiddenImports := map[string]struct{}{
+		"golang.org/x/xerrors":        {},
+		"github.com/pkg/errors":       {},
+		"github.com/go-errors/errors": {},
+	}
+
+	var issues []string
+	fset := token.NewFileSet()
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "testdata" || (strings.HasPrefix(d.Name(), ".") && path != root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		for _, imp := range f.Imports {
+			p, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return err
+			}
+			if _, ok := forbiddenImports[p]; ok {
+				issues = append(issues, fset.Position(imp.Pos()).String()+": use the errors package of the stdlib instead of "+p)
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			stmt, ok := n.(*ast.IfStmt)
+			if !ok || usesIdent(stmt.Cond, "err") || (stmt.Init != nil && usesIdent(stmt.Init, "err")) {
+				return true
+			}
+			for _, s := range stmt.Body.List {
+				var exprs []ast.Expr
+				switch s := s.(type) {
+				case *ast.ReturnStmt:
+					exprs = s.Results
+				case *ast.AssignStmt:
+					exprs = s.Rhs
+				}
+				for _, e := range exprs {
+					if wrapsIdent(e, "err") {
+						issues = append(issues, fset.Position(e.Pos()).String()+": err is not the error being handled")
+					}
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+}
+
+// usesIdent returns whether the given identifier is used in n
+func usesIdent(n ast.Node, name string) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// wrapsIdent returns whether e is a call to fmt.Errorf that wraps
+// the given identifier
+func wrapsIdent(e ast.Expr, name string) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok || len(call.Args) < 2 {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Errorf" {
+		return false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "fmt" {
+		return false
+	}
+	format, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || !strings.Contains(format.Value, "%w") {
+		return false
+	}
+	id, ok := call.Args[len(call.Args)-1].(*ast.Ident)
+	return ok && id.Name == name
+}
@@ -0,0 +1,117 @@
+// Package fsutil contains methods to simplify writing files safely
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/afero"
+)
+
+// WriteTempFile writes data to a new file created in dir, and returns
+// its path. The name of the file starts with prefix and is followed by
+// random characters, so concurrent writers never use the same file.
+// The content is flushed to disk before returning.
+// The file is removed if it cannot be written
+func WriteTempFile(fs afero.Fs, dir, prefix string, data []byte, perm os.FileMode) (path string, err error) {
+	f, err := afero.TempFile(fs, dir, prefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temporary file in %s: %w", dir, err)
+	}
+	path = f.Name()
+	defer func() {
+		if err != nil {
+			fs.Remove(path) //nolint:errcheck // it already failed
+		}
+	}()
+
+	if err = writeAndSync(f, data); err != nil {
+		return "", fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err = fs.Chmod(path, perm); err != nil {
+		return "", fmt.Errorf("could not set the permissions of %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// writeAndSync writes data to f, flushes it to disk, and closes f
+func writeAndSync(f afero.File, data []byte) (err error) {
+	defer errutil.Close(f, &err)
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// MoveFile moves the file at src to dst.
+// If src and dst are not on the same device (when the temporary
+// directory is a tmpfs, or the repository is on a network mount), the
+// file is copied to a temporary file next to dst, flushed to disk,
+// renamed to dst, and src is removed. This way dst is never left
+// partially written
+func MoveFile(fs afero.Fs, src, dst string) (err error) {
+	err = fs.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := fs.Stat(src)
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %w", src, err)
+	}
+	tmp, err := copyToTempFile(fs, src, filepath.Dir(dst), "."+filepath.Base(dst)+".tmp_", info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err = fs.Rename(tmp, dst); err != nil {
+		fs.Remove(tmp) //nolint:errcheck // it already failed
+		return fmt.Errorf("could not move %s to %s: %w", tmp, dst, err)
+	}
+	if err = fs.Remove(src); err != nil {
+		return fmt.Errorf("could not remove %s: %w", src, err)
+	}
+	return nil
+}
+
+// copyToTempFile copies the file at src to a new temporary file
+// created in dir, and returns its path
+func copyToTempFile(fs afero.Fs, src, dir, prefix string, perm os.FileMode) (path string, err error) {
+	in, err := fs.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", src, err)
+	}
+	defer errutil.Close(in, &err)
+
+	out, err := afero.TempFile(fs, dir, prefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temporary file in %s: %w", dir, err)
+	}
+	path = out.Name()
+	defer func() {
+		if err != nil {
+			fs.Remove(path) //nolint:errcheck // it already failed
+		}
+	}()
+
+	if err = copyAndSync(out, in); err != nil {
+		return "", fmt.Errorf("could not copy %s to %s: %w", src, path, err)
+	}
+	if err = fs.Chmod(path, perm); err != nil {
+		return "", fmt.Errorf("could not set the permissions of %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// copyAndSync copies r to f, flushes f to disk, and closes f
+func copyAndSync(f afero.File, r io.Reader) (err error) {
+	defer errutil.Close(f, &err)
+	if _, err = io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Sync()
+}
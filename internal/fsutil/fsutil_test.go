@@ -0,0 +1,90 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/fsutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crossDeviceFs is a filesystem that fails renaming files from
+// a directory to another, like when they are on different devices
+type crossDeviceFs struct {
+	afero.Fs
+}
+
+func (fs crossDeviceFs) Rename(oldname, newname string) error {
+	if filepath.Dir(oldname) != filepath.Dir(newname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+	}
+	return fs.Fs.Rename(oldname, newname)
+}
+
+func TestWriteTempFile(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("dir", 0o755))
+
+	p1, err := fsutil.WriteTempFile(fs, "dir", "tmp_obj_", []byte("content"), 0o444)
+	require.NoError(t, err)
+	p2, err := fsutil.WriteTempFile(fs, "dir", "tmp_obj_", []byte("content"), 0o444)
+	require.NoError(t, err)
+	assert.NotEqual(t, p1, p2, "temporary files should have unique names")
+	assert.Equal(t, "dir", filepath.Dir(p1))
+	assert.True(t, strings.HasPrefix(filepath.Base(p1), "tmp_obj_"), "unexpected name: %s", p1)
+
+	data, err := afero.ReadFile(fs, p1)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+	info, err := fs.Stat(p1)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+}
+
+func TestMoveFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should rename the file", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, filepath.Join("a", "src"), []byte("content"), 0o644))
+		require.NoError(t, fs.MkdirAll("b", 0o755))
+
+		require.NoError(t, fsutil.MoveFile(fs, filepath.Join("a", "src"), filepath.Join("b", "dst")))
+		data, err := afero.ReadFile(fs, filepath.Join("b", "dst"))
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+		_, err = fs.Stat(filepath.Join("a", "src"))
+		assert.True(t, os.IsNotExist(err), "src should have been removed")
+	})
+
+	t.Run("should copy the file across devices", func(t *testing.T) {
+		t.Parallel()
+
+		fs := crossDeviceFs{afero.NewMemMapFs()}
+		require.NoError(t, afero.WriteFile(fs, filepath.Join("a", "src"), []byte("content"), 0o444))
+		require.NoError(t, fs.MkdirAll("b", 0o755))
+
+		require.NoError(t, fsutil.MoveFile(fs, filepath.Join("a", "src"), filepath.Join("b", "dst")))
+		data, err := afero.ReadFile(fs, filepath.Join("b", "dst"))
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+		info, err := fs.Stat(filepath.Join("b", "dst"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+
+		_, err = fs.Stat(filepath.Join("a", "src"))
+		assert.True(t, os.IsNotExist(err), "src should have been removed")
+		entries, err := afero.ReadDir(fs, "b")
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "no temporary files should be left behind")
+	})
+}
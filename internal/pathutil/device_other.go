@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package pathutil
+
+import "os"
+
+// deviceID always fails since the platform doesn't expose the
+// device of the files
+func deviceID(info os.FileInfo) (id uint64, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package pathutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceID returns the ID of the device containing the file
+func deviceID(info os.FileInfo) (id uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true //nolint:unconvert // the type of Dev depends on the platform
+}
@@ -0,0 +1,162 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// maxShortPath is the length from which a path needs to use the
+// extended-length syntax on Windows. MAX_PATH is 260 chars, but
+// directories are limited to 248 chars (MAX_PATH minus the 12 chars
+// of a 8.3 file name)
+const maxShortPath = 248
+
+// ExtendedLengthPath returns the extended-length form (\\?\C:\path or
+// \\?\UNC\server\share\path) of the given absolute and clean Windows
+// path, if the path is too long to be used as is by the Windows API.
+// Since Windows doesn't normalize extended-length paths, the
+// forward slashes are converted to backslashes.
+// Paths that are short enough, relative, or already using the
+// \\?\ or \\.\ syntax are returned unchanged
+func ExtendedLengthPath(p string) string {
+	if len(p) < maxShortPath {
+		return p
+	}
+	p = strings.ReplaceAll(p, "/", `\`)
+	switch {
+	case strings.HasPrefix(p, `\\?\`), strings.HasPrefix(p, `\\.\`):
+		return p
+	case strings.HasPrefix(p, `\\`):
+		// UNC path: \\server\share\path
+		return `\\?\UNC\` + p[2:]
+	case len(p) >= 3 && p[1] == ':' && p[2] == '\\':
+		// Drive path: C:\path
+		return `\\?\` + p
+	}
+	// relative paths, or paths relative to the current drive (\path)
+	// cannot be converted
+	return p
+}
+
+// LongPath returns a path that can be used to access the given file
+// even if its path is longer than what the platform supports by
+// default. On Windows, long paths are made absolute and converted to
+// their extended-length form. Other platforms don't have this
+// limitation and the path is returned as is
+func LongPath(p string) string {
+	if runtime.GOOS != "windows" || len(p) < maxShortPath {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return ExtendedLengthPath(abs)
+}
+
+// NewOsFs returns an afero.Fs using the local FileSystem.
+// On Windows, the paths given to the FS are converted using LongPath
+// so files with a path longer than 260 chars can be accessed
+func NewOsFs() afero.Fs {
+	if runtime.GOOS != "windows" {
+		return afero.NewOsFs()
+	}
+	return &longPathFs{OsFs: &afero.OsFs{}}
+}
+
+// longPathFs is an afero.OsFs that converts all the paths using
+// LongPath before using them
+type longPathFs struct {
+	*afero.OsFs
+}
+
+// Name returns the name of the FS
+func (fs *longPathFs) Name() string {
+	return "LongPathFs"
+}
+
+// Create creates a file in the filesystem, returning the file and an
+// error, if any happens
+func (fs *longPathFs) Create(name string) (afero.File, error) {
+	return fs.OsFs.Create(LongPath(name))
+}
+
+// Mkdir creates a directory in the filesystem
+func (fs *longPathFs) Mkdir(name string, perm os.FileMode) error {
+	return fs.OsFs.Mkdir(LongPath(name), perm)
+}
+
+// MkdirAll creates a directory path and all parents that does not
+// exist yet
+func (fs *longPathFs) MkdirAll(path string, perm os.FileMode) error {
+	return fs.OsFs.MkdirAll(LongPath(path), perm)
+}
+
+// Open opens a file, returning it or an error, if any happens
+func (fs *longPathFs) Open(name string) (afero.File, error) {
+	return fs.OsFs.Open(LongPath(name))
+}
+
+// OpenFile opens a file using the given flags and the given mode
+func (fs *longPathFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return fs.OsFs.OpenFile(LongPath(name), flag, perm)
+}
+
+// Remove removes a file identified by name, returning an error, if
+// any happens
+func (fs *longPathFs) Remove(name string) error {
+	return fs.OsFs.Remove(LongPath(name))
+}
+
+// RemoveAll removes a directory path and any children it contains
+func (fs *longPathFs) RemoveAll(path string) error {
+	return fs.OsFs.RemoveAll(LongPath(path))
+}
+
+// Rename renames a file
+func (fs *longPathFs) Rename(oldname, newname string) error {
+	return fs.OsFs.Rename(LongPath(oldname), LongPath(newname))
+}
+
+// Stat returns a FileInfo describing the named file, or an error, if
+// any happens
+func (fs *longPathFs) Stat(name string) (os.FileInfo, error) {
+	return fs.OsFs.Stat(LongPath(name))
+}
+
+// Chmod changes the mode of the named file to mode
+func (fs *longPathFs) Chmod(name string, mode os.FileMode) error {
+	return fs.OsFs.Chmod(LongPath(name), mode)
+}
+
+// Chown changes the uid and gid of the named file
+func (fs *longPathFs) Chown(name string, uid, gid int) error {
+	return fs.OsFs.Chown(LongPath(name), uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file
+func (fs *longPathFs) Chtimes(name string, atime, mtime time.Time) error {
+	return fs.OsFs.Chtimes(LongPath(name), atime, mtime)
+}
+
+// LstatIfPossible returns a FileInfo describing the named file,
+// without following the symbolic links
+func (fs *longPathFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	return fs.OsFs.LstatIfPossible(LongPath(name))
+}
+
+// SymlinkIfPossible creates newname as a symbolic link to oldname.
+// The target of the link is kept as is
+func (fs *longPathFs) SymlinkIfPossible(oldname, newname string) error {
+	return fs.OsFs.SymlinkIfPossible(oldname, LongPath(newname))
+}
+
+// ReadlinkIfPossible returns the target of the given symbolic link
+func (fs *longPathFs) ReadlinkIfPossible(name string) (string, error) {
+	return fs.OsFs.ReadlinkIfPossible(LongPath(name))
+}
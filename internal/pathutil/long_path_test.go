@@ -0,0 +1,111 @@
+package pathutil_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendedLengthPath(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 250)
+	testCases := []struct {
+		desc     string
+		path     string
+		expected string
+	}{
+		{
+			desc:     "short paths should not change",
+			path:     `C:\Users\git\repo\file`,
+			expected: `C:\Users\git\repo\file`,
+		},
+		{
+			desc:     "long drive paths should be prefixed",
+			path:     `C:\repo\` + long,
+			expected: `\\?\C:\repo\` + long,
+		},
+		{
+			desc:     "slashes should be converted",
+			path:     `C:/repo/` + long,
+			expected: `\\?\C:\repo\` + long,
+		},
+		{
+			desc:     "long UNC paths should use the UNC prefix",
+			path:     `\\server\share\` + long,
+			expected: `\\?\UNC\server\share\` + long,
+		},
+		{
+			desc:     "prefixed paths should not change",
+			path:     `\\?\C:\repo\` + long,
+			expected: `\\?\C:\repo\` + long,
+		},
+		{
+			desc:     "device paths should not change",
+			path:     `\\.\C:\repo\` + long,
+			expected: `\\.\C:\repo\` + long,
+		},
+		{
+			desc:     "relative paths should not change",
+			path:     `repo\` + long,
+			expected: `repo\` + long,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, pathutil.ExtendedLengthPath(tc.path))
+		})
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	t.Parallel()
+
+	p := filepath.Join("repo", strings.Repeat("a", 300))
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, p, pathutil.LongPath(p))
+		return
+	}
+	out := pathutil.LongPath(p)
+	assert.True(t, strings.HasPrefix(out, `\\?\`), "unexpected path %s", out)
+	assert.True(t, filepath.IsAbs(strings.TrimPrefix(out, `\\?\`)), "unexpected path %s", out)
+}
+
+func TestNewOsFs(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+
+	// Each directory is short enough, but the full path is longer
+	// than what Windows supports by default
+	p := dir
+	for i := 0; i < 10; i++ {
+		p = filepath.Join(p, strings.Repeat(string(rune('a'+i)), 40))
+	}
+	require.Greater(t, len(p), 260)
+
+	fs := pathutil.NewOsFs()
+	require.NoError(t, fs.MkdirAll(p, 0o755))
+	file := filepath.Join(p, "file")
+	require.NoError(t, afero.WriteFile(fs, file, []byte("content"), 0o644))
+	data, err := afero.ReadFile(fs, file)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	require.NoError(t, fs.Rename(file, file+".new"))
+	_, err = fs.Stat(file + ".new")
+	require.NoError(t, err)
+}
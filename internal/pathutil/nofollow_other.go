@@ -0,0 +1,8 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package pathutil
+
+// ONoFollow is a no-op since the platform doesn't support refusing
+// to open symbolic links
+const ONoFollow = 0
@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package pathutil
+
+import "syscall"
+
+// ONoFollow is a flag to use when opening a file to make sure the
+// file is not a symbolic link. The open fails if the last component
+// of the path is a symbolic link
+const ONoFollow = syscall.O_NOFOLLOW
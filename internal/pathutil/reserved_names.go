@@ -0,0 +1,52 @@
+package pathutil
+
+import "strings"
+
+// windowsReservedNames contains the names of the devices of Windows.
+// A file cannot use those names, even with an extension
+//
+//nolint:gochecknoglobals // Treat it as a const
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// IsValidWindowsName returns whether the given file name (not a path)
+// can safely be used on Windows. A name is invalid if:
+//   - it's the name of a device (CON, NUL, AUX, COM1, etc.), even if
+//     followed by an extension (con.txt), since it would target the
+//     device instead of a file
+//   - it contains a char that cannot be used in a name, like a
+//     control char, <, >, ", /, \, |, ?, *, or :. The colon is used
+//     to access the NTFS alternate data streams ("file:stream")
+//   - it ends with a "." or a space, which are silently removed by
+//     Windows ("file." would target "file")
+func IsValidWindowsName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x20 || strings.ContainsRune(`<>:"/\|?*`, c) {
+			return false
+		}
+	}
+	if last := name[len(name)-1]; last == '.' || last == ' ' {
+		// "." and ".." are the only exceptions
+		return name == "." || name == ".."
+	}
+
+	// The device names are still reserved when followed by spaces
+	// or an extension
+	base := name
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.TrimRight(base, " ")
+	for _, reserved := range windowsReservedNames {
+		if strings.EqualFold(base, reserved) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package pathutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidWindowsName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		isValid bool
+	}{
+		{name: "file.go", isValid: true},
+		{name: "console", isValid: true},
+		{name: "com10", isValid: true},
+		{name: "nul-device", isValid: true},
+		{name: ".gitignore", isValid: true},
+		{name: "..", isValid: true},
+		{name: "", isValid: false},
+		{name: "CON", isValid: false},
+		{name: "con", isValid: false},
+		{name: "Nul.txt", isValid: false},
+		{name: "aux.tar.gz", isValid: false},
+		{name: "com1", isValid: false},
+		{name: "LPT9", isValid: false},
+		{name: "conout$", isValid: false},
+		{name: "prn .txt", isValid: false},
+		{name: "file:stream", isValid: false},
+		{name: "file::$DATA", isValid: false},
+		{name: "file.", isValid: false},
+		{name: "file ", isValid: false},
+		{name: "a<b", isValid: false},
+		{name: "a|b", isValid: false},
+		{name: "a\\b", isValid: false},
+		{name: "a/b", isValid: false},
+		{name: "what?", isValid: false},
+		{name: "star*", isValid: false},
+		{name: "tab\tname", isValid: false},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%q", i, tc.name), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.isValid, pathutil.IsValidWindowsName(tc.name))
+		})
+	}
+}
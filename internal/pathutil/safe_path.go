@@ -0,0 +1,110 @@
+package pathutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ErrUnsafePath is returned when a path goes through a symbolic link,
+// or targets something outside of its root
+var ErrUnsafePath = errors.New("unsafe path")
+
+// isHFSIgnorable returns whether the given rune is ignored by HFS+
+// when comparing names. ".g\u200cit" targets ".git" on macOS
+func isHFSIgnorable(c rune) bool {
+	return (c >= 0x200c && c <= 0x200f) ||
+		(c >= 0x202a && c <= 0x202e) ||
+		(c >= 0x206a && c <= 0x206f) ||
+		c == 0xfeff
+}
+
+// IsDotGit returns whether the given file name (not a path) would
+// target a .git directory on any of the filesystems git supports:
+//   - ".git" using any case, since most filesystems of Windows and
+//     macOS are case-insensitive
+//   - ".git" followed by dots or spaces, which are silently removed
+//     by Windows, or by an NTFS stream (".git::$INDEX_ALLOCATION")
+//   - "git~1", which is the NTFS short name of ".git"
+//   - ".git" containing chars ignored by HFS+, like ".g\u200cit"
+func IsDotGit(name string) bool {
+	name = strings.Map(func(c rune) rune {
+		if isHFSIgnorable(c) {
+			return -1
+		}
+		return c
+	}, strings.ToLower(name))
+
+	if name == "git~1" {
+		return true
+	}
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimRight(name, ". ") == ".git"
+}
+
+// IsValidTreeEntryName returns whether the given name can safely be
+// used as the name of an entry of a tree that will be written in a
+// working tree. A name is invalid if it is empty, if it's "." or
+// "..", if it contains a "/" or a NUL char, or if it targets a .git
+// directory (see IsDotGit)
+func IsValidTreeEntryName(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	if strings.ContainsAny(name, "/\x00") {
+		return false
+	}
+	return !IsDotGit(name)
+}
+
+// VerifyPath makes sure that rel, a path relative to root that uses
+// slashes, can safely be accessed:
+//   - all its components must be valid tree entry names (see
+//     IsValidTreeEntryName), which means rel cannot go outside of
+//     root or inside a .git directory
+//   - none of its parent directories can be a symbolic link, since
+//     it could point anywhere on the disk
+//
+// The last component is allowed to be a symbolic link, since it is
+// not going to be followed: it will either be read as a link or
+// replaced.
+// ErrUnsafePath is returned if the path is not safe.
+// A parent directory that doesn't exist is not an error
+func VerifyPath(fs afero.Fs, root, rel string) error {
+	components := strings.Split(rel, "/")
+	for _, c := range components {
+		if !IsValidTreeEntryName(c) {
+			return fmt.Errorf("%s: invalid component %q: %w", rel, c, ErrUnsafePath)
+		}
+	}
+
+	lstater, ok := fs.(afero.Lstater)
+	if !ok {
+		// The filesystem doesn't support symbolic links
+		return nil
+	}
+	p := root
+	for _, c := range components[:len(components)-1] {
+		p = filepath.Join(p, c)
+		info, _, err := lstater.LstatIfPossible(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("could not check %s: %w", p, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is beyond a symbolic link: %w", rel, ErrUnsafePath)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s: %s is not a directory: %w", rel, p, ErrUnsafePath)
+		}
+	}
+	return nil
+}
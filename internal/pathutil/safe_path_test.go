@@ -0,0 +1,125 @@
+package pathutil_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidTreeEntryName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		isValid bool
+	}{
+		{name: "file.go", isValid: true},
+		{name: ".gitignore", isValid: true},
+		{name: ".gitmodules", isValid: true},
+		{name: "git", isValid: true},
+		{name: ".git2", isValid: true},
+		{name: "git~2", isValid: true},
+		{name: "...", isValid: true},
+		{name: "", isValid: false},
+		{name: ".", isValid: false},
+		{name: "..", isValid: false},
+		{name: "a/b", isValid: false},
+		{name: "a\x00b", isValid: false},
+		{name: ".git", isValid: false},
+		{name: ".GIT", isValid: false},
+		{name: ".Git", isValid: false},
+		{name: ".git.", isValid: false},
+		{name: ".git . ", isValid: false},
+		{name: ".git::$INDEX_ALLOCATION", isValid: false},
+		{name: "GIT~1", isValid: false},
+		{name: ".g\u200cit", isValid: false},
+		{name: "\ufeff.git", isValid: false},
+		{name: ".gi\u206at", isValid: false},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%q", i, tc.name), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.isValid, pathutil.IsValidTreeEntryName(tc.name))
+		})
+	}
+}
+
+func TestVerifyPath(t *testing.T) {
+	t.Parallel()
+
+	// newRoot returns a directory containing:
+	//   - dir/file
+	//   - file
+	//   - link -> dir, unless symbolic links are not supported
+	newRoot := func(t *testing.T) string {
+		t.Helper()
+
+		root, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		require.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "file"), []byte("content"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("content"), 0o644))
+		if runtime.GOOS != "windows" {
+			require.NoError(t, os.Symlink("dir", filepath.Join(root, "link")))
+		}
+		return root
+	}
+
+	t.Run("should accept safe paths", func(t *testing.T) {
+		t.Parallel()
+
+		root := newRoot(t)
+		for i, p := range []string{"file", "dir/file", "dir/new", "new/dir/file", "link"} {
+			assert.NoError(t, pathutil.VerifyPath(afero.NewOsFs(), root, p), "test %d", i)
+		}
+	})
+
+	t.Run("should refuse invalid components", func(t *testing.T) {
+		t.Parallel()
+
+		root := newRoot(t)
+		for i, p := range []string{"", "../file", "dir/../../file", "./file", ".git/config", "dir/.GIT/config", "dir//file"} {
+			err := pathutil.VerifyPath(afero.NewOsFs(), root, p)
+			assert.ErrorIs(t, err, pathutil.ErrUnsafePath, "test %d", i)
+		}
+	})
+
+	t.Run("should refuse to go through a file", func(t *testing.T) {
+		t.Parallel()
+
+		root := newRoot(t)
+		err := pathutil.VerifyPath(afero.NewOsFs(), root, "file/file")
+		assert.ErrorIs(t, err, pathutil.ErrUnsafePath)
+	})
+
+	t.Run("should refuse to go through a symbolic link", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links need special permissions on Windows")
+		}
+
+		root := newRoot(t)
+		err := pathutil.VerifyPath(afero.NewOsFs(), root, "link/file")
+		assert.ErrorIs(t, err, pathutil.ErrUnsafePath)
+	})
+
+	t.Run("should not check symbolic links on filesystems that don't support them", func(t *testing.T) {
+		t.Parallel()
+
+		fs := afero.NewMemMapFs()
+		require.NoError(t, fs.MkdirAll("/root/dir", 0o755))
+		assert.NoError(t, pathutil.VerifyPath(fs, "/root", "dir/file"))
+	})
+}
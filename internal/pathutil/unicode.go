@@ -0,0 +1,16 @@
+package pathutil
+
+import "golang.org/x/text/unicode/norm"
+
+// Precompose returns the given path using precomposed unicode
+// characters (NFC). This is the form git uses to store the paths
+// when core.precomposeUnicode is true, while some filesystems, like
+// HFS+ on macOS, return decomposed characters (NFD): "é" is stored
+// as 1 character (U+00E9) by git, but is returned as an "e" followed
+// by a combining accent (U+0065 U+0301) by the filesystem
+func Precompose(path string) string {
+	if norm.NFC.IsNormalString(path) {
+		return path
+	}
+	return norm.NFC.String(path)
+}
@@ -0,0 +1,49 @@
+package pathutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecompose(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		path     string
+		expected string
+	}{
+		{
+			desc:     "ascii path should not change",
+			path:     "dir/file.go",
+			expected: "dir/file.go",
+		},
+		{
+			desc:     "precomposed path should not change",
+			path:     "dir/caf\u00e9.txt",
+			expected: "dir/caf\u00e9.txt",
+		},
+		{
+			desc:     "decomposed path should be precomposed",
+			path:     "dir/cafe\u0301.txt",
+			expected: "dir/caf\u00e9.txt",
+		},
+		{
+			desc:     "decomposed directory should be precomposed",
+			path:     "a\u0308/cafe\u0301",
+			expected: "\u00e4/caf\u00e9",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, pathutil.Precompose(tc.path))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ErrNoRepo is an error returned when no repo are found
@@ -19,31 +20,105 @@ func WorkingTree(dotGitDirName string) (path string, err error) {
 	return WorkingTreeFromPath(wd, dotGitDirName)
 }
 
+// DiscoveryOptions represents the options used to find the root of
+// a repo
+type DiscoveryOptions struct {
+	// CeilingDirectories contains absolute paths of directories the
+	// lookup should not go into. The lookup will still check the
+	// starting directory, even if it's a ceiling.
+	// Relative paths are ignored
+	CeilingDirectories []string
+	// StopAtFilesystemBoundary prevents the lookup from going into a
+	// directory that is on a different filesystem than the starting
+	// directory. This is ignored on the platforms where we cannot
+	// know on which filesystem a directory is
+	StopAtFilesystemBoundary bool
+}
+
 // WorkingTreeFromPath returns the absolute path to the root of a repo containing
 // the provided directory
 func WorkingTreeFromPath(p, dotGitDirName string) (path string, err error) {
+	return WorkingTreeFromPathWithOptions(p, dotGitDirName, DiscoveryOptions{})
+}
+
+// WorkingTreeFromPathWithOptions returns the absolute path to the
+// root of a repo containing the provided directory, using the
+// provided options
+func WorkingTreeFromPathWithOptions(p, dotGitDirName string, opts DiscoveryOptions) (path string, err error) {
+	return FindUp(p, opts, func(dir string) (bool, error) {
+		info, err := os.Stat(filepath.Join(dir, dotGitDirName))
+		if err != nil {
+			return false, nil
+		}
+		// A file named .git is valid, this file should contain a
+		// gitdir instruction with a path to the repo.
+		if !info.IsDir() {
+			return true, nil
+		}
+
+		// in case the .git is a directory, we need to check the directory
+		// has a HEAD to validate that it's an actual git repo
+		head, err := os.Stat(filepath.Join(dir, dotGitDirName, "HEAD"))
+		return err == nil && !head.IsDir(), nil
+	})
+}
+
+// FindUp calls match on p and on each of its parents, from the
+// deepest to the root, and returns the first directory that matches.
+// ErrNoRepo is returned if no directories match
+func FindUp(p string, opts DiscoveryOptions, match func(dir string) (bool, error)) (string, error) {
+	ceiling := longestCeiling(p, opts.CeilingDirectories)
+
+	var startDevice uint64
+	checkDevice := false
+	if opts.StopAtFilesystemBoundary {
+		if info, err := os.Stat(p); err == nil {
+			startDevice, checkDevice = deviceID(info)
+		}
+	}
+
 	prev := ""
 	for p != prev {
-		info, err := os.Stat(filepath.Join(p, dotGitDirName))
-		if err == nil {
-			// A file named .git is valid, this file should contain a
-			// gitdir instruction with a path to the repo.
-			if !info.IsDir() {
-				return p, nil
-			}
-
-			// in case the .git is a directory, we need to check the directory
-			// has a HEAD to validate that it's an actual git repo
-			head, err := os.Stat(filepath.Join(p, dotGitDirName, "HEAD"))
-			if err == nil {
-				if !head.IsDir() {
-					return p, nil
-				}
-			}
+		ok, err := match(p)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return p, nil
 		}
 
 		prev = p
 		p = filepath.Dir(p)
+		if p == ceiling {
+			break
+		}
+		if checkDevice {
+			if info, err := os.Stat(p); err == nil {
+				if device, ok := deviceID(info); ok && device != startDevice {
+					break
+				}
+			}
+		}
 	}
 	return "", ErrNoRepo
 }
+
+// longestCeiling returns the deepest ceiling directory that is a
+// parent of p, or an empty string if there are none
+func longestCeiling(p string, ceilings []string) string {
+	longest := ""
+	for _, c := range ceilings {
+		if c == "" || !filepath.IsAbs(c) {
+			continue
+		}
+		c = filepath.Clean(c)
+		rel, err := filepath.Rel(c, p)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(c) > len(longest) {
+			longest = c
+		}
+	}
+	return longest
+}
@@ -96,3 +96,69 @@ func TestWorkingTree(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestWorkingTreeFromPathWithOptions(t *testing.T) {
+	t.Parallel()
+
+	// newRepo creates a directory containing a .git and returns the
+	// path of root/a/b/c
+	newRepo := func(t *testing.T) (root, finalPath string) {
+		t.Helper()
+
+		root, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		require.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte(""), 0o644))
+		finalPath = filepath.Join(root, "a", "b", "c")
+		require.NoError(t, os.MkdirAll(finalPath, 0o755))
+		return root, finalPath
+	}
+
+	t.Run("should stop at the ceiling directories", func(t *testing.T) {
+		t.Parallel()
+
+		root, finalPath := newRepo(t)
+		_, err := pathutil.WorkingTreeFromPathWithOptions(finalPath, ".git", pathutil.DiscoveryOptions{
+			CeilingDirectories: []string{filepath.Join(root, "a")},
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, pathutil.ErrNoRepo)
+	})
+
+	t.Run("should ignore the ceilings that are not parents", func(t *testing.T) {
+		t.Parallel()
+
+		root, finalPath := newRepo(t)
+		p, err := pathutil.WorkingTreeFromPathWithOptions(finalPath, ".git", pathutil.DiscoveryOptions{
+			CeilingDirectories: []string{
+				finalPath,
+				filepath.Join(root, "a", "bb"),
+				"a",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, root, p)
+	})
+
+	t.Run("should check the directory right below the ceiling", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := newRepo(t)
+		p, err := pathutil.WorkingTreeFromPathWithOptions(root, ".git", pathutil.DiscoveryOptions{
+			CeilingDirectories: []string{filepath.Dir(root)},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, root, p)
+	})
+
+	t.Run("should find repos on the same filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		root, finalPath := newRepo(t)
+		p, err := pathutil.WorkingTreeFromPathWithOptions(finalPath, ".git", pathutil.DiscoveryOptions{
+			StopAtFilesystemBoundary: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, root, p)
+	})
+}
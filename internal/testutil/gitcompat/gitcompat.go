@@ -0,0 +1,185 @@
+// Package gitcompat contains a harness to run an operation with both
+// the library and the git binary, and make sure they produce the exact
+// same result.
+//
+// A Case describes how to prepare a repository, how to run the
+// operation with the library and with git, and which files of the
+// git directory need to be byte-identical once done. Run creates
+// two identical repositories, runs one side of the operation on each
+// of them, and compares the results.
+package gitcompat
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// Name contains the name used for both the author and the
+	// committer by Git
+	Name = "compat"
+	// Email contains the email used for both the author and the
+	// committer by Git
+	Email = "compat@domain.tld"
+	// Date contains the date used for both the author and the
+	// committer by Git, using the git internal format
+	Date = "1600000000 +0000"
+)
+
+// Case represents an operation to run with both the library and git
+type Case struct {
+	// Setup prepares the repository the operation will run on. It's
+	// called once per side, so it must be deterministic.
+	// Defaults to an empty repository created by git
+	Setup func(t *testing.T, repoPath string)
+	// Lib runs the operation using the library, and returns a value
+	// that must match the one returned by Git, like the ID of a
+	// created object.
+	Lib func(t *testing.T, repoPath string) string
+	// Git runs the operation using the git binary, and returns a
+	// value that must match the one returned by Lib.
+	Git func(t *testing.T, repoPath string) string
+	// Files contains glob patterns (see filepath.Match) of the files
+	// of the git directory that must be identical once the operation
+	// ran. The patterns are relative to the git directory, and use
+	// slashes (ex. "objects/pack/*.idx")
+	Files []string
+}
+
+// Signature returns the signature git uses for the authors, the
+// committers, and the taggers
+func Signature() object.Signature {
+	return object.Signature{
+		Name:  Name,
+		Email: Email,
+		Time:  time.Unix(1600000000, 0).UTC(),
+	}
+}
+
+// RequireGit skips the test if the git binary is not available
+func RequireGit(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+}
+
+// Git runs git in the given directory and returns its output.
+// The identity and dates of the author and the committer are set to
+// Name, Email, and Date, and the global and system configs are
+// ignored, so the result only depends on the repository
+func Git(t *testing.T, dir string, stdin []byte, args ...string) string {
+	t.Helper()
+
+	home := t.TempDir()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"HOME="+home,
+		"XDG_CONFIG_HOME="+home,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME="+Name,
+		"GIT_AUTHOR_EMAIL="+Email,
+		"GIT_AUTHOR_DATE="+Date,
+		"GIT_COMMITTER_NAME="+Name,
+		"GIT_COMMITTER_EMAIL="+Email,
+		"GIT_COMMITTER_DATE="+Date,
+	)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), stderr.String())
+	return string(out)
+}
+
+// InitRepo creates an empty repository using git
+func InitRepo(t *testing.T, repoPath string) {
+	t.Helper()
+
+	Git(t, repoPath, nil, "init", "--quiet", "--initial-branch", "master", ".")
+}
+
+// Run runs the given case and fails the test if the library and git
+// don't produce the same result
+func Run(t *testing.T, c Case) {
+	t.Helper()
+
+	RequireGit(t)
+	require.NotNil(t, c.Lib, "Lib is required")
+	require.NotNil(t, c.Git, "Git is required")
+	setup := c.Setup
+	if setup == nil {
+		setup = InitRepo
+	}
+
+	libPath, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	gitPath, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	setup(t, libPath)
+	setup(t, gitPath)
+
+	libOut := c.Lib(t, libPath)
+	gitOut := c.Git(t, gitPath)
+	assert.Equal(t, gitOut, libOut, "the library and git returned different values")
+
+	for _, pattern := range c.Files {
+		libFiles := matchFiles(t, libPath, pattern)
+		gitFiles := matchFiles(t, gitPath, pattern)
+		require.Equal(t, keys(gitFiles), keys(libFiles), "the library and git created different files for %s", pattern)
+		for name, expected := range gitFiles {
+			assert.Equal(t, expected, libFiles[name], "%s is different", name)
+		}
+	}
+}
+
+// matchFiles returns the content of the files of the git directory of
+// the repository that match the given pattern, indexed by their path
+// relative to the git directory. Directories are ignored
+func matchFiles(t *testing.T, repoPath, pattern string) map[string]string {
+	t.Helper()
+
+	gitDir := filepath.Join(repoPath, config.DefaultDotGitDirName)
+	paths, err := filepath.Glob(filepath.Join(gitDir, filepath.FromSlash(pattern)))
+	require.NoError(t, err)
+	files := make(map[string]string, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		require.NoError(t, err)
+		if info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		rel, err := filepath.Rel(gitDir, p)
+		require.NoError(t, err)
+		files[filepath.ToSlash(rel)] = string(data)
+	}
+	return files
+}
+
+// keys returns the sorted keys of the given map
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,50 @@
+package testutil
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LogEvent represents an event received by a Logger
+type LogEvent struct {
+	Msg string
+	// Args contains the arguments of the event, indexed by key
+	Args map[string]interface{}
+}
+
+// Logger is a debuglog.Logger that records all the events it
+// receives. It can be used concurrently
+type Logger struct {
+	mu     sync.Mutex
+	events []LogEvent
+}
+
+// Debug records the given event
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	e := LogEvent{
+		Msg:  msg,
+		Args: make(map[string]interface{}, len(args)/2),
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		e.Args[fmt.Sprint(args[i])] = args[i+1]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+// Events returns the events recorded with the given message, in the
+// order they have been received
+func (l *Logger) Events(msg string) []LogEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := []LogEvent{}
+	for _, e := range l.events {
+		if e.Msg == msg {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,452 @@
+// Package repogen contains helpers to generate repositories of any
+// size, for the tests and the benchmarks that need more than the
+// repositories of testutil.
+//
+// The repositories are written directly on disk without using the
+// backend, so the package can be used by the tests of any package
+// but packfile and object.
+package repogen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// ErrInvalidOptions is returned when the options used to generate
+// a repository are invalid
+var ErrInvalidOptions = errors.New("invalid options")
+
+// baseTime contains the date of the first commit of the generated
+// repositories. The commits are spaced by a minute
+var baseTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Options contains the options used to generate a repository
+type Options struct {
+	// Commits contains the number of commits to create on master.
+	// Must be greater than 0
+	Commits int
+	// Width contains the number of files of each directory. Each
+	// commit changes one of the files, so the blobs of a same file
+	// make good delta bases.
+	// Must be greater than 0
+	Width int
+	// Depth contains the number of nested directories. Each directory
+	// contains Width files, and the next directory.
+	// Defaults to 0, all the files are at the root of the repository
+	Depth int
+	// FileLines contains the number of lines of each file in the
+	// first commit
+	FileLines int
+	// BinaryFiles contains the number of binary files to add at the
+	// root of the repository in the first commit. Their content is
+	// random and never changes
+	BinaryFiles int
+	// BinarySize contains the size of each binary file
+	BinarySize int
+	// Seed is used to generate the content of the binary files
+	Seed int64
+
+	// Branches contains the number of branches to create, in
+	// addition to master. The branches are spread over the history
+	Branches int
+	// Tags contains the number of annotated tags to create. The tags
+	// are spread over the history
+	Tags int
+	// PackedRefs writes all the references in packed-refs, instead
+	// of using one file per reference
+	PackedRefs bool
+
+	// Packs contains the number of packfiles to store the objects in.
+	// The commits are split between the packfiles in the order of the
+	// history, the first packfile containing the oldest commits.
+	// Defaults to 0, all the objects are stored as loose objects
+	Packs int
+	// LargeOffsetThreshold contains the biggest offset that can be
+	// stored in layer4 of the indexes. The objects stored after this
+	// offset are stored in layer5, like if the packfiles were bigger
+	// than 2GB. Use 1 to store all the objects but the first one in
+	// layer5 (see packfile.BuildOptions).
+	// Defaults to 0x7fffffff
+	LargeOffsetThreshold uint64
+}
+
+// Result contains information about a generated repository
+type Result struct {
+	// Path contains the path of the working tree of the repository
+	Path string
+	// Head contains the ID of the last commit of master
+	Head ginternals.Oid
+	// Commits contains the IDs of the commits, from the oldest to
+	// the newest
+	Commits []ginternals.Oid
+	// Blobs contains the IDs of all the blobs of the repository, in
+	// the order they have been created
+	Blobs []ginternals.Oid
+	// References contains the name of all the references, HEAD
+	// excluded, sorted by name
+	References []string
+	// Packs contains the IDs of the packfiles
+	Packs []ginternals.Oid
+	// Deltas contains the number of deltified objects in the
+	// packfiles
+	Deltas int
+}
+
+// New generates a repository in a new temporary directory
+func New(t *testing.T, opts Options) (res *Result, cleanup func()) {
+	t.Helper()
+
+	dir, cleanup := testutil.TempDir(t)
+	res, err := Generate(dir, opts)
+	if err != nil {
+		cleanup()
+	}
+	require.NoError(t, err)
+	return res, cleanup
+}
+
+// generator contains the state of a repository being generated
+type generator struct {
+	cfg  *config.Config
+	opts Options
+	res  *Result
+
+	// files contains the content of each text file, indexed by
+	// directory level and position in the directory
+	files [][]string
+	// binaries contains the blobs of the binary files
+	binaries []*object.Object
+	// commits contains the objects of the commits, from the oldest
+	// to the newest
+	commits []*object.Object
+	// tags contains the ID of the annotated tags, indexed by name
+	tags map[string]ginternals.Oid
+	// objects contains the objects that have not been written
+	// yet, with their path
+	objects []*packfile.BuildObject
+	// seen contains the IDs of all the objects created so far
+	seen map[ginternals.Oid]struct{}
+}
+
+// Generate creates a new repository in the given directory, using the
+// given options. The generated repository is always the same for a
+// given set of options
+func Generate(path string, opts Options) (*Result, error) {
+	if opts.Commits <= 0 || opts.Width <= 0 {
+		return nil, fmt.Errorf("at least one commit and one file are needed: %w", ErrInvalidOptions)
+	}
+	if opts.Depth < 0 || opts.Packs < 0 || opts.Branches < 0 || opts.Tags < 0 || opts.BinaryFiles < 0 || opts.BinarySize < 0 {
+		return nil, fmt.Errorf("negative values are not allowed: %w", ErrInvalidOptions)
+	}
+	if opts.Packs > opts.Commits {
+		return nil, fmt.Errorf("cannot split %d commits in %d packfiles: %w", opts.Commits, opts.Packs, ErrInvalidOptions)
+	}
+
+	cfg, err := config.LoadConfigSkipEnv(config.LoadConfigOptions{
+		WorkTreePath: path,
+		GitDirPath:   filepath.Join(path, config.DefaultDotGitDirName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create the config: %w", err)
+	}
+	g := &generator{
+		cfg:  cfg,
+		opts: opts,
+		tags: map[string]ginternals.Oid{},
+		seen: map[ginternals.Oid]struct{}{},
+		res: &Result{
+			Path:    path,
+			Commits: make([]ginternals.Oid, 0, opts.Commits),
+		},
+	}
+	if err = g.init(); err != nil {
+		return nil, err
+	}
+	if err = g.generateHistory(); err != nil {
+		return nil, err
+	}
+	if err = g.generateReferences(); err != nil {
+		return nil, err
+	}
+	return g.res, nil
+}
+
+// init creates the skeleton of the repository
+func (g *generator) init() error {
+	dirs := []string{
+		ginternals.TagsPath(g.cfg),
+		ginternals.LocalBranchesPath(g.cfg),
+		ginternals.ObjectsInfoPath(g.cfg),
+		ginternals.ObjectsPacksPath(g.cfg),
+	}
+	for _, d := range dirs {
+		if err := os.MkdirAll(d, 0o750); err != nil {
+			return fmt.Errorf("could not create directory %s: %w", d, err)
+		}
+	}
+	if err := g.cfg.FromFile().Save(); err != nil {
+		return fmt.Errorf("could not save the config: %w", err)
+	}
+	head := fmt.Sprintf("ref: %s\n", ginternals.LocalBranchFullName(ginternals.Master))
+	if err := os.WriteFile(filepath.Join(ginternals.DotGitPath(g.cfg), ginternals.Head), []byte(head), 0o644); err != nil {
+		return fmt.Errorf("could not write HEAD: %w", err)
+	}
+	return nil
+}
+
+// generateHistory creates all the commits of master
+func (g *generator) generateHistory() error {
+	g.files = make([][]string, g.opts.Depth+1)
+	for level := range g.files {
+		g.files[level] = make([]string, g.opts.Width)
+		for i := range g.files[level] {
+			g.files[level][i] = strings.Repeat(fmt.Sprintf("line of file %d of level %d\n", i, level), g.opts.FileLines)
+		}
+	}
+	rnd := rand.New(rand.NewSource(g.opts.Seed)) //nolint:gosec // the content doesn't need to be secure
+	for i := 0; i < g.opts.BinaryFiles; i++ {
+		data := make([]byte, g.opts.BinarySize)
+		rnd.Read(data)
+		blob := object.New(object.TypeBlob, data)
+		g.binaries = append(g.binaries, blob)
+		g.addObject(blob, fmt.Sprintf("bin-%04d.bin", i))
+	}
+
+	fileCount := len(g.files) * g.opts.Width
+	var parents []ginternals.Oid
+	for i := 0; i < g.opts.Commits; i++ {
+		if i > 0 {
+			level := (i % fileCount) / g.opts.Width
+			g.files[level][i%g.opts.Width] += fmt.Sprintf("line added by commit %d\n", i)
+		}
+		tree := g.writeTree(0, "")
+
+		sig := object.Signature{
+			Name:  "repogen",
+			Email: "repogen@domain.tld",
+			Time:  baseTime.Add(time.Duration(i) * time.Minute),
+		}
+		c := object.NewCommit(tree.ID(), sig, &object.CommitOptions{
+			Message:   fmt.Sprintf("commit %d\n", i),
+			ParentsID: parents,
+		})
+		o := c.ToObject()
+		g.addObject(o, "")
+		g.commits = append(g.commits, o)
+		g.res.Commits = append(g.res.Commits, o.ID())
+		parents = []ginternals.Oid{o.ID()}
+
+		// The tags are written with the last commit
+		if i == g.opts.Commits-1 {
+			g.generateTags()
+		}
+		if err := g.flushObjects(i); err != nil {
+			return err
+		}
+	}
+	g.res.Head = g.res.Commits[len(g.res.Commits)-1]
+	return nil
+}
+
+// generateTags creates the annotated tags
+func (g *generator) generateTags() {
+	for i := 0; i < g.opts.Tags; i++ {
+		name := fmt.Sprintf("v0.0.%d", i)
+		tag := object.NewTag(&object.TagParams{
+			Target: g.commits[i*len(g.commits)/g.opts.Tags],
+			Name:   name,
+			Tagger: object.Signature{
+				Name:  "repogen",
+				Email: "repogen@domain.tld",
+				Time:  baseTime,
+			},
+			Message: fmt.Sprintf("tag %d\n", i),
+		})
+		o := tag.ToObject()
+		g.addObject(o, name)
+		g.tags[name] = o.ID()
+	}
+}
+
+// writeTree creates the tree of the given level, using the current
+// content of the files
+func (g *generator) writeTree(level int, dir string) *object.Tree {
+	// The entries need to be sorted, which is why "bin-" < "file-"
+	// < "subdir"
+	entries := []object.TreeEntry{}
+	if level == 0 {
+		for i, blob := range g.binaries {
+			entries = append(entries, object.TreeEntry{
+				Path: fmt.Sprintf("bin-%04d.bin", i),
+				ID:   blob.ID(),
+				Mode: object.ModeFile,
+			})
+		}
+	}
+	for i, content := range g.files[level] {
+		name := fmt.Sprintf("file-%04d", i)
+		blob := object.New(object.TypeBlob, []byte(content))
+		g.addObject(blob, dir+name)
+		entries = append(entries, object.TreeEntry{
+			Path: name,
+			ID:   blob.ID(),
+			Mode: object.ModeFile,
+		})
+	}
+	if level < g.opts.Depth {
+		sub := g.writeTree(level+1, dir+"subdir/")
+		entries = append(entries, object.TreeEntry{
+			Path: "subdir",
+			ID:   sub.ID(),
+			Mode: object.ModeDirectory,
+		})
+	}
+	tree := object.NewTree(entries)
+	g.addObject(tree.ToObject(), strings.TrimSuffix(dir, "/"))
+	return tree
+}
+
+// addObject adds an object to the list of objects to write. Objects
+// that are already in the list or already written are skipped
+func (g *generator) addObject(o *object.Object, path string) {
+	if _, ok := g.seen[o.ID()]; ok {
+		return
+	}
+	g.seen[o.ID()] = struct{}{}
+	if o.Type() == object.TypeBlob {
+		g.res.Blobs = append(g.res.Blobs, o.ID())
+	}
+	g.objects = append(g.objects, &packfile.BuildObject{
+		Object: o,
+		Path:   path,
+	})
+}
+
+// flushObjects writes the pending objects once the given commit has
+// been created. The objects are written as loose objects, or in a
+// packfile if the commit is the last one of its packfile
+func (g *generator) flushObjects(commit int) error {
+	if g.opts.Packs == 0 {
+		for _, o := range g.objects {
+			if err := g.writeLooseObject(o.Object); err != nil {
+				return err
+			}
+		}
+		g.objects = g.objects[:0]
+		return nil
+	}
+
+	// commit i goes in pack i*Packs/Commits
+	pack := commit * g.opts.Packs / g.opts.Commits
+	if commit != g.opts.Commits-1 && (commit+1)*g.opts.Packs/g.opts.Commits == pack {
+		return nil
+	}
+	res, err := packfile.Build(g.objects, packfile.BuildOptions{
+		Window:               packfile.DefaultWindow,
+		Depth:                packfile.DefaultDepth,
+		LargeOffsetThreshold: g.opts.LargeOffsetThreshold,
+	})
+	if err != nil {
+		return fmt.Errorf("could not build packfile %d: %w", pack, err)
+	}
+	name := "pack-" + res.ID.String()
+	files := []struct {
+		ext  string
+		data []byte
+	}{
+		{ext: packfile.ExtPackfile, data: res.Pack},
+		{ext: packfile.ExtIndex, data: res.Index},
+	}
+	for _, f := range files {
+		p := ginternals.PackfilePath(g.cfg, name+f.ext)
+		if err = os.WriteFile(p, f.data, 0o444); err != nil {
+			return fmt.Errorf("could not write %s: %w", p, err)
+		}
+	}
+	g.res.Packs = append(g.res.Packs, res.ID)
+	g.res.Deltas += res.Deltas
+	g.objects = g.objects[:0]
+	return nil
+}
+
+// writeLooseObject writes the given object in the odb
+func (g *generator) writeLooseObject(o *object.Object) error {
+	data, err := o.Compress()
+	if err != nil {
+		return fmt.Errorf("could not compress object %s: %w", o.ID().String(), err)
+	}
+	p := ginternals.LooseObjectPath(g.cfg, o.ID().String())
+	if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("could not create the directory of %s: %w", p, err)
+	}
+	if err = os.WriteFile(p, data, 0o444); err != nil {
+		return fmt.Errorf("could not write object %s: %w", o.ID().String(), err)
+	}
+	return nil
+}
+
+// generateReferences creates master, the branches, and the tags
+func (g *generator) generateReferences() error {
+	refs := map[string]ginternals.Oid{
+		ginternals.LocalBranchFullName(ginternals.Master): g.res.Head,
+	}
+	// peeled contains the commits targeted by the annotated tags
+	peeled := map[string]ginternals.Oid{}
+	for i := 0; i < g.opts.Branches; i++ {
+		target := g.res.Commits[i*len(g.res.Commits)/g.opts.Branches]
+		refs[ginternals.LocalBranchFullName(fmt.Sprintf("branch-%04d", i))] = target
+	}
+	for i := 0; i < g.opts.Tags; i++ {
+		name := fmt.Sprintf("v0.0.%d", i)
+		refs[ginternals.LocalTagFullName(name)] = g.tags[name]
+		peeled[ginternals.LocalTagFullName(name)] = g.res.Commits[i*len(g.res.Commits)/g.opts.Tags]
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	g.res.References = names
+
+	if !g.opts.PackedRefs {
+		for _, name := range names {
+			p := filepath.Join(g.cfg.CommonDirPath, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return fmt.Errorf("could not create the directory of %s: %w", name, err)
+			}
+			if err := os.WriteFile(p, []byte(refs[name].String()+"\n"), 0o644); err != nil {
+				return fmt.Errorf("could not write %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("# pack-refs with: peeled fully-peeled sorted \n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s %s\n", refs[name].String(), name)
+		if target, ok := peeled[name]; ok {
+			fmt.Fprintf(buf, "^%s\n", target.String())
+		}
+	}
+	if err := os.WriteFile(ginternals.PackedRefsPath(g.cfg), buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("could not write packed-refs: %w", err)
+	}
+	return nil
+}
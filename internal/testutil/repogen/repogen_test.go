@@ -0,0 +1,182 @@
+package repogen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc string
+		opts repogen.Options
+	}{
+		{
+			desc: "loose objects and references",
+			opts: repogen.Options{
+				Commits:     20,
+				Width:       3,
+				Depth:       2,
+				FileLines:   10,
+				BinaryFiles: 2,
+				BinarySize:  1024,
+				Branches:    3,
+				Tags:        2,
+			},
+		},
+		{
+			desc: "packed objects and references",
+			opts: repogen.Options{
+				Commits:     20,
+				Width:       3,
+				Depth:       2,
+				FileLines:   10,
+				BinaryFiles: 2,
+				BinarySize:  1024,
+				Branches:    3,
+				Tags:        2,
+				PackedRefs:  true,
+				Packs:       3,
+			},
+		},
+		{
+			desc: "large offsets",
+			opts: repogen.Options{
+				Commits:              20,
+				Width:                3,
+				FileLines:            10,
+				Packs:                1,
+				LargeOffsetThreshold: 1,
+			},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			res, cleanup := repogen.New(t, tc.opts)
+			t.Cleanup(cleanup)
+
+			r, err := git.OpenRepository(res.Path)
+			require.NoError(t, err, "test %d", i)
+			t.Cleanup(func() {
+				require.NoError(t, r.Close(), "test %d", i)
+			})
+
+			// History
+			head, err := r.Head()
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, res.Head, head.Target, "test %d", i)
+			commits := []ginternals.Oid{}
+			err = r.WalkCommits([]ginternals.Oid{res.Head}, git.WalkOptions{}, func(c *object.Commit) error {
+				commits = append([]ginternals.Oid{c.ID()}, commits...)
+				return nil
+			})
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, res.Commits, commits, "test %d", i)
+
+			// Objects
+			for _, oid := range res.Blobs {
+				_, err = r.Blob(oid)
+				require.NoError(t, err, "test %d: blob %s", i, oid.String())
+			}
+			err = r.WalkObjects([]ginternals.Oid{res.Head}, git.WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+				_, err := r.Object(oid)
+				return err
+			})
+			require.NoError(t, err, "test %d", i)
+			assert.Len(t, res.Packs, tc.opts.Packs, "test %d", i)
+			if tc.opts.Packs > 0 {
+				assert.NotZero(t, res.Deltas, "test %d", i)
+			}
+
+			// References
+			refs := []string{}
+			err = r.WalkReferences(func(ref *ginternals.Reference) error {
+				if ref.Name() != ginternals.Head {
+					refs = append(refs, ref.Name())
+				}
+				return nil
+			})
+			require.NoError(t, err, "test %d", i)
+			assert.ElementsMatch(t, res.References, refs, "test %d", i)
+			assert.Len(t, res.References, 1+tc.opts.Branches+tc.opts.Tags, "test %d", i)
+			_, err = os.Stat(filepath.Join(res.Path, ".git", "packed-refs"))
+			assert.Equal(t, tc.opts.PackedRefs, err == nil, "test %d", i)
+		})
+	}
+}
+
+func TestGenerateLargeOffsets(t *testing.T) {
+	t.Parallel()
+
+	res, cleanup := repogen.New(t, repogen.Options{
+		Commits:              10,
+		Width:                2,
+		Packs:                1,
+		LargeOffsetThreshold: 1,
+	})
+	t.Cleanup(cleanup)
+
+	idxPath := filepath.Join(res.Path, ".git", "objects", "pack", "pack-"+res.Packs[0].String()+packfile.ExtIndex)
+	idx, err := os.ReadFile(idxPath)
+	require.NoError(t, err)
+	pack, err := os.ReadFile(filepath.Join(res.Path, ".git", "objects", "pack", "pack-"+res.Packs[0].String()+packfile.ExtPackfile))
+	require.NoError(t, err)
+
+	// The regular index is built by BuildIndex, which only uses layer5
+	// for the offsets bigger than 2GB. The one of the generator should
+	// have an extra 8 bytes per object, but the first one
+	regular, err := packfile.BuildIndex(pack)
+	require.NoError(t, err)
+	objectCount := (len(regular) - 8 - 256*4 - 2*ginternals.OidSize) / (ginternals.OidSize + 4 + 4)
+	assert.Equal(t, len(regular)+(objectCount-1)*8, len(idx))
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	t.Parallel()
+
+	opts := repogen.Options{
+		Commits:     10,
+		Width:       2,
+		BinaryFiles: 1,
+		BinarySize:  100,
+		Tags:        1,
+	}
+	res1, cleanup := repogen.New(t, opts)
+	t.Cleanup(cleanup)
+	res2, cleanup := repogen.New(t, opts)
+	t.Cleanup(cleanup)
+	assert.Equal(t, res1.Commits, res2.Commits)
+
+	opts.Seed = 1
+	res3, cleanup := repogen.New(t, opts)
+	t.Cleanup(cleanup)
+	assert.NotEqual(t, res1.Head, res3.Head, "the seed should change the binary files")
+}
+
+func TestGenerateInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	for i, opts := range []repogen.Options{
+		{Width: 1},
+		{Commits: 1},
+		{Commits: 1, Width: 1, Depth: -1},
+		{Commits: 1, Width: 1, Packs: 2},
+	} {
+		_, err := repogen.Generate(t.TempDir(), opts)
+		require.ErrorIs(t, err, repogen.ErrInvalidOptions, "test %d", i)
+	}
+}
@@ -0,0 +1,62 @@
+// Package zlibutil contains methods to decompress zlib data, which is
+// how git stores the content of its objects.
+// All the decompression of the objects should go through this
+// package, so the implementation can be swapped or tuned in a
+// single place
+package zlibutil
+
+import (
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// readerPool contains the zlib readers that are not used anymore.
+// Creating a zlib reader allocates a fair amount of memory, which
+// adds up when reading thousands of small objects
+//nolint:gochecknoglobals // the pool needs to be shared by everyone
+var readerPool sync.Pool
+
+// zlibReader represents the readers returned by zlib.NewReader
+type zlibReader interface {
+	io.ReadCloser
+	zlib.Resetter
+}
+
+// reader is a zlib reader that goes back to the pool once closed
+type reader struct {
+	zlibReader
+}
+
+// Close closes the reader and puts it back in the pool.
+// The reader must not be used once closed
+func (r *reader) Close() error {
+	if r.zlibReader == nil {
+		return nil
+	}
+	err := r.zlibReader.Close()
+	readerPool.Put(r.zlibReader)
+	r.zlibReader = nil
+	return err
+}
+
+// NewReader returns a reader that decompresses the zlib data read
+// from src.
+// The reader needs to be closed once done
+func NewReader(src io.Reader) (io.ReadCloser, error) {
+	if zr, ok := readerPool.Get().(zlibReader); ok {
+		if err := zr.Reset(src, nil); err != nil {
+			// The reader is still valid, so it can be reused
+			readerPool.Put(zr)
+			return nil, fmt.Errorf("could not reset the zlib reader: %w", err)
+		}
+		return &reader{zlibReader: zr}, nil
+	}
+
+	zr, err := zlib.NewReader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{zlibReader: zr.(zlibReader)}, nil //nolint:forcetypeassert // zlib always returns a Resetter
+}
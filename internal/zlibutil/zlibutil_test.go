@@ -0,0 +1,57 @@
+package zlibutil_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/zlibutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReader(t *testing.T) {
+	t.Parallel()
+
+	// compress returns the zlib version of data
+	compress := func(t *testing.T, data string) []byte {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		_, err := zw.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	t.Run("readers should be reusable", func(t *testing.T) {
+		t.Parallel()
+
+		for _, data := range []string{"first", "second", "third"} {
+			r, err := zlibutil.NewReader(bytes.NewReader(compress(t, data)))
+			require.NoError(t, err)
+			out, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, data, string(out))
+			require.NoError(t, r.Close())
+			// closing twice should be a no-op
+			require.NoError(t, r.Close())
+		}
+	})
+
+	t.Run("should fail on invalid data", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := zlibutil.NewReader(bytes.NewReader([]byte("not zlib")))
+		require.Error(t, err)
+
+		// the pool should still work
+		r, err := zlibutil.NewReader(bytes.NewReader(compress(t, "data")))
+		require.NoError(t, err)
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(out))
+		require.NoError(t, r.Close())
+	})
+}
@@ -0,0 +1,57 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/transport"
+)
+
+// LsRemoteOptions represents the options that can be used to list
+// the references of a remote
+type LsRemoteOptions struct {
+	// Heads limits the references to the branches (refs/heads/)
+	Heads bool
+	// Tags limits the references to the tags (refs/tags/).
+	// Using both Heads and Tags returns the branches and the tags
+	Tags bool
+	// Transport contains the options used to connect to the remote
+	Transport transport.Options
+}
+
+// LsRemote returns the references advertised by the remote
+// repository at the given URL, in the order they were advertised.
+// The URL can also be the path to a local repository.
+// No objects are downloaded, and no local repository is needed.
+// The symbolic references (like HEAD) have their SymbolicTarget
+// set if the remote advertised it, and the annotated tags have their
+// Peeled object set.
+// This is the equivalent of git ls-remote
+func LsRemote(url string, opts LsRemoteOptions) (refs []*transport.Ref, err error) {
+	ep, err := transport.ParseEndpoint(url)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the error already contains the URL
+	}
+	t, err := transport.New(ep, opts.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %w", url, err)
+	}
+	defer errutil.Close(t, &err)
+
+	adv, err := t.ListRefs()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the references of %s: %w", url, err)
+	}
+
+	refs = make([]*transport.Ref, 0, len(adv.Refs))
+	for _, ref := range adv.Refs {
+		if (opts.Heads || opts.Tags) &&
+			!(opts.Heads && strings.HasPrefix(ref.Name, "refs/heads/")) &&
+			!(opts.Tags && strings.HasPrefix(ref.Name, "refs/tags/")) {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
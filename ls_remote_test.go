@@ -0,0 +1,76 @@
+package git
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLsRemote(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		for _, line := range []string{
+			"# service=git-upload-pack\n",
+			"",
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD\x00ofs-delta symref=HEAD:refs/heads/master\n",
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master\n",
+			"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated\n",
+			"6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refs/tags/annotated^{}\n",
+			"3fe6cf63fceced491a79fe634eb1e2c888225707 refs/stash\n",
+			"",
+		} {
+			if line == "" {
+				w.Write([]byte(pktline.Flush)) //nolint:errcheck // the test will fail anyway
+				continue
+			}
+			w.Write(pktline.Encode([]byte(line))) //nolint:errcheck // the test will fail anyway
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	refNames := func(refs []*transport.Ref) []string {
+		names := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			names = append(names, ref.Name)
+		}
+		return names
+	}
+
+	t.Run("should return all the refs", func(t *testing.T) {
+		t.Parallel()
+
+		refs, err := LsRemote(srv.URL+"/repo.git", LsRemoteOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"HEAD", "refs/heads/master", "refs/tags/annotated", "refs/stash"}, refNames(refs))
+		assert.Equal(t, "refs/heads/master", refs[0].SymbolicTarget)
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", refs[2].Peeled.String())
+	})
+
+	t.Run("should filter the refs", func(t *testing.T) {
+		t.Parallel()
+
+		refs, err := LsRemote(srv.URL+"/repo.git", LsRemoteOptions{Heads: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"refs/heads/master"}, refNames(refs))
+
+		refs, err = LsRemote(srv.URL+"/repo.git", LsRemoteOptions{Heads: true, Tags: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"refs/heads/master", "refs/tags/annotated"}, refNames(refs))
+	})
+
+	t.Run("should fail with an invalid URL", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := LsRemote("ftp://localhost/repo.git", LsRemoteOptions{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, transport.ErrInvalidEndpoint), "unexpected error: %v", err)
+	})
+}
@@ -0,0 +1,57 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nivl/git-go/ginternals/mailmap"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/afero"
+)
+
+// mailmapFileName is the name of the mailmap file at the root of the
+// working tree
+const mailmapFileName = ".mailmap"
+
+// Mailmap returns the mailmap of the repository, built from the
+// .mailmap file at the root of the working tree and the file set in
+// mailmap.file (which takes precedence).
+// An empty mailmap is returned if none of the files exist
+func (r *Repository) Mailmap() (*mailmap.Mailmap, error) {
+	m := mailmap.New()
+	if r.workTree != nil {
+		if err := parseMailmapFile(r.workTree, m, filepath.Join(r.Config.WorkTreePath, mailmapFileName)); err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := r.Config.FromFile().MailmapFile(); ok {
+		// Relative paths are relative to the working tree
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(r.Config.WorkTreePath, p)
+		}
+		if err := parseMailmapFile(r.Config.FS, m, p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseMailmapFile adds the content of the given file to the mailmap.
+// Missing files are ignored
+func parseMailmapFile(fs afero.Fs, m *mailmap.Mailmap, path string) (err error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer errutil.Close(f, &err)
+
+	if err = m.Parse(f); err != nil {
+		return fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package git
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// paintSlop is the number of commits that are still walked once only
+// stale commits are left in the queue. This is the same value as git,
+// and gives a chance to commits with a skewed date to be reached
+const paintSlop = 5
+
+// generationInfinity is the generation used for the commits that are
+// not in the commit-graph
+const generationInfinity = math.MaxUint32
+
+// Flags used to paint the commits
+const (
+	// paintLeft is set on the commits reachable from the first commit
+	paintLeft uint8 = 1 << iota
+	// paintRight is set on the commits reachable from the other
+	// commits
+	paintRight
+	// paintStale is set on the commits that are reachable from a
+	// commit reachable from both sides
+	paintStale
+	// paintResult is set on the merge bases
+	paintResult
+)
+
+// MergeBases returns the best common ancestors of one and the other
+// commits, most recent first. If several commits are provided in
+// others, the merge bases are computed between one and a hypothetical
+// merge of all the others.
+// This is the equivalent of git merge-base --all.
+//
+// Like git, the history is walked from the most recent commits to the
+// oldest, and the walk stops as soon as all the commits left are
+// reachable from both sides. When the repository has a commit-graph,
+// the commits are sorted by generation number, which is always
+// accurate. Otherwise the committer dates are used, which can give a
+// wrong result if a commit is older than its parents (clock skew). To
+// limit the risk, a few more commits are walked once the walk
+// should have stopped.
+// An empty list is returned if the commits have no common ancestors
+func (r *Repository) MergeBases(one ginternals.Oid, others ...ginternals.Oid) ([]*object.Commit, error) {
+	p, err := r.newCommitPainter()
+	if err != nil {
+		return nil, err
+	}
+	bases, err := p.paint(one, others)
+	if err != nil {
+		return nil, err
+	}
+	return r.removeRedundantCommits(bases)
+}
+
+// IsAncestor returns whether ancestor is reachable from descendant.
+// A commit is considered to be its own ancestor.
+// This is the equivalent of git merge-base --is-ancestor, and is
+// subject to the same accuracy trade-offs as MergeBases()
+func (r *Repository) IsAncestor(ancestor, descendant ginternals.Oid) (bool, error) {
+	p, err := r.newCommitPainter()
+	if err != nil {
+		return false, err
+	}
+	ancestorCommit, err := r.peelToCommit(ancestor)
+	if err != nil {
+		return false, err
+	}
+	bases, err := p.paint(descendant, []ginternals.Oid{ancestorCommit.ID()})
+	if err != nil {
+		return false, err
+	}
+	for _, c := range bases {
+		if c.ID() == ancestorCommit.ID() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AheadBehind returns the number of commits reachable from one but
+// not from other (ahead), and the number of commits reachable from
+// other but not from one (behind).
+// This is the equivalent of git rev-list --left-right --count one...other,
+// and is subject to the same accuracy trade-offs as MergeBases()
+func (r *Repository) AheadBehind(one, other ginternals.Oid) (ahead, behind int, err error) {
+	p, err := r.newCommitPainter()
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err = p.paint(one, []ginternals.Oid{other}); err != nil {
+		return 0, 0, err
+	}
+	for _, flags := range p.flags {
+		switch flags & (paintLeft | paintRight) {
+		case paintLeft:
+			ahead++
+		case paintRight:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// removeRedundantCommits removes the commits that are reachable from
+// other commits of the list, and sorts the remaining ones by date,
+// most recent first
+func (r *Repository) removeRedundantCommits(commits []*object.Commit) ([]*object.Commit, error) {
+	out := make([]*object.Commit, 0, len(commits))
+	for i, c := range commits {
+		redundant := false
+		for j, other := range commits {
+			if i == j {
+				continue
+			}
+			isAncestor, err := r.IsAncestor(c.ID(), other.ID())
+			if err != nil {
+				return nil, err
+			}
+			if isAncestor {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Committer().Time.After(out[j].Committer().Time)
+	})
+	return out, nil
+}
+
+// commitPainter walks the history of 2 sides to find their common
+// ancestors, marking every commit with the side it's reachable from.
+// This is the same algorithm as paint_down_to_common() in git
+type commitPainter struct {
+	r     *Repository
+	graph *commitgraph.Graph
+	flags map[ginternals.Oid]uint8
+	queue *paintQueue
+}
+
+// newCommitPainter returns a new painter, using the commit-graph of the
+// repository if it has one
+func (r *Repository) newCommitPainter() (*commitPainter, error) {
+	graph, err := r.dotGit.CommitGraph()
+	if err != nil {
+		if !errors.Is(err, commitgraph.ErrNoGraph) {
+			return nil, fmt.Errorf("could not load the commit-graph: %w", err)
+		}
+		graph = nil
+	}
+	return &commitPainter{
+		r:     r,
+		graph: graph,
+		flags: map[ginternals.Oid]uint8{},
+		queue: &paintQueue{},
+	}, nil
+}
+
+// paint paints all the commits reachable from one with paintLeft, and
+// all the commits reachable from others with paintRight, and returns
+// the commits that are reachable from both sides, without their
+// ancestors
+func (p *commitPainter) paint(one ginternals.Oid, others []ginternals.Oid) ([]*object.Commit, error) {
+	if err := p.push(one, paintLeft); err != nil {
+		return nil, err
+	}
+	for _, oid := range others {
+		if err := p.push(oid, paintRight); err != nil {
+			return nil, err
+		}
+	}
+
+	results := []*object.Commit{}
+	slop := paintSlop
+	for p.queue.Len() > 0 {
+		if p.queue.stale() {
+			if slop == 0 {
+				break
+			}
+			slop--
+		} else {
+			slop = paintSlop
+		}
+
+		entry := heap.Pop(p.queue).(paintEntry) //nolint:forcetypeassert // the queue only contains paintEntry
+		c := entry.commit
+		flags := p.flags[c.ID()] & (paintLeft | paintRight | paintStale)
+		if flags&(paintLeft|paintRight) == paintLeft|paintRight {
+			if p.flags[c.ID()]&paintResult == 0 && flags&paintStale == 0 {
+				p.flags[c.ID()] |= paintResult
+				results = append(results, c)
+			}
+			// The ancestors of a common ancestor cannot be the best
+			// common ancestor
+			flags |= paintStale
+		}
+
+		for _, parentID := range c.ParentIDs() {
+			if p.flags[parentID]&flags == flags {
+				continue
+			}
+			if err := p.push(parentID, flags); err != nil {
+				return nil, fmt.Errorf("could not get parent %s of %s: %w", parentID.String(), c.ID().String(), err)
+			}
+		}
+	}
+
+	// A commit may have been marked as stale after being added to the
+	// results
+	out := make([]*object.Commit, 0, len(results))
+	for _, c := range results {
+		if p.flags[c.ID()]&paintStale == 0 {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// push adds the given flags to the commit, and adds it to the queue.
+// A commit already in the queue is added again, so its new flags
+// are propagated to its parents
+func (p *commitPainter) push(oid ginternals.Oid, flags uint8) error {
+	c, err := p.r.peelToCommit(oid)
+	if err != nil {
+		return err
+	}
+	p.flags[c.ID()] |= flags
+	entry := paintEntry{
+		commit:     c,
+		generation: generationInfinity,
+		stale:      p.flags[c.ID()]&paintStale != 0,
+	}
+	if p.graph != nil {
+		if gc, err := p.graph.Commit(c.ID()); err == nil && gc.Generation != 0 {
+			entry.generation = gc.Generation
+		}
+	}
+	heap.Push(p.queue, entry)
+	return nil
+}
+
+// paintEntry represents a commit in a paintQueue
+type paintEntry struct {
+	commit     *object.Commit
+	generation uint32
+	stale      bool
+}
+
+// paintQueue is a priority queue that returns the commits with the
+// highest generation first, and then the most recent commits first
+// (using the committer date).
+// It keeps track of the number of stale commits it contains.
+// It implements heap.Interface and should be used with container/heap
+type paintQueue struct {
+	entries    []paintEntry
+	staleCount int
+}
+
+// stale returns whether the queue only contains stale commits
+func (q *paintQueue) stale() bool {
+	return q.staleCount == len(q.entries)
+}
+
+func (q *paintQueue) Len() int {
+	return len(q.entries)
+}
+
+func (q *paintQueue) Less(i, j int) bool {
+	a, b := q.entries[i], q.entries[j]
+	if a.generation != b.generation {
+		return a.generation > b.generation
+	}
+	return a.commit.Committer().Time.After(b.commit.Committer().Time)
+}
+
+func (q *paintQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+}
+
+func (q *paintQueue) Push(x interface{}) {
+	entry := x.(paintEntry) //nolint:forcetypeassert // the queue only contains paintEntry
+	if entry.stale {
+		q.staleCount++
+	}
+	q.entries = append(q.entries, entry)
+}
+
+func (q *paintQueue) Pop() interface{} {
+	last := len(q.entries) - 1
+	entry := q.entries[last]
+	q.entries[last] = paintEntry{}
+	q.entries = q.entries[:last]
+	if entry.stale {
+		q.staleCount--
+	}
+	return entry
+}
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeBases(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns a new copy of the small repo, with or without
+	// its commit-graph
+	newRepo := func(t *testing.T, withCommitGraph bool) *Repository {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		if !withCommitGraph {
+			require.NoError(t, os.Remove(filepath.Join(repoPath, ".git", "objects", "info", "commit-graph")))
+		}
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		return r
+	}
+
+	oid := func(t *testing.T, sha string) ginternals.Oid {
+		t.Helper()
+		oid, err := ginternals.NewOidFromStr(sha)
+		require.NoError(t, err)
+		return oid
+	}
+
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	// b328320 "doc: Update TODOs in readme", on ml/tests
+	rebased := "b328320060eb503cf337c7cff281712ef236963a"
+	// 5f35f2d "stash", on ml/feat/clone
+	clone := "5f35f2dc6cec7356da02ca26192ce2bc3f271e79"
+	// 3fe6cf6 "WIP on tests", the stash
+	stash := "3fe6cf63fceced491a79fe634eb1e2c888225707"
+	// f0f7014 "refactor: Update codebase to go 1.13"
+	forkPoint := "f0f70144f38695250606b86a50cff2b440a417f3"
+
+	// All the expected values have been generated using
+	// git merge-base --all and git rev-list --left-right --count
+	testCases := []struct {
+		desc           string
+		one            string
+		others         []string
+		expectedBases  []string
+		expectedAhead  int
+		expectedBehind int
+	}{
+		{
+			desc:          "same commit",
+			one:           head,
+			others:        []string{head},
+			expectedBases: []string{head},
+		},
+		{
+			desc:          "ancestor",
+			one:           head,
+			others:        []string{forkPoint},
+			expectedBases: []string{forkPoint},
+			expectedAhead: 8,
+		},
+		{
+			desc:           "diverging branches",
+			one:            clone,
+			others:         []string{head},
+			expectedBases:  []string{forkPoint},
+			expectedAhead:  7,
+			expectedBehind: 8,
+		},
+		{
+			desc:           "diverging branches with a merge",
+			one:            stash,
+			others:         []string{clone},
+			expectedBases:  []string{forkPoint},
+			expectedAhead:  2,
+			expectedBehind: 7,
+		},
+		{
+			desc:           "diverging branches with the same content",
+			one:            rebased,
+			others:         []string{head},
+			expectedBases:  []string{forkPoint},
+			expectedAhead:  8,
+			expectedBehind: 8,
+		},
+		{
+			desc:          "multiple commits",
+			one:           clone,
+			others:        []string{rebased, head},
+			expectedBases: []string{forkPoint},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		for _, withCommitGraph := range []bool{true, false} {
+			withCommitGraph := withCommitGraph
+			t.Run(fmt.Sprintf("%d/%s/commit-graph=%t", i, tc.desc, withCommitGraph), func(t *testing.T) {
+				t.Parallel()
+
+				r := newRepo(t, withCommitGraph)
+				others := make([]ginternals.Oid, 0, len(tc.others))
+				for _, sha := range tc.others {
+					others = append(others, oid(t, sha))
+				}
+
+				bases, err := r.MergeBases(oid(t, tc.one), others...)
+				require.NoError(t, err)
+				basesSHA := make([]string, 0, len(bases))
+				for _, c := range bases {
+					basesSHA = append(basesSHA, c.ID().String())
+				}
+				assert.Equal(t, tc.expectedBases, basesSHA)
+
+				if len(tc.others) == 1 {
+					ahead, behind, err := r.AheadBehind(oid(t, tc.one), others[0])
+					require.NoError(t, err)
+					assert.Equal(t, tc.expectedAhead, ahead, "invalid ahead")
+					assert.Equal(t, tc.expectedBehind, behind, "invalid behind")
+				}
+			})
+		}
+	}
+
+	t.Run("criss-cross merges should have multiple bases", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, false)
+		headCommit, err := r.Commit(oid(t, head))
+		require.NoError(t, err)
+		tree, err := r.Tree(headCommit.TreeID())
+		require.NoError(t, err)
+
+		sig := object.NewSignature("author", "author@domain.tld")
+		newCommit := func(t *testing.T, parents ...ginternals.Oid) ginternals.Oid {
+			t.Helper()
+			c, err := r.NewDetachedCommit(tree, sig, &object.CommitOptions{
+				ParentsID: parents,
+				Message:   "commit",
+			})
+			require.NoError(t, err)
+			return c.ID()
+		}
+
+		a := newCommit(t, oid(t, head))
+		b := newCommit(t, oid(t, rebased))
+		ab := newCommit(t, a, b)
+		ba := newCommit(t, b, a)
+
+		bases, err := r.MergeBases(ab, ba)
+		require.NoError(t, err)
+		basesID := make([]ginternals.Oid, 0, len(bases))
+		for _, c := range bases {
+			basesID = append(basesID, c.ID())
+		}
+		assert.ElementsMatch(t, []ginternals.Oid{a, b}, basesID)
+	})
+
+	t.Run("unrelated commits should have no bases", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, false)
+		headCommit, err := r.Commit(oid(t, head))
+		require.NoError(t, err)
+		tree, err := r.Tree(headCommit.TreeID())
+		require.NoError(t, err)
+
+		root, err := r.NewDetachedCommit(tree, object.NewSignature("author", "author@domain.tld"), &object.CommitOptions{
+			Message: "root commit",
+		})
+		require.NoError(t, err)
+
+		bases, err := r.MergeBases(root.ID(), headCommit.ID())
+		require.NoError(t, err)
+		assert.Empty(t, bases)
+
+		ahead, behind, err := r.AheadBehind(root.ID(), headCommit.ID())
+		require.NoError(t, err)
+		assert.Equal(t, 1, ahead, "invalid ahead")
+		assert.Equal(t, 17, behind, "invalid behind")
+	})
+}
+
+func TestIsAncestor(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	// generated using git merge-base --is-ancestor
+	testCases := []struct {
+		desc       string
+		ancestor   string
+		descendant string
+		expected   bool
+	}{
+		{
+			desc:       "a commit is its own ancestor",
+			ancestor:   "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			descendant: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			expected:   true,
+		},
+		{
+			desc:       "parent",
+			ancestor:   "1dcdadc2a420225783794fbffd51e2e137a69646",
+			descendant: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			expected:   true,
+		},
+		{
+			desc:       "child",
+			ancestor:   "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			descendant: "1dcdadc2a420225783794fbffd51e2e137a69646",
+			expected:   false,
+		},
+		{
+			desc:       "other branch",
+			ancestor:   "5f35f2dc6cec7356da02ca26192ce2bc3f271e79",
+			descendant: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			expected:   false,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			ancestor, err := ginternals.NewOidFromStr(tc.ancestor)
+			require.NoError(t, err)
+			descendant, err := ginternals.NewOidFromStr(tc.descendant)
+			require.NoError(t, err)
+
+			isAncestor, err := r.IsAncestor(ancestor, descendant)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, isAncestor)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals/pathspec"
+)
+
+// Pathspec parses the provided pathspecs, honoring the
+// GIT_*_PATHSPECS environment variables of the repository (like
+// GIT_LITERAL_PATHSPECS).
+// The pathspecs are expected to be relative to the root of the
+// repository
+func (r *Repository) Pathspec(specs ...string) (*pathspec.Pathspec, error) {
+	opts, err := pathspec.OptionsFromEnv(r.Config.Env())
+	if err != nil {
+		return nil, fmt.Errorf("could not read the pathspec settings: %w", err)
+	}
+	return pathspec.New(specs, opts)
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// Peel returns the object targeted by the given reference once all
+// the annotated tags have been followed.
+// The peeled values of the packed-refs file are used when available,
+// so the tags don't have to be loaded
+func (r *Repository) Peel(ref *ginternals.Reference) (ginternals.Oid, error) {
+	return r.dotGit.PeelReference(ref)
+}
+
+// PeelObject follows the given object until an object of the given type
+// is found, the same way git does with the <rev>^{<type>} syntax.
+// The annotated tags are followed to their target, and the commits
+// to their tree.
+// object.ErrObjectInvalid is returned if the object cannot be peeled
+// to the given type
+func (r *Repository) PeelObject(o *object.Object, targetType object.Type) (*object.Object, error) {
+	for o.Type() != targetType {
+		var next ginternals.Oid
+		switch o.Type() {
+		case object.TypeTag:
+			tag, err := object.NewTagFromObjectWithLimits(o, r.dotGit.Limits())
+			if err != nil {
+				return nil, fmt.Errorf("could not parse tag %s: %w", o.ID().String(), err)
+			}
+			next = tag.Target()
+		case object.TypeCommit:
+			if targetType != object.TypeTree {
+				return nil, fmt.Errorf("%s is a commit, not a %s: %w", o.ID().String(), targetType.String(), object.ErrObjectInvalid)
+			}
+			c, err := object.NewCommitFromObjectWithLimits(o, r.dotGit.Limits())
+			if err != nil {
+				return nil, fmt.Errorf("could not parse commit %s: %w", o.ID().String(), err)
+			}
+			next = c.TreeID()
+		default:
+			return nil, fmt.Errorf("%s is a %s, not a %s: %w", o.ID().String(), o.Type().String(), targetType.String(), object.ErrObjectInvalid)
+		}
+
+		var err error
+		if o, err = r.dotGit.Object(next); err != nil {
+			return nil, fmt.Errorf("could not get object %s: %w", next.String(), err)
+		}
+	}
+	return o, nil
+}
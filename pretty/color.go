@@ -0,0 +1,99 @@
+package pretty
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColorFunc represents a function that returns the sequence used to
+// apply the given color specification (ex. "red", "bold blue",
+// "reset") to the text that follows it.
+// An empty string should be returned if the specification is not
+// supported
+type ColorFunc func(spec string) string
+
+// colorCodes contains the ANSI codes of the colors supported by git
+//
+//nolint:gochecknoglobals // Treat it as a const
+var colorCodes = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+// attributeCodes contains the ANSI codes of the attributes supported
+// by git
+//
+//nolint:gochecknoglobals // Treat it as a const
+var attributeCodes = map[string]int{
+	"bold":    1,
+	"dim":     2,
+	"italic":  3,
+	"ul":      4,
+	"blink":   5,
+	"reverse": 7,
+	"strike":  9,
+}
+
+// ANSIColor is a ColorFunc that returns the ANSI escape sequences of
+// the color specifications, using the same syntax as the colors in
+// the git config: up to 2 colors (the foreground, then the
+// background) and any number of attributes.
+// The colors can be prefixed with "bright", and the attributes can be
+// prefixed with "no" to turn them off
+func ANSIColor(spec string) string {
+	words := strings.Fields(spec)
+	if len(words) == 1 && words[0] == "reset" {
+		return "\x1b[m"
+	}
+
+	codes := []string{}
+	colors := 0
+	for _, word := range words {
+		if code, ok := attributeCodes[word]; ok {
+			codes = append(codes, strconv.Itoa(code))
+			continue
+		}
+		if code, ok := attributeCodes[strings.TrimPrefix(strings.TrimPrefix(word, "no"), "-")]; ok && strings.HasPrefix(word, "no") {
+			// bold and dim are both turned off by 22
+			if code == 1 {
+				code = 2
+			}
+			codes = append(codes, strconv.Itoa(20+code))
+			continue
+		}
+
+		if colors == 2 {
+			return ""
+		}
+		base := 30
+		if colors == 1 {
+			base = 40
+		}
+		colors++
+		if word == "normal" || word == "default" {
+			if word == "default" {
+				codes = append(codes, strconv.Itoa(base+9))
+			}
+			continue
+		}
+		if strings.HasPrefix(word, "bright") {
+			base += 60
+			word = strings.TrimPrefix(word, "bright")
+		}
+		code, ok := colorCodes[word]
+		if !ok {
+			return ""
+		}
+		codes = append(codes, strconv.Itoa(base+code))
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
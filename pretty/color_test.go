@@ -0,0 +1,36 @@
+package pretty_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/pretty"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestANSIColor(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		spec     string
+		expected string
+	}{
+		{spec: "reset", expected: "\x1b[m"},
+		{spec: "red", expected: "\x1b[31m"},
+		{spec: "bold red", expected: "\x1b[1;31m"},
+		{spec: "yellow blue", expected: "\x1b[33;44m"},
+		{spec: "brightgreen", expected: "\x1b[92m"},
+		{spec: "normal blue", expected: "\x1b[44m"},
+		{spec: "nobold", expected: "\x1b[22m"},
+		{spec: "red green blue", expected: ""},
+		{spec: "nope", expected: ""},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.spec), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, pretty.ANSIColor(tc.spec))
+		})
+	}
+}
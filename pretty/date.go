@@ -0,0 +1,139 @@
+package pretty
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrUnknownDateFormat is an error thrown when a date format is not
+// supported
+var ErrUnknownDateFormat = errors.New("unknown date format")
+
+// DateFormat represents the format used to display the dates
+type DateFormat int8
+
+const (
+	// DateDefault displays the dates using the default format of git
+	// log (ex. "Sat Jun 20 01:24:10 2020 -0700")
+	DateDefault DateFormat = iota
+	// DateRelative displays the dates relative to the current time
+	// (ex. "2 hours ago")
+	DateRelative
+	// DateISO displays the dates using an ISO 8601-like format
+	// (ex. "2020-06-20 01:24:10 -0700")
+	DateISO
+	// DateISOStrict displays the dates using the strict ISO 8601
+	// format (ex. "2020-06-20T01:24:10-07:00")
+	DateISOStrict
+	// DateRFC2822 displays the dates using the format of the emails
+	// (ex. "Sat, 20 Jun 2020 01:24:10 -0700")
+	DateRFC2822
+	// DateShort only displays the day (ex. "2020-06-20")
+	DateShort
+	// DateRaw displays the dates using the internal format of git
+	// (ex. "1592641450 -0700")
+	DateRaw
+	// DateUnix displays the dates as unix timestamps
+	// (ex. "1592641450")
+	DateUnix
+)
+
+// ParseDateFormat returns the DateFormat matching the given name, as
+// used by --date (ex. "relative", "iso")
+func ParseDateFormat(name string) (DateFormat, error) {
+	switch name {
+	case "", "default":
+		return DateDefault, nil
+	case "relative":
+		return DateRelative, nil
+	case "iso", "iso8601":
+		return DateISO, nil
+	case "iso-strict", "iso8601-strict":
+		return DateISOStrict, nil
+	case "rfc", "rfc2822":
+		return DateRFC2822, nil
+	case "short":
+		return DateShort, nil
+	case "raw":
+		return DateRaw, nil
+	case "unix":
+		return DateUnix, nil
+	}
+	return DateDefault, fmt.Errorf("%s: %w", name, ErrUnknownDateFormat)
+}
+
+// FormatDate returns the given date using the provided format.
+// now is used to compute the relative dates
+func FormatDate(t time.Time, format DateFormat, now time.Time) string {
+	switch format {
+	case DateRelative:
+		return relativeDate(t, now)
+	case DateISO:
+		return t.Format("2006-01-02 15:04:05 -0700")
+	case DateISOStrict:
+		return t.Format(time.RFC3339)
+	case DateRFC2822:
+		return t.Format("Mon, 2 Jan 2006 15:04:05 -0700")
+	case DateShort:
+		return t.Format("2006-01-02")
+	case DateRaw:
+		return strconv.FormatInt(t.Unix(), 10) + " " + t.Format("-0700")
+	case DateUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case DateDefault:
+	}
+	return t.Format("Mon Jan 2 15:04:05 2006 -0700")
+}
+
+// relativeDate returns how long ago t was, using the same rounding
+// as git
+func relativeDate(t, now time.Time) string {
+	if t.After(now) {
+		return "in the future"
+	}
+	diff := int64(now.Sub(t) / time.Second)
+	if diff < 90 {
+		return plural(diff, "second") + " ago"
+	}
+	// We round to the closest unit
+	diff = (diff + 30) / 60
+	if diff < 90 {
+		return plural(diff, "minute") + " ago"
+	}
+	diff = (diff + 30) / 60
+	if diff < 36 {
+		return plural(diff, "hour") + " ago"
+	}
+	days := (diff + 12) / 24
+	if days < 14 {
+		return plural(days, "day") + " ago"
+	}
+	if days < 70 {
+		return plural((days+3)/7, "week") + " ago"
+	}
+	if days < 365 {
+		return plural((days+15)/30, "month") + " ago"
+	}
+	// Up to 5 years, we display the years and months
+	if days < 1825 {
+		totalMonths := (days*12*2 + 365) / (365 * 2)
+		years := totalMonths / 12
+		months := totalMonths % 12
+		if months == 0 {
+			return plural(years, "year") + " ago"
+		}
+		return plural(years, "year") + ", " + plural(months, "month") + " ago"
+	}
+	return plural((days+183)/365, "year") + " ago"
+}
+
+// plural returns the given count followed by the given unit,
+// pluralized if needed (ex. "1 day", "2 days")
+func plural(count int64, unit string) string {
+	if count == 1 {
+		return "1 " + unit
+	}
+	return strconv.FormatInt(count, 10) + " " + unit + "s"
+}
@@ -0,0 +1,82 @@
+package pretty_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/pretty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDateFormat(t *testing.T) {
+	t.Parallel()
+
+	format, err := pretty.ParseDateFormat("iso8601")
+	require.NoError(t, err)
+	assert.Equal(t, pretty.DateISO, format)
+
+	_, err = pretty.ParseDateFormat("nope")
+	require.ErrorIs(t, err, pretty.ErrUnknownDateFormat)
+}
+
+func TestFormatDate(t *testing.T) {
+	t.Parallel()
+
+	date := time.Unix(1592641450, 0).In(time.FixedZone("", -7*3600))
+
+	testCases := []struct {
+		desc     string
+		format   pretty.DateFormat
+		expected string
+	}{
+		{desc: "default", format: pretty.DateDefault, expected: "Sat Jun 20 01:24:10 2020 -0700"},
+		{desc: "iso", format: pretty.DateISO, expected: "2020-06-20 01:24:10 -0700"},
+		{desc: "iso-strict", format: pretty.DateISOStrict, expected: "2020-06-20T01:24:10-07:00"},
+		{desc: "rfc2822", format: pretty.DateRFC2822, expected: "Sat, 20 Jun 2020 01:24:10 -0700"},
+		{desc: "short", format: pretty.DateShort, expected: "2020-06-20"},
+		{desc: "raw", format: pretty.DateRaw, expected: "1592641450 -0700"},
+		{desc: "unix", format: pretty.DateUnix, expected: "1592641450"},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, pretty.FormatDate(date, tc.format, date))
+		})
+	}
+}
+
+func TestFormatDateRelative(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1592641450, 0)
+	testCases := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{ago: -time.Second, expected: "in the future"},
+		{ago: time.Second, expected: "1 second ago"},
+		{ago: 89 * time.Second, expected: "89 seconds ago"},
+		{ago: 90 * time.Second, expected: "2 minutes ago"},
+		{ago: 89 * time.Minute, expected: "89 minutes ago"},
+		{ago: 35 * time.Hour, expected: "35 hours ago"},
+		{ago: 36 * time.Hour, expected: "2 days ago"},
+		{ago: 13 * 24 * time.Hour, expected: "13 days ago"},
+		{ago: 14 * 24 * time.Hour, expected: "2 weeks ago"},
+		{ago: 70 * 24 * time.Hour, expected: "2 months ago"},
+		{ago: 365 * 24 * time.Hour, expected: "1 year ago"},
+		{ago: 500 * 24 * time.Hour, expected: "1 year, 4 months ago"},
+		{ago: 3650 * 24 * time.Hour, expected: "10 years ago"},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.expected), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, pretty.FormatDate(now.Add(-tc.ago), pretty.DateRelative, now))
+		})
+	}
+}
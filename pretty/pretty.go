@@ -0,0 +1,443 @@
+// Package pretty contains methods to display commits, tags, and tree
+// entries using format strings, like git log --format, git
+// for-each-ref --format, or git ls-tree --format do.
+// https://git-scm.com/docs/pretty-formats
+package pretty
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// defaultAbbrevLen is the number of chars used to display an
+// abbreviated oid
+const defaultAbbrevLen = 7
+
+// Options represents the options that can be used to format objects
+type Options struct {
+	// Date sets the format of the dates of %ad, %cd, and of the
+	// date atoms (ex. %(authordate))
+	Date DateFormat
+	// Now contains the time used to compute the relative dates.
+	// Defaults to the current time
+	Now time.Time
+	// Color returns the sequences used by the %C placeholders.
+	// The colors are removed when nil
+	Color ColorFunc
+	// AbbrevLen contains the number of chars used to display the
+	// abbreviated oids.
+	// Defaults to 7. The oids are not checked for uniqueness
+	AbbrevLen int
+}
+
+// token represents a part of a format string
+type token struct {
+	// literal contains the text to display as-is
+	literal string
+	// placeholder contains the name of the placeholder, without
+	// the "%" (ex. "an", "(authorname)")
+	placeholder string
+	// color contains the color specification of a %C placeholder
+	color string
+	// isColor is set if the token is a %C placeholder
+	isColor bool
+}
+
+// Formatter formats objects using a format string.
+//
+// The following placeholders of git log are supported:
+//   - %H, %h: the oid of the object, full and abbreviated
+//   - %T, %t: the oid of the tree of a commit
+//   - %P, %p: the oids of the parents of a commit, space separated
+//   - %an, %ae, %ad, %ar, %ai, %aI, %at, %as, %aD: the name, the
+//     email, and the date of the author
+//   - %cn, %ce, %cd, %cr, %ci, %cI, %ct, %cs, %cD: the same for the
+//     committer
+//   - %s, %f, %b, %B: the subject, the subject sanitized to be used
+//     as a file name, the body, and the raw message
+//   - %Cred, %Cgreen, %Cblue, %Creset, %C(<spec>): colors
+//   - %n, %%, %x<hex>: a new line, a percent sign, and a raw byte
+//
+// The atoms of git for-each-ref and git ls-tree are also supported:
+// %(objectname), %(objectname:short), %(objecttype), %(tree),
+// %(parent), %(subject), %(body), %(contents), %(authorname),
+// %(authoremail), %(authordate), %(committername), %(committeremail),
+// %(committerdate), %(taggername), %(taggeremail), %(taggerdate),
+// %(tag), %(object), %(type), %(objectmode), and %(path).
+//
+// For a tag, the author and committer placeholders are about the
+// tagger. Like git, placeholders that are not supported, or that
+// don't apply to the object, are displayed as-is
+type Formatter struct {
+	tokens []token
+	opts   Options
+}
+
+// New returns a Formatter that uses the given format string
+func New(format string, opts Options) *Formatter {
+	if opts.AbbrevLen <= 0 || opts.AbbrevLen > ginternals.OidSize*2 {
+		opts.AbbrevLen = defaultAbbrevLen
+	}
+	return &Formatter{
+		tokens: parse(format),
+		opts:   opts,
+	}
+}
+
+// parse splits a format string into tokens
+func parse(format string) []token {
+	tokens := []token{}
+	literal := &strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			tokens = append(tokens, token{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			literal.WriteByte(format[i])
+			continue
+		}
+		rest := format[i+1:]
+		switch {
+		case rest[0] == '%':
+			literal.WriteByte('%')
+			i++
+		case rest[0] == 'n':
+			literal.WriteByte('\n')
+			i++
+		case rest[0] == 'x' && len(rest) >= 3 && isHex(rest[1:3]):
+			b, _ := strconv.ParseUint(rest[1:3], 16, 8) //nolint:errcheck // the value has already been validated
+			literal.WriteByte(byte(b))
+			i += 3
+		case rest[0] == 'C':
+			spec, length := parseColor(rest[1:])
+			if length == 0 {
+				literal.WriteByte('%')
+				continue
+			}
+			flush()
+			tokens = append(tokens, token{isColor: true, color: spec})
+			i += 1 + length
+		case rest[0] == '(':
+			end := strings.IndexByte(rest, ')')
+			if end == -1 {
+				literal.WriteByte('%')
+				continue
+			}
+			flush()
+			tokens = append(tokens, token{placeholder: rest[:end+1]})
+			i += end + 1
+		case (rest[0] == 'a' || rest[0] == 'c') && len(rest) >= 2:
+			flush()
+			tokens = append(tokens, token{placeholder: rest[:2]})
+			i += 2
+		default:
+			flush()
+			tokens = append(tokens, token{placeholder: rest[:1]})
+			i++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseColor returns the color specification at the beginning of s,
+// and the number of chars it uses (0 if there's none).
+// s is what follows %C
+func parseColor(s string) (spec string, length int) {
+	for _, name := range []string{"red", "green", "blue", "reset"} {
+		if strings.HasPrefix(s, name) {
+			return name, len(name)
+		}
+	}
+	if strings.HasPrefix(s, "(") {
+		if end := strings.IndexByte(s, ')'); end != -1 {
+			return strings.TrimSpace(s[1:end]), end + 1
+		}
+	}
+	return "", 0
+}
+
+// isHex returns whether s only contains hexadecimal chars
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Commit returns the given commit formatted
+func (f *Formatter) Commit(c *object.Commit) string {
+	return f.format(func(name string) (string, bool) {
+		return f.commitPlaceholder(c, name)
+	})
+}
+
+// Tag returns the given annotated tag formatted
+func (f *Formatter) Tag(t *object.Tag) string {
+	return f.format(func(name string) (string, bool) {
+		return f.tagPlaceholder(t, name)
+	})
+}
+
+// TreeEntry returns the given tree entry formatted
+func (f *Formatter) TreeEntry(e object.TreeEntry) string {
+	return f.format(func(name string) (string, bool) {
+		return f.treeEntryPlaceholder(e, name)
+	})
+}
+
+// format builds the output using the provided function to get the
+// value of the placeholders
+func (f *Formatter) format(value func(name string) (string, bool)) string {
+	b := &strings.Builder{}
+	for _, t := range f.tokens {
+		switch {
+		case t.isColor:
+			if f.opts.Color != nil && t.color != "auto" {
+				b.WriteString(f.opts.Color(t.color))
+			}
+		case t.placeholder != "":
+			v, ok := value(t.placeholder)
+			if !ok {
+				v = "%" + t.placeholder
+			}
+			b.WriteString(v)
+		default:
+			b.WriteString(t.literal)
+		}
+	}
+	return b.String()
+}
+
+// commitPlaceholder returns the value of a placeholder for a commit
+func (f *Formatter) commitPlaceholder(c *object.Commit, name string) (string, bool) {
+	switch name {
+	case "H", "(objectname)":
+		return c.ID().String(), true
+	case "h", "(objectname:short)":
+		return f.abbrev(c.ID()), true
+	case "(objecttype)":
+		return object.TypeCommit.String(), true
+	case "T", "(tree)":
+		return c.TreeID().String(), true
+	case "t":
+		return f.abbrev(c.TreeID()), true
+	case "P", "(parent)":
+		return f.oids(c.ParentIDs(), false), true
+	case "p":
+		return f.oids(c.ParentIDs(), true), true
+	}
+	if v, ok := f.signaturePlaceholder(c.Author(), "a", "author", name); ok {
+		return v, true
+	}
+	if v, ok := f.signaturePlaceholder(c.Committer(), "c", "committer", name); ok {
+		return v, true
+	}
+	return messagePlaceholder(c.Message(), name)
+}
+
+// tagPlaceholder returns the value of a placeholder for a tag
+func (f *Formatter) tagPlaceholder(t *object.Tag, name string) (string, bool) {
+	switch name {
+	case "H", "(objectname)":
+		return t.ID().String(), true
+	case "h", "(objectname:short)":
+		return f.abbrev(t.ID()), true
+	case "(objecttype)":
+		return object.TypeTag.String(), true
+	case "(tag)":
+		return t.Name(), true
+	case "(object)":
+		return t.Target().String(), true
+	case "(type)":
+		return t.Type().String(), true
+	}
+	if v, ok := f.signaturePlaceholder(t.Tagger(), "a", "tagger", name); ok {
+		return v, true
+	}
+	if v, ok := f.signaturePlaceholder(t.Tagger(), "c", "tagger", name); ok {
+		return v, true
+	}
+	return messagePlaceholder(t.Message(), name)
+}
+
+// treeEntryPlaceholder returns the value of a placeholder for an
+// entry of a tree
+func (f *Formatter) treeEntryPlaceholder(e object.TreeEntry, name string) (string, bool) {
+	switch name {
+	case "H", "(objectname)":
+		return e.ID.String(), true
+	case "h", "(objectname:short)":
+		return f.abbrev(e.ID), true
+	case "(objecttype)":
+		return e.Mode.ObjectType().String(), true
+	case "(objectmode)":
+		return strconv.FormatInt(int64(e.Mode), 8), true
+	case "(path)":
+		return e.Path, true
+	}
+	return "", false
+}
+
+// signaturePlaceholder returns the value of a placeholder about a
+// signature. letter is the letter used by the placeholders of git log
+// (ex. "a" for %an), and atom is the prefix of the atoms of git
+// for-each-ref (ex. "author" for %(authorname))
+func (f *Formatter) signaturePlaceholder(sig object.Signature, letter, atom, name string) (string, bool) {
+	switch name {
+	case "(" + atom + "name)":
+		return sig.Name, true
+	case "(" + atom + "email)":
+		return "<" + sig.Email + ">", true
+	case "(" + atom + "date)":
+		return f.date(sig.Time, f.opts.Date), true
+	}
+	if len(name) != 2 || name[:1] != letter {
+		return "", false
+	}
+	switch name[1] {
+	case 'n':
+		return sig.Name, true
+	case 'e':
+		return sig.Email, true
+	case 'd':
+		return f.date(sig.Time, f.opts.Date), true
+	case 'r':
+		return f.date(sig.Time, DateRelative), true
+	case 'i':
+		return f.date(sig.Time, DateISO), true
+	case 'I':
+		return f.date(sig.Time, DateISOStrict), true
+	case 't':
+		return f.date(sig.Time, DateUnix), true
+	case 's':
+		return f.date(sig.Time, DateShort), true
+	case 'D':
+		return f.date(sig.Time, DateRFC2822), true
+	}
+	return "", false
+}
+
+// messagePlaceholder returns the value of a placeholder about the
+// message of an object
+func messagePlaceholder(message, name string) (string, bool) {
+	switch name {
+	case "s", "(subject)":
+		return Subject(message), true
+	case "f":
+		return SanitizedSubject(message), true
+	case "b", "(body)":
+		return Body(message), true
+	case "B", "(contents)":
+		return message, true
+	}
+	return "", false
+}
+
+// date returns the given time using the given format
+func (f *Formatter) date(t time.Time, format DateFormat) string {
+	now := f.opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return FormatDate(t, format, now)
+}
+
+// abbrev returns the abbreviated version of the given oid
+func (f *Formatter) abbrev(oid ginternals.Oid) string {
+	return oid.String()[:f.opts.AbbrevLen]
+}
+
+// oids returns the given oids space separated
+func (f *Formatter) oids(oids []ginternals.Oid, abbrev bool) string {
+	out := make([]string, len(oids))
+	for i, oid := range oids {
+		out[i] = oid.String()
+		if abbrev {
+			out[i] = f.abbrev(oid)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// Subject returns the subject of a message, which is its first
+// paragraph on a single line
+func Subject(message string) string {
+	subject, _ := splitMessage(message)
+	lines := strings.Split(strings.TrimSpace(subject), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// Body returns the body of a message, which is everything after its
+// first paragraph
+func Body(message string) string {
+	_, body := splitMessage(message)
+	return body
+}
+
+// SanitizedSubject returns the subject of a message, made suitable
+// to be used in a file name, like git format-patch does
+// (ex. "Fix: the tests" becomes "Fix-the-tests")
+func SanitizedSubject(message string) string {
+	subject := Subject(message)
+	b := &strings.Builder{}
+	// needsDash is set when chars have been skipped since the last
+	// char that has been kept
+	needsDash := false
+	for i := 0; i < len(subject); i++ {
+		c := subject[i]
+		if !isTitleChar(c) {
+			needsDash = b.Len() > 0
+			continue
+		}
+		if needsDash {
+			b.WriteByte('-')
+			needsDash = false
+		}
+		b.WriteByte(c)
+		// consecutive dots are collapsed
+		for c == '.' && i+1 < len(subject) && subject[i+1] == '.' {
+			i++
+		}
+	}
+	return strings.TrimRight(b.String(), ".-")
+}
+
+// isTitleChar returns whether the given char can be kept in a
+// sanitized subject
+func isTitleChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '.' || c == '_'
+}
+
+// splitMessage returns the first paragraph of a message, and what
+// follows it. The blank lines surrounding the paragraph are removed
+func splitMessage(message string) (subject, body string) {
+	lines := strings.SplitAfter(message, "\n")
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	start := i
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+		i++
+	}
+	subject = strings.Join(lines[start:i], "")
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	return subject, strings.Join(lines[i:], "")
+}
@@ -0,0 +1,193 @@
+package pretty_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/pretty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatterCommit(t *testing.T) {
+	t.Parallel()
+
+	treeID, err := ginternals.NewOidFromStr("e5b9e846e1b468bc9597ff95d71dfacda8bd54e3")
+	require.NoError(t, err)
+	parentID, err := ginternals.NewOidFromStr("6097a04b7a327c4be68f222ca66e61b8e1abe5c1")
+	require.NoError(t, err)
+	tz := time.FixedZone("", -7*3600)
+	author := object.Signature{
+		Name:  "Author",
+		Email: "author@domain.tld",
+		Time:  time.Unix(1592641450, 0).In(tz),
+	}
+	committer := object.Signature{
+		Name:  "Committer",
+		Email: "committer@domain.tld",
+		Time:  time.Unix(1592645050, 0).In(tz),
+	}
+	c := object.NewCommit(treeID, author, &object.CommitOptions{
+		Message:   "\nfix: the subject\non 2 lines\n\n\nThe body\n\nwith 2 paragraphs\n",
+		Committer: committer,
+		ParentsID: []ginternals.Oid{parentID},
+	})
+	now := committer.Time.Add(3 * time.Hour)
+
+	testCases := []struct {
+		desc     string
+		format   string
+		opts     pretty.Options
+		expected string
+	}{
+		{
+			desc:     "oids",
+			format:   "%H %h %T %t %P %p",
+			expected: c.ID().String() + " " + c.ID().String()[:7] + " e5b9e846e1b468bc9597ff95d71dfacda8bd54e3 e5b9e84 6097a04b7a327c4be68f222ca66e61b8e1abe5c1 6097a04",
+		},
+		{
+			desc:     "abbreviated oids should use AbbrevLen",
+			format:   "%t",
+			opts:     pretty.Options{AbbrevLen: 10},
+			expected: "e5b9e846e1",
+		},
+		{
+			desc:     "author",
+			format:   "%an <%ae> %ad|%ar|%ai|%aI|%at|%as|%aD",
+			expected: "Author <author@domain.tld> Sat Jun 20 01:24:10 2020 -0700|4 hours ago|2020-06-20 01:24:10 -0700|2020-06-20T01:24:10-07:00|1592641450|2020-06-20|Sat, 20 Jun 2020 01:24:10 -0700",
+		},
+		{
+			desc:     "committer with a date format",
+			format:   "%cn <%ce> %cd",
+			opts:     pretty.Options{Date: pretty.DateRelative},
+			expected: "Committer <committer@domain.tld> 3 hours ago",
+		},
+		{
+			desc:     "message",
+			format:   "[%s]%n[%f]%n[%b]",
+			expected: "[fix: the subject on 2 lines]\n[fix-the-subject-on-2-lines]\n[The body\n\nwith 2 paragraphs\n]",
+		},
+		{
+			desc:     "raw message",
+			format:   "%B",
+			expected: c.Message(),
+		},
+		{
+			desc:     "atoms",
+			format:   "%(objecttype) %(tree) %(authorname) %(committeremail) %(subject)",
+			expected: "commit e5b9e846e1b468bc9597ff95d71dfacda8bd54e3 Author <committer@domain.tld> fix: the subject on 2 lines",
+		},
+		{
+			desc:     "special chars",
+			format:   "100%% %x41%x00",
+			expected: "100% A\x00",
+		},
+		{
+			desc:     "colors should be removed by default",
+			format:   "%Cred%an%Creset %C(bold blue)%cn%C(reset)",
+			expected: "Author Committer",
+		},
+		{
+			desc:     "colors should use the color func",
+			format:   "%Cred%an%Creset %C(bold blue)%cn%C(reset)",
+			opts:     pretty.Options{Color: pretty.ANSIColor},
+			expected: "\x1b[31mAuthor\x1b[m \x1b[1;34mCommitter\x1b[m",
+		},
+		{
+			desc:     "unknown placeholders should be kept",
+			format:   "%an %z %aX %(nope) %C %(unclosed",
+			expected: "Author %z %aX %(nope) %C %(unclosed",
+		},
+		{
+			desc:     "tag placeholders should be kept",
+			format:   "%(tag)",
+			expected: "%(tag)",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			if tc.opts.Now.IsZero() {
+				tc.opts.Now = now
+			}
+			f := pretty.New(tc.format, tc.opts)
+			assert.Equal(t, tc.expected, f.Commit(c))
+		})
+	}
+}
+
+func TestFormatterTag(t *testing.T) {
+	t.Parallel()
+
+	treeID, err := ginternals.NewOidFromStr("e5b9e846e1b468bc9597ff95d71dfacda8bd54e3")
+	require.NoError(t, err)
+	c := object.NewCommit(treeID, object.Signature{
+		Name:  "Author",
+		Email: "author@domain.tld",
+		Time:  time.Unix(1592641450, 0).UTC(),
+	}, &object.CommitOptions{Message: "message"})
+
+	o := object.NewTag(&object.TagParams{
+		Target: c.ToObject(),
+		Name:   "v1.0.0",
+		Tagger: object.Signature{
+			Name:  "Tagger",
+			Email: "tagger@domain.tld",
+			Time:  time.Unix(1592641450, 0).UTC(),
+		},
+		Message: "Release v1.0.0\n\nThe notes\n",
+	}).ToObject()
+	tag, err := o.AsTag()
+	require.NoError(t, err)
+
+	f := pretty.New("%(tag) %(type) %(object) %an %(taggeremail) %cs %s|%b", pretty.Options{})
+	expected := fmt.Sprintf("v1.0.0 commit %s Tagger <tagger@domain.tld> 2020-06-20 Release v1.0.0|The notes\n", c.ID().String())
+	assert.Equal(t, expected, f.Tag(tag))
+
+	f = pretty.New("%H", pretty.Options{})
+	assert.Equal(t, o.ID().String(), f.Tag(tag))
+}
+
+func TestFormatterTreeEntry(t *testing.T) {
+	t.Parallel()
+
+	blobID, err := ginternals.NewOidFromStr("642480605b8b0fd464ab5762e044269cf29a60a3")
+	require.NoError(t, err)
+	e := object.TreeEntry{
+		Path: "README.md",
+		ID:   blobID,
+		Mode: object.ModeFile,
+	}
+
+	// the default format of git ls-tree
+	f := pretty.New("%(objectmode) %(objecttype) %(objectname)%x09%(path)", pretty.Options{})
+	assert.Equal(t, "100644 blob 642480605b8b0fd464ab5762e044269cf29a60a3\tREADME.md", f.TreeEntry(e))
+}
+
+func TestSanitizedSubject(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		message  string
+		expected string
+	}{
+		{message: "Fix: the tests", expected: "Fix-the-tests"},
+		{message: "  --leading and trailing--  \n", expected: "leading-and-trailing"},
+		{message: "v1...2 is out...", expected: "v1.2-is-out"},
+		{message: "snake_case stays", expected: "snake_case-stays"},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.expected), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, pretty.SanitizedSubject(tc.message))
+		})
+	}
+}
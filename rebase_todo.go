@@ -0,0 +1,164 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrTodoRearranged is an error thrown when trying to autosquash a
+// todo list that has already been rearranged
+var ErrTodoRearranged = errors.New("the todo list was already rearranged")
+
+// TodoAction represents the action to apply on a commit during a
+// rebase
+type TodoAction string
+
+// List of the supported actions
+const (
+	// TodoPick applies the commit
+	TodoPick TodoAction = "pick"
+	// TodoSquash melds the commit into the previous one, and
+	// combines their messages
+	TodoSquash TodoAction = "squash"
+	// TodoFixup melds the commit into the previous one, and
+	// discards its message
+	TodoFixup TodoAction = "fixup"
+	// TodoDrop removes the commit
+	TodoDrop TodoAction = "drop"
+)
+
+// TodoItem represents a line of the todo list of a rebase
+type TodoItem struct {
+	Action TodoAction
+	Commit *object.Commit
+}
+
+// String returns the item the way it would appear in the todo list
+// of git rebase --interactive
+func (item TodoItem) String() string {
+	return fmt.Sprintf("%s %s %s", item.Action, item.Commit.ID().String()[:7], item.Commit.Subject())
+}
+
+// Autosquash rearranges a todo list made of "pick" (and "drop")
+// items the same way git rebase --autosquash does: commits created
+// using CommitOptions.Fixup or CommitOptions.Squash are moved right
+// after the commit they target, and their action is changed to
+// fixup or squash. Multiple fixups of the same commit keep their
+// relative order.
+// The target of a fixup is the first commit of the list that precedes
+// it and has the exact subject, or the provided commit ID (or a prefix
+// of it), or a subject starting with the provided text. Fixups
+// that have no target are left untouched.
+// The provided list is not modified
+func Autosquash(todo []TodoItem) ([]TodoItem, error) {
+	items := make([]TodoItem, len(todo))
+	copy(items, todo)
+
+	// next contains, for each item, the index of the next item to
+	// insert after it. tail contains the index of the last fixup
+	// of an item
+	next := make([]int, len(items))
+	tail := make([]int, len(items))
+	subjects := make([]string, len(items))
+	subjectToIndex := map[string]int{}
+	for i, item := range items {
+		next[i], tail[i] = -1, -1
+		if item.Action == TodoFixup || item.Action == TodoSquash {
+			return nil, ErrTodoRearranged
+		}
+		if item.Action == TodoDrop {
+			continue
+		}
+
+		subjects[i] = item.Commit.Subject()
+		target := -1
+		if text, ok := trimFixupPrefixes(subjects[i]); ok {
+			target = autosquashTarget(items[:i], subjects[:i], subjectToIndex, text)
+		}
+		if target == -1 {
+			if _, ok := subjectToIndex[subjects[i]]; !ok {
+				subjectToIndex[subjects[i]] = i
+			}
+			continue
+		}
+
+		items[i].Action = TodoSquash
+		if strings.HasPrefix(subjects[i], "fixup!") {
+			items[i].Action = TodoFixup
+		}
+		// The item is added after the last fixup of its target
+		last := target
+		if tail[target] >= 0 {
+			last = tail[target]
+		}
+		next[i] = next[last]
+		next[last] = i
+		tail[target] = i
+	}
+
+	out := make([]TodoItem, 0, len(items))
+	for i, item := range items {
+		// the fixups and squashes are added after their target
+		if item.Action == TodoFixup || item.Action == TodoSquash {
+			continue
+		}
+		for cur := i; cur >= 0; cur = next[cur] {
+			out = append(out, items[cur])
+		}
+	}
+	return out, nil
+}
+
+// trimFixupPrefixes removes all the "fixup!" and "squash!" prefixes
+// from a subject. Returns false if the subject has no prefix
+func trimFixupPrefixes(subject string) (string, bool) {
+	found := false
+	for {
+		subject = strings.TrimLeft(subject, " \t")
+		switch {
+		case strings.HasPrefix(subject, "fixup!"):
+			subject = subject[len("fixup!"):]
+		case strings.HasPrefix(subject, "squash!"):
+			subject = subject[len("squash!"):]
+		default:
+			return subject, found
+		}
+		found = true
+	}
+}
+
+// autosquashTarget returns the index of the commit targeted by a fixup
+// or a squash, or -1
+func autosquashTarget(items []TodoItem, subjects []string, subjectToIndex map[string]int, text string) int {
+	// by subject
+	if i, ok := subjectToIndex[text]; ok {
+		return i
+	}
+	// by commit ID
+	if text != "" && !strings.Contains(text, " ") && len(text) <= 40 {
+		match := -1
+		for i, item := range items {
+			if item.Commit != nil && strings.HasPrefix(item.Commit.ID().String(), text) {
+				if match != -1 && items[match].Commit.ID() != item.Commit.ID() {
+					// ambiguous
+					match = -1
+					break
+				}
+				match = i
+			}
+		}
+		if match != -1 && len(text) >= 4 {
+			return match
+		}
+	}
+	// by the beginning of the subject
+	for i, subject := range subjects {
+		if items[i].Action != TodoDrop && strings.HasPrefix(subject, text) {
+			return i
+		}
+	}
+	return -1
+}
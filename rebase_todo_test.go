@@ -0,0 +1,137 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutosquash(t *testing.T) {
+	t.Parallel()
+
+	// newCommit returns a commit with the given message
+	newCommit := func(message string) *object.Commit {
+		return object.NewCommit(ginternals.NullOid, object.NewSignature("author", "email"), &object.CommitOptions{
+			Message: message,
+		})
+	}
+	// subjects returns the action and the subjects of the items
+	subjects := func(todo []TodoItem) []string {
+		out := make([]string, 0, len(todo))
+		for _, item := range todo {
+			out = append(out, string(item.Action)+" "+item.Commit.Subject())
+		}
+		return out
+	}
+
+	t.Run("should rearrange the list like git", func(t *testing.T) {
+		t.Parallel()
+
+		base := newCommit("base\n")
+		a := newCommit("feat: add A\n")
+		b := newCommit("feat: add B\nsecond line\n")
+		fixupA := &object.CommitOptions{}
+		fixupA.Fixup(a)
+		squashB := &object.CommitOptions{}
+		squashB.Squash(b, "")
+
+		commits := []*object.Commit{
+			base,
+			a,
+			b,
+			newCommit(fixupA.Message),
+			newCommit(squashB.Message),
+			newCommit(fixupA.Message),
+			newCommit("fixup! " + fixupA.Message),
+			newCommit("fixup! " + a.ID().String()[:8] + "\n"),
+			newCommit("fixup! feat: add\n"),
+			newCommit("fixup! unknown\n"),
+			newCommit("squash! fixup! feat: add B second line\n"),
+		}
+		todo := make([]TodoItem, 0, len(commits))
+		for _, c := range commits {
+			todo = append(todo, TodoItem{Action: TodoPick, Commit: c})
+		}
+
+		out, err := Autosquash(todo)
+		require.NoError(t, err)
+
+		// generated using git rebase -i --autosquash on the same
+		// history
+		expected := []string{
+			"pick base",
+			"pick feat: add A",
+			"fixup fixup! feat: add A",
+			"fixup fixup! feat: add A",
+			"fixup fixup! fixup! feat: add A",
+			"fixup fixup! " + a.ID().String()[:8],
+			"fixup fixup! feat: add",
+			"pick feat: add B second line",
+			"squash squash! feat: add B second line",
+			"squash squash! fixup! feat: add B second line",
+			"pick fixup! unknown",
+		}
+		assert.Equal(t, expected, subjects(out))
+
+		// the provided list should not have been modified
+		for _, item := range todo {
+			assert.Equal(t, TodoPick, item.Action)
+		}
+	})
+
+	t.Run("should not target later commits", func(t *testing.T) {
+		t.Parallel()
+
+		todo := []TodoItem{
+			{Action: TodoPick, Commit: newCommit("fixup! feat: add A\n")},
+			{Action: TodoPick, Commit: newCommit("feat: add A\n")},
+		}
+		out, err := Autosquash(todo)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"pick fixup! feat: add A",
+			"pick feat: add A",
+		}, subjects(out))
+	})
+
+	t.Run("should not target dropped commits", func(t *testing.T) {
+		t.Parallel()
+
+		todo := []TodoItem{
+			{Action: TodoDrop, Commit: newCommit("feat: add A\n")},
+			{Action: TodoPick, Commit: newCommit("fixup! feat: add A\n")},
+		}
+		out, err := Autosquash(todo)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"drop feat: add A",
+			"pick fixup! feat: add A",
+		}, subjects(out))
+	})
+
+	t.Run("should fail on a list already rearranged", func(t *testing.T) {
+		t.Parallel()
+
+		todo := []TodoItem{
+			{Action: TodoPick, Commit: newCommit("feat: add A\n")},
+			{Action: TodoFixup, Commit: newCommit("fixup! feat: add A\n")},
+		}
+		_, err := Autosquash(todo)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrTodoRearranged), "unexpected error: %v", err)
+	})
+}
+
+func TestTodoItemString(t *testing.T) {
+	t.Parallel()
+
+	c := object.NewCommit(ginternals.NullOid, object.NewSignature("author", "email"), &object.CommitOptions{
+		Message: "feat: add A\n\nbody\n",
+	})
+	item := TodoItem{Action: TodoFixup, Commit: c}
+	assert.Equal(t, "fixup "+c.ID().String()[:7]+" feat: add A", item.String())
+}
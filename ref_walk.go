@@ -0,0 +1,98 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+)
+
+// RefEntry represents a reference found while walking the branches
+// or the tags of a repository
+type RefEntry struct {
+	repo *Repository
+
+	// Reference contains the reference. Symbolic references are
+	// resolved
+	Reference *ginternals.Reference
+	// ShortName contains the name of the reference without the
+	// refs/ layout (ex. "main" for refs/heads/main, "v1.0" for
+	// refs/tags/v1.0, or "origin/main" for refs/remotes/origin/main)
+	ShortName string
+
+	peeled   ginternals.Oid
+	peelErr  error
+	isPeeled bool
+}
+
+// Peel returns the object targeted by the reference once all the
+// annotated tags have been followed.
+// Nothing is loaded until Peel is called, and the result is cached
+func (e *RefEntry) Peel() (ginternals.Oid, error) {
+	if !e.isPeeled {
+		e.peeled, e.peelErr = e.repo.Peel(e.Reference)
+		e.isPeeled = true
+	}
+	return e.peeled, e.peelErr
+}
+
+// RefEntryWalkFunc represents a function that will be applied on all
+// the branches or the tags found by a walk.
+// backend.WalkStop can be returned to stop the walk
+type RefEntryWalkFunc = func(e *RefEntry) error
+
+// WalkBranches runs the provided method on all the local branches of
+// the repository (refs/heads/), sorted by name
+func (r *Repository) WalkBranches(f RefEntryWalkFunc) error {
+	return r.walkRefEntries("refs/heads", ginternals.LocalBranchShortName, f)
+}
+
+// WalkRemoteBranches runs the provided method on all the branches of
+// the given remote (refs/remotes/<remote>/), sorted by name.
+// The branches of all the remotes are walked if remote is empty.
+// The HEAD of the remotes (ex. refs/remotes/origin/HEAD) are not
+// branches, and are skipped (see WalkRemoteHeads)
+func (r *Repository) WalkRemoteBranches(remote string, f RefEntryWalkFunc) error {
+	return r.walkRefEntries(ginternals.RemoteBranchFullName(remote, ""), ginternals.RemoteBranchShortName, func(e *RefEntry) error {
+		if strings.HasSuffix(e.Reference.Name(), "/"+ginternals.Head) {
+			return nil
+		}
+		return f(e)
+	})
+}
+
+// WalkRemoteHeads runs the provided method on the HEAD of the given
+// remote (refs/remotes/<remote>/HEAD).
+// The HEAD of all the remotes are walked, sorted by name, if remote
+// is empty. The HEADs are usually symbolic references, the branch
+// they target is available through Reference.SymbolicTarget()
+func (r *Repository) WalkRemoteHeads(remote string, f RefEntryWalkFunc) error {
+	return r.walkRefEntries(ginternals.RemoteBranchFullName(remote, ""), ginternals.RemoteBranchShortName, func(e *RefEntry) error {
+		if !strings.HasSuffix(e.Reference.Name(), "/"+ginternals.Head) {
+			return nil
+		}
+		return f(e)
+	})
+}
+
+// WalkTags runs the provided method on all the tags of the
+// repository (refs/tags/), sorted by name
+func (r *Repository) WalkTags(f RefEntryWalkFunc) error {
+	return r.walkRefEntries("refs/tags", ginternals.LocalTagShortName, f)
+}
+
+// walkRefEntries runs the provided method on all the references
+// matching the given prefix, sorted by name
+func (r *Repository) walkRefEntries(prefix string, shortName func(string) string, f RefEntryWalkFunc) error {
+	opts := backend.WalkReferencesOptions{
+		Pattern: prefix,
+		Sort:    backend.RefSortName,
+	}
+	return r.dotGit.WalkReferencesWithOptions(opts, func(ref *ginternals.Reference) error {
+		return f(&RefEntry{
+			repo:      r,
+			Reference: ref,
+			ShortName: shortName(ref.Name()),
+		})
+	})
+}
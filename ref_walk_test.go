@@ -0,0 +1,136 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/internal/testutil/repogen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRefWalkTestRepo returns a repository containing 2 branches
+// in addition to master, 2 annotated tags, and the branches of 2
+// remotes
+func newRefWalkTestRepo(t *testing.T, packedRefs bool) (*Repository, *repogen.Result) {
+	t.Helper()
+
+	res, cleanup := repogen.New(t, repogen.Options{
+		Commits:    4,
+		Width:      2,
+		Branches:   2,
+		Tags:       2,
+		PackedRefs: packedRefs,
+	})
+	t.Cleanup(cleanup)
+	r, err := OpenRepository(res.Path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	for _, name := range []string{"refs/remotes/origin/main", "refs/remotes/origin/feat/nested", "refs/remotes/origin2/main"} {
+		require.NoError(t, r.dotGit.WriteReference(ginternals.NewReference(name, res.Head)))
+	}
+	require.NoError(t, r.dotGit.WriteReference(ginternals.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/main")))
+	return r, res
+}
+
+// collectRefEntries returns the short names of the entries walked by
+// the given method, alongside their peeled target
+func collectRefEntries(t *testing.T, walk func(f RefEntryWalkFunc) error) (names []string, peeled []ginternals.Oid) {
+	t.Helper()
+
+	err := walk(func(e *RefEntry) error {
+		oid, err := e.Peel()
+		if err != nil {
+			return err
+		}
+		names = append(names, e.ShortName)
+		peeled = append(peeled, oid)
+		return nil
+	})
+	require.NoError(t, err)
+	return names, peeled
+}
+
+func TestWalkBranches(t *testing.T) {
+	t.Parallel()
+
+	r, res := newRefWalkTestRepo(t, false)
+	names, peeled := collectRefEntries(t, r.WalkBranches)
+	assert.Equal(t, []string{"branch-0000", "branch-0001", "master"}, names)
+	assert.Equal(t, []ginternals.Oid{res.Commits[0], res.Commits[2], res.Head}, peeled)
+}
+
+func TestWalkRemoteBranches(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should only walk the branches of the remote", func(t *testing.T) {
+		t.Parallel()
+
+		r, _ := newRefWalkTestRepo(t, false)
+		names, _ := collectRefEntries(t, func(f RefEntryWalkFunc) error {
+			return r.WalkRemoteBranches("origin", f)
+		})
+		assert.Equal(t, []string{"origin/feat/nested", "origin/main"}, names)
+	})
+
+	t.Run("should walk the branches of all the remotes", func(t *testing.T) {
+		t.Parallel()
+
+		r, _ := newRefWalkTestRepo(t, false)
+		names, _ := collectRefEntries(t, func(f RefEntryWalkFunc) error {
+			return r.WalkRemoteBranches("", f)
+		})
+		assert.Equal(t, []string{"origin/feat/nested", "origin/main", "origin2/main"}, names)
+	})
+
+	t.Run("should walk nothing for an unknown remote", func(t *testing.T) {
+		t.Parallel()
+
+		r, _ := newRefWalkTestRepo(t, false)
+		names, _ := collectRefEntries(t, func(f RefEntryWalkFunc) error {
+			return r.WalkRemoteBranches("upstream", f)
+		})
+		assert.Empty(t, names)
+	})
+}
+
+func TestWalkTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc       string
+		packedRefs bool
+	}{
+		{desc: "loose references"},
+		{desc: "packed references", packedRefs: true},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			r, res := newRefWalkTestRepo(t, tc.packedRefs)
+			names, peeled := collectRefEntries(t, r.WalkTags)
+			assert.Equal(t, []string{"v0.0.0", "v0.0.1"}, names, "test %d", i)
+			assert.Equal(t, []ginternals.Oid{res.Commits[0], res.Commits[2]}, peeled, "test %d", i)
+		})
+	}
+
+	t.Run("should stop with WalkStop", func(t *testing.T) {
+		t.Parallel()
+
+		r, _ := newRefWalkTestRepo(t, false)
+		count := 0
+		err := r.WalkTags(func(e *RefEntry) error {
+			count++
+			return backend.WalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// Repack packs the objects of the repository into a new packfile,
+// the same way git repack does. See backend.Backend.Repack for the
+// details of the different modes.
+// The objects reachable from the references are written first, in
+// the order of the history, and their path is used to find the
+// objects that are likely to be similar. The hints of opts are
+// ignored.
+func (r *Repository) Repack(opts backend.RepackOptions) (*backend.RepackResult, error) {
+	from := []ginternals.Oid{}
+	seen := map[ginternals.Oid]struct{}{}
+	err := r.dotGit.WalkReferences(func(ref *ginternals.Reference) error {
+		if _, ok := seen[ref.Target()]; ok {
+			return nil
+		}
+		seen[ref.Target()] = struct{}{}
+		// The references that don't lead to a commit are skipped.
+		// Their objects will still be packed, just without hints
+		if _, err := r.peelToCommit(ref.Target()); err != nil {
+			if errors.Is(err, object.ErrObjectInvalid) {
+				return nil
+			}
+			return fmt.Errorf("could not resolve %s: %w", ref.Name(), err)
+		}
+		from = append(from, ref.Target())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the references: %w", err)
+	}
+
+	opts.Hints = nil
+	err = r.WalkObjects(from, WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+		opts.Hints = append(opts.Hints, backend.ObjectHint{
+			ID:   oid,
+			Path: path,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the objects: %w", err)
+	}
+	return r.dotGit.Repack(opts)
+}
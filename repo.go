@@ -8,8 +8,11 @@ import (
 
 	"github.com/Nivl/git-go/backend"
 	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/commitgraph"
 	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/debuglog"
 	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/pathutil"
 	"github.com/spf13/afero"
 )
 
@@ -20,7 +23,12 @@ var (
 	ErrTagNotFound                  = errors.New("tag not found")
 	ErrTagExists                    = errors.New("tag already exists")
 	ErrNotADirectory                = errors.New("not a directory")
+	ErrNotAFile                     = errors.New("not a file")
+	ErrInvalidPath                  = errors.New("invalid path")
 	ErrInvalidBranchName            = errors.New("invalid branch name")
+	ErrUnknownRevision              = errors.New("unknown revision")
+	ErrPathNotFound                 = errors.New("path not found")
+	ErrBareRepository               = errors.New("operation requires a working tree, but the repository is bare")
 )
 
 // Repository represent a git repository
@@ -56,6 +64,16 @@ type InitOptions struct {
 	// Symlink will create a .git text file in the working tree that points
 	// toward the actual repository
 	Symlink bool
+	// TemplateDir contains the path of a directory whose content is
+	// copied into the new repository (hooks, info/exclude,
+	// description, etc.).
+	// Defaults to $GIT_TEMPLATE_DIR, then to init.templateDir. No
+	// templates are used if none of them are set
+	TemplateDir string
+	// Shared sets how the repository is shared between several
+	// users (see config.ParseSharedRepository()).
+	// Defaults to a non-shared repository
+	Shared config.SharedRepository
 }
 
 // InitRepository initialize a new git repository by creating the .git
@@ -107,6 +125,7 @@ func InitRepositoryWithOptions(rootPath string, opts InitOptions) (r *Repository
 func InitRepositoryWithParams(cfg *config.Config, opts InitOptions) (r *Repository, err error) {
 	r = &Repository{
 		Config: cfg,
+		dotGit: opts.GitBackend,
 	}
 
 	// Validate the branch name
@@ -117,7 +136,9 @@ func InitRepositoryWithParams(cfg *config.Config, opts InitOptions) (r *Reposito
 			branchName = ginternals.Master
 		}
 	}
-	if !ginternals.IsRefNameValid(branchName) {
+	// We don't use LocalBranchFullName() because it cleans the path,
+	// which would make names like "../master" valid
+	if _, err = ginternals.CheckRefFormat("refs/heads/"+branchName, ginternals.RefFormatOptions{}); err != nil {
 		return nil, ErrInvalidBranchName
 	}
 
@@ -142,7 +163,7 @@ func InitRepositoryWithParams(cfg *config.Config, opts InitOptions) (r *Reposito
 
 		r.workTree = opts.WorkingTreeBackend
 		if r.workTree == nil {
-			r.workTree = afero.NewOsFs()
+			r.workTree = pathutil.NewOsFs()
 		}
 	}
 
@@ -161,8 +182,17 @@ func InitRepositoryWithParams(cfg *config.Config, opts InitOptions) (r *Reposito
 		}(r)
 	}
 
+	templateDir := opts.TemplateDir
+	if templateDir == "" {
+		templateDir = cfg.Env().Get("GIT_TEMPLATE_DIR")
+	}
+	if templateDir == "" {
+		templateDir, _ = cfg.FromFile().InitTemplateDir()
+	}
 	err = r.dotGit.InitWithOptions(branchName, backend.InitOptions{
 		CreateSymlink: opts.Symlink,
+		TemplateDir:   templateDir,
+		Shared:        opts.Shared,
 	})
 	if err != nil {
 		return nil, err
@@ -185,15 +215,33 @@ type OpenOptions struct {
 	WorkingTreeBackend afero.Fs
 	// GitDirPath represents the path to the .git directory
 	// Defaults to .git
-	// IsBare represents whether a bare repository will be created or not
+	// IsBare represents whether the repository is bare or not.
+	// When not set, OpenRepositoryWithOptions will still open the
+	// repository as bare if the path points to a git directory that
+	// has core.bare set to true
 	IsBare bool
+	// MmapPackfiles maps the packfiles in memory instead of reading
+	// them with syscalls. This speeds up the operations that read
+	// a lot of objects, like walking the history.
+	// Ignored if GitBackend is set
+	MmapPackfiles bool
+	// Limits contains the limits enforced when reading and parsing
+	// the objects of the repository, which should be set when the
+	// repository may contain data coming from an untrusted source (see
+	// object.UntrustedLimits()).
+	// Ignored if GitBackend is set, the limits of the backend are
+	// used instead
+	Limits object.Limits
+	// Logger receives the debug events emitted by the repository.
+	// Overrides the Logger of the config when set
+	Logger debuglog.Logger
 }
 
 // OpenRepository loads an existing git repository by reading its
 // config file, and returns a Repository instance
 //
 // This assumes:
-// - The repo is not bare (see WithOptions)
+// - The repo is not bare, unless core.bare is set (see WithOptions)
 // - We're not interested in env vars (see WithParams)
 // - The git dir is in the working tree under .git
 func OpenRepository(workTreePath string) (*Repository, error) {
@@ -205,8 +253,19 @@ func OpenRepository(workTreePath string) (*Repository, error) {
 //
 // This assumes:
 // - We're not interested in env vars (see WithParams)
-// - The git dir is in the working tree under .git
+// - The git dir is in the working tree under .git (unless core.bare is set)
 func OpenRepositoryWithOptions(rootPath string, opts OpenOptions) (r *Repository, err error) {
+	if !opts.IsBare {
+		cfg, err := loadBareConfig(rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not check if the repository is bare: %w", err)
+		}
+		if cfg != nil {
+			opts.IsBare = true
+			return OpenRepositoryWithParams(cfg, opts)
+		}
+	}
+
 	WorkTreePath := rootPath
 	GitDirPath := filepath.Join(rootPath, config.DefaultDotGitDirName)
 	if opts.IsBare {
@@ -230,19 +289,26 @@ func OpenRepositoryWithOptions(rootPath string, opts OpenOptions) (r *Repository
 //
 // This method makes no assumptions
 func OpenRepositoryWithParams(cfg *config.Config, opts OpenOptions) (r *Repository, err error) {
+	if opts.Logger != nil {
+		cfg.Logger = opts.Logger
+	}
 	r = &Repository{
 		Config: cfg,
+		dotGit: opts.GitBackend,
 	}
 
 	if !opts.IsBare {
 		r.workTree = opts.WorkingTreeBackend
 		if r.workTree == nil {
-			r.workTree = afero.NewOsFs()
+			r.workTree = pathutil.NewOsFs()
 		}
 	}
 
 	if opts.GitBackend == nil {
-		r.dotGit, err = backend.NewFS(cfg)
+		r.dotGit, err = backend.NewWithOptions(cfg, pathutil.NewOsFs(), backend.Options{
+			MmapPacks: opts.MmapPackfiles,
+			Limits:    opts.Limits,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("could not create backend: %w", err)
 		}
@@ -259,7 +325,7 @@ func OpenRepositoryWithParams(cfg *config.Config, opts OpenOptions) (r *Reposito
 	// since we can't check if the directory exists on disk to
 	// validate if the repo exists, we're instead going to see if HEAD
 	// exists (since it should always be there)
-	_, err = r.dotGit.Reference(ginternals.Head)
+	_, err = r.dotGit.UnresolvedReference(ginternals.Head)
 	if err != nil {
 		return nil, ErrRepositoryNotExist
 	}
@@ -267,23 +333,90 @@ func OpenRepositoryWithParams(cfg *config.Config, opts OpenOptions) (r *Reposito
 	return r, nil
 }
 
+// loadBareConfig returns the config of the repository stored in
+// rootPath if it's a bare repository. A repository is considered bare
+// if rootPath has no .git, looks like a git directory, and has
+// core.bare set to true.
+// A nil config is returned if the repository is not bare
+func loadBareConfig(rootPath string) (*config.Config, error) {
+	if _, err := os.Stat(filepath.Join(rootPath, config.DefaultDotGitDirName)); !errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if !isGitDirectory(rootPath) {
+		return nil, nil
+	}
+
+	cfg, err := config.LoadConfigSkipEnv(config.LoadConfigOptions{
+		GitDirPath: rootPath,
+		IsBare:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load the config of %s: %w", rootPath, err)
+	}
+	if _, err = os.Stat(ginternals.ConfigPath(cfg)); err != nil {
+		return nil, nil //nolint:nilerr // no config means not bare
+	}
+	if isBare, ok := cfg.FromFile().IsBare(); !ok || !isBare {
+		return nil, nil
+	}
+	return cfg, nil
+}
+
 // IsBare returns whether the repo is bare or not.
 // A bare repo doesn't have a workign tree
 func (r *Repository) IsBare() bool {
 	return r.workTree == nil
 }
 
+// WorkTreePath returns the path of the working tree of the repository.
+// ErrBareRepository is returned if the repository is bare
+func (r *Repository) WorkTreePath() (string, error) {
+	if r.IsBare() {
+		return "", ErrBareRepository
+	}
+	return r.Config.WorkTreePath, nil
+}
+
 // Object returns the object matching the given ID
 func (r *Repository) Object(oid ginternals.Oid) (*object.Object, error) {
 	return r.dotGit.Object(oid)
 }
 
+// ObjectInfo returns the type and the size of the object that has
+// the given oid, without loading the whole object
+func (r *Repository) ObjectInfo(oid ginternals.Oid) (object.Type, int, error) {
+	return r.dotGit.ObjectInfo(oid)
+}
+
+// WriteObject stores the given object in the object database.
+// Nothing is written if the object already exists
+func (r *Repository) WriteObject(o *object.Object) (ginternals.Oid, error) {
+	return r.dotGit.WriteObject(o)
+}
+
 // NewCommit creates, stores, and returns a new Commit object
 // The head of the reference $refname will be updated to this
 // new commit.
 // An empty refName will create a detached (loose) commit
 // If the reference doesn't exists, it will be created
+// If author is a zero value, the author and the committer will be
+// generated using DefaultSignature()
 func (r *Repository) NewCommit(refname string, tree *object.Tree, author object.Signature, opts *object.CommitOptions) (*object.Commit, error) {
+	if author.IsZero() {
+		var committer object.Signature
+		var err error
+		author, committer, err = r.DefaultSignature()
+		if err != nil {
+			return nil, fmt.Errorf("could not generate the signatures: %w", err)
+		}
+		if opts.Committer.IsZero() {
+			// we don't want to update the options of the caller
+			o := *opts
+			o.Committer = committer
+			opts = &o
+		}
+	}
+
 	// We first validate the parents actually exists
 	for _, id := range opts.ParentsID {
 		parent, err := r.dotGit.Object(id)
@@ -324,7 +457,7 @@ func (r *Repository) Commit(oid ginternals.Oid) (*object.Commit, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not get object: %w", err)
 	}
-	return o.AsCommit()
+	return object.NewCommitFromObjectWithLimits(o, r.dotGit.Limits())
 }
 
 // Tree returns the tree matching the given SHA
@@ -333,11 +466,24 @@ func (r *Repository) Tree(oid ginternals.Oid) (*object.Tree, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not get object: %w", err)
 	}
-	return o.AsTree()
+	return object.NewTreeFromObjectWithLimits(o, r.dotGit.Limits())
 }
 
 // NewTag creates, stores, and returns a new annoted tag
+// If p.Tagger is a zero value, the committer returned by
+// DefaultSignature() will be used
 func (r *Repository) NewTag(p *object.TagParams) (*object.Tag, error) {
+	if p.Tagger.IsZero() {
+		_, committer, err := r.DefaultSignature()
+		if err != nil {
+			return nil, fmt.Errorf("could not generate the tagger: %w", err)
+		}
+		// we don't want to update the params of the caller
+		params := *p
+		params.Tagger = committer
+		p = &params
+	}
+
 	found, err := r.dotGit.HasObject(p.Target.ID())
 	if err != nil {
 		return nil, fmt.Errorf("could not check if target exists: %w", err)
@@ -456,9 +602,97 @@ func (r *Repository) NewSymbolicReferenceSafe(name, target string) (*ginternals.
 	return ref, nil
 }
 
-// Reference returns the reference matching the given name
+// Reference returns the reference matching the given name.
+// Like git, short names are accepted, and are looked for (in that
+// order) in refs/, refs/tags/, refs/heads/, refs/remotes/, and as a
+// remote's HEAD (origin -> refs/remotes/origin/HEAD).
+// ginternals.ErrRefNotFound is returned if no reference matches
 func (r *Repository) Reference(name string) (*ginternals.Reference, error) {
-	return r.dotGit.Reference(name)
+	for _, candidate := range ginternals.RefNameCandidates(name) {
+		ref, err := r.dotGit.Reference(candidate)
+		if err == nil {
+			return ref, nil
+		}
+		// An invalid name can happen when a candidate is built from
+		// a name that isn't a ref (a SHA for example), in which
+		// case we just move on to the next candidate
+		if !errors.Is(err, ginternals.ErrRefNotFound) && !errors.Is(err, ginternals.ErrRefNameInvalid) {
+			return nil, fmt.Errorf("could not check if ref %s exists: %w", candidate, err)
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, ginternals.ErrRefNotFound)
+}
+
+// ShortenRefName returns the shortest unambiguous name that can be
+// used to find the given reference, like git rev-parse --abbrev-ref.
+// A short name is ambiguous if Reference() would return another
+// reference when given this name.
+// ex. refs/heads/main returns main, unless refs/tags/main exists, in
+// which case heads/main is returned.
+// The name is returned as-is if it cannot be shortened
+func (r *Repository) ShortenRefName(fullName string) (string, error) {
+	for _, short := range ginternals.RefShortNames(fullName) {
+		for _, candidate := range ginternals.RefNameCandidates(short) {
+			if candidate == fullName {
+				return short, nil
+			}
+			_, err := r.dotGit.Reference(candidate)
+			if err == nil {
+				// the name refers to another reference first
+				break
+			}
+			if !errors.Is(err, ginternals.ErrRefNotFound) && !errors.Is(err, ginternals.ErrRefNameInvalid) {
+				return "", fmt.Errorf("could not check if ref %s exists: %w", candidate, err)
+			}
+		}
+	}
+	return fullName, nil
+}
+
+// WalkReferences runs the provided method on all the references of
+// the repository. The symbolic references are resolved.
+// backend.WalkStop can be returned to stop the walk
+func (r *Repository) WalkReferences(f backend.RefWalkFunc) error {
+	return r.dotGit.WalkReferences(f)
+}
+
+// WalkReferencesWithOptions works like WalkReferences, but uses the
+// provided options to filter and sort the references
+func (r *Repository) WalkReferencesWithOptions(opts backend.WalkReferencesOptions, f backend.RefWalkFunc) error {
+	return r.dotGit.WalkReferencesWithOptions(opts, f)
+}
+
+// ReferencesGlob returns, sorted by name, all the references matching
+// the given pattern (ex. refs/tags/v1.*)
+func (r *Repository) ReferencesGlob(pattern string) ([]*ginternals.Reference, error) {
+	return r.dotGit.ReferencesGlob(pattern)
+}
+
+// ReferencesWithPrefix returns, sorted by name, all the references
+// that start with any of the provided prefixes, alongside the object
+// they target once peeled. All the references are returned if no
+// prefixes are provided
+func (r *Repository) ReferencesWithPrefix(prefixes []string) ([]*backend.ReferenceInfo, error) {
+	return r.dotGit.ReferencesWithPrefix(prefixes)
+}
+
+// Stats returns statistics about the objects and the references of
+// the repository, like git count-objects -v
+func (r *Repository) Stats() (*backend.Stats, error) {
+	return r.dotGit.Stats()
+}
+
+// PackReferences packs all the references of refs/ into the
+// packed-refs file, the same way git pack-refs --all does
+func (r *Repository) PackReferences() error {
+	return r.dotGit.PackReferences()
+}
+
+// WriteCommitGraph adds all the commits reachable from the references
+// of the repository to its commit-graph, the same way
+// git commit-graph write --reachable does
+func (r *Repository) WriteCommitGraph(opts commitgraph.WriteOptions) (*commitgraph.Graph, error) {
+	return r.dotGit.WriteCommitGraph(opts)
 }
 
 // NewBlob creates, stores, and returns a new Blob object
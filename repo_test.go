@@ -232,6 +232,63 @@ func TestInit(t *testing.T) {
 		assert.NoFileExists(t, filepath.Join(d, config.DefaultDotGitDirName))
 		assert.FileExists(t, filepath.Join(d, ginternals.Head))
 	})
+
+	t.Run("should use the provided template", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		templateDir := filepath.Join(d, "template")
+		require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "info"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "info", "exclude"), []byte("*.log\n"), 0o644))
+
+		r, err := InitRepositoryWithOptions(filepath.Join(d, "repo"), InitOptions{
+			TemplateDir: templateDir,
+			Shared:      config.SharedEveryone(),
+		})
+		require.NoError(t, err, "failed creating a repo")
+		t.Cleanup(func() {
+			require.NoError(t, r.Close(), "failed closing repo")
+		})
+
+		data, err := os.ReadFile(filepath.Join(ginternals.DotGitPath(r.Config), "info", "exclude"))
+		require.NoError(t, err)
+		assert.Equal(t, "*.log\n", string(data))
+
+		shared, ok, err := r.Config.FromFile().SharedRepository()
+		require.NoError(t, err)
+		assert.True(t, ok, "core.sharedRepository should be set")
+		assert.Equal(t, config.SharedEveryone(), shared)
+	})
+
+	t.Run("should use GIT_TEMPLATE_DIR", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		templateDir := filepath.Join(d, "template")
+		require.NoError(t, os.MkdirAll(templateDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "description"), []byte("from env\n"), 0o644))
+
+		e := env.NewFromKVList([]string{
+			"GIT_TEMPLATE_DIR=" + templateDir,
+		})
+		p, err := config.LoadConfig(e, config.LoadConfigOptions{
+			WorkingDirectory: filepath.Join(d, "repo"),
+			SkipGitDirLookUp: true,
+		})
+		require.NoError(t, err)
+
+		r, err := InitRepositoryWithParams(p, InitOptions{})
+		require.NoError(t, err, "failed creating a repo")
+		t.Cleanup(func() {
+			require.NoError(t, r.Close(), "failed closing repo")
+		})
+
+		data, err := os.ReadFile(filepath.Join(ginternals.DotGitPath(r.Config), "description"))
+		require.NoError(t, err)
+		assert.Equal(t, "from env\n", string(data))
+	})
 }
 
 func TestOpen(t *testing.T) {
@@ -257,6 +314,25 @@ func TestOpen(t *testing.T) {
 		assert.False(t, r.IsBare(), "repos should not be bare")
 	})
 
+	t.Run("should use the logger of the options", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		logger := &testutil.Logger{}
+		r, err := OpenRepositoryWithOptions(repoPath, OpenOptions{
+			Logger: logger,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		assert.Equal(t, logger, r.Config.Logger)
+		assert.Len(t, logger.Events("repository loaded"), 1)
+	})
+
 	t.Run("bare repo", func(t *testing.T) {
 		t.Parallel()
 
@@ -280,6 +356,42 @@ func TestOpen(t *testing.T) {
 		assert.True(t, r.IsBare(), "repos should be bare")
 	})
 
+	t.Run("bare repo should be detected", func(t *testing.T) {
+		t.Parallel()
+
+		d, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepositoryWithOptions(d, InitOptions{
+			IsBare: true,
+		})
+		require.NoError(t, err, "failed creating a repo")
+		require.NoError(t, r.Close())
+
+		r, err = OpenRepository(d)
+		require.NoError(t, err, "failed loading a repo")
+		t.Cleanup(func() {
+			require.NoError(t, r.Close(), "failed closing repo")
+		})
+
+		require.Empty(t, r.Config.WorkTreePath)
+		require.Equal(t, d, r.dotGit.Path())
+		assert.True(t, r.IsBare(), "repos should be bare")
+
+		_, err = r.WorkTreePath()
+		assert.ErrorIs(t, err, ErrBareRepository)
+	})
+
+	t.Run("git dir of a non-bare repo should not be opened as bare", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		_, err := OpenRepository(filepath.Join(repoPath, ".git"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRepositoryNotExist)
+	})
+
 	t.Run("repo with a custom .git", func(t *testing.T) {
 		t.Parallel()
 
@@ -377,6 +489,29 @@ func TestRepositoryObject(t *testing.T) {
 		assert.Equal(t, oid, obj.ID())
 		assert.Equal(t, object.TypeCommit, obj.Type())
 	})
+
+	t.Run("Object from a packfile mapped in memory", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		r, err := OpenRepositoryWithOptions(repoPath, OpenOptions{MmapPackfiles: true})
+		require.NoError(t, err, "failed loading a repo")
+		t.Cleanup(func() {
+			require.NoError(t, r.Close(), "failed closing repo")
+		})
+
+		oid, err := ginternals.NewOidFromStr("1dcdadc2a420225783794fbffd51e2e137a69646")
+		require.NoError(t, err)
+
+		obj, err := r.Object(oid)
+		require.NoError(t, err)
+		require.NotNil(t, obj)
+
+		assert.Equal(t, oid, obj.ID())
+		assert.Equal(t, object.TypeCommit, obj.Type())
+	})
 }
 
 func TestRepositoryBlob(t *testing.T) {
@@ -499,6 +634,26 @@ func TestRepositoryReference(t *testing.T) {
 			refName:        "refs/heads/ml/packfile/tests",
 			expectedTarget: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
 		},
+		{
+			desc:           "a short branch name should work",
+			refName:        "ml/cleanup-062020",
+			expectedTarget: "b328320060eb503cf337c7cff281712ef236963a",
+		},
+		{
+			desc:           "a short tag name should work",
+			refName:        "annotated",
+			expectedTarget: "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442",
+		},
+		{
+			desc:           "a remote branch should work",
+			refName:        "origin/master",
+			expectedTarget: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+		},
+		{
+			desc:           "heads/<branch> should work",
+			refName:        "heads/ml/tests",
+			expectedTarget: "f0f70144f38695250606b86a50cff2b440a417f3",
+		},
 		{
 			desc:          "an invalid name should fail",
 			refName:       "nope",
@@ -523,6 +678,62 @@ func TestRepositoryReference(t *testing.T) {
 	}
 }
 
+func TestRepositoryShortenRefName(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	// tags have the priority over branches
+	target, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+	require.NoError(t, err)
+	err = r.dotGit.WriteReference(ginternals.NewReference("refs/tags/ml/cleanup-062020", target))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc     string
+		name     string
+		expected string
+	}{
+		{
+			desc:     "a branch should be shortened",
+			name:     "refs/heads/ml/tests",
+			expected: "ml/tests",
+		},
+		{
+			desc:     "a remote branch should be shortened",
+			name:     "refs/remotes/origin/ml/feat/clone",
+			expected: "origin/ml/feat/clone",
+		},
+		{
+			desc:     "an ambiguous name should not be used",
+			name:     "refs/heads/ml/cleanup-062020",
+			expected: "heads/ml/cleanup-062020",
+		},
+		{
+			desc:     "a name that cannot be shortened should be kept",
+			name:     "HEAD",
+			expected: "HEAD",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			short, err := r.ShortenRefName(tc.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, short)
+		})
+	}
+}
+
 func TestRepositoryTree(t *testing.T) {
 	t.Parallel()
 
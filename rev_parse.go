@@ -0,0 +1,80 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// RevParse returns the ID of the object targeted by the given
+// revision.
+//
+// A revision can be:
+// - A full SHA: 9b91da06e69613397b38e0808e0ba5ee6983251b
+// - A full reference name: HEAD, ORIG_HEAD, refs/heads/master
+// - A short reference name: heads/master, master, origin/master, origin
+// - Any of the above followed by ^{<type>}, which peels the object to
+// the given type (commit, tree, blob, tag, or object), or by ^{},
+// which follows the annotated tags: v1.0.0^{commit}, master^{tree}
+//
+// Short reference names are looked for (in that order) in refs/,
+// refs/tags/, refs/heads/, refs/remotes/, and as a remote's HEAD
+// (origin -> refs/remotes/origin/HEAD).
+//
+// ErrUnknownRevision is returned if the revision cannot be resolved
+// https://git-scm.com/docs/git-rev-parse#_specifying_revisions
+func (r *Repository) RevParse(revision string) (ginternals.Oid, error) {
+	if i := strings.LastIndex(revision, "^{"); i != -1 && strings.HasSuffix(revision, "}") {
+		return r.revParsePeel(revision[:i], revision[i+2:len(revision)-1])
+	}
+	if oid, err := ginternals.NewOidFromStr(revision); err == nil {
+		return oid, nil
+	}
+
+	ref, err := r.Reference(revision)
+	if err != nil {
+		if errors.Is(err, ginternals.ErrRefNotFound) {
+			return ginternals.NullOid, fmt.Errorf("%s: %w", revision, ErrUnknownRevision)
+		}
+		return ginternals.NullOid, err
+	}
+	return ref.Target(), nil
+}
+
+// revParsePeel resolves a <rev>^{<type>} revision
+func (r *Repository) revParsePeel(revision, typ string) (ginternals.Oid, error) {
+	oid, err := r.RevParse(revision)
+	if err != nil {
+		return ginternals.NullOid, err
+	}
+
+	switch typ {
+	case "":
+		return r.dotGit.Peel(oid)
+	case "object":
+		found, err := r.dotGit.HasObject(oid)
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not check if %s exists: %w", oid.String(), err)
+		}
+		if !found {
+			return ginternals.NullOid, fmt.Errorf("%s^{%s}: %w", revision, typ, ErrUnknownRevision)
+		}
+		return oid, nil
+	}
+
+	t, err := object.NewTypeFromString(typ)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("%s^{%s}: %w", revision, typ, ErrUnknownRevision)
+	}
+	o, err := r.dotGit.Object(oid)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+	}
+	if o, err = r.PeelObject(o, t); err != nil {
+		return ginternals.NullOid, err
+	}
+	return o.ID(), nil
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevParse(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	testCases := []struct {
+		desc          string
+		revision      string
+		expectedOid   string
+		expectedError error
+	}{
+		{
+			desc:        "full sha",
+			revision:    "b328320060eb503cf337c7cff281712ef236963a",
+			expectedOid: "b328320060eb503cf337c7cff281712ef236963a",
+		},
+		{
+			desc:        "HEAD",
+			revision:    "HEAD",
+			expectedOid: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+		},
+		{
+			desc:        "pseudo-ref",
+			revision:    "ORIG_HEAD",
+			expectedOid: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+		},
+		{
+			desc:        "full ref name",
+			revision:    "refs/heads/ml/cleanup-062020",
+			expectedOid: "b328320060eb503cf337c7cff281712ef236963a",
+		},
+		{
+			desc:        "ref relative to refs/",
+			revision:    "heads/ml/cleanup-062020",
+			expectedOid: "b328320060eb503cf337c7cff281712ef236963a",
+		},
+		{
+			desc:        "branch name",
+			revision:    "ml/cleanup-062020",
+			expectedOid: "b328320060eb503cf337c7cff281712ef236963a",
+		},
+		{
+			desc:        "tag name",
+			revision:    "lightweight",
+			expectedOid: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+		},
+		{
+			desc:        "remote branch",
+			revision:    "origin/ml/feat/clone",
+			expectedOid: "5f35f2dc6cec7356da02ca26192ce2bc3f271e79",
+		},
+		{
+			desc:        "remote HEAD",
+			revision:    "origin",
+			expectedOid: "bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+		},
+		{
+			desc:        "peeled tag",
+			revision:    "annotated^{}",
+			expectedOid: "6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+		},
+		{
+			desc:        "tag peeled to a commit",
+			revision:    "annotated^{commit}",
+			expectedOid: "6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+		},
+		{
+			desc:        "tag peeled to a tree",
+			revision:    "annotated^{tree}",
+			expectedOid: "faecfa7505b905ed41923ad47ab81b1367c6131e",
+		},
+		{
+			desc:        "tag peeled to a tag",
+			revision:    "annotated^{tag}",
+			expectedOid: "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442",
+		},
+		{
+			desc:        "any object",
+			revision:    "annotated^{object}",
+			expectedOid: "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442",
+		},
+		{
+			desc:        "branch peeled to a tree",
+			revision:    "master^{tree}",
+			expectedOid: "e5b9e846e1b468bc9597ff95d71dfacda8bd54e3",
+		},
+		{
+			desc:          "commit peeled to a tag",
+			revision:      "master^{tag}",
+			expectedError: object.ErrObjectInvalid,
+		},
+		{
+			desc:          "unknown type",
+			revision:      "master^{foo}",
+			expectedError: ErrUnknownRevision,
+		},
+		{
+			desc:          "unknown revision",
+			revision:      "does-not-exist",
+			expectedError: ErrUnknownRevision,
+		},
+		{
+			desc:          "invalid revision",
+			revision:      "not a ref",
+			expectedError: ErrUnknownRevision,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			oid, err := r.RevParse(tc.revision)
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tc.expectedError)
+				assert.Equal(t, ginternals.NullOid, oid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOid, oid.String())
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+)
+
+// RevisionRange represents a set of commits defined by the commits
+// to include, and the commits to exclude (alongside their ancestors)
+type RevisionRange struct {
+	Include []ginternals.Oid
+	Exclude []ginternals.Oid
+}
+
+// ResolveRevisionRange resolves the provided revisions into a
+// RevisionRange.
+// The following formats are supported:
+// "rev" includes rev, "^rev" excludes rev, "from..to" excludes
+// from and includes to, and "a...b" (symmetric difference) includes
+// a and b, and excludes all their merge bases. An empty side of ".."
+// or "..." means HEAD
+func (r *Repository) ResolveRevisionRange(revisions ...string) (*RevisionRange, error) {
+	rng := &RevisionRange{
+		Include: []ginternals.Oid{},
+		Exclude: []ginternals.Oid{},
+	}
+	for _, rev := range revisions {
+		switch {
+		case strings.HasPrefix(rev, "^"):
+			oid, err := r.RevParse(strings.TrimPrefix(rev, "^"))
+			if err != nil {
+				return nil, err
+			}
+			rng.Exclude = append(rng.Exclude, oid)
+		// "..." needs to be checked first since it contains ".."
+		case strings.Contains(rev, "..."):
+			a, b, err := r.resolveRangeSides(rev, "...")
+			if err != nil {
+				return nil, err
+			}
+			bases, err := r.MergeBases(a, b)
+			if err != nil {
+				return nil, fmt.Errorf("could not get the merge bases of %s: %w", rev, err)
+			}
+			rng.Include = append(rng.Include, a, b)
+			for _, base := range bases {
+				rng.Exclude = append(rng.Exclude, base.ID())
+			}
+		case strings.Contains(rev, ".."):
+			from, to, err := r.resolveRangeSides(rev, "..")
+			if err != nil {
+				return nil, err
+			}
+			rng.Exclude = append(rng.Exclude, from)
+			rng.Include = append(rng.Include, to)
+		default:
+			oid, err := r.RevParse(rev)
+			if err != nil {
+				return nil, err
+			}
+			rng.Include = append(rng.Include, oid)
+		}
+	}
+	return rng, nil
+}
+
+// resolveRangeSides resolves the 2 revisions of a range separated by
+// sep. An empty side means HEAD
+func (r *Repository) resolveRangeSides(rev, sep string) (left, right ginternals.Oid, err error) {
+	parts := strings.SplitN(rev, sep, 2)
+	for i := range parts {
+		if parts[i] == "" {
+			parts[i] = ginternals.Head
+		}
+	}
+	left, err = r.RevParse(parts[0])
+	if err != nil {
+		return ginternals.NullOid, ginternals.NullOid, err
+	}
+	right, err = r.RevParse(parts[1])
+	if err != nil {
+		return ginternals.NullOid, ginternals.NullOid, err
+	}
+	return left, right, nil
+}
+
+// WalkOptions returns WalkOptions configured to exclude the excluded
+// commits of the range
+func (rng *RevisionRange) WalkOptions() WalkOptions {
+	return WalkOptions{
+		Exclude: rng.Exclude,
+	}
+}
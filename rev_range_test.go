@@ -0,0 +1,109 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRevisionRange(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	cleanupBranch := "b328320060eb503cf337c7cff281712ef236963a"
+
+	testCases := []struct {
+		desc            string
+		revisions       []string
+		expectedInclude []string
+		expectedExclude []string
+		expectedError   error
+	}{
+		{
+			desc:            "single revision should be included",
+			revisions:       []string{"master"},
+			expectedInclude: []string{head},
+			expectedExclude: []string{},
+		},
+		{
+			desc:            "^rev should be excluded",
+			revisions:       []string{"master", "^ml/cleanup-062020"},
+			expectedInclude: []string{head},
+			expectedExclude: []string{cleanupBranch},
+		},
+		{
+			desc:            "a..b should exclude a and include b",
+			revisions:       []string{"ml/cleanup-062020..master"},
+			expectedInclude: []string{head},
+			expectedExclude: []string{cleanupBranch},
+		},
+		{
+			desc:            "missing side of a range should be HEAD",
+			revisions:       []string{"ml/cleanup-062020.."},
+			expectedInclude: []string{head},
+			expectedExclude: []string{cleanupBranch},
+		},
+		{
+			desc:            "a...b should include a and b and exclude their merge bases",
+			revisions:       []string{"ml/cleanup-062020...master"},
+			expectedInclude: []string{cleanupBranch, head},
+			// generated using
+			// git merge-base --all ml/cleanup-062020 master
+			expectedExclude: []string{"f0f70144f38695250606b86a50cff2b440a417f3"},
+		},
+		{
+			desc:            "missing side of a symmetric difference should be HEAD",
+			revisions:       []string{"master..."},
+			expectedInclude: []string{head, head},
+			expectedExclude: []string{head},
+		},
+		{
+			desc:          "unknown revision should fail",
+			revisions:     []string{"master..does-not-exist"},
+			expectedError: ErrUnknownRevision,
+		},
+		{
+			desc:          "unknown revision of a symmetric difference should fail",
+			revisions:     []string{"does-not-exist...master"},
+			expectedError: ErrUnknownRevision,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			rng, err := r.ResolveRevisionRange(tc.revisions...)
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			include := make([]string, 0, len(rng.Include))
+			for _, oid := range rng.Include {
+				include = append(include, oid.String())
+			}
+			exclude := make([]string, 0, len(rng.Exclude))
+			for _, oid := range rng.Exclude {
+				exclude = append(exclude, oid.String())
+			}
+			assert.Equal(t, tc.expectedInclude, include)
+			assert.Equal(t, tc.expectedExclude, exclude)
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package git
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// WalkStop is a fake error used to tell WalkCommits() and
+// WalkObjects() to stop
+var WalkStop = errors.New("stop walking") //nolint // the linter expects all errors to start with Err, but since here we're faking an error we don't want that
+
+// CommitWalkFunc represents a function that will be applied on all
+// the commits found by WalkCommits()
+type CommitWalkFunc = func(c *object.Commit) error
+
+// ObjectWalkFunc represents a function that will be applied on all
+// the objects found by WalkObjects().
+// path contains the path of the tree or blob relative to the root
+// of the repository, or the name of the tag for annotated tags.
+// It's empty for commits and root trees.
+type ObjectWalkFunc = func(oid ginternals.Oid, typ object.Type, path string) error
+
+// WalkOptions represents the options that can be used to walk the
+// history of a repository
+type WalkOptions struct {
+	// Exclude contains the commits that should be excluded from the
+	// walk, alongside all their ancestors.
+	// This is the equivalent of ^commit
+	Exclude []ginternals.Oid
+	// MaxCount represents the maximum number of commits to walk.
+	// 0 means no limit
+	MaxCount int
+	// Since skips the commits that are older than the provided
+	// date (by committer date), alongside their ancestors.
+	// A zero value means no limit.
+	// This is the equivalent of --since
+	Since time.Time
+	// Until skips the commits that are more recent than the
+	// provided date (by committer date). Their ancestors are still
+	// walked.
+	// A zero value means no limit.
+	// This is the equivalent of --until
+	Until time.Time
+	// FirstParent only follows the first parent of the merge
+	// commits.
+	// This is the equivalent of --first-parent
+	FirstParent bool
+	// Shallow contains commits whose parents are not walked, as if
+	// they were root commits. This is used to walk the history of
+	// shallow clones
+	Shallow []ginternals.Oid
+}
+
+// WalkCommits runs the provided method on all the commits reachable
+// from the provided oids, most recent commits (by committer date)
+// first.
+// Tags are peeled to the commit they target.
+func (r *Repository) WalkCommits(from []ginternals.Oid, opts WalkOptions, f CommitWalkFunc) error {
+	excluded, err := r.reachableCommits(opts.Exclude)
+	if err != nil {
+		return fmt.Errorf("could not list the excluded commits: %w", err)
+	}
+	return r.walkCommits(from, excluded, opts, f)
+}
+
+// walkCommits runs the provided method on all the commits reachable
+// from the provided oids, skipping the excluded ones
+func (r *Repository) walkCommits(from []ginternals.Oid, excluded map[ginternals.Oid]struct{}, opts WalkOptions, f CommitWalkFunc) error {
+	var err error
+	queue := &commitQueue{}
+	seen := make(map[ginternals.Oid]struct{}, len(from))
+	shallow := oidSet(opts.Shallow)
+	push := func(oid ginternals.Oid) error {
+		c, err := r.peelToCommit(oid)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[c.ID()]; ok {
+			return nil
+		}
+		seen[c.ID()] = struct{}{}
+		if _, ok := excluded[c.ID()]; ok {
+			return nil
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+
+	for _, oid := range from {
+		if err = push(oid); err != nil {
+			return err
+		}
+	}
+
+	for count := 0; queue.Len() > 0; {
+		if opts.MaxCount > 0 && count >= opts.MaxCount {
+			break
+		}
+
+		c := heap.Pop(queue).(*object.Commit)
+		date := c.Committer().Time
+		// Like git, we don't walk the parents of the commits that
+		// are too old
+		if !opts.Since.IsZero() && date.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && date.After(opts.Until) {
+			if _, ok := shallow[c.ID()]; ok {
+				continue
+			}
+			if err = pushParents(c, seen, opts.FirstParent, push); err != nil {
+				return err
+			}
+			continue
+		}
+
+		count++
+		if err = f(c); err != nil {
+			if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+				return nil
+			}
+			return err
+		}
+		if _, ok := shallow[c.ID()]; ok {
+			continue
+		}
+		if err = pushParents(c, seen, opts.FirstParent, push); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oidSet returns a set containing the given oids
+func oidSet(oids []ginternals.Oid) map[ginternals.Oid]struct{} {
+	set := make(map[ginternals.Oid]struct{}, len(oids))
+	for _, oid := range oids {
+		set[oid] = struct{}{}
+	}
+	return set
+}
+
+// pushParents calls push on all the parents of c that haven't been
+// seen yet. Only the first parent is pushed if firstParent is set
+func pushParents(c *object.Commit, seen map[ginternals.Oid]struct{}, firstParent bool, push func(ginternals.Oid) error) error {
+	parentIDs := c.ParentIDs()
+	if firstParent && len(parentIDs) > 1 {
+		parentIDs = parentIDs[:1]
+	}
+	for _, parentID := range parentIDs {
+		if _, ok := seen[parentID]; ok {
+			continue
+		}
+		if err := push(parentID); err != nil {
+			return fmt.Errorf("could not get parent %s of %s: %w", parentID.String(), c.ID().String(), err)
+		}
+	}
+	return nil
+}
+
+// WalkObjects runs the provided method on all the objects reachable
+// from the provided oids. All the commits are walked first (in the
+// same order as WalkCommits()), followed by the annotated tags, and
+// then the trees and blobs of each commit.
+// Trees and blobs reachable from the excluded parents of the walked
+// commits are skipped.
+// Gitlinks (submodules) are not walked.
+//
+// Blobs are not loaded from the odb, which means that a missing blob
+// won't be reported.
+func (r *Repository) WalkObjects(from []ginternals.Oid, opts WalkOptions, f ObjectWalkFunc) error {
+	excluded, err := r.reachableCommits(opts.Exclude)
+	if err != nil {
+		return fmt.Errorf("could not list the excluded commits: %w", err)
+	}
+
+	// Like git, we only skip the objects reachable from the excluded
+	// commits that are parents of the walked commits (the boundary).
+	// This means that objects reachable from older excluded commits
+	// may still be listed
+	treeIDs := []ginternals.Oid{}
+	boundary := []ginternals.Oid{}
+	seenBoundary := map[ginternals.Oid]struct{}{}
+	shallow := oidSet(opts.Shallow)
+	stopped := false
+	err = r.walkCommits(from, excluded, opts, func(c *object.Commit) error {
+		treeIDs = append(treeIDs, c.TreeID())
+		parentIDs := c.ParentIDs()
+		if _, ok := shallow[c.ID()]; ok {
+			parentIDs = nil
+		}
+		for _, parentID := range parentIDs {
+			if _, ok := excluded[parentID]; !ok {
+				continue
+			}
+			if _, ok := seenBoundary[parentID]; ok {
+				continue
+			}
+			seenBoundary[parentID] = struct{}{}
+			boundary = append(boundary, parentID)
+		}
+		if err := f(c.ID(), object.TypeCommit, ""); err != nil {
+			stopped = err == WalkStop //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+			return err
+		}
+		return nil
+	})
+	if err != nil || stopped {
+		return err
+	}
+
+	// We mark all the trees and blobs of the boundary as seen so we
+	// don't walk them
+	seen := map[ginternals.Oid]struct{}{}
+	noop := func(ginternals.Oid, object.Type, string) error { return nil }
+	for _, oid := range boundary {
+		c, err := r.Commit(oid)
+		if err != nil {
+			return fmt.Errorf("could not get excluded commit %s: %w", oid.String(), err)
+		}
+		if err = r.walkTree(c.TreeID(), "", seen, noop); err != nil {
+			return fmt.Errorf("could not walk the tree of excluded commit %s: %w", oid.String(), err)
+		}
+	}
+
+	for _, oid := range from {
+		o, err := r.dotGit.Object(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		for o.Type() == object.TypeTag {
+			if _, ok := seen[o.ID()]; ok {
+				break
+			}
+			seen[o.ID()] = struct{}{}
+			tag, err := o.AsTag()
+			if err != nil {
+				return fmt.Errorf("could not parse tag %s: %w", o.ID().String(), err)
+			}
+			if err = f(o.ID(), object.TypeTag, tag.Name()); err != nil {
+				if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+					return nil
+				}
+				return err
+			}
+
+			o, err = r.dotGit.Object(tag.Target())
+			if err != nil {
+				return fmt.Errorf("could not get target of tag %s: %w", tag.ID().String(), err)
+			}
+		}
+	}
+
+	for _, oid := range treeIDs {
+		if err = r.walkTree(oid, "", seen, f); err != nil {
+			if err == WalkStop { //nolint:errorlint,goerr113 // it's a fake error so no need to use Error.Is()
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTree runs the provided method on the given tree and all its
+// children, depth first. Already seen objects are skipped
+func (r *Repository) walkTree(oid ginternals.Oid, treePath string, seen map[ginternals.Oid]struct{}, f ObjectWalkFunc) error {
+	if _, ok := seen[oid]; ok {
+		return nil
+	}
+	seen[oid] = struct{}{}
+
+	if err := f(oid, object.TypeTree, treePath); err != nil {
+		return err
+	}
+
+	tree, err := r.Tree(oid)
+	if err != nil {
+		return fmt.Errorf("could not get tree %s: %w", oid.String(), err)
+	}
+	for _, e := range tree.Entries() {
+		p := e.Path
+		if treePath != "" {
+			p = treePath + "/" + e.Path
+		}
+
+		switch e.Mode.ObjectType() {
+		case object.TypeTree:
+			if err = r.walkTree(e.ID, p, seen, f); err != nil {
+				return err
+			}
+		case object.TypeBlob:
+			if _, ok := seen[e.ID]; ok {
+				continue
+			}
+			seen[e.ID] = struct{}{}
+			if err = f(e.ID, object.TypeBlob, p); err != nil {
+				return err
+			}
+		case object.TypeCommit, object.TypeTag, object.ObjectDeltaOFS, object.ObjectDeltaRef:
+			// Gitlinks point to commits of other repositories, so
+			// there's nothing we can do with them
+		}
+	}
+	return nil
+}
+
+// reachableCommits returns all the commits reachable from the given
+// oids, including the commits targeted by the oids
+func (r *Repository) reachableCommits(from []ginternals.Oid) (map[ginternals.Oid]struct{}, error) {
+	reachable := map[ginternals.Oid]struct{}{}
+	if len(from) == 0 {
+		return reachable, nil
+	}
+
+	err := r.WalkCommits(from, WalkOptions{}, func(c *object.Commit) error {
+		reachable[c.ID()] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reachable, nil
+}
+
+// peelToCommit returns the commit targeted by the given oid, following
+// the annotated tags if needed.
+// object.ErrObjectInvalid is returned if oid doesn't lead to a commit
+func (r *Repository) peelToCommit(oid ginternals.Oid) (*object.Commit, error) {
+	o, err := r.dotGit.Object(oid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+	}
+	for o.Type() == object.TypeTag {
+		tag, err := object.NewTagFromObjectWithLimits(o, r.dotGit.Limits())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse tag %s: %w", o.ID().String(), err)
+		}
+		o, err = r.dotGit.Object(tag.Target())
+		if err != nil {
+			return nil, fmt.Errorf("could not get target of tag %s: %w", tag.ID().String(), err)
+		}
+	}
+	if o.Type() != object.TypeCommit {
+		return nil, fmt.Errorf("%s is a %s, not a commit: %w", oid.String(), o.Type().String(), object.ErrObjectInvalid)
+	}
+	return object.NewCommitFromObjectWithLimits(o, r.dotGit.Limits())
+}
+
+// commitQueue is a priority queue that returns the most recent commits
+// first (using the committer date).
+// It implements heap.Interface and should be used with container/heap
+type commitQueue struct {
+	commits []*object.Commit
+}
+
+// Len returns the number of commits in the queue
+func (q *commitQueue) Len() int {
+	return len(q.commits)
+}
+
+// Less returns whether the commit at i is more recent than the one at j
+func (q *commitQueue) Less(i, j int) bool {
+	return q.commits[i].Committer().Time.After(q.commits[j].Committer().Time)
+}
+
+// Swap swaps the commits at i and j
+func (q *commitQueue) Swap(i, j int) {
+	q.commits[i], q.commits[j] = q.commits[j], q.commits[i]
+}
+
+// Push adds a commit to the queue. x is expected to be an *object.Commit
+func (q *commitQueue) Push(x interface{}) {
+	q.commits = append(q.commits, x.(*object.Commit))
+}
+
+// Pop removes and returns the last commit of the queue
+func (q *commitQueue) Pop() interface{} {
+	last := len(q.commits) - 1
+	c := q.commits[last]
+	q.commits[last] = nil
+	q.commits = q.commits[:last]
+	return c
+}
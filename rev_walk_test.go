@@ -0,0 +1,260 @@
+package git
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCommits(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	head, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	cleanupBranch, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+	require.NoError(t, err)
+	annotatedTag, err := ginternals.NewOidFromStr("80316e01dbfdf5c2a8a20de66c747ecd4c4bd442")
+	require.NoError(t, err)
+
+	t.Run("should walk all the commits newest first", func(t *testing.T) {
+		t.Parallel()
+
+		commits := []*object.Commit{}
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{}, func(c *object.Commit) error {
+			commits = append(commits, c)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, commits, 17)
+		assert.Equal(t, head, commits[0].ID())
+		for i := 1; i < len(commits); i++ {
+			assert.False(t, commits[i].Committer().Time.After(commits[i-1].Committer().Time), "commit %d is newer than its predecessor", i)
+		}
+	})
+
+	t.Run("should respect Since and Until", func(t *testing.T) {
+		t.Parallel()
+
+		ids := []string{}
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{
+			Since: time.Unix(1566185593, 0),
+			Until: time.Unix(1566626872, 0),
+		}, func(c *object.Commit) error {
+			ids = append(ids, c.ID().String())
+			return nil
+		})
+		require.NoError(t, err)
+		// generated using
+		// git rev-list --since=1566185593 --until=1566626872 HEAD
+		expected := []string{
+			"925718a17eae5fc2c70ba547d20b6ed6674c898c",
+			"24d4f7fb1f89b8a9ffca1650baed70a79f261c28",
+			"d70260b4430fbc6416442545e44b4112ebfb504d",
+			"1dcdadc2a420225783794fbffd51e2e137a69646",
+			"f96f63e52cb8862b2c2d1a8b868229259c57854e",
+		}
+		assert.Equal(t, expected, ids)
+	})
+
+	t.Run("should respect MaxCount", func(t *testing.T) {
+		t.Parallel()
+
+		ids := []string{}
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{MaxCount: 3}, func(c *object.Commit) error {
+			ids = append(ids, c.ID().String())
+			return nil
+		})
+		require.NoError(t, err)
+		expected := []string{
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			"6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+			"add862f16c9befc4b88a24e22fda2fa9b68c1653",
+		}
+		assert.Equal(t, expected, ids)
+	})
+
+	t.Run("should skip the excluded commits", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{Exclude: []ginternals.Oid{cleanupBranch}}, func(c *object.Commit) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 8, count)
+	})
+
+	t.Run("should not walk the parents of the shallow commits", func(t *testing.T) {
+		t.Parallel()
+
+		ids := []ginternals.Oid{}
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{Shallow: []ginternals.Oid{head}}, func(c *object.Commit) error {
+			ids = append(ids, c.ID())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []ginternals.Oid{head}, ids)
+	})
+
+	t.Run("should peel annotated tags", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.WalkCommits([]ginternals.Oid{annotatedTag}, WalkOptions{}, func(c *object.Commit) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 16, count)
+	})
+
+	t.Run("should stop on WalkStop", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{}, func(c *object.Commit) error {
+			count++
+			return WalkStop
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("should return the errors of the callback", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := errors.New("expected error")
+		err := r.WalkCommits([]ginternals.Oid{head}, WalkOptions{}, func(c *object.Commit) error {
+			return expectedErr
+		})
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("should fail on non-commit objects", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := ginternals.NewOidFromStr("e5b9e846e1b468bc9597ff95d71dfacda8bd54e3")
+		require.NoError(t, err)
+		err = r.WalkCommits([]ginternals.Oid{tree}, WalkOptions{}, func(c *object.Commit) error {
+			return nil
+		})
+		require.ErrorIs(t, err, object.ErrObjectInvalid)
+	})
+}
+
+func TestWalkObjects(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+
+	head, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+	require.NoError(t, err)
+	cleanupBranch, err := ginternals.NewOidFromStr("b328320060eb503cf337c7cff281712ef236963a")
+	require.NoError(t, err)
+	annotatedTag, err := ginternals.NewOidFromStr("80316e01dbfdf5c2a8a20de66c747ecd4c4bd442")
+	require.NoError(t, err)
+
+	t.Run("should walk all the objects", func(t *testing.T) {
+		t.Parallel()
+
+		seen := map[ginternals.Oid]struct{}{}
+		err := r.WalkObjects([]ginternals.Oid{head}, WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			_, dup := seen[oid]
+			require.False(t, dup, "%s has been walked twice", oid.String())
+			seen[oid] = struct{}{}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Len(t, seen, 280)
+	})
+
+	t.Run("should list the commits first, then their trees", func(t *testing.T) {
+		t.Parallel()
+
+		type entry struct {
+			oid  string
+			typ  object.Type
+			path string
+		}
+		entries := []entry{}
+		err := r.WalkObjects([]ginternals.Oid{head}, WalkOptions{MaxCount: 1}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			entries = append(entries, entry{oid.String(), typ, path})
+			return nil
+		})
+		require.NoError(t, err)
+		require.True(t, len(entries) > 4)
+		assert.Equal(t, entry{"bbb720a96e4c29b9950a4c577c98470a4d5dd089", object.TypeCommit, ""}, entries[0])
+		assert.Equal(t, entry{"e5b9e846e1b468bc9597ff95d71dfacda8bd54e3", object.TypeTree, ""}, entries[1])
+		assert.Equal(t, entry{"f8b43dc7c5ff26296ae2720b356564f7db729b2c", object.TypeTree, ".github"}, entries[2])
+		assert.Equal(t, entry{"8741a2a2864e83638bc8347307d7539073a4e7a7", object.TypeTree, ".github/workflows"}, entries[3])
+		assert.Equal(t, entry{"d55aca68dd3bee5055521e5900ab6251e76d9a17", object.TypeBlob, ".github/workflows/go.yml"}, entries[4])
+	})
+
+	t.Run("should skip the objects of the boundary", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.WalkObjects([]ginternals.Oid{head}, WalkOptions{Exclude: []ginternals.Oid{cleanupBranch}}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 71, count)
+	})
+
+	t.Run("should list annotated tags", func(t *testing.T) {
+		t.Parallel()
+
+		tagPath := ""
+		count := 0
+		err := r.WalkObjects([]ginternals.Oid{annotatedTag}, WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			count++
+			if oid == annotatedTag {
+				require.Equal(t, object.TypeTag, typ)
+				tagPath = path
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 278, count)
+		assert.Equal(t, "annotated", tagPath)
+	})
+
+	t.Run("should stop on WalkStop", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		err := r.WalkObjects([]ginternals.Oid{head}, WalkOptions{}, func(oid ginternals.Oid, typ object.Type, path string) error {
+			count++
+			if typ == object.TypeTree {
+				return WalkStop
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 18, count, "expected 17 commits and 1 tree")
+	})
+}
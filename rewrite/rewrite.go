@@ -0,0 +1,531 @@
+// Package rewrite contains methods to rewrite the history of a
+// repository, like git filter-repo or BFG do. It can be used to
+// remove files from all the commits, to replace the content of some
+// files, or to change the messages and authors of the commits.
+// https://github.com/newren/git-filter-repo
+package rewrite
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// PathFilterFunc represents a function that returns whether the given
+// entry of a tree should be kept.
+// path is relative to the root of the repository
+type PathFilterFunc func(path string, mode object.TreeObjectMode) bool
+
+// Blob represents a blob being rewritten
+type Blob struct {
+	// Path contains the path of the file, relative to the root of the
+	// repository
+	Path string
+	// ID contains the oid of the original blob
+	ID ginternals.Oid
+	// Data contains the content of the blob. The content of the file
+	// is replaced if Data is changed
+	Data []byte
+}
+
+// BlobCallbackFunc represents a function that can change the content
+// of a file
+type BlobCallbackFunc func(b *Blob) error
+
+// Commit represents a commit being rewritten. All the fields but
+// Original can be changed
+type Commit struct {
+	// Original contains the commit being rewritten
+	Original  *object.Commit
+	Author    object.Signature
+	Committer object.Signature
+	Message   string
+	// TreeID contains the rewritten tree of the commit
+	TreeID ginternals.Oid
+	// ParentIDs contains the rewritten parents of the commit
+	ParentIDs []ginternals.Oid
+}
+
+// CommitCallbackFunc represents a function that can change a commit
+// before it's written
+type CommitCallbackFunc func(c *Commit) error
+
+// RewriteOptions represents the options that can be used to rewrite
+// the history of a repository
+type RewriteOptions struct {
+	// Refs contains the full names of the references to rewrite,
+	// alongside their history (ex. refs/heads/master).
+	// Defaults to all the references of refs/, and to HEAD if it's
+	// detached
+	Refs []string
+	// PathFilter is called on every entry of every tree, directories
+	// included, and removes the entries for which it returns false.
+	// Removing a directory removes all its content.
+	// Defaults to keeping everything
+	PathFilter PathFilterFunc
+	// BlobCallback is called on every file that is kept, and can
+	// change its content
+	BlobCallback BlobCallbackFunc
+	// CommitCallback is called on every commit before it's written,
+	// once its tree and parents have been rewritten
+	CommitCallback CommitCallbackFunc
+	// PruneEmpty removes the commits that don't change anything
+	// anymore once rewritten. The commits that were already empty
+	// and the merge commits are kept
+	PruneEmpty bool
+}
+
+// Result contains the outcome of a rewrite
+type Result struct {
+	// Commits maps the oid of the original commits to the oid of
+	// their rewritten version. A pruned commit is mapped to its
+	// rewritten parent, or to NullOid if it had none
+	Commits map[ginternals.Oid]ginternals.Oid
+	// Refs contains the names of the references that have been
+	// updated, sorted by name
+	Refs []string
+}
+
+// rewriter contains the state of a rewrite
+type rewriter struct {
+	repo *git.Repository
+	opts RewriteOptions
+
+	// commits maps the original commits to their new version
+	commits map[ginternals.Oid]ginternals.Oid
+	// newTrees contains the tree of the new commits
+	newTrees map[ginternals.Oid]ginternals.Oid
+	// trees maps a directory (path + NUL + oid) to its new tree
+	trees map[string]ginternals.Oid
+	// blobs maps a file (path + NUL + oid) to its new blob
+	blobs map[string]ginternals.Oid
+	// tags maps the original annotated tags to their new version
+	tags map[ginternals.Oid]ginternals.Oid
+}
+
+// RewriteHistory rewrites the commits reachable from the references,
+// and updates the references to target the rewritten commits.
+// The commits are rewritten parents first, so each commit has its
+// rewritten parents. The annotated tags targeting a rewritten commit
+// are rewritten too.
+// Like git filter-repo, the signatures of the rewritten commits and
+// tags are removed since they would not be valid anymore.
+// A reference is left untouched if all its commits have been pruned
+func RewriteHistory(repo *git.Repository, opts RewriteOptions) (*Result, error) {
+	rw := &rewriter{
+		repo:     repo,
+		opts:     opts,
+		commits:  map[ginternals.Oid]ginternals.Oid{},
+		newTrees: map[ginternals.Oid]ginternals.Oid{},
+		trees:    map[string]ginternals.Oid{},
+		blobs:    map[string]ginternals.Oid{},
+		tags:     map[ginternals.Oid]ginternals.Oid{},
+	}
+
+	refs, err := rw.refs()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]ginternals.Oid, len(refs))
+	for i, ref := range refs {
+		if targets[i], err = rw.peelToCommit(ref.Target()); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := rw.order(targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range order {
+		if err = rw.rewriteCommit(c); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &Result{
+		Commits: rw.commits,
+		Refs:    []string{},
+	}
+	for _, ref := range refs {
+		target, err := rw.rewriteObject(ref.Target())
+		if err != nil {
+			return nil, fmt.Errorf("could not rewrite %s: %w", ref.Name(), err)
+		}
+		if target.IsZero() || target == ref.Target() {
+			continue
+		}
+		if _, err = repo.NewReference(ref.Name(), target); err != nil {
+			return nil, fmt.Errorf("could not update %s: %w", ref.Name(), err)
+		}
+		res.Refs = append(res.Refs, ref.Name())
+	}
+	return res, nil
+}
+
+// refs returns the references to rewrite, sorted by name
+func (rw *rewriter) refs() ([]*ginternals.Reference, error) {
+	refs := []*ginternals.Reference{}
+	if len(rw.opts.Refs) > 0 {
+		for _, name := range rw.opts.Refs {
+			ref, err := rw.repo.Reference(name)
+			if err != nil {
+				return nil, fmt.Errorf("could not get reference %s: %w", name, err)
+			}
+			refs = append(refs, ref)
+		}
+		return refs, nil
+	}
+
+	err := rw.repo.WalkReferences(func(ref *ginternals.Reference) error {
+		if ref.Type() == ginternals.SymbolicReference {
+			return nil
+		}
+		if ref.Name() == ginternals.Head || strings.HasPrefix(ref.Name(), "refs/") {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the references: %w", err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+	return refs, nil
+}
+
+// peelToCommit returns the commit targeted by the given object, by
+// following the tags. NullOid is returned if the object doesn't lead
+// to a commit
+func (rw *rewriter) peelToCommit(oid ginternals.Oid) (ginternals.Oid, error) {
+	for {
+		o, err := rw.repo.Object(oid)
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		switch o.Type() { //nolint:exhaustive // only tags and commits matter
+		case object.TypeCommit:
+			return oid, nil
+		case object.TypeTag:
+			tag, err := o.AsTag()
+			if err != nil {
+				return ginternals.NullOid, fmt.Errorf("could not parse tag %s: %w", oid.String(), err)
+			}
+			oid = tag.Target()
+		default:
+			return ginternals.NullOid, nil
+		}
+	}
+}
+
+// order returns all the commits reachable from the given commits,
+// parents first
+func (rw *rewriter) order(from []ginternals.Oid) ([]*object.Commit, error) {
+	type frame struct {
+		commit *object.Commit
+		// next contains the index of the next parent to visit
+		next int
+	}
+
+	order := []*object.Commit{}
+	seen := map[ginternals.Oid]struct{}{}
+	for _, oid := range from {
+		if _, ok := seen[oid]; ok || oid.IsZero() {
+			continue
+		}
+		c, err := rw.repo.Commit(oid)
+		if err != nil {
+			return nil, fmt.Errorf("could not get commit %s: %w", oid.String(), err)
+		}
+		seen[oid] = struct{}{}
+		stack := []*frame{{commit: c}}
+		for len(stack) > 0 {
+			f := stack[len(stack)-1]
+			parents := f.commit.ParentIDs()
+			if f.next == len(parents) {
+				order = append(order, f.commit)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			parentID := parents[f.next]
+			f.next++
+			if _, ok := seen[parentID]; ok {
+				continue
+			}
+			parent, err := rw.repo.Commit(parentID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get commit %s: %w", parentID.String(), err)
+			}
+			seen[parentID] = struct{}{}
+			stack = append(stack, &frame{commit: parent})
+		}
+	}
+	return order, nil
+}
+
+// rewriteCommit rewrites the given commit. Its parents are expected
+// to have been rewritten already
+func (rw *rewriter) rewriteCommit(orig *object.Commit) error {
+	treeID, err := rw.rewriteRootTree(orig.TreeID())
+	if err != nil {
+		return fmt.Errorf("could not rewrite the tree of %s: %w", orig.ID().String(), err)
+	}
+
+	c := &Commit{
+		Original:  orig,
+		Author:    orig.Author(),
+		Committer: orig.Committer(),
+		Message:   orig.Message(),
+		TreeID:    treeID,
+		ParentIDs: make([]ginternals.Oid, 0, len(orig.ParentIDs())),
+	}
+	for _, parentID := range orig.ParentIDs() {
+		newID := rw.commits[parentID]
+		if newID.IsZero() || containsOid(c.ParentIDs, newID) {
+			continue
+		}
+		c.ParentIDs = append(c.ParentIDs, newID)
+	}
+
+	if rw.opts.PruneEmpty && len(orig.ParentIDs()) <= 1 && len(c.ParentIDs) <= 1 {
+		prune, err := rw.isNowEmpty(orig, c)
+		if err != nil {
+			return err
+		}
+		if prune {
+			rw.commits[orig.ID()] = ginternals.NullOid
+			if len(c.ParentIDs) == 1 {
+				rw.commits[orig.ID()] = c.ParentIDs[0]
+			}
+			return nil
+		}
+	}
+
+	if rw.opts.CommitCallback != nil {
+		if err = rw.opts.CommitCallback(c); err != nil {
+			return fmt.Errorf("could not rewrite commit %s: %w", orig.ID().String(), err)
+		}
+	}
+
+	newCommit := object.NewCommit(c.TreeID, c.Author, &object.CommitOptions{
+		Message:   c.Message,
+		Committer: c.Committer,
+		ParentsID: c.ParentIDs,
+	})
+	if _, err = rw.repo.WriteObject(newCommit.ToObject()); err != nil {
+		return fmt.Errorf("could not write the new version of %s: %w", orig.ID().String(), err)
+	}
+	rw.commits[orig.ID()] = newCommit.ID()
+	rw.newTrees[newCommit.ID()] = c.TreeID
+	return nil
+}
+
+// isNowEmpty returns whether a commit that used to change something
+// doesn't change anything once rewritten
+func (rw *rewriter) isNowEmpty(orig *object.Commit, c *Commit) (bool, error) {
+	origParentTree := ginternals.NullOid
+	if parents := orig.ParentIDs(); len(parents) > 0 {
+		parent, err := rw.repo.Commit(parents[0])
+		if err != nil {
+			return false, fmt.Errorf("could not get commit %s: %w", parents[0].String(), err)
+		}
+		origParentTree = parent.TreeID()
+	}
+	emptyTree := object.NewTree([]object.TreeEntry{}).ID()
+	if origParentTree.IsZero() {
+		origParentTree = emptyTree
+	}
+	if orig.TreeID() == origParentTree {
+		return false, nil
+	}
+
+	newParentTree := emptyTree
+	if len(c.ParentIDs) > 0 {
+		newParentTree = rw.newTrees[c.ParentIDs[0]]
+	}
+	return c.TreeID == newParentTree, nil
+}
+
+// rewriteRootTree rewrites the tree of a commit. Unlike the other
+// trees, the root tree is written even if it's empty
+func (rw *rewriter) rewriteRootTree(oid ginternals.Oid) (ginternals.Oid, error) {
+	newID, err := rw.rewriteTree(oid, "")
+	if err != nil || !newID.IsZero() {
+		return newID, err
+	}
+	o := object.NewTree([]object.TreeEntry{}).ToObject()
+	if _, err = rw.repo.WriteObject(o); err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not write the empty tree: %w", err)
+	}
+	return o.ID(), nil
+}
+
+// rewriteTree rewrites the tree located at the given directory.
+// NullOid is returned, and nothing is written, if the tree ends up
+// empty
+func (rw *rewriter) rewriteTree(oid ginternals.Oid, dir string) (ginternals.Oid, error) {
+	if rw.opts.PathFilter == nil && rw.opts.BlobCallback == nil {
+		return oid, nil
+	}
+	key := dir + "\x00" + oid.String()
+	if newID, ok := rw.trees[key]; ok {
+		return newID, nil
+	}
+
+	tree, err := rw.repo.Tree(oid)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not get tree %s: %w", oid.String(), err)
+	}
+	changed := false
+	entries := make([]object.TreeEntry, 0, len(tree.Entries()))
+	for _, e := range tree.Entries() {
+		p := path.Join(dir, e.Path)
+		if rw.opts.PathFilter != nil && !rw.opts.PathFilter(p, e.Mode) {
+			changed = true
+			continue
+		}
+
+		newID := e.ID
+		switch e.Mode {
+		case object.ModeDirectory:
+			if newID, err = rw.rewriteTree(e.ID, p); err != nil {
+				return ginternals.NullOid, err
+			}
+		case object.ModeFile, object.ModeExecutable, object.ModeSymLink:
+			if newID, err = rw.rewriteBlob(e.ID, p); err != nil {
+				return ginternals.NullOid, err
+			}
+		case object.ModeGitLink:
+		}
+		if newID != e.ID {
+			changed = true
+		}
+		// Like git, we remove the directories that are now empty
+		if newID.IsZero() {
+			continue
+		}
+		e.ID = newID
+		// The entries are already sorted, and changing their oid
+		// doesn't change their order
+		entries = append(entries, e)
+	}
+
+	newID := oid
+	switch {
+	case len(entries) == 0:
+		newID = ginternals.NullOid
+	case changed:
+		o := object.NewTree(entries).ToObject()
+		if _, err = rw.repo.WriteObject(o); err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not write the new version of tree %s: %w", oid.String(), err)
+		}
+		newID = o.ID()
+	}
+	rw.trees[key] = newID
+	return newID, nil
+}
+
+// rewriteBlob runs the blob callback on the given file
+func (rw *rewriter) rewriteBlob(oid ginternals.Oid, p string) (ginternals.Oid, error) {
+	if rw.opts.BlobCallback == nil {
+		return oid, nil
+	}
+	key := p + "\x00" + oid.String()
+	if newID, ok := rw.blobs[key]; ok {
+		return newID, nil
+	}
+
+	blob, err := rw.repo.Blob(oid)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not get blob %s: %w", oid.String(), err)
+	}
+	b := &Blob{
+		Path: p,
+		ID:   oid,
+		Data: blob.Bytes(),
+	}
+	if err = rw.opts.BlobCallback(b); err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not rewrite %s: %w", p, err)
+	}
+	newBlob, err := rw.repo.NewBlob(b.Data)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not write the new version of %s: %w", p, err)
+	}
+	rw.blobs[key] = newBlob.ID()
+	return newBlob.ID(), nil
+}
+
+// rewriteObject returns the new version of the object targeted by a
+// reference. NullOid is returned if the object doesn't exist anymore
+func (rw *rewriter) rewriteObject(oid ginternals.Oid) (ginternals.Oid, error) {
+	if newID, ok := rw.commits[oid]; ok {
+		return newID, nil
+	}
+	o, err := rw.repo.Object(oid)
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+	}
+	switch o.Type() { //nolint:exhaustive // the commits have been handled already
+	case object.TypeTag:
+		return rw.rewriteTag(o)
+	case object.TypeTree:
+		return rw.rewriteTree(oid, "")
+	default:
+		return oid, nil
+	}
+}
+
+// rewriteTag rewrites an annotated tag so it targets the new version
+// of its object
+func (rw *rewriter) rewriteTag(o *object.Object) (ginternals.Oid, error) {
+	if newID, ok := rw.tags[o.ID()]; ok {
+		return newID, nil
+	}
+	tag, err := o.AsTag()
+	if err != nil {
+		return ginternals.NullOid, fmt.Errorf("could not parse tag %s: %w", o.ID().String(), err)
+	}
+	targetID, err := rw.rewriteObject(tag.Target())
+	if err != nil {
+		return ginternals.NullOid, err
+	}
+
+	newID := o.ID()
+	switch {
+	case targetID.IsZero():
+		newID = ginternals.NullOid
+	case targetID != tag.Target():
+		target, err := rw.repo.Object(targetID)
+		if err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not get object %s: %w", targetID.String(), err)
+		}
+		newTag := object.NewTag(&object.TagParams{
+			Target:  target,
+			Name:    tag.Name(),
+			Tagger:  tag.Tagger(),
+			Message: tag.Message(),
+		}).ToObject()
+		if _, err = rw.repo.WriteObject(newTag); err != nil {
+			return ginternals.NullOid, fmt.Errorf("could not write the new version of tag %s: %w", tag.Name(), err)
+		}
+		newID = newTag.ID()
+	}
+	rw.tags[o.ID()] = newID
+	return newID, nil
+}
+
+// containsOid returns whether the list contains the given oid
+func containsOid(list []ginternals.Oid, oid ginternals.Oid) bool {
+	for _, id := range list {
+		if id == oid {
+			return true
+		}
+	}
+	return false
+}
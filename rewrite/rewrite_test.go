@@ -0,0 +1,214 @@
+package rewrite_test
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/rewrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// walkHistory runs f on all the commits reachable from the given
+// reference
+func walkHistory(t *testing.T, r *git.Repository, refName string, f func(c *object.Commit)) {
+	t.Helper()
+
+	ref, err := r.Reference(refName)
+	require.NoError(t, err)
+	o, err := r.Object(ref.Target())
+	require.NoError(t, err)
+	target := ref.Target()
+	if o.Type() == object.TypeTag {
+		tag, err := o.AsTag()
+		require.NoError(t, err)
+		target = tag.Target()
+	}
+
+	seen := map[ginternals.Oid]struct{}{}
+	queue := []ginternals.Oid{target}
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[oid]; ok {
+			continue
+		}
+		seen[oid] = struct{}{}
+		c, err := r.Commit(oid)
+		require.NoError(t, err)
+		f(c)
+		queue = append(queue, c.ParentIDs()...)
+	}
+}
+
+// openRepo returns a copy of the small repository
+func openRepo(t *testing.T) (*git.Repository, string) {
+	t.Helper()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := git.OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+	return r, repoPath
+}
+
+// assertValidRepo makes sure git can read the repository
+func assertValidRepo(t *testing.T, repoPath string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	output, err := exec.Command("git", "-C", repoPath, "fsck", "--no-dangling").CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestRewriteHistory(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should remove a file from the history", func(t *testing.T) {
+		t.Parallel()
+
+		r, repoPath := openRepo(t)
+		master, err := r.Reference("refs/heads/master")
+		require.NoError(t, err)
+		annotated, err := r.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+
+		res, err := rewrite.RewriteHistory(r, rewrite.RewriteOptions{
+			PathFilter: func(path string, mode object.TreeObjectMode) bool {
+				return path != "README.md"
+			},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, res.Refs, "refs/heads/master")
+		assert.Contains(t, res.Refs, "refs/tags/annotated")
+
+		newMaster, err := r.Reference("refs/heads/master")
+		require.NoError(t, err)
+		assert.Equal(t, res.Commits[master.Target()], newMaster.Target())
+		walkHistory(t, r, "refs/heads/master", func(c *object.Commit) {
+			tree, err := r.Tree(c.TreeID())
+			require.NoError(t, err)
+			_, ok := tree.Entry("README.md")
+			assert.False(t, ok, "README.md should not exist in %s", c.ID().String())
+			_, ok = tree.Entry(".gitignore")
+			assert.True(t, ok, ".gitignore should exist in %s", c.ID().String())
+			assert.Empty(t, c.GPGSig(), "the signature should have been removed")
+		})
+
+		// The annotated tag should have been rewritten to target the
+		// new commit
+		newAnnotated, err := r.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+		assert.NotEqual(t, annotated.Target(), newAnnotated.Target())
+		o, err := r.Object(newAnnotated.Target())
+		require.NoError(t, err)
+		tag, err := o.AsTag()
+		require.NoError(t, err)
+		assert.Equal(t, "annotated", tag.Name())
+		assert.Equal(t, "annotated tag\n", tag.Message())
+
+		assertValidRepo(t, repoPath)
+	})
+
+	t.Run("should replace the content of the files", func(t *testing.T) {
+		t.Parallel()
+
+		r, repoPath := openRepo(t)
+		_, err := rewrite.RewriteHistory(r, rewrite.RewriteOptions{
+			Refs: []string{"refs/heads/master"},
+			BlobCallback: func(b *rewrite.Blob) error {
+				if b.Path == ".gitignore" {
+					b.Data = []byte("redacted\n")
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+
+		walkHistory(t, r, "refs/heads/master", func(c *object.Commit) {
+			tree, err := r.Tree(c.TreeID())
+			require.NoError(t, err)
+			e, ok := tree.Entry(".gitignore")
+			require.True(t, ok)
+			blob, err := r.Blob(e.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "redacted\n", string(blob.Bytes()))
+		})
+		assertValidRepo(t, repoPath)
+	})
+
+	t.Run("should rewrite the commits", func(t *testing.T) {
+		t.Parallel()
+
+		r, repoPath := openRepo(t)
+		annotated, err := r.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+
+		sig := object.NewSignature("New Author", "new@domain.tld")
+		res, err := rewrite.RewriteHistory(r, rewrite.RewriteOptions{
+			Refs: []string{"refs/heads/master"},
+			CommitCallback: func(c *rewrite.Commit) error {
+				c.Author = sig
+				c.Message = "[rewritten] " + c.Message
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"refs/heads/master"}, res.Refs)
+
+		walkHistory(t, r, "refs/heads/master", func(c *object.Commit) {
+			assert.Equal(t, sig.Email, c.Author().Email)
+			assert.True(t, strings.HasPrefix(c.Message(), "[rewritten] "))
+		})
+
+		ref, err := r.Reference("refs/tags/annotated")
+		require.NoError(t, err)
+		assert.Equal(t, annotated.Target(), ref.Target(), "the references not listed should not be updated")
+		assertValidRepo(t, repoPath)
+	})
+
+	t.Run("should prune the commits that became empty", func(t *testing.T) {
+		t.Parallel()
+
+		r, repoPath := openRepo(t)
+		count := 0
+		walkHistory(t, r, "refs/heads/master", func(c *object.Commit) {
+			count++
+		})
+
+		_, err := rewrite.RewriteHistory(r, rewrite.RewriteOptions{
+			Refs:       []string{"refs/heads/master"},
+			PruneEmpty: true,
+			PathFilter: func(path string, mode object.TreeObjectMode) bool {
+				return path == ".gitignore"
+			},
+		})
+		require.NoError(t, err)
+
+		newCount := 0
+		walkHistory(t, r, "refs/heads/master", func(c *object.Commit) {
+			newCount++
+			tree, err := r.Tree(c.TreeID())
+			require.NoError(t, err)
+			assert.Len(t, tree.Entries(), 1)
+			if len(c.ParentIDs()) != 1 {
+				return
+			}
+			parent, err := r.Commit(c.ParentIDs()[0])
+			require.NoError(t, err)
+			assert.NotEqual(t, parent.TreeID(), c.TreeID(), "%s should have been pruned", c.ID().String())
+		})
+		assert.Less(t, newCount, count)
+		assertValidRepo(t, repoPath)
+	})
+}
@@ -0,0 +1,565 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/transport"
+)
+
+// bandData is the band of the sideband used to send the data
+const bandData byte = 1
+
+// FetchStats contains the statistics of a packfile sent to a client
+type FetchStats struct {
+	// Objects contains the number of objects in the packfile
+	Objects int
+	// Deltas contains the number of deltified objects, reused deltas
+	// included
+	Deltas int
+	// ReusedDeltas contains the number of deltas that have been
+	// copied as is instead of being computed
+	ReusedDeltas int
+	// Counting contains the time spent listing the objects to send
+	Counting time.Duration
+	// Timings contains how long each phase of the creation of the
+	// packfile took
+	Timings packfile.BuildTimings
+}
+
+// fetchArgs represents the arguments of the fetch command
+type fetchArgs struct {
+	wants    []ginternals.Oid
+	haves    []ginternals.Oid
+	shallows []ginternals.Oid
+	filter   *transport.Filter
+
+	deepen      int
+	deepenSince time.Time
+
+	done        bool
+	waitForDone bool
+	includeTag  bool
+	ofsDelta    bool
+	sidebandAll bool
+}
+
+// isDeepening returns whether the client asked for a shallow fetch
+func (args *fetchArgs) isDeepening() bool {
+	return args.deepen > 0 || !args.deepenSince.IsZero()
+}
+
+// parseFetchArgs parses the arguments of the fetch command
+func (up *UploadPack) parseFetchArgs(args []string) (*fetchArgs, error) {
+	parsed := &fetchArgs{
+		wants:    []ginternals.Oid{},
+		haves:    []ginternals.Oid{},
+		shallows: []ginternals.Oid{},
+	}
+	parseOid := func(arg, prefix string, list *[]ginternals.Oid) error {
+		oid, err := ginternals.NewOidFromStr(strings.TrimPrefix(arg, prefix))
+		if err != nil {
+			return fmt.Errorf("invalid %q: %w", arg, ErrInvalidRequest)
+		}
+		*list = append(*list, oid)
+		return nil
+	}
+
+	for _, arg := range args {
+		var err error
+		switch {
+		case strings.HasPrefix(arg, "want "):
+			err = parseOid(arg, "want ", &parsed.wants)
+		case strings.HasPrefix(arg, "have "):
+			err = parseOid(arg, "have ", &parsed.haves)
+		case strings.HasPrefix(arg, "shallow "):
+			err = parseOid(arg, "shallow ", &parsed.shallows)
+		case strings.HasPrefix(arg, "deepen "):
+			parsed.deepen, err = strconv.Atoi(strings.TrimPrefix(arg, "deepen "))
+			if err != nil || parsed.deepen <= 0 {
+				err = fmt.Errorf("invalid %q: %w", arg, ErrInvalidRequest)
+			}
+		case strings.HasPrefix(arg, "deepen-since "):
+			var ts int64
+			ts, err = strconv.ParseInt(strings.TrimPrefix(arg, "deepen-since "), 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid %q: %w", arg, ErrInvalidRequest)
+			}
+			parsed.deepenSince = time.Unix(ts, 0)
+		case strings.HasPrefix(arg, "filter "):
+			if !up.opts.AllowFilter {
+				return nil, fmt.Errorf("filter not allowed: %w", ErrInvalidRequest)
+			}
+			parsed.filter, err = transport.ParseFilter(strings.TrimPrefix(arg, "filter "))
+			if err != nil {
+				err = fmt.Errorf("%s: %w", err.Error(), ErrInvalidRequest)
+			}
+		case arg == "sideband-all":
+			if !up.opts.AllowSidebandAll {
+				return nil, fmt.Errorf("sideband-all not allowed: %w", ErrInvalidRequest)
+			}
+			parsed.sidebandAll = true
+		case arg == "done":
+			parsed.done = true
+		case arg == "wait-for-done":
+			parsed.waitForDone = true
+		case arg == "include-tag":
+			parsed.includeTag = true
+		case arg == "ofs-delta":
+			parsed.ofsDelta = true
+		case arg == "thin-pack", arg == "no-progress":
+			// We never send thin packs, nor progress
+		default:
+			return nil, fmt.Errorf("unexpected fetch argument %q: %w", arg, ErrInvalidRequest)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(parsed.wants) == 0 {
+		return nil, fmt.Errorf("no wants: %w", ErrInvalidRequest)
+	}
+	return parsed, nil
+}
+
+// fetch negotiates the objects to send with the client, and sends
+// them in a packfile once the client is done.
+// The response contains the following sections, in that order:
+//   - acknowledgments: the haves the server has, only sent if the
+//     client is not done
+//   - shallow-info: the new shallow boundary of the client, only sent
+//     for the shallow fetches
+//   - packfile: the packfile, sent through the sideband
+func (up *UploadPack) fetch(rawArgs []string, out io.Writer) error {
+	args, err := up.parseFetchArgs(rawArgs)
+	if err != nil {
+		return err
+	}
+	for _, oid := range args.wants {
+		if _, _, err = up.repo.ObjectInfo(oid); err != nil {
+			if errors.Is(err, ginternals.ErrObjectNotFound) {
+				return fmt.Errorf("not our ref %s: %w", oid.String(), ErrInvalidRequest)
+			}
+			return fmt.Errorf("could not check want %s: %w", oid.String(), err)
+		}
+	}
+	common := []ginternals.Oid{}
+	for _, oid := range args.haves {
+		if _, _, err = up.repo.ObjectInfo(oid); err != nil {
+			if errors.Is(err, ginternals.ErrObjectNotFound) {
+				continue
+			}
+			return fmt.Errorf("could not check have %s: %w", oid.String(), err)
+		}
+		common = append(common, oid)
+	}
+
+	w := &responseWriter{
+		w:           pktline.NewWriter(out),
+		sidebandAll: args.sidebandAll,
+	}
+	if !args.done {
+		if err = w.writeLine("acknowledgments"); err != nil {
+			return err
+		}
+		if len(common) == 0 {
+			if err = w.writeLine("NAK"); err != nil {
+				return err
+			}
+		}
+		for _, oid := range common {
+			if err = w.writeLine("ACK " + oid.String()); err != nil {
+				return err
+			}
+		}
+		// We don't look for the best common commits, any common
+		// commit is enough to not send the whole history
+		if len(common) == 0 || args.waitForDone {
+			return w.w.WriteFlush()
+		}
+		if err = w.writeLine("ready"); err != nil {
+			return err
+		}
+		if err = w.w.WriteDelim(); err != nil {
+			return err
+		}
+	}
+
+	walkOpts := git.WalkOptions{
+		Exclude: common,
+		Shallow: args.shallows,
+	}
+	if len(args.shallows) > 0 || args.isDeepening() {
+		shallows, unshallows, err := up.shallowBoundary(args)
+		if err != nil {
+			return err
+		}
+		if err = up.writeShallowInfo(w, args, shallows, unshallows); err != nil {
+			return err
+		}
+		if args.isDeepening() {
+			walkOpts.Shallow = shallows
+			// The client doesn't have the parents of its shallow
+			// commits, so we cannot exclude what's reachable from
+			// its haves. Everything within the new boundary is sent
+			walkOpts.Exclude = nil
+		}
+	}
+
+	start := time.Now()
+	objects, err := up.objectsToSend(args, walkOpts)
+	if err != nil {
+		return err
+	}
+	counting := time.Since(start)
+	buildOpts := packfile.BuildOptions{}
+	// Build() only writes deltas that reference their base by offset
+	if args.ofsDelta {
+		buildOpts.Window = packfile.DefaultWindow
+		buildOpts.Depth = packfile.DefaultDepth
+	}
+	pack, err := packfile.Build(objects, buildOpts)
+	if err != nil {
+		return fmt.Errorf("could not build the packfile: %w", err)
+	}
+
+	if err = w.writeLine("packfile"); err != nil {
+		return err
+	}
+	if err = w.writeBand(bandData, pack.Pack); err != nil {
+		return err
+	}
+	if err = w.w.WriteFlush(); err != nil {
+		return err
+	}
+	up.reportStats(FetchStats{
+		Objects:      pack.ObjectCount,
+		Deltas:       pack.Deltas,
+		ReusedDeltas: pack.ReusedDeltas,
+		Counting:     counting,
+		Timings:      pack.Timings,
+	})
+	return nil
+}
+
+// reportStats traces the statistics of a packfile that has been sent,
+// and passes them to UploadPackOptions.OnPack
+func (up *UploadPack) reportStats(stats FetchStats) {
+	t := up.opts.Trace
+	t.Printf("upload-pack: total %d (delta %d), reused %d (delta %d)", stats.Objects, stats.Deltas, stats.ReusedDeltas, stats.ReusedDeltas)
+	t.Printf("upload-pack: counting objects: %.9f s, deltas: %.9f s, writing objects: %.9f s, index: %.9f s",
+		stats.Counting.Seconds(),
+		stats.Timings.Deltas.Seconds(),
+		stats.Timings.Write.Seconds(),
+		stats.Timings.Index.Seconds())
+	if up.opts.OnPack != nil {
+		up.opts.OnPack(stats)
+	}
+}
+
+// shallowBoundary returns the commits that will be shallow once the
+// client has received the packfile, and the commits that are shallow
+// for the client but won't be anymore.
+// The client shallows are returned as is if the client is not
+// deepening its history
+func (up *UploadPack) shallowBoundary(args *fetchArgs) (shallows, unshallows []ginternals.Oid, err error) {
+	if !args.isDeepening() {
+		return args.shallows, []ginternals.Oid{}, nil
+	}
+
+	clientShallows := make(map[ginternals.Oid]struct{}, len(args.shallows))
+	for _, oid := range args.shallows {
+		clientShallows[oid] = struct{}{}
+	}
+	// included returns whether a commit at the given depth is within
+	// the new boundary
+	included := func(c *object.Commit, depth int) bool {
+		if args.deepen > 0 && depth > args.deepen {
+			return false
+		}
+		return args.deepenSince.IsZero() || !c.Committer().Time.Before(args.deepenSince)
+	}
+
+	type queued struct {
+		commit *object.Commit
+		depth  int
+	}
+	queue := []queued{}
+	seen := map[ginternals.Oid]struct{}{}
+	for _, oid := range args.wants {
+		c, err := up.peelToCommit(oid)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c == nil {
+			continue
+		}
+		if _, ok := seen[c.ID()]; ok {
+			continue
+		}
+		seen[c.ID()] = struct{}{}
+		queue = append(queue, queued{commit: c, depth: 1})
+	}
+
+	shallows = []ginternals.Oid{}
+	unshallows = []ginternals.Oid{}
+	// The walk is breadth first, so the commits are reached using
+	// the shortest path
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		c := current.commit
+
+		parents := make([]*object.Commit, 0, len(c.ParentIDs()))
+		isShallow := false
+		for _, parentID := range c.ParentIDs() {
+			parent, err := up.repo.Commit(parentID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not get parent %s of %s: %w", parentID.String(), c.ID().String(), err)
+			}
+			if !included(parent, current.depth+1) {
+				isShallow = true
+				break
+			}
+			parents = append(parents, parent)
+		}
+		if isShallow {
+			shallows = append(shallows, c.ID())
+			continue
+		}
+		if _, ok := clientShallows[c.ID()]; ok && len(parents) > 0 {
+			unshallows = append(unshallows, c.ID())
+		}
+		for _, parent := range parents {
+			if _, ok := seen[parent.ID()]; ok {
+				continue
+			}
+			seen[parent.ID()] = struct{}{}
+			queue = append(queue, queued{commit: parent, depth: current.depth + 1})
+		}
+	}
+	return shallows, unshallows, nil
+}
+
+// writeShallowInfo writes the shallow-info section of a fetch. The
+// commits that are already shallow for the client are not sent
+func (up *UploadPack) writeShallowInfo(w *responseWriter, args *fetchArgs, shallows, unshallows []ginternals.Oid) error {
+	clientShallows := make(map[ginternals.Oid]struct{}, len(args.shallows))
+	for _, oid := range args.shallows {
+		clientShallows[oid] = struct{}{}
+	}
+
+	if err := w.writeLine("shallow-info"); err != nil {
+		return err
+	}
+	for _, oid := range shallows {
+		if _, ok := clientShallows[oid]; ok {
+			continue
+		}
+		if err := w.writeLine("shallow " + oid.String()); err != nil {
+			return err
+		}
+	}
+	for _, oid := range unshallows {
+		if err := w.writeLine("unshallow " + oid.String()); err != nil {
+			return err
+		}
+	}
+	return w.w.WriteDelim()
+}
+
+// objectsToSend returns the objects reachable from the wants of the
+// client, minus the ones filtered out
+func (up *UploadPack) objectsToSend(args *fetchArgs, walkOpts git.WalkOptions) ([]*packfile.BuildObject, error) {
+	objects := []*packfile.BuildObject{}
+	sent := map[ginternals.Oid]struct{}{}
+	add := func(oid ginternals.Oid, path string) error {
+		if _, ok := sent[oid]; ok {
+			return nil
+		}
+		o, err := up.repo.Object(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		sent[oid] = struct{}{}
+		objects = append(objects, &packfile.BuildObject{
+			Object: o,
+			Path:   path,
+		})
+		return nil
+	}
+
+	// WalkObjects only walks commits, so the wants that target a
+	// tree or a blob are added directly
+	commitWants := make([]ginternals.Oid, 0, len(args.wants))
+	for _, oid := range args.wants {
+		c, err := up.peelToCommit(oid)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			commitWants = append(commitWants, oid)
+			continue
+		}
+		if err = add(oid, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	err := up.repo.WalkObjects(commitWants, walkOpts, func(oid ginternals.Oid, typ object.Type, path string) error {
+		keep, err := up.filterAllows(args.filter, oid, typ, path)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			return nil
+		}
+		return add(oid, path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the objects: %w", err)
+	}
+
+	// Like git, include-tag sends the annotated tags that point to
+	// objects being sent, so the client doesn't need to fetch them
+	// separately
+	if args.includeTag {
+		tags, err := up.repo.ReferencesWithPrefix([]string{"refs/tags/"})
+		if err != nil {
+			return nil, fmt.Errorf("could not list the tags: %w", err)
+		}
+		for _, ref := range tags {
+			if ref.Peeled.IsZero() {
+				continue
+			}
+			if _, ok := sent[ref.Peeled]; !ok {
+				continue
+			}
+			if err = up.addTagChain(ref.Target(), add); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return objects, nil
+}
+
+// addTagChain adds the annotated tag with the given oid, and all the
+// annotated tags it targets
+func (up *UploadPack) addTagChain(oid ginternals.Oid, add func(ginternals.Oid, string) error) error {
+	for {
+		o, err := up.repo.Object(oid)
+		if err != nil {
+			return fmt.Errorf("could not get object %s: %w", oid.String(), err)
+		}
+		if o.Type() != object.TypeTag {
+			return nil
+		}
+		tag, err := o.AsTag()
+		if err != nil {
+			return fmt.Errorf("could not parse tag %s: %w", oid.String(), err)
+		}
+		if err = add(oid, tag.Name()); err != nil {
+			return err
+		}
+		oid = tag.Target()
+	}
+}
+
+// peelToCommit returns the commit targeted by the given oid, following
+// the annotated tags. nil is returned if the oid doesn't lead to a
+// commit
+func (up *UploadPack) peelToCommit(oid ginternals.Oid) (*object.Commit, error) {
+	o, err := up.repo.Object(oid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %s: %w", oid.String(), err)
+	}
+	o, err = up.repo.PeelObject(o, object.TypeCommit)
+	if err != nil {
+		if errors.Is(err, object.ErrObjectInvalid) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not peel %s: %w", oid.String(), err)
+	}
+	return o.AsCommit()
+}
+
+// filterAllows returns whether the given object is allowed by the
+// filter. All the objects are allowed if there's no filter
+func (up *UploadPack) filterAllows(f *transport.Filter, oid ginternals.Oid, typ object.Type, path string) (bool, error) {
+	if f == nil || typ == object.TypeCommit || typ == object.TypeTag {
+		return true, nil
+	}
+	switch f.Type {
+	case transport.FilterBlobNone:
+		return typ != object.TypeBlob, nil
+	case transport.FilterBlobLimit:
+		if typ != object.TypeBlob {
+			return true, nil
+		}
+		_, size, err := up.repo.ObjectInfo(oid)
+		if err != nil {
+			return false, fmt.Errorf("could not get the size of %s: %w", oid.String(), err)
+		}
+		return uint64(size) < f.Limit, nil
+	case transport.FilterTreeDepth:
+		// The root tree has a depth of 0, and its entries a depth
+		// of 1
+		depth := uint64(0)
+		if path != "" {
+			depth = uint64(strings.Count(path, "/") + 1)
+		}
+		return depth < f.Depth, nil
+	case transport.FilterCombine:
+		for _, sub := range f.Filters {
+			ok, err := up.filterAllows(sub, oid, typ, path)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported filter %s: %w", f.Type, ErrInvalidRequest)
+}
+
+// responseWriter writes the response of a command, using the sideband
+// for all the data packets if requested by the client
+type responseWriter struct {
+	w           *pktline.Writer
+	sidebandAll bool
+}
+
+// writeLine writes a line of the response
+func (w *responseWriter) writeLine(line string) error {
+	if w.sidebandAll {
+		return w.writeBand(bandData, []byte(line+"\n"))
+	}
+	return w.w.WriteLine(line)
+}
+
+// writeBand writes the given data to a band of the sideband,
+// split into as many packets as needed
+func (w *responseWriter) writeBand(band byte, data []byte) error {
+	buf := make([]byte, 0, pktline.MaxPayloadSize)
+	for len(data) > 0 {
+		size := len(data)
+		if size > pktline.MaxPayloadSize-1 {
+			size = pktline.MaxPayloadSize - 1
+		}
+		buf = append(buf[:0], band)
+		buf = append(buf, data[:size]...)
+		if err := w.w.WritePacket(buf); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchResponse represents the parsed response of a fetch
+type fetchResponse struct {
+	// lines contains the lines of the response that are not part of
+	// the packfile
+	lines []string
+	pack  []byte
+}
+
+// objectCount returns the number of objects in the packfile
+func (res *fetchResponse) objectCount(t *testing.T) int {
+	t.Helper()
+
+	require.True(t, len(res.pack) > 12, "invalid packfile")
+	require.Equal(t, "PACK", string(res.pack[:4]))
+	return int(binary.BigEndian.Uint32(res.pack[8:12]))
+}
+
+// parseFetchResponse parses the response of a fetch. If sidebandAll
+// is true, all the data lines are expected to be in the first band
+func parseFetchResponse(t *testing.T, response []byte, sidebandAll bool) *fetchResponse {
+	t.Helper()
+
+	res := &fetchResponse{
+		lines: []string{},
+	}
+	inPack := false
+	r := pktline.NewReader(bytes.NewReader(response))
+	for {
+		typ, data, err := r.ReadPacket()
+		if err != nil {
+			return res
+		}
+		switch typ {
+		case pktline.FlushPacket:
+			res.lines = append(res.lines, "<flush>")
+			continue
+		case pktline.DelimPacket:
+			res.lines = append(res.lines, "<delim>")
+			continue
+		}
+		if inPack || sidebandAll {
+			require.Equal(t, byte(1), data[0], "unexpected band")
+			data = data[1:]
+		}
+		if inPack {
+			res.pack = append(res.pack, data...)
+			continue
+		}
+		line := string(bytes.TrimSuffix(data, []byte("\n")))
+		res.lines = append(res.lines, line)
+		inPack = line == "packfile"
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	head := "bbb720a96e4c29b9950a4c577c98470a4d5dd089"
+	cleanupBranch := "b328320060eb503cf337c7cff281712ef236963a"
+
+	testCases := []struct {
+		desc          string
+		opts          UploadPackOptions
+		args          []string
+		sidebandAll   bool
+		expectedLines []string
+		// expectedCount is the number of objects expected in the
+		// packfile. No packfile is expected if 0
+		expectedCount int
+	}{
+		{
+			desc:          "should send all the objects",
+			args:          []string{"want " + head, "done"},
+			expectedLines: []string{"packfile", "<flush>"},
+			expectedCount: 280,
+		},
+		{
+			desc: "should only send a NAK if nothing is in common",
+			args: []string{"want " + head, "have 0000000000000000000000000000000000000001"},
+			expectedLines: []string{
+				"acknowledgments",
+				"NAK",
+				"<flush>",
+			},
+		},
+		{
+			desc: "should send the packfile once ready",
+			args: []string{"want " + head, "have " + cleanupBranch},
+			expectedLines: []string{
+				"acknowledgments",
+				"ACK " + cleanupBranch,
+				"ready",
+				"<delim>",
+				"packfile",
+				"<flush>",
+			},
+			expectedCount: 71,
+		},
+		{
+			desc: "should wait for done",
+			args: []string{"want " + head, "have " + cleanupBranch, "wait-for-done"},
+			expectedLines: []string{
+				"acknowledgments",
+				"ACK " + cleanupBranch,
+				"<flush>",
+			},
+		},
+		{
+			desc: "should only send the requested depth",
+			args: []string{"want " + head, "deepen 1", "done"},
+			expectedLines: []string{
+				"shallow-info",
+				"shallow " + head,
+				"<delim>",
+				"packfile",
+				"<flush>",
+			},
+			expectedCount: 35,
+		},
+		{
+			desc: "should apply the filters",
+			opts: UploadPackOptions{AllowFilter: true},
+			args: []string{"want " + head, "filter blob:none", "done"},
+			expectedLines: []string{
+				"packfile",
+				"<flush>",
+			},
+			expectedCount: 83,
+		},
+		{
+			desc:        "should multiplex the whole response",
+			opts:        UploadPackOptions{AllowSidebandAll: true},
+			args:        []string{"want " + head, "have " + cleanupBranch, "sideband-all", "ofs-delta"},
+			sidebandAll: true,
+			expectedLines: []string{
+				"acknowledgments",
+				"ACK " + cleanupBranch,
+				"ready",
+				"<delim>",
+				"packfile",
+				"<flush>",
+			},
+			expectedCount: 71,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			up := newTestUploadPack(t, tc.opts)
+			out := &bytes.Buffer{}
+			err := up.ServeCommand(bytes.NewReader(newRequest(t, "fetch", tc.args...)), out)
+			require.NoError(t, err, "test %d", i)
+
+			res := parseFetchResponse(t, out.Bytes(), tc.sidebandAll)
+			assert.Equal(t, tc.expectedLines, res.lines, "test %d", i)
+			if tc.expectedCount == 0 {
+				assert.Empty(t, res.pack, "test %d", i)
+				return
+			}
+			assert.Equal(t, tc.expectedCount, res.objectCount(t), "test %d", i)
+		})
+	}
+
+	t.Run("should unshallow the deepened commits", func(t *testing.T) {
+		t.Parallel()
+
+		up := newTestUploadPack(t, UploadPackOptions{})
+		out := &bytes.Buffer{}
+		err := up.ServeCommand(bytes.NewReader(newRequest(t, "fetch",
+			"want "+head,
+			"have "+head,
+			"shallow "+head,
+			"deepen 2",
+			"done",
+		)), out)
+		require.NoError(t, err)
+
+		res := parseFetchResponse(t, out.Bytes(), false)
+		assert.Equal(t, []string{
+			"shallow-info",
+			"shallow 6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+			"unshallow " + head,
+			"<delim>",
+			"packfile",
+			"<flush>",
+		}, res.lines)
+	})
+
+	t.Run("should report the statistics of the packfile", func(t *testing.T) {
+		t.Parallel()
+
+		traces := &bytes.Buffer{}
+		var stats []FetchStats
+		up := newTestUploadPack(t, UploadPackOptions{
+			Trace: trace.NewWithWriter(traces),
+			OnPack: func(s FetchStats) {
+				stats = append(stats, s)
+			},
+		})
+		out := &bytes.Buffer{}
+		err := up.ServeCommand(bytes.NewReader(newRequest(t, "fetch", "want "+head, "ofs-delta", "done")), out)
+		require.NoError(t, err)
+
+		require.Len(t, stats, 1)
+		assert.Equal(t, 280, stats[0].Objects)
+		assert.NotZero(t, stats[0].Deltas)
+		assert.Zero(t, stats[0].ReusedDeltas)
+		assert.Contains(t, traces.String(), fmt.Sprintf("upload-pack: total 280 (delta %d), reused 0 (delta 0)", stats[0].Deltas))
+		assert.Contains(t, traces.String(), "upload-pack: counting objects: ")
+	})
+
+	errorTestCases := []struct {
+		desc string
+		args []string
+	}{
+		{
+			desc: "should fail on unknown wants",
+			args: []string{"want 0000000000000000000000000000000000000001", "done"},
+		},
+		{
+			desc: "should fail without wants",
+			args: []string{"done"},
+		},
+		{
+			desc: "should fail on filters if not allowed",
+			args: []string{"want " + head, "filter blob:none", "done"},
+		},
+		{
+			desc: "should fail on sideband-all if not allowed",
+			args: []string{"want " + head, "sideband-all", "done"},
+		},
+		{
+			desc: "should fail on unsupported arguments",
+			args: []string{"want " + head, "deepen-not refs/heads/master", "done"},
+		},
+	}
+	for i, tc := range errorTestCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			up := newTestUploadPack(t, UploadPackOptions{})
+			out := &bytes.Buffer{}
+			err := up.ServeCommand(bytes.NewReader(newRequest(t, "fetch", tc.args...)), out)
+			require.ErrorIs(t, err, ErrInvalidRequest, "test %d", i)
+			lines := readLines(t, out.Bytes())
+			require.Len(t, lines, 1, "test %d", i)
+			assert.Contains(t, lines[0], "ERR ", "test %d", i)
+		})
+	}
+}
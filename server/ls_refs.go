@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/pktline"
+)
+
+// lsRefsArgs represents the arguments of the ls-refs command
+type lsRefsArgs struct {
+	prefixes []string
+	symrefs  bool
+	peel     bool
+	unborn   bool
+}
+
+// parseLsRefsArgs parses the arguments of the ls-refs command
+func parseLsRefsArgs(args []string) (*lsRefsArgs, error) {
+	parsed := &lsRefsArgs{
+		prefixes: []string{},
+	}
+	for _, arg := range args {
+		switch {
+		case arg == "symrefs":
+			parsed.symrefs = true
+		case arg == "peel":
+			parsed.peel = true
+		case arg == "unborn":
+			parsed.unborn = true
+		case strings.HasPrefix(arg, "ref-prefix "):
+			parsed.prefixes = append(parsed.prefixes, strings.TrimPrefix(arg, "ref-prefix "))
+		default:
+			return nil, fmt.Errorf("unexpected ls-refs argument %q: %w", arg, ErrInvalidRequest)
+		}
+	}
+	return parsed, nil
+}
+
+// lsRefs lists the references of the repository that match the
+// prefixes sent by the client. Unlike the ref advertisement of the
+// protocol v0, only the requested references are sent.
+// Each reference is sent as:
+//
+//	<oid> <name>[ symref-target:<target>][ peeled:<oid>]
+func (up *UploadPack) lsRefs(rawArgs []string, out io.Writer) error {
+	args, err := parseLsRefsArgs(rawArgs)
+	if err != nil {
+		return err
+	}
+
+	refs, err := up.repo.ReferencesWithPrefix(args.prefixes)
+	if err != nil {
+		return fmt.Errorf("could not list the references: %w", err)
+	}
+
+	w := pktline.NewWriter(out)
+	headSent := false
+	for _, ref := range refs {
+		if ref.Name() != ginternals.Head && !strings.HasPrefix(ref.Name(), "refs/") {
+			continue
+		}
+		// The symbolic references targeting a reference that doesn't
+		// exist (like an unborn HEAD) have no object to advertise
+		if ref.Target().IsZero() {
+			continue
+		}
+		line := ref.Target().String() + " " + ref.Name()
+		if args.symrefs && ref.SymbolicTarget() != "" {
+			line += " symref-target:" + ref.SymbolicTarget()
+		}
+		if args.peel && !ref.Peeled.IsZero() {
+			line += " peeled:" + ref.Peeled.String()
+		}
+		if err = w.WriteLine(line); err != nil {
+			return err
+		}
+		headSent = headSent || ref.Name() == ginternals.Head
+	}
+
+	// An unborn HEAD can only be sent if the client asked for it
+	if args.unborn && !headSent && hasAnyPrefix(ginternals.Head, args.prefixes) {
+		head, err := up.repo.Head()
+		if err != nil {
+			return fmt.Errorf("could not get HEAD: %w", err)
+		}
+		if head.IsUnborn() {
+			line := "unborn " + ginternals.Head
+			if args.symrefs {
+				line += " symref-target:" + head.Branch
+			}
+			if err = w.WriteLine(line); err != nil {
+				return err
+			}
+		}
+	}
+	return w.WriteFlush()
+}
+
+// hasAnyPrefix returns whether s starts with any of the provided
+// prefixes. true is returned if there are no prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLsRefs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		args     []string
+		expected []string
+	}{
+		{
+			desc: "should list the matching references",
+			args: []string{"ref-prefix HEAD", "ref-prefix refs/tags/"},
+			expected: []string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD",
+				"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated",
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+				"<flush>",
+			},
+		},
+		{
+			desc: "should send the symrefs and the peeled tags",
+			args: []string{"symrefs", "peel", "ref-prefix HEAD", "ref-prefix refs/tags/"},
+			expected: []string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD symref-target:refs/heads/ml/packfile/tests",
+				"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated peeled:6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+				"<flush>",
+			},
+		},
+		{
+			desc: "should not send anything if nothing matches",
+			args: []string{"ref-prefix refs/nope/"},
+			expected: []string{
+				"<flush>",
+			},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			up := newTestUploadPack(t, UploadPackOptions{})
+			out := &bytes.Buffer{}
+			err := up.ServeCommand(bytes.NewReader(newRequest(t, "ls-refs", tc.args...)), out)
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, tc.expected, readLines(t, out.Bytes()), "test %d", i)
+		})
+	}
+
+	t.Run("empty repository", func(t *testing.T) {
+		t.Parallel()
+
+		newEmptyUploadPack := func(t *testing.T) *UploadPack {
+			t.Helper()
+
+			dir, cleanup := testutil.TempDir(t)
+			t.Cleanup(cleanup)
+			r, err := git.InitRepository(dir)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, r.Close(), "failed closing repo")
+			})
+			return NewUploadPack(r, UploadPackOptions{})
+		}
+
+		t.Run("should send an unborn HEAD", func(t *testing.T) {
+			t.Parallel()
+
+			up := newEmptyUploadPack(t)
+			out := &bytes.Buffer{}
+			req := newRequest(t, "ls-refs", "symrefs", "unborn", "ref-prefix HEAD", "ref-prefix refs/heads/")
+			err := up.ServeCommand(bytes.NewReader(req), out)
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				"unborn HEAD symref-target:refs/heads/master",
+				"<flush>",
+			}, readLines(t, out.Bytes()))
+		})
+
+		t.Run("should not send an unborn HEAD if not requested", func(t *testing.T) {
+			t.Parallel()
+
+			up := newEmptyUploadPack(t)
+			out := &bytes.Buffer{}
+			err := up.ServeCommand(bytes.NewReader(newRequest(t, "ls-refs", "symrefs", "ref-prefix HEAD")), out)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"<flush>"}, readLines(t, out.Bytes()))
+		})
+	})
+
+	t.Run("should fail on unknown arguments", func(t *testing.T) {
+		t.Parallel()
+
+		up := newTestUploadPack(t, UploadPackOptions{})
+		err := up.ServeCommand(bytes.NewReader(newRequest(t, "ls-refs", "nope")), &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
@@ -0,0 +1,229 @@
+// Package server contains the server side of the git protocols, used
+// to serve a repository to git clients
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+// agent is the agent sent to the clients
+const agent = "git/git-go"
+
+// Commands of the protocol v2
+const (
+	commandLsRefs = "ls-refs"
+	commandFetch  = "fetch"
+)
+
+var (
+	// ErrInvalidRequest is an error thrown when the request of a
+	// client cannot be parsed, or contains invalid values
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrUnknownCommand is an error thrown when a client requests a
+	// command that is not supported
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// UploadPackOptions represents the options used to serve a repository
+// to the clients that want to fetch from it
+type UploadPackOptions struct {
+	// AllowFilter allows the clients to omit some objects from the
+	// packfiles, to create partial clones.
+	// This is the equivalent of uploadpack.allowFilter
+	AllowFilter bool
+	// AllowSidebandAll allows the clients to request the whole
+	// response of a fetch to be multiplexed.
+	// This is the equivalent of uploadpack.allowSidebandAll
+	AllowSidebandAll bool
+	// Trace is used to trace the statistics of the packfiles sent to
+	// the clients, like the number of reused deltas, and how long it
+	// took to build them.
+	// Defaults to no traces
+	Trace *trace.Tracer
+	// OnPack is called with the statistics of every packfile sent to
+	// a client.
+	// Defaults to nothing
+	OnPack func(FetchStats)
+}
+
+// UploadPack serves a repository to the clients that want to fetch
+// from it, using the protocol v2, the same way git upload-pack does.
+// https://git-scm.com/docs/protocol-v2
+type UploadPack struct {
+	repo *git.Repository
+	opts UploadPackOptions
+}
+
+// NewUploadPack returns an UploadPack serving the given repository
+func NewUploadPack(r *git.Repository, opts UploadPackOptions) *UploadPack {
+	return &UploadPack{
+		repo: r,
+		opts: opts,
+	}
+}
+
+// request represents a command sent by a client
+type request struct {
+	command      string
+	capabilities []string
+	args         []string
+}
+
+// Serve advertises the capabilities of the server and runs the
+// commands sent by the client until the client closes the connection.
+// This is meant to be used with the stateful connections (ssh, git://,
+// or a local process)
+func (up *UploadPack) Serve(in io.Reader, out io.Writer) error {
+	if err := up.AdvertiseCapabilities(out); err != nil {
+		return err
+	}
+	r := pktline.NewReader(in)
+	for {
+		req, err := readRequest(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return up.writeError(out, err)
+		}
+		// A flush-pkt on its own means the client is done
+		if req == nil {
+			return nil
+		}
+		if err = up.run(req, out); err != nil {
+			return up.writeError(out, err)
+		}
+	}
+}
+
+// ServeCommand runs a single command sent by the client.
+// This is meant to be used with the stateless connections (http),
+// where the capabilities are advertised by a different request using
+// AdvertiseCapabilities()
+func (up *UploadPack) ServeCommand(in io.Reader, out io.Writer) error {
+	req, err := readRequest(pktline.NewReader(in))
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("no command received: %w", ErrInvalidRequest)
+		}
+		return up.writeError(out, err)
+	}
+	if req == nil {
+		return nil
+	}
+	if err = up.run(req, out); err != nil {
+		return up.writeError(out, err)
+	}
+	return nil
+}
+
+// AdvertiseCapabilities writes the capabilities of the server, which
+// are the first thing a client receives
+func (up *UploadPack) AdvertiseCapabilities(out io.Writer) error {
+	fetch := commandFetch + "=shallow wait-for-done"
+	if up.opts.AllowFilter {
+		fetch += " filter"
+	}
+	if up.opts.AllowSidebandAll {
+		fetch += " sideband-all"
+	}
+
+	w := pktline.NewWriter(out)
+	for _, line := range []string{
+		"version 2",
+		"agent=" + agent,
+		commandLsRefs + "=unborn",
+		fetch,
+		"server-option",
+		"object-format=sha1",
+	} {
+		if err := w.WriteLine(line); err != nil {
+			return err
+		}
+	}
+	return w.WriteFlush()
+}
+
+// run runs the given command
+func (up *UploadPack) run(req *request, out io.Writer) error {
+	for _, c := range req.capabilities {
+		if format := strings.TrimPrefix(c, "object-format="); format != c && format != "sha1" {
+			return fmt.Errorf("unsupported object-format %s: %w", format, ErrInvalidRequest)
+		}
+	}
+	switch req.command {
+	case commandLsRefs:
+		return up.lsRefs(req.args, out)
+	case commandFetch:
+		return up.fetch(req.args, out)
+	}
+	return fmt.Errorf("%s: %w", req.command, ErrUnknownCommand)
+}
+
+// writeError sends the given error to the client and returns it
+func (up *UploadPack) writeError(out io.Writer, err error) error {
+	// The error is already being returned, and the client may be
+	// gone anyway
+	pktline.NewWriter(out).WriteLine("ERR " + err.Error()) //nolint:errcheck // see above
+	return err
+}
+
+// readRequest reads the next command sent by a client.
+// A request looks like:
+//
+//	command=<name>
+//	<capability>*
+//	delim-pkt
+//	<arg>*
+//	flush-pkt
+//
+// nil is returned if the client sent a flush-pkt instead of a command
+func readRequest(r *pktline.Reader) (*request, error) {
+	line, ok, err := r.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	command := strings.TrimPrefix(string(line), "command=")
+	if command == string(line) {
+		return nil, fmt.Errorf("expected a command, got %q: %w", line, ErrInvalidRequest)
+	}
+
+	req := &request{
+		command:      command,
+		capabilities: []string{},
+		args:         []string{},
+	}
+	list := &req.capabilities
+	for {
+		typ, data, err := r.ReadPacket()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("truncated request: %w", ErrInvalidRequest)
+			}
+			return nil, err
+		}
+		switch typ {
+		case pktline.FlushPacket:
+			return req, nil
+		case pktline.DelimPacket:
+			if list == &req.args {
+				return nil, fmt.Errorf("unexpected delim-pkt: %w", ErrInvalidRequest)
+			}
+			list = &req.args
+		case pktline.DataPacket:
+			*list = append(*list, strings.TrimSuffix(string(data), "\n"))
+		case pktline.ResponseEndPacket:
+			return nil, fmt.Errorf("unexpected response-end-pkt: %w", ErrInvalidRequest)
+		}
+	}
+}
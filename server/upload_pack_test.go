@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestUploadPack returns an UploadPack serving a copy of the small
+// repo
+func newTestUploadPack(t *testing.T, opts UploadPackOptions) *UploadPack {
+	t.Helper()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+
+	r, err := git.OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(), "failed closing repo")
+	})
+	return NewUploadPack(r, opts)
+}
+
+// newRequest returns the payload of a command with the given
+// arguments
+func newRequest(t *testing.T, command string, args ...string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := pktline.NewWriter(buf)
+	require.NoError(t, w.WriteLine("command="+command))
+	require.NoError(t, w.WriteLine("object-format=sha1"))
+	require.NoError(t, w.WriteDelim())
+	for _, arg := range args {
+		require.NoError(t, w.WriteLine(arg))
+	}
+	require.NoError(t, w.WriteFlush())
+	return buf.Bytes()
+}
+
+// readLines returns all the data lines of the given response, with
+// "<delim>" and "<flush>" in place of the special packets
+func readLines(t *testing.T, response []byte) []string {
+	t.Helper()
+
+	lines := []string{}
+	r := pktline.NewReader(bytes.NewReader(response))
+	for {
+		typ, data, err := r.ReadPacket()
+		if err != nil {
+			return lines
+		}
+		switch typ {
+		case pktline.FlushPacket:
+			lines = append(lines, "<flush>")
+		case pktline.DelimPacket:
+			lines = append(lines, "<delim>")
+		case pktline.DataPacket:
+			lines = append(lines, string(bytes.TrimSuffix(data, []byte("\n"))))
+		}
+	}
+}
+
+func TestAdvertiseCapabilities(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		opts          UploadPackOptions
+		expectedFetch string
+	}{
+		{
+			desc:          "default options",
+			expectedFetch: "fetch=shallow wait-for-done",
+		},
+		{
+			desc: "all options",
+			opts: UploadPackOptions{
+				AllowFilter:      true,
+				AllowSidebandAll: true,
+			},
+			expectedFetch: "fetch=shallow wait-for-done filter sideband-all",
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			up := NewUploadPack(nil, tc.opts)
+			require.NoError(t, up.AdvertiseCapabilities(out), "test %d", i)
+			assert.Equal(t, []string{
+				"version 2",
+				"agent=git/git-go",
+				"ls-refs=unborn",
+				tc.expectedFetch,
+				"server-option",
+				"object-format=sha1",
+				"<flush>",
+			}, readLines(t, out.Bytes()), "test %d", i)
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should run commands until the client is done", func(t *testing.T) {
+		t.Parallel()
+
+		up := newTestUploadPack(t, UploadPackOptions{})
+		in := &bytes.Buffer{}
+		in.Write(newRequest(t, "ls-refs", "ref-prefix refs/heads/master"))
+		in.Write(newRequest(t, "ls-refs", "ref-prefix refs/tags/lightweight"))
+		require.NoError(t, pktline.NewWriter(in).WriteFlush())
+
+		out := &bytes.Buffer{}
+		require.NoError(t, up.Serve(in, out))
+		lines := readLines(t, out.Bytes())
+		require.Len(t, lines, 11)
+		assert.Equal(t, "version 2", lines[0])
+		assert.Equal(t, []string{
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master",
+			"<flush>",
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+			"<flush>",
+		}, lines[7:])
+	})
+
+	t.Run("should fail on unknown commands", func(t *testing.T) {
+		t.Parallel()
+
+		up := newTestUploadPack(t, UploadPackOptions{})
+		out := &bytes.Buffer{}
+		err := up.ServeCommand(bytes.NewReader(newRequest(t, "push")), out)
+		require.ErrorIs(t, err, ErrUnknownCommand)
+		assert.Equal(t, []string{"ERR push: unknown command"}, readLines(t, out.Bytes()))
+	})
+
+	t.Run("should fail on unsupported object formats", func(t *testing.T) {
+		t.Parallel()
+
+		up := newTestUploadPack(t, UploadPackOptions{})
+		in := &bytes.Buffer{}
+		w := pktline.NewWriter(in)
+		require.NoError(t, w.WriteLine("command=ls-refs"))
+		require.NoError(t, w.WriteLine("object-format=sha256"))
+		require.NoError(t, w.WriteFlush())
+
+		err := up.ServeCommand(in, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ShortlogOptions represents the options that can be used to
+// summarize the history of a repository
+type ShortlogOptions struct {
+	// Committer groups the commits by committer instead of author.
+	// This is the equivalent of --committer
+	Committer bool
+	// Email groups the commits by name and email instead of by name.
+	// This is the equivalent of --email
+	Email bool
+	// SortByCount sorts the entries by number of commits instead
+	// of by name.
+	// This is the equivalent of --numbered
+	SortByCount bool
+}
+
+// ShortlogEntry represents the commits of a person
+type ShortlogEntry struct {
+	Name string
+	// Email is only set when ShortlogOptions.Email is used
+	Email string
+	// Subjects contains the subjects of the commits, oldest first
+	Subjects []string
+}
+
+// String returns the identifier of the person, as it would be printed
+// by git shortlog
+func (e *ShortlogEntry) String() string {
+	if e.Email == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s <%s>", e.Name, e.Email)
+}
+
+// Shortlog groups the commits of the provided range by author (or
+// committer), the same way git shortlog does. The mailmap of the
+// repository is used to merge the different identities of a person.
+// rangeSpec is a revision, or a range ("from..to").
+// The entries are sorted by name, unless opts.SortByCount is set
+func (r *Repository) Shortlog(rangeSpec string, opts ShortlogOptions) ([]*ShortlogEntry, error) {
+	rng, err := r.ResolveRevisionRange(rangeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", rangeSpec, err)
+	}
+	mm, err := r.Mailmap()
+	if err != nil {
+		return nil, fmt.Errorf("could not load the mailmap: %w", err)
+	}
+
+	entries := map[string]*ShortlogEntry{}
+	err = r.WalkCommits(rng.Include, rng.WalkOptions(), func(c *object.Commit) error {
+		sig := c.Author()
+		if opts.Committer {
+			sig = c.Committer()
+		}
+		name, email := mm.Lookup(sig.Name, sig.Email)
+		if !opts.Email {
+			email = ""
+		}
+		e := &ShortlogEntry{Name: name, Email: email}
+		key := e.String()
+		if existing, ok := entries[key]; ok {
+			e = existing
+		} else {
+			entries[key] = e
+		}
+		e.Subjects = append(e.Subjects, shortlogSubject(c))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk the history: %w", err)
+	}
+
+	out := make([]*ShortlogEntry, 0, len(entries))
+	for _, e := range entries {
+		// The commits have been walked newest first
+		for i, j := 0, len(e.Subjects)-1; i < j; i, j = i+1, j-1 {
+			e.Subjects[i], e.Subjects[j] = e.Subjects[j], e.Subjects[i]
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	if opts.SortByCount {
+		sort.SliceStable(out, func(i, j int) bool {
+			return len(out[i].Subjects) > len(out[j].Subjects)
+		})
+	}
+	return out, nil
+}
+
+// shortlogSubject returns the subject of a commit, without the
+// "[PATCH]" prefix added by some tools
+func shortlogSubject(c *object.Commit) string {
+	subject := c.Subject()
+	if strings.HasPrefix(subject, "[PATCH") {
+		if i := strings.IndexByte(subject, ']'); i != -1 {
+			subject = strings.TrimLeft(subject[i+1:], " \t")
+		}
+	}
+	return subject
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortlog(t *testing.T) {
+	t.Parallel()
+
+	// summarize returns the identity and number of commits of each
+	// entry
+	summarize := func(entries []*ShortlogEntry) map[string]int {
+		out := make(map[string]int, len(entries))
+		for _, e := range entries {
+			out[e.String()] = len(e.Subjects)
+		}
+		return out
+	}
+
+	t.Run("should group by author", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		// generated using git shortlog HEAD
+		entries, err := r.Shortlog("HEAD", ShortlogOptions{})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "Melvin", entries[0].Name)
+		assert.Empty(t, entries[0].Email)
+		assert.Equal(t, []string{
+			"Initial commit",
+			"feat: parse loose objects and packfiles (#1)",
+			"ci: add github actions to build, test, and lint (#2)",
+			"docs(packfile): add more comments on COPY (#3)",
+			"refactor: Update codebase to go 1.13",
+		}, entries[0].Subjects)
+		assert.Equal(t, "Melvin Laplanche", entries[1].Name)
+		assert.Len(t, entries[1].Subjects, 12)
+	})
+
+	t.Run("should group by committer within a range", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		// generated using git shortlog -sce ml/cleanup-062020..HEAD
+		entries, err := r.Shortlog("ml/cleanup-062020..HEAD", ShortlogOptions{
+			Committer:   true,
+			Email:       true,
+			SortByCount: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{
+			"Melvin <Nivl@users.noreply.github.com>": 8,
+		}, summarize(entries))
+	})
+
+	t.Run("should sort by count", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		entries, err := r.Shortlog("HEAD", ShortlogOptions{SortByCount: true})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "Melvin Laplanche", entries[0].Name)
+		assert.Equal(t, "Melvin", entries[1].Name)
+	})
+
+	t.Run("should use the mailmap", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		mailmap := "Melvin Laplanche <melvin.wont.reply@gmail.com> <Nivl@users.noreply.github.com>\n"
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".mailmap"), []byte(mailmap), 0o644))
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		// generated using git shortlog -sce HEAD
+		entries, err := r.Shortlog("HEAD", ShortlogOptions{Committer: true, Email: true})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{
+			"GitHub <noreply@github.com>":                    1,
+			"Melvin Laplanche <melvin.wont.reply@gmail.com>": 16,
+		}, summarize(entries))
+	})
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/gitdate"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrNoIdentity is an error thrown when the name or the email of
+// the user cannot be found in the config or in the environment
+var ErrNoIdentity = errors.New("could not find the identity of the user")
+
+// identity contains the sources of a signature, by order of
+// precedence
+type identity struct {
+	nameEnv  string
+	emailEnv string
+	dateEnv  string
+
+	name  func(cfg *config.FileAggregate) (string, bool)
+	email func(cfg *config.FileAggregate) (string, bool)
+}
+
+// List of the supported identities
+//
+//nolint:gochecknoglobals // Treat them as const
+var (
+	authorIdentity = identity{
+		nameEnv:  "GIT_AUTHOR_NAME",
+		emailEnv: "GIT_AUTHOR_EMAIL",
+		dateEnv:  "GIT_AUTHOR_DATE",
+		name:     (*config.FileAggregate).AuthorName,
+		email:    (*config.FileAggregate).AuthorEmail,
+	}
+	committerIdentity = identity{
+		nameEnv:  "GIT_COMMITTER_NAME",
+		emailEnv: "GIT_COMMITTER_EMAIL",
+		dateEnv:  "GIT_COMMITTER_DATE",
+		name:     (*config.FileAggregate).CommitterName,
+		email:    (*config.FileAggregate).CommitterEmail,
+	}
+)
+
+// DefaultSignature returns the signatures of the author and of the
+// committer the same way git does:
+//   - The name comes from $GIT_{AUTHOR,COMMITTER}_NAME, then from
+//     {author,committer}.name, then from user.name
+//   - The email comes from $GIT_{AUTHOR,COMMITTER}_EMAIL, then from
+//     {author,committer}.email, then from user.email, then from $EMAIL
+//   - The date comes from $GIT_{AUTHOR,COMMITTER}_DATE (see
+//     gitdate.Parse() for the supported formats), and defaults to now
+//
+// ErrNoIdentity is returned if a name or an email is missing
+func (r *Repository) DefaultSignature() (author, committer object.Signature, err error) {
+	now := time.Now()
+	author, err = r.signature(authorIdentity, now)
+	if err != nil {
+		return object.Signature{}, object.Signature{}, fmt.Errorf("could not get the author: %w", err)
+	}
+	committer, err = r.signature(committerIdentity, now)
+	if err != nil {
+		return object.Signature{}, object.Signature{}, fmt.Errorf("could not get the committer: %w", err)
+	}
+	return author, committer, nil
+}
+
+// signature builds the signature of the given identity
+func (r *Repository) signature(id identity, now time.Time) (object.Signature, error) {
+	e := r.Config.Env()
+	cfg := r.Config.FromFile()
+
+	sig := object.Signature{
+		Name:  lookupIdentity(e, cfg, id.nameEnv, id.name, (*config.FileAggregate).UserName, ""),
+		Email: lookupIdentity(e, cfg, id.emailEnv, id.email, (*config.FileAggregate).UserEmail, "EMAIL"),
+		Time:  now,
+	}
+	if sig.Name == "" {
+		return object.Signature{}, fmt.Errorf("name not set: %w", ErrNoIdentity)
+	}
+	if sig.Email == "" {
+		return object.Signature{}, fmt.Errorf("email not set: %w", ErrNoIdentity)
+	}
+
+	if date := e.Get(id.dateEnv); date != "" {
+		t, err := gitdate.Parse(date)
+		if err != nil {
+			return object.Signature{}, fmt.Errorf("could not parse $%s: %w", id.dateEnv, err)
+		}
+		sig.Time = t
+	}
+	return sig, nil
+}
+
+// lookupIdentity returns the first value set in the environment or in
+// the config
+func lookupIdentity(e *env.Env, cfg *config.FileAggregate, envKey string, fromCfg, fromUserCfg func(*config.FileAggregate) (string, bool), fallbackEnvKey string) string {
+	if v := strings.TrimSpace(e.Get(envKey)); v != "" {
+		return v
+	}
+	if cfg != nil {
+		if v, ok := fromCfg(cfg); ok {
+			return v
+		}
+		if v, ok := fromUserCfg(cfg); ok {
+			return v
+		}
+	}
+	if fallbackEnvKey != "" {
+		return strings.TrimSpace(e.Get(fallbackEnvKey))
+	}
+	return ""
+}
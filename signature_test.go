@@ -0,0 +1,131 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/gitdate"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultSignature(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns a repository of the small repo using the
+	// provided env and extra local config
+	newRepo := func(t *testing.T, envVars []string, extraConfig string) *Repository {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		if extraConfig != "" {
+			f, err := os.OpenFile(filepath.Join(repoPath, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0o644)
+			require.NoError(t, err)
+			_, err = f.WriteString(extraConfig)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+		}
+
+		envVars = append(envVars, "GIT_CONFIG_NOSYSTEM=1")
+		cfg, err := config.LoadConfig(env.NewFromKVList(envVars), config.LoadConfigOptions{
+			WorkingDirectory: repoPath,
+		})
+		require.NoError(t, err)
+		r, err := OpenRepositoryWithParams(cfg, OpenOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		return r
+	}
+
+	t.Run("should use the config", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, nil, "[user]\n\tname = User\n\temail = user@domain.tld\n[committer]\n\temail = committer@domain.tld\n")
+		author, committer, err := r.DefaultSignature()
+		require.NoError(t, err)
+		assert.Equal(t, "User", author.Name)
+		assert.Equal(t, "user@domain.tld", author.Email)
+		assert.Equal(t, "User", committer.Name)
+		assert.Equal(t, "committer@domain.tld", committer.Email)
+		assert.WithinDuration(t, time.Now(), author.Time, time.Minute)
+		assert.Equal(t, author.Time, committer.Time)
+	})
+
+	t.Run("env should take precedence over the config", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, []string{
+			"GIT_AUTHOR_NAME=Author",
+			"GIT_AUTHOR_EMAIL=author@domain.tld",
+			"GIT_AUTHOR_DATE=1592616250 -0700",
+			"GIT_COMMITTER_NAME=Committer",
+			"GIT_COMMITTER_DATE=2020-06-20 01:24:10 +0000",
+		}, "[user]\n\tname = User\n\temail = user@domain.tld\n")
+		author, committer, err := r.DefaultSignature()
+		require.NoError(t, err)
+		assert.Equal(t, "Author <author@domain.tld> 1592616250 -0700", author.String())
+		assert.Equal(t, "Committer <user@domain.tld> 1592616250 +0000", committer.String())
+	})
+
+	t.Run("$EMAIL should be used as last resort", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, []string{"GIT_AUTHOR_NAME=Author", "GIT_COMMITTER_NAME=Committer", "EMAIL=email@domain.tld"}, "")
+		author, committer, err := r.DefaultSignature()
+		require.NoError(t, err)
+		assert.Equal(t, "email@domain.tld", author.Email)
+		assert.Equal(t, "email@domain.tld", committer.Email)
+	})
+
+	t.Run("should fail without identity", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, []string{"GIT_AUTHOR_NAME=Author"}, "")
+		_, _, err := r.DefaultSignature()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrNoIdentity), "unexpected error: %v", err)
+	})
+
+	t.Run("should fail with an invalid date", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, []string{"GIT_AUTHOR_DATE=not a date"}, "[user]\n\tname = User\n\temail = user@domain.tld\n")
+		_, _, err := r.DefaultSignature()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, gitdate.ErrInvalidDate), "unexpected error: %v", err)
+	})
+
+	t.Run("NewCommit should use the default signatures", func(t *testing.T) {
+		t.Parallel()
+
+		r := newRepo(t, []string{
+			"GIT_AUTHOR_DATE=1592616250 -0700",
+			"GIT_COMMITTER_NAME=Committer",
+		}, "[user]\n\tname = User\n\temail = user@domain.tld\n")
+		oid, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		head, err := r.Commit(oid)
+		require.NoError(t, err)
+		tree, err := r.Tree(head.TreeID())
+		require.NoError(t, err)
+
+		opts := &object.CommitOptions{Message: "message\n"}
+		c, err := r.NewDetachedCommit(tree, object.Signature{}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "User <user@domain.tld> 1592616250 -0700", c.Author().String())
+		assert.Equal(t, "Committer", c.Committer().Name)
+		assert.True(t, opts.Committer.IsZero(), "the options should not have been updated")
+	})
+}
@@ -0,0 +1,170 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// stashRefName contains the name of the reference that points to the
+// latest stash. The other stashes are only stored in its reflog
+const stashRefName = "refs/stash"
+
+// ErrStashNotFound is an error thrown when trying to access a stash
+// that doesn't exist
+var ErrStashNotFound = errors.New("stash not found")
+
+// StashEntry represents a stash, as created by git stash
+// A stash is made of up to 3 commits:
+//   - The stash commit, that contains the state of the working tree
+//     and has the other commits as parents
+//   - The index commit, that contains the state of the index
+//   - The untracked commit, that contains the untracked files. This
+//     commit is only created by git stash --include-untracked
+type StashEntry struct {
+	// Index contains the position of the stash in the list, 0 being
+	// the most recent one
+	Index int
+	// Message contains the description of the stash, as displayed
+	// by git stash list
+	Message string
+	// Branch contains the name of the branch the stash was created
+	// on, or "(no branch)" if HEAD was detached
+	Branch string
+	// Commit contains the state of the working tree
+	Commit *object.Commit
+	// BaseCommit contains the commit HEAD was pointing to when the
+	// stash was created
+	BaseCommit *object.Commit
+	// IndexCommit contains the state of the index
+	IndexCommit *object.Commit
+	// UntrackedCommit contains the untracked files, if any.
+	// It's nil if the stash doesn't contain untracked files
+	UntrackedCommit *object.Commit
+}
+
+// Name returns the name of the stash, as used by git (stash@{n})
+func (e *StashEntry) Name() string {
+	return fmt.Sprintf("stash@{%d}", e.Index)
+}
+
+// Stashes returns all the stashes of the repository, the most
+// recent first
+func (r *Repository) Stashes() ([]*StashEntry, error) {
+	entries, err := r.dotGit.Reflog(stashRefName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the stash list: %w", err)
+	}
+
+	stashes := make([]*StashEntry, 0, len(entries))
+	// The reflog is ordered oldest first
+	for i := len(entries) - 1; i >= 0; i-- {
+		s, err := r.stashEntry(len(stashes), entries[i].New, entries[i].Message)
+		if err != nil {
+			return nil, err
+		}
+		stashes = append(stashes, s)
+	}
+	return stashes, nil
+}
+
+// Stash returns the stash at the given position, 0 being the most
+// recent one. ErrStashNotFound is returned if the stash doesn't exist
+func (r *Repository) Stash(index int) (*StashEntry, error) {
+	entries, err := r.dotGit.Reflog(stashRefName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the stash list: %w", err)
+	}
+	if index < 0 || index >= len(entries) {
+		return nil, fmt.Errorf("stash@{%d}: %w", index, ErrStashNotFound)
+	}
+	e := entries[len(entries)-1-index]
+	return r.stashEntry(index, e.New, e.Message)
+}
+
+// stashEntry loads all the commits of a stash
+func (r *Repository) stashEntry(index int, oid ginternals.Oid, message string) (*StashEntry, error) {
+	s := &StashEntry{
+		Index:   index,
+		Message: message,
+		Branch:  stashBranch(message),
+	}
+
+	var err error
+	s.Commit, err = r.Commit(oid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the commit of %s: %w", s.Name(), err)
+	}
+	parents := s.Commit.ParentIDs()
+	if len(parents) < 2 {
+		return nil, fmt.Errorf("%s has %d parents, expected at least 2: %w", s.Name(), len(parents), object.ErrCommitInvalid)
+	}
+	s.BaseCommit, err = r.Commit(parents[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not get the base commit of %s: %w", s.Name(), err)
+	}
+	s.IndexCommit, err = r.Commit(parents[1])
+	if err != nil {
+		return nil, fmt.Errorf("could not get the index commit of %s: %w", s.Name(), err)
+	}
+	if len(parents) > 2 {
+		s.UntrackedCommit, err = r.Commit(parents[2])
+		if err != nil {
+			return nil, fmt.Errorf("could not get the untracked commit of %s: %w", s.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+// stashBranch extracts the name of the branch from the message of
+// a stash. git uses "WIP on branch: sha subject" when no message is
+// provided, and "On branch: message" otherwise
+func stashBranch(message string) string {
+	branch := strings.TrimPrefix(message, "WIP ")
+	if !strings.HasPrefix(branch, "On ") && !strings.HasPrefix(branch, "on ") {
+		return ""
+	}
+	branch = branch[len("on "):]
+	if i := strings.Index(branch, ": "); i != -1 {
+		return branch[:i]
+	}
+	return strings.TrimSuffix(branch, ":")
+}
+
+// StashDiffOptions represents the options that can be used to
+// compute the changes of a stash
+type StashDiffOptions struct {
+	DiffOptions
+	// IncludeUntracked adds the untracked files of the stash to the
+	// changes, as new files.
+	// This is the equivalent of --include-untracked
+	IncludeUntracked bool
+}
+
+// StashDiff returns the changes stored in a stash, compared to the
+// commit the stash was created on.
+// This is the equivalent of git stash show -p
+func (r *Repository) StashDiff(s *StashEntry, opts StashDiffOptions) ([]*diff.FilePatch, error) {
+	patches, err := r.DiffTrees(s.BaseCommit.TreeID(), s.Commit.TreeID(), opts.DiffOptions)
+	if err != nil {
+		return nil, fmt.Errorf("could not diff %s: %w", s.Name(), err)
+	}
+	if !opts.IncludeUntracked || s.UntrackedCommit == nil {
+		return patches, nil
+	}
+
+	untracked, err := r.DiffTrees(ginternals.NullOid, s.UntrackedCommit.TreeID(), opts.DiffOptions)
+	if err != nil {
+		return nil, fmt.Errorf("could not diff the untracked files of %s: %w", s.Name(), err)
+	}
+	patches = append(patches, untracked...)
+	sort.SliceStable(patches, func(i, j int) bool {
+		return patches[i].Path() < patches[j].Path()
+	})
+	return patches, nil
+}
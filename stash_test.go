@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStashes(t *testing.T) {
+	t.Parallel()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	r, err := OpenRepository(repoPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	t.Run("should list the stashes", func(t *testing.T) {
+		t.Parallel()
+
+		stashes, err := r.Stashes()
+		require.NoError(t, err)
+		require.Len(t, stashes, 1)
+
+		s := stashes[0]
+		assert.Equal(t, "stash@{0}", s.Name())
+		assert.Equal(t, "WIP on tests: f0f7014 refactor: Update codebase to go 1.13", s.Message)
+		assert.Equal(t, "tests", s.Branch)
+		assert.Equal(t, "3fe6cf63fceced491a79fe634eb1e2c888225707", s.Commit.ID().String())
+		assert.Equal(t, "f0f70144f38695250606b86a50cff2b440a417f3", s.BaseCommit.ID().String())
+		assert.Equal(t, "897e67ddbb71754c15d0dd106a1ba81a80df3b13", s.IndexCommit.ID().String())
+		assert.Nil(t, s.UntrackedCommit)
+	})
+
+	t.Run("should fail on a stash that doesn't exist", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := r.Stash(1)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrStashNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("should return the changes of a stash", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := r.Stash(0)
+		require.NoError(t, err)
+		patches, err := r.StashDiff(s, StashDiffOptions{DiffOptions: DefaultDiffOptions()})
+		require.NoError(t, err)
+		require.NotEmpty(t, patches)
+
+		// generated using git stash show -p
+		assert.Equal(t, "vendor/github.com/davecgh/go-spew/LICENSE", patches[0].Path())
+		assert.Nil(t, patches[0].To, "the file should have been deleted")
+	})
+}
+
+func TestStashBranch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		message  string
+		expected string
+	}{
+		{message: "WIP on main: f0f7014 subject", expected: "main"},
+		{message: "On ml/tests: my message", expected: "ml/tests"},
+		{message: "WIP on (no branch): f0f7014 subject", expected: "(no branch)"},
+		{message: "unexpected format", expected: ""},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d/%s", i, tc.message), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, stashBranch(tc.message))
+		})
+	}
+}
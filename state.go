@@ -0,0 +1,233 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/spf13/afero"
+)
+
+// List of the files and directories of the git directory used to
+// keep track of the operations in progress
+const (
+	rebaseMergeDirName = "rebase-merge"
+	rebaseApplyDirName = "rebase-apply"
+	revertHeadFileName = "REVERT_HEAD"
+	bisectLogFileName  = "BISECT_LOG"
+	sequencerTodoPath  = "sequencer/todo"
+)
+
+// Operation represents an operation that is in progress in a
+// repository
+type Operation int8
+
+// List of the operations that can be in progress
+const (
+	// OperationNone means that no operation is in progress
+	OperationNone Operation = iota
+	// OperationMerging means that a merge is in progress
+	OperationMerging
+	// OperationRebasing means that a rebase is in progress
+	OperationRebasing
+	// OperationApplyingMailbox means that git am is in progress
+	OperationApplyingMailbox
+	// OperationApplyingMailboxOrRebasing means that either git am or
+	// a rebase is in progress, but git didn't record which one
+	OperationApplyingMailboxOrRebasing
+	// OperationCherryPicking means that a cherry-pick is in progress
+	OperationCherryPicking
+	// OperationReverting means that a revert is in progress
+	OperationReverting
+	// OperationBisecting means that a bisect session is in progress
+	OperationBisecting
+)
+
+// String returns the name of the operation, as displayed by the
+// git prompt (__git_ps1). An empty string is returned for
+// OperationNone
+func (o Operation) String() string {
+	switch o {
+	case OperationMerging:
+		return "MERGING"
+	case OperationRebasing:
+		return "REBASE"
+	case OperationApplyingMailbox:
+		return "AM"
+	case OperationApplyingMailboxOrRebasing:
+		return "AM/REBASE"
+	case OperationCherryPicking:
+		return "CHERRY-PICKING"
+	case OperationReverting:
+		return "REVERTING"
+	case OperationBisecting:
+		return "BISECTING"
+	default:
+		return ""
+	}
+}
+
+// RepositoryState represents the state of a repository
+type RepositoryState struct {
+	Operation Operation
+	// Step contains the number of the patch being applied by a rebase
+	// or git am. It's 0 if unknown
+	Step int
+	// Total contains the total number of patches to apply by a rebase
+	// or git am. It's 0 if unknown
+	Total int
+	// HeadName contains the name of the branch being rebased, or an
+	// empty string if unknown or detached
+	HeadName string
+}
+
+// State returns the operation currently in progress in the
+// repository, using the same rules as the git prompt (__git_ps1)
+func (r *Repository) State() (*RepositoryState, error) {
+	fs := r.Config.FS
+	gitDir := r.Config.GitDirPath
+	exists := func(name string) (bool, error) {
+		_, err := fs.Stat(filepath.Join(gitDir, filepath.FromSlash(name)))
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check %s: %w", name, err)
+	}
+	read := func(name string) (string, error) {
+		data, err := afero.ReadFile(fs, filepath.Join(gitDir, filepath.FromSlash(name)))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", nil
+			}
+			return "", fmt.Errorf("could not read %s: %w", name, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	readInt := func(name string) (int, error) {
+		s, err := read(name)
+		if err != nil || s == "" {
+			return 0, err
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number in %s: %w", name, err)
+		}
+		return n, nil
+	}
+	headName := func(name string) (string, error) {
+		s, err := read(name)
+		if err != nil {
+			return "", err
+		}
+		if s == "detached HEAD" {
+			return "", nil
+		}
+		return s, nil
+	}
+
+	state := &RepositoryState{}
+
+	// Rebases using the merge backend
+	ok, err := exists(rebaseMergeDirName)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		state.Operation = OperationRebasing
+		if state.HeadName, err = headName(rebaseMergeDirName + "/head-name"); err != nil {
+			return nil, err
+		}
+		if state.Step, err = readInt(rebaseMergeDirName + "/msgnum"); err != nil {
+			return nil, err
+		}
+		if state.Total, err = readInt(rebaseMergeDirName + "/end"); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
+
+	// git am, and rebases using the apply backend
+	if ok, err = exists(rebaseApplyDirName); err != nil {
+		return nil, err
+	}
+	if ok {
+		if state.Step, err = readInt(rebaseApplyDirName + "/next"); err != nil {
+			return nil, err
+		}
+		if state.Total, err = readInt(rebaseApplyDirName + "/last"); err != nil {
+			return nil, err
+		}
+		rebasing, err := exists(rebaseApplyDirName + "/rebasing")
+		if err != nil {
+			return nil, err
+		}
+		applying, err := exists(rebaseApplyDirName + "/applying")
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case rebasing:
+			state.Operation = OperationRebasing
+			if state.HeadName, err = headName(rebaseApplyDirName + "/head-name"); err != nil {
+				return nil, err
+			}
+		case applying:
+			state.Operation = OperationApplyingMailbox
+		default:
+			state.Operation = OperationApplyingMailboxOrRebasing
+		}
+		return state, nil
+	}
+
+	// The other operations are only identified by the presence of a
+	// file
+	markers := []struct {
+		name string
+		op   Operation
+	}{
+		{name: ginternals.MergeHead, op: OperationMerging},
+		{name: ginternals.CherryPickHead, op: OperationCherryPicking},
+		{name: revertHeadFileName, op: OperationReverting},
+	}
+	for _, m := range markers {
+		if ok, err = exists(m.name); err != nil {
+			return nil, err
+		}
+		if ok {
+			state.Operation = m.op
+			return state, nil
+		}
+	}
+
+	// A cherry-pick or revert of multiple commits may be paused
+	// between 2 commits, in which case only the sequencer knows about it
+	todo, err := read(sequencerTodoPath)
+	if err != nil {
+		return nil, err
+	}
+	if fields := strings.Fields(todo); len(fields) > 0 {
+		switch fields[0] {
+		case "p", "pick":
+			state.Operation = OperationCherryPicking
+			return state, nil
+		case "revert":
+			state.Operation = OperationReverting
+			return state, nil
+		}
+	}
+
+	if ok, err = exists(bisectLogFileName); err != nil {
+		return nil, err
+	}
+	if ok {
+		state.Operation = OperationBisecting
+	}
+	return state, nil
+}
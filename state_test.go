@@ -0,0 +1,140 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		files    map[string]string
+		expected RepositoryState
+	}{
+		{
+			desc:     "no operation in progress",
+			expected: RepositoryState{Operation: OperationNone},
+		},
+		{
+			desc: "interactive rebase",
+			files: map[string]string{
+				"rebase-merge/head-name":   "refs/heads/feature\n",
+				"rebase-merge/msgnum":      "2\n",
+				"rebase-merge/end":         "5\n",
+				"rebase-merge/interactive": "",
+			},
+			expected: RepositoryState{Operation: OperationRebasing, Step: 2, Total: 5, HeadName: "refs/heads/feature"},
+		},
+		{
+			desc: "rebase using the apply backend on a detached HEAD",
+			files: map[string]string{
+				"rebase-apply/rebasing":  "",
+				"rebase-apply/head-name": "detached HEAD\n",
+				"rebase-apply/next":      "1\n",
+				"rebase-apply/last":      "3\n",
+			},
+			expected: RepositoryState{Operation: OperationRebasing, Step: 1, Total: 3},
+		},
+		{
+			desc: "am",
+			files: map[string]string{
+				"rebase-apply/applying": "",
+				"rebase-apply/next":     "1\n",
+				"rebase-apply/last":     "1\n",
+			},
+			expected: RepositoryState{Operation: OperationApplyingMailbox, Step: 1, Total: 1},
+		},
+		{
+			desc: "am or rebase",
+			files: map[string]string{
+				"rebase-apply/next": "1\n",
+			},
+			expected: RepositoryState{Operation: OperationApplyingMailboxOrRebasing, Step: 1},
+		},
+		{
+			desc: "merge",
+			files: map[string]string{
+				"MERGE_HEAD": "bbb720a96e4c29b9950a4c577c98470a4d5dd089\n",
+				"BISECT_LOG": "",
+			},
+			expected: RepositoryState{Operation: OperationMerging},
+		},
+		{
+			desc: "cherry-pick",
+			files: map[string]string{
+				"CHERRY_PICK_HEAD": "bbb720a96e4c29b9950a4c577c98470a4d5dd089\n",
+			},
+			expected: RepositoryState{Operation: OperationCherryPicking},
+		},
+		{
+			desc: "revert",
+			files: map[string]string{
+				"REVERT_HEAD": "bbb720a96e4c29b9950a4c577c98470a4d5dd089\n",
+			},
+			expected: RepositoryState{Operation: OperationReverting},
+		},
+		{
+			desc: "paused cherry-pick of multiple commits",
+			files: map[string]string{
+				"sequencer/todo": "pick bbb720a Add tests\n",
+			},
+			expected: RepositoryState{Operation: OperationCherryPicking},
+		},
+		{
+			desc: "paused revert of multiple commits",
+			files: map[string]string{
+				"sequencer/todo": "revert bbb720a Add tests\n",
+			},
+			expected: RepositoryState{Operation: OperationReverting},
+		},
+		{
+			desc: "bisect",
+			files: map[string]string{
+				"BISECT_LOG": "git bisect start\n",
+			},
+			expected: RepositoryState{Operation: OperationBisecting},
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			dir, cleanup := testutil.TempDir(t)
+			t.Cleanup(cleanup)
+			r, err := InitRepository(dir)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, r.Close())
+			})
+
+			for name, content := range tc.files {
+				p := filepath.Join(r.Config.GitDirPath, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+				require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+			}
+
+			state, err := r.State()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, *state)
+		})
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", OperationNone.String())
+	assert.Equal(t, "REBASE", OperationRebasing.String())
+	assert.Equal(t, "AM/REBASE", OperationApplyingMailboxOrRebasing.String())
+	assert.Equal(t, "CHERRY-PICKING", OperationCherryPicking.String())
+}
@@ -0,0 +1,427 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/pathutil"
+	"github.com/spf13/afero"
+)
+
+// FileStatus represents how a file of the working tree differs from
+// the file in HEAD
+type FileStatus int8
+
+// List of all the possible statuses
+const (
+	// StatusModified represents a file which content changed
+	StatusModified FileStatus = iota + 1
+	// StatusModeChanged represents a file which content didn't change,
+	// but which executable bit did
+	StatusModeChanged
+	// StatusTypeChanged represents a file that changed type, like a
+	// regular file replaced by a symbolic link
+	StatusTypeChanged
+	// StatusDeleted represents a file that has been removed from the
+	// working tree
+	StatusDeleted
+	// StatusUntracked represents a file of the working tree that is
+	// not in HEAD
+	StatusUntracked
+)
+
+// String returns the letter used by git status --short to
+// represent the status
+func (s FileStatus) String() string {
+	switch s {
+	case StatusModified, StatusModeChanged:
+		return "M"
+	case StatusTypeChanged:
+		return "T"
+	case StatusDeleted:
+		return "D"
+	case StatusUntracked:
+		return "?"
+	default:
+		return "unknown"
+	}
+}
+
+// StatusEntry represents a file of the working tree that is different
+// from HEAD
+type StatusEntry struct {
+	// Path contains the path of the file, relative to the root of the
+	// working tree, using / as separator
+	Path   string
+	Status FileStatus
+	// HeadMode contains the mode of the file in HEAD, 0 if the file
+	// is untracked
+	HeadMode object.TreeObjectMode
+	// WorktreeMode contains the mode of the file in the working tree,
+	// 0 if the file has been deleted
+	WorktreeMode object.TreeObjectMode
+}
+
+// worktreeOptions contains the config used to read the files of the
+// working tree
+type worktreeOptions struct {
+	// fileMode is false when the executable bit of the files cannot
+	// be trusted (core.fileMode)
+	fileMode bool
+	// symlinks is false when the symbolic links are checked out as
+	// regular files (core.symlinks)
+	symlinks bool
+	// precomposeUnicode is true when the paths of the working tree
+	// have to be converted to NFC (core.precomposeUnicode)
+	precomposeUnicode bool
+	// protectWindows is true when the names that cannot be used on
+	// Windows must be refused
+	protectWindows bool
+	// ignoreCase is true when the filesystem is case-insensitive
+	// (core.ignoreCase)
+	ignoreCase bool
+}
+
+// worktreeOptions returns the config used to read and write the files
+// of the working tree
+func (r *Repository) worktreeOptions() worktreeOptions {
+	cfg := r.Config.FromFile()
+	opts := worktreeOptions{}
+
+	var ok bool
+	if opts.fileMode, ok = cfg.FileMode(); !ok {
+		opts.fileMode = true
+	}
+	if opts.symlinks, ok = cfg.Symlinks(); !ok {
+		// Windows needs special permissions to create symbolic links,
+		// so git disables them by default
+		opts.symlinks = runtime.GOOS != "windows"
+	}
+	if _, ok := r.workTree.(afero.Linker); !ok {
+		opts.symlinks = false
+	}
+	opts.precomposeUnicode, _ = cfg.PrecomposeUnicode()
+	opts.ignoreCase, _ = cfg.IgnoreCase()
+	opts.protectWindows = runtime.GOOS == "windows"
+	return opts
+}
+
+// worktreeMode returns the mode to use in git for a file of the
+// working tree. known contains the mode currently recorded in git,
+// or 0 if the file is not tracked:
+//   - If core.fileMode is false, the executable bit of a regular file
+//     is ignored and the known mode is kept. New files are never
+//     executable
+//   - If core.symlinks is false, a regular file that is known to be
+//     a symbolic link stays a symbolic link
+func worktreeMode(info os.FileInfo, known object.TreeObjectMode, opts worktreeOptions) object.TreeObjectMode {
+	switch {
+	case info.IsDir():
+		// A directory can only be tracked as a gitlink, otherwise we
+		// just go through its content
+		return object.ModeDirectory
+	case info.Mode()&os.ModeSymlink != 0:
+		return object.ModeSymLink
+	case !opts.symlinks && known == object.ModeSymLink:
+		return object.ModeSymLink
+	case !opts.fileMode && (known == object.ModeFile || known == object.ModeExecutable):
+		return known
+	case !opts.fileMode:
+		return object.ModeFile
+	case info.Mode().Perm()&0o100 != 0:
+		return object.ModeExecutable
+	default:
+		return object.ModeFile
+	}
+}
+
+// worktreeBlob returns the content of the given file of the working
+// tree as a blob
+func (r *Repository) worktreeBlob(p string, mode object.TreeObjectMode) (*object.Object, error) {
+	if mode == object.ModeSymLink {
+		// The file may be a regular file if symlinks are not supported
+		if reader, ok := r.workTree.(afero.LinkReader); ok {
+			if info, err := r.lstatWorktreePath(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				target, err := reader.ReadlinkIfPossible(p)
+				if err != nil {
+					return nil, fmt.Errorf("could not read the link %s: %w", p, err)
+				}
+				return object.New(object.TypeBlob, []byte(filepath.ToSlash(target))), nil
+			}
+		}
+	}
+	data, err := afero.ReadFile(r.workTree, p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", p, err)
+	}
+	return object.New(object.TypeBlob, data), nil
+}
+
+// HashWorktreeFile stores the given file of the working tree in the
+// odb, and returns its entry, which Path is the given path, converted
+// to NFC if core.precomposeUnicode is true.
+// knownMode contains the mode of the file currently recorded in git,
+// or 0 if the file is new. Like git add, the known mode is kept if
+// core.fileMode is false and only the executable bit may have changed.
+// The path is relative to the root of the working tree.
+// ErrInvalidPath is returned if the path goes through a symbolic link,
+// or targets something outside of the working tree or in a .git
+// directory.
+// ErrBareRepository is returned if the repository is bare
+func (r *Repository) HashWorktreeFile(p string, knownMode object.TreeObjectMode) (object.TreeEntry, error) {
+	if r.IsBare() {
+		return object.TreeEntry{}, ErrBareRepository
+	}
+	if err := pathutil.VerifyPath(r.workTree, r.Config.WorkTreePath, p); err != nil {
+		return object.TreeEntry{}, fmt.Errorf("%s: %w", err.Error(), ErrInvalidPath)
+	}
+	fullPath := filepath.Join(r.Config.WorkTreePath, filepath.FromSlash(p))
+	info, err := r.lstatWorktreePath(fullPath)
+	if err != nil {
+		return object.TreeEntry{}, fmt.Errorf("could not stat %s: %w", p, err)
+	}
+	opts := r.worktreeOptions()
+	mode := worktreeMode(info, knownMode, opts)
+	if mode == object.ModeDirectory {
+		return object.TreeEntry{}, fmt.Errorf("%s: %w", p, ErrNotAFile)
+	}
+	o, err := r.worktreeBlob(fullPath, mode)
+	if err != nil {
+		return object.TreeEntry{}, err
+	}
+	if _, err = r.dotGit.WriteObject(o); err != nil {
+		return object.TreeEntry{}, fmt.Errorf("could not store %s: %w", p, err)
+	}
+	if opts.precomposeUnicode {
+		p = pathutil.Precompose(p)
+	}
+	return object.TreeEntry{
+		Path: p,
+		ID:   o.ID(),
+		Mode: mode,
+	}, nil
+}
+
+// Status returns the files of the working tree that are different
+// from HEAD, sorted by path.
+// The files of the untracked directories are all listed one by one,
+// and the content of the submodules is not checked.
+// When core.ignoreCase is true, a file which case changed is reported
+// using its path in HEAD.
+// ErrBareRepository is returned if the repository is bare
+func (r *Repository) Status() ([]*StatusEntry, error) {
+	if r.IsBare() {
+		return nil, ErrBareRepository
+	}
+	head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	tracked := map[string]object.TreeEntry{}
+	if !head.IsUnborn() {
+		commit, err := r.Commit(head.Target)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the commit of HEAD: %w", err)
+		}
+		if err = r.flattenTree(commit.TreeID(), "", tracked); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := r.worktreeOptions()
+	files := []worktreeFile{}
+	err = r.walkWorktree("", "", tracked, opts, func(p, diskPath string, info os.FileInfo) error {
+		files = append(files, worktreeFile{path: p, diskPath: diskPath, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	matchTrackedFiles(files, tracked, opts.ignoreCase)
+
+	entries := []*StatusEntry{}
+	seen := make(map[string]struct{}, len(tracked))
+	for _, f := range files {
+		if f.trackedPath == "" {
+			entries = append(entries, &StatusEntry{
+				Path:         f.path,
+				Status:       StatusUntracked,
+				WorktreeMode: worktreeMode(f.info, 0, opts),
+			})
+			continue
+		}
+		seen[f.trackedPath] = struct{}{}
+		entry, err := r.statusEntry(f, tracked[f.trackedPath], opts)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	for p, e := range tracked {
+		if _, ok := seen[p]; !ok {
+			entries = append(entries, &StatusEntry{
+				Path:     p,
+				Status:   StatusDeleted,
+				HeadMode: e.Mode,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// worktreeFile represents a file of the working tree
+type worktreeFile struct {
+	info os.FileInfo
+	// path contains the path of the file as known by git
+	path string
+	// diskPath contains the path of the file on the disk
+	diskPath string
+	// trackedPath contains the path of the file in HEAD, which may
+	// have a different case than path if core.ignoreCase is true.
+	// Empty if the file is untracked
+	trackedPath string
+}
+
+// matchTrackedFiles sets the trackedPath of the files of the working
+// tree that are tracked.
+// When ignoreCase is true, a file that doesn't match any tracked
+// file can match a tracked file using a different case, as long as
+// the tracked file doesn't exist with its own case. This is what git
+// does with core.ignoreCase, so a file renamed only by case on a
+// case-insensitive filesystem (README.md to readme.md) is still
+// considered to be the same file
+func matchTrackedFiles(files []worktreeFile, tracked map[string]object.TreeEntry, ignoreCase bool) {
+	matched := make(map[string]struct{}, len(files))
+	for i, f := range files {
+		if _, ok := tracked[f.path]; ok {
+			files[i].trackedPath = f.path
+			matched[f.path] = struct{}{}
+		}
+	}
+	if !ignoreCase {
+		return
+	}
+
+	folded := make(map[string]string, len(tracked))
+	for p := range tracked {
+		if _, ok := matched[p]; !ok {
+			folded[strings.ToLower(p)] = p
+		}
+	}
+	for i, f := range files {
+		if f.trackedPath != "" {
+			continue
+		}
+		key := strings.ToLower(f.path)
+		if p, ok := folded[key]; ok {
+			files[i].trackedPath = p
+			// A tracked file can only match one file
+			delete(folded, key)
+		}
+	}
+}
+
+// statusEntry returns the status of a tracked file of the working
+// tree, or nil if the file didn't change
+func (r *Repository) statusEntry(f worktreeFile, known object.TreeEntry, opts worktreeOptions) (*StatusEntry, error) {
+	mode := worktreeMode(f.info, known.Mode, opts)
+	entry := &StatusEntry{
+		Path:         f.trackedPath,
+		HeadMode:     known.Mode,
+		WorktreeMode: mode,
+	}
+	switch {
+	case known.Mode == object.ModeGitLink && mode == object.ModeDirectory:
+		// The content of the submodules is not checked
+		return nil, nil
+	case fileType(known.Mode) != fileType(mode):
+		entry.Status = StatusTypeChanged
+		return entry, nil
+	}
+
+	o, err := r.worktreeBlob(f.diskPath, mode)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case o.ID() != known.ID:
+		entry.Status = StatusModified
+	case mode != known.Mode:
+		entry.Status = StatusModeChanged
+	default:
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// flattenTree adds to entries all the files of the given tree,
+// indexed by their path. Gitlinks are considered files
+func (r *Repository) flattenTree(oid ginternals.Oid, basePath string, entries map[string]object.TreeEntry) error {
+	treeEntries, err := r.treeEntries(oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range treeEntries {
+		e.Path = joinTreePath(basePath, e.Path)
+		if e.Mode == object.ModeDirectory {
+			if err = r.flattenTree(e.ID, e.Path, entries); err != nil {
+				return err
+			}
+			continue
+		}
+		entries[e.Path] = e
+	}
+	return nil
+}
+
+// walkWorktree calls f for each file of the given directory of the
+// working tree, recursively. The .git entries are skipped, and the
+// directories of the submodules are given to f instead of being
+// walked.
+// dir is the path of the directory as known by git, and diskPath is
+// its path on the disk. Both are different if the path contains
+// unicode characters that have to be precomposed
+func (r *Repository) walkWorktree(dir, diskPath string, tracked map[string]object.TreeEntry, opts worktreeOptions, f func(p, diskPath string, info os.FileInfo) error) error {
+	if diskPath == "" {
+		diskPath = r.Config.WorkTreePath
+	}
+	infos, err := afero.ReadDir(r.workTree, diskPath)
+	if err != nil {
+		return fmt.Errorf("could not read the directory %s: %w", dir, err)
+	}
+	for _, info := range infos {
+		if info.Name() == config.DefaultDotGitDirName {
+			continue
+		}
+		name := info.Name()
+		if opts.precomposeUnicode {
+			name = pathutil.Precompose(name)
+		}
+		p := path.Join(dir, name)
+		fileDiskPath := filepath.Join(diskPath, info.Name())
+		if info.IsDir() && tracked[p].Mode != object.ModeGitLink {
+			if err = r.walkWorktree(p, fileDiskPath, tracked, opts, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = f(p, fileDiskPath, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
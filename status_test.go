@@ -0,0 +1,317 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatusTestRepo returns a repository using the given config,
+// which HEAD contains all the types of entry, and which working tree
+// matches HEAD
+func newStatusTestRepo(t *testing.T, config string) *Repository {
+	t.Helper()
+
+	r, tree := newWorktreeTestRepo(t, config)
+	head, err := r.Head()
+	require.NoError(t, err)
+	_, err = r.NewCommit(head.Branch, tree, object.NewSignature("author", "author@domain.tld"), &object.CommitOptions{
+		Message: "initial commit",
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.CheckoutTree(tree))
+	return r
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the executable bit is not supported on Windows")
+	}
+
+	t.Run("should return nothing on a clean working tree", func(t *testing.T) {
+		t.Parallel()
+
+		r := newStatusTestRepo(t, "")
+		entries, err := r.Status()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("should return all the changes", func(t *testing.T) {
+		t.Parallel()
+
+		r := newStatusTestRepo(t, "")
+		root := r.Config.WorkTreePath
+		require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("new content\n"), 0o644))
+		require.NoError(t, os.Chmod(filepath.Join(root, "exec"), 0o644))
+		require.NoError(t, os.Remove(filepath.Join(root, "dir", "nested")))
+		require.NoError(t, os.Remove(filepath.Join(root, "link")))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "link"), []byte("file"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "untracked"), []byte("untracked"), 0o644))
+
+		entries, err := r.Status()
+		require.NoError(t, err)
+		assert.Equal(t, []*StatusEntry{
+			{Path: "dir/nested", Status: StatusDeleted, HeadMode: object.ModeExecutable},
+			{Path: "dir/untracked", Status: StatusUntracked, WorktreeMode: object.ModeFile},
+			{Path: "exec", Status: StatusModeChanged, HeadMode: object.ModeExecutable, WorktreeMode: object.ModeFile},
+			{Path: "file", Status: StatusModified, HeadMode: object.ModeFile, WorktreeMode: object.ModeFile},
+			{Path: "link", Status: StatusTypeChanged, HeadMode: object.ModeSymLink, WorktreeMode: object.ModeFile},
+		}, entries)
+	})
+
+	t.Run("should ignore the executable bit if core.fileMode is false", func(t *testing.T) {
+		t.Parallel()
+
+		r := newStatusTestRepo(t, "[core]\n\tfilemode = false\n")
+		root := r.Config.WorkTreePath
+		require.NoError(t, os.Chmod(filepath.Join(root, "exec"), 0o644))
+		require.NoError(t, os.Chmod(filepath.Join(root, "file"), 0o755))
+
+		entries, err := r.Status()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		// Changes of content should still be detected
+		require.NoError(t, os.WriteFile(filepath.Join(root, "exec"), []byte("new content\n"), 0o644))
+		entries, err = r.Status()
+		require.NoError(t, err)
+		assert.Equal(t, []*StatusEntry{
+			{Path: "exec", Status: StatusModified, HeadMode: object.ModeExecutable, WorktreeMode: object.ModeExecutable},
+		}, entries)
+	})
+
+	t.Run("should not report symlinks checked out as files if core.symlinks is false", func(t *testing.T) {
+		t.Parallel()
+
+		r := newStatusTestRepo(t, "[core]\n\tsymlinks = false\n")
+		entries, err := r.Status()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("core.precomposeUnicode", func(t *testing.T) {
+		t.Parallel()
+
+		// newRepo returns a repository which HEAD contains a file
+		// named using precomposed unicode, and which working tree
+		// contains the same file named using decomposed unicode,
+		// like on macOS
+		newRepo := func(t *testing.T, config string) *Repository {
+			t.Helper()
+
+			r, _ := newWorktreeTestRepo(t, config)
+			blob, err := r.NewBlob([]byte("content\n"))
+			require.NoError(t, err)
+			tb := r.NewTreeBuilder()
+			require.NoError(t, tb.Insert("caf\u00e9", blob.ID(), object.ModeFile))
+			tree, err := tb.Write()
+			require.NoError(t, err)
+			head, err := r.Head()
+			require.NoError(t, err)
+			_, err = r.NewCommit(head.Branch, tree, object.NewSignature("author", "author@domain.tld"), &object.CommitOptions{
+				Message: "initial commit",
+			})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(r.Config.WorkTreePath, "cafe\u0301"), []byte("content\n"), 0o644))
+			return r
+		}
+
+		t.Run("should match decomposed paths when true", func(t *testing.T) {
+			t.Parallel()
+
+			r := newRepo(t, "[core]\n\tprecomposeunicode = true\n")
+			entries, err := r.Status()
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+
+			entry, err := r.HashWorktreeFile("cafe\u0301", 0)
+			require.NoError(t, err)
+			assert.Equal(t, "caf\u00e9", entry.Path)
+		})
+
+		t.Run("should keep the paths as is when false", func(t *testing.T) {
+			t.Parallel()
+
+			r := newRepo(t, "[core]\n\tprecomposeunicode = false\n")
+			entries, err := r.Status()
+			require.NoError(t, err)
+			assert.Equal(t, []*StatusEntry{
+				{Path: "cafe\u0301", Status: StatusUntracked, WorktreeMode: object.ModeFile},
+				{Path: "caf\u00e9", Status: StatusDeleted, HeadMode: object.ModeFile},
+			}, entries)
+
+			entry, err := r.HashWorktreeFile("cafe\u0301", 0)
+			require.NoError(t, err)
+			assert.Equal(t, "cafe\u0301", entry.Path)
+		})
+	})
+
+	t.Run("core.ignoreCase", func(t *testing.T) {
+		t.Parallel()
+
+		// renameFile renames "file" to "FILE" in the working tree, as
+		// a user would do on a case-insensitive filesystem
+		renameFile := func(t *testing.T, r *Repository) {
+			t.Helper()
+
+			root := r.Config.WorkTreePath
+			require.NoError(t, os.Rename(filepath.Join(root, "file"), filepath.Join(root, "FILE")))
+		}
+
+		t.Run("should match files renamed by case when true", func(t *testing.T) {
+			t.Parallel()
+
+			r := newStatusTestRepo(t, "[core]\n\tignorecase = true\n")
+			renameFile(t, r)
+			entries, err := r.Status()
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+
+			// Changes of content should still be detected
+			require.NoError(t, os.WriteFile(filepath.Join(r.Config.WorkTreePath, "FILE"), []byte("new content\n"), 0o644))
+			entries, err = r.Status()
+			require.NoError(t, err)
+			assert.Equal(t, []*StatusEntry{
+				{Path: "file", Status: StatusModified, HeadMode: object.ModeFile, WorktreeMode: object.ModeFile},
+			}, entries)
+		})
+
+		t.Run("should prefer the file matching the case when true", func(t *testing.T) {
+			t.Parallel()
+
+			r := newStatusTestRepo(t, "[core]\n\tignorecase = true\n")
+			require.NoError(t, os.WriteFile(filepath.Join(r.Config.WorkTreePath, "FILE"), []byte("content\n"), 0o644))
+			entries, err := r.Status()
+			require.NoError(t, err)
+			assert.Equal(t, []*StatusEntry{
+				{Path: "FILE", Status: StatusUntracked, WorktreeMode: object.ModeFile},
+			}, entries)
+		})
+
+		t.Run("should report a delete and an untracked file when false", func(t *testing.T) {
+			t.Parallel()
+
+			r := newStatusTestRepo(t, "[core]\n\tignorecase = false\n")
+			renameFile(t, r)
+			entries, err := r.Status()
+			require.NoError(t, err)
+			assert.Equal(t, []*StatusEntry{
+				{Path: "FILE", Status: StatusUntracked, WorktreeMode: object.ModeFile},
+				{Path: "file", Status: StatusDeleted, HeadMode: object.ModeFile},
+			}, entries)
+		})
+	})
+
+	t.Run("should fail on a bare repository", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := InitRepositoryWithOptions(dir, InitOptions{IsBare: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+
+		_, err = r.Status()
+		require.ErrorIs(t, err, ErrBareRepository)
+	})
+}
+
+func TestHashWorktreeFile(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the executable bit is not supported on Windows")
+	}
+
+	testCases := []struct {
+		desc         string
+		config       string
+		knownMode    object.TreeObjectMode
+		perm         os.FileMode
+		expectedMode object.TreeObjectMode
+	}{
+		{
+			desc:         "new executable file",
+			perm:         0o755,
+			expectedMode: object.ModeExecutable,
+		},
+		{
+			desc:         "file made executable",
+			knownMode:    object.ModeFile,
+			perm:         0o755,
+			expectedMode: object.ModeExecutable,
+		},
+		{
+			desc:         "file made executable with core.fileMode=false",
+			config:       "[core]\n\tfilemode = false\n",
+			knownMode:    object.ModeFile,
+			perm:         0o755,
+			expectedMode: object.ModeFile,
+		},
+		{
+			desc:         "executable made non-executable with core.fileMode=false",
+			config:       "[core]\n\tfilemode = false\n",
+			knownMode:    object.ModeExecutable,
+			perm:         0o644,
+			expectedMode: object.ModeExecutable,
+		},
+		{
+			desc:         "new file with core.fileMode=false",
+			config:       "[core]\n\tfilemode = false\n",
+			perm:         0o755,
+			expectedMode: object.ModeFile,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			r, _ := newWorktreeTestRepo(t, tc.config)
+			p := filepath.Join(r.Config.WorkTreePath, "file")
+			require.NoError(t, os.WriteFile(p, []byte("content"), 0o644), "test %d", i)
+			require.NoError(t, os.Chmod(p, tc.perm), "test %d", i)
+
+			entry, err := r.HashWorktreeFile("file", tc.knownMode)
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, tc.expectedMode, entry.Mode, "test %d", i)
+			assert.Equal(t, "file", entry.Path, "test %d", i)
+
+			blob, err := r.Blob(entry.ID)
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, "content", string(blob.Bytes()), "test %d", i)
+		})
+	}
+}
+
+func TestHashWorktreeFileUnsafePaths(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need special permissions on Windows")
+	}
+
+	r, _ := newWorktreeTestRepo(t, "")
+	root := r.Config.WorkTreePath
+	outside, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	for i, p := range []string{"link/secret", ".git/config", "../secret"} {
+		_, err := r.HashWorktreeFile(p, 0)
+		require.ErrorIs(t, err, ErrInvalidPath, "test %d", i)
+	}
+}
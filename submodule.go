@@ -0,0 +1,202 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/spf13/afero"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	// gitmodulesFileName is the name of the file containing the
+	// configuration of the submodules, at the root of the working tree
+	gitmodulesFileName = ".gitmodules"
+	// submodulesDirName is the name of the directory of the common
+	// dir containing the repositories of the submodules
+	submodulesDirName = "modules"
+)
+
+// SubmoduleFormat represents the format used to display the changes
+// made to a submodule
+type SubmoduleFormat int8
+
+const (
+	// SubmoduleShort displays the changes made to a submodule as a
+	// patch of "Subproject commit <oid>" lines.
+	// This is the equivalent of --submodule=short
+	SubmoduleShort SubmoduleFormat = iota
+	// SubmoduleLog displays the changes made to a submodule as the
+	// list of commits that have been added or removed, like
+	// git diff --submodule=log. The repository of the submodule
+	// needs to be available for the commits to be listed
+	SubmoduleLog
+)
+
+// SubmoduleRepository opens the repository of the submodule stored
+// at the given path, relative to the root of the working tree.
+// Like git, the repository is looked for in the "modules" directory
+// of the repository, and then in the working tree.
+// ErrRepositoryNotExist is returned if the submodule has not been
+// cloned.
+// The returned repository needs to be closed by the caller
+func (r *Repository) SubmoduleRepository(path string) (*Repository, error) {
+	fs := r.Config.FS
+	name, err := r.submoduleName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gitDir := filepath.Join(r.Config.CommonDirPath, submodulesDirName, filepath.FromSlash(name))
+	if _, err = fs.Stat(gitDir); err == nil {
+		return OpenRepositoryWithOptions(gitDir, OpenOptions{IsBare: true})
+	}
+	if r.IsBare() {
+		return nil, fmt.Errorf("%s: %w", path, ErrRepositoryNotExist)
+	}
+
+	// Submodules cloned by old versions of git contain their own
+	// .git directory
+	gitDir = filepath.Join(r.Config.WorkTreePath, filepath.FromSlash(path), ".git")
+	info, err := fs.Stat(gitDir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("%s: %w", path, ErrRepositoryNotExist)
+	case err != nil:
+		return nil, fmt.Errorf("could not check %s: %w", gitDir, err)
+	case info.IsDir():
+		return OpenRepositoryWithOptions(gitDir, OpenOptions{IsBare: true})
+	}
+
+	data, err := afero.ReadFile(fs, gitDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", gitDir, err)
+	}
+	if !bytes.HasPrefix(data, []byte(gitfilePrefix)) {
+		return nil, fmt.Errorf("%s: %w", path, ErrRepositoryNotExist)
+	}
+	target := strings.TrimSpace(string(data[len(gitfilePrefix):]))
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(gitDir), target)
+	}
+	return OpenRepositoryWithOptions(target, OpenOptions{IsBare: true})
+}
+
+// submoduleName returns the name of the submodule stored at the given
+// path, using the .gitmodules file of the working tree.
+// The path is returned if the submodule is not listed, since
+// that's the name git uses by default
+func (r *Repository) submoduleName(path string) (string, error) {
+	if r.IsBare() {
+		return path, nil
+	}
+
+	filePath := filepath.Join(r.Config.WorkTreePath, gitmodulesFileName)
+	data, err := afero.ReadFile(r.Config.FS, filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		return "", fmt.Errorf("could not read %s: %w", filePath, err)
+	}
+	cfg, err := ini.LoadSources(ini.LoadOptions{SkipUnrecognizableLines: true}, data)
+	if err != nil {
+		return "", fmt.Errorf("could not parse %s: %w", filePath, err)
+	}
+	// The sections look like [submodule "name"]
+	for _, section := range cfg.Sections() {
+		name := strings.TrimPrefix(section.Name(), "submodule ")
+		if name == section.Name() || section.Key("path").String() != path {
+			continue
+		}
+		return strings.Trim(name, `"`), nil
+	}
+	return path, nil
+}
+
+// submoduleLog returns the summary of the changes made to the
+// submodule stored at the given path. A nil side means that the
+// submodule has been added or removed
+func (r *Repository) submoduleLog(path string, from, to *diff.File) (log *diff.SubmoduleLog, err error) {
+	log = &diff.SubmoduleLog{}
+	switch {
+	case from == nil:
+		log.Message = "new submodule"
+	case to == nil:
+		log.Message = "submodule deleted"
+	}
+
+	sub, err := r.SubmoduleRepository(path)
+	if err != nil {
+		if !errors.Is(err, ErrRepositoryNotExist) {
+			return nil, fmt.Errorf("could not open submodule %s: %w", path, err)
+		}
+		if log.Message == "" {
+			log.Message = "commits not present"
+		}
+		return log, nil
+	}
+	defer errutil.Close(sub, &err)
+
+	var fromCommit, toCommit *object.Commit
+	if from != nil {
+		if fromCommit, err = sub.Commit(from.ID); err != nil && !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return nil, fmt.Errorf("could not get commit %s of submodule %s: %w", from.ID.String(), path, err)
+		}
+	}
+	if to != nil {
+		if toCommit, err = sub.Commit(to.ID); err != nil && !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return nil, fmt.Errorf("could not get commit %s of submodule %s: %w", to.ID.String(), path, err)
+		}
+	}
+	if (from != nil && fromCommit == nil) || (to != nil && toCommit == nil) {
+		log.Message = "commits not present"
+	}
+	if fromCommit == nil || toCommit == nil {
+		return log, nil
+	}
+
+	fromReachable, err := sub.reachableCommits([]ginternals.Oid{fromCommit.ID()})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the commits of submodule %s: %w", path, err)
+	}
+	toReachable, err := sub.reachableCommits([]ginternals.Oid{toCommit.ID()})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the commits of submodule %s: %w", path, err)
+	}
+	_, log.FastForward = toReachable[fromCommit.ID()]
+	_, log.Rewind = fromReachable[toCommit.ID()]
+	if log.Message != "" {
+		return log, nil
+	}
+
+	// Like git, we list the commits that are only reachable from one
+	// side, following the first parent only
+	common := map[ginternals.Oid]struct{}{}
+	for oid := range fromReachable {
+		if _, ok := toReachable[oid]; ok {
+			common[oid] = struct{}{}
+		}
+	}
+	err = sub.walkCommits([]ginternals.Oid{fromCommit.ID(), toCommit.ID()}, common, WalkOptions{FirstParent: true}, func(c *object.Commit) error {
+		_, removed := fromReachable[c.ID()]
+		subject, _ := splitCommitMessage(c.Message())
+		log.Commits = append(log.Commits, diff.SubmoduleCommit{
+			Subject: subject,
+			Removed: removed,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the commits of submodule %s: %w", path, err)
+	}
+	return log, nil
+}
@@ -0,0 +1,202 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/diff"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSuperproject creates a repository containing the small repo as
+// a submodule named "mysub", stored at "libs/sub"
+func newSuperproject(t *testing.T) *Repository {
+	t.Helper()
+
+	superPath, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	r, err := InitRepository(superPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+
+	subPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	modulesPath := filepath.Join(r.Config.CommonDirPath, "modules")
+	require.NoError(t, os.MkdirAll(modulesPath, 0o755))
+	require.NoError(t, os.Rename(filepath.Join(subPath, ".git"), filepath.Join(modulesPath, "mysub")))
+
+	gitmodules := "[submodule \"mysub\"]\n\tpath = libs/sub\n\turl = https://github.com/Nivl/git-go\n"
+	require.NoError(t, os.WriteFile(filepath.Join(superPath, ".gitmodules"), []byte(gitmodules), 0o644))
+	return r
+}
+
+func TestSubmoduleRepository(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should open the repository stored in modules", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSuperproject(t)
+		sub, err := r.SubmoduleRepository("libs/sub")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, sub.Close())
+		})
+		ref, err := sub.Reference(ginternals.Head)
+		require.NoError(t, err)
+		assert.Equal(t, "refs/heads/ml/packfile/tests", ref.SymbolicTarget())
+	})
+
+	t.Run("should follow the .git file of the working tree", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSuperproject(t)
+		gitDir, err := filepath.EvalSymlinks(filepath.Join(r.Config.CommonDirPath, "modules", "mysub"))
+		require.NoError(t, err)
+		subPath := filepath.Join(r.Config.WorkTreePath, "other")
+		require.NoError(t, os.MkdirAll(subPath, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(subPath, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o644))
+
+		sub, err := r.SubmoduleRepository("other")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, sub.Close())
+		})
+		_, err = sub.Reference(ginternals.Head)
+		require.NoError(t, err)
+	})
+
+	t.Run("should fail if the submodule has not been cloned", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSuperproject(t)
+		_, err := r.SubmoduleRepository("nope")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrRepositoryNotExist), "unexpected error: %v", err)
+	})
+}
+
+func TestDiffTreesSubmoduleLog(t *testing.T) {
+	t.Parallel()
+
+	// treeWithGitlinks returns a tree containing the provided
+	// gitlinks, stored in "libs"
+	treeWithGitlinks := func(t *testing.T, r *Repository, gitlinks map[string]string) ginternals.Oid {
+		t.Helper()
+
+		tb := r.NewTreeBuilder()
+		for path, sha := range gitlinks {
+			oid, err := ginternals.NewOidFromStr(sha)
+			require.NoError(t, err)
+			require.NoError(t, tb.Insert(path, oid, object.ModeGitLink))
+		}
+		tree, err := tb.Write()
+		require.NoError(t, err)
+
+		libs := r.NewTreeBuilder()
+		require.NoError(t, libs.Insert("libs", tree.ID(), object.ModeDirectory))
+		tree, err = libs.Write()
+		require.NoError(t, err)
+		return tree.ID()
+	}
+
+	r := newSuperproject(t)
+	opts := DefaultDiffOptions()
+	opts.Submodule = SubmoduleLog
+
+	testCases := []struct {
+		desc     string
+		from     map[string]string
+		to       map[string]string
+		expected *diff.SubmoduleLog
+	}{
+		{
+			desc: "fast-forward",
+			from: map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			to:   map[string]string{"sub": "1dcdadc2a420225783794fbffd51e2e137a69646"},
+			expected: &diff.SubmoduleLog{
+				FastForward: true,
+				Commits: []diff.SubmoduleCommit{
+					{Subject: "build: switch to go module"},
+					{Subject: "doc(readme): update short term goals"},
+				},
+			},
+		},
+		{
+			desc: "rewind",
+			from: map[string]string{"sub": "f96f63e52cb8862b2c2d1a8b868229259c57854e"},
+			to:   map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			expected: &diff.SubmoduleLog{
+				Rewind: true,
+				Commits: []diff.SubmoduleCommit{
+					{Subject: "doc(readme): update short term goals", Removed: true},
+				},
+			},
+		},
+		{
+			desc: "new submodule",
+			to:   map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			expected: &diff.SubmoduleLog{
+				Message: "new submodule",
+			},
+		},
+		{
+			desc: "deleted submodule",
+			from: map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			expected: &diff.SubmoduleLog{
+				Message: "submodule deleted",
+			},
+		},
+		{
+			desc: "commits not present",
+			from: map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			to:   map[string]string{"sub": "0348308155fbeb0015970d1a30666e51a2ea0e9f"},
+			expected: &diff.SubmoduleLog{
+				Message: "commits not present",
+			},
+		},
+		{
+			desc: "submodule not cloned",
+			from: map[string]string{"other": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"},
+			to:   map[string]string{"other": "1dcdadc2a420225783794fbffd51e2e137a69646"},
+			expected: &diff.SubmoduleLog{
+				Message: "commits not present",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			from, to := ginternals.NullOid, ginternals.NullOid
+			if tc.from != nil {
+				from = treeWithGitlinks(t, r, tc.from)
+			}
+			if tc.to != nil {
+				to = treeWithGitlinks(t, r, tc.to)
+			}
+
+			patches, err := r.DiffTrees(from, to, opts)
+			require.NoError(t, err)
+			require.Len(t, patches, 1)
+			assert.Equal(t, tc.expected, patches[0].Submodule)
+		})
+	}
+
+	t.Run("should use the short format by default", func(t *testing.T) {
+		from := treeWithGitlinks(t, r, map[string]string{"sub": "645bda6fdb1a0651ac564394f8edb32b02dde7b3"})
+		to := treeWithGitlinks(t, r, map[string]string{"sub": "1dcdadc2a420225783794fbffd51e2e137a69646"})
+		patches, err := r.DiffTrees(from, to, DefaultDiffOptions())
+		require.NoError(t, err)
+		require.Len(t, patches, 1)
+		assert.Nil(t, patches[0].Submodule)
+		assert.Contains(t, patches[0].String(), "+Subproject commit 1dcdadc2a420225783794fbffd51e2e137a69646\n")
+	})
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+)
+
+// ErrTagNotAnnotated is returned when the annotation of a lightweight
+// tag is requested
+var ErrTagNotAnnotated = errors.New("tag is not annotated")
+
+// Tag represents a tag (refs/tags/), annotated or lightweight
+type Tag struct {
+	*RefEntry
+}
+
+// Name returns the full name of the tag
+// (ex. refs/tags/v1.0)
+func (t *Tag) Name() string {
+	return t.Reference.Name()
+}
+
+// IsAnnotated returns whether the tag targets an annotated tag
+// object, as opposed to a lightweight tag that directly targets an
+// object
+func (t *Tag) IsAnnotated() (bool, error) {
+	_, err := t.Annotation()
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrTagNotAnnotated):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Annotation returns the annotated tag object targeted by the tag.
+// ErrTagNotAnnotated is returned if the tag is a lightweight tag
+func (t *Tag) Annotation() (*object.Tag, error) {
+	o, err := t.repo.dotGit.Object(t.Reference.Target())
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %s: %w", t.Reference.Target().String(), err)
+	}
+	if o.Type() != object.TypeTag {
+		return nil, fmt.Errorf("%s: %w", t.ShortName, ErrTagNotAnnotated)
+	}
+	return object.NewTagFromObjectWithLimits(o, t.repo.dotGit.Limits())
+}
+
+// Commit returns the commit targeted by the tag once all the
+// annotated tags have been followed.
+// object.ErrObjectInvalid is returned if the tag doesn't target a
+// commit
+func (t *Tag) Commit() (*object.Commit, error) {
+	oid, err := t.Peel()
+	if err != nil {
+		return nil, fmt.Errorf("could not peel %s: %w", t.Name(), err)
+	}
+	return t.repo.peelToCommit(oid)
+}
+
+// LookupTag returns the tag matching the given short name
+// (ex. v1.0 for refs/tags/v1.0).
+// ErrTagNotFound is returned if the tag doesn't exist
+func (r *Repository) LookupTag(name string) (*Tag, error) {
+	fullName := ginternals.LocalTagFullName(name)
+	ref, err := r.dotGit.Reference(fullName)
+	if err != nil {
+		if errors.Is(err, ginternals.ErrRefNotFound) {
+			return nil, fmt.Errorf("%s: %w", fullName, ErrTagNotFound)
+		}
+		return nil, fmt.Errorf("could not get %s: %w", fullName, err)
+	}
+	return &Tag{
+		RefEntry: &RefEntry{
+			repo:      r,
+			Reference: ref,
+			ShortName: name,
+		},
+	}, nil
+}
+
+// Tags returns all the tags of the repository, sorted by name
+func (r *Repository) Tags() ([]*Tag, error) {
+	tags := []*Tag{}
+	err := r.WalkTags(func(e *RefEntry) error {
+		tags = append(tags, &Tag{RefEntry: e})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
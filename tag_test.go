@@ -0,0 +1,89 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	r := newSmallTestRepo(t)
+	tags, err := r.Tags()
+	require.NoError(t, err)
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.ShortName)
+	}
+	assert.Equal(t, []string{"annotated", "lightweight"}, names)
+}
+
+func TestLookupTag(t *testing.T) {
+	t.Parallel()
+
+	t.Run("annotated tags", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		tag, err := r.LookupTag("annotated")
+		require.NoError(t, err)
+		assert.Equal(t, "refs/tags/annotated", tag.Name())
+		assert.Equal(t, "annotated", tag.ShortName)
+
+		annotated, err := tag.IsAnnotated()
+		require.NoError(t, err)
+		assert.True(t, annotated)
+		annotation, err := tag.Annotation()
+		require.NoError(t, err)
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", annotation.ID().String())
+		assert.Equal(t, "annotated tag\n", annotation.Message())
+
+		c, err := tag.Commit()
+		require.NoError(t, err)
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", c.ID().String())
+	})
+
+	t.Run("lightweight tags", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		tag, err := r.LookupTag("lightweight")
+		require.NoError(t, err)
+
+		annotated, err := tag.IsAnnotated()
+		require.NoError(t, err)
+		assert.False(t, annotated)
+		_, err = tag.Annotation()
+		require.ErrorIs(t, err, ErrTagNotAnnotated)
+
+		c, err := tag.Commit()
+		require.NoError(t, err)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", c.ID().String())
+	})
+
+	t.Run("tags that don't target a commit", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		blob, err := r.NewBlob([]byte("content"))
+		require.NoError(t, err)
+		_, err = r.NewLightweightTag("blob", blob.ID())
+		require.NoError(t, err)
+
+		tag, err := r.LookupTag("blob")
+		require.NoError(t, err)
+		_, err = tag.Commit()
+		require.ErrorIs(t, err, object.ErrObjectInvalid)
+	})
+
+	t.Run("should fail on unknown tags", func(t *testing.T) {
+		t.Parallel()
+
+		r := newSmallTestRepo(t)
+		_, err := r.LookupTag("nope")
+		require.ErrorIs(t, err, ErrTagNotFound)
+	})
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/attributes"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/internal/errutil"
+)
+
+// Textconv returns the content of the file at the given path of the
+// given tree, converted to text by the textconv command of its diff
+// driver (diff.<driver>.textconv).
+// The content is returned as-is if the file doesn't have a textconv
+// command.
+// Like the diffs, the attributes are read from the given tree and
+// from $GIT_DIR/info/attributes.
+// ErrPathNotFound is returned if the path doesn't target a file of
+// the tree
+func (r *Repository) Textconv(treeID ginternals.Oid, path string) ([]byte, error) {
+	tree, err := r.Tree(treeID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get tree %s: %w", treeID.String(), err)
+	}
+	entry, err := tree.EntryByPath(r.dotGit, path)
+	if err != nil {
+		if errors.Is(err, object.ErrTreeEntryNotFound) {
+			return nil, fmt.Errorf("%s: %w", path, ErrPathNotFound)
+		}
+		return nil, fmt.Errorf("could not get %s: %w", path, err)
+	}
+	if entry.Mode.ObjectType() != object.TypeBlob {
+		return nil, fmt.Errorf("%s: %w", path, ErrPathNotFound)
+	}
+	o, err := r.dotGit.Object(entry.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %s: %w", entry.ID.String(), err)
+	}
+
+	attrs, err := r.newTreeAttributes(treeID)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := attrs.get(path, "diff")
+	if err != nil {
+		return nil, err
+	}
+	if driver.State != attributes.Valued {
+		return o.Bytes(), nil
+	}
+	command, ok := r.Config.FromFile().DiffTextconv(driver.Value)
+	if !ok {
+		return o.Bytes(), nil
+	}
+	return r.runTextconv(command, o.Bytes())
+}
+
+// runTextconv runs the given textconv command on the given content.
+// Like git, the content is written to a temporary file, which path
+// is given to the command run by the shell
+func (r *Repository) runTextconv(command string, content []byte) (out []byte, err error) {
+	f, err := os.CreateTemp("", "git-go-textconv-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %w", err)
+	}
+	defer func() {
+		if e := os.Remove(f.Name()); e != nil && err == nil {
+			err = fmt.Errorf("could not remove %s: %w", f.Name(), e)
+		}
+	}()
+	_, err = f.Write(content)
+	errutil.Close(f, &err)
+	if err != nil {
+		return nil, fmt.Errorf("could not write %s: %w", f.Name(), err)
+	}
+
+	cmd := exec.Command("sh", "-c", command+` "$@"`, command, f.Name()) //nolint:gosec // running a user-defined command is the whole point
+	cmd.Dir = r.Config.WorkTreePath
+	// The env of the config is empty when the repository has been
+	// opened without env vars, in which case the command inherits the
+	// env of the process
+	if e := r.Config.Env().List(); len(e) > 0 {
+		cmd.Env = e
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not run textconv '%s': %w", command, withStderr(stderr, err))
+	}
+	return stdout.Bytes(), nil
+}
+
+// withStderr adds the error printed by a command, if any, to the
+// given error
+func withStderr(stderr *bytes.Buffer, err error) error {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,78 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextconv(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns a repository in which the markdown files use the
+	// given diff driver
+	newRepo := func(t *testing.T, driver string) (*Repository, ginternals.Oid, []byte) {
+		t.Helper()
+
+		r := newSmallTestRepo(t)
+		attrsPath := ginternals.InfoAttributesPath(r.Config)
+		require.NoError(t, os.MkdirAll(filepath.Dir(attrsPath), 0o755))
+		require.NoError(t, os.WriteFile(attrsPath, []byte("*.md diff="+driver+"\n"), 0o644))
+
+		treeID, err := r.RevParse("HEAD^{tree}")
+		require.NoError(t, err)
+		tree, err := r.Tree(treeID)
+		require.NoError(t, err)
+		entry, ok := tree.Entry("README.md")
+		require.True(t, ok)
+		blob, err := r.Object(entry.ID)
+		require.NoError(t, err)
+		return r, treeID, blob.Bytes()
+	}
+
+	t.Run("should convert the file", func(t *testing.T) {
+		t.Parallel()
+
+		r, treeID, raw := newRepo(t, "upper")
+		appendConfig(t, r, "[diff \"upper\"]\n\ttextconv = tr a-z A-Z <\n")
+		out, err := r.Textconv(treeID, "README.md")
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(string(raw)), string(out))
+	})
+
+	t.Run("should return the raw content without textconv", func(t *testing.T) {
+		t.Parallel()
+
+		r, treeID, raw := newRepo(t, "upper")
+		out, err := r.Textconv(treeID, "README.md")
+		require.NoError(t, err)
+		assert.Equal(t, raw, out)
+	})
+
+	t.Run("should fail if the command fails", func(t *testing.T) {
+		t.Parallel()
+
+		r, treeID, _ := newRepo(t, "upper")
+		appendConfig(t, r, "[diff \"upper\"]\n\ttextconv = echo oops >&2 && false\n")
+		_, err := r.Textconv(treeID, "README.md")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "oops")
+	})
+
+	t.Run("should fail on unknown paths", func(t *testing.T) {
+		t.Parallel()
+
+		r, treeID, _ := newRepo(t, "upper")
+		_, err := r.Textconv(treeID, "nope.md")
+		require.ErrorIs(t, err, ErrPathNotFound)
+
+		// directories are not files
+		_, err = r.Textconv(treeID, "internal")
+		require.ErrorIs(t, err, ErrPathNotFound)
+	})
+}
@@ -0,0 +1,191 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/pktline"
+)
+
+var (
+	// ErrInvalidAdvertisement is an error thrown when the references
+	// advertised by a remote cannot be parsed
+	ErrInvalidAdvertisement = errors.New("invalid reference advertisement")
+	// ErrRemote is an error thrown when the remote sent an error
+	// instead of a response
+	ErrRemote = errors.New("remote error")
+)
+
+// peeledSuffix is the suffix added to the name of a tag to advertise
+// the object it targets
+const peeledSuffix = "^{}"
+
+// emptyRepoRefName is the name of the fake reference advertised by
+// a remote that has no references, to send its capabilities
+const emptyRepoRefName = "capabilities" + peeledSuffix
+
+// Ref represents a reference advertised by a remote
+type Ref struct {
+	// Name contains the full name of the reference
+	// (ex. refs/heads/master)
+	Name string
+	// ID contains the object targeted by the reference
+	ID ginternals.Oid
+	// Peeled contains the object targeted by the annotated tag
+	// targeted by the reference.
+	// NullOid if the reference doesn't target an annotated tag
+	Peeled ginternals.Oid
+	// SymbolicTarget contains the reference targeted by the reference
+	// if it's a symbolic reference (ex. refs/heads/master for HEAD).
+	// Empty if the reference is not symbolic, or if the remote
+	// didn't advertise it
+	SymbolicTarget string
+}
+
+// Capabilities represents the capabilities advertised by a remote,
+// associated with their values
+type Capabilities map[string][]string
+
+// Has returns whether the given capability is supported
+func (c Capabilities) Has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// Value returns the first value of the given capability
+func (c Capabilities) Value(name string) (value string, ok bool) {
+	values := c[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// parseCapabilities parses a space separated list of capabilities
+func parseCapabilities(list []byte) Capabilities {
+	caps := Capabilities{}
+	for _, c := range strings.Fields(string(list)) {
+		name, value := c, ""
+		if i := strings.IndexByte(c, '='); i != -1 {
+			name, value = c[:i], c[i+1:]
+		}
+		if value == "" {
+			if _, ok := caps[name]; !ok {
+				caps[name] = []string{}
+			}
+			continue
+		}
+		caps[name] = append(caps[name], value)
+	}
+	return caps
+}
+
+// RefAdvertisement represents the references and capabilities
+// advertised by a remote
+type RefAdvertisement struct {
+	// Refs contains the references of the remote, in the order they
+	// were advertised
+	Refs []*Ref
+	// Capabilities contains the capabilities of the remote
+	Capabilities Capabilities
+	// Shallows contains the commits that are shallow on the remote
+	Shallows []ginternals.Oid
+}
+
+// Ref returns the reference with the given name, or nil
+func (adv *RefAdvertisement) Ref(name string) *Ref {
+	for _, ref := range adv.Refs {
+		if ref.Name == name {
+			return ref
+		}
+	}
+	return nil
+}
+
+// ParseRefAdvertisement parses the references advertised by a remote
+// using the protocol v0 or v1, up to the flush-pkt that ends the
+// advertisement.
+// https://git-scm.com/docs/pack-protocol#_reference_discovery
+func ParseRefAdvertisement(r *pktline.Reader) (*RefAdvertisement, error) {
+	adv := &RefAdvertisement{
+		Refs:         []*Ref{},
+		Capabilities: Capabilities{},
+		Shallows:     []ginternals.Oid{},
+	}
+	first := true
+	for {
+		line, ok, err := r.ReadLine()
+		if err != nil {
+			return nil, fmt.Errorf("could not read the advertisement: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if bytes.HasPrefix(line, []byte("ERR ")) {
+			return nil, fmt.Errorf("%s: %w", line[4:], ErrRemote)
+		}
+		// The protocol v1 starts with its version
+		if first && bytes.Equal(line, []byte("version 1")) {
+			continue
+		}
+
+		if bytes.HasPrefix(line, []byte("shallow ")) {
+			oid, err := ginternals.NewOidFromChars(line[len("shallow "):])
+			if err != nil {
+				return nil, fmt.Errorf("invalid shallow line %q: %w", line, ErrInvalidAdvertisement)
+			}
+			adv.Shallows = append(adv.Shallows, oid)
+			continue
+		}
+
+		// The capabilities are sent after the first reference,
+		// separated by a NUL char
+		if first {
+			first = false
+			if i := bytes.IndexByte(line, 0); i != -1 {
+				adv.Capabilities = parseCapabilities(line[i+1:])
+				line = line[:i]
+			}
+		}
+		if len(line) < ginternals.OidHexSize+2 || line[ginternals.OidHexSize] != ' ' {
+			return nil, fmt.Errorf("invalid line %q: %w", line, ErrInvalidAdvertisement)
+		}
+		oid, err := ginternals.NewOidFromChars(line[:ginternals.OidHexSize])
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid in %q: %w", line, ErrInvalidAdvertisement)
+		}
+		name := string(line[ginternals.OidHexSize+1:])
+		if name == emptyRepoRefName {
+			continue
+		}
+
+		// A peeled tag is sent right after the tag
+		if strings.HasSuffix(name, peeledSuffix) {
+			name = strings.TrimSuffix(name, peeledSuffix)
+			if len(adv.Refs) == 0 || adv.Refs[len(adv.Refs)-1].Name != name {
+				return nil, fmt.Errorf("peeled ref %s doesn't follow its tag: %w", name, ErrInvalidAdvertisement)
+			}
+			adv.Refs[len(adv.Refs)-1].Peeled = oid
+			continue
+		}
+		adv.Refs = append(adv.Refs, &Ref{
+			Name: name,
+			ID:   oid,
+		})
+	}
+
+	// The symbolic refs are sent as symref=HEAD:refs/heads/master
+	for _, symref := range adv.Capabilities["symref"] {
+		i := strings.IndexByte(symref, ':')
+		if i == -1 {
+			continue
+		}
+		if ref := adv.Ref(symref[:i]); ref != nil {
+			ref.SymbolicTarget = symref[i+1:]
+		}
+	}
+	return adv, nil
+}
@@ -0,0 +1,136 @@
+package transport_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// advertisement returns the given lines encoded as pkt-lines, and
+// followed by a flush-pkt
+func advertisement(lines ...string) []byte {
+	buf := new(bytes.Buffer)
+	for _, line := range lines {
+		buf.Write(pktline.Encode([]byte(line + "\n")))
+	}
+	buf.WriteString(pktline.Flush)
+	return buf.Bytes()
+}
+
+// smallRepoAdvertisement contains the beginning of the advertisement
+// sent by git upload-pack for the small repo
+var smallRepoAdvertisement = []string{ //nolint:gochecknoglobals // it's a test fixture
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD\x00multi_ack thin-pack side-band side-band-64k ofs-delta shallow no-progress include-tag symref=HEAD:refs/heads/ml/packfile/tests object-format=sha1 agent=git/2.39.5",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/ml/packfile/tests",
+	"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated",
+	"6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refs/tags/annotated^{}",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+}
+
+func TestParseRefAdvertisement(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse the refs and capabilities", func(t *testing.T) {
+		t.Parallel()
+
+		r := pktline.NewReader(bytes.NewReader(advertisement(smallRepoAdvertisement...)))
+		adv, err := transport.ParseRefAdvertisement(r)
+		require.NoError(t, err)
+		require.Len(t, adv.Refs, 5)
+
+		assert.Equal(t, "HEAD", adv.Refs[0].Name)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", adv.Refs[0].ID.String())
+		assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+
+		tag := adv.Ref("refs/tags/annotated")
+		require.NotNil(t, tag)
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", tag.ID.String())
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", tag.Peeled.String())
+
+		lightweight := adv.Ref("refs/tags/lightweight")
+		require.NotNil(t, lightweight)
+		assert.True(t, lightweight.Peeled.IsZero())
+		assert.Empty(t, lightweight.SymbolicTarget)
+
+		assert.True(t, adv.Capabilities.Has("ofs-delta"))
+		assert.False(t, adv.Capabilities.Has("filter"))
+		agent, ok := adv.Capabilities.Value("agent")
+		assert.True(t, ok)
+		assert.Equal(t, "git/2.39.5", agent)
+	})
+
+	t.Run("should parse the advertisement of an empty repository", func(t *testing.T) {
+		t.Parallel()
+
+		r := pktline.NewReader(bytes.NewReader(advertisement(
+			"version 1",
+			"0000000000000000000000000000000000000000 capabilities^{}\x00ofs-delta agent=git/2.39.5",
+		)))
+		adv, err := transport.ParseRefAdvertisement(r)
+		require.NoError(t, err)
+		assert.Empty(t, adv.Refs)
+		assert.True(t, adv.Capabilities.Has("ofs-delta"))
+	})
+
+	t.Run("should parse the shallow commits", func(t *testing.T) {
+		t.Parallel()
+
+		r := pktline.NewReader(bytes.NewReader(advertisement(
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD\x00shallow",
+			"shallow f0f70144f38695250606b86a50cff2b440a417f3",
+		)))
+		adv, err := transport.ParseRefAdvertisement(r)
+		require.NoError(t, err)
+		require.Len(t, adv.Shallows, 1)
+		assert.Equal(t, "f0f70144f38695250606b86a50cff2b440a417f3", adv.Shallows[0].String())
+	})
+
+	testCases := []struct {
+		desc          string
+		lines         []string
+		expectedError error
+	}{
+		{
+			desc:          "remote error",
+			lines:         []string{"ERR access denied"},
+			expectedError: transport.ErrRemote,
+		},
+		{
+			desc:          "invalid oid",
+			lines:         []string{"zzz720a96e4c29b9950a4c577c98470a4d5dd089 HEAD"},
+			expectedError: transport.ErrInvalidAdvertisement,
+		},
+		{
+			desc:          "missing name",
+			lines:         []string{"bbb720a96e4c29b9950a4c577c98470a4d5dd089"},
+			expectedError: transport.ErrInvalidAdvertisement,
+		},
+		{
+			desc: "peeled ref without its tag",
+			lines: []string{
+				"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD",
+				"6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refs/tags/annotated^{}",
+			},
+			expectedError: transport.ErrInvalidAdvertisement,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			r := pktline.NewReader(bytes.NewReader(advertisement(tc.lines...)))
+			_, err := transport.ParseRefAdvertisement(r)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+)
+
+var (
+	// ErrInvalidBundle is an error thrown when a bundle cannot be
+	// parsed
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrMissingPrerequisite is an error thrown when a bundle depends
+	// on commits that the repository doesn't have
+	ErrMissingPrerequisite = errors.New("missing prerequisite")
+)
+
+// Signatures of the supported bundle versions
+const (
+	bundleV2Signature = "# v2 git bundle"
+	bundleV3Signature = "# v3 git bundle"
+)
+
+// Bundle represents a git bundle, which contains a packfile and the
+// references pointing to its objects.
+// Bundles are used by the bundle-uri capability to let the clients
+// download most of the objects of a clone from a static file, and
+// only fetch the missing objects from the remote
+// https://git-scm.com/docs/gitformat-bundle
+type Bundle struct {
+	// Version contains the version of the bundle (2 or 3)
+	Version int
+	// Capabilities contains the capabilities of a v3 bundle, like
+	// object-format or filter
+	Capabilities map[string]string
+	// Prerequisites contains the commits that the objects of the
+	// packfile depend on, which must already be in the repository
+	Prerequisites []ginternals.Oid
+	// Refs contains the references of the bundle, in order
+	Refs []*Ref
+	// Pack contains the packfile of the bundle
+	Pack []byte
+}
+
+// ParseBundle parses the given bundle.
+// A bundle looks like:
+//
+//	# v2 git bundle
+//	-<oid> <comment>     (prerequisites)
+//	<oid> <ref name>     (references)
+//	<empty line>
+//	<packfile>
+//
+// A v3 bundle also has capabilities (@<key>[=<value>]) after the
+// signature
+func ParseBundle(data []byte) (*Bundle, error) {
+	b := &Bundle{
+		Capabilities:  map[string]string{},
+		Prerequisites: []ginternals.Oid{},
+		Refs:          []*Ref{},
+	}
+	for lineNum := 0; ; lineNum++ {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil, fmt.Errorf("missing packfile: %w", ErrInvalidBundle)
+		}
+		line := string(data[:i])
+		data = data[i+1:]
+
+		if lineNum == 0 {
+			switch line {
+			case bundleV2Signature:
+				b.Version = 2
+			case bundleV3Signature:
+				b.Version = 3
+			default:
+				return nil, fmt.Errorf("unsupported signature %q: %w", line, ErrInvalidBundle)
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			b.Pack = data
+			return b, nil
+		case line[0] == '@':
+			if b.Version < 3 || len(b.Prerequisites) > 0 || len(b.Refs) > 0 {
+				return nil, fmt.Errorf("unexpected capability %q: %w", line, ErrInvalidBundle)
+			}
+			key, value := line[1:], ""
+			if j := strings.IndexByte(key, '='); j >= 0 {
+				key, value = key[:j], key[j+1:]
+			}
+			if key == "object-format" && value != "sha1" {
+				return nil, fmt.Errorf("unsupported object-format %s: %w", value, ErrInvalidBundle)
+			}
+			b.Capabilities[key] = value
+		case line[0] == '-':
+			// The prerequisite may be followed by a comment
+			oidStr := line[1:]
+			if j := strings.IndexByte(oidStr, ' '); j >= 0 {
+				oidStr = oidStr[:j]
+			}
+			oid, err := ginternals.NewOidFromStr(oidStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid prerequisite %q: %w", line, ErrInvalidBundle)
+			}
+			b.Prerequisites = append(b.Prerequisites, oid)
+		default:
+			j := strings.IndexByte(line, ' ')
+			if j < 0 {
+				return nil, fmt.Errorf("invalid reference %q: %w", line, ErrInvalidBundle)
+			}
+			oid, err := ginternals.NewOidFromStr(line[:j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid reference %q: %w", line, ErrInvalidBundle)
+			}
+			b.Refs = append(b.Refs, &Ref{
+				Name: line[j+1:],
+				ID:   oid,
+			})
+		}
+	}
+}
+
+// FetchBundleURI downloads the bundle at the given URI using the
+// URIDownloader of the options, and adds its packfile to dst.
+// ErrMissingPrerequisite is returned if dst doesn't have all the
+// prerequisites of the bundle.
+// No references are created, it's up to the caller to decide what
+// to do with the references of the returned bundle.
+// Nothing is added to dst if the bundle has no references
+func FetchBundleURI(dst *backend.Backend, uri string, opts Options) (*Bundle, error) {
+	d, err := newURIDownloader(opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.Download(uri)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", uri, err)
+	}
+	b, err := ParseBundle(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", uri, err)
+	}
+	for _, oid := range b.Prerequisites {
+		ok, err := dst.HasObject(oid)
+		if err != nil {
+			return nil, fmt.Errorf("could not check prerequisite %s: %w", oid.String(), err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", oid.String(), ErrMissingPrerequisite)
+		}
+	}
+	if len(b.Refs) == 0 {
+		return b, nil
+	}
+	if err = addDownloadedPackfile(dst, b.Pack, ginternals.NullOid); err != nil {
+		return nil, fmt.Errorf("could not add the packfile of %s: %w", uri, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,131 @@
+package transport_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBundle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should parse a v2 bundle", func(t *testing.T) {
+		t.Parallel()
+
+		data := "# v2 git bundle\n" +
+			"-6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refactor: rename command to git-go\n" +
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master\n" +
+			"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated\n" +
+			"\n" +
+			"PACK"
+		b, err := transport.ParseBundle([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, 2, b.Version)
+		assert.Empty(t, b.Capabilities)
+		require.Len(t, b.Prerequisites, 1)
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", b.Prerequisites[0].String())
+		require.Len(t, b.Refs, 2)
+		assert.Equal(t, "refs/heads/master", b.Refs[0].Name)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", b.Refs[0].ID.String())
+		assert.Equal(t, "refs/tags/annotated", b.Refs[1].Name)
+		assert.Equal(t, []byte("PACK"), b.Pack)
+	})
+
+	t.Run("should parse the capabilities of a v3 bundle", func(t *testing.T) {
+		t.Parallel()
+
+		data := "# v3 git bundle\n" +
+			"@object-format=sha1\n" +
+			"@filter=blob:none\n" +
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master\n" +
+			"\n"
+		b, err := transport.ParseBundle([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, 3, b.Version)
+		assert.Equal(t, map[string]string{
+			"object-format": "sha1",
+			"filter":        "blob:none",
+		}, b.Capabilities)
+	})
+
+	errorTestCases := []struct {
+		desc string
+		data string
+	}{
+		{
+			desc: "unknown signature",
+			data: "# v4 git bundle\n\n",
+		},
+		{
+			desc: "missing packfile",
+			data: "# v2 git bundle\nbbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master\n",
+		},
+		{
+			desc: "capability in a v2 bundle",
+			data: "# v2 git bundle\n@object-format=sha1\n\n",
+		},
+		{
+			desc: "unsupported object format",
+			data: "# v3 git bundle\n@object-format=sha256\n\n",
+		},
+		{
+			desc: "invalid reference",
+			data: "# v2 git bundle\nnope refs/heads/master\n\n",
+		},
+	}
+	for i, tc := range errorTestCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := transport.ParseBundle([]byte(tc.data))
+			require.ErrorIs(t, err, transport.ErrInvalidBundle, "test %d", i)
+		})
+	}
+}
+
+func TestFetchBundleURI(t *testing.T) {
+	t.Parallel()
+
+	blob := object.New(object.TypeBlob, []byte("from a bundle"))
+	pack, err := packfile.Build([]*packfile.BuildObject{
+		{Object: blob},
+	}, packfile.BuildOptions{})
+	require.NoError(t, err)
+	bundle := append([]byte("# v2 git bundle\n"+blob.ID().String()+" refs/heads/master\n\n"), pack.Pack...)
+	withPrerequisite := append([]byte("# v2 git bundle\n-bbb720a96e4c29b9950a4c577c98470a4d5dd089\n"+blob.ID().String()+" refs/heads/master\n\n"), pack.Pack...)
+	opts := transport.Options{
+		URIDownloader: mapDownloader{
+			"https://cdn.example.com/repo.bundle":   bundle,
+			"https://cdn.example.com/prereq.bundle": withPrerequisite,
+		},
+	}
+
+	t.Run("should add the objects of the bundle", func(t *testing.T) {
+		t.Parallel()
+
+		b := newEmptyBackend(t)
+		res, err := transport.FetchBundleURI(b, "https://cdn.example.com/repo.bundle", opts)
+		require.NoError(t, err)
+		require.Len(t, res.Refs, 1)
+		has, err := b.HasObject(blob.ID())
+		require.NoError(t, err)
+		assert.True(t, has)
+	})
+
+	t.Run("should fail if a prerequisite is missing", func(t *testing.T) {
+		t.Parallel()
+
+		b := newEmptyBackend(t)
+		_, err := transport.FetchBundleURI(b, "https://cdn.example.com/prereq.bundle", opts)
+		require.ErrorIs(t, err, transport.ErrMissingPrerequisite)
+		has, err := b.HasObject(blob.ID())
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+}
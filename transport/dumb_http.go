@@ -0,0 +1,389 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/errutil"
+	"github.com/Nivl/git-go/internal/readutil"
+	"github.com/Nivl/git-go/internal/zlibutil"
+)
+
+// listDumbRefs parses the info/refs file sent by a remote that only
+// supports the dumb protocol. The file contains one "<oid>\t<name>"
+// line per reference, and doesn't contain HEAD, which is read
+// separately
+func (t *httpTransport) listDumbRefs(body io.Reader) (*RefAdvertisement, error) {
+	adv := &RefAdvertisement{
+		Refs:         []*Ref{},
+		Capabilities: Capabilities{},
+		Shallows:     []ginternals.Oid{},
+	}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		i := strings.IndexByte(line, '\t')
+		if i != ginternals.OidHexSize {
+			return nil, fmt.Errorf("invalid line %q: %w", line, ErrInvalidAdvertisement)
+		}
+		oid, err := ginternals.NewOidFromStr(line[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid in %q: %w", line, ErrInvalidAdvertisement)
+		}
+		name := line[i+1:]
+
+		// Like the smart protocol, a peeled tag follows its tag
+		if strings.HasSuffix(name, peeledSuffix) {
+			name = strings.TrimSuffix(name, peeledSuffix)
+			if len(adv.Refs) == 0 || adv.Refs[len(adv.Refs)-1].Name != name {
+				return nil, fmt.Errorf("peeled ref %s doesn't follow its tag: %w", name, ErrInvalidAdvertisement)
+			}
+			adv.Refs[len(adv.Refs)-1].Peeled = oid
+			continue
+		}
+		adv.Refs = append(adv.Refs, &Ref{
+			Name: name,
+			ID:   oid,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		err = fmt.Errorf("could not read the references: %w", err)
+		if isNetworkError(err) {
+			err = transient(err)
+		}
+		return nil, err
+	}
+
+	if err := t.addDumbHead(adv); err != nil {
+		return nil, err
+	}
+	return adv, nil
+}
+
+// addDumbHead adds the HEAD of the remote at the beginning of the
+// advertised references. Nothing is added if the remote doesn't have
+// a HEAD, or if its HEAD targets a branch that doesn't exist
+func (t *httpTransport) addDumbHead(adv *RefAdvertisement) error {
+	data, found, err := t.download(ginternals.Head)
+	if err != nil {
+		return fmt.Errorf("could not download %s: %w", ginternals.Head, err)
+	}
+	if !found {
+		return nil
+	}
+
+	head := &Ref{Name: ginternals.Head}
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "ref: ") {
+		head.SymbolicTarget = strings.TrimPrefix(content, "ref: ")
+		target := adv.Ref(head.SymbolicTarget)
+		if target == nil {
+			return nil
+		}
+		head.ID = target.ID
+	} else {
+		head.ID, err = ginternals.NewOidFromStr(content)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", ginternals.Head, content, ErrInvalidAdvertisement)
+		}
+	}
+	adv.Refs = append([]*Ref{head}, adv.Refs...)
+	return nil
+}
+
+// download downloads the given file of the remote. found is false if
+// the file doesn't exist.
+// The request is retried if it fails because of a transient error, and
+// an interrupted download is resumed if the server supports it
+func (t *httpTransport) download(file string) (data []byte, found bool, err error) {
+	partial := &partialDownload{}
+	err = retry(t.retry, func() error {
+		found, err = t.downloadOnce(file, partial)
+		return err
+	})
+	if err != nil || !found {
+		return nil, found, err
+	}
+	return partial.data, true, nil
+}
+
+// downloadOnce downloads the given file of the remote, or the part of
+// the file that hasn't been received yet
+func (t *httpTransport) downloadOnce(file string, partial *partialDownload) (found bool, err error) {
+	res, err := t.getRange(file, partial.rangeHeader())
+	if err != nil {
+		return false, err
+	}
+	defer errutil.Close(res.Body, &err)
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		if err = t.statusError(res); err != nil {
+			return false, err
+		}
+	}
+	if err = partial.read(res, file); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Fetch downloads the given objects, and all the objects they
+// reference, into dst.
+// Only the dumb protocol is supported for now, ErrUnsupportedProtocol
+// is returned if the remote supports the smart protocol
+func (t *httpTransport) Fetch(dst *backend.Backend, wants []ginternals.Oid) error {
+	if !t.probed {
+		if _, err := t.ListRefs(); err != nil {
+			return err
+		}
+	}
+	if !t.dumb {
+		return fmt.Errorf("fetching from a smart HTTP remote: %w", ErrUnsupportedProtocol)
+	}
+	w := &dumbWalker{
+		t:   t,
+		dst: dst,
+	}
+	return w.fetch(wants)
+}
+
+// dumbPack represents a packfile of a remote using the dumb protocol
+type dumbPack struct {
+	// name contains the name of the packfile (pack-<sha>.pack)
+	name string
+	// idxData contains the content of the index of the packfile.
+	// It's nil until the index has been downloaded
+	idxData []byte
+	idx     *packfile.PackIndex
+}
+
+// dumbWalker downloads objects from a remote using the dumb protocol.
+// Like git, each object is first looked for as a loose object, then
+// in the packfiles listed in objects/info/packs
+type dumbWalker struct {
+	t   *httpTransport
+	dst *backend.Backend
+	// packs contains the packfiles of the remote that have not been
+	// downloaded. It's nil until objects/info/packs has been read
+	packs []*dumbPack
+}
+
+// fetch downloads the given objects and the objects they reference
+func (w *dumbWalker) fetch(wants []ginternals.Oid) error {
+	queue := append([]ginternals.Oid{}, wants...)
+	seen := map[ginternals.Oid]struct{}{}
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[oid]; ok {
+			continue
+		}
+		seen[oid] = struct{}{}
+
+		has, err := w.dst.HasObject(oid)
+		if err != nil {
+			return fmt.Errorf("could not check if object %s exists: %w", oid.String(), err)
+		}
+		if has {
+			continue
+		}
+		o, err := w.fetchObject(oid)
+		if err != nil {
+			return err
+		}
+		links, err := objectLinks(o)
+		if err != nil {
+			return fmt.Errorf("could not parse object %s: %w", oid.String(), err)
+		}
+		queue = append(queue, links...)
+	}
+	return nil
+}
+
+// fetchObject downloads the given object and stores it in the
+// destination. If the object is in a packfile, the whole packfile
+// is downloaded
+func (w *dumbWalker) fetchObject(oid ginternals.Oid) (*object.Object, error) {
+	sha := oid.String()
+	data, found, err := w.t.download("objects/" + sha[:2] + "/" + sha[2:])
+	if err != nil {
+		return nil, fmt.Errorf("could not download object %s: %w", sha, err)
+	}
+	if found {
+		o, err := parseLooseObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse object %s: %w", sha, err)
+		}
+		if o.ID() != oid {
+			return nil, fmt.Errorf("expected object %s, got %s: %w", sha, o.ID().String(), object.ErrObjectInvalid)
+		}
+		if _, err = w.dst.WriteObject(o); err != nil {
+			return nil, fmt.Errorf("could not write object %s: %w", sha, err)
+		}
+		return o, nil
+	}
+
+	pack, err := w.findPack(oid)
+	if err != nil {
+		return nil, err
+	}
+	if pack == nil {
+		return nil, fmt.Errorf("%s: %w", sha, ginternals.ErrObjectNotFound)
+	}
+	packData, found, err := w.t.download("objects/pack/" + pack.name)
+	if err != nil {
+		return nil, fmt.Errorf("could not download packfile %s: %w", pack.name, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("packfile %s is listed but doesn't exist: %w", pack.name, ginternals.ErrObjectNotFound)
+	}
+	if _, err = w.dst.AddPackfile(packData, pack.idxData); err != nil {
+		return nil, fmt.Errorf("could not add packfile %s: %w", pack.name, err)
+	}
+	w.removePack(pack)
+	return w.dst.Object(oid)
+}
+
+// findPack returns the packfile of the remote containing the given
+// object, or nil if no packfiles contain it.
+// The indexes of the packfiles are downloaded as needed
+func (w *dumbWalker) findPack(oid ginternals.Oid) (*dumbPack, error) {
+	if w.packs == nil {
+		data, found, err := w.t.download("objects/info/packs")
+		if err != nil {
+			return nil, fmt.Errorf("could not download the list of packfiles: %w", err)
+		}
+		names := []string{}
+		if found {
+			if names, err = packfile.ParseInfoPacks(data); err != nil {
+				return nil, fmt.Errorf("could not parse the list of packfiles: %w", err)
+			}
+		}
+		w.packs = make([]*dumbPack, 0, len(names))
+		for _, name := range names {
+			w.packs = append(w.packs, &dumbPack{name: name})
+		}
+	}
+
+	for _, pack := range w.packs {
+		if pack.idx == nil {
+			idxName := strings.TrimSuffix(pack.name, packfile.ExtPackfile) + packfile.ExtIndex
+			data, found, err := w.t.download("objects/pack/" + idxName)
+			if err != nil {
+				return nil, fmt.Errorf("could not download %s: %w", idxName, err)
+			}
+			if !found {
+				return nil, fmt.Errorf("index %s is missing: %w", idxName, ginternals.ErrObjectNotFound)
+			}
+			if pack.idx, err = packfile.NewIndex(bufio.NewReader(bytes.NewReader(data))); err != nil {
+				return nil, fmt.Errorf("could not parse %s: %w", idxName, err)
+			}
+			pack.idxData = data
+		}
+		_, err := pack.idx.GetObjectOffset(oid)
+		if err == nil {
+			return pack, nil
+		}
+		if !errors.Is(err, ginternals.ErrObjectNotFound) {
+			return nil, fmt.Errorf("could not look for object %s in %s: %w", oid.String(), pack.name, err)
+		}
+	}
+	return nil, nil
+}
+
+// removePack removes the given packfile from the list of packfiles
+// left to download
+func (w *dumbWalker) removePack(pack *dumbPack) {
+	for i, p := range w.packs {
+		if p == pack {
+			w.packs = append(w.packs[:i], w.packs[i+1:]...)
+			return
+		}
+	}
+}
+
+// parseLooseObject parses the content of a loose object, which is
+// a zlib compressed "<type> <size>\0<content>"
+func parseLooseObject(data []byte) (o *object.Object, err error) {
+	zr, err := zlibutil.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress the object: %w", err)
+	}
+	defer errutil.Close(zr, &err)
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress the object: %w", err)
+	}
+
+	typ := readutil.ReadTo(raw, ' ')
+	if typ == nil {
+		return nil, fmt.Errorf("could not find the type: %w", object.ErrObjectInvalid)
+	}
+	oType, err := object.NewTypeFromString(string(typ))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported type %s: %w", typ, object.ErrObjectInvalid)
+	}
+	raw = raw[len(typ)+1:]
+	size := readutil.ReadTo(raw, 0)
+	if size == nil {
+		return nil, fmt.Errorf("could not find the size: %w", object.ErrObjectInvalid)
+	}
+	oSize, err := strconv.Atoi(string(size))
+	if err != nil {
+		return nil, fmt.Errorf("invalid size %s: %w", size, object.ErrObjectInvalid)
+	}
+	content := raw[len(size)+1:]
+	if len(content) != oSize {
+		return nil, fmt.Errorf("object marked as size %d, but has %d: %w", oSize, len(content), object.ErrObjectInvalid)
+	}
+	return object.New(oType, content), nil
+}
+
+// objectLinks returns the objects referenced by the given object
+func objectLinks(o *object.Object) ([]ginternals.Oid, error) {
+	switch o.Type() {
+	case object.TypeCommit:
+		c, err := o.AsCommit()
+		if err != nil {
+			return nil, err
+		}
+		return append([]ginternals.Oid{c.TreeID()}, c.ParentIDs()...), nil
+	case object.TypeTree:
+		tree, err := o.AsTree()
+		if err != nil {
+			return nil, err
+		}
+		links := []ginternals.Oid{}
+		for _, e := range tree.Entries() {
+			// Submodules are not part of the repository
+			if e.Mode != object.ModeGitLink {
+				links = append(links, e.ID)
+			}
+		}
+		return links, nil
+	case object.TypeTag:
+		tag, err := o.AsTag()
+		if err != nil {
+			return nil, err
+		}
+		return []ginternals.Oid{tag.Target()}, nil
+	default:
+		return nil, nil
+	}
+}
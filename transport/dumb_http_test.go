@@ -0,0 +1,211 @@
+package transport_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDumbHTTPServer returns a server that serves the git directory of
+// the small repo at /repo.git, like a static server would
+func newDumbHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+	t.Cleanup(cleanup)
+	// This is the file written by git update-server-info
+	infoRefs := strings.Join([]string{
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/heads/master",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/heads/ml/packfile/tests",
+		"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442\trefs/tags/annotated",
+		"6097a04b7a327c4be68f222ca66e61b8e1abe5c1\trefs/tags/annotated^{}",
+		"bbb720a96e4c29b9950a4c577c98470a4d5dd089\trefs/tags/lightweight",
+	}, "\n") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".git", "info", "refs"), []byte(infoRefs), 0o644))
+
+	mux := http.NewServeMux()
+	mux.Handle("/repo.git/", http.StripPrefix("/repo.git/", http.FileServer(http.Dir(filepath.Join(repoPath, ".git")))))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDumbHTTP(t *testing.T) {
+	t.Parallel()
+
+	srv := newDumbHTTPServer(t)
+
+	newTransport := func(t *testing.T, url string) transport.Transport {
+		t.Helper()
+
+		ep, err := transport.ParseEndpoint(url)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, tr.Close())
+		})
+		return tr
+	}
+
+	t.Run("should list the refs", func(t *testing.T) {
+		t.Parallel()
+
+		adv, err := newTransport(t, srv.URL+"/repo.git").ListRefs()
+		require.NoError(t, err)
+		require.Len(t, adv.Refs, 5)
+		assert.Equal(t, ginternals.Head, adv.Refs[0].Name)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", adv.Refs[0].ID.String())
+		assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+
+		tag := adv.Ref("refs/tags/annotated")
+		require.NotNil(t, tag)
+		assert.Equal(t, "80316e01dbfdf5c2a8a20de66c747ecd4c4bd442", tag.ID.String())
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", tag.Peeled.String())
+	})
+
+	t.Run("should fetch loose and packed objects", func(t *testing.T) {
+		t.Parallel()
+
+		tr := newTransport(t, srv.URL+"/repo.git")
+		fetcher, ok := tr.(transport.Fetcher)
+		require.True(t, ok, "the http transport should be a Fetcher")
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		b, err := backend.NewFS(confutil.NewCommonConfig(t, dir))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		commitID, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		// The tag is a loose object targeting a packed commit
+		tagID, err := ginternals.NewOidFromStr("80316e01dbfdf5c2a8a20de66c747ecd4c4bd442")
+		require.NoError(t, err)
+		require.NoError(t, fetcher.Fetch(b, []ginternals.Oid{commitID, tagID}))
+
+		for _, sha := range []string{
+			"bbb720a96e4c29b9950a4c577c98470a4d5dd089",
+			"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442",
+			"6097a04b7a327c4be68f222ca66e61b8e1abe5c1",
+		} {
+			oid, err := ginternals.NewOidFromStr(sha)
+			require.NoError(t, err)
+			has, err := b.HasObject(oid)
+			require.NoError(t, err)
+			assert.True(t, has, "%s should have been fetched", sha)
+		}
+		_, err = os.Stat(filepath.Join(dir, ".git", "objects", "80", "316e01dbfdf5c2a8a20de66c747ecd4c4bd442"))
+		require.NoError(t, err, "the tag should have been fetched as a loose object")
+		packs, err := b.Packfiles()
+		require.NoError(t, err)
+		require.Len(t, packs, 1)
+		assert.Equal(t, "0163931160835b1de2f120e1aa7e52206debeb14", packs[0].ID.String())
+	})
+
+	t.Run("should fail on missing objects", func(t *testing.T) {
+		t.Parallel()
+
+		tr := newTransport(t, srv.URL+"/repo.git")
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		b, err := backend.NewFS(confutil.NewCommonConfig(t, dir))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		oid, err := ginternals.NewOidFromStr("0000000000000000000000000000000000000001")
+		require.NoError(t, err)
+		err = tr.(transport.Fetcher).Fetch(b, []ginternals.Oid{oid})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ginternals.ErrObjectNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("smart servers are not supported", func(t *testing.T) {
+		t.Parallel()
+
+		smart := newSmartHTTPServer(t)
+		tr := newTransport(t, smart.URL+"/repo.git")
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		b, err := backend.NewFS(confutil.NewCommonConfig(t, dir))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+
+		err = tr.(transport.Fetcher).Fetch(b, []ginternals.Oid{ginternals.NullOid})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, transport.ErrUnsupportedProtocol), "unexpected error: %v", err)
+	})
+
+	t.Run("should resume interrupted pack downloads", func(t *testing.T) {
+		t.Parallel()
+
+		h := &interruptingHandler{
+			h:      srv.Config.Handler,
+			suffix: packfile.ExtPackfile,
+		}
+		flaky := httptest.NewServer(h)
+		t.Cleanup(flaky.Close)
+
+		ep, err := transport.ParseEndpoint(flaky.URL + "/repo.git")
+		require.NoError(t, err)
+		retries := 0
+		tr, err := transport.New(ep, transport.Options{
+			Retry: transport.RetryOptions{
+				MaxRetries: 1,
+				Backoff:    time.Millisecond,
+				OnRetry: func(retry int, err error) {
+					retries++
+					var partialErr *transport.PartialDownloadError
+					assert.True(t, errors.As(err, &partialErr), "unexpected error: %v", err)
+				},
+			},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, tr.Close())
+		})
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		b, err := backend.NewFS(confutil.NewCommonConfig(t, dir))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, b.Close())
+		})
+		require.NoError(t, b.Init(ginternals.Master))
+
+		commitID, err := ginternals.NewOidFromStr("bbb720a96e4c29b9950a4c577c98470a4d5dd089")
+		require.NoError(t, err)
+		require.NoError(t, tr.(transport.Fetcher).Fetch(b, []ginternals.Oid{commitID}))
+		assert.Equal(t, 1, retries)
+		ranges := h.receivedRanges()
+		require.Len(t, ranges, 1)
+		assert.True(t, strings.HasPrefix(ranges[0], "bytes="), "unexpected range %q", ranges[0])
+
+		has, err := b.HasObject(commitID)
+		require.NoError(t, err)
+		assert.True(t, has, "the commit should have been fetched")
+	})
+}
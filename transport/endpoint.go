@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidEndpoint is an error thrown when the URL of a remote
+// cannot be parsed
+var ErrInvalidEndpoint = errors.New("invalid remote URL")
+
+// Protocol represents the protocol used to talk to a remote
+type Protocol string
+
+// List of the supported protocols
+const (
+	ProtocolHTTP  Protocol = "http"
+	ProtocolHTTPS Protocol = "https"
+	ProtocolSSH   Protocol = "ssh"
+	ProtocolGit   Protocol = "git"
+	ProtocolFile  Protocol = "file"
+)
+
+// Endpoint represents the location of a remote repository
+type Endpoint struct {
+	Protocol Protocol
+	// User contains the user to use to connect to the remote, if any
+	User string
+	// Password contains the password to use to connect to the
+	// remote, if any. Only used by HTTP
+	Password string
+	// Host contains the host of the remote, without the port.
+	// Empty for ProtocolFile
+	Host string
+	// Port contains the port of the remote. 0 means that the default
+	// port of the protocol should be used
+	Port int
+	// Path contains the path of the repository on the remote
+	Path string
+}
+
+// ParseEndpoint parses the URL of a remote. The URL can be:
+//   - a URL using one of the supported protocols (ex.
+//     https://github.com/Nivl/git-go.git)
+//   - an scp-like address, which uses ssh (ex.
+//     git@github.com:Nivl/git-go.git)
+//   - a local path (ex. /path/to/repo.git)
+//
+// https://git-scm.com/docs/git-clone#_git_urls
+func ParseEndpoint(rawURL string) (*Endpoint, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("empty URL: %w", ErrInvalidEndpoint)
+	}
+	if strings.Contains(rawURL, "://") {
+		return parseURL(rawURL)
+	}
+	if isSCPLike(rawURL) {
+		return parseSCPLike(rawURL), nil
+	}
+	return &Endpoint{
+		Protocol: ProtocolFile,
+		Path:     rawURL,
+	}, nil
+}
+
+// parseURL parses a URL of the form protocol://[user@]host[:port]/path
+func parseURL(rawURL string) (*Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %w", rawURL, err, ErrInvalidEndpoint) //nolint:errorlint // we can only wrap one error
+	}
+
+	ep := &Endpoint{
+		Host: u.Hostname(),
+		Path: u.Path,
+	}
+	switch u.Scheme {
+	case "http", "https", "git", "file", "ssh":
+		ep.Protocol = Protocol(u.Scheme)
+	case "git+ssh", "ssh+git":
+		ep.Protocol = ProtocolSSH
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s: %w", u.Scheme, ErrInvalidEndpoint)
+	}
+	if ep.Protocol != ProtocolFile && ep.Host == "" {
+		return nil, fmt.Errorf("%s: missing host: %w", rawURL, ErrInvalidEndpoint)
+	}
+	if u.User != nil {
+		ep.User = u.User.Username()
+		ep.Password, _ = u.User.Password()
+	}
+	if port := u.Port(); port != "" {
+		ep.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %s: %w", port, ErrInvalidEndpoint)
+		}
+	}
+	// Like git, a path starting with /~ is relative to the home
+	// directory of the user on the remote
+	if ep.Protocol == ProtocolSSH || ep.Protocol == ProtocolGit {
+		if strings.HasPrefix(ep.Path, "/~") {
+			ep.Path = ep.Path[1:]
+		}
+	}
+	return ep, nil
+}
+
+// isSCPLike returns whether the URL uses the scp-like syntax
+// [user@]host:path. Like git, a colon after a slash means that
+// the URL is a local path
+func isSCPLike(rawURL string) bool {
+	colon := scpColon(rawURL)
+	if colon == -1 {
+		return false
+	}
+	// Windows paths like C:\repo or C:/repo
+	if colon == 1 && filepath.VolumeName(rawURL) != "" {
+		return false
+	}
+	slash := strings.IndexByte(rawURL, '/')
+	return slash == -1 || colon < slash
+}
+
+// scpColon returns the index of the colon separating the host from
+// the path in an scp-like URL, or -1.
+// IPv6 addresses are written between brackets, so their colons
+// are skipped
+func scpColon(rawURL string) int {
+	start := 0
+	if open := strings.IndexByte(rawURL, '['); open != -1 && open < strings.IndexByte(rawURL, ':') {
+		closing := strings.IndexByte(rawURL, ']')
+		if closing == -1 {
+			return -1
+		}
+		start = closing
+	}
+	colon := strings.IndexByte(rawURL[start:], ':')
+	if colon == -1 {
+		return -1
+	}
+	return start + colon
+}
+
+// parseSCPLike parses a URL of the form [user@]host:path
+func parseSCPLike(rawURL string) *Endpoint {
+	colon := scpColon(rawURL)
+	host, path := rawURL[:colon], rawURL[colon+1:]
+	ep := &Endpoint{
+		Protocol: ProtocolSSH,
+		Path:     path,
+	}
+	if at := strings.LastIndexByte(host, '@'); at != -1 {
+		ep.User = host[:at]
+		host = host[at+1:]
+	}
+	// IPv6 addresses are written between brackets
+	ep.Host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return ep
+}
+
+// String returns the endpoint as a URL. The password is not
+// included
+func (ep *Endpoint) String() string {
+	if ep.Protocol == ProtocolFile {
+		return ep.Path
+	}
+	u := url.URL{
+		Scheme: string(ep.Protocol),
+		Host:   ep.hostPort(),
+		Path:   ep.Path,
+	}
+	if ep.User != "" {
+		u.User = url.User(ep.User)
+	}
+	if strings.HasPrefix(ep.Path, "~") {
+		u.Path = "/" + ep.Path
+	}
+	return u.String()
+}
+
+// hostPort returns the host of the endpoint, followed by its port
+// if it's not the default one
+func (ep *Endpoint) hostPort() string {
+	host := ep.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if ep.Port != 0 {
+		host += ":" + strconv.Itoa(ep.Port)
+	}
+	return host
+}
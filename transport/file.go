@@ -0,0 +1,188 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+// newFileTransport returns a transport to a repository on the local
+// file system.
+// The repository is read directly, unless an upload-pack program
+// has been provided
+func newFileTransport(ep *Endpoint, opts Options) Transport {
+	if opts.UploadPack != "" {
+		return &processTransport{
+			newCmd: func() *exec.Cmd {
+				return exec.Command(opts.UploadPack, ep.Path) //nolint:gosec // running a user-defined command is the whole point
+			},
+			env:   opts.Env,
+			trace: trace.New(opts.Env, trace.KeyPacket),
+		}
+	}
+	return &fileTransport{
+		path: ep.Path,
+	}
+}
+
+// fileTransport is a Transport reading a local repository directly,
+// without spawning any process
+type fileTransport struct {
+	path   string
+	closed bool
+}
+
+// open returns a backend to the local repository.
+// Like git, path can either point to a work tree or to a git
+// directory
+func (t *fileTransport) open() (*backend.Backend, error) {
+	workTree := t.path
+	gitDir := filepath.Join(t.path, config.DefaultDotGitDirName)
+	isBare := false
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		workTree = ""
+		gitDir = t.path
+		isBare = true
+	}
+	cfg, err := config.LoadConfigSkipEnv(config.LoadConfigOptions{
+		WorkTreePath: workTree,
+		GitDirPath:   gitDir,
+		IsBare:       isBare,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load the config of %s: %w", t.path, err)
+	}
+	b, err := backend.NewFS(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", t.path, err)
+	}
+	// HEAD should always be there, if it's not then this is not a
+	// repository
+	if _, err = b.UnresolvedReference(ginternals.Head); err != nil {
+		b.Close() //nolint:errcheck // it already failed
+		return nil, fmt.Errorf("%s: %w", t.path, ErrRepositoryNotFound)
+	}
+	return b, nil
+}
+
+// ListRefs returns the references of the repository, the same way
+// git-upload-pack would advertise them: HEAD first, followed by the
+// other references sorted by name
+func (t *fileTransport) ListRefs() (adv *RefAdvertisement, err error) {
+	if t.closed {
+		return nil, errTransportClosed
+	}
+	b, err := t.open()
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close() //nolint:errcheck // we only read from the repository
+
+	adv = &RefAdvertisement{
+		Refs:         []*Ref{},
+		Capabilities: Capabilities{},
+		Shallows:     []ginternals.Oid{},
+	}
+
+	refs := []*Ref{}
+	// The references are walked unresolved so a dangling symbolic
+	// reference (like an unborn HEAD) doesn't prevent us from
+	// listing the others
+	err = b.WalkUnresolvedReferences(func(ref *ginternals.Reference) error {
+		if !strings.HasPrefix(ref.Name(), "refs/") {
+			return nil
+		}
+		if ref.Type() == ginternals.SymbolicReference {
+			resolved, e := b.Reference(ref.Name())
+			if e != nil {
+				if errors.Is(e, ginternals.ErrRefNotFound) {
+					return nil
+				}
+				return e
+			}
+			ref = resolved
+		}
+		r, e := t.newRef(b, ref.Name(), ref.Target())
+		if e != nil {
+			return e
+		}
+		// Like with protocol v2, the target of all the symbolic
+		// references is reported (ex. refs/remotes/origin/HEAD)
+		if ref.Type() == ginternals.SymbolicReference {
+			r.SymbolicTarget = ref.SymbolicTarget()
+		}
+		refs = append(refs, r)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the references: %w", err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+
+	// An unborn HEAD is not advertised
+	head, err := b.Reference(ginternals.Head)
+	if err != nil && !errors.Is(err, ginternals.ErrRefNotFound) {
+		return nil, fmt.Errorf("could not resolve HEAD: %w", err)
+	}
+	if err == nil {
+		r, err := t.newRef(b, ginternals.Head, head.Target())
+		if err != nil {
+			return nil, err
+		}
+		unresolved, err := b.UnresolvedReference(ginternals.Head)
+		if err != nil {
+			return nil, fmt.Errorf("could not read HEAD: %w", err)
+		}
+		if unresolved.Type() == ginternals.SymbolicReference {
+			r.SymbolicTarget = unresolved.SymbolicTarget()
+			adv.Capabilities["symref"] = []string{ginternals.Head + ":" + r.SymbolicTarget}
+		}
+		adv.Refs = append(adv.Refs, r)
+	}
+	adv.Refs = append(adv.Refs, refs...)
+	return adv, nil
+}
+
+// newRef returns a Ref targeting the given object, peeled if the
+// object is an annotated tag
+func (t *fileTransport) newRef(b *backend.Backend, name string, target ginternals.Oid) (*Ref, error) {
+	ref := &Ref{
+		Name: name,
+		ID:   target,
+	}
+	for oid := target; ; {
+		o, err := b.Object(oid)
+		if err != nil {
+			return nil, fmt.Errorf("could not get object %s targeted by %s: %w", oid.String(), name, err)
+		}
+		if o.Type() != object.TypeTag {
+			break
+		}
+		tag, err := o.AsTag()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse tag %s: %w", oid.String(), err)
+		}
+		oid = tag.Target()
+		ref.Peeled = oid
+	}
+	return ref, nil
+}
+
+// Close marks the transport as closed. Nothing is kept open between
+// two calls
+func (t *fileTransport) Close() error {
+	t.closed = true
+	return nil
+}
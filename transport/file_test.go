@@ -0,0 +1,152 @@
+package transport_test
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	git "github.com/Nivl/git-go"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smallRepoRefs contains the refs of the small repo, as listed by
+// git ls-remote
+var smallRepoRefs = []string{ //nolint:gochecknoglobals // it's a test fixture
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 HEAD",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/master",
+	"b328320060eb503cf337c7cff281712ef236963a refs/heads/ml/cleanup-062020",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/heads/ml/packfile/tests",
+	"f0f70144f38695250606b86a50cff2b440a417f3 refs/heads/ml/tests",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/remotes/origin/HEAD",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/remotes/origin/master",
+	"b328320060eb503cf337c7cff281712ef236963a refs/remotes/origin/ml/cleanup-062020",
+	"5f35f2dc6cec7356da02ca26192ce2bc3f271e79 refs/remotes/origin/ml/feat/clone",
+	"3fe6cf63fceced491a79fe634eb1e2c888225707 refs/stash",
+	"80316e01dbfdf5c2a8a20de66c747ecd4c4bd442 refs/tags/annotated",
+	"6097a04b7a327c4be68f222ca66e61b8e1abe5c1 refs/tags/annotated^{}",
+	"bbb720a96e4c29b9950a4c577c98470a4d5dd089 refs/tags/lightweight",
+}
+
+// listRefs returns the refs of the advertisement using the format
+// of git ls-remote
+func listRefs(adv *transport.RefAdvertisement) []string {
+	lines := make([]string, 0, len(adv.Refs))
+	for _, ref := range adv.Refs {
+		lines = append(lines, ref.ID.String()+" "+ref.Name)
+		if !ref.Peeled.IsZero() {
+			lines = append(lines, ref.Peeled.String()+" "+ref.Name+"^{}")
+		}
+	}
+	return lines
+}
+
+func TestFileListRefs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should list the refs of a work tree", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint(repoPath)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+
+		assert.Equal(t, smallRepoRefs, listRefs(adv))
+		assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+		assert.Equal(t, []string{"HEAD:refs/heads/ml/packfile/tests"}, adv.Capabilities["symref"])
+	})
+
+	t.Run("should list the refs of a git directory using file://", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint("file://" + filepath.ToSlash(filepath.Join(repoPath, ".git")))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+		assert.Equal(t, smallRepoRefs, listRefs(adv))
+	})
+
+	t.Run("should list no refs on an empty repository", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		r, err := git.InitRepository(dir)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		ep, err := transport.ParseEndpoint(dir)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+		// An unborn HEAD is not advertised
+		assert.Empty(t, adv.Refs)
+		assert.NotContains(t, adv.Capabilities, "symref")
+	})
+
+	t.Run("should fail if the path is not a repository", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint(dir)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		_, err = tr.ListRefs()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, transport.ErrRepositoryNotFound), "unexpected error: %v", err)
+		require.NoError(t, tr.Close())
+	})
+
+	t.Run("should spawn upload-pack", func(t *testing.T) {
+		t.Parallel()
+
+		uploadPack, err := exec.LookPath("git-upload-pack")
+		if err != nil {
+			t.Skip("git-upload-pack is needed to run this test")
+		}
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint(repoPath)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{
+			UploadPack: uploadPack,
+		})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+
+		assert.Equal(t, smallRepoRefs, listRefs(adv))
+		assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+		assert.True(t, strings.HasPrefix(adv.Capabilities["agent"][0], "git/"))
+	})
+}
@@ -0,0 +1,173 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFilter is an error thrown when a filter-spec is not valid
+var ErrInvalidFilter = errors.New("invalid filter-spec")
+
+// FilterType represents the type of objects filter
+type FilterType string
+
+// List of all the supported filter types
+const (
+	// FilterBlobNone omits all the blobs
+	FilterBlobNone FilterType = "blob:none"
+	// FilterBlobLimit omits the blobs bigger than a given size
+	FilterBlobLimit FilterType = "blob:limit"
+	// FilterTreeDepth omits the blobs and trees deeper than a given
+	// depth
+	FilterTreeDepth FilterType = "tree"
+	// FilterCombine omits the objects omitted by any of the sub filters
+	FilterCombine FilterType = "combine"
+)
+
+// Filter represents an objects filter sent to the remote when
+// fetching, so it can omit some objects from the packfile.
+// A filter can only be used if the remote advertises the "filter"
+// capability.
+// https://git-scm.com/docs/git-rev-list#Documentation/git-rev-list.txt---filterltfilter-specgt
+type Filter struct {
+	Type FilterType
+	// Limit contains the maximum size of a blob, in bytes, for
+	// FilterBlobLimit
+	Limit uint64
+	// Depth contains the maximum depth of a tree or blob for
+	// FilterTreeDepth. The root tree has a depth of 1, and a depth of
+	// 0 omits all the trees and blobs
+	Depth uint64
+	// Filters contains the sub filters of FilterCombine
+	Filters []*Filter
+}
+
+// NewBlobNoneFilter returns a filter omitting all the blobs
+func NewBlobNoneFilter() *Filter {
+	return &Filter{Type: FilterBlobNone}
+}
+
+// NewBlobLimitFilter returns a filter omitting the blobs bigger than
+// the given size, in bytes
+func NewBlobLimitFilter(limit uint64) *Filter {
+	return &Filter{Type: FilterBlobLimit, Limit: limit}
+}
+
+// NewTreeDepthFilter returns a filter omitting the blobs and trees
+// deeper than the given depth
+func NewTreeDepthFilter(depth uint64) *Filter {
+	return &Filter{Type: FilterTreeDepth, Depth: depth}
+}
+
+// NewCombineFilter returns a filter omitting the objects omitted by
+// any of the given filters
+func NewCombineFilter(filters ...*Filter) *Filter {
+	return &Filter{Type: FilterCombine, Filters: filters}
+}
+
+// ParseFilter parses a filter-spec, like the one passed to
+// git clone --filter.
+// The size of blob:limit accepts the k, m, and g suffixes
+func ParseFilter(spec string) (*Filter, error) {
+	switch {
+	case spec == string(FilterBlobNone):
+		return NewBlobNoneFilter(), nil
+	case strings.HasPrefix(spec, string(FilterBlobLimit)+"="):
+		limit, err := parseSize(strings.TrimPrefix(spec, string(FilterBlobLimit)+"="))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec, ErrInvalidFilter)
+		}
+		return NewBlobLimitFilter(limit), nil
+	case strings.HasPrefix(spec, string(FilterTreeDepth)+":"):
+		depth, err := strconv.ParseUint(strings.TrimPrefix(spec, string(FilterTreeDepth)+":"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec, ErrInvalidFilter)
+		}
+		return NewTreeDepthFilter(depth), nil
+	case strings.HasPrefix(spec, string(FilterCombine)+":"):
+		return parseCombineFilter(spec)
+	}
+	return nil, fmt.Errorf("%s: %w", spec, ErrInvalidFilter)
+}
+
+// parseCombineFilter parses a combine:<filter>+<filter>... filter-spec.
+// The sub filters are URL-encoded
+func parseCombineFilter(spec string) (*Filter, error) {
+	parts := strings.Split(strings.TrimPrefix(spec, string(FilterCombine)+":"), "+")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%s: combine needs at least 2 filters: %w", spec, ErrInvalidFilter)
+	}
+	filters := make([]*Filter, 0, len(parts))
+	for _, part := range parts {
+		sub, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec, ErrInvalidFilter)
+		}
+		f, err := ParseFilter(sub)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return NewCombineFilter(filters...), nil
+}
+
+// parseSize parses a size in bytes, optionally followed by k, m, or g
+func parseSize(s string) (uint64, error) {
+	unit := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		}
+	}
+	if unit != 1 {
+		s = s[:len(s)-1]
+	}
+	size, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse size %s: %w", s, err)
+	}
+	return size * unit, nil
+}
+
+// String returns the filter-spec of the filter, as sent to the remote
+func (f *Filter) String() string {
+	switch f.Type {
+	case FilterBlobLimit:
+		return string(FilterBlobLimit) + "=" + strconv.FormatUint(f.Limit, 10)
+	case FilterTreeDepth:
+		return string(FilterTreeDepth) + ":" + strconv.FormatUint(f.Depth, 10)
+	case FilterCombine:
+		parts := make([]string, 0, len(f.Filters))
+		for _, sub := range f.Filters {
+			parts = append(parts, escapeFilter(sub.String()))
+		}
+		return string(FilterCombine) + ":" + strings.Join(parts, "+")
+	default:
+		return string(f.Type)
+	}
+}
+
+// escapeFilter escapes the characters of a sub filter that are
+// reserved in a combine filter-spec.
+// Like git, only the reserved characters are escaped
+func escapeFilter(spec string) string {
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		c := spec[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("~`!@#$^&*()[]{}\\;'\",<>?+%", c) != -1 {
+			fmt.Fprintf(&b, "%%%02x", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
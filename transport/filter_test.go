@@ -0,0 +1,104 @@
+package transport_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		spec           string
+		expected       *transport.Filter
+		expectedString string
+		expectedError  error
+	}{
+		{
+			desc:           "blob:none",
+			spec:           "blob:none",
+			expected:       transport.NewBlobNoneFilter(),
+			expectedString: "blob:none",
+		},
+		{
+			desc:           "blob:limit in bytes",
+			spec:           "blob:limit=1024",
+			expected:       transport.NewBlobLimitFilter(1024),
+			expectedString: "blob:limit=1024",
+		},
+		{
+			desc:           "blob:limit with a unit",
+			spec:           "blob:limit=1m",
+			expected:       transport.NewBlobLimitFilter(1 << 20),
+			expectedString: "blob:limit=1048576",
+		},
+		{
+			desc:           "tree depth",
+			spec:           "tree:0",
+			expected:       transport.NewTreeDepthFilter(0),
+			expectedString: "tree:0",
+		},
+		{
+			desc:           "combine",
+			spec:           "combine:blob:none+tree:3",
+			expected:       transport.NewCombineFilter(transport.NewBlobNoneFilter(), transport.NewTreeDepthFilter(3)),
+			expectedString: "combine:blob:none+tree:3",
+		},
+		{
+			desc:           "combine with encoded filters",
+			spec:           "combine:blob%3alimit=1k+tree%3A1",
+			expected:       transport.NewCombineFilter(transport.NewBlobLimitFilter(1024), transport.NewTreeDepthFilter(1)),
+			expectedString: "combine:blob:limit=1024+tree:1",
+		},
+		{
+			desc:          "combine with a single filter",
+			spec:          "combine:blob:none",
+			expectedError: transport.ErrInvalidFilter,
+		},
+		{
+			desc:          "blob:limit without size",
+			spec:          "blob:limit=",
+			expectedError: transport.ErrInvalidFilter,
+		},
+		{
+			desc:          "invalid depth",
+			spec:          "tree:-1",
+			expectedError: transport.ErrInvalidFilter,
+		},
+		{
+			desc:          "unknown filter",
+			spec:          "sparse:path=foo",
+			expectedError: transport.ErrInvalidFilter,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			f, err := transport.ParseFilter(tc.spec)
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+			assert.Equal(t, tc.expectedString, f.String())
+		})
+	}
+
+	t.Run("String should escape the reserved characters of combine", func(t *testing.T) {
+		t.Parallel()
+
+		f := transport.NewCombineFilter(transport.NewBlobNoneFilter(), &transport.Filter{Type: "sparse:oid=main:a+b"})
+		assert.Equal(t, "combine:blob:none+sparse:oid=main:a%2bb", f.String())
+	})
+}
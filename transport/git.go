@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+// defaultGitPort is the port used by git-daemon
+const defaultGitPort = 9418
+
+// gitTransport is a Transport using the anonymous git protocol,
+// served by git-daemon
+// https://git-scm.com/docs/pack-protocol#_git_transport
+type gitTransport struct {
+	ep    *Endpoint
+	retry RetryOptions
+	// trace receives the packets exchanged with the remote
+	trace *trace.Tracer
+
+	conn   net.Conn
+	closed bool
+}
+
+// newGitTransport returns a transport using the git protocol
+func newGitTransport(ep *Endpoint, opts Options) *gitTransport {
+	return &gitTransport{
+		ep:    ep,
+		retry: opts.Retry,
+		trace: trace.New(opts.Env, trace.KeyPacket),
+	}
+}
+
+// address returns the address to connect to
+func (t *gitTransport) address() string {
+	port := t.ep.Port
+	if port == 0 {
+		port = defaultGitPort
+	}
+	return net.JoinHostPort(t.ep.Host, strconv.Itoa(port))
+}
+
+// request returns the request sent to git-daemon to start a
+// git-upload-pack session.
+// The host is sent to support virtual hosting
+func (t *gitTransport) request() []byte {
+	return []byte(uploadPackService + " " + t.ep.Path + "\x00host=" + t.ep.hostPort() + "\x00")
+}
+
+// pktlineOptions returns the options used to read and write the
+// packets
+func (t *gitTransport) pktlineOptions() pktline.Options {
+	return pktline.Options{Trace: t.trace}
+}
+
+// ListRefs returns the references advertised by the remote.
+// The connection stays open until Close() is called
+func (t *gitTransport) ListRefs() (*RefAdvertisement, error) {
+	if t.closed {
+		return nil, errTransportClosed
+	}
+	if t.conn != nil {
+		return nil, errors.New("the references have already been listed") //nolint:goerr113 // this is a programming error
+	}
+
+	// Only the connection is retried since git-daemon may stop
+	// accepting connections when it's overloaded
+	err := retry(t.retry, func() (err error) {
+		t.conn, err = net.Dial("tcp", t.address())
+		if err != nil {
+			err = fmt.Errorf("could not connect to %s: %w", t.address(), err)
+			if isNetworkError(err) {
+				err = transient(err)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = pktline.NewWriterWithOptions(t.conn, t.pktlineOptions()).WritePacket(t.request()); err != nil {
+		t.Close() //nolint:errcheck // it already failed
+		return nil, fmt.Errorf("could not send the request: %w", err)
+	}
+	adv, err := ParseRefAdvertisement(pktline.NewReaderWithOptions(t.conn, t.pktlineOptions()))
+	if err != nil {
+		t.Close() //nolint:errcheck // it already failed
+		return nil, err
+	}
+	return adv, nil
+}
+
+// Close ends the session with the remote and closes the connection
+func (t *gitTransport) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if t.conn == nil {
+		return nil
+	}
+
+	// A flush-pkt tells the remote that we don't want anything.
+	// The remote may already be gone, so we don't care if it fails
+	pktline.NewWriterWithOptions(t.conn, t.pktlineOptions()).WriteFlush() //nolint:errcheck // the connection is being closed anyway
+	if err := t.conn.Close(); err != nil {
+		return fmt.Errorf("could not close the connection: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package transport_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitDaemon starts a fake git-daemon that accepts a single
+// connection, sends the given response, and reports the request it
+// received, and whether the client ended the session with a flush-pkt
+func newGitDaemon(t *testing.T, response []byte) (addr string, requests <-chan string, flushed <-chan bool) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		l.Close() //nolint:errcheck // the test is over
+	})
+
+	req := make(chan string, 1)
+	flush := make(chan bool, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck // the test is over
+
+		r := pktline.NewReader(conn)
+		_, pkt, err := r.ReadPacket()
+		if err != nil {
+			req <- err.Error()
+			return
+		}
+		req <- string(pkt)
+		conn.Write(response) //nolint:errcheck // the test will fail anyway
+
+		typ, _, err := r.ReadPacket()
+		flush <- err == nil && typ == pktline.FlushPacket
+	}()
+	return l.Addr().String(), req, flush
+}
+
+func TestGitListRefs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should list the refs", func(t *testing.T) {
+		t.Parallel()
+
+		addr, requests, flushed := newGitDaemon(t, advertisement(smallRepoAdvertisement...))
+		ep, err := transport.ParseEndpoint(fmt.Sprintf("git://%s/repo.git", addr))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.Len(t, adv.Refs, 5)
+		assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+		require.NoError(t, tr.Close())
+
+		assert.Equal(t, "git-upload-pack /repo.git\x00host="+addr+"\x00", <-requests)
+		assert.True(t, <-flushed, "the session should end with a flush-pkt")
+	})
+
+	t.Run("should return the error of the daemon", func(t *testing.T) {
+		t.Parallel()
+
+		addr, _, _ := newGitDaemon(t, pktline.Encode([]byte("ERR access denied or repository not exported: /nope.git")))
+		ep, err := transport.ParseEndpoint(fmt.Sprintf("git://%s/nope.git", addr))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{})
+		require.NoError(t, err)
+
+		_, err = tr.ListRefs()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, transport.ErrRemote), "unexpected error: %v", err)
+		assert.Contains(t, err.Error(), "access denied")
+		require.NoError(t, tr.Close())
+	})
+}
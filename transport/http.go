@@ -0,0 +1,309 @@
+package transport
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Nivl/git-go/env"
+
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+	"github.com/Nivl/git-go/internal/errutil"
+)
+
+var (
+	// ErrAuthenticationRequired is an error thrown when the remote
+	// requires credentials that were not provided, or are invalid
+	ErrAuthenticationRequired = errors.New("authentication required")
+	// ErrRepositoryNotFound is an error thrown when the remote
+	// repository doesn't exist
+	ErrRepositoryNotFound = errors.New("repository not found")
+	// ErrInvalidHeader is an error thrown when an extra header doesn't
+	// use the format "Name: value"
+	ErrInvalidHeader = errors.New("invalid header")
+)
+
+// userAgent is the user agent sent to the http(s) remotes
+const userAgent = "git/git-go"
+
+// httpTransport is a Transport using the smart HTTP protocol, or the
+// dumb HTTP protocol if the remote doesn't support the smart one
+// https://git-scm.com/docs/http-protocol
+type httpTransport struct {
+	ep     *Endpoint
+	client *http.Client
+	header http.Header
+	retry  RetryOptions
+	// trace receives the packets received from the remote
+	trace *trace.Tracer
+
+	// probed is set once we know which protocol the remote uses.
+	// dumb is set if the remote only supports the dumb protocol
+	probed bool
+	dumb   bool
+}
+
+// newHTTPTransport returns a transport using the smart HTTP
+// protocol
+func newHTTPTransport(ep *Endpoint, opts Options) (*httpTransport, error) {
+	header, err := parseExtraHeaders(opts.HTTPExtraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client, err = newHTTPClient(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &httpTransport{
+		ep:     ep,
+		client: client,
+		header: header,
+		retry:  opts.Retry,
+		trace:  trace.New(opts.Env, trace.KeyPacket),
+	}, nil
+}
+
+// parseExtraHeaders parses headers using the format "Name: value"
+func parseExtraHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, h := range headers {
+		i := strings.IndexByte(h, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("%q: %w", h, ErrInvalidHeader)
+		}
+		header.Add(strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:]))
+	}
+	return header, nil
+}
+
+// newHTTPClient returns a client using the proxy and TLS options
+func newHTTPClient(opts Options) (*http.Client, error) {
+	proxy, err := proxyFunc(opts.Env, opts.HTTPProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{} //nolint:gosec // we use the default values of the standard library
+	if opts.HTTPTLSConfig != nil {
+		tlsConfig = opts.HTTPTLSConfig.Clone()
+	}
+	// Like git, any value of GIT_SSL_NO_VERIFY disables the verification
+	if opts.HTTPSkipTLSVerify || opts.Env.Has("GIT_SSL_NO_VERIFY") {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = proxy
+	tr.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: tr}, nil
+}
+
+// proxyFunc returns a method returning the proxy to use for a request.
+// The provided proxy is used for all the requests, otherwise the
+// proxy is taken from the environment
+func proxyFunc(e *env.Env, proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy != "" {
+		u, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		noProxy := envProxy(e, "no_proxy")
+		return func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(noProxy, req.URL.Hostname()) {
+				return nil, nil
+			}
+			return u, nil
+		}, nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(envProxy(e, "no_proxy"), req.URL.Hostname()) {
+			return nil, nil
+		}
+		// Like curl, http_proxy is only read in lowercase
+		p := e.Get("http_proxy")
+		if req.URL.Scheme == "https" {
+			p = envProxy(e, "https_proxy")
+		}
+		if p == "" {
+			p = envProxy(e, "all_proxy")
+		}
+		if p == "" {
+			return nil, nil
+		}
+		return parseProxy(p)
+	}, nil
+}
+
+// envProxy returns the value of the given proxy environment variable,
+// looking at the uppercase variant if the variable is not set
+func envProxy(e *env.Env, name string) string {
+	if e.Has(name) {
+		return e.Get(name)
+	}
+	return e.Get(strings.ToUpper(name))
+}
+
+// parseProxy parses the URL of a proxy. Like git, the scheme is
+// optional and defaults to http
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %s: %w", proxy, err)
+	}
+	return u, nil
+}
+
+// bypassProxy returns whether the given host matches one of the
+// entries of noProxy, which is a comma separated list of domains
+// and IPs. "*" matches all the hosts
+func bypassProxy(noProxy, host string) bool {
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if strings.EqualFold(host, entry) || strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceURL returns the URL of the given file of the remote
+func (t *httpTransport) serviceURL(file string) string {
+	return strings.TrimSuffix(t.ep.String(), "/") + "/" + file
+}
+
+// ListRefs returns the references advertised by the remote.
+// The request is retried if it fails because of a transient error
+func (t *httpTransport) ListRefs() (adv *RefAdvertisement, err error) {
+	err = retry(t.retry, func() error {
+		adv, err = t.listRefs()
+		return err
+	})
+	return adv, err
+}
+
+// get sends a GET request for the given file of the remote
+func (t *httpTransport) get(file string) (*http.Response, error) {
+	return t.getRange(file, "")
+}
+
+// getRange sends a GET request for the given file of the remote,
+// using byteRange as Range header if not empty
+func (t *httpTransport) getRange(file, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, t.serviceURL(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the request: %w", err)
+	}
+	for name, values := range t.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if t.ep.User != "" || t.ep.Password != "" {
+		req.SetBasicAuth(t.ep.User, t.ep.Password)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("could not reach %s: %w", t.ep.String(), err)
+		if isNetworkError(err) {
+			err = transient(err)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// statusError returns the error matching the status code of the
+// response, or nil if the request succeeded
+func (t *httpTransport) statusError(res *http.Response) error {
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: %w", t.ep.String(), ErrAuthenticationRequired)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", t.ep.String(), ErrRepositoryNotFound)
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return transient(fmt.Errorf("unexpected HTTP status %d from %s", res.StatusCode, t.ep.String())) //nolint:goerr113 // no need to check this error
+	default:
+		return fmt.Errorf("unexpected HTTP status %d from %s", res.StatusCode, t.ep.String()) //nolint:goerr113 // no need to check this error
+	}
+}
+
+// listRefs requests the references advertised by the remote
+func (t *httpTransport) listRefs() (adv *RefAdvertisement, err error) {
+	res, err := t.get("info/refs?service=" + uploadPackService)
+	if err != nil {
+		return nil, err
+	}
+	defer errutil.Close(res.Body, &err)
+	if err = t.statusError(res); err != nil {
+		return nil, err
+	}
+
+	// A server that doesn't support the smart protocol returns
+	// the info/refs file as plain text
+	t.probed = true
+	t.dumb = res.Header.Get("Content-Type") != "application/x-"+uploadPackService+"-advertisement"
+	if t.dumb {
+		return t.listDumbRefs(res.Body)
+	}
+
+	// The smart protocol starts with the name of the service,
+	// followed by a flush-pkt
+	r := pktline.NewReaderWithOptions(res.Body, pktline.Options{Trace: t.trace})
+	line, ok, err := r.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the service line: %w", err)
+	}
+	if !ok || string(line) != "# service="+uploadPackService {
+		return nil, fmt.Errorf("unexpected service line %q: %w", line, ErrInvalidAdvertisement)
+	}
+	if _, ok, err = r.ReadLine(); err != nil || ok {
+		return nil, fmt.Errorf("missing flush-pkt after the service line: %w", ErrInvalidAdvertisement)
+	}
+	adv, err = ParseRefAdvertisement(r)
+	if err != nil {
+		if isNetworkError(err) {
+			err = transient(err)
+		}
+		return nil, err
+	}
+	// The rest of the body is ignored, but we read it anyway so the
+	// connection can be reused
+	if _, err = io.Copy(io.Discard, res.Body); err != nil {
+		return nil, fmt.Errorf("could not read the response: %w", err)
+	}
+	return adv, nil
+}
+
+// Close is a no-op since the protocol is stateless
+func (t *httpTransport) Close() error {
+	return nil
+}
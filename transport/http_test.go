@@ -0,0 +1,292 @@
+package transport_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSmartHTTPServer returns a server that advertises the refs of the
+// small repo at /repo.git, and requires the credentials user:pass
+// at /private.git
+func newSmartHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	advertise := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "git-upload-pack" {
+			http.Error(w, "dumb protocol", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		w.Write(pktline.Encode([]byte("# service=git-upload-pack\n"))) //nolint:errcheck // the test will fail anyway
+		w.Write([]byte(pktline.Flush))                                 //nolint:errcheck // the test will fail anyway
+		w.Write(advertisement(smallRepoAdvertisement...))              //nolint:errcheck // the test will fail anyway
+	}
+	mux.HandleFunc("/repo.git/info/refs", advertise)
+	mux.HandleFunc("/private.git/info/refs", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		advertise(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPListRefs(t *testing.T) {
+	t.Parallel()
+
+	srv := newSmartHTTPServer(t)
+
+	testCases := []struct {
+		desc          string
+		url           string
+		expectedError error
+	}{
+		{
+			desc: "public repository",
+			url:  srv.URL + "/repo.git",
+		},
+		{
+			desc: "trailing slash",
+			url:  srv.URL + "/repo.git/",
+		},
+		{
+			desc: "private repository with credentials",
+			url:  "http://user:pass@" + srv.Listener.Addr().String() + "/private.git",
+		},
+		{
+			desc:          "private repository without credentials",
+			url:           srv.URL + "/private.git",
+			expectedError: transport.ErrAuthenticationRequired,
+		},
+		{
+			desc:          "missing repository",
+			url:           srv.URL + "/nope.git",
+			expectedError: transport.ErrRepositoryNotFound,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			ep, err := transport.ParseEndpoint(tc.url)
+			require.NoError(t, err)
+			tr, err := transport.New(ep, transport.Options{})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, tr.Close())
+			})
+
+			adv, err := tr.ListRefs()
+			if tc.expectedError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tc.expectedError), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, adv.Refs, 5)
+			assert.Equal(t, "refs/heads/ml/packfile/tests", adv.Refs[0].SymbolicTarget)
+		})
+	}
+}
+
+func TestHTTPOptions(t *testing.T) {
+	t.Parallel()
+
+	// listRefs returns the refs advertised by the remote at the given
+	// URL
+	listRefs := func(t *testing.T, rawURL string, opts transport.Options) (*transport.RefAdvertisement, error) {
+		t.Helper()
+
+		ep, err := transport.ParseEndpoint(rawURL)
+		require.NoError(t, err)
+		tr, err := transport.New(ep, opts)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, tr.Close())
+		})
+		return tr.ListRefs()
+	}
+
+	t.Run("should send the extra headers", func(t *testing.T) {
+		t.Parallel()
+
+		remote := newSmartHTTPServer(t)
+		headers := make(chan http.Header, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers <- r.Header
+			remote.Config.Handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(srv.Close)
+
+		_, err := listRefs(t, srv.URL+"/repo.git", transport.Options{
+			HTTPExtraHeaders: []string{"Authorization: Bearer token", "X-Custom:value"},
+		})
+		require.NoError(t, err)
+		h := <-headers
+		assert.Equal(t, "Bearer token", h.Get("Authorization"))
+		assert.Equal(t, "value", h.Get("X-Custom"))
+	})
+
+	t.Run("should fail with an invalid header", func(t *testing.T) {
+		t.Parallel()
+
+		ep, err := transport.ParseEndpoint("https://example.com/repo.git")
+		require.NoError(t, err)
+		_, err = transport.New(ep, transport.Options{
+			HTTPExtraHeaders: []string{"no colon"},
+		})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, transport.ErrInvalidHeader), "unexpected error: %v", err)
+	})
+
+	t.Run("proxies", func(t *testing.T) {
+		t.Parallel()
+
+		// The proxy serves the remote at git.example.invalid, which
+		// cannot be reached without it
+		remote := newSmartHTTPServer(t)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host != "git.example.invalid" {
+				http.Error(w, "unexpected host "+r.URL.Host, http.StatusBadGateway)
+				return
+			}
+			remote.Config.Handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(proxy.Close)
+
+		testCases := []struct {
+			desc      string
+			opts      transport.Options
+			shouldErr bool
+		}{
+			{
+				desc: "http.proxy",
+				opts: transport.Options{
+					Env:       env.NewFromKVList([]string{}),
+					HTTPProxy: proxy.Listener.Addr().String(),
+				},
+			},
+			{
+				desc: "http_proxy",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{"http_proxy=" + proxy.URL}),
+				},
+			},
+			{
+				desc: "ALL_PROXY",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{"ALL_PROXY=" + proxy.URL}),
+				},
+			},
+			{
+				desc: "no_proxy should bypass the proxy",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{
+						"http_proxy=" + proxy.URL,
+						"no_proxy=localhost,.example.invalid",
+					}),
+				},
+				shouldErr: true,
+			},
+			{
+				desc: "no proxy",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{}),
+				},
+				shouldErr: true,
+			},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				adv, err := listRefs(t, "http://git.example.invalid/repo.git", tc.opts)
+				if tc.shouldErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, adv.Refs, 5)
+			})
+		}
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newSmartHTTPServer(t)
+		tlsSrv := httptest.NewTLSServer(srv.Config.Handler)
+		t.Cleanup(tlsSrv.Close)
+		pool := x509.NewCertPool()
+		pool.AddCert(tlsSrv.Certificate())
+
+		testCases := []struct {
+			desc      string
+			opts      transport.Options
+			shouldErr bool
+		}{
+			{
+				desc: "unknown certificate",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{}),
+				},
+				shouldErr: true,
+			},
+			{
+				desc: "custom TLS config",
+				opts: transport.Options{
+					Env:           env.NewFromKVList([]string{}),
+					HTTPTLSConfig: &tls.Config{RootCAs: pool}, //nolint:gosec // it's a test
+				},
+			},
+			{
+				desc: "HTTPSkipTLSVerify",
+				opts: transport.Options{
+					Env:               env.NewFromKVList([]string{}),
+					HTTPSkipTLSVerify: true,
+				},
+			},
+			{
+				desc: "GIT_SSL_NO_VERIFY",
+				opts: transport.Options{
+					Env: env.NewFromKVList([]string{"GIT_SSL_NO_VERIFY=1"}),
+				},
+			},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				adv, err := listRefs(t, tlsSrv.URL+"/repo.git", tc.opts)
+				if tc.shouldErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, adv.Refs, 5)
+			})
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/packfile"
+)
+
+// ErrInvalidPackfileURI is an error thrown when a packfile URI sent by
+// a remote cannot be parsed
+var ErrInvalidPackfileURI = errors.New("invalid packfile URI")
+
+// PackfileURI represents a packfile that a remote supporting the
+// packfile-uris capability asks the client to download from a URI,
+// instead of sending its objects in the response of a fetch
+// https://git-scm.com/docs/packfile-uri
+type PackfileURI struct {
+	// Hash contains the checksum of the packfile, which is also
+	// its ID
+	Hash ginternals.Oid
+	URI  string
+}
+
+// ParsePackfileURI parses a line of the packfile-uris section of the
+// response of a fetch, which uses the format:
+//
+//	<hash> SP <uri>
+func ParsePackfileURI(line string) (*PackfileURI, error) {
+	line = strings.TrimSuffix(line, "\n")
+	i := strings.IndexByte(line, ' ')
+	if i < 0 || i+1 == len(line) {
+		return nil, fmt.Errorf("%q: %w", line, ErrInvalidPackfileURI)
+	}
+	hash, err := ginternals.NewOidFromStr(line[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash in %q: %w", line, ErrInvalidPackfileURI)
+	}
+	return &PackfileURI{
+		Hash: hash,
+		URI:  line[i+1:],
+	}, nil
+}
+
+// FetchPackfileURIs downloads the given packfiles using the
+// URIDownloader of the options, indexes them, and adds them to dst.
+// The packfiles are verified against the hashes sent by the remote
+// before being added
+func FetchPackfileURIs(dst *backend.Backend, uris []*PackfileURI, opts Options) error {
+	d, err := newURIDownloader(opts)
+	if err != nil {
+		return err
+	}
+	for _, uri := range uris {
+		data, err := d.Download(uri.URI)
+		if err != nil {
+			return fmt.Errorf("could not download %s: %w", uri.URI, err)
+		}
+		if err = addDownloadedPackfile(dst, data, uri.Hash); err != nil {
+			return fmt.Errorf("could not add the packfile of %s: %w", uri.URI, err)
+		}
+	}
+	return nil
+}
+
+// addDownloadedPackfile indexes the given packfile and adds it to dst.
+// If expected is not NullOid, the ID of the packfile must match it
+func addDownloadedPackfile(dst *backend.Backend, data []byte, expected ginternals.Oid) error {
+	if len(data) < ginternals.OidSize {
+		return fmt.Errorf("packfile too small: %w", packfile.ErrInvalidMagic)
+	}
+	id, err := ginternals.NewOidFromHex(data[len(data)-ginternals.OidSize:])
+	if err != nil {
+		return fmt.Errorf("could not read the ID of the packfile: %w", err)
+	}
+	if !expected.IsZero() && id != expected {
+		return fmt.Errorf("expected packfile %s, got %s: %w", expected.String(), id.String(), packfile.ErrChecksumMismatch)
+	}
+	idx, err := packfile.BuildIndex(data)
+	if err != nil {
+		return fmt.Errorf("could not index the packfile: %w", err)
+	}
+	if _, err = dst.AddPackfile(data, idx); err != nil {
+		return fmt.Errorf("could not add the packfile: %w", err)
+	}
+	return nil
+}
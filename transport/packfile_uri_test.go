@@ -0,0 +1,134 @@
+package transport_test
+
+import (
+	"testing"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/object"
+	"github.com/Nivl/git-go/ginternals/packfile"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapDownloader is a URIDownloader serving files from memory
+type mapDownloader map[string][]byte
+
+func (d mapDownloader) Download(uri string) ([]byte, error) {
+	data, ok := d[uri]
+	if !ok {
+		return nil, transport.ErrURINotFound
+	}
+	return data, nil
+}
+
+// newEmptyBackend returns the backend of a new empty repository
+func newEmptyBackend(t *testing.T) *backend.Backend {
+	t.Helper()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	b, err := backend.NewFS(confutil.NewCommonConfig(t, dir))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+	require.NoError(t, b.Init(ginternals.Master))
+	return b
+}
+
+func TestParsePackfileURI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		line          string
+		expectedHash  string
+		expectedURI   string
+		expectedError error
+	}{
+		{
+			desc:         "valid line",
+			line:         "0163931160835b1de2f120e1aa7e52206debeb14 https://cdn.example.com/pack.pack\n",
+			expectedHash: "0163931160835b1de2f120e1aa7e52206debeb14",
+			expectedURI:  "https://cdn.example.com/pack.pack",
+		},
+		{
+			desc:          "missing URI",
+			line:          "0163931160835b1de2f120e1aa7e52206debeb14",
+			expectedError: transport.ErrInvalidPackfileURI,
+		},
+		{
+			desc:          "invalid hash",
+			line:          "nope https://cdn.example.com/pack.pack",
+			expectedError: transport.ErrInvalidPackfileURI,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			uri, err := transport.ParsePackfileURI(tc.line)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError, "test %d", i)
+				return
+			}
+			require.NoError(t, err, "test %d", i)
+			assert.Equal(t, tc.expectedHash, uri.Hash.String(), "test %d", i)
+			assert.Equal(t, tc.expectedURI, uri.URI, "test %d", i)
+		})
+	}
+}
+
+func TestFetchPackfileURIs(t *testing.T) {
+	t.Parallel()
+
+	blob := object.New(object.TypeBlob, []byte("from a CDN"))
+	pack, err := packfile.Build([]*packfile.BuildObject{
+		{Object: blob},
+	}, packfile.BuildOptions{})
+	require.NoError(t, err)
+	opts := transport.Options{
+		URIDownloader: mapDownloader{
+			"https://cdn.example.com/pack.pack": pack.Pack,
+		},
+	}
+
+	t.Run("should add the packfiles", func(t *testing.T) {
+		t.Parallel()
+
+		b := newEmptyBackend(t)
+		err := transport.FetchPackfileURIs(b, []*transport.PackfileURI{
+			{Hash: pack.ID, URI: "https://cdn.example.com/pack.pack"},
+		}, opts)
+		require.NoError(t, err)
+		has, err := b.HasObject(blob.ID())
+		require.NoError(t, err)
+		assert.True(t, has)
+	})
+
+	t.Run("should fail if the hash doesn't match", func(t *testing.T) {
+		t.Parallel()
+
+		b := newEmptyBackend(t)
+		err := transport.FetchPackfileURIs(b, []*transport.PackfileURI{
+			{Hash: blob.ID(), URI: "https://cdn.example.com/pack.pack"},
+		}, opts)
+		require.ErrorIs(t, err, packfile.ErrChecksumMismatch)
+	})
+
+	t.Run("should fail if the packfile doesn't exist", func(t *testing.T) {
+		t.Parallel()
+
+		b := newEmptyBackend(t)
+		err := transport.FetchPackfileURIs(b, []*transport.PackfileURI{
+			{Hash: pack.ID, URI: "https://cdn.example.com/nope.pack"},
+		}, opts)
+		require.ErrorIs(t, err, transport.ErrURINotFound)
+	})
+}
@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/pktline"
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+// errTransportClosed is an error thrown when a closed transport is used
+var errTransportClosed = errors.New("transport closed")
+
+// processTransport is a Transport talking to a git-upload-pack
+// process through its stdin and stdout. The process can be run
+// locally, or on the remote through ssh
+type processTransport struct {
+	// newCmd returns the command to run to start git-upload-pack
+	newCmd func() *exec.Cmd
+	env    *env.Env
+	// trace receives the packets exchanged with the remote
+	trace *trace.Tracer
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	closed bool
+}
+
+// ListRefs returns the references advertised by the remote.
+// The connection stays open until Close() is called
+func (t *processTransport) ListRefs() (*RefAdvertisement, error) {
+	if t.closed {
+		return nil, errTransportClosed
+	}
+	if t.cmd != nil {
+		return nil, errors.New("the references have already been listed") //nolint:goerr113 // this is a programming error
+	}
+
+	t.cmd = t.newCmd()
+	t.cmd.Env = t.env.List()
+	t.stderr = new(bytes.Buffer)
+	t.cmd.Stderr = t.stderr
+	var err error
+	if t.stdin, err = t.cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("could not get the stdin of %s: %w", t.cmd.Path, err)
+	}
+	if t.stdout, err = t.cmd.StdoutPipe(); err != nil {
+		return nil, fmt.Errorf("could not get the stdout of %s: %w", t.cmd.Path, err)
+	}
+	if err = t.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start %s: %w", t.cmd.Path, err)
+	}
+
+	adv, err := ParseRefAdvertisement(pktline.NewReaderWithOptions(t.stdout, t.pktlineOptions()))
+	if err != nil {
+		t.Close() //nolint:errcheck // it already failed
+		return nil, t.withStderr(err)
+	}
+	return adv, nil
+}
+
+// pktlineOptions returns the options used to read and write the
+// packets
+func (t *processTransport) pktlineOptions() pktline.Options {
+	return pktline.Options{Trace: t.trace}
+}
+
+// withStderr adds the error printed by the remote command, if any,
+// to the given error
+func (t *processTransport) withStderr(err error) error {
+	msg := strings.TrimSpace(t.stderr.String())
+	if msg == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// Close ends the session with the remote and waits for the remote
+// command to exit
+func (t *processTransport) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if t.cmd == nil {
+		return nil
+	}
+
+	// A flush-pkt tells the remote that we don't want anything
+	if err := pktline.NewWriterWithOptions(t.stdin, t.pktlineOptions()).WriteFlush(); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		t.cmd.Process.Kill() //nolint:errcheck // it already failed
+	}
+	t.stdin.Close() //nolint:errcheck // the remote will exit anyway
+	// Drain the output so the remote doesn't block
+	io.Copy(io.Discard, t.stdout) //nolint:errcheck // we're only draining the pipe
+	if err := t.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// Like git, we don't care about the exit code of
+			// the remote once we got what we wanted
+			return nil
+		}
+		return fmt.Errorf("could not wait for %s: %w", t.cmd.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryBackoff is the delay before the first retry, when none
+// is provided
+const defaultRetryBackoff = time.Second
+
+// RetryOptions represents the options used to retry the requests that
+// failed because of a transient error, like a network error or an
+// unavailable server
+type RetryOptions struct {
+	// MaxRetries is the maximum number of times a request is retried.
+	// Defaults to 0, which disables the retries
+	MaxRetries int
+	// Backoff is the delay before the first retry. The delay is
+	// doubled after each retry.
+	// Defaults to 1 second
+	Backoff time.Duration
+	// OnRetry is called before each retry with the number of the
+	// retry (starting at 1) and the error that caused it.
+	// If a file download got interrupted, err is a
+	// *PartialDownloadError containing the number of bytes already
+	// received, which won't be downloaded again if the server
+	// supports resuming the download
+	OnRetry func(retry int, err error)
+}
+
+// PartialDownloadError is the error returned when a download got
+// interrupted by a transient error. The data received so far are
+// kept, and the download is resumed where it stopped when retried, if
+// the server supports range requests.
+// PartialDownloadError wraps the error that interrupted the download
+type PartialDownloadError struct {
+	// Received contains the number of bytes of the file received so
+	// far
+	Received int64
+	// Total contains the size of the file, or -1 if the server
+	// didn't provide it
+	Total int64
+	Err   error
+}
+
+func (e *PartialDownloadError) Error() string {
+	if e.Total < 0 {
+		return fmt.Sprintf("download interrupted after %d bytes: %s", e.Received, e.Err.Error())
+	}
+	return fmt.Sprintf("download interrupted after %d/%d bytes: %s", e.Received, e.Total, e.Err.Error())
+}
+
+func (e *PartialDownloadError) Unwrap() error {
+	return e.Err
+}
+
+// transientError represents an error that may not happen again if
+// the request is retried
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// transient marks the given error as transient
+func transient(err error) error {
+	return &transientError{err: err}
+}
+
+// IsTransient returns whether the given error is transient, meaning
+// that retrying the request that failed may succeed
+func IsTransient(err error) bool {
+	var e *transientError
+	return errors.As(err, &e)
+}
+
+// isNetworkError returns whether err has been caused by the network,
+// and not by the remote or by the request itself
+func isNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	// The connection was closed by the remote
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retry runs f until it succeeds, returns a non-transient error, or
+// the maximum number of retries has been reached
+func retry(opts RetryOptions, f func() error) error {
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || !IsTransient(err) || i > opts.MaxRetries {
+			return err
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(i, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// partialDownload contains the data of a file being downloaded over
+// HTTP, so the download can be resumed if it gets interrupted
+type partialDownload struct {
+	data  []byte
+	total int64
+}
+
+// rangeHeader returns the value of the Range header to use to only
+// request what hasn't been received yet, or an empty string if nothing
+// has been received
+func (d *partialDownload) rangeHeader() string {
+	if len(d.data) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("bytes=%d-", len(d.data))
+}
+
+// read reads the body of the given response, which contains either
+// the whole file, or the part of the file that was requested using
+// rangeHeader().
+// If the body cannot be fully read because of a network error, a
+// transient *PartialDownloadError is returned and the data received
+// so far are kept
+func (d *partialDownload) read(res *http.Response, name string) error {
+	if res.StatusCode == http.StatusPartialContent {
+		start, total, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || start != int64(len(d.data)) {
+			return fmt.Errorf("unexpected range %q received for %s", res.Header.Get("Content-Range"), name) //nolint:goerr113 // no need to check this error
+		}
+		d.total = total
+	} else {
+		// The server doesn't support ranges, so we're getting the
+		// whole file again
+		d.data = d.data[:0]
+		d.total = res.ContentLength
+	}
+
+	buf := bytes.NewBuffer(d.data)
+	_, err := io.Copy(buf, res.Body)
+	d.data = buf.Bytes()
+	if err != nil {
+		err = fmt.Errorf("could not read %s: %w", name, err)
+		if !isNetworkError(err) {
+			return err
+		}
+		return &PartialDownloadError{
+			Received: int64(len(d.data)),
+			Total:    d.total,
+			Err:      transient(err),
+		}
+	}
+	return nil
+}
+
+// parseContentRange parses the value of a Content-Range header
+// ("bytes <start>-<end>/<total>"). total is -1 if the size of the
+// file is unknown ("*")
+func parseContentRange(value string) (start, total int64, ok bool) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, false
+	}
+	value = strings.TrimPrefix(value, "bytes ")
+	i := strings.IndexByte(value, '-')
+	j := strings.IndexByte(value, '/')
+	if i <= 0 || j < i {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(value[:i], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if value[j+1:] == "*" {
+		return start, -1, true
+	}
+	total, err = strconv.ParseInt(value[j+1:], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
@@ -0,0 +1,225 @@
+package transport_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	// newFlakyServer returns a server that answers the first requests
+	// with the given status, and then serves the small repo.
+	// The number of requests received is stored in requests
+	newFlakyServer := func(t *testing.T, status, failures int) (srv *httptest.Server, requests *int32) {
+		t.Helper()
+
+		remote := newSmartHTTPServer(t)
+		requests = new(int32)
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(atomic.AddInt32(requests, 1)) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			remote.Config.Handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(srv.Close)
+		return srv, requests
+	}
+
+	testCases := []struct {
+		desc             string
+		status           int
+		failures         int
+		maxRetries       int
+		expectedRequests int32
+		shouldFail       bool
+	}{
+		{
+			desc:             "should retry until it succeeds",
+			status:           http.StatusServiceUnavailable,
+			failures:         2,
+			maxRetries:       3,
+			expectedRequests: 3,
+		},
+		{
+			desc:             "should give up after the max retries",
+			status:           http.StatusBadGateway,
+			failures:         5,
+			maxRetries:       2,
+			expectedRequests: 3,
+			shouldFail:       true,
+		},
+		{
+			desc:             "should not retry by default",
+			status:           http.StatusServiceUnavailable,
+			failures:         1,
+			expectedRequests: 1,
+			shouldFail:       true,
+		},
+		{
+			desc:             "should not retry a missing repository",
+			status:           http.StatusNotFound,
+			failures:         1,
+			maxRetries:       3,
+			expectedRequests: 1,
+			shouldFail:       true,
+		},
+	}
+	for i, tc := range testCases {
+		tc := tc
+		i := i
+		t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+			t.Parallel()
+
+			srv, requests := newFlakyServer(t, tc.status, tc.failures)
+			retries := []int{}
+			ep, err := transport.ParseEndpoint(srv.URL + "/repo.git")
+			require.NoError(t, err)
+			tr, err := transport.New(ep, transport.Options{
+				Retry: transport.RetryOptions{
+					MaxRetries: tc.maxRetries,
+					Backoff:    time.Millisecond,
+					OnRetry: func(retry int, err error) {
+						retries = append(retries, retry)
+					},
+				},
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, tr.Close())
+			})
+
+			_, err = tr.ListRefs()
+			assert.Equal(t, tc.expectedRequests, atomic.LoadInt32(requests))
+			assert.Len(t, retries, int(tc.expectedRequests)-1)
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("should retry a refused git:// connection", func(t *testing.T) {
+		t.Parallel()
+
+		// We reserve a port, and we only start listening after the
+		// first retry
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		ep, err := transport.ParseEndpoint("git://" + addr + "/repo.git")
+		require.NoError(t, err)
+		var connErr error
+		tr, err := transport.New(ep, transport.Options{
+			Retry: transport.RetryOptions{
+				MaxRetries: 1,
+				Backoff:    time.Millisecond,
+				OnRetry: func(retry int, err error) {
+					connErr = err
+					l, err = net.Listen("tcp", addr)
+					require.NoError(t, err)
+					go func() {
+						conn, err := l.Accept()
+						if err != nil {
+							return
+						}
+						defer conn.Close()                                   //nolint:errcheck // the test is over
+						conn.Write(advertisement(smallRepoAdvertisement...)) //nolint:errcheck // the test will fail anyway
+					}()
+				},
+			},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, tr.Close())
+			if l != nil {
+				l.Close() //nolint:errcheck // the test is over
+			}
+		})
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		assert.Len(t, adv.Refs, 5)
+		require.Error(t, connErr)
+		assert.True(t, transport.IsTransient(connErr), "the error should be transient")
+	})
+
+	t.Run("IsTransient", func(t *testing.T) {
+		t.Parallel()
+
+		assert.False(t, transport.IsTransient(errors.New("nope")))
+		assert.False(t, transport.IsTransient(nil))
+	})
+}
+
+// interruptingHandler wraps a handler so the first response to a
+// request for a file ending with suffix gets interrupted halfway
+// through. The Range headers of the following requests for the file
+// are recorded
+type interruptingHandler struct {
+	h      http.Handler
+	suffix string
+	// ignoreRange removes the Range header of the requests, like
+	// a server that doesn't support range requests would do
+	ignoreRange bool
+
+	mu          sync.Mutex
+	interrupted bool
+	ranges      []string
+}
+
+func (h *interruptingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, h.suffix) {
+		h.h.ServeHTTP(w, r)
+		return
+	}
+	h.mu.Lock()
+	first := !h.interrupted
+	h.interrupted = true
+	if !first {
+		h.ranges = append(h.ranges, r.Header.Get("Range"))
+	}
+	h.mu.Unlock()
+
+	if h.ignoreRange {
+		r.Header.Del("Range")
+	}
+	if !first {
+		h.h.ServeHTTP(w, r)
+		return
+	}
+
+	rec := httptest.NewRecorder()
+	h.h.ServeHTTP(rec, r)
+	body := rec.Body.Bytes()
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(rec.Code)
+	w.Write(body[:len(body)/2]) //nolint:errcheck // the connection is dropped anyway
+	w.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+// receivedRanges returns the Range headers received after the
+// interruption
+func (h *interruptingHandler) receivedRanges() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.ranges...)
+}
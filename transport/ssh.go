@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals/trace"
+)
+
+// newSSHTransport returns a transport running git-upload-pack on the
+// remote through ssh
+func newSSHTransport(ep *Endpoint, opts Options) *processTransport {
+	return &processTransport{
+		newCmd: func() *exec.Cmd {
+			return sshCommand(ep, opts.Env)
+		},
+		env:   opts.Env,
+		trace: trace.New(opts.Env, trace.KeyPacket),
+	}
+}
+
+// sshCommand returns the command to run to start git-upload-pack on
+// the given remote.
+// Like git, the command can be changed using GIT_SSH_COMMAND, which
+// is run by the shell, or GIT_SSH, which is run directly
+func sshCommand(ep *Endpoint, e *env.Env) *exec.Cmd {
+	args := []string{}
+	if ep.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(ep.Port))
+	}
+	dest := ep.Host
+	if ep.User != "" {
+		dest = ep.User + "@" + dest
+	}
+	args = append(args, dest, uploadPackService+" "+shellQuote(ep.Path))
+
+	if command := e.Get("GIT_SSH_COMMAND"); command != "" {
+		return exec.Command("sh", append([]string{"-c", command + ` "$@"`, command}, args...)...) //nolint:gosec // running a user-defined command is the whole point
+	}
+	program := e.Get("GIT_SSH")
+	if program == "" {
+		program = "ssh"
+	}
+	return exec.Command(program, args...) //nolint:gosec // running a user-defined command is the whole point
+}
+
+// shellQuote quotes s so it can safely be used as a single argument
+// in a shell command
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
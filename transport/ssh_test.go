@@ -0,0 +1,132 @@
+package transport_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeSSH creates a script that can be used as GIT_SSH. The script
+// logs its arguments in a file, and runs the remote command locally
+func newFakeSSH(t *testing.T) (script, argsLog string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is needed to run this test")
+	}
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git-upload-pack is needed to run this test")
+	}
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	script = filepath.Join(dir, "ssh")
+	argsLog = filepath.Join(dir, "args")
+	content := `#!/bin/sh
+echo "$@" > '` + argsLog + `'
+# the last argument is the command to run on the remote
+while [ $# -gt 1 ]; do shift; done
+exec sh -c "$1"
+`
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o755)) //nolint:gosec // the script needs to be executable
+	return script, argsLog
+}
+
+func TestSSHListRefs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should list the refs using GIT_SSH", func(t *testing.T) {
+		t.Parallel()
+
+		script, argsLog := newFakeSSH(t)
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint("ssh://git@localhost:2222" + filepath.ToSlash(repoPath))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{
+			Env: env.NewFromKVList([]string{
+				"PATH=" + os.Getenv("PATH"),
+				"HOME=" + os.Getenv("HOME"),
+				"GIT_SSH=" + script,
+			}),
+		})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+
+		head := adv.Ref("HEAD")
+		require.NotNil(t, head)
+		assert.Equal(t, "bbb720a96e4c29b9950a4c577c98470a4d5dd089", head.ID.String())
+		assert.Equal(t, "refs/heads/ml/packfile/tests", head.SymbolicTarget)
+		tag := adv.Ref("refs/tags/annotated")
+		require.NotNil(t, tag)
+		assert.Equal(t, "6097a04b7a327c4be68f222ca66e61b8e1abe5c1", tag.Peeled.String())
+
+		args, err := os.ReadFile(argsLog)
+		require.NoError(t, err)
+		assert.Equal(t, "-p 2222 git@localhost git-upload-pack '"+filepath.ToSlash(repoPath)+"'", strings.TrimSpace(string(args)))
+	})
+
+	t.Run("GIT_SSH_COMMAND should be run by the shell", func(t *testing.T) {
+		t.Parallel()
+
+		script, argsLog := newFakeSSH(t)
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint("localhost:" + filepath.ToSlash(repoPath))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{
+			Env: env.NewFromKVList([]string{
+				"PATH=" + os.Getenv("PATH"),
+				"HOME=" + os.Getenv("HOME"),
+				"GIT_SSH_COMMAND=" + script + " -o User=git",
+				"GIT_SSH=/does/not/exist",
+			}),
+		})
+		require.NoError(t, err)
+
+		adv, err := tr.ListRefs()
+		require.NoError(t, err)
+		require.NoError(t, tr.Close())
+		assert.NotEmpty(t, adv.Refs)
+
+		args, err := os.ReadFile(argsLog)
+		require.NoError(t, err)
+		assert.Equal(t, "-o User=git localhost git-upload-pack '"+filepath.ToSlash(repoPath)+"'", strings.TrimSpace(string(args)))
+	})
+
+	t.Run("should return the error of the remote", func(t *testing.T) {
+		t.Parallel()
+
+		script, _ := newFakeSSH(t)
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+
+		ep, err := transport.ParseEndpoint("localhost:" + filepath.ToSlash(filepath.Join(dir, "nope")))
+		require.NoError(t, err)
+		tr, err := transport.New(ep, transport.Options{
+			Env: env.NewFromKVList([]string{
+				"PATH=" + os.Getenv("PATH"),
+				"GIT_SSH=" + script,
+			}),
+		})
+		require.NoError(t, err)
+
+		_, err = tr.ListRefs()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not appear to be a git repository")
+		require.NoError(t, tr.Close())
+	})
+}
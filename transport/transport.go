@@ -0,0 +1,122 @@
+// Package transport contains methods to talk to remote repositories
+// using the git protocols
+// https://git-scm.com/docs/pack-protocol
+package transport
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Nivl/git-go/backend"
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/ginternals"
+	"github.com/Nivl/git-go/ginternals/config"
+)
+
+// ErrUnsupportedProtocol is an error thrown when a remote uses
+// a protocol that is not supported
+var ErrUnsupportedProtocol = errors.New("unsupported protocol")
+
+// uploadPackService is the name of the service used to fetch from
+// a remote
+const uploadPackService = "git-upload-pack"
+
+// Transport represents a connection to a remote repository
+type Transport interface {
+	// ListRefs returns the references advertised by the remote.
+	// No objects are downloaded
+	ListRefs() (*RefAdvertisement, error)
+	// Close closes the connection to the remote
+	Close() error
+}
+
+// Fetcher is implemented by the transports that can download
+// objects from the remote
+type Fetcher interface {
+	// Fetch downloads the given objects, and all the objects they
+	// reference, into dst.
+	// Like git, the objects that dst already has are assumed to be
+	// complete, and the objects they reference are not downloaded
+	Fetch(dst *backend.Backend, wants []ginternals.Oid) error
+}
+
+// Options represents the options used to connect to a remote
+type Options struct {
+	// Env contains the environment variables used to connect to the
+	// remote, like GIT_SSH_COMMAND.
+	// Defaults to the environment of the process
+	Env *env.Env
+	// HTTPClient is the client used to connect to the http(s) remotes.
+	// When set, HTTPProxy, HTTPTLSConfig, and HTTPSkipTLSVerify are
+	// ignored.
+	// Defaults to a client using the other HTTP options
+	HTTPClient *http.Client
+	// HTTPProxy is the URL of the proxy used to connect to the http(s)
+	// remotes.
+	// Defaults to the proxy set in the environment (http_proxy,
+	// https_proxy, all_proxy, and no_proxy)
+	HTTPProxy string
+	// HTTPTLSConfig is the TLS configuration used to connect to the
+	// https remotes
+	HTTPTLSConfig *tls.Config
+	// HTTPSkipTLSVerify disables the verification of the certificates
+	// of the https remotes.
+	// Setting GIT_SSL_NO_VERIFY has the same effect
+	HTTPSkipTLSVerify bool
+	// HTTPExtraHeaders contains headers sent with every http(s)
+	// request, using the format "Name: value"
+	HTTPExtraHeaders []string
+	// Retry contains the options used to retry the requests that
+	// failed because of a network error or an unavailable server.
+	// Only the http(s) and git protocols retry the requests.
+	// Defaults to no retries
+	Retry RetryOptions
+	// UploadPack is the program to run to list the references of a
+	// local repository (ex. git-upload-pack). The program receives
+	// the path of the repository as only argument.
+	// Defaults to reading the repository directly
+	UploadPack string
+	// URIDownloader is used to download the packfiles and the bundles
+	// that the remote asks to download from somewhere else, like a
+	// CDN.
+	// Defaults to the downloader returned by NewURIDownloader()
+	URIDownloader URIDownloader
+}
+
+// ApplyConfig sets the options that are configurable using the config
+// files (http.proxy, http.sslVerify, and http.extraHeader).
+// The options that are already set are left untouched, except for the
+// extra headers which are added to the existing ones
+func (opts *Options) ApplyConfig(cfg *config.FileAggregate) {
+	if proxy, ok := cfg.HTTPProxy(); ok && opts.HTTPProxy == "" {
+		opts.HTTPProxy = proxy
+	}
+	if verify, ok := cfg.HTTPSSLVerify(); ok && !verify {
+		opts.HTTPSkipTLSVerify = true
+	}
+	if header, ok := cfg.HTTPExtraHeader(); ok {
+		opts.HTTPExtraHeaders = append(opts.HTTPExtraHeaders, header)
+	}
+}
+
+// New returns a Transport to connect to the given remote.
+// The connection is only established when needed
+func New(ep *Endpoint, opts Options) (Transport, error) {
+	if opts.Env == nil {
+		opts.Env = env.NewFromOs()
+	}
+	switch ep.Protocol {
+	case ProtocolHTTP, ProtocolHTTPS:
+		return newHTTPTransport(ep, opts)
+	case ProtocolSSH:
+		return newSSHTransport(ep, opts), nil
+	case ProtocolGit:
+		return newGitTransport(ep, opts), nil
+	case ProtocolFile:
+		return newFileTransport(ep, opts), nil
+	default:
+		return nil, fmt.Errorf("%s: %w", ep.Protocol, ErrUnsupportedProtocol)
+	}
+}
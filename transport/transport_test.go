@@ -0,0 +1,50 @@
+package transport_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/internal/testutil/confutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := testutil.TempDir(t)
+	t.Cleanup(cleanup)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "config"), []byte(`
+[http]
+	proxy = proxy.example.com:3128
+	sslVerify = false
+	extraHeader = X-Custom: value
+`), 0o644))
+	cfg := confutil.NewCommonConfig(t, dir)
+
+	t.Run("should set the options", func(t *testing.T) {
+		t.Parallel()
+
+		opts := transport.Options{}
+		opts.ApplyConfig(cfg.FromFile())
+		assert.Equal(t, "proxy.example.com:3128", opts.HTTPProxy)
+		assert.True(t, opts.HTTPSkipTLSVerify)
+		assert.Equal(t, []string{"X-Custom: value"}, opts.HTTPExtraHeaders)
+	})
+
+	t.Run("should not override the options", func(t *testing.T) {
+		t.Parallel()
+
+		opts := transport.Options{
+			HTTPProxy:        "http://other.example.com",
+			HTTPExtraHeaders: []string{"X-Other: value"},
+		}
+		opts.ApplyConfig(cfg.FromFile())
+		assert.Equal(t, "http://other.example.com", opts.HTTPProxy)
+		assert.Equal(t, []string{"X-Other: value", "X-Custom: value"}, opts.HTTPExtraHeaders)
+	})
+}
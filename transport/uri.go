@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/Nivl/git-go/env"
+	"github.com/Nivl/git-go/internal/errutil"
+)
+
+// ErrURINotFound is an error thrown when a file downloaded using
+// a URI doesn't exist
+var ErrURINotFound = errors.New("URI not found")
+
+// URIDownloader downloads the files that a remote asks the client to
+// download from somewhere else than the remote itself, like the
+// packfiles of the packfile-uris capability, or the bundles of the
+// bundle-uri capability. This allows most of the objects of a clone to
+// be served by a CDN.
+// Implementing this interface allows the files to be downloaded using
+// a custom client, or to be read from a local cache
+type URIDownloader interface {
+	// Download returns the content of the file at the given URI
+	Download(uri string) ([]byte, error)
+}
+
+// NewURIDownloader returns the URIDownloader used when
+// Options.URIDownloader is not set. The http(s) URIs are downloaded
+// using the HTTP options, and the file:// URIs are read from the disk.
+// The requests are retried if they fail because of a transient error,
+// and the interrupted downloads are resumed if the server supports it
+func NewURIDownloader(opts Options) (URIDownloader, error) {
+	if opts.Env == nil {
+		opts.Env = env.NewFromOs()
+	}
+	header, err := parseExtraHeaders(opts.HTTPExtraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client, err = newHTTPClient(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &uriDownloader{
+		client: client,
+		header: header,
+		retry:  opts.Retry,
+	}, nil
+}
+
+// newURIDownloader returns the URIDownloader set in the options, or
+// the default one
+func newURIDownloader(opts Options) (URIDownloader, error) {
+	if opts.URIDownloader != nil {
+		return opts.URIDownloader, nil
+	}
+	return NewURIDownloader(opts)
+}
+
+// uriDownloader is the default URIDownloader
+type uriDownloader struct {
+	client *http.Client
+	header http.Header
+	retry  RetryOptions
+}
+
+// Download returns the content of the file at the given URI
+func (d *uriDownloader) Download(uri string) (data []byte, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", uri, ErrInvalidEndpoint)
+	}
+	switch Protocol(u.Scheme) {
+	case ProtocolHTTP, ProtocolHTTPS:
+		partial := &partialDownload{}
+		err = retry(d.retry, func() error {
+			return d.downloadHTTP(u, partial)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return partial.data, nil
+	case ProtocolFile:
+		data, err = os.ReadFile(u.Path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%s: %w", uri, ErrURINotFound)
+			}
+			return nil, fmt.Errorf("could not read %s: %w", u.Path, err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", u.Scheme, ErrUnsupportedProtocol)
+	}
+}
+
+// downloadHTTP downloads the file at the given http(s) URL.
+// If some data have already been received, only the rest of the file
+// is requested
+func (d *uriDownloader) downloadHTTP(u *url.URL, partial *partialDownload) (err error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("could not create the request: %w", err)
+	}
+	for name, values := range d.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if byteRange := partial.rangeHeader(); byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("could not reach %s: %w", u.Redacted(), err)
+		if isNetworkError(err) {
+			err = transient(err)
+		}
+		return err
+	}
+	defer errutil.Close(res.Body, &err)
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", u.Redacted(), ErrURINotFound)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: %w", u.Redacted(), ErrAuthenticationRequired)
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return transient(fmt.Errorf("unexpected HTTP status %d from %s", res.StatusCode, u.Redacted())) //nolint:goerr113 // no need to check this error
+	default:
+		return fmt.Errorf("unexpected HTTP status %d from %s", res.StatusCode, u.Redacted()) //nolint:goerr113 // no need to check this error
+	}
+	return partial.read(res, u.Redacted())
+}
@@ -0,0 +1,145 @@
+package transport_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/Nivl/git-go/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURIDownloader(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pack.pack", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("content")) //nolint:errcheck // the test will fail if the write fails
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	d, err := transport.NewURIDownloader(transport.Options{
+		HTTPExtraHeaders: []string{"X-Token: secret"},
+	})
+	require.NoError(t, err)
+
+	t.Run("should download http URIs", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := d.Download(srv.URL + "/pack.pack")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("content"), data)
+	})
+
+	t.Run("should send the extra headers", func(t *testing.T) {
+		t.Parallel()
+
+		noHeaders, err := transport.NewURIDownloader(transport.Options{})
+		require.NoError(t, err)
+		_, err = noHeaders.Download(srv.URL + "/pack.pack")
+		require.ErrorIs(t, err, transport.ErrAuthenticationRequired)
+	})
+
+	t.Run("should fail on missing files", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := d.Download(srv.URL + "/nope.pack")
+		require.ErrorIs(t, err, transport.ErrURINotFound)
+	})
+
+	t.Run("should read file URIs", func(t *testing.T) {
+		t.Parallel()
+
+		dir, cleanup := testutil.TempDir(t)
+		t.Cleanup(cleanup)
+		p := filepath.Join(dir, "repo.bundle")
+		require.NoError(t, os.WriteFile(p, []byte("bundle"), 0o644))
+
+		data, err := d.Download("file://" + p)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bundle"), data)
+
+		_, err = d.Download("file://" + filepath.Join(dir, "nope"))
+		require.ErrorIs(t, err, transport.ErrURINotFound)
+	})
+
+	t.Run("should fail on unsupported protocols", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := d.Download("ftp://example.com/pack.pack")
+		require.ErrorIs(t, err, transport.ErrUnsupportedProtocol)
+	})
+
+	t.Run("should resume interrupted downloads", func(t *testing.T) {
+		t.Parallel()
+
+		content := bytes.Repeat([]byte("0123456789"), 1000)
+		testCases := []struct {
+			desc           string
+			ignoreRange    bool
+			expectedRanges []string
+		}{
+			{
+				desc:           "server supporting ranges",
+				expectedRanges: []string{"bytes=5000-"},
+			},
+			{
+				desc:           "server ignoring ranges",
+				ignoreRange:    true,
+				expectedRanges: []string{"bytes=5000-"},
+			},
+		}
+		for i, tc := range testCases {
+			tc := tc
+			i := i
+			t.Run(fmt.Sprintf("%d/%s", i, tc.desc), func(t *testing.T) {
+				t.Parallel()
+
+				h := &interruptingHandler{
+					suffix:      ".pack",
+					ignoreRange: tc.ignoreRange,
+					h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						http.ServeContent(w, r, "pack.pack", time.Time{}, bytes.NewReader(content))
+					}),
+				}
+				srv := httptest.NewServer(h)
+				t.Cleanup(srv.Close)
+
+				var retryErr error
+				d, err := transport.NewURIDownloader(transport.Options{
+					Retry: transport.RetryOptions{
+						MaxRetries: 1,
+						Backoff:    time.Millisecond,
+						OnRetry: func(retry int, err error) {
+							retryErr = err
+						},
+					},
+				})
+				require.NoError(t, err)
+				data, err := d.Download(srv.URL + "/pack.pack")
+				require.NoError(t, err)
+				assert.Equal(t, content, data)
+				assert.Equal(t, tc.expectedRanges, h.receivedRanges())
+
+				var partialErr *transport.PartialDownloadError
+				require.True(t, errors.As(retryErr, &partialErr), "unexpected error: %v", retryErr)
+				assert.Equal(t, int64(5000), partialErr.Received)
+				assert.Equal(t, int64(len(content)), partialErr.Total)
+				assert.True(t, transport.IsTransient(retryErr), "the error should be transient")
+			})
+		}
+	})
+}
@@ -39,19 +39,19 @@ func (r *Repository) NewTreeBuilderFromTree(t *object.Tree) *TreeBuilder {
 // Insert inserts a new object in a tree
 func (tb *TreeBuilder) Insert(path string, oid ginternals.Oid, mode object.TreeObjectMode) error {
 	if !mode.IsValid() {
-		//nolint:goerr113 // no need to wrap the error, this would only be caused by a bug in the codebase
-		return fmt.Errorf("invalid mode %o", mode)
+		return fmt.Errorf("%o: %w", mode, object.ErrTreeModeInvalid)
 	}
 
-	o, err := tb.Backend.Object(oid)
-	if err != nil {
-		return fmt.Errorf("cannot verify object: %w", err)
-	}
-
-	// TODO(melvin):
-	// 2. gitlink?
-	if o.Type() != object.TypeBlob && o.Type() != object.TypeTree {
-		return fmt.Errorf("unexpected object %s: %w", o.Type().String(), object.ErrObjectInvalid)
+	// Gitlinks point to commits of other repositories, so there's
+	// nothing we can verify
+	if mode != object.ModeGitLink {
+		o, err := tb.Backend.Object(oid)
+		if err != nil {
+			return fmt.Errorf("cannot verify object: %w", err)
+		}
+		if o.Type() != object.TypeBlob && o.Type() != object.TypeTree {
+			return fmt.Errorf("unexpected object %s: %w", o.Type().String(), object.ErrObjectInvalid)
+		}
 	}
 
 	e := object.TreeEntry{
@@ -77,19 +77,16 @@ func (tb *TreeBuilder) Remove(path string) {
 
 // Write creates and persists a new Tree object
 func (tb *TreeBuilder) Write() (*object.Tree, error) {
-	// We need to order all our entries alphabetically
-	// We're going to extract the paths of the map
-	// and just loop over the keys instead of the entries
-	paths := make([]string, 0, len(tb.entries))
-	for p := range tb.entries {
-		paths = append(paths, p)
-	}
-	sort.Strings(paths)
-
-	entries := make([]object.TreeEntry, 0, len(paths))
-	for _, p := range paths {
-		entries = append(entries, tb.entries[p])
+	// The entries need to be sorted the way git sorts them, which
+	// is alphabetically, but with a / appended to the name of the
+	// directories
+	entries := make([]object.TreeEntry, 0, len(tb.entries))
+	for _, e := range tb.entries {
+		entries = append(entries, e)
 	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compareTreeEntries(entries[i], entries[j]) < 0
+	})
 
 	t := object.NewTree(entries)
 	o := t.ToObject()
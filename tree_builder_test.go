@@ -21,6 +21,7 @@ func TestTreeBuilderInsert(t *testing.T) {
 		testCases := []struct {
 			desc          string
 			sha           string
+			mode          object.TreeObjectMode
 			expectedError error
 		}{
 			{
@@ -41,6 +42,11 @@ func TestTreeBuilderInsert(t *testing.T) {
 				desc: "should pass inserting a tree",
 				sha:  "e5b9e846e1b468bc9597ff95d71dfacda8bd54e3",
 			},
+			{
+				desc: "should pass inserting a gitlink to a commit of another repo",
+				sha:  "0348308155fbeb0015970d1a30666e51a2ea0e9f",
+				mode: object.ModeGitLink,
+			},
 		}
 		for i, tc := range testCases {
 			tc := tc
@@ -60,8 +66,12 @@ func TestTreeBuilderInsert(t *testing.T) {
 				oid, err := ginternals.NewOidFromStr(tc.sha)
 				require.NoError(t, err)
 
+				mode := tc.mode
+				if mode == 0 {
+					mode = object.ModeFile
+				}
 				tb := r.NewTreeBuilder()
-				err = tb.Insert("somewhere", oid, object.ModeFile)
+				err = tb.Insert("somewhere", oid, mode)
 				if tc.expectedError != nil {
 					require.Error(t, err)
 					assert.True(t, errors.Is(err, tc.expectedError))
@@ -155,7 +165,7 @@ func TestTreeBuilderInsert(t *testing.T) {
 		oid, err := ginternals.NewOidFromStr("642480605b8b0fd464ab5762e044269cf29a60a3")
 		require.NoError(t, err)
 		err = tb.Insert("path", oid, 0o644)
-		require.Error(t, err)
+		require.ErrorIs(t, err, object.ErrTreeModeInvalid)
 	})
 }
 
@@ -311,4 +321,36 @@ func TestTreeBuilderWrite(t *testing.T) {
 		assert.Equal(t, tree.ID().String(), newTree.ID().String())
 		assert.Equal(t, tree.Entries(), newTree.Entries())
 	})
+	t.Run("should sort the directories as if they ended with a /", func(t *testing.T) {
+		t.Parallel()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err, "failed loading a repo")
+		t.Cleanup(func() {
+			require.NoError(t, r.Close(), "failed closing repo")
+		})
+
+		blob, err := ginternals.NewOidFromStr("642480605b8b0fd464ab5762e044269cf29a60a3")
+		require.NoError(t, err)
+		tree, err := ginternals.NewOidFromStr("e5b9e846e1b468bc9597ff95d71dfacda8bd54e3")
+		require.NoError(t, err)
+
+		tb := r.NewTreeBuilder()
+		require.NoError(t, tb.Insert("a0", blob, object.ModeFile))
+		require.NoError(t, tb.Insert("a", tree, object.ModeDirectory))
+		require.NoError(t, tb.Insert("a.txt", blob, object.ModeFile))
+		require.NoError(t, tb.Insert("a-b", blob, object.ModeFile))
+		newTree, err := tb.Write()
+		require.NoError(t, err)
+
+		paths := []string{}
+		for _, e := range newTree.Entries() {
+			paths = append(paths, e.Path)
+		}
+		// '-' < '.' < '/' < '0'
+		assert.Equal(t, []string{"a-b", "a.txt", "a", "a0"}, paths)
+	})
 }
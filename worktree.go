@@ -0,0 +1,265 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nivl/git-go/ginternals/lockfile"
+	"github.com/spf13/afero"
+)
+
+// List of errors returned when managing worktrees
+var (
+	ErrWorktreeNotFound = errors.New("not a linked worktree")
+	ErrWorktreeLocked   = errors.New("worktree is locked")
+	ErrWorktreeExists   = errors.New("destination already exists")
+)
+
+const (
+	// worktreesDirName is the name of the directory of the common
+	// dir containing the administrative files of the linked worktrees
+	worktreesDirName = "worktrees"
+	// gitdirFileName is the name of the file that contains the path
+	// of the .git file of a linked worktree
+	gitdirFileName = "gitdir"
+	// lockedFileName is the name of the file that marks a linked
+	// worktree as locked
+	lockedFileName = "locked"
+	// gitfilePrefix is the prefix of the content of the .git file of
+	// a linked worktree
+	gitfilePrefix = "gitdir: "
+)
+
+// Worktree represents a linked worktree, created using
+// git worktree add
+type Worktree struct {
+	// Name is the name of the administrative directory of the
+	// worktree, in $GIT_COMMON_DIR/worktrees
+	Name string
+	// Path is the path of the working tree
+	Path string
+	// AdminDir is the path of the administrative directory
+	AdminDir string
+	// Locked is set to true if the worktree cannot be moved or
+	// pruned
+	Locked bool
+	// LockReason contains the reason of the lock, if any
+	LockReason string
+	// Prunable is set to true if the working tree doesn't exist
+	// anymore
+	Prunable bool
+}
+
+// Worktrees returns the linked worktrees of the repository, sorted
+// by name. The main worktree is not returned
+func (r *Repository) Worktrees() ([]*Worktree, error) {
+	fs := r.Config.FS
+	root := filepath.Join(r.Config.CommonDirPath, worktreesDirName)
+	entries, err := afero.ReadDir(fs, root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*Worktree{}, nil
+		}
+		return nil, fmt.Errorf("could not list the worktrees: %w", err)
+	}
+
+	worktrees := make([]*Worktree, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		wt, err := r.worktree(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, wt)
+	}
+	sort.Slice(worktrees, func(i, j int) bool {
+		return worktrees[i].Name < worktrees[j].Name
+	})
+	return worktrees, nil
+}
+
+// worktree returns the worktree administrated by the given directory
+func (r *Repository) worktree(adminDir string) (*Worktree, error) {
+	fs := r.Config.FS
+	wt := &Worktree{
+		Name:     filepath.Base(adminDir),
+		AdminDir: adminDir,
+	}
+
+	gitfile, err := afero.ReadFile(fs, filepath.Join(adminDir, gitdirFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read the gitdir of worktree %s: %w", wt.Name, err)
+	}
+	wt.Prunable = true
+	if p := strings.TrimSpace(string(gitfile)); p != "" {
+		// the path is relative to the administrative directory
+		// when worktree.useRelativePaths is set
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(adminDir, p)
+		}
+		wt.Path = filepath.Dir(p)
+		_, err = fs.Stat(p)
+		wt.Prunable = err != nil
+	}
+
+	reason, err := afero.ReadFile(fs, filepath.Join(adminDir, lockedFileName))
+	switch {
+	case err == nil:
+		wt.Locked = true
+		wt.LockReason = strings.TrimSpace(string(reason))
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("could not check if worktree %s is locked: %w", wt.Name, err)
+	}
+	return wt, nil
+}
+
+// worktreeByPath returns the linked worktree stored at the given path
+func (r *Repository) worktreeByPath(path string) (*Worktree, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the absolute path of %s: %w", path, err)
+	}
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if wt.Path == path {
+			return wt, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", path, ErrWorktreeNotFound)
+}
+
+// MoveWorktree moves a linked worktree to a new location, and updates
+// the repository so it points to the new location.
+// If dst is an existing directory, the worktree will be moved inside
+// it, the same way git worktree move does.
+// Locked worktrees, and the main worktree, cannot be moved
+func (r *Repository) MoveWorktree(src, dst string) error {
+	wt, err := r.worktreeByPath(src)
+	if err != nil {
+		return err
+	}
+	if wt.Locked {
+		if wt.LockReason != "" {
+			return fmt.Errorf("%s (%s): %w", wt.Path, wt.LockReason, ErrWorktreeLocked)
+		}
+		return fmt.Errorf("%s: %w", wt.Path, ErrWorktreeLocked)
+	}
+
+	fs := r.Config.FS
+	if dst, err = filepath.Abs(dst); err != nil {
+		return fmt.Errorf("could not get the absolute path of %s: %w", dst, err)
+	}
+	if info, err := fs.Stat(dst); err == nil && info.IsDir() {
+		dst = filepath.Join(dst, filepath.Base(wt.Path))
+	}
+	if _, err = fs.Stat(dst); err == nil {
+		return fmt.Errorf("%s: %w", dst, ErrWorktreeExists)
+	}
+
+	if err = fs.Rename(wt.Path, dst); err != nil {
+		return fmt.Errorf("could not move %s to %s: %w", wt.Path, dst, err)
+	}
+	// The .git file of the worktree points to the administrative
+	// directory, which doesn't move. The only thing left is to
+	// update the back pointer
+	if err = r.writeWorktreeGitdir(wt, dst); err != nil {
+		// we move the directory back, so the repository stays
+		// consistent
+		if e := fs.Rename(dst, wt.Path); e != nil {
+			return fmt.Errorf("could not update the gitdir of %s (%v), and could not move the worktree back: %w", wt.Name, err, e) //nolint:errorlint // we can only wrap one error
+		}
+		return err
+	}
+	return nil
+}
+
+// writeWorktreeGitdir updates the administrative files of the given
+// worktree so they point to the provided working tree
+func (r *Repository) writeWorktreeGitdir(wt *Worktree, path string) error {
+	data := []byte(filepath.Join(path, ".git") + "\n")
+	if err := lockfile.WriteFile(r.Config.FS, filepath.Join(wt.AdminDir, gitdirFileName), data, lockfile.Options{}); err != nil {
+		return fmt.Errorf("could not update the gitdir of %s: %w", wt.Name, err)
+	}
+	return nil
+}
+
+// RepairWorktrees fixes the links between the repository and its
+// linked worktrees, the same way git worktree repair does:
+//   - The .git file of each linked worktree is updated to point to the
+//     repository, in case the repository has been moved manually
+//   - The provided paths are linked worktrees that have been moved
+//     manually. The repository is updated to point to their new
+//     location (and their .git file to point to the repository, in case
+//     both the repository and the worktrees have been moved)
+//
+// The files that have been updated are returned
+func (r *Repository) RepairWorktrees(paths ...string) (repaired []string, err error) {
+	fs := r.Config.FS
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*Worktree, len(worktrees))
+	for _, wt := range worktrees {
+		byName[wt.Name] = wt
+	}
+
+	for _, p := range paths {
+		if p, err = filepath.Abs(p); err != nil {
+			return repaired, fmt.Errorf("could not get the absolute path of %s: %w", p, err)
+		}
+		gitfile := filepath.Join(p, ".git")
+		data, err := afero.ReadFile(fs, gitfile)
+		if err != nil {
+			return repaired, fmt.Errorf("could not read %s: %w", gitfile, err)
+		}
+		if !bytes.HasPrefix(data, []byte(gitfilePrefix)) {
+			return repaired, fmt.Errorf("%s: %w", p, ErrWorktreeNotFound)
+		}
+		// The administrative directory has the same name, but may
+		// have been moved with the repository
+		name := filepath.Base(strings.TrimSpace(string(data[len(gitfilePrefix):])))
+		wt, ok := byName[name]
+		if !ok {
+			return repaired, fmt.Errorf("%s: %w", p, ErrWorktreeNotFound)
+		}
+		if wt.Path != p {
+			if err = r.writeWorktreeGitdir(wt, p); err != nil {
+				return repaired, err
+			}
+			repaired = append(repaired, filepath.Join(wt.AdminDir, gitdirFileName))
+			wt.Path = p
+			wt.Prunable = false
+		}
+	}
+
+	for _, wt := range worktrees {
+		if wt.Prunable {
+			continue
+		}
+		gitfile := filepath.Join(wt.Path, ".git")
+		data, err := afero.ReadFile(fs, gitfile)
+		if err != nil {
+			return repaired, fmt.Errorf("could not read %s: %w", gitfile, err)
+		}
+		expected := gitfilePrefix + wt.AdminDir + "\n"
+		if string(data) == expected {
+			continue
+		}
+		if err = lockfile.WriteFile(fs, gitfile, []byte(expected), lockfile.Options{}); err != nil {
+			return repaired, fmt.Errorf("could not update %s: %w", gitfile, err)
+		}
+		repaired = append(repaired, gitfile)
+	}
+	return repaired, nil
+}
@@ -0,0 +1,165 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/git-go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addLinkedWorktree creates the files of a linked worktree the same
+// way git worktree add does
+func addLinkedWorktree(t *testing.T, dotGitPath, name, path string) {
+	t.Helper()
+
+	adminDir := filepath.Join(dotGitPath, "worktrees", name)
+	require.NoError(t, os.MkdirAll(adminDir, 0o755))
+	require.NoError(t, os.MkdirAll(path, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(adminDir, "gitdir"), []byte(filepath.Join(path, ".git")+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(adminDir, "commondir"), []byte("../..\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(adminDir, "HEAD"), []byte("bbb720a96e4c29b9950a4c577c98470a4d5dd089\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(path, ".git"), []byte("gitdir: "+adminDir+"\n"), 0o644))
+}
+
+func TestWorktrees(t *testing.T) {
+	t.Parallel()
+
+	// newRepo returns the small repo with a linked worktree named
+	// "linked", and the directory that contains the worktree
+	newRepo := func(t *testing.T) (r *Repository, dir string) {
+		t.Helper()
+
+		repoPath, cleanup := testutil.UnTar(t, testutil.RepoSmall)
+		t.Cleanup(cleanup)
+		dir, cleanupDir := testutil.TempDir(t)
+		t.Cleanup(cleanupDir)
+
+		addLinkedWorktree(t, filepath.Join(repoPath, ".git"), "linked", filepath.Join(dir, "linked"))
+		r, err := OpenRepository(repoPath)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, r.Close())
+		})
+		return r, dir
+	}
+
+	t.Run("should list the linked worktrees", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		worktrees, err := r.Worktrees()
+		require.NoError(t, err)
+		require.Len(t, worktrees, 1)
+		assert.Equal(t, "linked", worktrees[0].Name)
+		assert.Equal(t, filepath.Join(dir, "linked"), worktrees[0].Path)
+		assert.False(t, worktrees[0].Locked)
+		assert.False(t, worktrees[0].Prunable)
+	})
+
+	t.Run("should move a worktree", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		dst := filepath.Join(dir, "moved")
+		require.NoError(t, r.MoveWorktree(filepath.Join(dir, "linked"), dst))
+
+		_, err := os.Stat(filepath.Join(dir, "linked"))
+		require.True(t, errors.Is(err, os.ErrNotExist), "the worktree should have been moved")
+		worktrees, err := r.Worktrees()
+		require.NoError(t, err)
+		require.Len(t, worktrees, 1)
+		assert.Equal(t, dst, worktrees[0].Path)
+		assert.False(t, worktrees[0].Prunable)
+	})
+
+	t.Run("should move a worktree inside an existing directory", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		dst := filepath.Join(dir, "parent")
+		require.NoError(t, os.Mkdir(dst, 0o755))
+		require.NoError(t, r.MoveWorktree(filepath.Join(dir, "linked"), dst))
+
+		worktrees, err := r.Worktrees()
+		require.NoError(t, err)
+		require.Len(t, worktrees, 1)
+		assert.Equal(t, filepath.Join(dst, "linked"), worktrees[0].Path)
+	})
+
+	t.Run("should not move locked worktrees", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		worktrees, err := r.Worktrees()
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(worktrees[0].AdminDir, "locked"), []byte("on a usb drive\n"), 0o644))
+
+		err = r.MoveWorktree(filepath.Join(dir, "linked"), filepath.Join(dir, "moved"))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrWorktreeLocked), "unexpected error: %v", err)
+		assert.Contains(t, err.Error(), "on a usb drive")
+	})
+
+	t.Run("should not move the main worktree", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		err := r.MoveWorktree(r.Config.WorkTreePath, filepath.Join(dir, "moved"))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrWorktreeNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("should repair a worktree moved manually", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		dst := filepath.Join(dir, "moved")
+		require.NoError(t, os.Rename(filepath.Join(dir, "linked"), dst))
+
+		worktrees, err := r.Worktrees()
+		require.NoError(t, err)
+		require.True(t, worktrees[0].Prunable)
+
+		repaired, err := r.RepairWorktrees(dst)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(worktrees[0].AdminDir, "gitdir")}, repaired)
+
+		worktrees, err = r.Worktrees()
+		require.NoError(t, err)
+		assert.Equal(t, dst, worktrees[0].Path)
+		assert.False(t, worktrees[0].Prunable)
+
+		// Running it again should be a no-op
+		repaired, err = r.RepairWorktrees(dst)
+		require.NoError(t, err)
+		assert.Empty(t, repaired)
+	})
+
+	t.Run("should repair the .git file of the worktrees", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		gitfile := filepath.Join(dir, "linked", ".git")
+		require.NoError(t, os.WriteFile(gitfile, []byte("gitdir: /old/path/.git/worktrees/linked\n"), 0o644))
+
+		repaired, err := r.RepairWorktrees()
+		require.NoError(t, err)
+		assert.Equal(t, []string{gitfile}, repaired)
+
+		data, err := os.ReadFile(gitfile)
+		require.NoError(t, err)
+		assert.Equal(t, "gitdir: "+filepath.Join(r.Config.CommonDirPath, "worktrees", "linked")+"\n", string(data))
+	})
+
+	t.Run("should fail repairing a path that is not a worktree", func(t *testing.T) {
+		t.Parallel()
+
+		r, dir := newRepo(t)
+		_, err := r.RepairWorktrees(dir)
+		require.Error(t, err)
+	})
+}